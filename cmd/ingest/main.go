@@ -13,37 +13,48 @@ import (
 	"rillnet/internal/core/ports"
 	"rillnet/internal/core/services"
 	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/distributed"
 	"rillnet/internal/infrastructure/middleware"
 	"rillnet/internal/infrastructure/monitoring"
+	"rillnet/internal/infrastructure/recording"
 	reliability "rillnet/internal/infrastructure/reliability"
 	repositories "rillnet/internal/infrastructure/repositories"
 	"rillnet/internal/infrastructure/db"
 	webrtcinfra "rillnet/internal/infrastructure/webrtc"
+	"rillnet/internal/wiring"
 	"rillnet/pkg/circuitbreaker"
 	"rillnet/pkg/config"
 	"rillnet/pkg/logger"
 	"rillnet/pkg/retry"
+	"rillnet/pkg/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pion/webrtc/v3"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
 func main() {
 	startTime := time.Now()
 
-	cfg, err := config.LoadResolved()
+	configPath := config.ResolveConfigPath()
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Initialize logger
-	zapLogger := logger.New(cfg.Logging.Level)
+	logLevel := zap.NewAtomicLevelAt(logger.ParseLevel(cfg.Logging.Level))
+	zapLogger := logger.NewWithLevel(logLevel)
 	defer func() { _ = zapLogger.Sync() }()
 
 	log := zapLogger.Sugar()
 
+	if err := validation.SetStreamIDPolicy(cfg.Streams.StreamIDPolicy()); err != nil {
+		log.Fatalw("invalid stream ID policy", "error", err)
+	}
+
 	// Initialize repository factory
 	repoFactory, err := repositories.NewRepositoryFactory(cfg, log)
 	if err != nil {
@@ -63,43 +74,27 @@ func main() {
 	meshRepo := repoFactory.CreateMeshRepository()
 	userRepo := repoFactory.CreateUserRepository()
 	refreshRepo := repoFactory.CreateRefreshTokenRepository()
+	refreshFamilies := repoFactory.CreateRefreshTokenFamilyStore()
 
 	// Initialize services
-	qualityService := services.NewQualityService()
-	metricsService := services.NewMetricsService()
-	baseMeshService := services.NewMeshService(peerRepo, meshRepo, cfg.Mesh, log)
-
-	// Wrap mesh service with retry and circuit breaker if enabled
-	var meshService ports.MeshService
-	if cfg.Retry.Enabled || cfg.CircuitBreaker.Enabled {
-		retryCfg := retry.Config{
-			Enabled:      cfg.Retry.Enabled,
-			MaxAttempts:  cfg.Retry.MaxAttempts,
-			InitialDelay: cfg.Retry.InitialDelay,
-			MaxDelay:     cfg.Retry.MaxDelay,
-			Multiplier:   cfg.Retry.Multiplier,
-			Jitter:       cfg.Retry.Jitter,
-		}
-		cbCfg := circuitbreaker.Config{
-			FailureThreshold:   cfg.CircuitBreaker.FailureThreshold,
-			SuccessThreshold:   cfg.CircuitBreaker.SuccessThreshold,
-			Timeout:            cfg.CircuitBreaker.Timeout,
-			MaxRequestsHalfOpen: cfg.CircuitBreaker.MaxRequestsHalfOpen,
-		}
-		meshService = reliability.NewMeshServiceWrapper(baseMeshService, retryCfg, cbCfg, log)
-	} else {
-		meshService = baseMeshService
+	container, err := wiring.NewBuilder(wiring.RoleIngest).
+		WithConfig(cfg).
+		WithLogger(log).
+		WithStreamRepository(streamRepo).
+		WithPeerRepository(peerRepo).
+		WithMeshRepository(meshRepo).
+		WithUserRepository(userRepo).
+		WithRefreshTokenRepository(refreshRepo).
+		WithRefreshTokenFamilyStore(refreshFamilies).
+		Build()
+	if err != nil {
+		log.Fatalw("failed to wire services", "error", err)
 	}
-
-	streamService := services.NewStreamService(streamRepo, peerRepo, meshRepo, meshService, metricsService)
-	authService := services.NewAuthService(
-		cfg.Auth.JWTSecret,
-		cfg.Auth.AccessTokenTTL,
-		cfg.Auth.RefreshTokenTTL,
-		streamService,
-		userRepo,
-		refreshRepo,
-	)
+	qualityService := container.QualityService
+	metricsService := container.MetricsService
+	meshService := container.MeshService
+	streamService := container.StreamService
+	authService := container.AuthService
 
 	// WebRTC configuration (including STUN/TURN from config)
 	var iceServers []webrtc.ICEServer
@@ -123,6 +118,31 @@ func main() {
 		Simulcast:  cfg.WebRTC.Simulcast,
 		MaxBitrate: cfg.WebRTC.MaxBitrate,
 		NAT1To1IPs: cfg.WebRTC.NAT1To1IPs,
+		KeyRotationInterval: cfg.WebRTC.KeyRotationInterval,
+		RenegotiationTimeout: cfg.WebRTC.RenegotiationTimeout,
+		TrickleICE:          cfg.WebRTC.TrickleICE,
+		MaxPendingCandidates: cfg.WebRTC.MaxPendingCandidates,
+		MaxICECandidatesPerMinute: cfg.WebRTC.MaxICECandidatesPerMinute,
+		PreconnectTTL:        cfg.WebRTC.PreconnectTTL,
+		MaxPreconnectsPerUser: cfg.WebRTC.MaxPreconnectsPerUser,
+		MaxForwardedStreams:  cfg.WebRTC.MaxForwardedStreams,
+		MaxDataChannelMessageSize: cfg.WebRTC.MaxDataChannelMessageBytes,
+		Eviction: webrtcinfra.EvictionPolicy{
+			ICEDisconnectGrace: cfg.WebRTC.Eviction.ICEDisconnectGrace,
+			MetricsStaleGrace:  cfg.WebRTC.Eviction.MetricsStaleGrace,
+			AnswerTimeout:      cfg.WebRTC.Eviction.AnswerTimeout,
+		},
+	}
+	if len(cfg.WebRTC.RTCPFeedback) > 0 {
+		webrtcConfig.RTCPFeedback = make(map[string]webrtcinfra.FeedbackPolicy, len(cfg.WebRTC.RTCPFeedback))
+		for codec, feedback := range cfg.WebRTC.RTCPFeedback {
+			webrtcConfig.RTCPFeedback[codec] = webrtcinfra.FeedbackPolicy{
+				NACK:        feedback.NACK,
+				PLI:         feedback.PLI,
+				FIR:         feedback.FIR,
+				TransportCC: feedback.TransportCC,
+			}
+		}
 	}
 	webrtcConfig.PortRange.Min = cfg.WebRTC.PortRange.Min
 	webrtcConfig.PortRange.Max = cfg.WebRTC.PortRange.Max
@@ -141,17 +161,104 @@ func main() {
 		SuccessThreshold:   cfg.CircuitBreaker.SuccessThreshold,
 		Timeout:            cfg.CircuitBreaker.Timeout,
 		MaxRequestsHalfOpen: cfg.CircuitBreaker.MaxRequestsHalfOpen,
+		WindowSize:          cfg.CircuitBreaker.WindowSize,
+		FailureRatio:        cfg.CircuitBreaker.FailureRatio,
 	}
 
 	// Initialize SFU
 	sfuService := webrtcinfra.NewSFUService(webrtcConfig, qualityService, metricsService, meshService, retryCfg, cbCfg)
+	rotationCtx, stopKeyRotation := context.WithCancel(context.Background())
+	defer stopKeyRotation()
+	go sfuService.(*webrtcinfra.SFUService).StartKeyRotation(rotationCtx)
+
+	// Catch a broken WebRTC setup before the first client does
+	if mode := cfg.WebRTC.StartupSelfTest; mode == "warn" || mode == "fatal" {
+		if err := sfuService.(*webrtcinfra.SFUService).RunSelfTest(context.Background()); err != nil {
+			if mode == "fatal" {
+				log.Fatalw("WebRTC startup self-test failed", "error", err)
+			}
+			log.Warnw("WebRTC startup self-test failed", "error", err)
+		} else {
+			log.Info("WebRTC startup self-test passed")
+		}
+	}
+
+	// Keep the repository entries of peers connected here from expiring;
+	// peers that joined over HTTP have no signaling socket to heartbeat them
+	heartbeatCtx, stopHeartbeats := context.WithCancel(context.Background())
+	defer stopHeartbeats()
+	if heartbeater, ok := peerRepo.(ports.PeerHeartbeater); ok && cfg.Redis.PeerTTL > 0 {
+		go sfuService.(*webrtcinfra.SFUService).RunPeerHeartbeats(heartbeatCtx, heartbeater, cfg.Redis.PeerTTL/3)
+	}
+
+	// Adapt each connected subscriber's simulcast layer to its measured network
+	abrService := services.NewAdaptiveBitrateServiceWithConfig(qualityService, meshService, cfg.AdaptiveBitrate, log)
+	layerSwitcher := sfuService.(*webrtcinfra.SFUService)
+	abrService.SetOnQualityChange(func(ctx context.Context, peerID domain.PeerID, from, to string) error {
+		return layerSwitcher.SwitchSubscriberLayer(ctx, peerID, to)
+	})
+	abrService.SetStatsProvider(sfuService)
+	sfuService.(*webrtcinfra.SFUService).SetSubscriberMonitor(abrService)
+
+	// Apply SFU requests (pause/resume, interest) sent by the signal servers,
+	// and push trickled ICE candidates, dropped peers, stopped streams and
+	// simulcast layer changes back through them
+	bridgeCtx, stopBridge := context.WithCancel(context.Background())
+	defer stopBridge()
+	if redisClient := repoFactory.RedisClient(); redisClient != nil {
+		distributed.ListenSFUCommands(bridgeCtx, redisClient, sfuService.(*webrtcinfra.SFUService), log)
+		events := distributed.NewSFUEventPublisher(redisClient)
+		sfuService.(*webrtcinfra.SFUService).SetICECandidateSink(events)
+		sfuService.(*webrtcinfra.SFUService).SetPeerLeftNotifier(events)
+		sfuService.(*webrtcinfra.SFUService).SetLayersNotifier(events)
+		if hooks, ok := streamService.(services.StreamStopHooks); ok {
+			hooks.SetStopNotifier(events)
+			hooks.SetResetNotifier(events)
+			hooks.SetKickNotifier(events)
+		}
+	} else if cfg.WebRTC.TrickleICE {
+		log.Warnw("webrtc.trickle_ice needs Redis to reach the signal servers; sending complete descriptions instead")
+	}
 
 	// Initialize monitoring
-	_ = monitoring.NewPrometheusCollector()
+	collector := monitoring.NewPrometheusCollector()
+	// Goroutine, forwarder and monitor counts, to alert on leaks
+	gaugesCtx, stopGauges := context.WithCancel(context.Background())
+	defer stopGauges()
+	if cfg.Monitoring.PrometheusEnabled {
+		go monitoring.NewRuntimeGauges(sfuService.(*webrtcinfra.SFUService), abrService).Run(gaugesCtx, cfg.Monitoring.MetricsInterval)
+	}
+
+	// Stopping a stream closes its SFU connections and ends its metrics
+	if hooks, ok := streamService.(services.StreamStopHooks); ok {
+		hooks.SetStopHooks(sfuService.(*webrtcinfra.SFUService), collector)
+	}
+	// Stream lifetimes are recorded by the stream service, peer sessions by the SFU
+	if hooks, ok := streamService.(services.MetricsHooks); ok {
+		hooks.SetMetrics(collector)
+	}
+	if hooks, ok := sfuService.(services.MetricsHooks); ok {
+		hooks.SetMetrics(collector)
+	}
+	if wrapper, ok := meshService.(*reliability.MeshServiceWrapper); ok {
+		wrapper.SetCircuitBreakerMetrics(collector)
+	}
 
 	// Initialize HTTP handlers
 	authHandler := httphandlers.NewAuthHandler(authService)
 	streamHandler := httphandlers.NewStreamHandler(streamService, sfuService)
+	streamHandler.SetQualityHistory(abrService)
+	if kicker, ok := streamService.(ports.PeerKicker); ok {
+		streamHandler.SetPeerKicker(kicker)
+	}
+	metricsHandler := httphandlers.NewMetricsHandler(streamService, metricsService, collector)
+	adminHandler := httphandlers.NewAdminHandler(meshService)
+	if resetter, ok := streamService.(ports.StreamResetter); ok {
+		adminHandler.SetStreamResetter(resetter)
+	}
+	configHandler := httphandlers.NewConfigHandler(cfg)
+	iceServerHandler := httphandlers.NewICEServerHandler(sfuService.(*webrtcinfra.SFUService))
+	reportHandler := httphandlers.NewReportHandler(streamService, meshService, peerRepo, metricsService, collector)
 
 	// Configure Gin
 	if cfg.Logging.Level != "debug" {
@@ -167,12 +274,15 @@ func main() {
 		router.Use(middleware.SimpleCORSMiddleware())
 	}
 
+	// Request body cap for JSON API routes
+	bodyLimit := middleware.NewBodyLimitMiddleware(cfg.Server.MaxRequestBodyBytes)
+
 	// Setup auth routes FIRST (public) - before any other middleware that might interfere
 	// Register directly on router to avoid any group conflicts
 	log.Info("Registering auth routes directly on router...")
-	router.POST("/api/v1/auth/register", authHandler.Register)
-	router.POST("/api/v1/auth/login", authHandler.Login)
-	router.POST("/api/v1/auth/refresh", authHandler.RefreshToken)
+	router.POST("/api/v1/auth/register", bodyLimit, authHandler.Register)
+	router.POST("/api/v1/auth/login", bodyLimit, authHandler.Login)
+	router.POST("/api/v1/auth/refresh", bodyLimit, authHandler.RefreshToken)
 	log.Info("Auth routes registered: /api/v1/auth/register, /api/v1/auth/login, /api/v1/auth/refresh")
 
 	// Health check endpoint (must be before rate limiting)
@@ -225,21 +335,83 @@ func main() {
 	// Setup stream routes with authentication
 	// Register stream routes directly with full path to avoid conflicts with auth routes
 	streamAPI := router.Group("/api/v1/streams")
-	streamAPI.Use(middleware.AuthMiddleware(authService))
+	streamAPI.Use(middleware.AuthMiddleware(authService), bodyLimit)
 	{
 		streamAPI.POST("", streamHandler.CreateStream)
 		streamAPI.GET("", streamHandler.ListStreams)
 		streamAPI.GET("/:id", streamHandler.GetStream)
 		streamAPI.POST("/:id/join", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.JoinStream)
 		streamAPI.POST("/:id/leave", streamHandler.LeaveStream)
+		streamAPI.POST("/:id/stop", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.StopStream)
 		streamAPI.GET("/:id/stats", streamHandler.GetStreamStats)
 		streamAPI.GET("/:id/webrtc/ready", streamHandler.GetWebRTCReadiness)
+		streamAPI.GET("/:id/peers", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.SearchPeers)
+		streamAPI.GET("/:id/peers/:peerId/stats", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.GetPeerStats)
+		streamAPI.GET("/:id/peers/:peerId/quality-history", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.GetQualityHistory)
 
 		// WebRTC endpoints
 		streamAPI.POST("/:id/publisher/offer", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.CreatePublisherOffer)
 		streamAPI.POST("/:id/publisher/answer", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.HandlePublisherAnswer)
+		streamAPI.POST("/:id/publisher/pause", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.PausePublisher)
+		streamAPI.POST("/:id/publisher/resume", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.ResumePublisher)
 		streamAPI.POST("/:id/subscriber/offer", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.CreateSubscriberOffer)
 		streamAPI.POST("/:id/subscriber/answer", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.HandleSubscriberAnswer)
+		streamAPI.PUT("/:id/subscriber/tracks", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.UpdateSubscription)
+		streamAPI.POST("/:id/ice-candidate", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.AddICECandidate)
+		streamAPI.POST("/:id/preconnect/:handle/bind", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.BindPreconnect)
+		streamAPI.POST("/:id/rotate-keys", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.RotateKeys)
+		streamAPI.PUT("/:id/keyframe-on-join", middleware.StreamPermissionMiddleware(authService, domain.RoleModerator), streamHandler.SetKeyframeOnJoin)
+		streamAPI.PUT("/:id/svc-layer", middleware.StreamPermissionMiddleware(authService, domain.RoleModerator), streamHandler.SetSVCLayer)
+		streamAPI.POST("/:id/peers/:peerId/kick", middleware.StreamPermissionMiddleware(authService, domain.RoleModerator), streamHandler.KickPeer)
+		streamAPI.GET("/:id/renegotiation", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.GetPendingRenegotiation)
+	}
+
+	// Subscriber preconnects, bound to a stream once the viewer picks one
+	// (POST /streams/:id/preconnect/:handle/bind). Only viewers' clients
+	// preconnect, so API keys are not accepted.
+	preconnectAPI := router.Group("/api/v1/preconnect")
+	preconnectAPI.Use(middleware.JWTAuthMiddleware(authService), bodyLimit)
+	{
+		preconnectAPI.POST("", streamHandler.CreatePreconnect)
+	}
+
+	// JSON metrics snapshot for tooling that cannot scrape Prometheus
+	metricsAPI := router.Group("/api/v1/metrics")
+	metricsAPI.Use(middleware.AuthMiddleware(authService))
+	{
+		metricsAPI.GET("/snapshot", metricsHandler.GetSnapshot)
+		metricsAPI.GET("/pressure", metricsHandler.GetPressure)
+	}
+
+	// Rotation of TURN credentials for new peer connections, by operators only
+	iceServerAPI := router.Group("/api/v1/ice-servers")
+	iceServerAPI.Use(middleware.AuthMiddleware(authService), middleware.RoleMiddleware(domain.RoleOperator), bodyLimit)
+	{
+		iceServerAPI.POST("", iceServerHandler.UpdateICEServers)
+	}
+
+	// Operator endpoints for debugging mesh state
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(middleware.AuthMiddleware(authService))
+	{
+		adminAPI.GET("/report", middleware.RoleMiddleware(domain.RoleOperator), reportHandler.GetReport)
+		adminAPI.GET("/config", middleware.RoleMiddleware(domain.RoleOperator), configHandler.GetConfig)
+		adminAPI.GET("/streams/:id/topology", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), adminHandler.GetTopology)
+		adminAPI.GET("/streams/:id/topology/diff", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), adminHandler.DiffTopology)
+		adminAPI.POST("/streams/:id/reset", middleware.RoleMiddleware(domain.RoleOperator), adminHandler.ResetStream)
+		adminAPI.GET("/mesh/weights", middleware.RoleMiddleware(domain.RoleOperator), adminHandler.GetScoringWeights)
+		adminAPI.PUT("/mesh/weights", middleware.RoleMiddleware(domain.RoleOperator), bodyLimit, adminHandler.SetScoringWeights)
+	}
+
+	// Recording playback, only when a recording directory is configured
+	if cfg.Recording.Directory != "" {
+		recordingIndex := recording.NewFileIndex(cfg.Recording.Directory)
+		if cfg.Recording.Enabled {
+			sfuService.(*webrtcinfra.SFUService).SetRecording(cfg.Recording.Directory, recordingIndex)
+		}
+		recordingHandler := httphandlers.NewRecordingHandler(recordingIndex)
+		streamAPI.GET("/:id/recordings", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), recordingHandler.ListRecordings)
+		streamAPI.GET("/:id/recordings/:recording_id", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), recordingHandler.DownloadRecording)
 	}
 
 	// Create HTTP server with timeouts
@@ -260,6 +432,25 @@ func main() {
 		}
 	}()
 
+
+	// Reload the log level, mesh scoring weights on SIGHUP
+	configWatcher := config.NewWatcher(configPath, cfg)
+	configWatcher.Subscribe(config.ReloadableFunc(func(reloaded *config.Config) {
+		logLevel.SetLevel(logger.ParseLevel(reloaded.Logging.Level))
+	}))
+	if reloadable, ok := meshService.(config.Reloadable); ok {
+		configWatcher.Subscribe(reloadable)
+	}
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	go configWatcher.Watch(reloadCtx, func(_ *config.Config, err error) {
+		if err != nil {
+			log.Errorw("config reload rejected, keeping the current config", "error", err)
+			return
+		}
+		log.Infow("config reloaded", "path", configPath)
+	})
+
 	// Wait for shutdown signals or server error
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -288,6 +479,18 @@ func main() {
 		log.Info("Server shutdown gracefully")
 	}
 
+	// Close every WebRTC session before the repositories go away
+	stopKeyRotation()
+	stopHeartbeats()
+	stopBridge()
+	abrService.Close()
+	if stopper, ok := streamService.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
+	if err := sfuService.(*webrtcinfra.SFUService).Shutdown(shutdownCtx); err != nil {
+		log.Errorw("Error shutting down SFU", "error", err)
+	}
+
 	// Close repository factory
 	if err := repoFactory.Close(); err != nil {
 		log.Errorw("Error closing repository factory", "error", err)
@@ -10,27 +10,39 @@ import (
 	"syscall"
 	"time"
 
-	"rillnet/internal/core/services"
+	"rillnet/internal/infrastructure/distributed"
+	"rillnet/internal/infrastructure/monitoring"
 	repositories "rillnet/internal/infrastructure/repositories"
 	signalserver "rillnet/internal/infrastructure/signal"
+	"rillnet/internal/wiring"
 	"rillnet/pkg/config"
 	"rillnet/pkg/logger"
+	"rillnet/pkg/validation"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
 func main() {
 	startTime := time.Now()
 
-	cfg, err := config.LoadResolved()
+	configPath := config.ResolveConfigPath()
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Initialize logger
-	zapLogger := logger.New(cfg.Logging.Level)
+	logLevel := zap.NewAtomicLevelAt(logger.ParseLevel(cfg.Logging.Level))
+	zapLogger := logger.NewWithLevel(logLevel)
 	defer func() { _ = zapLogger.Sync() }()
 	log := zapLogger.Sugar()
 
+	if err := validation.SetStreamIDPolicy(cfg.Streams.StreamIDPolicy()); err != nil {
+		log.Fatalw("invalid stream ID policy", "error", err)
+	}
+
 	// Initialize repository factory
 	repoFactory, err := repositories.NewRepositoryFactory(cfg, log)
 	if err != nil {
@@ -41,22 +53,44 @@ func main() {
 	// Initialize repositories
 	peerRepo := repoFactory.CreatePeerRepository()
 	meshRepo := repoFactory.CreateMeshRepository()
+	streamRepo := repoFactory.CreateStreamRepository()
 
-	// Initialize mesh service
-	meshService := services.NewMeshService(peerRepo, meshRepo, cfg.Mesh, log)
-
-	// Initialize auth service (stream service not needed for signal server)
-	authService := services.NewAuthService(
-		cfg.Auth.JWTSecret,
-		cfg.Auth.AccessTokenTTL,
-		cfg.Auth.RefreshTokenTTL,
-		nil, // Stream service not needed for WebSocket token validation
-		nil,
-		nil,
-	)
+	// Build the signaling services; signal only validates tokens
+	container, err := wiring.NewBuilder(wiring.RoleSignal).
+		WithConfig(cfg).
+		WithLogger(log).
+		WithStreamRepository(streamRepo).
+		WithPeerRepository(peerRepo).
+		WithMeshRepository(meshRepo).
+		Build()
+	if err != nil {
+		log.Fatalw("failed to wire services", "error", err)
+	}
+	meshService := container.MeshService
+	streamService := container.StreamService
+	authService := container.AuthService
 
 	// Initialize WebSocket server
 	wsServer := signalserver.NewWebSocketServer(peerRepo, meshService, authService, cfg.Auth.AllowedOrigins)
+	wsServer.SetStreamRepository(streamRepo)
+	wsServer.SetStreamService(streamService)
+
+	// Relay signaling to peers connected to other instances through Redis
+	bridgeCtx, stopBridge := context.WithCancel(context.Background())
+	defer stopBridge()
+	if redisClient := repoFactory.RedisClient(); redisClient != nil {
+		peerRegistry := distributed.NewSharedPeerRegistry(redisClient, cfg.Distributed.InstanceID, log)
+		peerRegistry.SetTTL(cfg.Distributed.PeerRegistryTTL, cfg.Distributed.PeerRegistryTTLJitter)
+		wsServer.EnableCrossInstanceRelay(redisClient, peerRegistry, cfg.Distributed.InstanceID)
+		log.Infow("cross-instance signaling relay enabled", "instance_id", cfg.Distributed.InstanceID)
+
+		// Forward SFU requests (pause/resume, interest) to the ingest instances and
+		// deliver their events (trickled ICE candidates, dropped peers) to local peers
+		sfuCommands := distributed.NewSFUCommandClient(redisClient)
+		wsServer.SetPublisherPauser(sfuCommands)
+		wsServer.SetInterestSetter(sfuCommands)
+		distributed.ListenSFUEvents(bridgeCtx, redisClient, wsServer, log)
+	}
 
 	// Configure ping/pong intervals from config
 	if cfg.Signal.PingInterval > 0 {
@@ -65,6 +99,14 @@ func main() {
 	if cfg.Signal.PongTimeout > 0 {
 		wsServer.SetPongTimeout(cfg.Signal.PongTimeout)
 	}
+	wsServer.SetMaxOutboundBacklog(cfg.Signal.MaxOutboundBacklog)
+	wsServer.SetIdleTimeout(cfg.Signal.IdleTimeout)
+	wsServer.SetReconnectBackoff(cfg.Signal.ReconnectBackoff, cfg.Signal.ReconnectBackoffMax)
+	wsServer.SetCompression(cfg.Signal.CompressionEnabled, cfg.Signal.CompressionLevel)
+	wsServer.SetMaxICECandidates(cfg.WebRTC.MaxICECandidatesPerMinute)
+	if cfg.Signal.AsyncAuth {
+		wsServer.SetAsyncAuth(signalserver.AuthServiceValidator{Auth: authService}, signalserver.AuthPolicy(cfg.Signal.AuthPolicy), cfg.Signal.AuthTimeout)
+	}
 
 	// Configure rate limiting for WebSocket server from config
 	if cfg.RateLimiting.Enabled {
@@ -80,6 +122,8 @@ func main() {
 		if cfg.RateLimiting.WebSocket.MaxMessageSizeBytes > 0 {
 			wsServer.SetMaxMessageSize(cfg.RateLimiting.WebSocket.MaxMessageSizeBytes)
 		}
+		wsServer.SetQuarantine(cfg.RateLimiting.WebSocket.QuarantineStrikes, cfg.RateLimiting.WebSocket.QuarantineWindow, cfg.RateLimiting.WebSocket.QuarantineCooldown)
+		wsServer.SetQuarantineByIP(cfg.RateLimiting.WebSocket.QuarantineByIP)
 	}
 
 	// Setup HTTP routes
@@ -128,6 +172,47 @@ func main() {
 		}
 	}()
 
+	// Metrics are served on their own port so they stay off the public signaling listener
+	var metricsSrv *http.Server
+	if cfg.Monitoring.PrometheusEnabled {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		metricsSrv = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Monitoring.PrometheusPort),
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		gaugesCtx, stopGauges := context.WithCancel(context.Background())
+		defer stopGauges()
+		go monitoring.NewRuntimeGauges(nil, nil).Run(gaugesCtx, cfg.Monitoring.MetricsInterval)
+
+		go func() {
+			log.Infof("Serving signaling metrics on %s", metricsSrv.Addr)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Errorw("Metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	// Reload the log level, WebSocket rate limits and mesh scoring weights on SIGHUP
+	configWatcher := config.NewWatcher(configPath, cfg)
+	configWatcher.Subscribe(config.ReloadableFunc(func(reloaded *config.Config) {
+		logLevel.SetLevel(logger.ParseLevel(reloaded.Logging.Level))
+	}))
+	configWatcher.Subscribe(wsServer)
+	if reloadable, ok := meshService.(config.Reloadable); ok {
+		configWatcher.Subscribe(reloadable)
+	}
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	go configWatcher.Watch(reloadCtx, func(_ *config.Config, err error) {
+		if err != nil {
+			log.Errorw("config reload rejected, keeping the current config", "error", err)
+			return
+		}
+		log.Infow("config reloaded", "path", configPath)
+	})
+
 	// Wait for shutdown signals or server error
 	sigChan := make(chan os.Signal, 1)
 	osignal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -145,6 +230,9 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Signal.ShutdownTimeout)
 	defer shutdownCancel()
 
+	// Stop delivering SFU events before the connections close
+	stopBridge()
+
 	// Shutdown WebSocket server gracefully (close all connections)
 	if err := wsServer.Shutdown(shutdownCtx); err != nil {
 		log.Errorw("Error during WebSocket server shutdown", "error", err)
@@ -161,6 +249,12 @@ func main() {
 		log.Info("HTTP server shutdown gracefully")
 	}
 
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			log.Errorw("Error during metrics server shutdown", "error", err)
+		}
+	}
+
 	// Close repository factory
 	if err := repoFactory.Close(); err != nil {
 		log.Errorw("Error closing repository factory", "error", err)
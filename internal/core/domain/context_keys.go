@@ -5,3 +5,6 @@ type contextKey string
 
 // UserIDContextKey carries the authenticated user ID in request context.
 const UserIDContextKey contextKey = "user_id"
+
+// UserRoleContextKey carries the authenticated user's global role (if any) in request context.
+const UserRoleContextKey contextKey = "user_role"
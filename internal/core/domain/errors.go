@@ -13,4 +13,20 @@ var (
 	ErrUserAlreadyExists   = errors.New("user already exists")
 	ErrInvalidCredentials  = errors.New("invalid credentials")
 	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	ErrStreamQuotaExceeded = errors.New("stream quota exceeded for owner")
+	ErrInstanceAtCapacity  = errors.New("instance stream capacity reached")
+	ErrCandidateQueueFull  = errors.New("pending ICE candidate queue full")
+	ErrTooManyCandidates   = errors.New("ICE candidate limit exceeded")
+	ErrRecordingNotFound   = errors.New("recording not found")
+	// A peer belongs to at most one stream; it must leave before joining another
+	ErrPeerInOtherStream = errors.New("peer already joined another stream")
+
+	ErrStreamBandwidthExceeded = errors.New("stream bandwidth budget exceeded")
+	ErrInvalidStreamPriority   = errors.New("invalid stream priority")
+	ErrPreconnectNotFound      = errors.New("preconnect not found or expired")
+	ErrTooManyPreconnects      = errors.New("too many open preconnects")
+	// A peer's connection renegotiates once at a time; the next offer waits for the answer
+	ErrRenegotiationPending = errors.New("renegotiation already in progress")
+	// A rotated refresh token was presented again, so it was copied; its whole family is revoked
+	ErrRefreshTokenReused = errors.New("refresh token reused")
 )
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// IDTypeError is returned when a JSON ID is neither a string nor a number
+type IDTypeError struct {
+	Type  string // Go type being decoded, e.g. "PeerID"
+	Value string // Raw JSON value
+}
+
+func (e *IDTypeError) Error() string {
+	return fmt.Sprintf("%s must be a JSON string or number, got %s", e.Type, e.Value)
+}
+
+// UnmarshalJSON accepts string and numeric IDs, keeping numbers as their literal text.
+func (id *PeerID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, "PeerID", (*string)(id))
+}
+
+// UnmarshalJSON accepts string and numeric IDs, keeping numbers as their literal text.
+func (id *StreamID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, "StreamID", (*string)(id))
+}
+
+// unmarshalID decodes a JSON string or number into dst. null leaves dst
+// unchanged, as it does for plain strings.
+func unmarshalID(data []byte, typeName string, dst *string) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return &IDTypeError{Type: typeName, Value: "empty input"}
+	}
+
+	switch c := data[0]; {
+	case c == '"':
+		return json.Unmarshal(data, dst)
+	case c == '-' || (c >= '0' && c <= '9'):
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return &IDTypeError{Type: typeName, Value: string(data)}
+		}
+		*dst = n.String()
+		return nil
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	default:
+		return &IDTypeError{Type: typeName, Value: string(data)}
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPeerID_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want PeerID
+	}{
+		{"string", `"peer-1"`, "peer-1"},
+		{"numeric string", `"42"`, "42"},
+		{"integer", `42`, "42"},
+		{"negative", `-7`, "-7"},
+		{"large integer keeps precision", `12345678901234567890`, "12345678901234567890"},
+		{"float keeps literal", `1.5`, "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id PeerID
+			if err := json.Unmarshal([]byte(tt.in), &id); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id != tt.want {
+				t.Fatalf("got %q, want %q", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamID_UnmarshalJSONInPayload(t *testing.T) {
+	var payload struct {
+		StreamID   StreamID `json:"stream_id"`
+		TargetPeer PeerID   `json:"target_peer"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"stream_id": 1001, "target_peer": "peer-a"}`), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.StreamID != "1001" || payload.TargetPeer != "peer-a" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+
+	payload.StreamID = "kept"
+	if err := json.Unmarshal([]byte(`{"stream_id": null}`), &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.StreamID != "kept" {
+		t.Fatalf("null should leave the ID unchanged, got %q", payload.StreamID)
+	}
+}
+
+func TestStreamID_UnmarshalJSONRejectsOtherTypes(t *testing.T) {
+	for _, in := range []string{`true`, `{"id": 1}`, `[1]`} {
+		var id StreamID
+		err := json.Unmarshal([]byte(in), &id)
+
+		var typeErr *IDTypeError
+		if !errors.As(err, &typeErr) {
+			t.Fatalf("%s: expected IDTypeError, got %v", in, err)
+		}
+		if typeErr.Type != "StreamID" {
+			t.Fatalf("%s: unexpected type %q", in, typeErr.Type)
+		}
+	}
+}
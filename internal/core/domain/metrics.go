@@ -12,6 +12,20 @@ type NetworkMetrics struct {
 	AvailableBitrate int // kbps
 }
 
+// PeerRTCStats is the latest connection snapshot the SFU holds for a peer.
+// Loss, jitter and RTT come from RTCP; byte counters from the ICE transport.
+type PeerRTCStats struct {
+	PeerID           PeerID
+	StreamID         StreamID
+	BytesSent        uint64
+	BytesReceived    uint64
+	PacketLoss       float64 // 0-1
+	Jitter           time.Duration
+	RoundTripTime    time.Duration
+	AvailableBitrate int       // kbps, the subscriber's latest REMB estimate; 0 until one arrives
+	UpdatedAt        time.Time // Time of the last RTCP snapshot, zero before the first one
+}
+
 type StreamMetrics struct {
 	StreamID          StreamID
 	ActivePublishers  int
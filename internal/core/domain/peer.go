@@ -1,11 +1,15 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Peer struct {
 	ID           PeerID
 	SessionID    SessionID
 	StreamID     StreamID
+	UserID       UserID // Authenticated user that joined the peer
 	Address      string
 	Capabilities PeerCapabilities
 	Connections  []PeerConnection
@@ -18,6 +22,7 @@ type PeerCapabilities struct {
 	SupportedCodecs []string
 	IsPublisher     bool
 	CanRelay        bool
+	IsObserver      bool // Receives events/stats only: no media, never a relay
 }
 
 type PeerMetrics struct {
@@ -43,3 +48,30 @@ const (
 	DirectionInbound  ConnectionDirection = "inbound"
 	DirectionOutbound ConnectionDirection = "outbound"
 )
+
+// PeerFilter selects a stream's peers by capability. Zero-valued fields match any peer.
+type PeerFilter struct {
+	CanRelay     *bool
+	Codec        string
+	MinBandwidth int // kbps
+	Limit        int // Max peers returned, 0 = no limit
+}
+
+// Matches reports whether the peer satisfies every criterion set on the filter
+func (f PeerFilter) Matches(peer *Peer) bool {
+	if f.CanRelay != nil && peer.Capabilities.CanRelay != *f.CanRelay {
+		return false
+	}
+	if f.MinBandwidth > 0 && peer.Metrics.Bandwidth < f.MinBandwidth {
+		return false
+	}
+	if f.Codec != "" {
+		for _, codec := range peer.Capabilities.SupportedCodecs {
+			if strings.EqualFold(codec, f.Codec) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
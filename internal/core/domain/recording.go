@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+type RecordingID string
+
+// Recording is a completed recording file of a stream
+type Recording struct {
+	ID        RecordingID `json:"id"`
+	StreamID  StreamID    `json:"stream_id"`
+	FileName  string      `json:"file_name"` // Relative to the stream's recording directory
+	StartedAt time.Time   `json:"started_at"`
+	EndedAt   time.Time   `json:"ended_at"`
+	SizeBytes int64       `json:"size_bytes"`
+}
+
+// Duration returns how long the recording covers
+func (r *Recording) Duration() time.Duration {
+	return r.EndedAt.Sub(r.StartedAt)
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,44 @@ type Stream struct {
 	MaxPeers      int
 	QualityLevels []StreamQuality
 	Permissions   []StreamPermission // User permissions for this stream
+
+	MaxTotalBitrate int // Subscriber egress budget in kbps (0 = unlimited)
+	// Priority protects the stream under contention: higher-priority streams
+	// keep their bandwidth while lower ones degrade first (0-MaxStreamPriority)
+	Priority int
+}
+
+// MaxStreamPriority is the highest stream priority; streams default to 0
+const MaxStreamPriority = 10
+
+// StreamWeight is a stream's share weight under contention. Streams of equal
+// priority share equally.
+func StreamWeight(priority int) int {
+	return priority + 1
+}
+
+// StreamListOptions selects a page of active streams
+type StreamListOptions struct {
+	Limit        int    // Max streams returned, 0 = no limit
+	Offset       int    // Matching streams skipped before the page starts
+	NameContains string // Case-insensitive name filter, empty matches every stream
+}
+
+// Matches reports whether the stream passes the name filter
+func (o StreamListOptions) Matches(stream *Stream) bool {
+	return o.NameContains == "" || strings.Contains(strings.ToLower(stream.Name), strings.ToLower(o.NameContains))
+}
+
+// Page cuts the page selected by Offset and Limit out of matched streams
+func (o StreamListOptions) Page(matched []*Stream) []*Stream {
+	if o.Offset >= len(matched) {
+		return []*Stream{}
+	}
+	end := len(matched)
+	if o.Limit > 0 && o.Offset+o.Limit < end {
+		end = o.Offset + o.Limit
+	}
+	return matched[o.Offset:end]
 }
 
 type StreamQuality struct {
@@ -28,3 +67,12 @@ type StreamQuality struct {
 	Height  int
 	Codec   string
 }
+
+// StreamState describes a stream's lifecycle as seen by subscribers.
+type StreamState string
+
+const (
+	StreamStateNotStarted StreamState = "not_started" // stream exists, no publisher yet
+	StreamStateLive       StreamState = "live"        // a publisher is present
+	StreamStateEnded      StreamState = "ended"       // stream was stopped
+)
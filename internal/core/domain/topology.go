@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// TopologyEdge is a directed media connection between two peers
+type TopologyEdge struct {
+	From PeerID `json:"from"`
+	To   PeerID `json:"to"`
+}
+
+// TopologySnapshot is a point-in-time view of a stream's mesh.
+// Nodes and edges are sorted so snapshots serialize deterministically.
+type TopologySnapshot struct {
+	StreamID StreamID       `json:"stream_id"`
+	TakenAt  time.Time      `json:"taken_at"`
+	Nodes    []PeerID       `json:"nodes"`
+	Edges    []TopologyEdge `json:"edges"`
+}
+
+// TopologyDiff lists what changed between two topology snapshots
+type TopologyDiff struct {
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	AddedNodes   []PeerID       `json:"added_nodes"`
+	RemovedNodes []PeerID       `json:"removed_nodes"`
+	AddedEdges   []TopologyEdge `json:"added_edges"`
+	RemovedEdges []TopologyEdge `json:"removed_edges"`
+}
+
+// Empty reports whether the diff contains no changes
+func (d TopologyDiff) Empty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0
+}
+
+// SortTopology orders nodes and edges in place
+func SortTopology(snapshot *TopologySnapshot) {
+	sort.Slice(snapshot.Nodes, func(i, j int) bool { return snapshot.Nodes[i] < snapshot.Nodes[j] })
+	sortEdges(snapshot.Edges)
+}
+
+// DiffTopology returns the nodes and edges added and removed going from a to b
+func DiffTopology(a, b *TopologySnapshot) TopologyDiff {
+	diff := TopologyDiff{
+		From:         a.TakenAt,
+		To:           b.TakenAt,
+		AddedNodes:   []PeerID{},
+		RemovedNodes: []PeerID{},
+		AddedEdges:   []TopologyEdge{},
+		RemovedEdges: []TopologyEdge{},
+	}
+
+	oldNodes := make(map[PeerID]bool, len(a.Nodes))
+	for _, node := range a.Nodes {
+		oldNodes[node] = true
+	}
+	newNodes := make(map[PeerID]bool, len(b.Nodes))
+	for _, node := range b.Nodes {
+		newNodes[node] = true
+		if !oldNodes[node] {
+			diff.AddedNodes = append(diff.AddedNodes, node)
+		}
+	}
+	for _, node := range a.Nodes {
+		if !newNodes[node] {
+			diff.RemovedNodes = append(diff.RemovedNodes, node)
+		}
+	}
+
+	oldEdges := make(map[TopologyEdge]bool, len(a.Edges))
+	for _, edge := range a.Edges {
+		oldEdges[edge] = true
+	}
+	newEdges := make(map[TopologyEdge]bool, len(b.Edges))
+	for _, edge := range b.Edges {
+		newEdges[edge] = true
+		if !oldEdges[edge] {
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+		}
+	}
+	for _, edge := range a.Edges {
+		if !newEdges[edge] {
+			diff.RemovedEdges = append(diff.RemovedEdges, edge)
+		}
+	}
+
+	sort.Slice(diff.AddedNodes, func(i, j int) bool { return diff.AddedNodes[i] < diff.AddedNodes[j] })
+	sort.Slice(diff.RemovedNodes, func(i, j int) bool { return diff.RemovedNodes[i] < diff.RemovedNodes[j] })
+	sortEdges(diff.AddedEdges)
+	sortEdges(diff.RemovedEdges)
+	return diff
+}
+
+func sortEdges(edges []TopologyEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
@@ -17,8 +17,29 @@ const (
 	RoleOwner     UserRole = "owner"
 	RoleViewer    UserRole = "viewer"
 	RoleModerator UserRole = "moderator"
+	// RoleOperator is a global role (auth.user_roles) for the admin endpoints
+	RoleOperator UserRole = "operator"
 )
 
+// streamRoleRanks orders the roles a user can hold in a stream
+var streamRoleRanks = map[UserRole]int{
+	RoleViewer:    1,
+	RoleModerator: 2, // Kicks peers and changes stream settings, but cannot stop the stream
+	RoleOwner:     3,
+}
+
+// Includes reports whether a user holding role r in a stream may do what
+// requires role required: owner > moderator > viewer. Roles outside that
+// ordering include nothing and are included by nothing.
+func (r UserRole) Includes(required UserRole) bool {
+	rank, ok := streamRoleRanks[r]
+	if !ok {
+		return false
+	}
+	requiredRank, ok := streamRoleRanks[required]
+	return ok && rank >= requiredRank
+}
+
 type StreamPermission struct {
 	StreamID  StreamID
 	UserID    UserID
@@ -21,3 +21,25 @@ type RefreshTokenRepository interface {
 	RevokeAllForUser(ctx context.Context, userID domain.UserID, revokedAt time.Time) error
 }
 
+
+// RefreshTokenRecord identifies the login a stored refresh token descends from
+type RefreshTokenRecord struct {
+	UserID   domain.UserID
+	FamilyID string
+}
+
+// RefreshTokenFamilyStore keeps refresh tokens grouped into families, one per
+// login, so that a token presented after it was rotated can be detected and
+// every token of its family revoked
+type RefreshTokenFamilyStore interface {
+	// StartFamily stores the first token of a new family
+	StartFamily(ctx context.Context, record RefreshTokenRecord, tokenHash string, expiresAt time.Time) error
+	// Rotate marks tokenHash, of the family familyID, used and stores nextHash
+	// in the same family, as one step. A token already used fails with
+	// domain.ErrRefreshTokenReused and still returns its record; an unknown or
+	// expired token, one outside familyID, or one of a revoked family, fails
+	// with domain.ErrRefreshTokenRevoked.
+	Rotate(ctx context.Context, familyID, tokenHash, nextHash string, expiresAt time.Time) (RefreshTokenRecord, error)
+	// RevokeFamily invalidates every token of the family
+	RevokeFamily(ctx context.Context, familyID string) error
+}
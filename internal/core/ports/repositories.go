@@ -2,28 +2,54 @@ package ports
 
 import (
 	"context"
+	"io"
 
 	"rillnet/internal/core/domain"
 )
 
 type StreamRepository interface {
 	Create(ctx context.Context, stream *domain.Stream) error
+	// CreateWithOwnerLimit creates the stream unless its owner already has
+	// limit active streams, failing with domain.ErrStreamQuotaExceeded. The
+	// count and the insert are one atomic step.
+	CreateWithOwnerLimit(ctx context.Context, stream *domain.Stream, limit int) error
 	GetByID(ctx context.Context, id domain.StreamID) (*domain.Stream, error)
 	Update(ctx context.Context, stream *domain.Stream) error
 	Delete(ctx context.Context, id domain.StreamID) error
 	ListActive(ctx context.Context) ([]*domain.Stream, error)
+	// ListActivePage returns one page of the active streams matching opts and
+	// how many active streams match in total
+	ListActivePage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error)
+	ListByOwner(ctx context.Context, owner domain.UserID) ([]*domain.Stream, error)
 }
 
 type PeerRepository interface {
+	// Add stores a peer. A peer already stored under another stream fails
+	// with domain.ErrPeerInOtherStream, checked atomically with the write.
 	Add(ctx context.Context, peer *domain.Peer) error
 	GetByID(ctx context.Context, id domain.PeerID) (*domain.Peer, error)
 	Remove(ctx context.Context, id domain.PeerID) error
 	FindByStream(ctx context.Context, streamID domain.StreamID) ([]*domain.Peer, error)
+	// FindByFilter returns up to filter.Limit of the stream's peers matching
+	// filter, in peer ID order
+	FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error)
 	FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error)
 	UpdateMetrics(ctx context.Context, peerID domain.PeerID, metrics domain.NetworkMetrics) error
 	UpdatePeerLoad(ctx context.Context, peerID domain.PeerID, load int) error
 }
 
+// PeerHeartbeater is implemented by peer repositories whose entries expire
+// unless the peer's connection keeps refreshing them
+type PeerHeartbeater interface {
+	Heartbeat(ctx context.Context, peerID domain.PeerID) error
+}
+
+// ActiveStreamLister lists the active streams, the part of StreamRepository
+// the mesh rebalancer needs
+type ActiveStreamLister interface {
+	ListActive(ctx context.Context) ([]*domain.Stream, error)
+}
+
 type MeshRepository interface {
 	AddConnection(ctx context.Context, conn *domain.PeerConnection) error
 	RemoveConnection(ctx context.Context, fromPeer, toPeer domain.PeerID) error
@@ -31,3 +57,11 @@ type MeshRepository interface {
 	BuildMesh(ctx context.Context, streamID domain.StreamID, maxConnections int) error
 	GetOptimalPath(ctx context.Context, sourcePeer, targetPeer domain.PeerID) ([]domain.PeerID, error)
 }
+
+// RecordingIndex tracks completed recording files per stream
+type RecordingIndex interface {
+	Add(ctx context.Context, recording *domain.Recording) error
+	ListByStream(ctx context.Context, streamID domain.StreamID) ([]*domain.Recording, error)
+	// Open returns the recording and its content; the caller closes the reader
+	Open(ctx context.Context, streamID domain.StreamID, id domain.RecordingID) (*domain.Recording, io.ReadSeekCloser, error)
+}
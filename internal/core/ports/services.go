@@ -2,8 +2,10 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"rillnet/internal/core/domain"
+	"rillnet/pkg/circuitbreaker"
 
 	"github.com/pion/webrtc/v3"
 )
@@ -15,6 +17,15 @@ type StreamService interface {
 	LeaveStream(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error
 	GetStreamStats(ctx context.Context, streamID domain.StreamID) (*domain.StreamMetrics, error)
 	ListStreams(ctx context.Context) ([]*domain.Stream, error)
+	// ListStreamsPage returns one page of the active streams matching opts
+	// and how many match in total
+	ListStreamsPage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error)
+	// FindPeers returns the stream's peers that match filter
+	FindPeers(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error)
+	// GetPeer returns a peer that joined the stream; peers of other streams are not found
+	GetPeer(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) (*domain.Peer, error)
+	// StopStream ends the stream and disconnects all of its peers
+	StopStream(ctx context.Context, streamID domain.StreamID) error
 }
 
 type MeshService interface {
@@ -27,6 +38,15 @@ type MeshService interface {
 	AddConnection(ctx context.Context, conn *domain.PeerConnection) error
 	RemoveConnection(ctx context.Context, fromPeer, toPeer domain.PeerID) error
 	GetOptimalPath(ctx context.Context, sourcePeer, targetPeer domain.PeerID) ([]domain.PeerID, error)
+	SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error)
+	// RecordConnectionResult reports whether targetPeer managed to connect to sourcePeer
+	RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error
+}
+
+// MeshScoringTuner adjusts the weights used to score mesh source candidates
+type MeshScoringTuner interface {
+	SetScoringWeights(latency, bandwidth, reliability float64) error
+	GetScoringWeights() (latency, bandwidth, reliability float64)
 }
 
 type WebRTCService interface {
@@ -35,9 +55,167 @@ type WebRTCService interface {
 	HandlePublisherAnswer(ctx context.Context, peerID domain.PeerID, answer webrtc.SessionDescription) error
 	CreateSubscriberOffer(ctx context.Context, peerID domain.PeerID, streamID domain.StreamID, sourcePeers []domain.PeerID) (webrtc.SessionDescription, error)
 	HandleSubscriberAnswer(ctx context.Context, peerID domain.PeerID, answer webrtc.SessionDescription) error
+	AddICECandidate(ctx context.Context, peerID domain.PeerID, candidate webrtc.ICECandidateInit) error
 	SwitchSubscriberQuality(ctx context.Context, peerID domain.PeerID, quality string) error
+	SetPublisherPaused(streamID domain.StreamID, peerID domain.PeerID, paused bool) error
+	RotateKeys(ctx context.Context, peerID domain.PeerID) (webrtc.SessionDescription, error)
+	PendingRenegotiation(peerID domain.PeerID) (webrtc.SessionDescription, bool)
 	HasActiveMedia(ctx context.Context, streamID domain.StreamID) bool
 	GetStreamWebRTCStatus(ctx context.Context, streamID domain.StreamID) StreamWebRTCStatus
+	CreatePreconnect(ctx context.Context, userID domain.UserID, peerID domain.PeerID) (*Preconnect, error)
+	BindPreconnect(ctx context.Context, handle string, peerID domain.PeerID, streamID domain.StreamID, answer webrtc.SessionDescription) error
+	GetPeerStats(peerID domain.PeerID) (*domain.PeerRTCStats, error)
+}
+
+// ICECandidateSink delivers SFU-gathered ICE candidates to a peer (trickle ICE).
+// A nil candidate signals end-of-candidates.
+type ICECandidateSink interface {
+	SendICECandidate(peerID domain.PeerID, candidate *webrtc.ICECandidateInit) error
+}
+
+// PeerRemovalObserver is told whenever a peer is removed from the mesh,
+// whichever path removed it (leave, disconnect, eviction or stream stop).
+type PeerRemovalObserver interface {
+	PeerRemoved(streamID domain.StreamID, peerID domain.PeerID)
+}
+
+// PeerLeftNotifier tells the remaining peers of a stream that a peer left,
+// e.g. when the SFU drops it after an ICE failure.
+type PeerLeftNotifier interface {
+	NotifyPeerLeft(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error
+}
+
+// StreamStopNotifier tells a stream's signaling peers that it was stopped
+type StreamStopNotifier interface {
+	NotifyStreamStopped(ctx context.Context, streamID domain.StreamID) error
+}
+
+// StreamResetNotifier tells a stream's signaling peers that its media plane
+// was reset and they should join again
+type StreamResetNotifier interface {
+	NotifyStreamReset(ctx context.Context, streamID domain.StreamID) error
+}
+
+// PeerKickNotifier tells a kicked peer, through whichever signal instance it
+// is connected to, that it was removed from the stream and must disconnect
+type PeerKickNotifier interface {
+	NotifyPeerKicked(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error
+}
+
+// LayersNotifier tells a stream's subscribers which simulcast layers a
+// publisher is currently producing, lowest first; empty when none
+type LayersNotifier interface {
+	NotifyLayersAvailable(ctx context.Context, streamID domain.StreamID, publisherID domain.PeerID, layers []string) error
+}
+
+// PublisherPauser pauses or resumes forwarding of a stream publisher's media.
+// An unknown publisher, or one of another stream, yields domain.ErrPeerNotFound.
+type PublisherPauser interface {
+	SetPublisherPaused(streamID domain.StreamID, peerID domain.PeerID, paused bool) error
+}
+
+// PeerStatsProvider reports the RTCP-derived stats kept for a connected peer
+type PeerStatsProvider interface {
+	GetPeerStats(peerID domain.PeerID) (*domain.PeerRTCStats, error)
+}
+
+// SubscriberMonitor adapts a subscriber's quality while it is connected
+type SubscriberMonitor interface {
+	StartMonitoring(ctx context.Context, peerID domain.PeerID, initialQuality string)
+	StopMonitoring(peerID domain.PeerID)
+}
+
+// SubscriberInterestSetter limits the video forwarded to a subscriber to the
+// tracks it is displaying; audio is always forwarded.
+type SubscriberInterestSetter interface {
+	SetSubscriberInterest(ctx context.Context, peerID domain.PeerID, activeTracks []string) error
+}
+
+// SubscriptionUpdater adds and removes a subscriber's forwarded tracks with a
+// single renegotiation offer, answered like any other subscriber offer
+type SubscriptionUpdater interface {
+	UpdateSubscription(ctx context.Context, peerID domain.PeerID, add, remove []domain.TrackID) (webrtc.SessionDescription, error)
+}
+
+// StreamCloser closes every media connection of a stream, e.g. when it is stopped
+type StreamCloser interface {
+	CloseStream(ctx context.Context, streamID domain.StreamID) error
+}
+
+// StreamMediaResetter closes every media connection of a stream like
+// StreamCloser, but keeps the stream's media settings for the peers that
+// join again after a reset
+type StreamMediaResetter interface {
+	ResetStreamMedia(ctx context.Context, streamID domain.StreamID) error
+}
+
+// PeerDisconnector closes one peer's media connections, e.g. when it is
+// kicked; domain.ErrPeerNotFound when it has none
+type PeerDisconnector interface {
+	DisconnectPeer(ctx context.Context, peerID domain.PeerID) error
+}
+
+// StreamEndRecorder records that a stream has ended in the metrics backend
+type StreamEndRecorder interface {
+	RecordStreamEnded(streamID domain.StreamID)
+}
+
+// Metrics records service events in a metrics backend (implemented by
+// monitoring.PrometheusCollector, and by monitoring.NopMetrics for tests).
+// The stream service records stream lifetimes and the SFU peer sessions.
+type Metrics interface {
+	StreamEndRecorder
+	RecordStreamCreated(streamID domain.StreamID)
+	RecordPeerConnected(streamID domain.StreamID, isPublisher bool)
+	RecordPeerDisconnected(streamID domain.StreamID, isPublisher bool)
+	RecordServerDataTransferred(bytes int64)
+	RecordWebRTCConnection(duration time.Duration)
+	RecordNetworkLatency(latency time.Duration)
+	RecordViewerSession(streamID domain.StreamID, duration time.Duration)
+}
+
+// CircuitBreakerMetrics records the state of circuit breakers. scope names
+// what a breaker guards; peerID is empty for breakers not tied to a peer.
+type CircuitBreakerMetrics interface {
+	RecordCircuitBreakerState(scope string, peerID domain.PeerID, state circuitbreaker.State)
+	RecordCircuitBreakerTrip(scope string)
+}
+
+// KeyframePolicy controls whether a subscriber joining a stream triggers a
+// keyframe request to the publisher
+type KeyframePolicy interface {
+	SetKeyframeOnJoin(streamID domain.StreamID, enabled bool)
+	KeyframeOnJoin(streamID domain.StreamID) bool
+}
+
+// SVCLayerPolicy limits the SVC temporal layers forwarded for a stream,
+// trading frame rate for bandwidth. A negative layer forwards all of them.
+type SVCLayerPolicy interface {
+	SetSVCTemporalLayer(streamID domain.StreamID, tid int) error
+	SVCTemporalLayer(streamID domain.StreamID) int
+}
+
+// StreamPrioritySetter sets the priority a stream is served with under
+// resource contention (domain.Stream.Priority)
+type StreamPrioritySetter interface {
+	SetStreamPriority(ctx context.Context, streamID domain.StreamID, priority int) error
+}
+
+// StreamResetter restarts a stream's media plane, e.g. after its publisher
+// crashed: every peer is evicted and its connections closed, while the
+// stream record is kept for the peers to join again
+type StreamResetter interface {
+	ResetStream(ctx context.Context, streamID domain.StreamID) error
+}
+
+// PeerKicker removes a peer from a stream on a moderator's request
+type PeerKicker interface {
+	KickPeer(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error
+}
+
+// ICEServerUpdater replaces the ICE servers used for new peer connections
+type ICEServerUpdater interface {
+	UpdateICEServers(servers []webrtc.ICEServer)
 }
 
 // StreamWebRTCStatus describes SFU-side WebRTC state for a stream (in-memory, single ingest).
@@ -48,3 +226,12 @@ type StreamWebRTCStatus struct {
 	PublisherICEState   string `json:"publisher_ice_state,omitempty"`
 	PublisherConnState  string `json:"publisher_connection_state,omitempty"`
 }
+
+// Preconnect is a subscriber PeerConnection opened before the client picks a
+// stream. The handle binds it to a stream until ExpiresAt.
+type Preconnect struct {
+	Handle    string                    `json:"handle"`
+	PeerID    domain.PeerID             `json:"peer_id"`
+	Offer     webrtc.SessionDescription `json:"offer"`
+	ExpiresAt time.Time                 `json:"expires_at"`
+}
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/ports"
+	"rillnet/pkg/config"
 	"go.uber.org/zap"
 )
 
@@ -16,19 +19,44 @@ type AdaptiveBitrateService struct {
 	meshService    ports.MeshService
 	logger         *zap.SugaredLogger
 
+	// Supplies the measured network stats quality is decided from; optional
+	statsProvider ports.PeerStatsProvider
+	// Applies each committed quality change, e.g. by switching the SFU's
+	// simulcast layer; optional
+	onQualityChange QualityChangeFunc
+
 	// Per-peer quality state
 	peerQuality     map[domain.PeerID]string
 	peerQualityMu   sync.RWMutex
 	lastQualityTime map[domain.PeerID]time.Time
-	qualityHistory  map[domain.PeerID][]qualitySnapshot
+	qualityHistory  map[domain.PeerID][]QualitySnapshot
+	pendingQuality  map[domain.PeerID]string // Recommendation awaiting confirmation
+	pendingCount    map[domain.PeerID]int    // Consecutive checks recommending pendingQuality
+
+	// Monitor goroutine lifecycle, guarded by peerQualityMu
+	monitors map[domain.PeerID]*peerMonitor
+	closed   bool
+	wg       sync.WaitGroup
 
 	// Configuration
 	checkInterval    time.Duration
+	maxCheckBackoff  time.Duration // Upper bound for check interval after transient failures
 	minTimeBetweenSwitches time.Duration
 	hysteresisFactor float64 // Prevents rapid switching
+	requiredConsecutiveChecks int // Checks a new quality must win in a row before switching
 }
 
-type qualitySnapshot struct {
+// peerMonitor cancels one monitorPeer goroutine
+type peerMonitor struct {
+	cancel context.CancelFunc
+}
+
+// MaxQualityHistory is how many quality switches are kept per peer
+const MaxQualityHistory = 100
+
+// QualitySnapshot is a committed quality switch and the measured metrics
+// that triggered it
+type QualitySnapshot struct {
 	Quality   string
 	Timestamp time.Time
 	Metrics   domain.NetworkMetrics
@@ -46,53 +74,162 @@ func NewAdaptiveBitrateService(
 		logger:                logger,
 		peerQuality:           make(map[domain.PeerID]string),
 		lastQualityTime:       make(map[domain.PeerID]time.Time),
-		qualityHistory:        make(map[domain.PeerID][]qualitySnapshot),
+		qualityHistory:        make(map[domain.PeerID][]QualitySnapshot),
+		pendingQuality:        make(map[domain.PeerID]string),
+		pendingCount:          make(map[domain.PeerID]int),
+		monitors:              make(map[domain.PeerID]*peerMonitor),
 		checkInterval:         5 * time.Second,
+		maxCheckBackoff:       time.Minute,
 		minTimeBetweenSwitches: 10 * time.Second,
 		hysteresisFactor:      0.15, // 15% hysteresis to prevent oscillation
+		requiredConsecutiveChecks: 3,
 	}
 }
 
-// StartMonitoring starts monitoring a peer's metrics and automatically adjusts quality
+// NewAdaptiveBitrateServiceWithConfig creates an adaptive bitrate service tuned from config
+func NewAdaptiveBitrateServiceWithConfig(
+	qualityService *QualityService,
+	meshService ports.MeshService,
+	cfg config.AdaptiveBitrateConfig,
+	logger *zap.SugaredLogger,
+) *AdaptiveBitrateService {
+	a := NewAdaptiveBitrateService(qualityService, meshService, logger)
+	a.SetCheckInterval(cfg.CheckInterval)
+	a.SetMinTimeBetweenSwitches(cfg.MinTimeBetweenSwitches)
+	a.SetHysteresisFactor(cfg.HysteresisFactor)
+	a.SetRequiredConsecutiveChecks(cfg.RequiredConsecutiveChecks)
+	a.maxCheckBackoff = cfg.MaxCheckBackoff
+	return a
+}
+
+// StartMonitoring starts monitoring a peer's metrics and automatically adjusts quality.
+// The monitor runs until ctx is done, StopMonitoring is called, or the service is closed.
 func (a *AdaptiveBitrateService) StartMonitoring(ctx context.Context, peerID domain.PeerID, initialQuality string) {
 	a.peerQualityMu.Lock()
+	defer a.peerQualityMu.Unlock()
+
+	if a.closed {
+		return
+	}
+
+	// Restarting replaces any existing monitor for the peer
+	if existing, ok := a.monitors[peerID]; ok {
+		existing.cancel()
+	}
+
+	monitorCtx, cancel := context.WithCancel(ctx)
+	monitor := &peerMonitor{cancel: cancel}
+	a.monitors[peerID] = monitor
+
 	a.peerQuality[peerID] = initialQuality
 	a.lastQualityTime[peerID] = time.Now()
-	a.qualityHistory[peerID] = []qualitySnapshot{}
-	a.peerQualityMu.Unlock()
+	a.qualityHistory[peerID] = []QualitySnapshot{}
 
-	go a.monitorPeer(ctx, peerID)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.monitorPeer(monitorCtx, peerID, monitor)
+	}()
 }
 
 // StopMonitoring stops monitoring a peer
 func (a *AdaptiveBitrateService) StopMonitoring(peerID domain.PeerID) {
 	a.peerQualityMu.Lock()
+	defer a.peerQualityMu.Unlock()
+	a.stopMonitoringLocked(peerID)
+}
+
+func (a *AdaptiveBitrateService) stopMonitoringLocked(peerID domain.PeerID) {
+	if monitor, ok := a.monitors[peerID]; ok {
+		monitor.cancel()
+		delete(a.monitors, peerID)
+	}
 	delete(a.peerQuality, peerID)
 	delete(a.lastQualityTime, peerID)
 	delete(a.qualityHistory, peerID)
+	delete(a.pendingQuality, peerID)
+	delete(a.pendingCount, peerID)
+}
+
+// ActiveMonitors returns how many peers are being monitored
+func (a *AdaptiveBitrateService) ActiveMonitors() int {
+	a.peerQualityMu.RLock()
+	defer a.peerQualityMu.RUnlock()
+	return len(a.monitors)
+}
+
+// Close stops all peer monitors and waits for their goroutines to exit.
+// Further StartMonitoring calls are ignored. Safe to call more than once.
+func (a *AdaptiveBitrateService) Close() {
+	a.peerQualityMu.Lock()
+	a.closed = true
+	for peerID := range a.monitors {
+		a.stopMonitoringLocked(peerID)
+	}
 	a.peerQualityMu.Unlock()
+
+	a.wg.Wait()
 }
 
-// monitorPeer continuously monitors a peer's metrics and adjusts quality
-func (a *AdaptiveBitrateService) monitorPeer(ctx context.Context, peerID domain.PeerID) {
-	ticker := time.NewTicker(a.checkInterval)
+// monitorPeer continuously monitors a peer's metrics and adjusts quality.
+// Monitoring stops once the peer is gone; transient failures back off the
+// check interval until a check succeeds again.
+func (a *AdaptiveBitrateService) monitorPeer(ctx context.Context, peerID domain.PeerID, monitor *peerMonitor) {
+	interval := a.checkInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	failures := 0
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := a.checkAndAdjustQuality(ctx, peerID); err != nil {
+			err := a.checkAndAdjustQuality(ctx, peerID)
+			if errors.Is(err, domain.ErrPeerNotFound) {
+				a.logger.Infow("peer gone, stopping quality monitoring", "peer_id", peerID)
+				a.peerQualityMu.Lock()
+				if a.monitors[peerID] == monitor {
+					a.stopMonitoringLocked(peerID)
+				}
+				a.peerQualityMu.Unlock()
+				return
+			}
+
+			next := a.checkInterval
+			if err != nil {
+				failures++
+				next = a.checkBackoff(failures)
 				a.logger.Warnw("error checking quality for peer",
 					"peer_id", peerID,
+					"consecutive_failures", failures,
+					"next_check_in", next,
 					"error", err,
 				)
+			} else {
+				failures = 0
+			}
+
+			if next != interval {
+				interval = next
+				ticker.Reset(interval)
 			}
 		}
 	}
 }
 
+// checkBackoff doubles the check interval per consecutive failure, capped at maxCheckBackoff
+func (a *AdaptiveBitrateService) checkBackoff(failures int) time.Duration {
+	backoff := a.checkInterval
+	for i := 0; i < failures && backoff < a.maxCheckBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > a.maxCheckBackoff {
+		backoff = a.maxCheckBackoff
+	}
+	return backoff
+}
+
 // checkAndAdjustQuality checks current metrics and adjusts quality if needed
 func (a *AdaptiveBitrateService) checkAndAdjustQuality(ctx context.Context, peerID domain.PeerID) error {
 	// Get current peer connections to verify peer exists
@@ -101,10 +238,6 @@ func (a *AdaptiveBitrateService) checkAndAdjustQuality(ctx context.Context, peer
 		return err
 	}
 
-	// For now, we'll get metrics from the peer repository
-	// In a full implementation, we'd get real-time metrics from RTCP
-	// This is a placeholder - actual implementation would get metrics from WebRTC stats
-	
 	// Get current quality
 	a.peerQualityMu.RLock()
 	currentQuality := a.peerQuality[peerID]
@@ -116,23 +249,15 @@ func (a *AdaptiveBitrateService) checkAndAdjustQuality(ctx context.Context, peer
 		return nil
 	}
 
-	// Get network metrics (this would come from RTCP in real implementation)
-	// For now, we'll use a simplified approach
-	metrics := domain.NetworkMetrics{
-		Timestamp: time.Now(),
-		// These would be populated from actual RTCP stats
-		BandwidthDown:    1000, // Placeholder
-		BandwidthUp:      500,  // Placeholder
-		PacketLoss:       0.02,
-		Latency:          150 * time.Millisecond,
-		Jitter:           40 * time.Millisecond,
-		AvailableBitrate: 800,
+	metrics, ok, err := a.measuredMetrics(peerID)
+	if err != nil || !ok {
+		return err
 	}
 
 	// Determine optimal quality with hysteresis
 	newQuality := a.determineQualityWithHysteresis(currentQuality, metrics)
 
-	if newQuality != currentQuality {
+	if a.confirmQualityChange(peerID, currentQuality, newQuality) {
 		a.logger.Infow("quality switch triggered",
 			"peer_id", peerID,
 			"from", currentQuality,
@@ -148,26 +273,100 @@ func (a *AdaptiveBitrateService) checkAndAdjustQuality(ctx context.Context, peer
 		a.lastQualityTime[peerID] = time.Now()
 		
 		// Record in history
-		a.qualityHistory[peerID] = append(a.qualityHistory[peerID], qualitySnapshot{
+		a.qualityHistory[peerID] = append(a.qualityHistory[peerID], QualitySnapshot{
 			Quality:   newQuality,
 			Timestamp: time.Now(),
 			Metrics:   metrics,
 		})
 		
-		// Keep only last 100 snapshots
-		if len(a.qualityHistory[peerID]) > 100 {
-			a.qualityHistory[peerID] = a.qualityHistory[peerID][len(a.qualityHistory[peerID])-100:]
+		// Keep only the last MaxQualityHistory snapshots
+		if len(a.qualityHistory[peerID]) > MaxQualityHistory {
+			a.qualityHistory[peerID] = a.qualityHistory[peerID][len(a.qualityHistory[peerID])-MaxQualityHistory:]
 		}
 		a.peerQualityMu.Unlock()
 
-		// Notify about quality change (this would trigger simulcast layer switch)
-		// The actual implementation would call SFU to switch simulcast layers
+		// Peers that are not SFU subscribers have no layers to switch
+		if a.onQualityChange != nil {
+			err := a.onQualityChange(ctx, peerID, currentQuality, newQuality)
+			if err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+				return fmt.Errorf("apply quality change to %s: %w", newQuality, err)
+			}
+		}
 		return nil
 	}
 
 	return nil
 }
 
+// measuredMetrics converts the peer's latest SFU stats into network metrics.
+// ok is false while there is nothing to decide from: no stats provider, no
+// RTCP yet, or no bandwidth estimate from the subscriber.
+func (a *AdaptiveBitrateService) measuredMetrics(peerID domain.PeerID) (domain.NetworkMetrics, bool, error) {
+	if a.statsProvider == nil {
+		return domain.NetworkMetrics{}, false, nil
+	}
+	stats, err := a.statsProvider.GetPeerStats(peerID)
+	if err != nil {
+		return domain.NetworkMetrics{}, false, err
+	}
+	if stats.UpdatedAt.IsZero() || stats.AvailableBitrate <= 0 {
+		return domain.NetworkMetrics{}, false, nil
+	}
+
+	// A subscriber only sends feedback upstream, so its estimate bounds both directions
+	return domain.NetworkMetrics{
+		Timestamp:        stats.UpdatedAt,
+		BandwidthDown:    stats.AvailableBitrate,
+		BandwidthUp:      stats.AvailableBitrate,
+		PacketLoss:       stats.PacketLoss,
+		Latency:          stats.RoundTripTime,
+		Jitter:           stats.Jitter,
+		AvailableBitrate: stats.AvailableBitrate,
+	}, true, nil
+}
+
+// confirmQualityChange reports whether a recommended quality has been seen on
+// enough consecutive checks to commit the switch. A differing recommendation
+// restarts the count.
+func (a *AdaptiveBitrateService) confirmQualityChange(peerID domain.PeerID, currentQuality, recommended string) bool {
+	a.peerQualityMu.Lock()
+	defer a.peerQualityMu.Unlock()
+
+	if recommended == currentQuality {
+		delete(a.pendingQuality, peerID)
+		delete(a.pendingCount, peerID)
+		return false
+	}
+
+	if a.pendingQuality[peerID] == recommended {
+		a.pendingCount[peerID]++
+	} else {
+		a.pendingQuality[peerID] = recommended
+		a.pendingCount[peerID] = 1
+	}
+
+	if a.pendingCount[peerID] < a.requiredConsecutiveChecks {
+		return false
+	}
+
+	delete(a.pendingQuality, peerID)
+	delete(a.pendingCount, peerID)
+	return true
+}
+
+// qualityLevels lists the quality names from lowest to highest
+var qualityLevels = []string{"low", "medium", "high"}
+
+// qualityRank returns a quality's position in qualityLevels, or -1
+func qualityRank(quality string) int {
+	for i, q := range qualityLevels {
+		if q == quality {
+			return i
+		}
+	}
+	return -1
+}
+
 // determineQualityWithHysteresis determines quality with hysteresis to prevent oscillation
 func (a *AdaptiveBitrateService) determineQualityWithHysteresis(currentQuality string, metrics domain.NetworkMetrics) string {
 	// Get optimal quality without hysteresis
@@ -179,7 +378,7 @@ func (a *AdaptiveBitrateService) determineQualityWithHysteresis(currentQuality s
 	}
 
 		// Apply hysteresis: be more conservative when downgrading, more aggressive when upgrading
-		if optimalQuality < currentQuality {
+		if qualityRank(optimalQuality) < qualityRank(currentQuality) {
 			// Downgrading: use stricter thresholds (with hysteresis)
 			thresholds := a.qualityService.GetThresholds()
 			threshold := thresholds[currentQuality]
@@ -223,16 +422,34 @@ func (a *AdaptiveBitrateService) GetCurrentQuality(peerID domain.PeerID) string
 	return a.peerQuality[peerID]
 }
 
-// GetQualityHistory returns quality change history for a peer
-func (a *AdaptiveBitrateService) GetQualityHistory(peerID domain.PeerID) []qualitySnapshot {
+// GetQualityHistory returns quality change history for a peer, oldest first
+func (a *AdaptiveBitrateService) GetQualityHistory(peerID domain.PeerID) []QualitySnapshot {
 	a.peerQualityMu.RLock()
 	defer a.peerQualityMu.RUnlock()
 	
-	history := make([]qualitySnapshot, len(a.qualityHistory[peerID]))
+	history := make([]QualitySnapshot, len(a.qualityHistory[peerID]))
 	copy(history, a.qualityHistory[peerID])
 	return history
 }
 
+// QualityChangeFunc applies a peer's committed switch from one quality to
+// another. domain.ErrPeerNotFound means the peer has nothing to switch, such
+// as a peer that is not an SFU subscriber; other errors fail the check.
+type QualityChangeFunc func(ctx context.Context, peerID domain.PeerID, from, to string) error
+
+// SetOnQualityChange sets the function applying each committed quality
+// switch, e.g. to the SFU's simulcast layers. Without one, quality is only
+// tracked. Must be called before monitoring starts.
+func (a *AdaptiveBitrateService) SetOnQualityChange(fn QualityChangeFunc) {
+	a.onQualityChange = fn
+}
+
+// SetStatsProvider sets where the measured stats of monitored peers come
+// from. Without one, monitored peers keep their initial quality.
+func (a *AdaptiveBitrateService) SetStatsProvider(provider ports.PeerStatsProvider) {
+	a.statsProvider = provider
+}
+
 // SetCheckInterval sets the interval for quality checks
 func (a *AdaptiveBitrateService) SetCheckInterval(interval time.Duration) {
 	a.checkInterval = interval
@@ -243,6 +460,15 @@ func (a *AdaptiveBitrateService) SetMinTimeBetweenSwitches(duration time.Duratio
 	a.minTimeBetweenSwitches = duration
 }
 
+// SetRequiredConsecutiveChecks sets how many consecutive checks must recommend
+// the same new quality before switching (minimum 1)
+func (a *AdaptiveBitrateService) SetRequiredConsecutiveChecks(n int) {
+	if n < 1 {
+		n = 1
+	}
+	a.requiredConsecutiveChecks = n
+}
+
 // SetHysteresisFactor sets the hysteresis factor (0.0-1.0)
 func (a *AdaptiveBitrateService) SetHysteresisFactor(factor float64) {
 	if factor < 0 {
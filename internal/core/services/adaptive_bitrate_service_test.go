@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestAdaptiveBitrateService_ConfirmQualityChange(t *testing.T) {
+	abr := NewAdaptiveBitrateService(NewQualityService(), nil, zaptest.NewLogger(t).Sugar())
+	abr.SetRequiredConsecutiveChecks(3)
+	peerID := domain.PeerID("peer-1")
+
+	// A one-off recommendation followed by a return to the current quality must not switch
+	if abr.confirmQualityChange(peerID, "high", "low") {
+		t.Fatal("single recommendation should not switch quality")
+	}
+	if abr.confirmQualityChange(peerID, "high", "high") {
+		t.Fatal("matching recommendation should never switch quality")
+	}
+	if abr.confirmQualityChange(peerID, "high", "low") {
+		t.Fatal("counter should have been reset by the intervening recommendation")
+	}
+
+	// A different recommendation restarts the count
+	if abr.confirmQualityChange(peerID, "high", "medium") {
+		t.Fatal("changed recommendation should restart the count")
+	}
+	if abr.confirmQualityChange(peerID, "high", "medium") {
+		t.Fatal("two consecutive recommendations should not switch with N=3")
+	}
+	if !abr.confirmQualityChange(peerID, "high", "medium") {
+		t.Fatal("three consecutive recommendations should switch")
+	}
+
+	// Count starts over after a committed switch
+	if abr.confirmQualityChange(peerID, "medium", "low") {
+		t.Fatal("count should start over after a switch")
+	}
+}
+
+func TestAdaptiveBitrateService_SetRequiredConsecutiveChecksClamps(t *testing.T) {
+	abr := NewAdaptiveBitrateService(NewQualityService(), nil, zaptest.NewLogger(t).Sugar())
+	abr.SetRequiredConsecutiveChecks(0)
+
+	if !abr.confirmQualityChange("peer-1", "high", "low") {
+		t.Fatal("with N clamped to 1 a single recommendation should switch")
+	}
+}
+
+// scriptedMeshService returns the scripted GetPeerConnections errors in order,
+// repeating the last one once the script runs out.
+type scriptedMeshService struct {
+	ports.MeshService
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (m *scriptedMeshService) GetPeerConnections(ctx context.Context, peerID domain.PeerID) ([]*domain.PeerConnection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.errs[min(m.calls, len(m.errs)-1)]
+	m.calls++
+	return nil, err
+}
+
+func (m *scriptedMeshService) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+func TestAdaptiveBitrateService_MonitoringSurvivesTransientErrors(t *testing.T) {
+	mesh := &scriptedMeshService{errs: []error{
+		errors.New("redis: connection reset"),
+		nil,
+		domain.ErrPeerNotFound,
+	}}
+	abr := NewAdaptiveBitrateService(NewQualityService(), mesh, zaptest.NewLogger(t).Sugar())
+	abr.SetCheckInterval(5 * time.Millisecond)
+	abr.SetMinTimeBetweenSwitches(time.Hour)
+	abr.maxCheckBackoff = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peerID := domain.PeerID("peer-1")
+	abr.StartMonitoring(ctx, peerID, "medium")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for abr.GetCurrentQuality(peerID) != "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("monitoring did not stop after peer was gone (calls=%d)", mesh.callCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The transient error must not have ended monitoring early
+	calls := mesh.callCount()
+	if calls != 3 {
+		t.Fatalf("expected 3 checks (transient, ok, not found), got %d", calls)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if mesh.callCount() != calls {
+		t.Fatal("monitoring loop kept running after the peer was gone")
+	}
+}
+
+func TestAdaptiveBitrateService_MonitoringStopsWhenPeerLeavesMesh(t *testing.T) {
+	peerRepo := memory.NewMemoryPeerRepository()
+	mesh := NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, config.MeshConfig{}, zap.NewNop().Sugar())
+	abr := NewAdaptiveBitrateService(NewQualityService(), mesh, zaptest.NewLogger(t).Sugar())
+	abr.SetCheckInterval(5 * time.Millisecond)
+	abr.SetMinTimeBetweenSwitches(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peer := &domain.Peer{ID: "leaving-peer", StreamID: "stream-1"}
+	if err := peerRepo.Add(ctx, peer); err != nil {
+		t.Fatal(err)
+	}
+	abr.StartMonitoring(ctx, peer.ID, "medium")
+
+	time.Sleep(20 * time.Millisecond)
+	if abr.GetCurrentQuality(peer.ID) == "" {
+		t.Fatal("monitoring stopped while the peer was still in the mesh")
+	}
+
+	if err := mesh.RemovePeer(ctx, peer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for abr.GetCurrentQuality(peer.ID) != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("monitoring did not stop after the peer left the mesh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// staticStats serves fixed SFU stats for every peer
+type staticStats struct {
+	stats domain.PeerRTCStats
+}
+
+func (p *staticStats) GetPeerStats(peerID domain.PeerID) (*domain.PeerRTCStats, error) {
+	stats := p.stats
+	return &stats, nil
+}
+
+// recordingSwitcher records the layers the service switches subscribers to
+type recordingSwitcher struct {
+	layers []string
+}
+
+func (s *recordingSwitcher) switchLayer(ctx context.Context, peerID domain.PeerID, from, to string) error {
+	s.layers = append(s.layers, to)
+	return nil
+}
+
+func TestAdaptiveBitrateService_SwitchesFromMeasuredStats(t *testing.T) {
+	ctx := context.Background()
+	mesh := &scriptedMeshService{errs: []error{nil}}
+	abr := NewAdaptiveBitrateService(NewQualityService(), mesh, zaptest.NewLogger(t).Sugar())
+	abr.SetMinTimeBetweenSwitches(0)
+	abr.SetRequiredConsecutiveChecks(1)
+	switcher := &recordingSwitcher{}
+	abr.SetOnQualityChange(switcher.switchLayer)
+
+	peerID := domain.PeerID("subscriber-1")
+	abr.peerQuality[peerID] = "high"
+
+	// Without stats there is nothing to decide from
+	if err := abr.checkAndAdjustQuality(ctx, peerID); err != nil {
+		t.Fatal(err)
+	}
+	if len(switcher.layers) != 0 {
+		t.Fatalf("switched without measured stats: %v", switcher.layers)
+	}
+
+	stats := &staticStats{stats: domain.PeerRTCStats{
+		PacketLoss:       0.2,
+		RoundTripTime:    50 * time.Millisecond,
+		AvailableBitrate: 300,
+		UpdatedAt:        time.Now(),
+	}}
+	abr.SetStatsProvider(stats)
+	if err := abr.checkAndAdjustQuality(ctx, peerID); err != nil {
+		t.Fatal(err)
+	}
+	if len(switcher.layers) != 1 || switcher.layers[0] != "low" {
+		t.Fatalf("expected a switch to low on a lossy 300 kbps link, got %v", switcher.layers)
+	}
+	if got := abr.GetCurrentQuality(peerID); got != "low" {
+		t.Fatalf("expected current quality low, got %q", got)
+	}
+}
+
+func TestAdaptiveBitrateService_OnQualityChange(t *testing.T) {
+	ctx := context.Background()
+	mesh := &scriptedMeshService{errs: []error{nil}}
+	abr := NewAdaptiveBitrateService(NewQualityService(), mesh, zaptest.NewLogger(t).Sugar())
+	abr.SetMinTimeBetweenSwitches(0)
+	abr.SetRequiredConsecutiveChecks(1)
+
+	var changes []string
+	abr.SetOnQualityChange(func(ctx context.Context, peerID domain.PeerID, from, to string) error {
+		changes = append(changes, string(peerID)+":"+from+"->"+to)
+		return nil
+	})
+
+	peerID := domain.PeerID("subscriber-1")
+	abr.peerQuality[peerID] = "high"
+	stats := &staticStats{stats: domain.PeerRTCStats{
+		PacketLoss:       0.2,
+		RoundTripTime:    50 * time.Millisecond,
+		AvailableBitrate: 300,
+		UpdatedAt:        time.Now(),
+	}}
+	abr.SetStatsProvider(stats)
+
+	if err := abr.checkAndAdjustQuality(ctx, peerID); err != nil {
+		t.Fatal(err)
+	}
+	// An unchanged quality is not reported
+	if err := abr.checkAndAdjustQuality(ctx, peerID); err != nil {
+		t.Fatal(err)
+	}
+
+	stats.stats = domain.PeerRTCStats{
+		RoundTripTime:    20 * time.Millisecond,
+		AvailableBitrate: 5000,
+		UpdatedAt:        time.Now(),
+	}
+	if err := abr.checkAndAdjustQuality(ctx, peerID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"subscriber-1:high->low", "subscriber-1:low->high"}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Fatalf("quality changes = %v, want %v", changes, want)
+	}
+}
+
+func TestAdaptiveBitrateService_CheckBackoff(t *testing.T) {
+	abr := NewAdaptiveBitrateService(NewQualityService(), nil, zaptest.NewLogger(t).Sugar())
+	abr.SetCheckInterval(time.Second)
+	abr.maxCheckBackoff = 5 * time.Second
+
+	if got := abr.checkBackoff(1); got != 2*time.Second {
+		t.Fatalf("expected 2s after one failure, got %v", got)
+	}
+	if got := abr.checkBackoff(10); got != 5*time.Second {
+		t.Fatalf("expected backoff capped at 5s, got %v", got)
+	}
+}
+
+func TestNewAdaptiveBitrateServiceWithConfig(t *testing.T) {
+	cfg := config.AdaptiveBitrateConfig{
+		CheckInterval:             2 * time.Second,
+		MinTimeBetweenSwitches:    7 * time.Second,
+		HysteresisFactor:          0.3,
+		RequiredConsecutiveChecks: 5,
+		MaxCheckBackoff:           30 * time.Second,
+	}
+	abr := NewAdaptiveBitrateServiceWithConfig(NewQualityService(), nil, cfg, zaptest.NewLogger(t).Sugar())
+
+	if abr.checkInterval != cfg.CheckInterval {
+		t.Errorf("check interval = %v, want %v", abr.checkInterval, cfg.CheckInterval)
+	}
+	if abr.minTimeBetweenSwitches != cfg.MinTimeBetweenSwitches {
+		t.Errorf("min time between switches = %v, want %v", abr.minTimeBetweenSwitches, cfg.MinTimeBetweenSwitches)
+	}
+	if abr.hysteresisFactor != cfg.HysteresisFactor {
+		t.Errorf("hysteresis factor = %v, want %v", abr.hysteresisFactor, cfg.HysteresisFactor)
+	}
+	if abr.requiredConsecutiveChecks != cfg.RequiredConsecutiveChecks {
+		t.Errorf("required consecutive checks = %d, want %d", abr.requiredConsecutiveChecks, cfg.RequiredConsecutiveChecks)
+	}
+	if abr.maxCheckBackoff != cfg.MaxCheckBackoff {
+		t.Errorf("max check backoff = %v, want %v", abr.maxCheckBackoff, cfg.MaxCheckBackoff)
+	}
+}
+
+func TestAdaptiveBitrateService_CloseDrainsMonitors(t *testing.T) {
+	mesh := &scriptedMeshService{errs: []error{nil}}
+	abr := NewAdaptiveBitrateService(NewQualityService(), mesh, zaptest.NewLogger(t).Sugar())
+	abr.SetCheckInterval(time.Millisecond)
+	abr.SetMinTimeBetweenSwitches(time.Hour)
+
+	peers := []domain.PeerID{"peer-1", "peer-2", "peer-3", "peer-4"}
+	for _, peerID := range peers {
+		abr.StartMonitoring(context.Background(), peerID, "medium")
+	}
+
+	// Stopping a monitor concurrently with Close must be safe
+	go abr.StopMonitoring(peers[0])
+
+	done := make(chan struct{})
+	go func() {
+		abr.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not wait for monitors to exit")
+	}
+
+	calls := mesh.callCount()
+	time.Sleep(20 * time.Millisecond)
+	if mesh.callCount() != calls {
+		t.Fatal("monitors kept running after Close")
+	}
+
+	// Monitoring is not restarted once closed, and a second Close returns immediately
+	abr.StartMonitoring(context.Background(), "peer-5", "medium")
+	if abr.GetCurrentQuality("peer-5") != "" {
+		t.Fatal("StartMonitoring after Close should be a no-op")
+	}
+	abr.Close()
+}
+
+type recordingLayerSwitcher struct {
+	mu       sync.Mutex
+	err      error
+	switches []string
+}
+
+func (s *recordingLayerSwitcher) switchLayer(ctx context.Context, peerID domain.PeerID, from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.switches = append(s.switches, string(peerID)+":"+to)
+	return s.err
+}
+
+func TestAdaptiveBitrateService_QualityChangeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		switchErr error
+		wantErr   bool
+	}{
+		{name: "switched"},
+		{name: "not an SFU subscriber", switchErr: domain.ErrPeerNotFound},
+		{name: "switch failed", switchErr: errors.New("replace track failed"), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mesh := &scriptedMeshService{errs: []error{nil}}
+			abr := NewAdaptiveBitrateService(NewQualityService(), mesh, zaptest.NewLogger(t).Sugar())
+			abr.SetMinTimeBetweenSwitches(0)
+			abr.SetRequiredConsecutiveChecks(1)
+			switcher := &recordingLayerSwitcher{err: tc.switchErr}
+			abr.SetOnQualityChange(switcher.switchLayer)
+			abr.SetStatsProvider(&staticStats{stats: domain.PeerRTCStats{
+				PacketLoss:       0.2,
+				RoundTripTime:    50 * time.Millisecond,
+				AvailableBitrate: 300,
+				UpdatedAt:        time.Now(),
+			}})
+
+			peerID := domain.PeerID("peer-1")
+			abr.peerQuality[peerID] = "high"
+
+			err := abr.checkAndAdjustQuality(context.Background(), peerID)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("checkAndAdjustQuality error = %v, want error %v", err, tc.wantErr)
+			}
+
+			quality := abr.GetCurrentQuality(peerID)
+			if quality == "high" {
+				t.Fatal("expected the lossy link to downgrade from high")
+			}
+			if len(switcher.switches) != 1 || switcher.switches[0] != "peer-1:"+quality {
+				t.Fatalf("switches = %v, want [peer-1:%s]", switcher.switches, quality)
+			}
+		})
+	}
+}
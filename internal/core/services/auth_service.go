@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -18,9 +19,10 @@ import (
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token expired")
-	ErrUnauthorized = errors.New("unauthorized")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrExpiredToken  = errors.New("token expired")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrInvalidAPIKey = errors.New("invalid API key")
 )
 
 type AuthService interface {
@@ -28,17 +30,21 @@ type AuthService interface {
 	GenerateRefreshToken(userID domain.UserID) (string, error)
 	ValidateToken(tokenString string) (*Claims, error)
 	ValidateRefreshToken(tokenString string) (*Claims, error)
+	ValidateAPIKey(key string) (*Claims, error)
 	RegisterUser(ctx context.Context, username, email, password string) (*domain.User, string, string, error) // user, access, refresh
 	LoginUser(ctx context.Context, username, password string) (*domain.User, string, string, error)         // user, access, refresh
 	RotateRefreshToken(ctx context.Context, refreshToken string) (string, string, error)                     // access, refresh
+	RefreshTokens(ctx context.Context, refreshToken string) (access, refresh string, err error)
 	Logout(ctx context.Context, refreshToken string) error
 	CheckStreamPermission(ctx context.Context, userID domain.UserID, streamID domain.StreamID, requiredRole domain.UserRole) error
 	GetUserFromContext(ctx context.Context) (domain.UserID, error)
 }
 
 type Claims struct {
-	UserID   domain.UserID `json:"user_id"`
-	Username string        `json:"username"`
+	UserID   domain.UserID   `json:"user_id"`
+	Username string          `json:"username"`
+	Role     domain.UserRole `json:"role,omitempty"` // Optional global role (used for per-role quotas)
+	FamilyID string          `json:"fid,omitempty"`  // Login a refresh token descends from, when rotation tracks families
 	jwt.RegisteredClaims
 }
 
@@ -49,6 +55,28 @@ type authService struct {
 	streamService    ports.StreamService // Optional, can be nil
 	userRepo         ports.UserRepository
 	refreshRepo      ports.RefreshTokenRepository
+	refreshFamilies  ports.RefreshTokenFamilyStore     // Optional; enables reuse detection in RefreshTokens
+	apiKeys          []APIKey
+	userRoles        map[domain.UserID]domain.UserRole // Global roles stamped into access tokens
+}
+
+// APIKey is a static credential for a server-to-server client. Only the
+// SHA-256 digest of the key is kept.
+type APIKey struct {
+	Hash   [sha256.Size]byte
+	UserID domain.UserID
+	Role   domain.UserRole // Optional; falls back to the user's configured role
+}
+
+// APIKeyHooks is implemented by auth services that accept API keys.
+type APIKeyHooks interface {
+	SetAPIKeys(keys []APIKey)
+}
+
+// RefreshRotationHooks is implemented by auth services that can detect the
+// reuse of rotated refresh tokens.
+type RefreshRotationHooks interface {
+	SetRefreshTokenStore(store ports.RefreshTokenFamilyStore)
 }
 
 func NewAuthService(
@@ -58,6 +86,20 @@ func NewAuthService(
 	streamService ports.StreamService, // Can be nil for token-only validation
 	userRepo ports.UserRepository,
 	refreshRepo ports.RefreshTokenRepository,
+) AuthService {
+	return NewAuthServiceWithRoles(jwtSecret, accessTokenTTL, refreshTokenTTL, streamService, userRepo, refreshRepo, nil)
+}
+
+// NewAuthServiceWithRoles creates an auth service that grants the given
+// users a global role, carried in their access tokens
+func NewAuthServiceWithRoles(
+	jwtSecret string,
+	accessTokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	streamService ports.StreamService,
+	userRepo ports.UserRepository,
+	refreshRepo ports.RefreshTokenRepository,
+	userRoles map[domain.UserID]domain.UserRole,
 ) AuthService {
 	return &authService{
 		jwtSecret:       []byte(jwtSecret),
@@ -66,13 +108,28 @@ func NewAuthService(
 		streamService:   streamService,
 		userRepo:        userRepo,
 		refreshRepo:     refreshRepo,
+		userRoles:       userRoles,
 	}
 }
 
+// SetRefreshTokenStore groups refresh tokens into families so RefreshTokens
+// can revoke a login whose rotated token is replayed. Must be called before
+// tokens are issued.
+func (s *authService) SetRefreshTokenStore(store ports.RefreshTokenFamilyStore) {
+	s.refreshFamilies = store
+}
+
+// SetAPIKeys sets the API keys ValidateAPIKey accepts. Must be called before
+// serving requests.
+func (s *authService) SetAPIKeys(keys []APIKey) {
+	s.apiKeys = keys
+}
+
 func (s *authService) GenerateToken(userID domain.UserID, username string) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     s.userRoles[userID],
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -85,9 +142,17 @@ func (s *authService) GenerateToken(userID domain.UserID, username string) (stri
 }
 
 func (s *authService) GenerateRefreshToken(userID domain.UserID) (string, error) {
+	return s.generateRefreshToken(userID, "")
+}
+
+// generateRefreshToken signs a refresh token of the given family. Each token
+// gets its own ID, so two issued within the same second still differ.
+func (s *authService) generateRefreshToken(userID domain.UserID, familyID string) (string, error) {
 	claims := &Claims{
-		UserID: userID,
+		UserID:   userID,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.refreshTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -139,6 +204,29 @@ func (s *authService) ValidateRefreshToken(tokenString string) (*Claims, error)
 	return claims, nil
 }
 
+// ValidateAPIKey returns the claims of the client the key belongs to. The
+// key's digest is compared with every configured digest in constant time, so
+// the response time reveals neither the key nor which one matched.
+func (s *authService) ValidateAPIKey(key string) (*Claims, error) {
+	digest := sha256.Sum256([]byte(key))
+
+	var match *APIKey
+	for i := range s.apiKeys {
+		if subtle.ConstantTimeCompare(digest[:], s.apiKeys[i].Hash[:]) == 1 && match == nil {
+			match = &s.apiKeys[i]
+		}
+	}
+	if key == "" || match == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	role := match.Role
+	if role == "" {
+		role = s.userRoles[match.UserID]
+	}
+	return &Claims{UserID: match.UserID, Role: role}, nil
+}
+
 func (s *authService) RegisterUser(ctx context.Context, username, email, password string) (*domain.User, string, string, error) {
 	// Backward-compatible stub mode (no persistent auth storage configured).
 	if s.userRepo == nil || s.refreshRepo == nil {
@@ -152,7 +240,7 @@ func (s *authService) RegisterUser(ctx context.Context, username, email, passwor
 		if err != nil {
 			return nil, "", "", err
 		}
-		refresh, err := s.GenerateRefreshToken(user.ID)
+		refresh, err := s.issueAndStoreRefreshToken(ctx, user.ID)
 		if err != nil {
 			return nil, "", "", err
 		}
@@ -197,7 +285,7 @@ func (s *authService) LoginUser(ctx context.Context, username, password string)
 		if err != nil {
 			return nil, "", "", err
 		}
-		refresh, err := s.GenerateRefreshToken(user.ID)
+		refresh, err := s.issueAndStoreRefreshToken(ctx, user.ID)
 		if err != nil {
 			return nil, "", "", err
 		}
@@ -257,21 +345,114 @@ func (s *authService) RotateRefreshToken(ctx context.Context, refreshToken strin
 	return access, newRefresh, nil
 }
 
+// RefreshTokens exchanges a refresh token for a new access and refresh
+// token. With a family store the presented token is used up: presenting it
+// again is taken as theft and revokes every token issued from the same login.
+// Without one it falls back to RotateRefreshToken.
+func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (string, string, error) {
+	if s.refreshFamilies == nil {
+		return s.RotateRefreshToken(ctx, refreshToken)
+	}
+	claims, err := s.ValidateToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.FamilyID == "" {
+		// Issued before rotation tracked families; it cannot be rotated safely
+		return "", "", domain.ErrRefreshTokenRevoked
+	}
+	if err := s.checkRefreshTokenActive(ctx, refreshToken, claims.FamilyID); err != nil {
+		return "", "", err
+	}
+
+	newRefresh, err := s.generateRefreshToken(claims.UserID, claims.FamilyID)
+	if err != nil {
+		return "", "", err
+	}
+	expiresAt := time.Now().Add(s.refreshTokenTTL)
+	record, err := s.refreshFamilies.Rotate(ctx, claims.FamilyID, hashToken(refreshToken), hashToken(newRefresh), expiresAt)
+	if errors.Is(err, domain.ErrRefreshTokenReused) {
+		if revokeErr := s.refreshFamilies.RevokeFamily(ctx, record.FamilyID); revokeErr != nil {
+			return "", "", fmt.Errorf("%w: %v", err, revokeErr)
+		}
+		return "", "", err
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if s.refreshRepo != nil {
+		if err := s.refreshRepo.Store(ctx, record.UserID, hashToken(newRefresh), expiresAt); err != nil {
+			return "", "", err
+		}
+		_ = s.refreshRepo.MarkReplaced(ctx, hashToken(refreshToken), hashToken(newRefresh))
+	}
+
+	access, err := s.GenerateToken(record.UserID, claims.Username)
+	if err != nil {
+		return "", "", err
+	}
+	return access, newRefresh, nil
+}
+
+// checkRefreshTokenActive rejects a refresh token the refresh repository no
+// longer holds active, such as one an operator revoked with the rest of its
+// user's tokens. Its family is revoked too: rotated tokens are inactive there,
+// so this also ends a login whose token is replayed.
+func (s *authService) checkRefreshTokenActive(ctx context.Context, refreshToken, familyID string) error {
+	if s.refreshRepo == nil {
+		return nil
+	}
+	active, err := s.refreshRepo.IsActive(ctx, hashToken(refreshToken), time.Now())
+	if err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+	if err := s.refreshFamilies.RevokeFamily(ctx, familyID); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrRefreshTokenRevoked, err)
+	}
+	return domain.ErrRefreshTokenRevoked
+}
+
 func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	// Logging out ends the whole login, including tokens rotated from this one
+	if s.refreshFamilies != nil {
+		if claims, err := s.ValidateToken(refreshToken); err == nil && claims.FamilyID != "" {
+			if err := s.refreshFamilies.RevokeFamily(ctx, claims.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
 	if s.refreshRepo == nil {
 		return nil
 	}
 	return s.refreshRepo.Revoke(ctx, hashToken(refreshToken), time.Now())
 }
 
+// issueAndStoreRefreshToken creates the first refresh token of a login and
+// records it in whichever stores are configured
 func (s *authService) issueAndStoreRefreshToken(ctx context.Context, userID domain.UserID) (string, error) {
-	refresh, err := s.GenerateRefreshToken(userID)
+	var familyID string
+	if s.refreshFamilies != nil {
+		familyID = uuid.New().String()
+	}
+	refresh, err := s.generateRefreshToken(userID, familyID)
 	if err != nil {
 		return "", err
 	}
 	expiresAt := time.Now().Add(s.refreshTokenTTL)
-	if err := s.refreshRepo.Store(ctx, userID, hashToken(refresh), expiresAt); err != nil {
-		return "", err
+	if s.refreshFamilies != nil {
+		record := ports.RefreshTokenRecord{UserID: userID, FamilyID: familyID}
+		if err := s.refreshFamilies.StartFamily(ctx, record, hashToken(refresh), expiresAt); err != nil {
+			return "", err
+		}
+	}
+	if s.refreshRepo != nil {
+		if err := s.refreshRepo.Store(ctx, userID, hashToken(refresh), expiresAt); err != nil {
+			return "", err
+		}
 	}
 	return refresh, nil
 }
@@ -287,6 +468,10 @@ func utilsGenerateUUID() string {
 	return uuid.New().String()
 }
 
+// CheckStreamPermission checks that the user's role in the stream includes
+// requiredRole. The owner holds RoleOwner, users granted a role in the
+// stream's permissions hold that role, and any other authenticated user is a
+// viewer.
 func (s *authService) CheckStreamPermission(ctx context.Context, userID domain.UserID, streamID domain.StreamID, requiredRole domain.UserRole) error {
 	if s.streamService == nil {
 		// Stream service not available, skip permission check
@@ -297,11 +482,8 @@ func (s *authService) CheckStreamPermission(ctx context.Context, userID domain.U
 	if streamID == "" || streamID == "undefined" || streamID == "null" {
 		return ErrUnauthorized
 	}
-
-	// If user is authenticated (userID is not empty), allow access
-	// This is a temporary fix - in production, you should check actual permissions
-	if userID != "" {
-		return nil
+	if userID == "" {
+		return ErrUnauthorized
 	}
 
 	stream, err := s.streamService.GetStream(ctx, streamID)
@@ -309,45 +491,27 @@ func (s *authService) CheckStreamPermission(ctx context.Context, userID domain.U
 		return err
 	}
 
-	// Owner always has all permissions
-	if stream.OwnerUserID == userID && userID != "" {
-		return nil
+	if !streamRole(stream, userID).Includes(requiredRole) {
+		return ErrUnauthorized
 	}
+	return nil
+}
 
-	// If stream has no OwnerUserID set but user is authenticated, allow access
-	// This handles the case where stream was created before OwnerUserID was properly set
-	if stream.OwnerUserID == "" && userID != "" {
-		return nil
+// streamRole returns the user's role in the stream, the highest when the
+// permissions grant several
+func streamRole(stream *domain.Stream, userID domain.UserID) domain.UserRole {
+	// Streams created before OwnerUserID was recorded have no owner to protect
+	if stream.OwnerUserID == userID || stream.OwnerUserID == "" {
+		return domain.RoleOwner
 	}
 
-	// Check user's role in stream permissions
+	role := domain.RoleViewer
 	for _, perm := range stream.Permissions {
-		if perm.UserID == userID {
-			if s.hasRequiredPermission(perm.Role, requiredRole) {
-				return nil
-			}
+		if perm.UserID == userID && perm.Role.Includes(role) {
+			role = perm.Role
 		}
 	}
-
-	// If user is authenticated, allow access (temporary fix)
-	if userID != "" {
-		return nil
-	}
-
-	return ErrUnauthorized
-}
-
-func (s *authService) hasRequiredPermission(userRole, requiredRole domain.UserRole) bool {
-	roleHierarchy := map[domain.UserRole]int{
-		domain.RoleViewer:    1,
-		domain.RoleModerator: 2,
-		domain.RoleOwner:     3,
-	}
-
-	userLevel := roleHierarchy[userRole]
-	requiredLevel := roleHierarchy[requiredRole]
-
-	return userLevel >= requiredLevel
+	return role
 }
 
 func (s *authService) GetUserFromContext(ctx context.Context) (domain.UserID, error) {
@@ -75,6 +75,33 @@ func (s *CachedStreamService) ListStreams(ctx context.Context) ([]*domain.Stream
 	return value.([]*domain.Stream), nil
 }
 
+// streamPage is a cached ListStreamsPage result
+type streamPage struct {
+	streams []*domain.Stream
+	total   int
+}
+
+// ListStreamsPage lists a page of streams with caching; pages share the
+// "streams:list:" prefix so stream changes invalidate them with the full list
+func (s *CachedStreamService) ListStreamsPage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error) {
+	cacheKey := fmt.Sprintf("streams:list:page:%d:%d:%s", opts.Limit, opts.Offset, opts.NameContains)
+
+	value, err := s.cache.GetOrSet(ctx, cacheKey, func(ctx context.Context) (interface{}, error) {
+		streams, total, err := s.baseService.ListStreamsPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &streamPage{streams: streams, total: total}, nil
+	}, s.streamTTL)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page := value.(*streamPage)
+	return page.streams, page.total, nil
+}
+
 // JoinStream joins a stream and invalidates relevant caches
 func (s *CachedStreamService) JoinStream(ctx context.Context, streamID domain.StreamID, peer *domain.Peer) error {
 	err := s.baseService.JoinStream(ctx, streamID, peer)
@@ -121,6 +148,30 @@ func (s *CachedStreamService) GetStreamStats(ctx context.Context, streamID domai
 	return value.(*domain.StreamMetrics), nil
 }
 
+// StopStream stops the stream and invalidates its cached entries
+func (s *CachedStreamService) StopStream(ctx context.Context, streamID domain.StreamID) error {
+	if err := s.baseService.StopStream(ctx, streamID); err != nil {
+		return err
+	}
+
+	s.cache.Invalidate(fmt.Sprintf("stream:%s", streamID))
+	s.cache.Invalidate(fmt.Sprintf("stream:%s:peers", streamID))
+	s.cache.Invalidate(fmt.Sprintf("stream:%s:stats", streamID))
+	s.cache.Invalidate("streams:list:")
+
+	return nil
+}
+
+// FindPeers delegates to the base service; filtered results are not cached
+func (s *CachedStreamService) FindPeers(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	return s.baseService.FindPeers(ctx, streamID, filter)
+}
+
+// GetPeer delegates to the base service; peers change too often to cache
+func (s *CachedStreamService) GetPeer(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) (*domain.Peer, error) {
+	return s.baseService.GetPeer(ctx, streamID, peerID)
+}
+
 // Stop stops the cache cleanup
 func (s *CachedStreamService) Stop() {
 	s.cache.Stop()
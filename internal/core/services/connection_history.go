@@ -0,0 +1,103 @@
+package services
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"rillnet/internal/core/domain"
+)
+
+const (
+	// connectionHistoryHalfLife is how long it takes a recorded outcome to lose
+	// half its weight, so a pair that failed behind a NAT gets retried eventually
+	connectionHistoryHalfLife = 5 * time.Minute
+	// connectionFailurePenalty outweighs the publisher bonus: a source the target
+	// just failed to reach should lose to any healthy one
+	connectionFailurePenalty = 40.0
+	connectionSuccessBonus   = 10.0
+	// Outcomes that decayed below this are dropped
+	connectionHistoryEpsilon = 0.01
+)
+
+type peerPair struct {
+	source domain.PeerID
+	target domain.PeerID
+}
+
+type connectionOutcome struct {
+	value     float64 // In [-1, 1]: -1 after failures, +1 after successes
+	updatedAt time.Time
+}
+
+// connectionHistory remembers recent connection outcomes between peer pairs
+type connectionHistory struct {
+	mu       sync.Mutex
+	outcomes map[peerPair]connectionOutcome
+	now      func() time.Time
+}
+
+func newConnectionHistory() *connectionHistory {
+	return &connectionHistory{
+		outcomes: make(map[peerPair]connectionOutcome),
+		now:      time.Now,
+	}
+}
+
+// decayed returns the outcome's value as of now
+func (o connectionOutcome) decayed(now time.Time) float64 {
+	elapsed := now.Sub(o.updatedAt)
+	if elapsed <= 0 {
+		return o.value
+	}
+	return o.value * math.Pow(0.5, float64(elapsed)/float64(connectionHistoryHalfLife))
+}
+
+// record adds an outcome on top of the decayed history of the pair
+func (h *connectionHistory) record(source, target domain.PeerID, connected bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	key := peerPair{source: source, target: target}
+	value := h.outcomes[key].decayed(now)
+	if connected {
+		value = math.Min(value+1, 1)
+	} else {
+		value = math.Max(value-1, -1)
+	}
+	h.outcomes[key] = connectionOutcome{value: value, updatedAt: now}
+}
+
+// scoreAdjustment returns the bonus (or penalty, when negative) for choosing
+// source as a source for target
+func (h *connectionHistory) scoreAdjustment(source, target domain.PeerID) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := peerPair{source: source, target: target}
+	outcome, ok := h.outcomes[key]
+	if !ok {
+		return 0
+	}
+	value := outcome.decayed(h.now())
+	if math.Abs(value) < connectionHistoryEpsilon {
+		delete(h.outcomes, key)
+		return 0
+	}
+	if value < 0 {
+		return value * connectionFailurePenalty
+	}
+	return value * connectionSuccessBonus
+}
+
+// forget drops every outcome involving a peer that left
+func (h *connectionHistory) forget(peerID domain.PeerID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key := range h.outcomes {
+		if key.source == peerID || key.target == peerID {
+			delete(h.outcomes, key)
+		}
+	}
+}
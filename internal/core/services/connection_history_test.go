@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionHistory_OutcomesDecay(t *testing.T) {
+	now := time.Now()
+	history := newConnectionHistory()
+	history.now = func() time.Time { return now }
+
+	history.record("source", "target", false)
+	if got := history.scoreAdjustment("source", "target"); got != -connectionFailurePenalty {
+		t.Fatalf("fresh failure adjustment = %v, want %v", got, -connectionFailurePenalty)
+	}
+	if got := history.scoreAdjustment("target", "source"); got != 0 {
+		t.Fatalf("reverse direction adjustment = %v, want 0", got)
+	}
+
+	now = now.Add(connectionHistoryHalfLife)
+	if got := history.scoreAdjustment("source", "target"); got != -connectionFailurePenalty/2 {
+		t.Fatalf("adjustment after one half-life = %v, want %v", got, -connectionFailurePenalty/2)
+	}
+
+	// A success offsets the decayed failure instead of erasing it
+	history.record("source", "target", true)
+	if got := history.scoreAdjustment("source", "target"); got != 0.5*connectionSuccessBonus {
+		t.Fatalf("adjustment after success = %v, want %v", got, 0.5*connectionSuccessBonus)
+	}
+
+	now = now.Add(20 * connectionHistoryHalfLife)
+	if got := history.scoreAdjustment("source", "target"); got != 0 {
+		t.Fatalf("adjustment after long decay = %v, want 0", got)
+	}
+	if len(history.outcomes) != 0 {
+		t.Fatalf("expected fully decayed outcome to be dropped, have %d", len(history.outcomes))
+	}
+}
+
+func TestConnectionHistory_Forget(t *testing.T) {
+	history := newConnectionHistory()
+	history.record("a", "b", false)
+	history.record("c", "a", true)
+	history.record("c", "b", true)
+
+	history.forget("a")
+	if len(history.outcomes) != 1 {
+		t.Fatalf("expected only the c->b outcome to remain, have %d", len(history.outcomes))
+	}
+}
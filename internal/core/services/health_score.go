@@ -0,0 +1,34 @@
+package services
+
+import (
+	"time"
+
+	"rillnet/pkg/config"
+)
+
+// calculateHealthScore computes a stream's 0-100 health score using the
+// weights and strategy in cfg (see config.HealthScoreConfig for the formula).
+func calculateHealthScore(cfg config.HealthScoreConfig, publishers, subscribers, bitrate int, latency time.Duration) float64 {
+	if cfg.Strategy == config.HealthStrategyPublisherGated && publishers == 0 {
+		return 0
+	}
+
+	publisherScore := float64(publishers) * cfg.PublisherWeight
+	subscriberScore := float64(subscribers) * cfg.SubscriberWeight
+	bitrateScore := float64(bitrate) * cfg.BitrateWeight
+
+	latencyScore := 0.0
+	if latency < 100*time.Millisecond {
+		latencyScore = cfg.LatencyWeight
+	} else if latency < 300*time.Millisecond {
+		latencyScore = cfg.LatencyWeight * 2 / 3
+	} else if latency < 500*time.Millisecond {
+		latencyScore = cfg.LatencyWeight / 3
+	}
+
+	totalScore := publisherScore + subscriberScore + bitrateScore + latencyScore
+	if totalScore > 100.0 {
+		return 100.0
+	}
+	return totalScore
+}
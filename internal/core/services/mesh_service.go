@@ -1,10 +1,13 @@
 package services
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"math"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"rillnet/internal/core/domain"
@@ -18,18 +21,33 @@ type meshService struct {
 	meshRepo ports.MeshRepository
 	config   config.MeshConfig
 	logger   *zap.SugaredLogger
+
+	// Recent connection outcomes between peer pairs, used in scoring
+	history *connectionHistory
+	// Guards the scoring weights in config, which can change at runtime
+	weightsMu sync.RWMutex
 	
+	// Told about every removed peer, optional
+	removalObserver ports.PeerRemovalObserver
+
 	// Rebalancing state
+	streams         ports.ActiveStreamLister // nil disables periodic rebalancing
 	rebalanceTicker *time.Ticker
 	rebalanceStop   chan struct{}
+	rebalancing     atomic.Bool // Set while a rebalance cycle runs
 }
 
-func NewMeshService(peerRepo ports.PeerRepository, meshRepo ports.MeshRepository, cfg config.MeshConfig, logger *zap.SugaredLogger) ports.MeshService {
+// NewMeshService creates the mesh service. streams lists the streams the
+// periodic rebalance walks; with nil streams the mesh is only rebuilt as
+// peers come and go.
+func NewMeshService(peerRepo ports.PeerRepository, meshRepo ports.MeshRepository, streams ports.ActiveStreamLister, cfg config.MeshConfig, logger *zap.SugaredLogger) ports.MeshService {
 	ms := &meshService{
 		peerRepo: peerRepo,
 		meshRepo: meshRepo,
+		streams:  streams,
 		config:   cfg,
 		logger:   logger,
+		history:  newConnectionHistory(),
 		rebalanceStop: make(chan struct{}),
 	}
 
@@ -42,26 +60,64 @@ func NewMeshService(peerRepo ports.PeerRepository, meshRepo ports.MeshRepository
 	return ms
 }
 
+// PeerRemovalHooks is implemented by mesh services that report every peer
+// they remove.
+type PeerRemovalHooks interface {
+	SetRemovalObserver(observer ports.PeerRemovalObserver)
+}
+
+// SetRemovalObserver sets who is told about removed peers. Must be called
+// before peers join.
+func (m *meshService) SetRemovalObserver(observer ports.PeerRemovalObserver) {
+	m.removalObserver = observer
+}
+
 // rebalanceLoop periodically rebalances the mesh network
 func (m *meshService) rebalanceLoop() {
 	for {
 		select {
 		case <-m.rebalanceTicker.C:
-			m.rebalanceAllStreams()
+			// Run off the loop so Stop is never held up by a slow cycle
+			go m.rebalanceAllStreams()
 		case <-m.rebalanceStop:
 			return
 		}
 	}
 }
 
-// rebalanceAllStreams rebalances all active streams
+// rebalanceAllStreams rebalances all active streams. A cycle that is still
+// running when the next tick fires makes that tick a no-op.
 func (m *meshService) rebalanceAllStreams() {
-	// This would need access to stream repository to get all streams
-	// For now, we'll implement per-stream rebalancing when called
-	m.logger.Debug("mesh rebalancing triggered")
+	if m.streams == nil {
+		return
+	}
+	if !m.rebalancing.CompareAndSwap(false, true) {
+		m.logger.Debug("mesh rebalancing skipped, previous cycle still running")
+		return
+	}
+	defer m.rebalancing.Store(false)
+
+	ctx := context.Background()
+	streams, err := m.streams.ListActive(ctx)
+	if err != nil {
+		m.logger.Warnw("failed to list streams for mesh rebalancing", "error", err)
+		return
+	}
+
+	for _, stream := range streams {
+		if err := m.rebalanceStream(ctx, stream.ID); err != nil {
+			m.logger.Warnw("failed to rebalance stream mesh",
+				"stream_id", stream.ID,
+				"error", err,
+			)
+		}
+	}
+	m.logger.Debugw("mesh rebalancing completed", "streams", len(streams))
 }
 
 func (m *meshService) AddPeer(ctx context.Context, peer *domain.Peer) error {
+	// Scoring and cleanup are per stream, so a peer may not span two
+	// streams; the repository rejects that atomically with the insert
 	if err := m.peerRepo.Add(ctx, peer); err != nil {
 		return err
 	}
@@ -110,6 +166,10 @@ func (m *meshService) RemovePeer(ctx context.Context, peerID domain.PeerID) erro
 	if err := m.peerRepo.Remove(ctx, peerID); err != nil {
 		return err
 	}
+	m.history.forget(peerID)
+	if m.removalObserver != nil {
+		m.removalObserver.PeerRemoved(streamID, peerID)
+	}
 
 	// Rebalance mesh after peer removal
 	go func() {
@@ -157,12 +217,21 @@ func (m *meshService) FindOptimalSources(ctx context.Context, streamID domain.St
 		}
 	}
 
+	// Distance of every peer from a publisher, for the relay depth cap
+	var depths map[domain.PeerID]int
+	if m.config.MaxHopDepth > 0 {
+		depths = m.hopDepths(ctx, allPeers, targetPeer)
+	}
+
 	// Exclude target peer and unsuitable candidates
 	var candidates []*scoredPeer
 	for _, peer := range allPeers {
 		if peer.ID == targetPeer {
 			continue
 		}
+		if peer.Capabilities.IsObserver {
+			continue
+		}
 		if !peer.Capabilities.IsPublisher && !peer.Capabilities.CanRelay {
 			continue
 		}
@@ -170,6 +239,18 @@ func (m *meshService) FindOptimalSources(ctx context.Context, streamID domain.St
 			continue
 		}
 
+		// Skip relays that would push the target past the hop depth cap. A
+		// relay not fed by any publisher has no known depth and nothing to
+		// forward yet, so it is never offered.
+		atDepthLimit := false
+		if depths != nil {
+			depth, placed := depths[peer.ID]
+			if !placed || depth+1 > m.config.MaxHopDepth {
+				continue
+			}
+			atDepthLimit = depth+1 == m.config.MaxHopDepth
+		}
+
 		// Check if peer already has max connections
 		peerConnections, err := m.meshRepo.GetConnections(ctx, peer.ID)
 		if err == nil && len(peerConnections) >= m.config.MaxConnectionsPerPeer {
@@ -179,8 +260,9 @@ func (m *meshService) FindOptimalSources(ctx context.Context, streamID domain.St
 		// Calculate score for this candidate
 		score := m.calculatePeerScore(peer, targetPeerData)
 		candidates = append(candidates, &scoredPeer{
-			Peer:  peer,
-			Score: score,
+			Peer:         peer,
+			Score:        score,
+			AtDepthLimit: atDepthLimit,
 		})
 	}
 
@@ -188,8 +270,12 @@ func (m *meshService) FindOptimalSources(ctx context.Context, streamID domain.St
 		return nil, domain.ErrPeerNotFound
 	}
 
-	// Sort by score (descending)
+	// Sort by score (descending), keeping sources that leave the target room
+	// to relay further ahead of those that would put it at the depth cap
 	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].AtDepthLimit != candidates[j].AtDepthLimit {
+			return !candidates[i].AtDepthLimit
+		}
 		return candidates[i].Score > candidates[j].Score
 	})
 
@@ -208,13 +294,15 @@ func (m *meshService) FindOptimalSources(ctx context.Context, streamID domain.St
 }
 
 type scoredPeer struct {
-	Peer  *domain.Peer
-	Score float64
+	Peer         *domain.Peer
+	Score        float64
+	AtDepthLimit bool // Sourcing from this peer puts the target at MaxHopDepth
 }
 
 // calculatePeerScore calculates a comprehensive score for a peer using weighted metrics
 func (m *meshService) calculatePeerScore(peer *domain.Peer, targetPeer *domain.Peer) float64 {
 	score := 0.0
+	latencyWeight, bandwidthWeight, reliabilityWeight := m.GetScoringWeights()
 
 	// Latency component (lower is better, normalized)
 	latencyScore := 1.0
@@ -227,7 +315,7 @@ func (m *meshService) calculatePeerScore(peer *domain.Peer, targetPeer *domain.P
 			latencyScore = 0.0
 		}
 	}
-	score += latencyScore * m.config.LatencyWeight * 100.0
+	score += latencyScore * latencyWeight * 100.0
 
 	// Bandwidth component (higher is better, normalized)
 	bandwidthScore := 0.0
@@ -236,14 +324,14 @@ func (m *meshService) calculatePeerScore(peer *domain.Peer, targetPeer *domain.P
 		maxBandwidth := 10000.0
 		bandwidthScore = math.Min(float64(peer.Metrics.Bandwidth)/maxBandwidth, 1.0)
 	}
-	score += bandwidthScore * m.config.BandwidthWeight * 100.0
+	score += bandwidthScore * bandwidthWeight * 100.0
 
 	// Reliability component (lower packet loss = higher score)
 	reliabilityScore := 1.0 - peer.Metrics.PacketLoss
 	if reliabilityScore < 0 {
 		reliabilityScore = 0
 	}
-	score += reliabilityScore * m.config.ReliabilityWeight * 100.0
+	score += reliabilityScore * reliabilityWeight * 100.0
 
 	// Publisher bonus
 	if peer.Capabilities.IsPublisher {
@@ -262,9 +350,59 @@ func (m *meshService) calculatePeerScore(peer *domain.Peer, targetPeer *domain.P
 		score -= 5.0
 	}
 
+	// Prefer sources the target recently reached, avoid ones it could not
+	if targetPeer != nil {
+		score += m.history.scoreAdjustment(peer.ID, targetPeer.ID)
+	}
+
 	return score
 }
 
+// SetScoringWeights replaces the latency, bandwidth and reliability weights
+// used to score candidate peers. The weights are validated like the mesh
+// config: each in [0,1] and at least one positive.
+func (m *meshService) SetScoringWeights(latency, bandwidth, reliability float64) error {
+	weights := config.MeshConfig{
+		LatencyWeight:     latency,
+		BandwidthWeight:   bandwidth,
+		ReliabilityWeight: reliability,
+	}
+	if err := weights.ValidateWeights(); err != nil {
+		return err
+	}
+
+	m.weightsMu.Lock()
+	m.config.LatencyWeight = latency
+	m.config.BandwidthWeight = bandwidth
+	m.config.ReliabilityWeight = reliability
+	m.weightsMu.Unlock()
+
+	m.logger.Infow("mesh scoring weights updated",
+		"latency_weight", latency,
+		"bandwidth_weight", bandwidth,
+		"reliability_weight", reliability,
+	)
+	return nil
+}
+
+// ApplyConfig adopts the scoring weights of a reloaded config
+func (m *meshService) ApplyConfig(cfg *config.Config) {
+	latency, bandwidth, reliability := m.GetScoringWeights()
+	if latency == cfg.Mesh.LatencyWeight && bandwidth == cfg.Mesh.BandwidthWeight && reliability == cfg.Mesh.ReliabilityWeight {
+		return
+	}
+	if err := m.SetScoringWeights(cfg.Mesh.LatencyWeight, cfg.Mesh.BandwidthWeight, cfg.Mesh.ReliabilityWeight); err != nil {
+		m.logger.Warnw("reloaded mesh scoring weights rejected", "error", err)
+	}
+}
+
+// GetScoringWeights returns the current latency, bandwidth and reliability weights
+func (m *meshService) GetScoringWeights() (latency, bandwidth, reliability float64) {
+	m.weightsMu.RLock()
+	defer m.weightsMu.RUnlock()
+	return m.config.LatencyWeight, m.config.BandwidthWeight, m.config.ReliabilityWeight
+}
+
 // BuildOptimalMesh builds an optimized mesh network for a stream
 func (m *meshService) BuildOptimalMesh(ctx context.Context, streamID domain.StreamID) error {
 	peers, err := m.peerRepo.FindByStream(ctx, streamID)
@@ -276,10 +414,13 @@ func (m *meshService) BuildOptimalMesh(ctx context.Context, streamID domain.Stre
 		return nil
 	}
 
-	// Separate publishers and subscribers
+	// Separate publishers and subscribers; observers take no part in the mesh
 	var publishers []*domain.Peer
 	var subscribers []*domain.Peer
 	for _, peer := range peers {
+		if peer.Capabilities.IsObserver {
+			continue
+		}
 		if peer.Capabilities.IsPublisher {
 			publishers = append(publishers, peer)
 		} else {
@@ -425,6 +566,9 @@ func (m *meshService) optimizeSubscriberConnections(ctx context.Context, streamI
 			if connectedSet[peer.ID] {
 				continue
 			}
+			if peer.Capabilities.IsObserver {
+				continue
+			}
 			if !peer.Capabilities.IsPublisher && !peer.Capabilities.CanRelay {
 				continue
 			}
@@ -481,7 +625,12 @@ func (m *meshService) rebalanceStream(ctx context.Context, streamID domain.Strea
 }
 
 // Additional methods for mesh network operations
+// GetPeerConnections returns a peer's mesh connections, or
+// domain.ErrPeerNotFound once the peer has left
 func (m *meshService) GetPeerConnections(ctx context.Context, peerID domain.PeerID) ([]*domain.PeerConnection, error) {
+	if _, err := m.peerRepo.GetByID(ctx, peerID); err != nil {
+		return nil, err
+	}
 	return m.meshRepo.GetConnections(ctx, peerID)
 }
 
@@ -493,7 +642,9 @@ func (m *meshService) RemoveConnection(ctx context.Context, fromPeer, toPeer dom
 	return m.meshRepo.RemoveConnection(ctx, fromPeer, toPeer)
 }
 
-// GetOptimalPath finds the optimal path between two peers using BFS
+// GetOptimalPath finds the lowest-cost path between two peers with Dijkstra.
+// Entering a peer costs the inverse of its quality score, so the path avoids
+// slow or lossy relays even when that takes more hops.
 func (m *meshService) GetOptimalPath(ctx context.Context, sourcePeer, targetPeer domain.PeerID) ([]domain.PeerID, error) {
 	if sourcePeer == targetPeer {
 		return []domain.PeerID{sourcePeer}, nil
@@ -513,10 +664,10 @@ func (m *meshService) GetOptimalPath(ctx context.Context, sourcePeer, targetPeer
 
 	// Build adjacency list from connections
 	graph := make(map[domain.PeerID][]domain.PeerID)
-	peerSet := make(map[domain.PeerID]bool)
+	peersByID := make(map[domain.PeerID]*domain.Peer)
 
 	for _, peer := range streamPeers {
-		peerSet[peer.ID] = true
+		peersByID[peer.ID] = peer
 		connections, err := m.meshRepo.GetConnections(ctx, peer.ID)
 		if err != nil {
 			continue
@@ -534,21 +685,24 @@ func (m *meshService) GetOptimalPath(ctx context.Context, sourcePeer, targetPeer
 	}
 
 	// Check if both peers are in the same stream
-	if !peerSet[sourcePeer] || !peerSet[targetPeer] {
+	if peersByID[sourcePeer] == nil || peersByID[targetPeer] == nil {
 		return nil, fmt.Errorf("peers are not in the same stream")
 	}
 
-	// BFS to find shortest path
-	queue := []domain.PeerID{sourcePeer}
-	visited := make(map[domain.PeerID]bool)
+	// Dijkstra to find the cheapest path
+	cost := map[domain.PeerID]float64{sourcePeer: 0}
 	parent := make(map[domain.PeerID]domain.PeerID)
-	visited[sourcePeer] = true
+	done := make(map[domain.PeerID]bool)
+	queue := &pathQueue{{peer: sourcePeer}}
 
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(pathItem)
+		if done[current.peer] {
+			continue
+		}
+		done[current.peer] = true
 
-		if current == targetPeer {
+		if current.peer == targetPeer {
 			// Reconstruct path
 			path := []domain.PeerID{targetPeer}
 			node := targetPeer
@@ -559,11 +713,16 @@ func (m *meshService) GetOptimalPath(ctx context.Context, sourcePeer, targetPeer
 			return path, nil
 		}
 
-		for _, neighbor := range graph[current] {
-			if !visited[neighbor] && peerSet[neighbor] {
-				visited[neighbor] = true
-				parent[neighbor] = current
-				queue = append(queue, neighbor)
+		for _, neighbor := range graph[current.peer] {
+			peer, inStream := peersByID[neighbor]
+			if !inStream || done[neighbor] {
+				continue
+			}
+			next := current.cost + m.linkCost(peer)
+			if known, seen := cost[neighbor]; !seen || next < known {
+				cost[neighbor] = next
+				parent[neighbor] = current.peer
+				heap.Push(queue, pathItem{peer: neighbor, cost: next})
 			}
 		}
 	}
@@ -571,6 +730,131 @@ func (m *meshService) GetOptimalPath(ctx context.Context, sourcePeer, targetPeer
 	return nil, fmt.Errorf("no path found from %s to %s", sourcePeer, targetPeer)
 }
 
+// hopDepths returns each peer's relay distance from the nearest publisher,
+// following connections in the direction media flows. Peers reachable only
+// through exclude are left out, so a subscriber is never offered a source
+// that it feeds itself.
+func (m *meshService) hopDepths(ctx context.Context, peers []*domain.Peer, exclude domain.PeerID) map[domain.PeerID]int {
+	downstream := make(map[domain.PeerID][]domain.PeerID)
+	depths := make(map[domain.PeerID]int)
+	var queue []domain.PeerID
+
+	for _, peer := range peers {
+		if peer.Capabilities.IsPublisher && !peer.Capabilities.IsObserver {
+			depths[peer.ID] = 0
+			queue = append(queue, peer.ID)
+		}
+
+		connections, err := m.meshRepo.GetConnections(ctx, peer.ID)
+		if err != nil {
+			continue
+		}
+		for _, conn := range connections {
+			if conn.FromPeer == peer.ID {
+				downstream[peer.ID] = append(downstream[peer.ID], conn.ToPeer)
+			}
+		}
+	}
+
+	// Breadth-first from every publisher at once yields the shortest depth
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == exclude {
+			continue
+		}
+
+		for _, next := range downstream[current] {
+			if _, seen := depths[next]; seen {
+				continue
+			}
+			depths[next] = depths[current] + 1
+			queue = append(queue, next)
+		}
+	}
+
+	return depths
+}
+
+// minPathScore floors peer scores used as link costs; penalties can push a
+// score to zero or below, which would make the link free or negative
+const minPathScore = 1.0
+
+// linkCost is the cost of routing media into peer, the inverse of its score
+func (m *meshService) linkCost(peer *domain.Peer) float64 {
+	return 1.0 / math.Max(m.calculatePeerScore(peer, nil), minPathScore)
+}
+
+// pathItem is a peer reached at a given total cost during path search
+type pathItem struct {
+	peer domain.PeerID
+	cost float64
+}
+
+// pathQueue is a min-heap of pathItems ordered by cost
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathItem)) }
+
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// SnapshotTopology captures the stream's current peers and the connections between them
+func (m *meshService) SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error) {
+	peers, err := m.peerRepo.FindByStream(ctx, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream peers: %w", err)
+	}
+
+	snapshot := &domain.TopologySnapshot{
+		StreamID: streamID,
+		TakenAt:  time.Now(),
+		Nodes:    make([]domain.PeerID, 0, len(peers)),
+		Edges:    []domain.TopologyEdge{},
+	}
+
+	// Connections are returned for both endpoints, so dedupe edges
+	seen := make(map[domain.TopologyEdge]bool)
+	for _, peer := range peers {
+		snapshot.Nodes = append(snapshot.Nodes, peer.ID)
+
+		connections, err := m.meshRepo.GetConnections(ctx, peer.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connections for peer %s: %w", peer.ID, err)
+		}
+		for _, conn := range connections {
+			edge := domain.TopologyEdge{From: conn.FromPeer, To: conn.ToPeer}
+			if seen[edge] {
+				continue
+			}
+			seen[edge] = true
+			snapshot.Edges = append(snapshot.Edges, edge)
+		}
+	}
+
+	domain.SortTopology(snapshot)
+	return snapshot, nil
+}
+
+// RecordConnectionResult records whether targetPeer connected to sourcePeer.
+// FindOptimalSources favours recent successes and penalises recent failures.
+func (m *meshService) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	m.history.record(sourcePeer, targetPeer, connected)
+	m.logger.Debugw("recorded connection result",
+		"source_peer", sourcePeer,
+		"target_peer", targetPeer,
+		"connected", connected,
+	)
+	return nil
+}
+
 // Stop stops the rebalancing loop
 func (m *meshService) Stop() {
 	if m.rebalanceTicker != nil {
@@ -84,6 +84,16 @@ func (m *OptimizedMeshService) GetOptimalPath(ctx context.Context, sourcePeer, t
 	return path, nil
 }
 
+// SnapshotTopology captures the stream's current mesh
+func (m *OptimizedMeshService) SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error) {
+	return m.baseService.SnapshotTopology(ctx, streamID)
+}
+
+// RecordConnectionResult records a connection outcome
+func (m *OptimizedMeshService) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	return m.baseService.RecordConnectionResult(ctx, sourcePeer, targetPeer, connected)
+}
+
 // Close closes the service
 func (m *OptimizedMeshService) Close() error {
 	if closer, ok := m.baseService.(interface{ Close() error }); ok {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"rillnet/internal/core/domain"
+	"rillnet/pkg/config"
 )
 
 type MetricsService struct {
@@ -19,6 +20,28 @@ type MetricsService struct {
 	totalBitrate    map[domain.StreamID]int
 	connectionCount map[domain.StreamID]int
 	averageLatency  map[domain.StreamID]time.Duration
+
+	health config.HealthScoreConfig
+}
+
+// StreamMetricsSnapshot is the per-stream part of MetricsSnapshot.
+type StreamMetricsSnapshot struct {
+	StreamID             domain.StreamID `json:"stream_id"`
+	Publishers           int             `json:"publishers"`
+	Subscribers          int             `json:"subscribers"`
+	Connections          int             `json:"connections"`
+	TotalBitrate         int             `json:"total_bitrate_kbps"`
+	HealthScore          float64         `json:"health_score"`
+	P2PEfficiencyPercent *float64        `json:"p2p_efficiency_percent,omitempty"`
+}
+
+// MetricsSnapshot is a point-in-time, JSON-friendly view of key metrics
+// for tooling that cannot scrape Prometheus.
+type MetricsSnapshot struct {
+	Timestamp      time.Time               `json:"timestamp"`
+	ActiveStreams  int                     `json:"active_streams"`
+	ConnectedPeers int                     `json:"connected_peers"`
+	Streams        []StreamMetricsSnapshot `json:"streams"`
 }
 
 func NewMetricsService() *MetricsService {
@@ -29,9 +52,17 @@ func NewMetricsService() *MetricsService {
 		totalBitrate:    make(map[domain.StreamID]int),
 		connectionCount: make(map[domain.StreamID]int),
 		averageLatency:  make(map[domain.StreamID]time.Duration),
+		health:          config.DefaultHealthScoreConfig(),
 	}
 }
 
+// SetHealthScoreConfig sets the weights and strategy used for stream health scores
+func (m *MetricsService) SetHealthScoreConfig(cfg config.HealthScoreConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health = cfg
+}
+
 func (m *MetricsService) IncrementPublisherCount(streamID domain.StreamID) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -110,31 +141,39 @@ func (m *MetricsService) updateStreamMetrics(streamID domain.StreamID) {
 		ActiveSubscribers: subscribers,
 		TotalBitrate:      bitrate,
 		AverageLatency:    latency,
-		HealthScore:       m.calculateHealthScore(publishers, subscribers, bitrate, latency),
+		HealthScore:       calculateHealthScore(m.health, publishers, subscribers, bitrate, latency),
 		Timestamp:         time.Now(),
 	}
 }
 
-func (m *MetricsService) calculateHealthScore(publishers, subscribers, bitrate int, latency time.Duration) float64 {
-	// Simplified health score calculation
-	publisherScore := float64(publishers) * 20.0
-	subscriberScore := float64(subscribers) * 2.0
-	bitrateScore := float64(bitrate) / 100.0
+// Snapshot builds a metrics snapshot for the given (active) streams.
+func (m *MetricsService) Snapshot(streamIDs []domain.StreamID) *MetricsSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	latencyScore := 0.0
-	if latency < 100*time.Millisecond {
-		latencyScore = 30.0
-	} else if latency < 300*time.Millisecond {
-		latencyScore = 20.0
-	} else if latency < 500*time.Millisecond {
-		latencyScore = 10.0
+	snapshot := &MetricsSnapshot{
+		Timestamp:     time.Now(),
+		ActiveStreams: len(streamIDs),
+		Streams:       make([]StreamMetricsSnapshot, 0, len(streamIDs)),
 	}
 
-	totalScore := publisherScore + subscriberScore + bitrateScore + latencyScore
-	if totalScore > 100.0 {
-		return 100.0
+	for _, streamID := range streamIDs {
+		publishers := m.publisherCount[streamID]
+		subscribers := m.subscriberCount[streamID]
+		bitrate := m.totalBitrate[streamID]
+
+		snapshot.ConnectedPeers += publishers + subscribers
+		snapshot.Streams = append(snapshot.Streams, StreamMetricsSnapshot{
+			StreamID:     streamID,
+			Publishers:   publishers,
+			Subscribers:  subscribers,
+			Connections:  m.connectionCount[streamID],
+			TotalBitrate: bitrate,
+			HealthScore:  calculateHealthScore(m.health, publishers, subscribers, bitrate, m.averageLatency[streamID]),
+		})
 	}
-	return totalScore
+
+	return snapshot
 }
 
 // Additional methods for updating metrics
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"rillnet/internal/core/domain"
+)
+
+// admitSubscriberEgress reserves the estimated egress of a new subscriber
+// against the stream's MaxTotalBitrate and the instance's MaxEgressBitrate.
+// When the full estimate does not fit, the peer is downgraded to the best
+// quality level that does by lowering its MaxBitrate; if none fits,
+// ErrStreamBandwidthExceeded is returned. Publishers, observers and uncapped
+// streams are not tracked.
+func (s *streamService) admitSubscriberEgress(stream *domain.Stream, peer *domain.Peer) error {
+	if (stream.MaxTotalBitrate <= 0 && s.config.MaxEgressBitrate <= 0) || peer.Capabilities.IsPublisher || peer.Capabilities.IsObserver {
+		return nil
+	}
+
+	want := estimateSubscriberBitrate(stream, peer)
+
+	s.egressMu.Lock()
+	defer s.egressMu.Unlock()
+
+	s.priorities[stream.ID] = stream.Priority
+
+	reserved := s.egress[stream.ID]
+	used := s.streamEgressLocked(stream.ID, peer.ID)
+	remaining := math.MaxInt
+	if stream.MaxTotalBitrate > 0 {
+		remaining = stream.MaxTotalBitrate - used
+	}
+	instanceLimited := false
+	if s.config.MaxEgressBitrate > 0 {
+		if headroom := s.instanceHeadroomLocked(stream.ID, peer.ID); headroom < remaining {
+			remaining = headroom
+			instanceLimited = true
+		}
+	}
+
+	if want > remaining {
+		downgraded, ok := bestQualityWithin(stream.QualityLevels, remaining)
+		if !ok {
+			if instanceLimited {
+				return fmt.Errorf("%w: %d kbps of instance egress left for priority %d", domain.ErrStreamBandwidthExceeded, max(remaining, 0), stream.Priority)
+			}
+			return fmt.Errorf("%w: %d/%d kbps in use", domain.ErrStreamBandwidthExceeded, used, stream.MaxTotalBitrate)
+		}
+		want = downgraded.Bitrate
+		peer.Capabilities.MaxBitrate = want
+	}
+
+	if reserved == nil {
+		reserved = make(map[domain.PeerID]int)
+		s.egress[stream.ID] = reserved
+	}
+	reserved[peer.ID] = want
+	return nil
+}
+
+// streamEgressLocked returns the egress reserved on a stream by peers other
+// than exclude. Callers hold egressMu.
+func (s *streamService) streamEgressLocked(streamID domain.StreamID, exclude domain.PeerID) int {
+	used := 0
+	for peerID, bitrate := range s.egress[streamID] {
+		if peerID != exclude {
+			used += bitrate
+		}
+	}
+	return used
+}
+
+// instanceHeadroomLocked returns how much of MaxEgressBitrate a new
+// subscriber of streamID may reserve: what is unreserved, less what the other
+// streams are still owed of their priority-weighted share. An idle
+// high-priority stream thus keeps its share while lower-priority streams are
+// downgraded first. Callers hold egressMu.
+func (s *streamService) instanceHeadroomLocked(streamID domain.StreamID, exclude domain.PeerID) int {
+	totalWeight := 0
+	for _, priority := range s.priorities {
+		totalWeight += domain.StreamWeight(priority)
+	}
+
+	headroom := s.config.MaxEgressBitrate
+	for id, priority := range s.priorities {
+		used := s.streamEgressLocked(id, exclude)
+		headroom -= used
+		if id == streamID {
+			continue
+		}
+		share := s.config.MaxEgressBitrate * domain.StreamWeight(priority) / totalWeight
+		if owed := share - used; owed > 0 {
+			headroom -= owed
+		}
+	}
+	return headroom
+}
+
+// releaseSubscriberEgress frees a peer's reserved egress
+func (s *streamService) releaseSubscriberEgress(streamID domain.StreamID, peerID domain.PeerID) {
+	s.egressMu.Lock()
+	defer s.egressMu.Unlock()
+
+	reserved := s.egress[streamID]
+	delete(reserved, peerID)
+	if len(reserved) == 0 {
+		delete(s.egress, streamID)
+	}
+}
+
+// PeerRemoved frees the egress of a peer removed from the mesh by any path
+// (ports.PeerRemovalObserver).
+func (s *streamService) PeerRemoved(streamID domain.StreamID, peerID domain.PeerID) {
+	s.releaseSubscriberEgress(streamID, peerID)
+}
+
+// EstimatedEgress returns the subscriber egress (kbps) currently reserved on a stream.
+func (s *streamService) EstimatedEgress(streamID domain.StreamID) int {
+	s.egressMu.Lock()
+	defer s.egressMu.Unlock()
+
+	total := 0
+	for _, bitrate := range s.egress[streamID] {
+		total += bitrate
+	}
+	return total
+}
+
+// estimateSubscriberBitrate is the top quality level's bitrate, capped by the
+// peer's own MaxBitrate when it has one.
+func estimateSubscriberBitrate(stream *domain.Stream, peer *domain.Peer) int {
+	estimate := 0
+	for _, level := range stream.QualityLevels {
+		if level.Bitrate > estimate {
+			estimate = level.Bitrate
+		}
+	}
+	if max := peer.Capabilities.MaxBitrate; max > 0 && (estimate == 0 || max < estimate) {
+		estimate = max
+	}
+	return estimate
+}
+
+// bestQualityWithin returns the highest-bitrate level not above budget
+func bestQualityWithin(levels []domain.StreamQuality, budget int) (domain.StreamQuality, bool) {
+	sorted := append([]domain.StreamQuality(nil), levels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Bitrate > sorted[j].Bitrate })
+	for _, level := range sorted {
+		if level.Bitrate > 0 && level.Bitrate <= budget {
+			return level, true
+		}
+	}
+	return domain.StreamQuality{}, false
+}
+
+// SetStreamPriority changes the priority a stream is served with under
+// contention (ports.StreamPrioritySetter). Reservations already made are kept.
+func (s *streamService) SetStreamPriority(ctx context.Context, streamID domain.StreamID, priority int) error {
+	if priority < 0 || priority > domain.MaxStreamPriority {
+		return fmt.Errorf("%w: %d (must be 0-%d)", domain.ErrInvalidStreamPriority, priority, domain.MaxStreamPriority)
+	}
+
+	stream, err := s.streamRepo.GetByID(ctx, streamID)
+	if err != nil {
+		return err
+	}
+	stream.Priority = priority
+	if err := s.streamRepo.Update(ctx, stream); err != nil {
+		return fmt.Errorf("failed to update stream priority: %w", err)
+	}
+
+	s.egressMu.Lock()
+	s.priorities[streamID] = priority
+	s.egressMu.Unlock()
+	return nil
+}
+
+// forgetStreamPriority stops counting a stream that is no longer hosted here
+// in the instance egress shares
+func (s *streamService) forgetStreamPriority(streamID domain.StreamID) {
+	s.egressMu.Lock()
+	delete(s.priorities, streamID)
+	s.egressMu.Unlock()
+}
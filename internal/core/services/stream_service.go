@@ -2,11 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/ports"
+	"rillnet/pkg/cache"
+	"rillnet/pkg/config"
 	"rillnet/pkg/utils"
 )
 
@@ -16,6 +20,50 @@ type streamService struct {
 	meshRepo       ports.MeshRepository
 	meshService    ports.MeshService
 	metricsService *MetricsService
+	config         config.StreamConfig
+	ids            utils.IDGenerator
+
+	// Streams created or joined on this instance, for the per-instance MaxStreams cap
+	hosted   map[domain.StreamID]struct{}
+	hostedMu sync.Mutex
+
+	// Estimated subscriber egress (kbps) per stream, for MaxTotalBitrate and
+	// MaxEgressBitrate admission
+	egress map[domain.StreamID]map[domain.PeerID]int
+	// Priority of each stream hosted here, weighting its MaxEgressBitrate share
+	priorities map[domain.StreamID]int
+	egressMu   sync.Mutex
+
+	// Recently computed stream stats, nil when StatsInterval is 0
+	stats         *cache.CacheWithFallback
+	statsTTL      time.Duration
+	statsStop     chan struct{}
+	statsStopOnce sync.Once
+
+	// Told when a stream is stopped or reset, or a peer kicked; any may be nil
+	streamCloser  ports.StreamCloser
+	endRecorder   ports.StreamEndRecorder
+	stopNotifier  ports.StreamStopNotifier
+	resetNotifier ports.StreamResetNotifier
+	kickNotifier  ports.PeerKickNotifier
+
+	// Records stream creations, nil when there is no metrics backend
+	metrics ports.Metrics
+}
+
+// StreamStopHooks is implemented by stream services that tear down a stream's
+// media connections and metrics and tell its peers when it is stopped.
+type StreamStopHooks interface {
+	SetStopHooks(closer ports.StreamCloser, recorder ports.StreamEndRecorder)
+	SetStopNotifier(notifier ports.StreamStopNotifier)
+	SetResetNotifier(notifier ports.StreamResetNotifier)
+	SetKickNotifier(notifier ports.PeerKickNotifier)
+}
+
+// MetricsHooks is implemented by services that record their events in a
+// metrics backend.
+type MetricsHooks interface {
+	SetMetrics(metrics ports.Metrics)
 }
 
 func NewStreamService(
@@ -25,13 +73,49 @@ func NewStreamService(
 	meshService ports.MeshService,
 	metricsService *MetricsService,
 ) ports.StreamService {
-	return &streamService{
+	return NewStreamServiceWithConfig(streamRepo, peerRepo, meshRepo, meshService, metricsService, config.StreamConfig{}, nil)
+}
+
+// NewStreamServiceWithConfig creates a stream service that enforces the given stream limits.
+// A nil ids falls back to utils.DefaultIDGenerator, and an unset cfg.Health to
+// config.DefaultHealthScoreConfig.
+func NewStreamServiceWithConfig(
+	streamRepo ports.StreamRepository,
+	peerRepo ports.PeerRepository,
+	meshRepo ports.MeshRepository,
+	meshService ports.MeshService,
+	metricsService *MetricsService,
+	cfg config.StreamConfig,
+	ids utils.IDGenerator,
+) ports.StreamService {
+	if ids == nil {
+		ids = utils.DefaultIDGenerator
+	}
+	if cfg.Health.Strategy == "" {
+		cfg.Health = config.DefaultHealthScoreConfig()
+	}
+	s := &streamService{
 		streamRepo:     streamRepo,
 		peerRepo:       peerRepo,
 		meshRepo:       meshRepo,
 		meshService:    meshService,
 		metricsService: metricsService,
+		config:         cfg,
+		ids:            ids,
+		hosted:         make(map[domain.StreamID]struct{}),
+		egress:         make(map[domain.StreamID]map[domain.PeerID]int),
+		priorities:     make(map[domain.StreamID]int),
+		statsStop:      make(chan struct{}),
 	}
+
+	// Keep stream stats warm in the background
+	if cfg.StatsInterval > 0 {
+		s.statsTTL = statsTTLFactor * cfg.StatsInterval
+		s.stats = cache.NewCacheWithFallback(s.statsTTL)
+		go s.statsRefreshLoop()
+	}
+
+	return s
 }
 
 func (s *streamService) CreateStream(ctx context.Context, name string, owner domain.PeerID, maxPeers int) (*domain.Stream, error) {
@@ -43,8 +127,13 @@ func (s *streamService) CreateStream(ctx context.Context, name string, owner dom
 		}
 	}
 
+	streamID := domain.StreamID(s.ids.NewID("stream"))
+	if _, err := s.reserveInstanceSlot(ctx, streamID); err != nil {
+		return nil, err
+	}
+
 	stream := &domain.Stream{
-		ID:          domain.StreamID(utils.GenerateStreamID()),
+		ID:          streamID,
 		Name:        name,
 		Owner:       owner,
 		OwnerUserID: ownerUserID,
@@ -57,15 +146,100 @@ func (s *streamService) CreateStream(ctx context.Context, name string, owner dom
 			{Quality: "medium", Bitrate: 1000, Width: 854, Height: 480, Codec: "VP8"},
 			{Quality: "low", Bitrate: 500, Width: 640, Height: 360, Codec: "VP8"},
 		},
+		MaxTotalBitrate: s.config.MaxTotalBitrate,
 	}
 
-	if err := s.streamRepo.Create(ctx, stream); err != nil {
+	// The repository counts and inserts in one step so concurrent creates
+	// cannot overshoot the owner's quota
+	var err error
+	if limit := s.ownerQuota(ctx, ownerUserID); limit > 0 {
+		err = s.streamRepo.CreateWithOwnerLimit(ctx, stream, limit)
+	} else {
+		err = s.streamRepo.Create(ctx, stream)
+	}
+	if err != nil {
+		s.releaseInstanceSlot(streamID)
+		if errors.Is(err, domain.ErrStreamQuotaExceeded) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create stream: %w", err)
 	}
+	if s.metrics != nil {
+		s.metrics.RecordStreamCreated(streamID)
+	}
+
+	// An idle stream is owed its egress share as well
+	s.egressMu.Lock()
+	s.priorities[streamID] = stream.Priority
+	s.egressMu.Unlock()
 
 	return stream, nil
 }
 
+// reserveInstanceSlot claims a slot for a stream on this instance, failing
+// with ErrInstanceAtCapacity once MaxStreams streams are active here. It
+// reports whether a new slot was taken; a stream already hosted here keeps its own.
+func (s *streamService) reserveInstanceSlot(ctx context.Context, streamID domain.StreamID) (bool, error) {
+	s.hostedMu.Lock()
+	defer s.hostedMu.Unlock()
+
+	if _, hosted := s.hosted[streamID]; hosted {
+		return false, nil
+	}
+
+	if limit := s.config.MaxStreams; limit > 0 && len(s.hosted) >= limit {
+		s.pruneHostedLocked(ctx)
+		if len(s.hosted) >= limit {
+			return false, fmt.Errorf("%w: %d/%d streams", domain.ErrInstanceAtCapacity, len(s.hosted), limit)
+		}
+	}
+
+	s.hosted[streamID] = struct{}{}
+	return true, nil
+}
+
+func (s *streamService) releaseInstanceSlot(streamID domain.StreamID) {
+	s.hostedMu.Lock()
+	delete(s.hosted, streamID)
+	s.hostedMu.Unlock()
+	s.forgetStreamPriority(streamID)
+}
+
+// pruneHostedLocked drops streams that were removed or stopped since creation.
+func (s *streamService) pruneHostedLocked(ctx context.Context) {
+	for streamID := range s.hosted {
+		stream, err := s.streamRepo.GetByID(ctx, streamID)
+		if errors.Is(err, domain.ErrStreamNotFound) || (err == nil && !stream.Active) {
+			delete(s.hosted, streamID)
+			s.forgetStreamPriority(streamID)
+		}
+	}
+}
+
+// StreamCapacity reports how many active streams this instance hosts and its cap (0 = unlimited).
+func (s *streamService) StreamCapacity(ctx context.Context) (active int, limit int) {
+	s.hostedMu.Lock()
+	defer s.hostedMu.Unlock()
+
+	s.pruneHostedLocked(ctx)
+	return len(s.hosted), s.config.MaxStreams
+}
+
+// ownerQuota returns how many active streams owner may have, 0 for no limit
+func (s *streamService) ownerQuota(ctx context.Context, owner domain.UserID) int {
+	if owner == "" {
+		return 0
+	}
+
+	limit := s.config.MaxPerOwner
+	if role, ok := ctx.Value(domain.UserRoleContextKey).(domain.UserRole); ok {
+		if roleLimit, exists := s.config.MaxPerOwnerByRole[string(role)]; exists {
+			limit = roleLimit
+		}
+	}
+	return limit
+}
+
 func (s *streamService) GetStream(ctx context.Context, streamID domain.StreamID) (*domain.Stream, error) {
 	return s.streamRepo.GetByID(ctx, streamID)
 }
@@ -74,6 +248,13 @@ func (s *streamService) ListStreams(ctx context.Context) ([]*domain.Stream, erro
 	return s.streamRepo.ListActive(ctx)
 }
 
+func (s *streamService) ListStreamsPage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error) {
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, 0, fmt.Errorf("invalid page: limit %d, offset %d", opts.Limit, opts.Offset)
+	}
+	return s.streamRepo.ListActivePage(ctx, opts)
+}
+
 func (s *streamService) JoinStream(ctx context.Context, streamID domain.StreamID, peer *domain.Peer) error {
 	// Check if stream exists
 	stream, err := s.streamRepo.GetByID(ctx, streamID)
@@ -85,21 +266,51 @@ func (s *streamService) JoinStream(ctx context.Context, streamID domain.StreamID
 		return domain.ErrStreamNotFound
 	}
 
-	// Check maximum peer count
+	// Check maximum peer count; observers don't consume relay capacity
 	currentPeers, err := s.peerRepo.FindByStream(ctx, streamID)
 	if err != nil {
 		return err
 	}
 
-	if len(currentPeers) >= stream.MaxPeers {
-		return fmt.Errorf("stream is full: %d/%d peers", len(currentPeers), stream.MaxPeers)
+	if !peer.Capabilities.IsObserver {
+		mediaPeers := 0
+		for _, p := range currentPeers {
+			if !p.Capabilities.IsObserver {
+				mediaPeers++
+			}
+		}
+		if mediaPeers >= stream.MaxPeers {
+			return fmt.Errorf("stream is full: %d/%d peers", mediaPeers, stream.MaxPeers)
+		}
+	}
+
+	// Serving a stream created on another instance makes this one host it too
+	newlyHosted, err := s.reserveInstanceSlot(ctx, streamID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.admitSubscriberEgress(stream, peer); err != nil {
+		if newlyHosted {
+			s.releaseInstanceSlot(streamID)
+		}
+		return err
 	}
 
 	// Mesh service owns peer repository insertion (avoids duplicate Add calls).
 	if err := s.meshService.AddPeer(ctx, peer); err != nil {
+		s.releaseSubscriberEgress(streamID, peer.ID)
+		if newlyHosted {
+			s.releaseInstanceSlot(streamID)
+		}
 		return fmt.Errorf("failed to add peer to mesh: %w", err)
 	}
 
+	// Observers get events and stats only, so they stay out of media metrics and the mesh
+	if peer.Capabilities.IsObserver {
+		return nil
+	}
+
 	// Update metrics
 	if peer.Capabilities.IsPublisher {
 		s.metricsService.IncrementPublisherCount(streamID)
@@ -121,6 +332,7 @@ func (s *streamService) LeaveStream(ctx context.Context, streamID domain.StreamI
 	if err := s.meshService.RemovePeer(ctx, peerID); err != nil {
 		return fmt.Errorf("failed to remove peer from mesh: %w", err)
 	}
+	s.releaseSubscriberEgress(streamID, peerID)
 
 	// Rebuild mesh network
 	if err := s.meshRepo.BuildMesh(ctx, streamID, 4); err != nil {
@@ -130,7 +342,209 @@ func (s *streamService) LeaveStream(ctx context.Context, streamID domain.StreamI
 	return nil
 }
 
+// SetStopHooks sets who closes a stopped stream's media connections and
+// records its end. Must be called before streams are stopped.
+func (s *streamService) SetStopHooks(closer ports.StreamCloser, recorder ports.StreamEndRecorder) {
+	s.streamCloser = closer
+	s.endRecorder = recorder
+}
+
+// SetMetrics sets the backend stream creations are recorded in, pairing the
+// ends recorded by the stop hooks. Must be called before streams are created.
+func (s *streamService) SetMetrics(metrics ports.Metrics) {
+	s.metrics = metrics
+}
+
+// SetStopNotifier sets who tells a stopped stream's signaling peers that it
+// ended. Must be called before streams are stopped.
+func (s *streamService) SetStopNotifier(notifier ports.StreamStopNotifier) {
+	s.stopNotifier = notifier
+}
+
+// SetResetNotifier sets who tells a reset stream's signaling peers to join
+// again. Must be called before streams are reset.
+func (s *streamService) SetResetNotifier(notifier ports.StreamResetNotifier) {
+	s.resetNotifier = notifier
+}
+
+// SetKickNotifier sets who tells a kicked peer to disconnect. Must be called
+// before peers are kicked.
+func (s *streamService) SetKickNotifier(notifier ports.PeerKickNotifier) {
+	s.kickNotifier = notifier
+}
+
+// StopStream evicts the stream's peers from the mesh, closes their media
+// connections and then marks the stream inactive. Cleanup runs to the end
+// despite errors; if any step failed the stream stays active so that stopping
+// it again retries. Stopping a stopped stream is a no-op.
+func (s *streamService) StopStream(ctx context.Context, streamID domain.StreamID) error {
+	stream, err := s.streamRepo.GetByID(ctx, streamID)
+	if err != nil {
+		return err
+	}
+	if !stream.Active {
+		return nil
+	}
+
+	var errs []error
+	peers, err := s.peerRepo.FindByStream(ctx, streamID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list stream peers: %w", err))
+	}
+	for _, peer := range peers {
+		if err := s.meshService.RemovePeer(ctx, peer.ID); err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+			errs = append(errs, fmt.Errorf("failed to remove peer %s from mesh: %w", peer.ID, err))
+		}
+	}
+
+	s.egressMu.Lock()
+	delete(s.egress, streamID)
+	s.egressMu.Unlock()
+
+	if s.streamCloser != nil {
+		if err := s.streamCloser.CloseStream(ctx, streamID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close stream connections: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	stream.Active = false
+	if err := s.streamRepo.Update(ctx, stream); err != nil {
+		return fmt.Errorf("failed to mark stream inactive: %w", err)
+	}
+	s.releaseInstanceSlot(streamID)
+
+	if s.endRecorder != nil {
+		s.endRecorder.RecordStreamEnded(streamID)
+	}
+	// Best effort: members also see their media connections close
+	if s.stopNotifier != nil {
+		_ = s.stopNotifier.NotifyStreamStopped(ctx, streamID)
+	}
+
+	return nil
+}
+
+// ResetStream evicts the stream's peers from the mesh and closes their media
+// connections like StopStream, but keeps the stream active so they can join
+// again, which they are told to. Cleanup runs to the end despite errors; the
+// peers are only told once it succeeded. An inactive stream is not found.
+func (s *streamService) ResetStream(ctx context.Context, streamID domain.StreamID) error {
+	stream, err := s.streamRepo.GetByID(ctx, streamID)
+	if err != nil {
+		return err
+	}
+	if !stream.Active {
+		return domain.ErrStreamNotFound
+	}
+
+	var errs []error
+	peers, err := s.peerRepo.FindByStream(ctx, streamID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list stream peers: %w", err))
+	}
+	for _, peer := range peers {
+		if err := s.meshService.RemovePeer(ctx, peer.ID); err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+			errs = append(errs, fmt.Errorf("failed to remove peer %s from mesh: %w", peer.ID, err))
+		}
+	}
+
+	s.egressMu.Lock()
+	delete(s.egress, streamID)
+	s.egressMu.Unlock()
+
+	if resetter, ok := s.streamCloser.(ports.StreamMediaResetter); ok {
+		if err := resetter.ResetStreamMedia(ctx, streamID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reset stream connections: %w", err))
+		}
+	} else if s.streamCloser != nil {
+		if err := s.streamCloser.CloseStream(ctx, streamID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close stream connections: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Best effort: members also see their media connections close
+	if s.resetNotifier != nil {
+		_ = s.resetNotifier.NotifyStreamReset(ctx, streamID)
+	}
+	return nil
+}
+
+// KickPeer removes a peer from the stream's mesh, closes its media
+// connections and tells it to disconnect. It returns domain.ErrPeerNotFound
+// when the peer did not join the stream.
+func (s *streamService) KickPeer(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error {
+	if _, err := s.GetPeer(ctx, streamID, peerID); err != nil {
+		return err
+	}
+
+	if err := s.meshService.RemovePeer(ctx, peerID); err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+		return fmt.Errorf("failed to remove peer from mesh: %w", err)
+	}
+	s.releaseSubscriberEgress(streamID, peerID)
+
+	if disconnector, ok := s.streamCloser.(ports.PeerDisconnector); ok {
+		if err := disconnector.DisconnectPeer(ctx, peerID); err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+			return fmt.Errorf("failed to close peer connections: %w", err)
+		}
+	}
+
+	// Best effort: the peer also sees its media connections close
+	if s.kickNotifier != nil {
+		_ = s.kickNotifier.NotifyPeerKicked(ctx, streamID, peerID)
+	}
+	return nil
+}
+
+// GetPeer returns a peer that joined the stream, or domain.ErrPeerNotFound
+// when it is unknown or belongs to another stream
+func (s *streamService) GetPeer(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) (*domain.Peer, error) {
+	peer, err := s.peerRepo.GetByID(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+	if peer.StreamID != streamID {
+		return nil, domain.ErrPeerNotFound
+	}
+	return peer, nil
+}
+
+// FindPeers returns the stream's peers matching filter, so callers get only
+// the candidates they asked for instead of the whole peer list. The
+// repository applies the filter and its limit.
+func (s *streamService) FindPeers(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	if _, err := s.streamRepo.GetByID(ctx, streamID); err != nil {
+		return nil, err
+	}
+
+	return s.peerRepo.FindByFilter(ctx, streamID, filter)
+}
+
+// GetStreamStats returns the stream's metrics, from the stats cache while it
+// is fresh
 func (s *streamService) GetStreamStats(ctx context.Context, streamID domain.StreamID) (*domain.StreamMetrics, error) {
+	if s.stats == nil {
+		return s.computeStreamStats(ctx, streamID)
+	}
+
+	value, err := s.stats.GetOrSet(ctx, statsCacheKey(streamID), func(ctx context.Context) (interface{}, error) {
+		return s.computeStreamStats(ctx, streamID)
+	}, s.statsTTL)
+	if err != nil {
+		return nil, err
+	}
+	// Callers get their own copy of the shared entry
+	metrics := *value.(*domain.StreamMetrics)
+	return &metrics, nil
+}
+
+// computeStreamStats scans the stream's peers for its current metrics
+func (s *streamService) computeStreamStats(ctx context.Context, streamID domain.StreamID) (*domain.StreamMetrics, error) {
 	peers, err := s.peerRepo.FindByStream(ctx, streamID)
 	if err != nil {
 		return nil, err
@@ -144,6 +558,9 @@ func (s *streamService) GetStreamStats(ctx context.Context, streamID domain.Stre
 	)
 
 	for _, peer := range peers {
+		if peer.Capabilities.IsObserver {
+			continue
+		}
 		if peer.Capabilities.IsPublisher {
 			publisherCount++
 			totalBitrate += peer.Metrics.Bandwidth
@@ -154,11 +571,11 @@ func (s *streamService) GetStreamStats(ctx context.Context, streamID domain.Stre
 	}
 
 	avgLatency := time.Duration(0)
-	if len(peers) > 0 {
-		avgLatency = totalLatency / time.Duration(len(peers))
+	if mediaPeers := publisherCount + subscriberCount; mediaPeers > 0 {
+		avgLatency = totalLatency / time.Duration(mediaPeers)
 	}
 
-	healthScore := s.calculateHealthScore(publisherCount, subscriberCount, totalBitrate, avgLatency)
+	healthScore := calculateHealthScore(s.config.Health, publisherCount, subscriberCount, totalBitrate, avgLatency)
 
 	return &domain.StreamMetrics{
 		StreamID:          streamID,
@@ -170,25 +587,3 @@ func (s *streamService) GetStreamStats(ctx context.Context, streamID domain.Stre
 	}, nil
 }
 
-func (s *streamService) calculateHealthScore(publishers, subscribers, bitrate int, latency time.Duration) float64 {
-	// Simplified health score calculation
-	publisherScore := float64(publishers) * 20.0
-	subscriberScore := float64(subscribers) * 2.0
-	bitrateScore := float64(bitrate) / 100.0
-
-	latencyScore := 0.0
-	if latency < 100*time.Millisecond {
-		latencyScore = 30.0
-	} else if latency < 300*time.Millisecond {
-		latencyScore = 20.0
-	} else if latency < 500*time.Millisecond {
-		latencyScore = 10.0
-	}
-
-	totalScore := publisherScore + subscriberScore + bitrateScore + latencyScore
-	if totalScore > 100.0 {
-		return 100.0
-	}
-	return totalScore
-}
-
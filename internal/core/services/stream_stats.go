@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+)
+
+// statsTTLFactor is how many refresh intervals cached stream stats live, so
+// a refresh always lands before an active stream's entry expires
+const statsTTLFactor = 2
+
+// statsCacheKey is the stats cache key of a stream
+func statsCacheKey(streamID domain.StreamID) string {
+	return fmt.Sprintf("stream:%s:stats", streamID)
+}
+
+// statsRefreshLoop recomputes the stats of every active stream each
+// StatsInterval, so GetStreamStats rarely has to scan a stream's peers itself
+func (s *streamService) statsRefreshLoop() {
+	ticker := time.NewTicker(s.config.StatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refreshStreamStats(context.Background())
+		case <-s.statsStop:
+			return
+		}
+	}
+}
+
+// refreshStreamStats recomputes and caches the stats of all active streams
+func (s *streamService) refreshStreamStats(ctx context.Context) {
+	streams, err := s.streamRepo.ListActive(ctx)
+	if err != nil {
+		return
+	}
+
+	// Stopped streams are not refreshed and drop out once their entry expires
+	for _, stream := range streams {
+		if metrics, err := s.computeStreamStats(ctx, stream.ID); err == nil {
+			s.stats.Set(statsCacheKey(stream.ID), metrics, s.statsTTL)
+		}
+	}
+}
+
+// Stop stops the background stats refresh
+func (s *streamService) Stop() {
+	s.statsStopOnce.Do(func() {
+		close(s.statsStop)
+		if s.stats != nil {
+			s.stats.Stop()
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	goerrors "errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// topologyHistoryLimit bounds the snapshots kept per stream for diffing
+const topologyHistoryLimit = 64
+
+// AdminHandler serves operator endpoints for inspecting mesh state
+type AdminHandler struct {
+	meshService ports.MeshService
+	// Resets streams' media planes; nil when resets are not supported
+	resetter ports.StreamResetter
+
+	mu        sync.Mutex
+	snapshots map[domain.StreamID][]*domain.TopologySnapshot // Oldest first
+}
+
+func NewAdminHandler(meshService ports.MeshService) *AdminHandler {
+	return &AdminHandler{
+		meshService: meshService,
+		snapshots:   make(map[domain.StreamID][]*domain.TopologySnapshot),
+	}
+}
+
+// SetStreamResetter sets the service streams are reset through. Must be
+// called before the handler serves requests.
+func (h *AdminHandler) SetStreamResetter(resetter ports.StreamResetter) {
+	h.resetter = resetter
+}
+
+// GetTopology records and returns a snapshot of the stream's mesh.
+func (h *AdminHandler) GetTopology(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+
+	snapshot, err := h.meshService.SnapshotTopology(c.Request.Context(), streamID)
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to snapshot topology", 500))
+		return
+	}
+	h.recordSnapshot(snapshot)
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// DiffTopology compares the stream's current mesh against a stored snapshot:
+// the latest one taken at or before ?since= (RFC3339), or the latest one
+// overall when since is omitted. The current snapshot is recorded as well.
+func (h *AdminHandler) DiffTopology(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			reportError(c, errors.NewInvalidInputError("since must be an RFC3339 timestamp"))
+			return
+		}
+		since = parsed
+	}
+
+	current, err := h.meshService.SnapshotTopology(c.Request.Context(), streamID)
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to snapshot topology", 500))
+		return
+	}
+
+	baseline := h.findSnapshot(streamID, since)
+	h.recordSnapshot(current)
+	if baseline == nil {
+		reportError(c, errors.NewNotFoundError("topology snapshot"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id": streamID,
+		"baseline":  baseline,
+		"current":   current,
+		"diff":      domain.DiffTopology(baseline, current),
+	})
+}
+
+// ResetStream restarts the stream's media plane, e.g. after its publisher
+// crashed and left subscribers on stale connections: every peer is evicted
+// and told to join again, while the stream itself is kept.
+func (h *AdminHandler) ResetStream(c *gin.Context) {
+	if h.resetter == nil {
+		reportError(c, errors.NewAppError(errors.ErrCodeInternal, "stream reset is not supported", http.StatusNotImplemented))
+		return
+	}
+
+	streamID := domain.StreamID(c.Param("id"))
+	if err := h.resetter.ResetStream(c.Request.Context(), streamID); err != nil {
+		if goerrors.Is(err, domain.ErrStreamNotFound) {
+			reportError(c, errors.NewNotFoundError("stream"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to reset stream", 500))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stream_id": streamID, "reset": true})
+}
+
+// scoringWeights is the body of the mesh scoring weights endpoints
+type scoringWeights struct {
+	LatencyWeight     *float64 `json:"latency_weight" binding:"required"`
+	BandwidthWeight   *float64 `json:"bandwidth_weight" binding:"required"`
+	ReliabilityWeight *float64 `json:"reliability_weight" binding:"required"`
+}
+
+// GetScoringWeights returns the weights the mesh scores source candidates with.
+func (h *AdminHandler) GetScoringWeights(c *gin.Context) {
+	tuner, ok := h.meshService.(ports.MeshScoringTuner)
+	if !ok {
+		reportError(c, errors.NewAppError(errors.ErrCodeInternal, "mesh scoring weights are not adjustable", http.StatusNotImplemented))
+		return
+	}
+
+	latency, bandwidth, reliability := tuner.GetScoringWeights()
+	c.JSON(http.StatusOK, scoringWeights{
+		LatencyWeight:     &latency,
+		BandwidthWeight:   &bandwidth,
+		ReliabilityWeight: &reliability,
+	})
+}
+
+// SetScoringWeights replaces the mesh scoring weights without a restart.
+func (h *AdminHandler) SetScoringWeights(c *gin.Context) {
+	tuner, ok := h.meshService.(ports.MeshScoringTuner)
+	if !ok {
+		reportError(c, errors.NewAppError(errors.ErrCodeInternal, "mesh scoring weights are not adjustable", http.StatusNotImplemented))
+		return
+	}
+
+	var req scoringWeights
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reportError(c, errors.NewInvalidInputError("latency_weight, bandwidth_weight and reliability_weight are required"))
+		return
+	}
+	if err := tuner.SetScoringWeights(*req.LatencyWeight, *req.BandwidthWeight, *req.ReliabilityWeight); err != nil {
+		reportError(c, errors.NewInvalidInputError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+func (h *AdminHandler) recordSnapshot(snapshot *domain.TopologySnapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history := append(h.snapshots[snapshot.StreamID], snapshot)
+	if len(history) > topologyHistoryLimit {
+		history = history[len(history)-topologyHistoryLimit:]
+	}
+	h.snapshots[snapshot.StreamID] = history
+}
+
+// findSnapshot returns the latest stored snapshot taken at or before since,
+// or the latest one when since is zero.
+func (h *AdminHandler) findSnapshot(streamID domain.StreamID, since time.Time) *domain.TopologySnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history := h.snapshots[streamID]
+	for i := len(history) - 1; i >= 0; i-- {
+		if since.IsZero() || !history[i].TakenAt.After(since) {
+			return history[i]
+		}
+	}
+	return nil
+}
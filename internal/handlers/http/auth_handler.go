@@ -1,6 +1,7 @@
 package http
 
 import (
+	goerrors "errors"
 	"net/http"
 	"strings"
 	"time"
@@ -133,15 +134,13 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	claims, err := h.authService.ValidateRefreshToken(req.RefreshToken)
+	accessToken, newRefreshToken, err := h.authService.RefreshTokens(c.Request.Context(), req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
-		return
-	}
-
-	accessToken, newRefreshToken, err := h.authService.RotateRefreshToken(c.Request.Context(), req.RefreshToken)
-	if err != nil {
-		_ = claims
+		if goerrors.Is(err, domain.ErrRefreshTokenReused) {
+			// The whole login was revoked; the client has to sign in again
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token reused"})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
 		return
 	}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"rillnet/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler serves the instance's effective configuration for diagnostics
+type ConfigHandler struct {
+	redacted *config.Config
+}
+
+// NewConfigHandler takes the loaded config, with defaults and env overrides
+// applied; secrets are redacted once here.
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{redacted: cfg.Redacted()}
+}
+
+// GetConfig returns the effective config with secrets redacted.
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.redacted)
+}
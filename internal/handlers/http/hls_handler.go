@@ -0,0 +1,138 @@
+package http
+
+import (
+	goerrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/infrastructure/streaming"
+	"rillnet/pkg/errors"
+	"rillnet/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Content types of HLS responses
+const (
+	hlsPlaylistContentType = "application/vnd.apple.mpegurl"
+	hlsSegmentContentType  = "video/mp2t"
+)
+
+// HLSHandler serves the playlists and cached segments of streams over HLS,
+// for clients that cannot use WebRTC. Nothing muxes publisher tracks into
+// MPEG-TS segments yet, so the servers do not register its routes; the
+// segmenter that fills cache must be the one passed here.
+type HLSHandler struct {
+	segmenter *streaming.Segmenter
+	cache     *streaming.SegmentCache
+	streams   ports.StreamService
+}
+
+func NewHLSHandler(segmenter *streaming.Segmenter, cache *streaming.SegmentCache, streams ports.StreamService) *HLSHandler {
+	return &HLSHandler{
+		segmenter: segmenter,
+		cache:     cache,
+		streams:   streams,
+	}
+}
+
+// GetMasterPlaylist lists a playlist per quality the stream has segments in
+func (h *HLSHandler) GetMasterPlaylist(c *gin.Context) {
+	streamID, ok := hlsStreamID(c)
+	if !ok {
+		return
+	}
+
+	qualities := h.cache.Qualities(streamID)
+	if len(qualities) == 0 {
+		reportError(c, errors.NewNotFoundError("stream"))
+		return
+	}
+
+	playlist, err := h.segmenter.GenerateMasterPlaylist(c.Request.Context(), streamID, qualities)
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to generate playlist", 500))
+		return
+	}
+	writePlaylist(c, playlist)
+}
+
+// GetMediaPlaylist lists the cached segments of one quality of the stream
+func (h *HLSHandler) GetMediaPlaylist(c *gin.Context) {
+	streamID, ok := hlsStreamID(c)
+	if !ok {
+		return
+	}
+
+	segments := h.cache.ListSegments(streamID, c.Param("quality"))
+	if len(segments) == 0 {
+		reportError(c, errors.NewNotFoundError("playlist"))
+		return
+	}
+
+	// Segments of a removed or stopped stream stay cached, and are all there is
+	stream, err := h.streams.GetStream(c.Request.Context(), streamID)
+	if err != nil && !goerrors.Is(err, domain.ErrStreamNotFound) {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to get stream", 500))
+		return
+	}
+	live := err == nil && stream.Active
+
+	playlist, err := h.segmenter.GeneratePlaylist(c.Request.Context(), streamID, c.Param("quality"), segments, live)
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to generate playlist", 500))
+		return
+	}
+	writePlaylist(c, playlist)
+}
+
+// GetSegment serves a cached segment, named by its index as <index>.ts
+func (h *HLSHandler) GetSegment(c *gin.Context) {
+	streamID, ok := hlsStreamID(c)
+	if !ok {
+		return
+	}
+
+	name, isTS := strings.CutSuffix(c.Param("segment"), ".ts")
+	index, err := strconv.Atoi(name)
+	if !isTS || err != nil || index < 0 {
+		reportError(c, errors.NewNotFoundError("segment"))
+		return
+	}
+
+	segment, found := h.cache.Get(streamID, c.Param("quality"), index)
+	if !found {
+		reportError(c, errors.NewNotFoundError("segment"))
+		return
+	}
+
+	// Segments never change once written
+	c.Header("Cache-Control", "public, max-age=3600")
+	if segment.Data != nil {
+		c.Data(http.StatusOK, hlsSegmentContentType, segment.Data)
+		return
+	}
+	c.Header("Content-Type", hlsSegmentContentType)
+	c.File(segment.FilePath)
+}
+
+// hlsStreamID returns the :id parameter, writing the error response
+// and returning false when it is not a valid stream ID
+func hlsStreamID(c *gin.Context) (domain.StreamID, bool) {
+	streamID := domain.StreamID(c.Param("id"))
+	if err := validation.ValidateStreamID(string(streamID)); err != nil {
+		reportError(c, errors.NewInvalidInputError(err.Error()))
+		return "", false
+	}
+	return streamID, true
+}
+
+// writePlaylist sends an M3U8 playlist, which players must refetch as the
+// stream goes on
+func writePlaylist(c *gin.Context, playlist string) {
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, hlsPlaylistContentType, []byte(playlist))
+}
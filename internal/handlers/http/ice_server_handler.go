@@ -0,0 +1,91 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"rillnet/internal/core/ports"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+)
+
+// iceURLSchemes are the URL schemes accepted for ICE servers
+var iceURLSchemes = []string{"stun:", "stuns:", "turn:", "turns:"}
+
+// ICEServerHandler lets operators push fresh ICE servers, e.g. rotated TURN
+// credentials, without restarting the SFU
+type ICEServerHandler struct {
+	updater ports.ICEServerUpdater
+}
+
+// NewICEServerHandler creates a handler updating the SFU's ICE servers
+func NewICEServerHandler(updater ports.ICEServerUpdater) *ICEServerHandler {
+	return &ICEServerHandler{updater: updater}
+}
+
+type iceServerRequest struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// UpdateICEServers replaces the ICE servers used for new peer connections.
+// Existing connections keep their servers.
+func (h *ICEServerHandler) UpdateICEServers(c *gin.Context) {
+	var req struct {
+		ICEServers []iceServerRequest `json:"ice_servers" binding:"required"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	servers := make([]webrtc.ICEServer, 0, len(req.ICEServers))
+	for i, server := range req.ICEServers {
+		if err := validateICEServer(server); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ice_servers[%d]: %v", i, err)})
+			return
+		}
+		servers = append(servers, webrtc.ICEServer{
+			URLs:       server.URLs,
+			Username:   server.Username,
+			Credential: server.Credential,
+		})
+	}
+	if len(servers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one ICE server is required"})
+		return
+	}
+
+	h.updater.UpdateICEServers(servers)
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "updated",
+		"ice_servers": len(servers),
+	})
+}
+
+func validateICEServer(server iceServerRequest) error {
+	if len(server.URLs) == 0 {
+		return fmt.Errorf("urls is required")
+	}
+	turn := false
+	for _, url := range server.URLs {
+		scheme := ""
+		for _, s := range iceURLSchemes {
+			if strings.HasPrefix(url, s) {
+				scheme = s
+				break
+			}
+		}
+		if scheme == "" {
+			return fmt.Errorf("unsupported ICE server url %q", url)
+		}
+		turn = turn || strings.HasPrefix(scheme, "turn")
+	}
+	if turn && (server.Username == "" || server.Credential == "") {
+		return fmt.Errorf("TURN servers need a username and credential")
+	}
+	return nil
+}
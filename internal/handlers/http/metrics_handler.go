@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// P2PEfficiencySource reports the last known P2P efficiency of a stream
+// (implemented by monitoring.PrometheusCollector).
+type P2PEfficiencySource interface {
+	P2PEfficiency(streamID domain.StreamID) (float64, bool)
+}
+
+// StreamCapacityReporter reports per-instance stream usage against its cap
+// (implemented by the core stream service).
+type StreamCapacityReporter interface {
+	StreamCapacity(ctx context.Context) (active int, limit int)
+}
+
+type MetricsHandler struct {
+	streamService  ports.StreamService
+	metricsService *services.MetricsService
+	p2pSource      P2PEfficiencySource // Optional, can be nil
+}
+
+func NewMetricsHandler(
+	streamService ports.StreamService,
+	metricsService *services.MetricsService,
+	p2pSource P2PEfficiencySource,
+) *MetricsHandler {
+	return &MetricsHandler{
+		streamService:  streamService,
+		metricsService: metricsService,
+		p2pSource:      p2pSource,
+	}
+}
+
+// GetSnapshot returns a JSON snapshot of key metrics for clients that cannot scrape Prometheus.
+func (h *MetricsHandler) GetSnapshot(c *gin.Context) {
+	streams, err := h.streamService.ListStreams(c.Request.Context())
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to list streams", 500))
+		return
+	}
+
+	streamIDs := make([]domain.StreamID, 0, len(streams))
+	for _, stream := range streams {
+		streamIDs = append(streamIDs, stream.ID)
+	}
+
+	snapshot := h.metricsService.Snapshot(streamIDs)
+	if h.p2pSource != nil {
+		for i := range snapshot.Streams {
+			if efficiency, ok := h.p2pSource.P2PEfficiency(snapshot.Streams[i].StreamID); ok {
+				snapshot.Streams[i].P2PEfficiencyPercent = &efficiency
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// GetPressure reports how close this instance is to its stream capacity so
+// load balancers can steer new streams elsewhere.
+func (h *MetricsHandler) GetPressure(c *gin.Context) {
+	var active, limit int
+	if reporter, ok := h.streamService.(StreamCapacityReporter); ok {
+		active, limit = reporter.StreamCapacity(c.Request.Context())
+	} else {
+		streams, err := h.streamService.ListStreams(c.Request.Context())
+		if err != nil {
+			reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to list streams", 500))
+			return
+		}
+		active = len(streams)
+	}
+
+	response := gin.H{
+		"active_streams": active,
+		"max_streams":    limit,
+		"at_capacity":    limit > 0 && active >= limit,
+	}
+	if limit > 0 {
+		response["stream_utilization"] = float64(active) / float64(limit)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
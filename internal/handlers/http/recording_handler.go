@@ -0,0 +1,58 @@
+package http
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecordingHandler serves the recording index and recorded files of a stream
+type RecordingHandler struct {
+	index ports.RecordingIndex
+}
+
+func NewRecordingHandler(index ports.RecordingIndex) *RecordingHandler {
+	return &RecordingHandler{index: index}
+}
+
+// ListRecordings returns the stream's completed recordings, oldest first.
+func (h *RecordingHandler) ListRecordings(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+
+	recordings, err := h.index.ListByStream(c.Request.Context(), streamID)
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to list recordings", 500))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id":  streamID,
+		"recordings": recordings,
+	})
+}
+
+// DownloadRecording streams a recorded file. Range requests are honoured so
+// players can seek without fetching the whole file.
+func (h *RecordingHandler) DownloadRecording(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+	recordingID := domain.RecordingID(c.Param("recording_id"))
+
+	recording, content, err := h.index.Open(c.Request.Context(), streamID, recordingID)
+	if err != nil {
+		if goerrors.Is(err, domain.ErrRecordingNotFound) {
+			reportError(c, errors.NewNotFoundError("recording"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to open recording", 500))
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Disposition", `attachment; filename="`+recording.FileName+`"`)
+	http.ServeContent(c.Writer, c.Request, recording.FileName, recording.EndedAt, content)
+}
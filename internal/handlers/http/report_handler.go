@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReportCacheTTL bounds how often the capacity report is recomputed
+const defaultReportCacheTTL = 5 * time.Second
+
+// StreamPeerLister lists the peers of a stream (implemented by ports.PeerRepository)
+type StreamPeerLister interface {
+	FindByStream(ctx context.Context, streamID domain.StreamID) ([]*domain.Peer, error)
+}
+
+// StreamCapacity is one stream's line in the capacity report
+type StreamCapacity struct {
+	StreamID             domain.StreamID `json:"stream_id"`
+	Publishers           int             `json:"publishers"`
+	Subscribers          int             `json:"subscribers"`
+	MeshEdges            int             `json:"mesh_edges"`
+	HealthScore          float64         `json:"health_score"`
+	P2PEfficiencyPercent *float64        `json:"p2p_efficiency_percent,omitempty"`
+	EstimatedEgressKbps  int             `json:"estimated_egress_kbps"`
+}
+
+// CapacityReport summarizes current load for capacity planning
+type CapacityReport struct {
+	GeneratedAt         time.Time        `json:"generated_at"`
+	ActiveStreams       int              `json:"active_streams"`
+	TotalPeers          int              `json:"total_peers"`
+	Publishers          int              `json:"publishers"`
+	Subscribers         int              `json:"subscribers"`
+	MeshEdges           int              `json:"mesh_edges"`
+	EstimatedEgressKbps int              `json:"estimated_egress_kbps"`
+	Streams             []StreamCapacity `json:"streams"`
+}
+
+// ReportHandler serves the operator capacity report. Streams, peers and mesh
+// edges come from the repositories, so with shared (Redis) storage the report
+// covers every instance; health and P2P efficiency are this instance's view.
+type ReportHandler struct {
+	streamService  ports.StreamService
+	meshService    ports.MeshService
+	peers          StreamPeerLister
+	metricsService *services.MetricsService
+	p2pSource      P2PEfficiencySource // Optional, can be nil
+
+	mu       sync.Mutex
+	cacheTTL time.Duration
+	cached   *CapacityReport
+}
+
+func NewReportHandler(
+	streamService ports.StreamService,
+	meshService ports.MeshService,
+	peers StreamPeerLister,
+	metricsService *services.MetricsService,
+	p2pSource P2PEfficiencySource,
+) *ReportHandler {
+	return &ReportHandler{
+		streamService:  streamService,
+		meshService:    meshService,
+		peers:          peers,
+		metricsService: metricsService,
+		p2pSource:      p2pSource,
+		cacheTTL:       defaultReportCacheTTL,
+	}
+}
+
+// SetCacheTTL sets how long a computed report is served before it is rebuilt (0 disables caching)
+func (h *ReportHandler) SetCacheTTL(ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cacheTTL = ttl
+}
+
+// GetReport returns the capacity report, recomputing it at most once per cache TTL.
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.cached.GeneratedAt) < h.cacheTTL {
+		c.JSON(http.StatusOK, h.cached)
+		return
+	}
+
+	report, err := h.buildReport(c.Request.Context())
+	if err != nil {
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to build capacity report", 500))
+		return
+	}
+	h.cached = report
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ReportHandler) buildReport(ctx context.Context) (*CapacityReport, error) {
+	streams, err := h.streamService.ListStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CapacityReport{
+		GeneratedAt: time.Now(),
+		Streams:     make([]StreamCapacity, 0, len(streams)),
+	}
+
+	streamIDs := make([]domain.StreamID, 0, len(streams))
+	for _, stream := range streams {
+		if stream.Active {
+			streamIDs = append(streamIDs, stream.ID)
+		}
+	}
+	metrics := h.metricsService.Snapshot(streamIDs)
+
+	for i, streamID := range streamIDs {
+		peers, err := h.peers.FindByStream(ctx, streamID)
+		if err != nil {
+			return nil, err
+		}
+		topology, err := h.meshService.SnapshotTopology(ctx, streamID)
+		if err != nil {
+			return nil, err
+		}
+
+		line := StreamCapacity{
+			StreamID:    streamID,
+			MeshEdges:   len(topology.Edges),
+			HealthScore: metrics.Streams[i].HealthScore,
+		}
+		// The stream's bitrate is what its publishers send in
+		bitrate := 0
+		for _, peer := range peers {
+			if peer.Capabilities.IsPublisher {
+				line.Publishers++
+				bitrate += peer.Metrics.Bandwidth
+			} else {
+				line.Subscribers++
+			}
+		}
+
+		// Subscribers not served over P2P are served by the SFU
+		sfuShare := 1.0
+		if h.p2pSource != nil {
+			if efficiency, ok := h.p2pSource.P2PEfficiency(streamID); ok {
+				line.P2PEfficiencyPercent = &efficiency
+				sfuShare = 1 - efficiency/100
+			}
+		}
+		line.EstimatedEgressKbps = int(float64(bitrate*line.Subscribers) * sfuShare)
+
+		report.ActiveStreams++
+		report.Publishers += line.Publishers
+		report.Subscribers += line.Subscribers
+		report.TotalPeers += line.Publishers + line.Subscribers
+		report.MeshEdges += line.MeshEdges
+		report.EstimatedEgressKbps += line.EstimatedEgressKbps
+		report.Streams = append(report.Streams, line)
+	}
+
+	return report, nil
+}
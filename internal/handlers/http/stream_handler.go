@@ -1,23 +1,53 @@
 package http
 
 import (
+	"context"
 	goerrors "errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
 	"rillnet/pkg/errors"
 	"rillnet/pkg/utils"
 	"rillnet/pkg/validation"
+	sdputil "rillnet/pkg/webrtc"
 
 	webrtc "github.com/pion/webrtc/v3"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Page size bounds of GET /streams
+const (
+	defaultStreamPageSize = 50
+	maxStreamPageSize     = 500
+)
+
+// Result size bounds of GET /streams/:id/peers/search
+const (
+	defaultPeerSearchLimit = 100
+	maxPeerSearchLimit     = 1000
+)
+
+// maxStreamNameFilterLength bounds the name_contains filter of GET /streams
+const maxStreamNameFilterLength = 100
+
+// QualityHistorySource returns the quality switches recorded for a peer,
+// oldest first (implemented by services.AdaptiveBitrateService).
+type QualityHistorySource interface {
+	GetQualityHistory(peerID domain.PeerID) []services.QualitySnapshot
+}
+
 type StreamHandler struct {
 	streamService ports.StreamService
 	webrtcService ports.WebRTCService
+	// Adaptive quality switches per peer; nil when quality is not adapted
+	qualityHistory QualityHistorySource
+	// Removes peers from their stream; nil when kicking is not supported
+	kicker ports.PeerKicker
 }
 
 func NewStreamHandler(
@@ -37,8 +67,11 @@ func (h *StreamHandler) SetupRoutes(router *gin.Engine) {
 		api.GET("/streams/:id", h.GetStream)
 		api.POST("/streams/:id/join", h.JoinStream)
 		api.POST("/streams/:id/leave", h.LeaveStream)
+		api.POST("/streams/:id/stop", h.StopStream)
 		api.GET("/streams/:id/stats", h.GetStreamStats)
 		api.GET("/streams/:id/webrtc/ready", h.GetWebRTCReadiness)
+		api.GET("/streams/:id/peers", h.SearchPeers)
+		api.GET("/streams/:id/peers/:peerId/stats", h.GetPeerStats)
 		api.GET("/streams", h.ListStreams)
 
 		// WebRTC endpoints
@@ -54,6 +87,10 @@ func (h *StreamHandler) CreateStream(c *gin.Context) {
 		Name     string        `json:"name" binding:"required,min=3,max=100"`
 		Owner    domain.PeerID `json:"owner" binding:"required"`
 		MaxPeers int           `json:"max_peers" binding:"min=1,max=1000"`
+		// Optional; joining subscribers request a keyframe unless set to false
+		KeyframeOnJoin *bool `json:"keyframe_on_join"`
+		// Optional; 0 (the default) to domain.MaxStreamPriority
+		Priority *int `json:"priority"`
 	}
 
 	if err := c.BindJSON(&req); err != nil {
@@ -74,23 +111,173 @@ func (h *StreamHandler) CreateStream(c *gin.Context) {
 		reportError(c, errors.NewInvalidInputError(err.Error()))
 		return
 	}
+	if req.Priority != nil && (*req.Priority < 0 || *req.Priority > domain.MaxStreamPriority) {
+		reportError(c, errors.NewInvalidInputError(fmt.Sprintf("priority must be between 0 and %d", domain.MaxStreamPriority)))
+		return
+	}
+
+	// Propagate identity set by AuthMiddleware so the service can attribute ownership
+	ctx := c.Request.Context()
+	if userID, ok := c.Get("user_id"); ok {
+		ctx = context.WithValue(ctx, domain.UserIDContextKey, userID)
+	}
+	if role, ok := c.Get("role"); ok {
+		ctx = context.WithValue(ctx, domain.UserRoleContextKey, role)
+	}
 
-	// User ID is already in context from AuthMiddleware
-	stream, err := h.streamService.CreateStream(c.Request.Context(), req.Name, req.Owner, req.MaxPeers)
+	stream, err := h.streamService.CreateStream(ctx, req.Name, req.Owner, req.MaxPeers)
 	if err != nil {
 		if err == domain.ErrStreamNotFound {
 			reportError(c, errors.NewNotFoundError("stream"))
 			return
 		}
+		if goerrors.Is(err, domain.ErrStreamQuotaExceeded) {
+			reportError(c, errors.NewForbiddenError(err.Error()))
+			return
+		}
+		if goerrors.Is(err, domain.ErrInstanceAtCapacity) {
+			reportError(c, errors.NewServiceUnavailableError(err.Error()))
+			return
+		}
 		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to create stream", 500))
 		return
 	}
 
+	if policy, ok := h.webrtcService.(ports.KeyframePolicy); ok && req.KeyframeOnJoin != nil {
+		policy.SetKeyframeOnJoin(stream.ID, *req.KeyframeOnJoin)
+	}
+	// Admission and forwarding each weigh the priority
+	if req.Priority != nil {
+		for _, target := range []interface{}{h.streamService, h.webrtcService} {
+			setter, ok := target.(ports.StreamPrioritySetter)
+			if !ok {
+				continue
+			}
+			if err := setter.SetStreamPriority(ctx, stream.ID, *req.Priority); err != nil {
+				reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to set stream priority", 500))
+				return
+			}
+		}
+		stream.Priority = *req.Priority
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"stream": stream,
 	})
 }
 
+// SetKeyframeOnJoin sets whether subscribers joining the stream trigger a
+// keyframe request to its publisher
+func (h *StreamHandler) SetKeyframeOnJoin(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, ok := h.webrtcService.(ports.KeyframePolicy)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "keyframe policy is not supported"})
+		return
+	}
+
+	streamID := domain.StreamID(c.Param("id"))
+	policy.SetKeyframeOnJoin(streamID, *req.Enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id":        streamID,
+		"keyframe_on_join": policy.KeyframeOnJoin(streamID),
+	})
+}
+
+// SetSVCLayer limits the SVC temporal layers forwarded to the stream's
+// subscribers; -1 forwards all of them again
+func (h *StreamHandler) SetSVCLayer(c *gin.Context) {
+	var req struct {
+		MaxTemporalLayer *int `json:"max_temporal_layer" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, ok := h.webrtcService.(ports.SVCLayerPolicy)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "svc layer selection is not supported"})
+		return
+	}
+
+	streamID := domain.StreamID(c.Param("id"))
+	if err := policy.SetSVCTemporalLayer(streamID, *req.MaxTemporalLayer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id":          streamID,
+		"max_temporal_layer": policy.SVCTemporalLayer(streamID),
+	})
+}
+
+// SetPeerKicker sets what removes kicked peers from their stream. Must be
+// called before the handler serves requests.
+func (h *StreamHandler) SetPeerKicker(kicker ports.PeerKicker) {
+	h.kicker = kicker
+}
+
+// KickPeer removes a peer from the stream and tells it to disconnect. Only
+// the stream's owner may kick the owner's own peers.
+func (h *StreamHandler) KickPeer(c *gin.Context) {
+	if h.kicker == nil {
+		reportError(c, errors.NewAppError(errors.ErrCodeInternal, "kicking peers is not supported", http.StatusNotImplemented))
+		return
+	}
+
+	streamID := domain.StreamID(c.Param("id"))
+	if err := validation.ValidateStreamID(string(streamID)); err != nil {
+		reportError(c, errors.NewInvalidInputError(err.Error()))
+		return
+	}
+
+	stream, err := h.streamService.GetStream(c.Request.Context(), streamID)
+	if err != nil {
+		if goerrors.Is(err, domain.ErrStreamNotFound) {
+			reportError(c, errors.NewNotFoundError("stream"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to get stream", 500))
+		return
+	}
+
+	peerID := domain.PeerID(c.Param("peerId"))
+	peer, ok := h.streamPeer(c, peerID)
+	if !ok {
+		return
+	}
+	ownersPeer := peerID == stream.Owner || (stream.OwnerUserID != "" && peer.UserID == stream.OwnerUserID)
+	if ownersPeer && callerID(c) != stream.OwnerUserID {
+		reportError(c, errors.NewForbiddenError("only the stream owner can kick the owner's peers"))
+		return
+	}
+
+	if err := h.kicker.KickPeer(c.Request.Context(), streamID, peerID); err != nil {
+		if goerrors.Is(err, domain.ErrPeerNotFound) {
+			reportError(c, errors.NewNotFoundError("peer"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to kick peer", 500))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "kicked",
+		"stream_id": streamID,
+		"peer_id":   peerID,
+	})
+}
+
 func (h *StreamHandler) GetStream(c *gin.Context) {
 	streamID := domain.StreamID(c.Param("id"))
 
@@ -121,6 +308,7 @@ func (h *StreamHandler) JoinStream(c *gin.Context) {
 	var req struct {
 		PeerID       domain.PeerID `json:"peer_id" binding:"required"`
 		IsPublisher  bool          `json:"is_publisher"`
+		IsObserver   bool          `json:"is_observer"`
 		Capabilities struct {
 			MaxBitrate int      `json:"max_bitrate" binding:"min=0,max=10000000"`
 			Codecs     []string `json:"codecs"`
@@ -132,21 +320,28 @@ func (h *StreamHandler) JoinStream(c *gin.Context) {
 		return
 	}
 
-	if len(req.Capabilities.Codecs) > 20 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "too many codecs specified"})
+	if err := validation.ValidateCodecs(req.Capabilities.Codecs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.IsObserver && req.IsPublisher {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a peer cannot be both publisher and observer"})
 		return
 	}
 
 	peer := &domain.Peer{
 		ID:        req.PeerID,
 		StreamID:  streamID,
+		UserID:    callerID(c),
 		SessionID: domain.SessionID(utils.GenerateSessionID()),
 		Address:   c.ClientIP(), // In real application, actual address should be obtained
 		Capabilities: domain.PeerCapabilities{
 			MaxBitrate:      req.Capabilities.MaxBitrate,
 			SupportedCodecs: req.Capabilities.Codecs,
 			IsPublisher:     req.IsPublisher,
-			CanRelay:        true,
+			CanRelay:        !req.IsObserver,
+			IsObserver:      req.IsObserver,
 		},
 		Metrics: domain.PeerMetrics{
 			Bandwidth:   req.Capabilities.MaxBitrate,
@@ -158,13 +353,15 @@ func (h *StreamHandler) JoinStream(c *gin.Context) {
 	}
 
 	if err := h.streamService.JoinStream(c.Request.Context(), streamID, peer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeJoinError(c, err)
 		return
 	}
 
+	// max_bitrate may have been lowered to fit the stream's bandwidth budget
 	c.JSON(http.StatusOK, gin.H{
-		"session_id": peer.SessionID,
-		"status":     "joined",
+		"session_id":  peer.SessionID,
+		"status":      "joined",
+		"max_bitrate": peer.Capabilities.MaxBitrate,
 	})
 }
 
@@ -190,6 +387,31 @@ func (h *StreamHandler) LeaveStream(c *gin.Context) {
 	})
 }
 
+// StopStream ends the stream and disconnects its peers; stopping a stopped
+// stream succeeds without doing anything
+func (h *StreamHandler) StopStream(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+
+	if err := validation.ValidateStreamID(string(streamID)); err != nil {
+		reportError(c, errors.NewInvalidInputError(err.Error()))
+		return
+	}
+
+	if err := h.streamService.StopStream(c.Request.Context(), streamID); err != nil {
+		if goerrors.Is(err, domain.ErrStreamNotFound) {
+			reportError(c, errors.NewNotFoundError("stream"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to stop stream", 500))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "stopped",
+		"stream_id": streamID,
+	})
+}
+
 func (h *StreamHandler) GetStreamStats(c *gin.Context) {
 	streamID := domain.StreamID(c.Param("id"))
 
@@ -224,8 +446,185 @@ func (h *StreamHandler) GetWebRTCReadiness(c *gin.Context) {
 	})
 }
 
+// GetPeerStats returns live WebRTC stats of a peer connected to the stream
+func (h *StreamHandler) GetPeerStats(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+	peerID := domain.PeerID(c.Param("peerId"))
+
+	stats, err := h.webrtcService.GetPeerStats(peerID)
+	if err != nil {
+		writeWebRTCError(c, err)
+		return
+	}
+	if stats.StreamID != streamID {
+		writeWebRTCError(c, domain.ErrPeerNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id":      streamID,
+		"peer_id":        peerID,
+		"bytes_sent":     stats.BytesSent,
+		"bytes_received": stats.BytesReceived,
+		"packet_loss":    stats.PacketLoss,
+		"jitter_ms":      stats.Jitter.Milliseconds(),
+		"rtt_ms":         stats.RoundTripTime.Milliseconds(),
+		"updated_at":     stats.UpdatedAt,
+	})
+}
+
+// SetQualityHistory sets where peers' adaptive quality switches are read
+// from. Must be called before the handler serves requests.
+func (h *StreamHandler) SetQualityHistory(source QualityHistorySource) {
+	h.qualityHistory = source
+}
+
+// GetQualityHistory returns the peer's recent adaptive quality switches with
+// the metrics that triggered each, for debugging viewer-side quality drops
+func (h *StreamHandler) GetQualityHistory(c *gin.Context) {
+	if h.qualityHistory == nil {
+		reportError(c, errors.NewAppError(errors.ErrCodeInternal, "adaptive quality is not enabled", http.StatusNotImplemented))
+		return
+	}
+
+	streamID := domain.StreamID(c.Param("id"))
+	peerID := domain.PeerID(c.Param("peerId"))
+	if _, err := h.streamService.GetPeer(c.Request.Context(), streamID, peerID); err != nil {
+		if goerrors.Is(err, domain.ErrPeerNotFound) {
+			reportError(c, errors.NewNotFoundError("peer"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to get peer", 500))
+		return
+	}
+
+	history := h.qualityHistory.GetQualityHistory(peerID)
+	if len(history) > services.MaxQualityHistory {
+		history = history[len(history)-services.MaxQualityHistory:]
+	}
+	switches := make([]gin.H, 0, len(history))
+	for _, snapshot := range history {
+		switches = append(switches, gin.H{
+			"quality":   snapshot.Quality,
+			"timestamp": snapshot.Timestamp,
+			"metrics": gin.H{
+				"bandwidth_down_kbps":    snapshot.Metrics.BandwidthDown,
+				"bandwidth_up_kbps":      snapshot.Metrics.BandwidthUp,
+				"available_bitrate_kbps": snapshot.Metrics.AvailableBitrate,
+				"packet_loss":            snapshot.Metrics.PacketLoss,
+				"latency_ms":             snapshot.Metrics.Latency.Milliseconds(),
+				"jitter_ms":              snapshot.Metrics.Jitter.Milliseconds(),
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id": streamID,
+		"peer_id":   peerID,
+		"history":   switches,
+	})
+}
+
+// SearchPeers returns up to limit of the stream's peers matching the
+// can_relay, codec and min_bandwidth query filters
+func (h *StreamHandler) SearchPeers(c *gin.Context) {
+	streamID := domain.StreamID(c.Param("id"))
+
+	if err := validation.ValidateStreamID(string(streamID)); err != nil {
+		reportError(c, errors.NewInvalidInputError(err.Error()))
+		return
+	}
+
+	filter := domain.PeerFilter{Limit: defaultPeerSearchLimit}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPeerSearchLimit {
+			reportError(c, errors.NewInvalidInputError(fmt.Sprintf("limit must be between 1 and %d", maxPeerSearchLimit)))
+			return
+		}
+		filter.Limit = limit
+	}
+	if raw := c.Query("can_relay"); raw != "" {
+		canRelay, err := strconv.ParseBool(raw)
+		if err != nil {
+			reportError(c, errors.NewInvalidInputError("can_relay must be a boolean"))
+			return
+		}
+		filter.CanRelay = &canRelay
+	}
+	if raw := c.Query("min_bandwidth"); raw != "" {
+		minBandwidth, err := strconv.Atoi(raw)
+		if err != nil || minBandwidth < 0 {
+			reportError(c, errors.NewInvalidInputError("min_bandwidth must be a non-negative integer"))
+			return
+		}
+		filter.MinBandwidth = minBandwidth
+	}
+	if codec := c.Query("codec"); codec != "" {
+		if err := validation.ValidateCodecs([]string{codec}); err != nil {
+			reportError(c, errors.NewInvalidInputError(err.Error()))
+			return
+		}
+		filter.Codec = codec
+	}
+
+	peers, err := h.streamService.FindPeers(c.Request.Context(), streamID, filter)
+	if err != nil {
+		if goerrors.Is(err, domain.ErrStreamNotFound) {
+			reportError(c, errors.NewNotFoundError("stream"))
+			return
+		}
+		reportError(c, errors.WrapError(err, errors.ErrCodeInternal, "failed to search peers", 500))
+		return
+	}
+
+	items := make([]gin.H, 0, len(peers))
+	for _, peer := range peers {
+		items = append(items, gin.H{
+			"peer_id":      peer.ID,
+			"is_publisher": peer.Capabilities.IsPublisher,
+			"can_relay":    peer.Capabilities.CanRelay,
+			"codecs":       peer.Capabilities.SupportedCodecs,
+			"bandwidth":    peer.Metrics.Bandwidth,
+			"latency_ms":   peer.Metrics.Latency.Milliseconds(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stream_id": streamID,
+		"peers":     items,
+		"count":     len(items),
+		"limit":     filter.Limit,
+	})
+}
+
+// ListStreams returns a page of active streams, selected by the limit,
+// offset and name_contains query parameters, with the total match count
 func (h *StreamHandler) ListStreams(c *gin.Context) {
-	streams, err := h.streamService.ListStreams(c.Request.Context())
+	opts := domain.StreamListOptions{Limit: defaultStreamPageSize}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxStreamPageSize {
+			reportError(c, errors.NewInvalidInputError(fmt.Sprintf("limit must be between 1 and %d", maxStreamPageSize)))
+			return
+		}
+		opts.Limit = limit
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			reportError(c, errors.NewInvalidInputError("offset must be a non-negative integer"))
+			return
+		}
+		opts.Offset = offset
+	}
+	opts.NameContains = c.Query("name_contains")
+	if len(opts.NameContains) > maxStreamNameFilterLength {
+		reportError(c, errors.NewInvalidInputError(fmt.Sprintf("name_contains must be at most %d characters", maxStreamNameFilterLength)))
+		return
+	}
+
+	streams, total, err := h.streamService.ListStreamsPage(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -252,6 +651,9 @@ func (h *StreamHandler) ListStreams(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"streams": items,
+		"total":   total,
+		"limit":   opts.Limit,
+		"offset":  opts.Offset,
 	})
 }
 
@@ -318,6 +720,92 @@ func (h *StreamHandler) HandlePublisherAnswer(c *gin.Context) {
 	})
 }
 
+// PausePublisher suspends forwarding of a publisher's media while keeping its connection alive.
+func (h *StreamHandler) PausePublisher(c *gin.Context) {
+	h.setPublisherPaused(c, true)
+}
+
+// ResumePublisher resumes forwarding of a paused publisher's media.
+func (h *StreamHandler) ResumePublisher(c *gin.Context) {
+	h.setPublisherPaused(c, false)
+}
+
+func (h *StreamHandler) setPublisherPaused(c *gin.Context, paused bool) {
+	var req struct {
+		PeerID domain.PeerID `json:"peer_id" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.webrtcService.SetPublisherPaused(domain.StreamID(c.Param("id")), req.PeerID, paused); err != nil {
+		writeWebRTCError(c, err)
+		return
+	}
+
+	status := "resumed"
+	if paused {
+		status = "paused"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+	})
+}
+
+// RotateKeys starts a key rotation renegotiation for a peer and returns the new offer.
+// The client answers through the usual publisher/subscriber answer endpoint.
+func (h *StreamHandler) RotateKeys(c *gin.Context) {
+	var req struct {
+		PeerID domain.PeerID `json:"peer_id" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := h.streamPeer(c, req.PeerID); !ok {
+		return
+	}
+
+	offer, err := h.webrtcService.RotateKeys(c.Request.Context(), req.PeerID)
+	if err != nil {
+		writeWebRTCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"type": "offer",
+		"sdp":  offer.SDP,
+	})
+}
+
+// GetPendingRenegotiation returns a key rotation or subscription update offer waiting for the answer
+// of one of the caller's peers in the stream.
+func (h *StreamHandler) GetPendingRenegotiation(c *gin.Context) {
+	peerID := domain.PeerID(c.Query("peer_id"))
+	if peerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "peer_id is required"})
+		return
+	}
+	if _, ok := h.callerPeer(c, peerID); !ok {
+		return
+	}
+
+	offer, ok := h.webrtcService.PendingRenegotiation(peerID)
+	if !ok {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"type": "offer",
+		"sdp":  offer.SDP,
+	})
+}
+
 func (h *StreamHandler) CreateSubscriberOffer(c *gin.Context) {
 	streamID := domain.StreamID(c.Param("id"))
 
@@ -331,6 +819,16 @@ func (h *StreamHandler) CreateSubscriberOffer(c *gin.Context) {
 		return
 	}
 
+	// Observers receive events and stats only, never media
+	peer, ok := h.streamPeer(c, req.PeerID)
+	if !ok {
+		return
+	}
+	if peer.Capabilities.IsObserver {
+		c.JSON(http.StatusForbidden, gin.H{"error": "observers do not receive media"})
+		return
+	}
+
 	offer, err := h.webrtcService.CreateSubscriberOffer(c.Request.Context(), req.PeerID, streamID, req.SourcePeers)
 	if err != nil {
 		writeWebRTCError(c, err)
@@ -343,6 +841,46 @@ func (h *StreamHandler) CreateSubscriberOffer(c *gin.Context) {
 	})
 }
 
+// UpdateSubscription adds and removes tracks forwarded to one of the caller's
+// subscribers and returns the renegotiation offer, answered through the
+// subscriber answer endpoint. No content is returned when nothing changed.
+func (h *StreamHandler) UpdateSubscription(c *gin.Context) {
+	var req struct {
+		PeerID domain.PeerID    `json:"peer_id" binding:"required"`
+		Add    []domain.TrackID `json:"add"`
+		Remove []domain.TrackID `json:"remove"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updater, ok := h.webrtcService.(ports.SubscriptionUpdater)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "subscription updates are not supported"})
+		return
+	}
+	if _, ok := h.callerPeer(c, req.PeerID); !ok {
+		return
+	}
+
+	offer, err := updater.UpdateSubscription(c.Request.Context(), req.PeerID, req.Add, req.Remove)
+	if err != nil {
+		writeWebRTCError(c, err)
+		return
+	}
+	if offer.SDP == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"type": "offer",
+		"sdp":  offer.SDP,
+	})
+}
+
 func (h *StreamHandler) HandleSubscriberAnswer(c *gin.Context) {
 	_ = domain.StreamID(c.Param("id")) // streamID for potential future use
 
@@ -366,6 +904,157 @@ func (h *StreamHandler) HandleSubscriberAnswer(c *gin.Context) {
 	})
 }
 
+// AddICECandidate applies a candidate trickled by the client. Candidates sent
+// before the answer are buffered by the SFU until the answer is applied.
+func (h *StreamHandler) AddICECandidate(c *gin.Context) {
+	var req struct {
+		PeerID    domain.PeerID           `json:"peer_id" binding:"required"`
+		Candidate webrtc.ICECandidateInit `json:"candidate" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only the caller's own peers in this stream may add candidates
+	if _, ok := h.callerPeer(c, req.PeerID); !ok {
+		return
+	}
+
+	if err := h.webrtcService.AddICECandidate(c.Request.Context(), req.PeerID, req.Candidate); err != nil {
+		writeWebRTCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "candidate_accepted",
+	})
+}
+
+// CreatePreconnect opens a subscriber connection before the viewer picks a
+// stream. The returned handle is bound to a stream with BindPreconnect.
+func (h *StreamHandler) CreatePreconnect(c *gin.Context) {
+	var req struct {
+		PeerID domain.PeerID `json:"peer_id" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preconnect, err := h.webrtcService.CreatePreconnect(c.Request.Context(), callerID(c), req.PeerID)
+	if err != nil {
+		writeWebRTCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preconnect)
+}
+
+// BindPreconnect attaches a preconnect to the :id stream's subscriber flow
+// using the client's answer to the preconnect offer. A peer that has not
+// joined the stream yet is admitted first, as JoinStream would admit it.
+func (h *StreamHandler) BindPreconnect(c *gin.Context) {
+	ctx := c.Request.Context()
+	streamID := domain.StreamID(c.Param("id"))
+	handle := c.Param("handle")
+
+	var req struct {
+		PeerID domain.PeerID             `json:"peer_id" binding:"required"`
+		Answer webrtc.SessionDescription `json:"answer" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	joined := false
+	peer, err := h.streamService.GetPeer(ctx, streamID, req.PeerID)
+	switch {
+	case goerrors.Is(err, domain.ErrPeerNotFound):
+		peer = &domain.Peer{
+			ID:           req.PeerID,
+			StreamID:     streamID,
+			UserID:       callerID(c),
+			SessionID:    domain.SessionID(utils.GenerateSessionID()),
+			Address:      c.ClientIP(),
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+		}
+		if err := h.streamService.JoinStream(ctx, streamID, peer); err != nil {
+			writeJoinError(c, err)
+			return
+		}
+		joined = true
+	case err != nil:
+		writeWebRTCError(c, err)
+		return
+	case peer.UserID == "" || peer.UserID != callerID(c):
+		writeWebRTCError(c, domain.ErrPeerNotFound)
+		return
+	case peer.Capabilities.IsPublisher || peer.Capabilities.IsObserver:
+		c.JSON(http.StatusForbidden, gin.H{"error": "only subscribers bind preconnects"})
+		return
+	}
+
+	if err := h.webrtcService.BindPreconnect(ctx, handle, req.PeerID, streamID, req.Answer); err != nil {
+		if joined {
+			_ = h.streamService.LeaveStream(ctx, streamID, req.PeerID)
+		}
+		writeWebRTCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "bound",
+		"stream_id":   streamID,
+		"max_bitrate": peer.Capabilities.MaxBitrate,
+	})
+}
+
+// streamPeer loads a peer that joined the :id stream, writing the error
+// response and returning false when there is none
+func (h *StreamHandler) streamPeer(c *gin.Context, peerID domain.PeerID) (*domain.Peer, bool) {
+	peer, err := h.streamService.GetPeer(c.Request.Context(), domain.StreamID(c.Param("id")), peerID)
+	if err != nil {
+		writeWebRTCError(c, err)
+		return nil, false
+	}
+	return peer, true
+}
+
+// callerPeer is streamPeer restricted to peers joined by the authenticated
+// caller; other users' peers are reported as not found
+func (h *StreamHandler) callerPeer(c *gin.Context, peerID domain.PeerID) (*domain.Peer, bool) {
+	peer, ok := h.streamPeer(c, peerID)
+	if !ok {
+		return nil, false
+	}
+	if userID := callerID(c); userID == "" || peer.UserID != userID {
+		writeWebRTCError(c, domain.ErrPeerNotFound)
+		return nil, false
+	}
+	return peer, true
+}
+
+// callerID returns the user set by AuthMiddleware, or "" when there is none
+func callerID(c *gin.Context) domain.UserID {
+	value, _ := c.Get("user_id")
+	userID, _ := value.(domain.UserID)
+	return userID
+}
+
+// writeJoinError maps a stream admission failure to its response
+func writeJoinError(c *gin.Context, err error) {
+	if goerrors.Is(err, domain.ErrStreamBandwidthExceeded) || goerrors.Is(err, domain.ErrInstanceAtCapacity) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
 func writeWebRTCError(c *gin.Context, err error) {
 	if goerrors.Is(err, domain.ErrNoPublisherMedia) {
 		c.JSON(http.StatusConflict, gin.H{
@@ -374,9 +1063,23 @@ func writeWebRTCError(c *gin.Context, err error) {
 		})
 		return
 	}
-	if goerrors.Is(err, domain.ErrPeerNotFound) {
+	if goerrors.Is(err, domain.ErrPeerNotFound) || goerrors.Is(err, domain.ErrPreconnectNotFound) ||
+		goerrors.Is(err, domain.ErrTrackNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	if goerrors.Is(err, domain.ErrRenegotiationPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if goerrors.Is(err, sdputil.ErrMediaDirectionMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if goerrors.Is(err, domain.ErrCandidateQueueFull) || goerrors.Is(err, domain.ErrTooManyCandidates) ||
+		goerrors.Is(err, domain.ErrTooManyPreconnects) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
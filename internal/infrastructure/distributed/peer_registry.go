@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"rillnet/internal/core/domain"
@@ -13,6 +14,11 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	defaultPeerTTL       = 5 * time.Minute
+	defaultPeerTTLJitter = 30 * time.Second
+)
+
 // SharedPeerRegistry provides shared peer registry across instances
 type SharedPeerRegistry struct {
 	client     *redis.Client
@@ -20,6 +26,10 @@ type SharedPeerRegistry struct {
 	instanceID string
 	logger     *zap.SugaredLogger
 	prefix     string
+
+	// Peer keys expire after ttl ± ttlJitter so a burst of joins does not expire all at once
+	ttl       time.Duration
+	ttlJitter time.Duration
 }
 
 // NewSharedPeerRegistry creates a new shared peer registry
@@ -34,7 +44,48 @@ func NewSharedPeerRegistry(
 		instanceID:  instanceID,
 		logger:      logger,
 		prefix:      "rillnet:peer:",
+		ttl:         defaultPeerTTL,
+		ttlJitter:   defaultPeerTTLJitter,
+	}
+}
+
+// SetTTL sets the nominal peer key TTL and the random jitter applied around it
+func (r *SharedPeerRegistry) SetTTL(ttl, jitter time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > ttl/2 {
+		jitter = ttl / 2
+	}
+	r.ttl = ttl
+	r.ttlJitter = jitter
+}
+
+// RefreshInterval returns a jittered interval for calling RefreshPeer,
+// comfortably shorter than the shortest possible key TTL.
+func (r *SharedPeerRegistry) RefreshInterval() time.Duration {
+	return jitterDuration((r.ttl-r.ttlJitter)/2, r.ttlJitter/4)
+}
+
+// peerTTL returns the TTL for a peer key: ttl ± ttlJitter
+func (r *SharedPeerRegistry) peerTTL() time.Duration {
+	return jitterDuration(r.ttl, r.ttlJitter)
+}
+
+// setTTL returns the TTL for stream/instance sets, which must outlive the peer keys they index
+func (r *SharedPeerRegistry) setTTL() time.Duration {
+	return jitterDuration(2*r.ttl, r.ttlJitter)
+}
+
+// jitterDuration returns a uniformly random duration in [base-jitter, base+jitter]
+func jitterDuration(base, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return base
 	}
+	return base - jitter + time.Duration(rand.Int64N(int64(2*jitter)+1))
 }
 
 // RegisterPeer registers a peer in the shared registry
@@ -59,8 +110,8 @@ func (r *SharedPeerRegistry) RegisterPeer(ctx context.Context, peer *domain.Peer
 		return fmt.Errorf("failed to marshal peer data: %w", err)
 	}
 
-	// Store with TTL (e.g., 5 minutes)
-	if err := r.client.Set(ctx, key, peerDataJSON, 5*time.Minute).Err(); err != nil {
+	// Store with jittered TTL
+	if err := r.client.Set(ctx, key, peerDataJSON, r.peerTTL()).Err(); err != nil {
 		return fmt.Errorf("failed to register peer: %w", err)
 	}
 
@@ -71,7 +122,7 @@ func (r *SharedPeerRegistry) RegisterPeer(ctx context.Context, peer *domain.Peer
 			return fmt.Errorf("failed to add peer to stream set: %w", err)
 		}
 		// Set expiration on stream set
-		r.client.Expire(ctx, streamKey, 10*time.Minute)
+		r.client.Expire(ctx, streamKey, r.setTTL())
 	}
 
 	// Add to instance peers set
@@ -79,7 +130,7 @@ func (r *SharedPeerRegistry) RegisterPeer(ctx context.Context, peer *domain.Peer
 	if err := r.client.SAdd(ctx, instanceKey, string(peer.ID)).Err(); err != nil {
 		return fmt.Errorf("failed to add peer to instance set: %w", err)
 	}
-	r.client.Expire(ctx, instanceKey, 10*time.Minute)
+	r.client.Expire(ctx, instanceKey, r.setTTL())
 
 	return nil
 }
@@ -154,6 +205,29 @@ func (r *SharedPeerRegistry) GetPeer(ctx context.Context, peerID domain.PeerID)
 	return &peer, nil
 }
 
+// GetPeerInstance returns the ID of the instance a peer is connected to
+func (r *SharedPeerRegistry) GetPeerInstance(ctx context.Context, peerID domain.PeerID) (string, error) {
+	key := r.peerKey(peerID)
+	peerDataJSON, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", fmt.Errorf("peer not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get peer: %w", err)
+	}
+
+	var peerData map[string]interface{}
+	if err := json.Unmarshal([]byte(peerDataJSON), &peerData); err != nil {
+		return "", fmt.Errorf("failed to unmarshal peer data: %w", err)
+	}
+
+	instanceID, ok := peerData["instance_id"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid peer data format")
+	}
+	return instanceID, nil
+}
+
 // FindPeersByStream finds all peers in a stream across all instances
 func (r *SharedPeerRegistry) FindPeersByStream(ctx context.Context, streamID domain.StreamID) ([]*domain.Peer, error) {
 	streamKey := r.streamPeersKey(streamID)
@@ -194,7 +268,7 @@ func (r *SharedPeerRegistry) GetInstancePeers(ctx context.Context, instanceID st
 // RefreshPeer refreshes the TTL of a peer registration
 func (r *SharedPeerRegistry) RefreshPeer(ctx context.Context, peerID domain.PeerID) error {
 	key := r.peerKey(peerID)
-	return r.client.Expire(ctx, key, 5*time.Minute).Err()
+	return r.client.Expire(ctx, key, r.peerTTL()).Err()
 }
 
 // CleanupInstancePeers cleans up peers for a specific instance (e.g., on shutdown)
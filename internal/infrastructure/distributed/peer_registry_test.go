@@ -0,0 +1,38 @@
+package distributed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSharedPeerRegistry_JitteredTTL(t *testing.T) {
+	registry := NewSharedPeerRegistry(nil, "instance-test", zap.NewNop().Sugar())
+
+	ttl := 2 * time.Minute
+	jitter := 15 * time.Second
+	registry.SetTTL(ttl, jitter)
+
+	seen := make(map[time.Duration]struct{})
+	for i := 0; i < 1000; i++ {
+		got := registry.peerTTL()
+		assert.GreaterOrEqual(t, got, ttl-jitter)
+		assert.LessOrEqual(t, got, ttl+jitter)
+		seen[got] = struct{}{}
+
+		interval := registry.RefreshInterval()
+		assert.Greater(t, interval, time.Duration(0))
+		assert.Less(t, interval, ttl-jitter, "refresh must happen before the shortest possible expiry")
+	}
+	assert.Greater(t, len(seen), 1, "TTLs should be spread out, not fixed")
+}
+
+func TestSharedPeerRegistry_ZeroJitterUsesNominalTTL(t *testing.T) {
+	registry := NewSharedPeerRegistry(nil, "instance-test", zap.NewNop().Sugar())
+	registry.SetTTL(time.Minute, 0)
+
+	assert.Equal(t, time.Minute, registry.peerTTL())
+	assert.Equal(t, 30*time.Second, registry.RefreshInterval())
+}
@@ -0,0 +1,339 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// sfuCommandChannel carries requests from signal instances to the ingest SFUs
+const sfuCommandChannel = "rillnet:sfu:commands"
+
+// sfuEventChannel carries notifications from the ingest SFUs to the signal instances
+const sfuEventChannel = "rillnet:sfu:events"
+
+// sfuPublishTimeout bounds the publish of one bridge message
+const sfuPublishTimeout = 2 * time.Second
+
+// ErrNoSFUListening is returned when no ingest instance receives a command
+var ErrNoSFUListening = errors.New("no SFU is listening for commands")
+
+// ErrNoSignalListening is returned when no signal instance receives an SFU event
+var ErrNoSignalListening = errors.New("no signal server is listening for SFU events")
+
+const (
+	sfuCommandSetPaused   = "set_paused"
+	sfuCommandSetInterest = "set_interest"
+
+	sfuEventICECandidate  = "ice_candidate"
+	sfuEventPeerLeft      = "peer_left"
+	sfuEventPeerKicked    = "peer_kicked"
+	sfuEventStreamStopped = "stream_stopped"
+	sfuEventStreamReset   = "stream_reset"
+	sfuEventLayers        = "layers_available"
+)
+
+// sfuCommand is a signaling-side request for whichever SFU holds a peer
+type sfuCommand struct {
+	Type         string          `json:"type"`
+	StreamID     domain.StreamID `json:"stream_id"`
+	PeerID       domain.PeerID   `json:"peer_id"`
+	Paused       bool            `json:"paused,omitempty"`
+	ActiveTracks []string        `json:"active_tracks,omitempty"`
+}
+
+// sfuEvent is an SFU notification for whichever signal instance holds a peer,
+// or for the members of a stream. A nil Candidate in an ice_candidate event
+// marks end-of-candidates.
+type sfuEvent struct {
+	Type      string                   `json:"type"`
+	PeerID    domain.PeerID            `json:"peer_id"`
+	StreamID  domain.StreamID          `json:"stream_id,omitempty"`
+	Candidate *webrtc.ICECandidateInit `json:"candidate,omitempty"`
+	Layers    []string                 `json:"layers,omitempty"`
+}
+
+// SFUCommandClient forwards signaling requests to the ingest SFUs over Redis
+// pub/sub, since signal and ingest run as separate processes
+type SFUCommandClient struct {
+	client *redis.Client
+}
+
+// NewSFUCommandClient creates a client publishing to the SFU command channel
+func NewSFUCommandClient(client *redis.Client) *SFUCommandClient {
+	return &SFUCommandClient{client: client}
+}
+
+// SetPublisherPaused asks the SFU holding the publisher to pause or resume
+// forwarding its media
+func (c *SFUCommandClient) SetPublisherPaused(streamID domain.StreamID, peerID domain.PeerID, paused bool) error {
+	return c.publish(sfuCommand{
+		Type:     sfuCommandSetPaused,
+		StreamID: streamID,
+		PeerID:   peerID,
+		Paused:   paused,
+	})
+}
+
+// SetSubscriberInterest asks the SFU holding the subscriber to forward only
+// the video tracks it is displaying (ports.SubscriberInterestSetter)
+func (c *SFUCommandClient) SetSubscriberInterest(ctx context.Context, peerID domain.PeerID, activeTracks []string) error {
+	return c.publish(sfuCommand{
+		Type:         sfuCommandSetInterest,
+		PeerID:       peerID,
+		ActiveTracks: activeTracks,
+	})
+}
+
+func (c *SFUCommandClient) publish(cmd sfuCommand) error {
+	receivers, err := publishBridge(c.client, sfuCommandChannel, cmd)
+	if err != nil {
+		return fmt.Errorf("failed to publish SFU command: %w", err)
+	}
+	if receivers == 0 {
+		return ErrNoSFUListening
+	}
+	return nil
+}
+
+// publishBridge publishes one bridge message and returns how many
+// subscribers received it
+func publishBridge(client *redis.Client, channel string, msg interface{}) (int64, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sfuPublishTimeout)
+	defer cancel()
+
+	return client.Publish(ctx, channel, data).Result()
+}
+
+// SFUCommandTarget is the SFU side of the command channel
+type SFUCommandTarget interface {
+	SetPublisherPaused(streamID domain.StreamID, peerID domain.PeerID, paused bool) error
+	SetSubscriberInterest(ctx context.Context, peerID domain.PeerID, activeTracks []string) error
+}
+
+// ListenSFUCommands applies commands published by signal instances to target
+// until ctx is done. Every ingest instance receives every command; those not
+// holding the peer ignore it.
+func ListenSFUCommands(ctx context.Context, client *redis.Client, target SFUCommandTarget, logger *zap.SugaredLogger) {
+	listenBridge(ctx, client, sfuCommandChannel, logger, func(payload []byte) {
+		var cmd sfuCommand
+		if err := json.Unmarshal(payload, &cmd); err != nil {
+			logger.Warnw("failed to unmarshal SFU command", "error", err)
+			return
+		}
+		if err := applySFUCommand(target, cmd); err != nil {
+			if errors.Is(err, domain.ErrPeerNotFound) {
+				logger.Debugw("SFU command for peer not held here", "type", cmd.Type, "peer_id", cmd.PeerID)
+				return
+			}
+			logger.Warnw("failed to apply SFU command", "type", cmd.Type, "peer_id", cmd.PeerID, "error", err)
+		}
+	})
+}
+
+// listenBridge passes each message on channel to handle until ctx is done
+func listenBridge(ctx context.Context, client *redis.Client, channel string, logger *zap.SugaredLogger, handle func(payload []byte)) {
+	// Subscribe before returning so no message published after this is missed
+	pubsub := client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		logger.Warnw("failed to subscribe to SFU bridge channel", "channel", channel, "error", err)
+	}
+
+	go func() {
+		defer func() { _ = pubsub.Close() }()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				handle([]byte(msg.Payload))
+			}
+		}
+	}()
+}
+
+func applySFUCommand(target SFUCommandTarget, cmd sfuCommand) error {
+	switch cmd.Type {
+	case sfuCommandSetPaused:
+		return target.SetPublisherPaused(cmd.StreamID, cmd.PeerID, cmd.Paused)
+	case sfuCommandSetInterest:
+		// An empty list is meaningful (display nothing) and must not become nil
+		activeTracks := cmd.ActiveTracks
+		if activeTracks == nil {
+			activeTracks = []string{}
+		}
+		return target.SetSubscriberInterest(context.Background(), cmd.PeerID, activeTracks)
+	default:
+		return fmt.Errorf("unknown SFU command %q", cmd.Type)
+	}
+}
+
+// SFUEventPublisher sends SFU notifications to the signal instances over
+// Redis pub/sub (ports.ICECandidateSink, ports.PeerLeftNotifier,
+// ports.StreamStopNotifier, ports.StreamResetNotifier, ports.PeerKickNotifier,
+// ports.LayersNotifier)
+type SFUEventPublisher struct {
+	client *redis.Client
+}
+
+// NewSFUEventPublisher creates a publisher on the SFU event channel
+func NewSFUEventPublisher(client *redis.Client) *SFUEventPublisher {
+	return &SFUEventPublisher{client: client}
+}
+
+// SendICECandidate pushes an SFU-gathered candidate to the peer through
+// whichever signal instance it is connected to
+func (p *SFUEventPublisher) SendICECandidate(peerID domain.PeerID, candidate *webrtc.ICECandidateInit) error {
+	return p.publish(sfuEvent{
+		Type:      sfuEventICECandidate,
+		PeerID:    peerID,
+		Candidate: candidate,
+	})
+}
+
+// NotifyPeerLeft tells the stream's peers on every signal instance that the
+// SFU dropped a peer
+func (p *SFUEventPublisher) NotifyPeerLeft(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error {
+	return p.publish(sfuEvent{
+		Type:     sfuEventPeerLeft,
+		PeerID:   peerID,
+		StreamID: streamID,
+	})
+}
+
+// NotifyPeerKicked tells the kicked peer, through whichever signal instance
+// it is connected to, to disconnect
+func (p *SFUEventPublisher) NotifyPeerKicked(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error {
+	return p.publish(sfuEvent{
+		Type:     sfuEventPeerKicked,
+		PeerID:   peerID,
+		StreamID: streamID,
+	})
+}
+
+// NotifyStreamStopped tells the stream's peers on every signal instance that
+// the stream was stopped
+func (p *SFUEventPublisher) NotifyStreamStopped(ctx context.Context, streamID domain.StreamID) error {
+	return p.publish(sfuEvent{
+		Type:     sfuEventStreamStopped,
+		StreamID: streamID,
+	})
+}
+
+// NotifyStreamReset tells the stream's peers on every signal instance that
+// the stream's media plane was reset and they should join again
+func (p *SFUEventPublisher) NotifyStreamReset(ctx context.Context, streamID domain.StreamID) error {
+	return p.publish(sfuEvent{
+		Type:     sfuEventStreamReset,
+		StreamID: streamID,
+	})
+}
+
+// NotifyLayersAvailable tells the stream's peers on every signal instance
+// which simulcast layers a publisher is producing
+func (p *SFUEventPublisher) NotifyLayersAvailable(ctx context.Context, streamID domain.StreamID, publisherID domain.PeerID, layers []string) error {
+	return p.publish(sfuEvent{
+		Type:     sfuEventLayers,
+		PeerID:   publisherID,
+		StreamID: streamID,
+		Layers:   layers,
+	})
+}
+
+func (p *SFUEventPublisher) publish(event sfuEvent) error {
+	receivers, err := publishBridge(p.client, sfuEventChannel, event)
+	if err != nil {
+		return fmt.Errorf("failed to publish SFU event: %w", err)
+	}
+	if receivers == 0 {
+		return ErrNoSignalListening
+	}
+	return nil
+}
+
+// SFUEventTarget is the signaling side of the event channel
+type SFUEventTarget interface {
+	IsPeerConnected(peerID domain.PeerID) bool
+	SendICECandidate(peerID domain.PeerID, candidate *webrtc.ICECandidateInit) error
+	// NotifyLocalPeerLeft tells only this instance's members of the stream,
+	// since every instance receives the event
+	NotifyLocalPeerLeft(streamID domain.StreamID, peerID domain.PeerID)
+	// NotifyLocalPeerKicked disconnects a kicked peer connected to this instance
+	NotifyLocalPeerKicked(streamID domain.StreamID, peerID domain.PeerID)
+	// NotifyLocalStreamStopped likewise tells only this instance's members
+	NotifyLocalStreamStopped(streamID domain.StreamID)
+	// NotifyLocalStreamReset likewise tells only this instance's members
+	NotifyLocalStreamReset(streamID domain.StreamID)
+	// NotifyLocalLayersAvailable likewise tells only this instance's members
+	NotifyLocalLayersAvailable(streamID domain.StreamID, publisherID domain.PeerID, layers []string)
+}
+
+// ListenSFUEvents delivers events published by the ingest SFUs to target
+// until ctx is done. Every signal instance receives every event; peer events
+// are delivered by the instance the peer is connected to, stream events by
+// each instance to its own members.
+func ListenSFUEvents(ctx context.Context, client *redis.Client, target SFUEventTarget, logger *zap.SugaredLogger) {
+	listenBridge(ctx, client, sfuEventChannel, logger, func(payload []byte) {
+		var event sfuEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			logger.Warnw("failed to unmarshal SFU event", "error", err)
+			return
+		}
+		if err := applySFUEvent(target, event); err != nil {
+			logger.Warnw("failed to deliver SFU event", "type", event.Type, "peer_id", event.PeerID, "error", err)
+		}
+	})
+}
+
+func applySFUEvent(target SFUEventTarget, event sfuEvent) error {
+	switch event.Type {
+	case sfuEventICECandidate:
+		if !target.IsPeerConnected(event.PeerID) {
+			return nil
+		}
+		return target.SendICECandidate(event.PeerID, event.Candidate)
+	case sfuEventPeerLeft:
+		target.NotifyLocalPeerLeft(event.StreamID, event.PeerID)
+		return nil
+	case sfuEventPeerKicked:
+		if !target.IsPeerConnected(event.PeerID) {
+			return nil
+		}
+		target.NotifyLocalPeerKicked(event.StreamID, event.PeerID)
+		return nil
+	case sfuEventStreamStopped:
+		target.NotifyLocalStreamStopped(event.StreamID)
+		return nil
+	case sfuEventStreamReset:
+		target.NotifyLocalStreamReset(event.StreamID)
+		return nil
+	case sfuEventLayers:
+		// No layers left is meaningful and must not become nil
+		layers := event.Layers
+		if layers == nil {
+			layers = []string{}
+		}
+		target.NotifyLocalLayersAvailable(event.StreamID, event.PeerID, layers)
+		return nil
+	default:
+		return fmt.Errorf("unknown SFU event %q", event.Type)
+	}
+}
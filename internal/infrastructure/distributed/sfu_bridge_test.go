@@ -0,0 +1,44 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// interestTarget records the interest commands applied to it
+type interestTarget struct {
+	peerID       domain.PeerID
+	activeTracks []string
+}
+
+func (t *interestTarget) SetPublisherPaused(streamID domain.StreamID, peerID domain.PeerID, paused bool) error {
+	return nil
+}
+
+func (t *interestTarget) SetSubscriberInterest(ctx context.Context, peerID domain.PeerID, activeTracks []string) error {
+	t.peerID = peerID
+	t.activeTracks = activeTracks
+	return nil
+}
+
+func TestApplySFUCommand_SetInterestSurvivesTheBridge(t *testing.T) {
+	for _, tracks := range [][]string{{"video-a", "video-b"}, {}} {
+		data, err := json.Marshal(sfuCommand{Type: sfuCommandSetInterest, PeerID: "viewer", ActiveTracks: tracks})
+		require.NoError(t, err)
+		var cmd sfuCommand
+		require.NoError(t, json.Unmarshal(data, &cmd))
+
+		target := &interestTarget{}
+		require.NoError(t, applySFUCommand(target, cmd))
+		assert.Equal(t, domain.PeerID("viewer"), target.peerID)
+		// An empty list pauses every video track rather than meaning "unset"
+		assert.NotNil(t, target.activeTracks)
+		assert.Equal(t, tracks, target.activeTracks)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -11,7 +12,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// AuthMiddleware authenticates callers by a JWT access token
+// ("Authorization: Bearer <token>") or a configured API key
+// ("Authorization: ApiKey <key>")
 func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
+	return authMiddleware(authService, true)
+}
+
+// JWTAuthMiddleware is AuthMiddleware for routes meant for interactive users
+// only, rejecting API keys
+func JWTAuthMiddleware(authService services.AuthService) gin.HandlerFunc {
+	return authMiddleware(authService, false)
+}
+
+func authMiddleware(authService services.AuthService, allowAPIKey bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip auth middleware for auth endpoints (register, login, refresh)
 		path := c.Request.URL.Path
@@ -28,14 +42,24 @@ func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
 		}
 
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
 			c.Abort()
 			return
 		}
 
-		token := parts[1]
-		claims, err := authService.ValidateToken(token)
+		var claims *services.Claims
+		var err error
+		if parts[0] == "ApiKey" {
+			if !allowAPIKey {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not accepted on this route"})
+				c.Abort()
+				return
+			}
+			claims, err = authService.ValidateAPIKey(parts[1])
+		} else {
+			claims, err = authService.ValidateToken(parts[1])
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
@@ -45,6 +69,9 @@ func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
 		// Store user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		if claims.Role != "" {
+			c.Set("role", claims.Role)
+		}
 		c.Next()
 	}
 }
@@ -70,6 +97,29 @@ func OptionalAuthMiddleware(authService services.AuthService) gin.HandlerFunc {
 	}
 }
 
+// RoleMiddleware only lets through callers whose access token carries the
+// given global role. Must run after AuthMiddleware.
+func RoleMiddleware(requiredRole domain.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("user_id"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			c.Abort()
+			return
+		}
+
+		role, _ := c.Get("role")
+		if role != requiredRole {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// StreamPermissionMiddleware only lets through callers whose role in the
+// stream includes requiredRole (owner > moderator > viewer). Must run after
+// AuthMiddleware.
 func StreamPermissionMiddleware(authService services.AuthService, requiredRole domain.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get user ID from context (set by AuthMiddleware)
@@ -107,6 +157,11 @@ func StreamPermissionMiddleware(authService services.AuthService, requiredRole d
 		// Create context with user_id for auth service
 		ctx := context.WithValue(c.Request.Context(), domain.UserIDContextKey, userID)
 		if err := authService.CheckStreamPermission(ctx, userID, streamID, requiredRole); err != nil {
+			if errors.Is(err, domain.ErrStreamNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "stream not found"})
+				c.Abort()
+				return
+			}
 			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
 			c.Abort()
 			return
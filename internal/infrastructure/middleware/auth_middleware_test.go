@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAPIKey = "ingest-bot-key"
+
+func newAPIKeyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	authService := services.NewAuthService("api-key-test-secret", time.Minute, time.Hour, nil, nil, nil)
+	authService.(services.APIKeyHooks).SetAPIKeys([]services.APIKey{
+		{Hash: sha256.Sum256([]byte(testAPIKey)), UserID: "ingest-bot", Role: domain.RoleOperator},
+	})
+
+	whoami := func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		role, _ := c.Get("role")
+		c.JSON(http.StatusOK, gin.H{"user_id": userID, "role": role})
+	}
+
+	router := gin.New()
+	router.GET("/any", AuthMiddleware(authService), whoami)
+	router.GET("/interactive", JWTAuthMiddleware(authService), whoami)
+	return router
+}
+
+func getWithAuthorization(router *gin.Engine, path, authorization string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", authorization)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware_AcceptsValidAPIKey(t *testing.T) {
+	w := getWithAuthorization(newAPIKeyRouter(), "/any", "ApiKey "+testAPIKey)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); body != `{"role":"operator","user_id":"ingest-bot"}` {
+		t.Fatalf("expected the key's user and role, got %s", body)
+	}
+}
+
+func TestAuthMiddleware_RejectsUnknownAPIKey(t *testing.T) {
+	w := getWithAuthorization(newAPIKeyRouter(), "/any", "ApiKey not-a-configured-key")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", w.Code)
+	}
+}
+
+func TestJWTAuthMiddleware_RejectsAPIKey(t *testing.T) {
+	w := getWithAuthorization(newAPIKeyRouter(), "/interactive", "ApiKey "+testAPIKey)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 for an API key on a JWT-only route, got %d", w.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newBodyLimitRouter(maxBytes int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(NewBodyLimitMiddleware(maxBytes))
+	api.POST("/echo", func(c *gin.Context) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"name": req.Name})
+	})
+	return router
+}
+
+func oversizedJSON(size int) string {
+	return `{"name": "` + strings.Repeat("a", size) + `"}`
+}
+
+func TestBodyLimitMiddleware_RejectsOversizedBody(t *testing.T) {
+	router := newBodyLimitRouter(64)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/echo", strings.NewReader(oversizedJSON(1024)))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d", w.Code)
+	}
+}
+
+func TestBodyLimitMiddleware_RejectsOversizedChunkedBody(t *testing.T) {
+	router := newBodyLimitRouter(64)
+
+	w := httptest.NewRecorder()
+	// An unknown length is how chunked requests arrive
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/echo", strings.NewReader(oversizedJSON(1024)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413 rather than a binding error, got %d", w.Code)
+	}
+}
+
+func TestBodyLimitMiddleware_AllowsBodyWithinLimit(t *testing.T) {
+	router := newBodyLimitRouter(64)
+
+	for _, chunked := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/echo", strings.NewReader(`{"name": "ok"}`))
+		if chunked {
+			req.ContentLength = -1
+		}
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("chunked=%v: expected status 200, got %d", chunked, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"ok"`) {
+			t.Fatalf("chunked=%v: expected bound body to be echoed, got %s", chunked, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionStreams serves a single stream owned by owner-user, on which
+// moderator-user was granted the moderator role
+type permissionStreams struct {
+	ports.StreamService
+}
+
+func (permissionStreams) GetStream(ctx context.Context, streamID domain.StreamID) (*domain.Stream, error) {
+	if streamID != "stream-1" {
+		return nil, domain.ErrStreamNotFound
+	}
+	return &domain.Stream{
+		ID:          streamID,
+		OwnerUserID: "owner-user",
+		Permissions: []domain.StreamPermission{
+			{StreamID: streamID, UserID: "moderator-user", Role: domain.RoleModerator},
+		},
+	}, nil
+}
+
+// newPermissionRouter guards one route per action with the role the ingest
+// server requires for it, trusting the X-User header as the caller
+func newPermissionRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	authService := services.NewAuthService("permission-test-secret", time.Minute, time.Hour, permissionStreams{}, nil, nil)
+	asCaller := func(c *gin.Context) {
+		c.Set("user_id", domain.UserID(c.GetHeader("X-User")))
+	}
+	ok := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+
+	router := gin.New()
+	streams := router.Group("/streams", asCaller)
+	streams.GET("/:id/peers", StreamPermissionMiddleware(authService, domain.RoleViewer), ok)
+	streams.POST("/:id/peers/:peerId/kick", StreamPermissionMiddleware(authService, domain.RoleModerator), ok)
+	streams.PUT("/:id/keyframe-on-join", StreamPermissionMiddleware(authService, domain.RoleModerator), ok)
+	streams.POST("/:id/stop", StreamPermissionMiddleware(authService, domain.RoleOwner), ok)
+	return router
+}
+
+func TestStreamPermissionMiddleware_RoleOrdering(t *testing.T) {
+	actions := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"view", http.MethodGet, "/streams/stream-1/peers"},
+		{"kick", http.MethodPost, "/streams/stream-1/peers/peer-1/kick"},
+		{"settings", http.MethodPut, "/streams/stream-1/keyframe-on-join"},
+		{"stop", http.MethodPost, "/streams/stream-1/stop"},
+	}
+	allowed := map[string]map[string]bool{
+		"owner-user":     {"view": true, "kick": true, "settings": true, "stop": true},
+		"moderator-user": {"view": true, "kick": true, "settings": true, "stop": false},
+		"viewer-user":    {"view": true, "kick": false, "settings": false, "stop": false},
+	}
+
+	router := newPermissionRouter()
+	for user, byAction := range allowed {
+		for _, action := range actions {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(action.method, action.path, nil)
+			req.Header.Set("X-User", user)
+			router.ServeHTTP(w, req)
+
+			want := http.StatusForbidden
+			if byAction[action.name] {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Fatalf("%s on %s: expected status %d, got %d", user, action.name, want, w.Code)
+			}
+		}
+	}
+}
+
+func TestStreamPermissionMiddleware_UnknownStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/streams/missing/stop", nil)
+	req.Header.Set("X-User", "owner-user")
+	newPermissionRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestStreamPermissionMiddleware_RequiresCaller(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/streams/stream-1/peers", nil)
+	newPermissionRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 without a caller, got %d", w.Code)
+	}
+}
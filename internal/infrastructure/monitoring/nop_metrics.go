@@ -0,0 +1,32 @@
+package monitoring
+
+import (
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/pkg/circuitbreaker"
+)
+
+var (
+	_ ports.Metrics               = (*PrometheusCollector)(nil)
+	_ ports.Metrics               = NopMetrics{}
+	_ ports.CircuitBreakerMetrics = (*PrometheusCollector)(nil)
+	_ ports.CircuitBreakerMetrics = NopMetrics{}
+)
+
+// NopMetrics discards every metric, for tests and deployments without a
+// metrics backend
+type NopMetrics struct{}
+
+func (NopMetrics) RecordStreamCreated(streamID domain.StreamID)                         {}
+func (NopMetrics) RecordStreamEnded(streamID domain.StreamID)                           {}
+func (NopMetrics) RecordPeerConnected(streamID domain.StreamID, isPublisher bool)       {}
+func (NopMetrics) RecordPeerDisconnected(streamID domain.StreamID, isPublisher bool)    {}
+func (NopMetrics) RecordServerDataTransferred(bytes int64)                              {}
+func (NopMetrics) RecordWebRTCConnection(duration time.Duration)                        {}
+func (NopMetrics) RecordNetworkLatency(latency time.Duration)                           {}
+func (NopMetrics) RecordViewerSession(streamID domain.StreamID, duration time.Duration) {}
+
+func (NopMetrics) RecordCircuitBreakerState(string, domain.PeerID, circuitbreaker.State) {}
+func (NopMetrics) RecordCircuitBreakerTrip(string)                                       {}
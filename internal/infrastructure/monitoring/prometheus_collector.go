@@ -1,9 +1,11 @@
 package monitoring
 
 import (
+	"sync"
 	"time"
 
 	"rillnet/internal/core/domain"
+	"rillnet/pkg/circuitbreaker"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -32,6 +34,14 @@ type PrometheusCollector struct {
 	p2pEfficiencyPercent   *prometheus.GaugeVec
 	p2pDataTransferred     prometheus.Counter
 	serverDataTransferred  prometheus.Counter
+
+	// Reliability metrics
+	circuitBreakerState *prometheus.GaugeVec
+	circuitBreakerTrips *prometheus.CounterVec
+
+	// Last reported P2P efficiency per stream (mirrors p2pEfficiencyPercent for JSON snapshots)
+	p2pEfficiencyMu sync.RWMutex
+	p2pEfficiency   map[domain.StreamID]float64
 }
 
 func NewPrometheusCollector() *PrometheusCollector {
@@ -115,6 +125,16 @@ func NewPrometheusCollector() *PrometheusCollector {
 			Name: "rillnet_server_data_transferred_bytes_total",
 			Help: "Total amount of data transferred directly from server in bytes",
 		}),
+		circuitBreakerState: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rillnet_circuit_breaker_state",
+			Help: "Circuit breaker state (0=closed, 1=half-open, 2=open)",
+		}, []string{"scope", "peer_id"}),
+		circuitBreakerTrips: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "rillnet_circuit_breaker_trips_total",
+			Help: "Times a circuit breaker opened",
+		}, []string{"scope"}),
+
+		p2pEfficiency: make(map[domain.StreamID]float64),
 	}
 }
 
@@ -154,6 +174,11 @@ func (p *PrometheusCollector) RecordStreamEnded(streamID domain.StreamID) {
 	p.streamPeerCount.DeleteLabelValues(string(streamID), "publisher")
 	p.streamPeerCount.DeleteLabelValues(string(streamID), "subscriber")
 	p.streamHealthScore.DeleteLabelValues(string(streamID))
+	p.p2pEfficiencyPercent.DeleteLabelValues(string(streamID))
+
+	p.p2pEfficiencyMu.Lock()
+	delete(p.p2pEfficiency, streamID)
+	p.p2pEfficiencyMu.Unlock()
 }
 
 func (p *PrometheusCollector) RecordDataTransferred(bytes int64) {
@@ -208,6 +233,18 @@ func (p *PrometheusCollector) UpdateP2PEfficiency(streamID domain.StreamID, effi
 		efficiency = 100
 	}
 	p.p2pEfficiencyPercent.WithLabelValues(string(streamID)).Set(efficiency)
+
+	p.p2pEfficiencyMu.Lock()
+	p.p2pEfficiency[streamID] = efficiency
+	p.p2pEfficiencyMu.Unlock()
+}
+
+// P2PEfficiency returns the last reported P2P efficiency for a stream
+func (p *PrometheusCollector) P2PEfficiency(streamID domain.StreamID) (float64, bool) {
+	p.p2pEfficiencyMu.RLock()
+	defer p.p2pEfficiencyMu.RUnlock()
+	efficiency, ok := p.p2pEfficiency[streamID]
+	return efficiency, ok
 }
 
 // CalculateAndUpdateP2PEfficiency calculates P2P efficiency based on transferred data
@@ -219,3 +256,26 @@ func (p *PrometheusCollector) CalculateAndUpdateP2PEfficiency(streamID domain.St
 	efficiency := (float64(p2pBytes) / float64(totalBytes)) * 100.0
 	p.UpdateP2PEfficiency(streamID, efficiency)
 }
+
+// RecordCircuitBreakerState sets the state gauge of a circuit breaker
+func (p *PrometheusCollector) RecordCircuitBreakerState(scope string, peerID domain.PeerID, state circuitbreaker.State) {
+	p.circuitBreakerState.WithLabelValues(scope, string(peerID)).Set(circuitBreakerStateValue(state))
+}
+
+// RecordCircuitBreakerTrip counts a circuit breaker opening
+func (p *PrometheusCollector) RecordCircuitBreakerTrip(scope string) {
+	p.circuitBreakerTrips.WithLabelValues(scope).Inc()
+}
+
+// circuitBreakerStateValue orders the states by severity, unlike
+// circuitbreaker.State
+func circuitBreakerStateValue(state circuitbreaker.State) float64 {
+	switch state {
+	case circuitbreaker.StateHalfOpen:
+		return 1
+	case circuitbreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,78 @@
+package monitoring
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	goroutinesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rillnet_goroutines",
+		Help: "Goroutines running in this instance",
+	})
+	activeForwardersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rillnet_active_forwarders",
+		Help: "Track forwarders held by this instance's SFU",
+	})
+	activeMonitorsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rillnet_active_monitors",
+		Help: "Adaptive bitrate peer monitors running in this instance",
+	})
+)
+
+// ForwarderCounter reports how many track forwarders exist
+type ForwarderCounter interface {
+	ActiveForwarders() int
+}
+
+// MonitorCounter reports how many peer monitor goroutines are running
+type MonitorCounter interface {
+	ActiveMonitors() int
+}
+
+// RuntimeGauges publishes goroutine, forwarder and monitor counts so leaks
+// show up as a steady climb. Either source may be nil on instances without it.
+type RuntimeGauges struct {
+	forwarders ForwarderCounter
+	monitors   MonitorCounter
+}
+
+func NewRuntimeGauges(forwarders ForwarderCounter, monitors MonitorCounter) *RuntimeGauges {
+	return &RuntimeGauges{forwarders: forwarders, monitors: monitors}
+}
+
+// Update sets every gauge from its current count
+func (g *RuntimeGauges) Update() {
+	goroutinesGauge.Set(float64(runtime.NumGoroutine()))
+	if g.forwarders != nil {
+		activeForwardersGauge.Set(float64(g.forwarders.ActiveForwarders()))
+	}
+	if g.monitors != nil {
+		activeMonitorsGauge.Set(float64(g.monitors.ActiveMonitors()))
+	}
+}
+
+// defaultGaugeInterval is used when Run is given no interval
+const defaultGaugeInterval = 30 * time.Second
+
+// Run updates the gauges every interval until ctx is done
+func (g *RuntimeGauges) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultGaugeInterval
+	}
+	g.Update()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Update()
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/pkg/validation"
+)
+
+// indexFileName is the per-stream index stored next to the recordings
+const indexFileName = "index.json"
+
+// FileIndex keeps recordings on disk as <root>/<stream_id>/<file_name>, with
+// each stream's index in <root>/<stream_id>/index.json.
+type FileIndex struct {
+	root string
+	mu   sync.Mutex
+}
+
+// NewFileIndex creates an index rooted at dir
+func NewFileIndex(dir string) ports.RecordingIndex {
+	return &FileIndex{root: dir}
+}
+
+// Add indexes a completed recording whose file is already in the stream's directory.
+// SizeBytes is taken from the file.
+func (i *FileIndex) Add(ctx context.Context, rec *domain.Recording) error {
+	if rec.ID == "" {
+		return fmt.Errorf("recording id is required")
+	}
+	if rec.EndedAt.IsZero() || rec.EndedAt.Before(rec.StartedAt) {
+		return fmt.Errorf("recording %s is not complete", rec.ID)
+	}
+	dir, err := i.streamDir(rec.StreamID)
+	if err != nil {
+		return err
+	}
+	if err := validateFileName(rec.FileName); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filepath.Join(dir, rec.FileName))
+	if err != nil {
+		return fmt.Errorf("stat recording file: %w", err)
+	}
+	rec.SizeBytes = info.Size()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	recordings, err := i.load(dir)
+	if err != nil {
+		return err
+	}
+	for _, existing := range recordings {
+		if existing.ID == rec.ID {
+			return fmt.Errorf("recording %s already indexed", rec.ID)
+		}
+	}
+	recordings = append(recordings, rec)
+	sort.Slice(recordings, func(a, b int) bool { return recordings[a].StartedAt.Before(recordings[b].StartedAt) })
+
+	return i.save(dir, recordings)
+}
+
+// ListByStream returns the stream's recordings, oldest first
+func (i *FileIndex) ListByStream(ctx context.Context, streamID domain.StreamID) ([]*domain.Recording, error) {
+	dir, err := i.streamDir(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.load(dir)
+}
+
+// Open returns an indexed recording and its file
+func (i *FileIndex) Open(ctx context.Context, streamID domain.StreamID, id domain.RecordingID) (*domain.Recording, io.ReadSeekCloser, error) {
+	recordings, err := i.ListByStream(ctx, streamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, rec := range recordings {
+		if rec.ID != id {
+			continue
+		}
+		dir, err := i.streamDir(streamID)
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := os.Open(filepath.Join(dir, rec.FileName))
+		if err != nil {
+			return nil, nil, fmt.Errorf("open recording file: %w", err)
+		}
+		return rec, file, nil
+	}
+
+	return nil, nil, domain.ErrRecordingNotFound
+}
+
+func (i *FileIndex) streamDir(streamID domain.StreamID) (string, error) {
+	return StreamDir(i.root, streamID)
+}
+
+// StreamDir returns the directory holding a stream's recordings under root.
+// The stream ID policy is configurable, so IDs that are not a single path
+// element, or would resolve outside root, are rejected here as well.
+func StreamDir(root string, streamID domain.StreamID) (string, error) {
+	if err := validation.ValidateStreamID(string(streamID)); err != nil {
+		return "", err
+	}
+	id := string(streamID)
+	if id == "." || id == ".." || filepath.Base(id) != id || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("invalid stream ID %q for a recording directory", id)
+	}
+
+	dir := filepath.Join(root, id)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("stream ID %q escapes the recording directory", id)
+	}
+	return dir, nil
+}
+
+// load reads a stream's index; a missing index means no recordings. Caller must hold i.mu.
+func (i *FileIndex) load(dir string) ([]*domain.Recording, error) {
+	data, err := os.ReadFile(filepath.Join(dir, indexFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return []*domain.Recording{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read recording index: %w", err)
+	}
+
+	var recordings []*domain.Recording
+	if err := json.Unmarshal(data, &recordings); err != nil {
+		return nil, fmt.Errorf("decode recording index: %w", err)
+	}
+	return recordings, nil
+}
+
+// save writes a stream's index atomically. Caller must hold i.mu.
+func (i *FileIndex) save(dir string, recordings []*domain.Recording) error {
+	data, err := json.MarshalIndent(recordings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode recording index: %w", err)
+	}
+
+	tmp := filepath.Join(dir, indexFileName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write recording index: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, indexFileName))
+}
+
+func validateFileName(name string) error {
+	if name == "" || name == indexFileName || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid recording file name %q", name)
+	}
+	return nil
+}
@@ -2,16 +2,26 @@ package reliability
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/ports"
 	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/config"
 	"rillnet/pkg/retry"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 )
 
+var meshServiceRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rillnet_mesh_service_retries_total",
+	Help: "Retried mesh service operations, by operation",
+}, []string{"operation"})
+
 // MeshServiceWrapper wraps a MeshService with retry logic and circuit breaker
 type MeshServiceWrapper struct {
 	service ports.MeshService
@@ -21,8 +31,16 @@ type MeshServiceWrapper struct {
 	circuitBreaker    *circuitbreaker.CircuitBreaker
 	peerBreakers      map[domain.PeerID]*circuitbreaker.CircuitBreaker
 	peerBreakersMu    sync.RWMutex
+
+	breakerMetrics ports.CircuitBreakerMetrics
 }
 
+// Scopes of the wrapper's circuit breakers in CircuitBreakerMetrics
+const (
+	meshBreakerScope     = "mesh"
+	meshPeerBreakerScope = "mesh_peer"
+)
+
 // NewMeshServiceWrapper creates a new wrapper with retry and circuit breaker
 func NewMeshServiceWrapper(
 	service ports.MeshService,
@@ -44,11 +62,52 @@ func NewMeshServiceWrapper(
 			"from", from.String(),
 			"to", to.String(),
 		)
+		wrapper.recordBreakerState(wrapper.circuitBreaker, meshBreakerScope, "", to)
 	})
 
 	return wrapper
 }
 
+// SetCircuitBreakerMetrics reports the state of the wrapper's circuit
+// breakers to metrics. Must be called before the wrapper is used.
+func (w *MeshServiceWrapper) SetCircuitBreakerMetrics(metrics ports.CircuitBreakerMetrics) {
+	w.breakerMetrics = metrics
+	metrics.RecordCircuitBreakerState(meshBreakerScope, "", w.circuitBreaker.GetState())
+}
+
+// recordBreakerState reports a breaker's transition to the state to. State
+// change callbacks run asynchronously, so the gauge is set from the breaker's
+// current state rather than to, which may already be stale.
+func (w *MeshServiceWrapper) recordBreakerState(cb *circuitbreaker.CircuitBreaker, scope string, peerID domain.PeerID, to circuitbreaker.State) {
+	if w.breakerMetrics == nil {
+		return
+	}
+	w.breakerMetrics.RecordCircuitBreakerState(scope, peerID, cb.GetState())
+	if to == circuitbreaker.StateOpen {
+		w.breakerMetrics.RecordCircuitBreakerTrip(scope)
+	}
+}
+
+// retryConfigFor returns the retry config of an operation, counting and
+// logging each of its retries
+func (w *MeshServiceWrapper) retryConfigFor(operation string) retry.Config {
+	cfg := w.retryConfig
+	onRetry := cfg.OnRetry
+	cfg.OnRetry = func(attempt int, err error, nextDelay time.Duration) {
+		meshServiceRetries.WithLabelValues(operation).Inc()
+		w.logger.Debugw("retrying mesh service operation",
+			"operation", operation,
+			"attempt", attempt,
+			"next_delay", nextDelay,
+			"error", err,
+		)
+		if onRetry != nil {
+			onRetry(attempt, err, nextDelay)
+		}
+	}
+	return cfg
+}
+
 // getPeerCircuitBreaker gets or creates a circuit breaker for a specific peer
 func (w *MeshServiceWrapper) getPeerCircuitBreaker(peerID domain.PeerID) *circuitbreaker.CircuitBreaker {
 	w.peerBreakersMu.RLock()
@@ -75,6 +134,7 @@ func (w *MeshServiceWrapper) getPeerCircuitBreaker(peerID domain.PeerID) *circui
 			"from", from.String(),
 			"to", to.String(),
 		)
+		w.recordBreakerState(cb, meshPeerBreakerScope, peerID, to)
 	})
 
 	w.peerBreakers[peerID] = cb
@@ -87,7 +147,7 @@ func (w *MeshServiceWrapper) AddPeer(ctx context.Context, peer *domain.Peer) err
 		return w.service.AddPeer(ctx, peer)
 	}
 
-	return retry.Retry(ctx, w.retryConfig, func() error {
+	return retry.Retry(ctx, w.retryConfigFor("add_peer"), func() error {
 		return w.circuitBreaker.Execute(ctx, func() error {
 			return w.service.AddPeer(ctx, peer)
 		})
@@ -100,7 +160,7 @@ func (w *MeshServiceWrapper) RemovePeer(ctx context.Context, peerID domain.PeerI
 		return w.service.RemovePeer(ctx, peerID)
 	}
 
-	return retry.Retry(ctx, w.retryConfig, func() error {
+	return retry.Retry(ctx, w.retryConfigFor("remove_peer"), func() error {
 		return w.circuitBreaker.Execute(ctx, func() error {
 			return w.service.RemovePeer(ctx, peerID)
 		})
@@ -113,23 +173,34 @@ func (w *MeshServiceWrapper) UpdatePeerMetrics(ctx context.Context, peerID domai
 		return w.service.UpdatePeerMetrics(ctx, peerID, metrics)
 	}
 
-	return retry.Retry(ctx, w.retryConfig, func() error {
+	return retry.Retry(ctx, w.retryConfigFor("update_peer_metrics"), func() error {
 		return w.circuitBreaker.Execute(ctx, func() error {
 			return w.service.UpdatePeerMetrics(ctx, peerID, metrics)
 		})
 	})
 }
 
-// FindOptimalSources finds optimal sources with retry logic
+// FindOptimalSources finds optimal sources with retry logic. While the
+// circuit is open no sources are returned rather than an error, so joins
+// degrade to SFU-only delivery instead of failing.
 func (w *MeshServiceWrapper) FindOptimalSources(ctx context.Context, streamID domain.StreamID, targetPeer domain.PeerID, count int) ([]*domain.Peer, error) {
 	if !w.retryConfig.Enabled {
 		return w.service.FindOptimalSources(ctx, streamID, targetPeer, count)
 	}
 
-	result, err := retry.RetryWithResult(ctx, w.retryConfig, func() ([]*domain.Peer, error) {
-		res, err := w.circuitBreaker.ExecuteWithResult(ctx, func() (interface{}, error) {
+	noSources := func(err error) (interface{}, error) {
+		w.logger.Warnw("mesh unavailable, joining without optimal sources",
+			"stream_id", streamID,
+			"peer_id", targetPeer,
+			"error", err,
+		)
+		return []*domain.Peer{}, nil
+	}
+
+	result, err := retry.RetryWithResult(ctx, w.retryConfigFor("find_optimal_sources"), func() ([]*domain.Peer, error) {
+		res, err := w.circuitBreaker.ExecuteWithResultFallback(ctx, func() (interface{}, error) {
 			return w.service.FindOptimalSources(ctx, streamID, targetPeer, count)
-		})
+		}, noSources)
 		if err != nil {
 			return nil, err
 		}
@@ -144,7 +215,7 @@ func (w *MeshServiceWrapper) BuildOptimalMesh(ctx context.Context, streamID doma
 		return w.service.BuildOptimalMesh(ctx, streamID)
 	}
 
-	return retry.Retry(ctx, w.retryConfig, func() error {
+	return retry.Retry(ctx, w.retryConfigFor("build_optimal_mesh"), func() error {
 		return w.circuitBreaker.Execute(ctx, func() error {
 			return w.service.BuildOptimalMesh(ctx, streamID)
 		})
@@ -160,7 +231,7 @@ func (w *MeshServiceWrapper) AddConnection(ctx context.Context, conn *domain.Pee
 	// Use per-peer circuit breaker for connections
 	peerCB := w.getPeerCircuitBreaker(conn.FromPeer)
 
-	return retry.Retry(ctx, w.retryConfig, func() error {
+	return retry.Retry(ctx, w.retryConfigFor("add_connection"), func() error {
 		return peerCB.Execute(ctx, func() error {
 			return w.service.AddConnection(ctx, conn)
 		})
@@ -175,7 +246,7 @@ func (w *MeshServiceWrapper) RemoveConnection(ctx context.Context, fromPeer, toP
 
 	peerCB := w.getPeerCircuitBreaker(fromPeer)
 
-	return retry.Retry(ctx, w.retryConfig, func() error {
+	return retry.Retry(ctx, w.retryConfigFor("remove_connection"), func() error {
 		return peerCB.Execute(ctx, func() error {
 			return w.service.RemoveConnection(ctx, fromPeer, toPeer)
 		})
@@ -192,6 +263,40 @@ func (w *MeshServiceWrapper) GetOptimalPath(ctx context.Context, sourcePeer, tar
 	return w.service.GetOptimalPath(ctx, sourcePeer, targetPeer)
 }
 
+// SnapshotTopology captures the stream's current mesh (no retry needed for read operations)
+func (w *MeshServiceWrapper) SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error) {
+	return w.service.SnapshotTopology(ctx, streamID)
+}
+
+// RecordConnectionResult records a connection outcome (in-memory, no retry needed)
+func (w *MeshServiceWrapper) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	return w.service.RecordConnectionResult(ctx, sourcePeer, targetPeer, connected)
+}
+
+// SetScoringWeights forwards to the wrapped service when it supports tuning
+func (w *MeshServiceWrapper) SetScoringWeights(latency, bandwidth, reliability float64) error {
+	tuner, ok := w.service.(ports.MeshScoringTuner)
+	if !ok {
+		return fmt.Errorf("mesh service does not support scoring weights")
+	}
+	return tuner.SetScoringWeights(latency, bandwidth, reliability)
+}
+
+// GetScoringWeights returns the wrapped service's weights, zero when it has none
+func (w *MeshServiceWrapper) GetScoringWeights() (latency, bandwidth, reliability float64) {
+	if tuner, ok := w.service.(ports.MeshScoringTuner); ok {
+		return tuner.GetScoringWeights()
+	}
+	return 0, 0, 0
+}
+
+// ApplyConfig forwards a reloaded config to the wrapped service when it can adopt one
+func (w *MeshServiceWrapper) ApplyConfig(cfg *config.Config) {
+	if reloadable, ok := w.service.(config.Reloadable); ok {
+		reloadable.ApplyConfig(cfg)
+	}
+}
+
 // GetCircuitBreakerStats returns circuit breaker statistics
 func (w *MeshServiceWrapper) GetCircuitBreakerStats() circuitbreaker.Stats {
 	return w.circuitBreaker.GetStats()
@@ -0,0 +1,79 @@
+package reliability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/infrastructure/monitoring"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// failingMeshService fails every BuildOptimalMesh call
+type failingMeshService struct {
+	ports.MeshService
+}
+
+func (failingMeshService) BuildOptimalMesh(ctx context.Context, streamID domain.StreamID) error {
+	return errors.New("mesh unavailable")
+}
+
+// gaugeValue reads a gauge from the default registry, or -1 when the series
+// does not exist
+func gaugeValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if labels[pair.GetName()] != pair.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetGauge().GetValue()
+		}
+	}
+	return -1
+}
+
+func TestMeshServiceWrapper_CircuitBreakerStateGauge(t *testing.T) {
+	wrapper := NewMeshServiceWrapper(
+		failingMeshService{},
+		retry.Config{Enabled: true, MaxAttempts: 1, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1},
+		circuitbreaker.Config{
+			FailureThreshold:    2,
+			SuccessThreshold:    1,
+			Timeout:             time.Minute,
+			MaxRequestsHalfOpen: 1,
+		},
+		zap.NewNop().Sugar(),
+	)
+	wrapper.SetCircuitBreakerMetrics(monitoring.NewPrometheusCollector())
+
+	labels := map[string]string{"scope": meshBreakerScope, "peer_id": ""}
+	require.Equal(t, 0.0, gaugeValue(t, "rillnet_circuit_breaker_state", labels))
+
+	for i := 0; i < 2; i++ {
+		require.Error(t, wrapper.BuildOptimalMesh(context.Background(), "stream-1"))
+	}
+	require.Equal(t, circuitbreaker.StateOpen, wrapper.GetCircuitBreakerStats().State)
+
+	// State change callbacks run in their own goroutine
+	require.Eventually(t, func() bool {
+		return gaugeValue(t, "rillnet_circuit_breaker_state", labels) == 2
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,168 @@
+package reliability
+
+import (
+	"context"
+	"errors"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/pkg/circuitbreaker"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var peerRepositoryBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "rillnet_peer_repository_circuit_breaker_state",
+	Help: "State of the peer repository circuit breaker (0 closed, 1 open, 2 half-open)",
+})
+
+// PeerRepositoryWrapper guards a PeerRepository backed by a remote store
+// (Redis) with a circuit breaker, so that once the store keeps failing calls
+// fail fast instead of each one waiting out its own timeout against it.
+type PeerRepositoryWrapper struct {
+	repo           ports.PeerRepository
+	circuitBreaker *circuitbreaker.CircuitBreaker
+	// Reads go to the store even while the circuit is open
+	readThrough bool
+}
+
+// NewPeerRepositoryWrapper creates a new wrapper with a circuit breaker
+func NewPeerRepositoryWrapper(
+	repo ports.PeerRepository,
+	cbConfig circuitbreaker.Config,
+	readThrough bool,
+	logger *zap.SugaredLogger,
+) *PeerRepositoryWrapper {
+	wrapper := &PeerRepositoryWrapper{
+		repo:           repo,
+		circuitBreaker: circuitbreaker.New(cbConfig),
+		readThrough:    readThrough,
+	}
+
+	wrapper.circuitBreaker.OnStateChange(func(from, to circuitbreaker.State) {
+		peerRepositoryBreakerState.Set(float64(to))
+		logger.Infow("peer repository circuit breaker state changed",
+			"from", from.String(),
+			"to", to.String(),
+		)
+	})
+
+	return wrapper
+}
+
+// execute runs fn through the circuit breaker. A missing peer is an answer
+// from the store, not a failure of it, so it does not count towards opening.
+func (w *PeerRepositoryWrapper) execute(ctx context.Context, fn func() error) error {
+	var notFound error
+	err := w.circuitBreaker.Execute(ctx, func() error {
+		err := fn()
+		if errors.Is(err, domain.ErrPeerNotFound) {
+			notFound = err
+			return nil
+		}
+		return err
+	})
+	if notFound != nil {
+		return notFound
+	}
+	return err
+}
+
+// read runs a read through the breaker unless reads bypass it
+func (w *PeerRepositoryWrapper) read(ctx context.Context, fn func() error) error {
+	if w.readThrough {
+		return fn()
+	}
+	return w.execute(ctx, fn)
+}
+
+// Add stores a peer through the circuit breaker
+func (w *PeerRepositoryWrapper) Add(ctx context.Context, peer *domain.Peer) error {
+	return w.execute(ctx, func() error {
+		return w.repo.Add(ctx, peer)
+	})
+}
+
+// GetByID gets a peer through the circuit breaker
+func (w *PeerRepositoryWrapper) GetByID(ctx context.Context, id domain.PeerID) (*domain.Peer, error) {
+	var peer *domain.Peer
+	err := w.read(ctx, func() error {
+		var err error
+		peer, err = w.repo.GetByID(ctx, id)
+		return err
+	})
+	return peer, err
+}
+
+// Remove removes a peer through the circuit breaker
+func (w *PeerRepositoryWrapper) Remove(ctx context.Context, id domain.PeerID) error {
+	return w.execute(ctx, func() error {
+		return w.repo.Remove(ctx, id)
+	})
+}
+
+// FindByStream lists a stream's peers through the circuit breaker
+func (w *PeerRepositoryWrapper) FindByStream(ctx context.Context, streamID domain.StreamID) ([]*domain.Peer, error) {
+	var peers []*domain.Peer
+	err := w.read(ctx, func() error {
+		var err error
+		peers, err = w.repo.FindByStream(ctx, streamID)
+		return err
+	})
+	return peers, err
+}
+
+// FindByFilter lists a stream's matching peers through the circuit breaker
+func (w *PeerRepositoryWrapper) FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	var peers []*domain.Peer
+	err := w.read(ctx, func() error {
+		var err error
+		peers, err = w.repo.FindByFilter(ctx, streamID, filter)
+		return err
+	})
+	return peers, err
+}
+
+// FindOptimalSource finds a source peer through the circuit breaker
+func (w *PeerRepositoryWrapper) FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error) {
+	var peer *domain.Peer
+	err := w.read(ctx, func() error {
+		var err error
+		peer, err = w.repo.FindOptimalSource(ctx, streamID, excludePeers)
+		return err
+	})
+	return peer, err
+}
+
+// UpdateMetrics updates peer metrics through the circuit breaker
+func (w *PeerRepositoryWrapper) UpdateMetrics(ctx context.Context, peerID domain.PeerID, metrics domain.NetworkMetrics) error {
+	return w.execute(ctx, func() error {
+		return w.repo.UpdateMetrics(ctx, peerID, metrics)
+	})
+}
+
+// UpdatePeerLoad updates peer load through the circuit breaker
+func (w *PeerRepositoryWrapper) UpdatePeerLoad(ctx context.Context, peerID domain.PeerID, load int) error {
+	return w.execute(ctx, func() error {
+		return w.repo.UpdatePeerLoad(ctx, peerID, load)
+	})
+}
+
+// GetCircuitBreakerStats returns circuit breaker statistics
+func (w *PeerRepositoryWrapper) GetCircuitBreakerStats() circuitbreaker.Stats {
+	return w.circuitBreaker.GetStats()
+}
+
+// Heartbeat refreshes a peer through the circuit breaker when the wrapped
+// repository expires peers
+func (w *PeerRepositoryWrapper) Heartbeat(ctx context.Context, peerID domain.PeerID) error {
+	heartbeater, ok := w.repo.(ports.PeerHeartbeater)
+	if !ok {
+		return nil
+	}
+	return w.execute(ctx, func() error {
+		return heartbeater.Heartbeat(ctx, peerID)
+	})
+}
@@ -0,0 +1,97 @@
+package reliability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	redisrepo "rillnet/internal/infrastructure/repositories/redis"
+	"rillnet/pkg/circuitbreaker"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// unreachableRedisRepository returns a Redis peer repository whose server
+// refuses every connection, counting the commands that reach the client.
+func unreachableRedisRepository(t *testing.T, commands *int) *PeerRepositoryWrapper {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	client.AddHook(countingHook{commands: commands})
+
+	return NewPeerRepositoryWrapper(
+		redisrepo.NewRedisPeerRepository(client),
+		circuitbreaker.Config{
+			FailureThreshold:    3,
+			SuccessThreshold:    1,
+			Timeout:             time.Minute,
+			MaxRequestsHalfOpen: 1,
+		},
+		false,
+		zap.NewNop().Sugar(),
+	)
+}
+
+type countingHook struct {
+	commands *int
+}
+
+func (h countingHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h countingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		*h.commands++
+		return next(ctx, cmd)
+	}
+}
+
+func (h countingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestPeerRepositoryWrapper_OpensAfterRedisFailuresAndFailsFast(t *testing.T) {
+	ctx := context.Background()
+	commands := 0
+	repo := unreachableRedisRepository(t, &commands)
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.GetByID(ctx, "peer-1")
+		require.Error(t, err)
+		require.False(t, errors.Is(err, circuitbreaker.ErrRejected))
+	}
+	require.Equal(t, 3, commands)
+	require.Equal(t, circuitbreaker.StateOpen, repo.GetCircuitBreakerStats().State)
+
+	err := repo.Add(ctx, &domain.Peer{ID: "peer-1", StreamID: "stream-1"})
+	require.ErrorIs(t, err, circuitbreaker.ErrRejected)
+	_, err = repo.FindByStream(ctx, "stream-1")
+	require.ErrorIs(t, err, circuitbreaker.ErrRejected)
+	require.Equal(t, 3, commands, "no command should reach Redis while the circuit is open")
+}
+
+func TestPeerRepositoryWrapper_ReadThroughBypassesOpenCircuit(t *testing.T) {
+	ctx := context.Background()
+	commands := 0
+	repo := unreachableRedisRepository(t, &commands)
+	repo.readThrough = true
+
+	for i := 0; i < 3; i++ {
+		require.Error(t, repo.UpdatePeerLoad(ctx, "peer-1", 1))
+	}
+	require.Equal(t, circuitbreaker.StateOpen, repo.GetCircuitBreakerStats().State)
+
+	before := commands
+	_, err := repo.GetByID(ctx, "peer-1")
+	require.Error(t, err)
+	require.False(t, errors.Is(err, circuitbreaker.ErrRejected))
+	require.Equal(t, before+1, commands)
+}
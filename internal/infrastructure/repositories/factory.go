@@ -3,11 +3,14 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"rillnet/internal/core/ports"
+	"rillnet/internal/infrastructure/reliability"
 	"rillnet/internal/infrastructure/repositories/memory"
 	pgrepo "rillnet/internal/infrastructure/repositories/postgres"
 	redisrepo "rillnet/internal/infrastructure/repositories/redis"
+	"rillnet/pkg/circuitbreaker"
 	"rillnet/pkg/config"
 
 	"github.com/redis/go-redis/v9"
@@ -22,12 +25,28 @@ type RepositoryFactory struct {
 	useDB       bool
 	dbPool      *pgxpool.Pool
 	logger      *zap.SugaredLogger
+
+	// Guards the Redis peer repository, nil when disabled
+	peerBreaker *circuitbreaker.Config
+	readThrough bool
+
+	peerTTL           time.Duration
+	peerSweepInterval time.Duration
+	// Stops the expired peer sweeper, nil when it is not running
+	stopSweeper context.CancelFunc
+	// Stops the stale mesh edge sweeper, nil when it is not running
+	stopMeshSweeper context.CancelFunc
 }
 
 func (f *RepositoryFactory) DBPool() *pgxpool.Pool {
 	return f.dbPool
 }
 
+// RedisClient returns the Redis client, or nil when repositories are in memory
+func (f *RepositoryFactory) RedisClient() *redis.Client {
+	return f.redisClient
+}
+
 func (f *RepositoryFactory) CreateUserRepository() ports.UserRepository {
 	if f.dbPool != nil {
 		return pgrepo.NewUserRepository(f.dbPool)
@@ -42,12 +61,36 @@ func (f *RepositoryFactory) CreateRefreshTokenRepository() ports.RefreshTokenRep
 	return nil
 }
 
+// CreateRefreshTokenFamilyStore creates the store behind refresh token
+// rotation (Redis or memory with fallback)
+func (f *RepositoryFactory) CreateRefreshTokenFamilyStore() ports.RefreshTokenFamilyStore {
+	if f.useRedis && f.redisClient != nil {
+		return redisrepo.NewRedisRefreshTokenStore(f.redisClient)
+	}
+	return memory.NewMemoryRefreshTokenStore()
+}
+
 // NewRepositoryFactory creates a new repository factory
 func NewRepositoryFactory(cfg *config.Config, logger *zap.SugaredLogger) (*RepositoryFactory, error) {
 	factory := &RepositoryFactory{
 		useRedis: cfg.Redis.Enabled,
 		useDB:    cfg.Database.Enabled,
 		logger:   logger,
+
+		peerTTL:           cfg.Redis.PeerTTL,
+		peerSweepInterval: cfg.Redis.PeerSweepInterval,
+	}
+
+	if cfg.Redis.CircuitBreaker && cfg.CircuitBreaker.Enabled {
+		factory.peerBreaker = &circuitbreaker.Config{
+			FailureThreshold:    cfg.CircuitBreaker.FailureThreshold,
+			SuccessThreshold:    cfg.CircuitBreaker.SuccessThreshold,
+			Timeout:             cfg.CircuitBreaker.Timeout,
+			MaxRequestsHalfOpen: cfg.CircuitBreaker.MaxRequestsHalfOpen,
+			WindowSize:          cfg.CircuitBreaker.WindowSize,
+			FailureRatio:        cfg.CircuitBreaker.FailureRatio,
+		}
+		factory.readThrough = cfg.Redis.ReadThrough
 	}
 
 	// Try to connect to Redis if enabled
@@ -86,7 +129,16 @@ func NewRepositoryFactory(cfg *config.Config, logger *zap.SugaredLogger) (*Repos
 // CreatePeerRepository creates a peer repository (Redis or memory with fallback)
 func (f *RepositoryFactory) CreatePeerRepository() ports.PeerRepository {
 	if f.useRedis && f.redisClient != nil {
-		return redisrepo.NewRedisPeerRepository(f.redisClient)
+		repo := redisrepo.NewRedisPeerRepositoryWithTTL(f.redisClient, f.peerTTL)
+		if f.peerTTL > 0 && f.peerSweepInterval > 0 && f.stopSweeper == nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			f.stopSweeper = cancel
+			go repo.RunSweeper(ctx, f.peerSweepInterval, f.logger)
+		}
+		if f.peerBreaker != nil {
+			return reliability.NewPeerRepositoryWrapper(repo, *f.peerBreaker, f.readThrough, f.logger)
+		}
+		return repo
 	}
 	return memory.NewMemoryPeerRepository()
 }
@@ -99,15 +151,29 @@ func (f *RepositoryFactory) CreateStreamRepository() ports.StreamRepository {
 	return memory.NewMemoryStreamRepository()
 }
 
-// CreateMeshRepository creates a mesh repository (always memory for now)
+// CreateMeshRepository creates a mesh repository (Redis or memory with fallback)
 func (f *RepositoryFactory) CreateMeshRepository() ports.MeshRepository {
-	// Mesh repository is always memory for now
-	// Can be extended to Redis later if needed
+	if f.useRedis && f.redisClient != nil {
+		repo := redisrepo.NewRedisMeshRepository(f.redisClient)
+		// Edges of expired peers go with them, on the peer sweep interval
+		if f.peerTTL > 0 && f.peerSweepInterval > 0 && f.stopMeshSweeper == nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			f.stopMeshSweeper = cancel
+			go repo.RunSweeper(ctx, f.peerSweepInterval, f.logger)
+		}
+		return repo
+	}
 	return memory.NewMemoryMeshRepository()
 }
 
 // Close closes Redis connection if used
 func (f *RepositoryFactory) Close() error {
+	if f.stopSweeper != nil {
+		f.stopSweeper()
+	}
+	if f.stopMeshSweeper != nil {
+		f.stopMeshSweeper()
+	}
 	if f.redisClient != nil {
 		return redisrepo.CloseRedisClient(f.redisClient)
 	}
@@ -26,7 +26,10 @@ func (r *MemoryPeerRepository) Add(ctx context.Context, peer *domain.Peer) error
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.peers[peer.ID]; exists {
+	if existing, exists := r.peers[peer.ID]; exists {
+		if existing.StreamID != peer.StreamID {
+			return fmt.Errorf("%w: %s", domain.ErrPeerInOtherStream, existing.StreamID)
+		}
 		return fmt.Errorf("peer already exists: %s", peer.ID)
 	}
 
@@ -72,6 +75,24 @@ func (r *MemoryPeerRepository) FindByStream(ctx context.Context, streamID domain
 	return streamPeers, nil
 }
 
+func (r *MemoryPeerRepository) FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*domain.Peer
+	for _, peer := range r.peers {
+		if peer.StreamID == streamID && filter.Matches(peer) {
+			matched = append(matched, peer)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
 func (r *MemoryPeerRepository) FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error) {
 	peers, err := r.FindByStream(ctx, streamID)
 	if err != nil {
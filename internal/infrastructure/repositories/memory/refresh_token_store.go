@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+)
+
+type memoryRefreshToken struct {
+	record    ports.RefreshTokenRecord
+	used      bool
+	expiresAt time.Time
+}
+
+type memoryRefreshFamily struct {
+	revoked   bool
+	expiresAt time.Time // Expiry of the family's newest token
+}
+
+// MemoryRefreshTokenStore keeps refresh token families for a single instance
+type MemoryRefreshTokenStore struct {
+	tokens   map[string]*memoryRefreshToken
+	families map[string]*memoryRefreshFamily
+	mu       sync.Mutex
+}
+
+func NewMemoryRefreshTokenStore() ports.RefreshTokenFamilyStore {
+	return &MemoryRefreshTokenStore{
+		tokens:   make(map[string]*memoryRefreshToken),
+		families: make(map[string]*memoryRefreshFamily),
+	}
+}
+
+func (s *MemoryRefreshTokenStore) StartFamily(ctx context.Context, record ports.RefreshTokenRecord, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	s.tokens[tokenHash] = &memoryRefreshToken{record: record, expiresAt: expiresAt}
+	s.families[record.FamilyID] = &memoryRefreshFamily{expiresAt: expiresAt}
+	return nil
+}
+
+func (s *MemoryRefreshTokenStore) Rotate(ctx context.Context, familyID, tokenHash, nextHash string, expiresAt time.Time) (ports.RefreshTokenRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	token, ok := s.tokens[tokenHash]
+	if !ok || !token.expiresAt.After(now) || token.record.FamilyID != familyID {
+		return ports.RefreshTokenRecord{}, domain.ErrRefreshTokenRevoked
+	}
+	family, ok := s.families[token.record.FamilyID]
+	if !ok || family.revoked || !family.expiresAt.After(now) {
+		return token.record, domain.ErrRefreshTokenRevoked
+	}
+	if token.used {
+		return token.record, domain.ErrRefreshTokenReused
+	}
+
+	token.used = true
+	s.tokens[nextHash] = &memoryRefreshToken{record: token.record, expiresAt: expiresAt}
+	family.expiresAt = expiresAt
+	return token.record, nil
+}
+
+func (s *MemoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if family, ok := s.families[familyID]; ok {
+		family.revoked = true
+	}
+	return nil
+}
+
+// pruneLocked drops expired tokens and families. Callers hold s.mu.
+func (s *MemoryRefreshTokenStore) pruneLocked(now time.Time) {
+	for hash, token := range s.tokens {
+		if !token.expiresAt.After(now) {
+			delete(s.tokens, hash)
+		}
+	}
+	for id, family := range s.families {
+		if !family.expiresAt.After(now) {
+			delete(s.families, id)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"rillnet/internal/core/domain"
@@ -11,12 +12,14 @@ import (
 
 type MemoryStreamRepository struct {
 	streams map[domain.StreamID]*domain.Stream
+	byOwner map[domain.UserID]map[domain.StreamID]struct{}
 	mu      sync.RWMutex
 }
 
 func NewMemoryStreamRepository() ports.StreamRepository {
 	return &MemoryStreamRepository{
 		streams: make(map[domain.StreamID]*domain.Stream),
+		byOwner: make(map[domain.UserID]map[domain.StreamID]struct{}),
 	}
 }
 
@@ -29,6 +32,30 @@ func (r *MemoryStreamRepository) Create(ctx context.Context, stream *domain.Stre
 	}
 
 	r.streams[stream.ID] = stream
+	r.indexOwner(stream)
+	return nil
+}
+
+func (r *MemoryStreamRepository) CreateWithOwnerLimit(ctx context.Context, stream *domain.Stream, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.streams[stream.ID]; exists {
+		return fmt.Errorf("stream already exists: %s", stream.ID)
+	}
+
+	active := 0
+	for id := range r.byOwner[stream.OwnerUserID] {
+		if owned, exists := r.streams[id]; exists && owned.Active {
+			active++
+		}
+	}
+	if active >= limit {
+		return fmt.Errorf("%w: %d/%d active streams", domain.ErrStreamQuotaExceeded, active, limit)
+	}
+
+	r.streams[stream.ID] = stream
+	r.indexOwner(stream)
 	return nil
 }
 
@@ -48,11 +75,14 @@ func (r *MemoryStreamRepository) Update(ctx context.Context, stream *domain.Stre
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.streams[stream.ID]; !exists {
+	existing, exists := r.streams[stream.ID]
+	if !exists {
 		return domain.ErrStreamNotFound
 	}
 
+	r.unindexOwner(existing)
 	r.streams[stream.ID] = stream
+	r.indexOwner(stream)
 	return nil
 }
 
@@ -60,10 +90,12 @@ func (r *MemoryStreamRepository) Delete(ctx context.Context, id domain.StreamID)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.streams[id]; !exists {
+	existing, exists := r.streams[id]
+	if !exists {
 		return domain.ErrStreamNotFound
 	}
 
+	r.unindexOwner(existing)
 	delete(r.streams, id)
 	return nil
 }
@@ -81,3 +113,63 @@ func (r *MemoryStreamRepository) ListActive(ctx context.Context) ([]*domain.Stre
 
 	return activeStreams, nil
 }
+
+// ListActivePage pages through the active streams ordered by creation time
+func (r *MemoryStreamRepository) ListActivePage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error) {
+	r.mu.RLock()
+	var matched []*domain.Stream
+	for _, stream := range r.streams {
+		if stream.Active && opts.Matches(stream) {
+			matched = append(matched, stream)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	return opts.Page(matched), len(matched), nil
+}
+
+func (r *MemoryStreamRepository) ListByOwner(ctx context.Context, owner domain.UserID) ([]*domain.Stream, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ownerStreams []*domain.Stream
+	for id := range r.byOwner[owner] {
+		if stream, exists := r.streams[id]; exists && stream.Active {
+			ownerStreams = append(ownerStreams, stream)
+		}
+	}
+
+	return ownerStreams, nil
+}
+
+// indexOwner records stream in the owner index; caller must hold r.mu.
+func (r *MemoryStreamRepository) indexOwner(stream *domain.Stream) {
+	if stream.OwnerUserID == "" {
+		return
+	}
+	ids, ok := r.byOwner[stream.OwnerUserID]
+	if !ok {
+		ids = make(map[domain.StreamID]struct{})
+		r.byOwner[stream.OwnerUserID] = ids
+	}
+	ids[stream.ID] = struct{}{}
+}
+
+// unindexOwner removes stream from the owner index; caller must hold r.mu.
+func (r *MemoryStreamRepository) unindexOwner(stream *domain.Stream) {
+	ids, ok := r.byOwner[stream.OwnerUserID]
+	if !ok {
+		return
+	}
+	delete(ids, stream.ID)
+	if len(ids) == 0 {
+		delete(r.byOwner, stream.OwnerUserID)
+	}
+}
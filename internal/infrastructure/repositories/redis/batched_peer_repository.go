@@ -119,40 +119,10 @@ func NewBatchedRedisPeerRepository(
 	}
 }
 
-// Add batches peer addition
+// Add stores the peer immediately (not batched) so the stream membership
+// check stays atomic with the write
 func (r *BatchedRedisPeerRepository) Add(ctx context.Context, peer *domain.Peer) error {
-	// Serialize peer to JSON
-	data, err := json.Marshal(peer)
-	if err != nil {
-		return fmt.Errorf("failed to marshal peer: %w", err)
-	}
-
-	// Batch SET operation
-	key := r.baseRepo.peerKey(peer.ID)
-	op := &RedisOperation{
-		Type:   "set",
-		Key:    key,
-		Value:  data,
-		TTL:    0,
-		client: r.baseRepo.client,
-	}
-	if err := r.batcher.Add(op); err != nil {
-		return err
-	}
-
-	// Batch SADD operation for stream peers set
-	if peer.StreamID != "" {
-		streamKey := r.baseRepo.streamPeersKey(peer.StreamID)
-		op := &RedisOperation{
-			Type:   "sadd",
-			Key:    streamKey,
-			Value:  string(peer.ID),
-			client: r.baseRepo.client,
-		}
-		_ = r.batcher.Add(op)
-	}
-
-	return nil
+	return r.baseRepo.Add(ctx, peer)
 }
 
 // GetByID gets peer by ID (not batched, immediate)
@@ -199,6 +169,11 @@ func (r *BatchedRedisPeerRepository) FindByStream(ctx context.Context, streamID
 	return r.baseRepo.FindByStream(ctx, streamID)
 }
 
+// FindByFilter finds matching peers by stream (not batched, immediate)
+func (r *BatchedRedisPeerRepository) FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	return r.baseRepo.FindByFilter(ctx, streamID, filter)
+}
+
 // FindOptimalSource finds optimal source (not batched, immediate)
 func (r *BatchedRedisPeerRepository) FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error) {
 	return r.baseRepo.FindOptimalSource(ctx, streamID, excludePeers)
@@ -0,0 +1,223 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// maxPathHops bounds the breadth-first search in GetOptimalPath
+const maxPathHops = 8
+
+// addConnectionScript stores an edge under both of its peers, or does nothing
+// if the edge already exists. Running it as one script keeps concurrent mesh
+// builds on different instances from writing the same edge twice.
+var addConnectionScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+redis.call("HSET", KEYS[2], ARGV[1], ARGV[2])
+return 1
+`)
+
+// removeConnectionScript deletes an edge from both of its peers, or does
+// nothing if the edge is not stored
+var removeConnectionScript = redis.NewScript(`
+if redis.call("HDEL", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[2], ARGV[1])
+return 1
+`)
+
+// meshConnectionsPattern matches every peer's connection hash
+const meshConnectionsPattern = "rillnet:mesh:*:conns"
+
+// sweepEdgesScript deletes, from both of their peers, the edges in KEYS[1]
+// whose sending or receiving peer key (ARGV[1] + peer ID) no longer exists,
+// and returns how many it deleted. ARGV[2] is the mesh key prefix.
+var sweepEdgesScript = redis.NewScript(`
+local removed = 0
+for _, field in ipairs(redis.call("HKEYS", KEYS[1])) do
+	local sep = string.find(field, "->", 1, true)
+	if sep then
+		local from = string.sub(field, 1, sep - 1)
+		local to = string.sub(field, sep + 2)
+		if redis.call("EXISTS", ARGV[1] .. from) == 0 or redis.call("EXISTS", ARGV[1] .. to) == 0 then
+			redis.call("HDEL", ARGV[2] .. from .. ":conns", field)
+			redis.call("HDEL", ARGV[2] .. to .. ":conns", field)
+			removed = removed + 1
+		end
+	end
+end
+return removed
+`)
+
+// RedisMeshRepository keeps each peer's connections in a hash keyed by edge,
+// holding every edge under both the sending and the receiving peer.
+type RedisMeshRepository struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisMeshRepository(client *redis.Client) *RedisMeshRepository {
+	return &RedisMeshRepository{
+		client: client,
+		prefix: "rillnet:mesh:",
+	}
+}
+
+func (r *RedisMeshRepository) connectionsKey(peerID domain.PeerID) string {
+	return r.prefix + string(peerID) + ":conns"
+}
+
+func (r *RedisMeshRepository) edgeField(fromPeer, toPeer domain.PeerID) string {
+	return string(fromPeer) + "->" + string(toPeer)
+}
+
+func (r *RedisMeshRepository) AddConnection(ctx context.Context, conn *domain.PeerConnection) error {
+	data, err := json.Marshal(conn)
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection: %w", err)
+	}
+
+	keys := []string{r.connectionsKey(conn.FromPeer), r.connectionsKey(conn.ToPeer)}
+	added, err := addConnectionScript.Run(ctx, r.client, keys, r.edgeField(conn.FromPeer, conn.ToPeer), data).Int()
+	if err != nil {
+		return fmt.Errorf("failed to add connection in Redis: %w", err)
+	}
+	if added == 0 {
+		return fmt.Errorf("connection already exists: %s->%s", conn.FromPeer, conn.ToPeer)
+	}
+
+	return nil
+}
+
+func (r *RedisMeshRepository) RemoveConnection(ctx context.Context, fromPeer, toPeer domain.PeerID) error {
+	keys := []string{r.connectionsKey(fromPeer), r.connectionsKey(toPeer)}
+	removed, err := removeConnectionScript.Run(ctx, r.client, keys, r.edgeField(fromPeer, toPeer)).Int()
+	if err != nil {
+		return fmt.Errorf("failed to remove connection in Redis: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("connection not found: %s->%s", fromPeer, toPeer)
+	}
+
+	return nil
+}
+
+func (r *RedisMeshRepository) GetConnections(ctx context.Context, peerID domain.PeerID) ([]*domain.PeerConnection, error) {
+	values, err := r.client.HVals(ctx, r.connectionsKey(peerID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connections from Redis: %w", err)
+	}
+
+	var result []*domain.PeerConnection
+	for _, value := range values {
+		var conn domain.PeerConnection
+		if err := json.Unmarshal([]byte(value), &conn); err != nil {
+			continue
+		}
+		result = append(result, &conn)
+	}
+
+	return result, nil
+}
+
+// SweepStaleEdges removes edges to or from peers whose keys have expired or
+// been removed, which a crashed instance never deletes itself, and returns
+// how many were removed
+func (r *RedisMeshRepository) SweepStaleEdges(ctx context.Context) (int, error) {
+	removed := 0
+	iter := r.client.Scan(ctx, 0, meshConnectionsPattern, 100).Iterator()
+	for iter.Next(ctx) {
+		n, err := sweepEdgesScript.Run(ctx, r.client, []string{iter.Val()}, peerKeyPrefix, r.prefix).Int()
+		if err != nil {
+			return removed, fmt.Errorf("failed to sweep stale mesh edges: %w", err)
+		}
+		removed += n
+	}
+	if err := iter.Err(); err != nil {
+		return removed, fmt.Errorf("failed to scan mesh connections: %w", err)
+	}
+	return removed, nil
+}
+
+// RunSweeper calls SweepStaleEdges every interval until ctx is done
+func (r *RedisMeshRepository) RunSweeper(ctx context.Context, interval time.Duration, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := r.SweepStaleEdges(ctx)
+			if err != nil {
+				logger.Warnw("failed to sweep stale mesh edges", "error", err)
+				continue
+			}
+			if removed > 0 {
+				logger.Infow("swept stale mesh edges", "removed", removed)
+			}
+		}
+	}
+}
+
+func (r *RedisMeshRepository) BuildMesh(ctx context.Context, streamID domain.StreamID, maxConnections int) error {
+	// The mesh service places connections itself; nothing to precompute here
+	return nil
+}
+
+// GetOptimalPath returns the shortest chain of stored edges from sourcePeer to
+// targetPeer, falling back to the direct path like the in-memory repository
+// when they are not connected within maxPathHops.
+func (r *RedisMeshRepository) GetOptimalPath(ctx context.Context, sourcePeer, targetPeer domain.PeerID) ([]domain.PeerID, error) {
+	if sourcePeer == targetPeer {
+		return []domain.PeerID{sourcePeer}, nil
+	}
+
+	previous := map[domain.PeerID]domain.PeerID{sourcePeer: ""}
+	frontier := []domain.PeerID{sourcePeer}
+	for hop := 0; hop < maxPathHops && len(frontier) > 0; hop++ {
+		var next []domain.PeerID
+		for _, peerID := range frontier {
+			conns, err := r.GetConnections(ctx, peerID)
+			if err != nil {
+				return nil, err
+			}
+			for _, conn := range conns {
+				if conn.FromPeer != peerID {
+					continue
+				}
+				if _, seen := previous[conn.ToPeer]; seen {
+					continue
+				}
+				previous[conn.ToPeer] = peerID
+				if conn.ToPeer == targetPeer {
+					return r.walkBack(previous, targetPeer), nil
+				}
+				next = append(next, conn.ToPeer)
+			}
+		}
+		frontier = next
+	}
+
+	return []domain.PeerID{sourcePeer, targetPeer}, nil
+}
+
+// walkBack rebuilds the path ending at targetPeer from BFS predecessors
+func (r *RedisMeshRepository) walkBack(previous map[domain.PeerID]domain.PeerID, targetPeer domain.PeerID) []domain.PeerID {
+	var path []domain.PeerID
+	for peerID := targetPeer; peerID != ""; peerID = previous[peerID] {
+		path = append([]domain.PeerID{peerID}, path...)
+	}
+	return path
+}
@@ -11,17 +11,71 @@ import (
 	"rillnet/internal/core/ports"
 
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
+// peerKeyPrefix prefixes each stored peer's key
+const peerKeyPrefix = "rillnet:peer:"
+
+// streamPeersPattern matches every stream's peer set
+const streamPeersPattern = "rillnet:stream:*:peers"
+
+// addPeerScript stores a peer unless the same peer ID is stored under another
+// stream, which it returns instead; checking and writing in one script keeps
+// concurrent joins from two streams from both succeeding. It returns "" once
+// the peer is stored.
+var addPeerScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current then
+	local ok, peer = pcall(cjson.decode, current)
+	if ok and type(peer["StreamID"]) == "string" and peer["StreamID"] ~= "" and peer["StreamID"] ~= ARGV[2] then
+		return peer["StreamID"]
+	end
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+if ARGV[2] ~= "" then
+	redis.call("SADD", KEYS[2], ARGV[4])
+end
+return ""
+`)
+
+// sweepMembersScript removes from the stream set KEYS[1] the peers whose keys
+// (ARGV[1] + peer ID) no longer exist and returns how many it removed. Checking
+// and removing in one script keeps a peer re-added meanwhile in the set.
+var sweepMembersScript = redis.NewScript(`
+local removed = 0
+for _, peerID in ipairs(redis.call("SMEMBERS", KEYS[1])) do
+	if redis.call("EXISTS", ARGV[1] .. peerID) == 0 then
+		removed = removed + redis.call("SREM", KEYS[1], peerID)
+	end
+end
+return removed
+`)
+
 type RedisPeerRepository struct {
 	client *redis.Client
 	prefix string
+	// Peer keys expire after ttl unless refreshed; 0 keeps them forever
+	ttl time.Duration
 }
 
+// NewRedisPeerRepository creates a peer repository whose peers never expire
 func NewRedisPeerRepository(client *redis.Client) ports.PeerRepository {
+	return NewRedisPeerRepositoryWithTTL(client, 0)
+}
+
+// NewRedisPeerRepositoryWithTTL creates a peer repository whose peers expire
+// ttl after they were last stored or heartbeated, so peers of a crashed
+// instance do not linger
+func NewRedisPeerRepositoryWithTTL(client *redis.Client, ttl time.Duration) *RedisPeerRepository {
 	return &RedisPeerRepository{
 		client: client,
-		prefix: "rillnet:peer:",
+		prefix: peerKeyPrefix,
+		ttl:    ttl,
 	}
 }
 
@@ -33,6 +87,8 @@ func (r *RedisPeerRepository) streamPeersKey(streamID domain.StreamID) string {
 	return fmt.Sprintf("rillnet:stream:%s:peers", streamID)
 }
 
+// Add stores the peer and adds it to its stream's peer set, failing with
+// domain.ErrPeerInOtherStream when the peer is stored under another stream
 func (r *RedisPeerRepository) Add(ctx context.Context, peer *domain.Peer) error {
 	// Serialize peer to JSON
 	data, err := json.Marshal(peer)
@@ -40,18 +96,13 @@ func (r *RedisPeerRepository) Add(ctx context.Context, peer *domain.Peer) error
 		return fmt.Errorf("failed to marshal peer: %w", err)
 	}
 
-	// Store peer data
-	key := r.peerKey(peer.ID)
-	if err := r.client.Set(ctx, key, data, 0).Err(); err != nil {
+	keys := []string{r.peerKey(peer.ID), r.streamPeersKey(peer.StreamID)}
+	otherStream, err := addPeerScript.Run(ctx, r.client, keys, data, string(peer.StreamID), r.ttl.Milliseconds(), string(peer.ID)).Text()
+	if err != nil {
 		return fmt.Errorf("failed to set peer in Redis: %w", err)
 	}
-
-	// Add to stream peers set
-	if peer.StreamID != "" {
-		streamKey := r.streamPeersKey(peer.StreamID)
-		if err := r.client.SAdd(ctx, streamKey, string(peer.ID)).Err(); err != nil {
-			return fmt.Errorf("failed to add peer to stream set: %w", err)
-		}
+	if otherStream != "" {
+		return fmt.Errorf("%w: %s", domain.ErrPeerInOtherStream, otherStream)
 	}
 
 	return nil
@@ -119,6 +170,50 @@ func (r *RedisPeerRepository) FindByStream(ctx context.Context, streamID domain.
 	return peers, nil
 }
 
+// FindByFilter loads the stream's peers in one MGET and decodes them in peer
+// ID order only until filter.Limit of them match
+func (r *RedisPeerRepository) FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	peerIDs, err := r.client.SMembers(ctx, r.streamPeersKey(streamID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream peers from Redis: %w", err)
+	}
+	if len(peerIDs) == 0 {
+		return nil, nil
+	}
+	sort.Strings(peerIDs)
+
+	keys := make([]string, len(peerIDs))
+	for i, peerID := range peerIDs {
+		keys[i] = r.peerKey(domain.PeerID(peerID))
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peers from Redis: %w", err)
+	}
+
+	var matched []*domain.Peer
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// Skip peers that no longer exist
+			continue
+		}
+		var peer domain.Peer
+		if err := json.Unmarshal([]byte(data), &peer); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal peer: %w", err)
+		}
+		if !filter.Matches(&peer) {
+			continue
+		}
+		matched = append(matched, &peer)
+		if filter.Limit > 0 && len(matched) == filter.Limit {
+			break
+		}
+	}
+
+	return matched, nil
+}
+
 func (r *RedisPeerRepository) FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error) {
 	peers, err := r.FindByStream(ctx, streamID)
 	if err != nil {
@@ -185,6 +280,60 @@ func (r *RedisPeerRepository) UpdatePeerLoad(ctx context.Context, peerID domain.
 	return r.Add(ctx, peer)
 }
 
+// Heartbeat pushes back the expiry of a connected peer
+func (r *RedisPeerRepository) Heartbeat(ctx context.Context, peerID domain.PeerID) error {
+	if r.ttl <= 0 {
+		return nil
+	}
+	refreshed, err := r.client.Expire(ctx, r.peerKey(peerID), r.ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to refresh peer in Redis: %w", err)
+	}
+	if !refreshed {
+		return domain.ErrPeerNotFound
+	}
+	return nil
+}
+
+// SweepStaleMembers removes peers whose keys have expired from every stream's
+// peer set and returns how many were removed
+func (r *RedisPeerRepository) SweepStaleMembers(ctx context.Context) (int, error) {
+	removed := 0
+	iter := r.client.Scan(ctx, 0, streamPeersPattern, 100).Iterator()
+	for iter.Next(ctx) {
+		n, err := sweepMembersScript.Run(ctx, r.client, []string{iter.Val()}, r.prefix).Int()
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove stale stream peers: %w", err)
+		}
+		removed += n
+	}
+	if err := iter.Err(); err != nil {
+		return removed, fmt.Errorf("failed to scan stream peer sets: %w", err)
+	}
+	return removed, nil
+}
+
+// RunSweeper calls SweepStaleMembers every interval until ctx is done
+func (r *RedisPeerRepository) RunSweeper(ctx context.Context, interval time.Duration, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := r.SweepStaleMembers(ctx)
+			if err != nil {
+				logger.Warnw("failed to sweep expired peers from stream sets", "error", err)
+				continue
+			}
+			if removed > 0 {
+				logger.Infow("swept expired peers from stream sets", "removed", removed)
+			}
+		}
+	}
+}
+
 func (r *RedisPeerRepository) calculatePeerScore(peer *domain.Peer) float64 {
 	score := float64(peer.Metrics.Bandwidth) / 1000.0
 
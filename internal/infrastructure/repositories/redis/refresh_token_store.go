@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rotateRefreshTokenScript marks the token in KEYS[1] used and stores the
+// next token of its family in KEYS[2], expiring at ARGV[1] (Unix ms). KEYS[3]
+// is the family hash; a missing one has expired. It returns
+// {status, user ID, family ID}, status being ok, missing, revoked or reused.
+var rotateRefreshTokenScript = redis.NewScript(`
+local token = redis.call("HMGET", KEYS[1], "user_id", "family", "used")
+if not token[1] then
+	return {"missing", "", ""}
+end
+if redis.call("HGET", KEYS[3], "revoked") ~= "0" then
+	return {"revoked", token[1], token[2]}
+end
+if token[3] == "1" then
+	return {"reused", token[1], token[2]}
+end
+redis.call("HSET", KEYS[1], "used", "1")
+redis.call("HSET", KEYS[2], "user_id", token[1], "family", token[2], "used", "0")
+redis.call("PEXPIREAT", KEYS[2], ARGV[1])
+redis.call("PEXPIREAT", KEYS[3], ARGV[1])
+return {"ok", token[1], token[2]}
+`)
+
+// revokeFamilyScript flags the family in KEYS[1] revoked, leaving an expired
+// family absent rather than recreating it without an expiry
+var revokeFamilyScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HSET", KEYS[1], "revoked", "1")
+end
+return 1
+`)
+
+// RedisRefreshTokenStore keeps each refresh token in a hash naming its user,
+// family and whether it was used, and each family in a hash flagging it
+// revoked. Both expire with the family's newest token, so reuse of a rotated
+// token stays detectable for as long as that token could have been valid.
+// Keys are hash-tagged with the family ID, keeping a family's tokens in one
+// Redis Cluster slot so rotation can run as a single script.
+type RedisRefreshTokenStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisRefreshTokenStore(client *redis.Client) ports.RefreshTokenFamilyStore {
+	return &RedisRefreshTokenStore{
+		client: client,
+		prefix: "rillnet:refresh:",
+	}
+}
+
+func (s *RedisRefreshTokenStore) tokenKey(familyID, tokenHash string) string {
+	return s.prefix + "{" + familyID + "}:token:" + tokenHash
+}
+
+func (s *RedisRefreshTokenStore) familyKey(familyID string) string {
+	return s.prefix + "{" + familyID + "}:family"
+}
+
+func (s *RedisRefreshTokenStore) StartFamily(ctx context.Context, record ports.RefreshTokenRecord, tokenHash string, expiresAt time.Time) error {
+	tokenKey := s.tokenKey(record.FamilyID, tokenHash)
+	familyKey := s.familyKey(record.FamilyID)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tokenKey, "user_id", string(record.UserID), "family", record.FamilyID, "used", "0")
+		pipe.PExpireAt(ctx, tokenKey, expiresAt)
+		pipe.HSet(ctx, familyKey, "revoked", "0")
+		pipe.PExpireAt(ctx, familyKey, expiresAt)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("store refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisRefreshTokenStore) Rotate(ctx context.Context, familyID, tokenHash, nextHash string, expiresAt time.Time) (ports.RefreshTokenRecord, error) {
+	result, err := rotateRefreshTokenScript.Run(ctx, s.client,
+		[]string{s.tokenKey(familyID, tokenHash), s.tokenKey(familyID, nextHash), s.familyKey(familyID)},
+		expiresAt.UnixMilli(),
+	).StringSlice()
+	if err != nil {
+		return ports.RefreshTokenRecord{}, fmt.Errorf("rotate refresh token: %w", err)
+	}
+	if len(result) != 3 {
+		return ports.RefreshTokenRecord{}, fmt.Errorf("rotate refresh token: unexpected reply %v", result)
+	}
+
+	record := ports.RefreshTokenRecord{UserID: domain.UserID(result[1]), FamilyID: result[2]}
+	switch result[0] {
+	case "ok":
+		return record, nil
+	case "reused":
+		return record, domain.ErrRefreshTokenReused
+	default:
+		return record, domain.ErrRefreshTokenRevoked
+	}
+}
+
+func (s *RedisRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	if err := revokeFamilyScript.Run(ctx, s.client, []string{s.familyKey(familyID)}).Err(); err != nil {
+		return fmt.Errorf("revoke refresh token family: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/ports"
@@ -11,6 +12,33 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// listPageBatchSize is how many index entries are read at a time when a name
+// filter forces ListActivePage to walk the index
+const listPageBatchSize = 100
+
+// createWithOwnerLimitScript counts the owner's streams that are still in the
+// active set and stores the new stream only while that count is below the
+// limit, so concurrent creates on any instance cannot exceed the quota.
+// It returns {created, active}.
+var createWithOwnerLimitScript = redis.NewScript(`
+local active = 0
+for _, id in ipairs(redis.call("SMEMBERS", KEYS[3])) do
+	if redis.call("SISMEMBER", KEYS[2], id) == 1 then
+		active = active + 1
+	end
+end
+if active >= tonumber(ARGV[3]) then
+	return {0, active}
+end
+redis.call("SET", KEYS[1], ARGV[2])
+if ARGV[4] == "1" then
+	redis.call("SADD", KEYS[2], ARGV[1])
+	redis.call("ZADD", KEYS[4], 0, ARGV[5])
+end
+redis.call("SADD", KEYS[3], ARGV[1])
+return {1, active}
+`)
+
 type RedisStreamRepository struct {
 	client *redis.Client
 	prefix string
@@ -31,6 +59,34 @@ func (r *RedisStreamRepository) activeStreamsKey() string {
 	return r.prefix + "active"
 }
 
+func (r *RedisStreamRepository) ownerStreamsKey(owner domain.UserID) string {
+	return r.prefix + "owner:" + string(owner)
+}
+
+// activeIndexKey is a sorted set of the active streams whose members all
+// score 0, so that ranks follow the members' byte order, creation time first
+func (r *RedisStreamRepository) activeIndexKey() string {
+	return r.prefix + "active:by_created"
+}
+
+// activeIndexMember orders a stream in the active index by creation time and
+// then by ID
+func activeIndexMember(stream *domain.Stream) string {
+	var nanos int64
+	if !stream.CreatedAt.IsZero() && stream.CreatedAt.Unix() > 0 {
+		nanos = stream.CreatedAt.UnixNano()
+	}
+	return fmt.Sprintf("%019d:%s", nanos, stream.ID)
+}
+
+// streamIDFromIndexMember strips the creation time off an active index member
+func streamIDFromIndexMember(member string) domain.StreamID {
+	if i := strings.IndexByte(member, ':'); i >= 0 {
+		return domain.StreamID(member[i+1:])
+	}
+	return domain.StreamID(member)
+}
+
 func (r *RedisStreamRepository) Create(ctx context.Context, stream *domain.Stream) error {
 	// Serialize stream to JSON
 	data, err := json.Marshal(stream)
@@ -50,6 +106,38 @@ func (r *RedisStreamRepository) Create(ctx context.Context, stream *domain.Strea
 		if err := r.client.SAdd(ctx, activeKey, string(stream.ID)).Err(); err != nil {
 			return fmt.Errorf("failed to add stream to active set: %w", err)
 		}
+		if err := r.client.ZAdd(ctx, r.activeIndexKey(), redis.Z{Member: activeIndexMember(stream)}).Err(); err != nil {
+			return fmt.Errorf("failed to add stream to active index: %w", err)
+		}
+	}
+
+	// Index stream by owning user for quota lookups
+	if stream.OwnerUserID != "" {
+		if err := r.client.SAdd(ctx, r.ownerStreamsKey(stream.OwnerUserID), string(stream.ID)).Err(); err != nil {
+			return fmt.Errorf("failed to add stream to owner index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *RedisStreamRepository) CreateWithOwnerLimit(ctx context.Context, stream *domain.Stream, limit int) error {
+	data, err := json.Marshal(stream)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream: %w", err)
+	}
+
+	active := "0"
+	if stream.Active {
+		active = "1"
+	}
+	keys := []string{r.streamKey(stream.ID), r.activeStreamsKey(), r.ownerStreamsKey(stream.OwnerUserID), r.activeIndexKey()}
+	result, err := createWithOwnerLimitScript.Run(ctx, r.client, keys, string(stream.ID), data, limit, active, activeIndexMember(stream)).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("failed to create stream in Redis: %w", err)
+	}
+	if result[0] == 0 {
+		return fmt.Errorf("%w: %d/%d active streams", domain.ErrStreamQuotaExceeded, result[1], limit)
 	}
 
 	return nil
@@ -75,7 +163,7 @@ func (r *RedisStreamRepository) GetByID(ctx context.Context, id domain.StreamID)
 
 func (r *RedisStreamRepository) Update(ctx context.Context, stream *domain.Stream) error {
 	// Check if stream exists
-	_, err := r.GetByID(ctx, stream.ID)
+	existing, err := r.GetByID(ctx, stream.ID)
 	if err != nil {
 		return err
 	}
@@ -92,22 +180,55 @@ func (r *RedisStreamRepository) Update(ctx context.Context, stream *domain.Strea
 		return fmt.Errorf("failed to update stream in Redis: %w", err)
 	}
 
-	// Update active streams set
+	// Update active streams set and index
 	activeKey := r.activeStreamsKey()
+	if err := r.client.ZRem(ctx, r.activeIndexKey(), activeIndexMember(existing)).Err(); err != nil {
+		return fmt.Errorf("failed to remove stream from active index: %w", err)
+	}
 	if stream.Active {
 		if err := r.client.SAdd(ctx, activeKey, string(stream.ID)).Err(); err != nil {
 			return fmt.Errorf("failed to add stream to active set: %w", err)
 		}
+		if err := r.client.ZAdd(ctx, r.activeIndexKey(), redis.Z{Member: activeIndexMember(stream)}).Err(); err != nil {
+			return fmt.Errorf("failed to add stream to active index: %w", err)
+		}
 	} else {
 		if err := r.client.SRem(ctx, activeKey, string(stream.ID)).Err(); err != nil {
 			return fmt.Errorf("failed to remove stream from active set: %w", err)
 		}
 	}
 
+	// Move the stream between owner indexes when its owner changed
+	if existing.OwnerUserID != stream.OwnerUserID {
+		if existing.OwnerUserID != "" {
+			if err := r.client.SRem(ctx, r.ownerStreamsKey(existing.OwnerUserID), string(stream.ID)).Err(); err != nil {
+				return fmt.Errorf("failed to remove stream from owner index: %w", err)
+			}
+		}
+		if stream.OwnerUserID != "" {
+			if err := r.client.SAdd(ctx, r.ownerStreamsKey(stream.OwnerUserID), string(stream.ID)).Err(); err != nil {
+				return fmt.Errorf("failed to add stream to owner index: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
 func (r *RedisStreamRepository) Delete(ctx context.Context, id domain.StreamID) error {
+	// Remove from owner and active indexes (best effort: stream data may
+	// already be gone)
+	if stream, err := r.GetByID(ctx, id); err == nil {
+		if stream.OwnerUserID != "" {
+			if err := r.client.SRem(ctx, r.ownerStreamsKey(stream.OwnerUserID), string(id)).Err(); err != nil {
+				return fmt.Errorf("failed to remove stream from owner index: %w", err)
+			}
+		}
+		if err := r.client.ZRem(ctx, r.activeIndexKey(), activeIndexMember(stream)).Err(); err != nil {
+			return fmt.Errorf("failed to remove stream from active index: %w", err)
+		}
+	}
+
 	// Remove from active streams set
 	activeKey := r.activeStreamsKey()
 	if err := r.client.SRem(ctx, activeKey, string(id)).Err(); err != nil {
@@ -143,4 +264,171 @@ func (r *RedisStreamRepository) ListActive(ctx context.Context) ([]*domain.Strea
 	}
 
 	return streams, nil
-}
\ No newline at end of file
+}
+
+// ListActivePage pages through the active streams ordered by creation time
+// and then ID. Without a name filter the page is read straight off the active
+// index with ZRANGE; with one, the index is walked in order a batch at a time,
+// so memory use stays bounded by the page either way.
+func (r *RedisStreamRepository) ListActivePage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error) {
+	if err := r.ensureActiveIndex(ctx); err != nil {
+		return nil, 0, err
+	}
+	indexKey := r.activeIndexKey()
+
+	if opts.NameContains == "" {
+		total, err := r.client.ZCard(ctx, indexKey).Result()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count active streams in Redis: %w", err)
+		}
+		stop := int64(-1)
+		if opts.Limit > 0 {
+			stop = int64(opts.Offset + opts.Limit - 1)
+		}
+		members, err := r.client.ZRange(ctx, indexKey, int64(opts.Offset), stop).Result()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to range active streams in Redis: %w", err)
+		}
+		page, err := r.loadIndexed(ctx, members)
+		if err != nil {
+			return nil, 0, err
+		}
+		return page, int(total), nil
+	}
+
+	page := []*domain.Stream{}
+	total := 0
+	for start := int64(0); ; start += listPageBatchSize {
+		members, err := r.client.ZRange(ctx, indexKey, start, start+listPageBatchSize-1).Result()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to range active streams in Redis: %w", err)
+		}
+		streams, err := r.loadIndexed(ctx, members)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, stream := range streams {
+			if !opts.Matches(stream) {
+				continue
+			}
+			if total >= opts.Offset && (opts.Limit <= 0 || len(page) < opts.Limit) {
+				page = append(page, stream)
+			}
+			total++
+		}
+		if len(members) < listPageBatchSize {
+			break
+		}
+	}
+
+	return page, total, nil
+}
+
+// loadIndexed loads the active streams behind index members, in order.
+// Streams that are gone or no longer active are skipped.
+func (r *RedisStreamRepository) loadIndexed(ctx context.Context, members []string) ([]*domain.Stream, error) {
+	streams := []*domain.Stream{}
+	if len(members) == 0 {
+		return streams, nil
+	}
+
+	keys := make([]string, len(members))
+	for i, member := range members {
+		keys[i] = r.streamKey(streamIDFromIndexMember(member))
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active streams from Redis: %w", err)
+	}
+
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var stream domain.Stream
+		if err := json.Unmarshal([]byte(data), &stream); err != nil {
+			continue
+		}
+		if stream.Active {
+			streams = append(streams, &stream)
+		}
+	}
+	return streams, nil
+}
+
+// ensureActiveIndex rebuilds the active index from the active set when the
+// two disagree, e.g. for streams stored before the index existed. Set members
+// whose stream is gone or inactive are dropped on the way so that the counts
+// match afterwards.
+func (r *RedisStreamRepository) ensureActiveIndex(ctx context.Context) error {
+	activeKey := r.activeStreamsKey()
+	activeCount, err := r.client.SCard(ctx, activeKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to count active streams in Redis: %w", err)
+	}
+	indexCount, err := r.client.ZCard(ctx, r.activeIndexKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to count active index in Redis: %w", err)
+	}
+	if activeCount == indexCount {
+		return nil
+	}
+
+	streamIDs, err := r.client.SMembers(ctx, activeKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get active streams from Redis: %w", err)
+	}
+	var entries []redis.Z
+	var stale []interface{}
+	for _, id := range streamIDs {
+		stream, err := r.GetByID(ctx, domain.StreamID(id))
+		if err == domain.ErrStreamNotFound || (err == nil && !stream.Active) {
+			stale = append(stale, id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, redis.Z{Member: activeIndexMember(stream)})
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.activeIndexKey())
+	if len(entries) > 0 {
+		pipe.ZAdd(ctx, r.activeIndexKey(), entries...)
+	}
+	if len(stale) > 0 {
+		pipe.SRem(ctx, activeKey, stale...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to rebuild active index in Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStreamRepository) ListByOwner(ctx context.Context, owner domain.UserID) ([]*domain.Stream, error) {
+	ownerKey := r.ownerStreamsKey(owner)
+	streamIDs, err := r.client.SMembers(ctx, ownerKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owner streams from Redis: %w", err)
+	}
+
+	var streams []*domain.Stream
+	for _, streamIDStr := range streamIDs {
+		stream, err := r.GetByID(ctx, domain.StreamID(streamIDStr))
+		if err == domain.ErrStreamNotFound {
+			// Drop stale index entries
+			_ = r.client.SRem(ctx, ownerKey, streamIDStr).Err()
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if stream.Active {
+			streams = append(streams, stream)
+		}
+	}
+
+	return streams, nil
+}
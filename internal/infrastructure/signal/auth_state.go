@@ -0,0 +1,119 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/services"
+)
+
+// AuthPolicy decides what happens to messages a peer sends before its
+// token has been validated
+type AuthPolicy string
+
+const (
+	// AuthPolicyQueue holds messages and handles them once auth succeeds
+	AuthPolicyQueue AuthPolicy = "queue"
+	// AuthPolicyReject answers each message with an AUTH_PENDING error
+	AuthPolicyReject AuthPolicy = "reject"
+)
+
+// DefaultAuthTimeout is how long a connection may take to authenticate
+const DefaultAuthTimeout = 10 * time.Second
+
+// maxQueuedBeforeAuth bounds the messages held for a pending connection;
+// further messages are rejected as under AuthPolicyReject
+const maxQueuedBeforeAuth = 32
+
+// authFailedCloseReason and authTimeoutCloseReason are sent in the close
+// frame to connections that did not authenticate
+const (
+	authFailedCloseReason  = "invalid token"
+	authTimeoutCloseReason = "authentication timed out"
+)
+
+// ErrAuthPending is returned for messages sent before authentication completed.
+var ErrAuthPending = errors.New("authentication not yet complete")
+
+// TokenValidator validates a connection's token after the WebSocket upgrade,
+// e.g. against a remote introspection endpoint
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (*services.Claims, error)
+}
+
+// AuthServiceValidator validates tokens with a local AuthService
+type AuthServiceValidator struct {
+	Auth services.AuthService
+}
+
+// ValidateToken implements TokenValidator
+func (v AuthServiceValidator) ValidateToken(ctx context.Context, token string) (*services.Claims, error) {
+	return v.Auth.ValidateToken(token)
+}
+
+// SetAsyncAuth validates tokens with validator after the upgrade instead of
+// during the handshake. Messages sent meanwhile are queued or rejected per
+// policy, and connections not authenticated within timeout are closed.
+func (s *WebSocketServer) SetAsyncAuth(validator TokenValidator, policy AuthPolicy, timeout time.Duration) {
+	if policy != AuthPolicyReject {
+		policy = AuthPolicyQueue
+	}
+	if timeout <= 0 {
+		timeout = DefaultAuthTimeout
+	}
+	s.tokenValidator = validator
+	s.authPolicy = policy
+	s.authTimeout = timeout
+}
+
+type authState int
+
+const (
+	authPending authState = iota
+	authConfirmed
+)
+
+// connAuth tracks whether a connection has authenticated and holds the
+// messages it sent before. Only the connection's read loop uses it.
+type connAuth struct {
+	state  authState
+	policy AuthPolicy
+	queued []SignalMessage
+}
+
+func newConnAuth(policy AuthPolicy) *connAuth {
+	return &connAuth{state: authPending, policy: policy}
+}
+
+// admit reports whether msg may be handled now. Otherwise it is queued, or
+// rejected with ErrAuthPending.
+func (a *connAuth) admit(msg SignalMessage) (bool, error) {
+	if a.state == authConfirmed {
+		return true, nil
+	}
+	if a.policy == AuthPolicyReject {
+		return false, ErrAuthPending
+	}
+	if len(a.queued) >= maxQueuedBeforeAuth {
+		return false, fmt.Errorf("%w: too many messages queued", ErrAuthPending)
+	}
+	a.queued = append(a.queued, msg)
+	return false, nil
+}
+
+// confirm marks the connection authenticated and returns the queued
+// messages, in the order they were sent
+func (a *connAuth) confirm() []SignalMessage {
+	a.state = authConfirmed
+	queued := a.queued
+	a.queued = nil
+	return queued
+}
+
+// authOutcome is the result of validating a connection's token
+type authOutcome struct {
+	claims *services.Claims
+	err    error
+}
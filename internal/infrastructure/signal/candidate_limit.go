@@ -0,0 +1,50 @@
+package signal
+
+import (
+	"sync/atomic"
+
+	"rillnet/internal/core/domain"
+	"rillnet/pkg/ratelimit"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultMaxICECandidatesPerMinute is how many ICE candidates a peer may send
+// per minute before further candidates are dropped
+const DefaultMaxICECandidatesPerMinute = 200
+
+var iceCandidatesDropped = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rillnet_signal_ice_candidates_dropped_total",
+	Help: "ICE candidates dropped because the sending peer exceeded its candidate limit",
+})
+
+// SetMaxICECandidates sets how many ICE candidates a peer may send per minute;
+// 0 disables the limit.
+func (s *WebSocketServer) SetMaxICECandidates(perMinute int) {
+	if perMinute < 0 {
+		return
+	}
+	s.candidateLimiter = ratelimit.NewKeyedLimiter[domain.PeerID](perMinute)
+}
+
+// allowICECandidate reports whether a candidate from peerID is within its
+// limit, counting and warning once per peer about dropped candidates
+func (s *WebSocketServer) allowICECandidate(peerID domain.PeerID) bool {
+	allowed, firstDrop := s.candidateLimiter.Allow(peerID)
+	if allowed {
+		return true
+	}
+	atomic.AddInt64(&s.droppedCandidates, 1)
+	iceCandidatesDropped.Inc()
+	if firstDrop {
+		s.logger.Warnw("peer exceeded ICE candidate limit, dropping candidates", "peer_id", peerID)
+	}
+	return false
+}
+
+// DroppedICECandidates returns how many ICE candidates were dropped for
+// exceeding the per-peer limit.
+func (s *WebSocketServer) DroppedICECandidates() int64 {
+	return atomic.LoadInt64(&s.droppedCandidates)
+}
@@ -0,0 +1,53 @@
+package signal
+
+import (
+	"time"
+
+	"rillnet/pkg/config"
+
+	"golang.org/x/time/rate"
+)
+
+// Rate limits in effect unless rate_limiting configures others
+const (
+	DefaultConnectionRate  = rate.Limit(1) // connections per second
+	DefaultConnectionBurst = 5
+	DefaultMessageRate     = rate.Limit(100) // messages per second, per peer
+	DefaultMessageBurst    = 200
+)
+
+// ApplyConfig adopts the WebSocket rate limits of a reloaded config: the
+// connection rate, the per-peer message rate (connected peers included) and
+// the concurrent connection cap. Limits rate_limiting leaves unset, or all of
+// them when it is disabled, return to their defaults. Other signal settings
+// apply to connections opened after a restart.
+func (s *WebSocketServer) ApplyConfig(cfg *config.Config) {
+	ws := cfg.RateLimiting.WebSocket
+	enabled := cfg.RateLimiting.Enabled
+
+	if enabled && ws.ConnectionsPerMinute > 0 {
+		s.setConnectionRate(rate.Every(time.Minute/time.Duration(ws.ConnectionsPerMinute)), ws.ConnectionsPerMinute)
+	} else {
+		s.setConnectionRate(DefaultConnectionRate, DefaultConnectionBurst)
+	}
+
+	if enabled && ws.MessagesPerSecond > 0 && ws.Burst > 0 {
+		s.setMessageRate(rate.Limit(ws.MessagesPerSecond), ws.Burst)
+	} else {
+		s.setMessageRate(DefaultMessageRate, DefaultMessageBurst)
+	}
+
+	maxConcurrent := 0
+	if enabled {
+		maxConcurrent = ws.MaxConcurrent
+	}
+	s.SetMaxConcurrentConnections(maxConcurrent)
+
+	s.logger.Infow("websocket rate limits reloaded",
+		"enabled", enabled,
+		"connections_per_minute", ws.ConnectionsPerMinute,
+		"messages_per_second", ws.MessagesPerSecond,
+		"burst", ws.Burst,
+		"max_concurrent_connections", maxConcurrent,
+	)
+}
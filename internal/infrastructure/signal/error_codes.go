@@ -0,0 +1,67 @@
+package signal
+
+import (
+	"encoding/json"
+	"errors"
+
+	"rillnet/internal/core/domain"
+	"rillnet/pkg/validation"
+	sdputil "rillnet/pkg/webrtc"
+)
+
+// ErrorCode is the machine-readable code of an "error" message sent to a peer
+type ErrorCode string
+
+const (
+	ErrCodeInvalidSDP         ErrorCode = "INVALID_SDP"
+	ErrCodeInvalidPayload     ErrorCode = "INVALID_PAYLOAD"
+	ErrCodeUnknownMessageType ErrorCode = "UNKNOWN_MESSAGE_TYPE"
+	ErrCodeTargetNotConnected ErrorCode = "TARGET_NOT_CONNECTED"
+	ErrCodeRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrCodeAlreadyInStream    ErrorCode = "ALREADY_IN_STREAM"
+	ErrCodeAuthPending        ErrorCode = "AUTH_PENDING"
+	// ErrCodeBadRequest is used for any other rejected message
+	ErrCodeBadRequest ErrorCode = "BAD_REQUEST"
+)
+
+// ErrInvalidSDP is returned for offers and answers whose SDP is rejected.
+var ErrInvalidSDP = errors.New("invalid SDP")
+
+// ErrUnknownMessageType is returned for messages of a type the server does not handle.
+var ErrUnknownMessageType = errors.New("unknown message type")
+
+// ErrTargetNotConnected is returned when the peer a message is routed to has no open connection.
+var ErrTargetNotConnected = errors.New("target peer is not connected")
+
+// ErrRateLimited is returned when a peer sends messages faster than its rate limit.
+var ErrRateLimited = errors.New("message rate limit exceeded")
+
+// errorCode maps an error returned by a message handler to its code
+func errorCode(err error) ErrorCode {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var idErr *domain.IDTypeError
+
+	switch {
+	case errors.Is(err, ErrInvalidSDP), errors.Is(err, sdputil.ErrMediaDirectionMismatch):
+		return ErrCodeInvalidSDP
+	case errors.Is(err, ErrMissingPayload), errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &idErr),
+		errors.Is(err, validation.ErrMetricOutOfRange):
+		return ErrCodeInvalidPayload
+	case errors.Is(err, ErrUnknownMessageType):
+		return ErrCodeUnknownMessageType
+	case errors.Is(err, ErrTargetNotConnected):
+		return ErrCodeTargetNotConnected
+	case errors.Is(err, ErrRateLimited):
+		return ErrCodeRateLimited
+	case errors.Is(err, ErrSignalingNotAllowed):
+		return ErrCodeUnauthorized
+	case errors.Is(err, domain.ErrPeerInOtherStream):
+		return ErrCodeAlreadyInStream
+	case errors.Is(err, ErrAuthPending):
+		return ErrCodeAuthPending
+	default:
+		return ErrCodeBadRequest
+	}
+}
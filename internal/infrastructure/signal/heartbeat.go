@@ -0,0 +1,25 @@
+package signal
+
+import (
+	"context"
+	"errors"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+)
+
+// heartbeatPeer keeps a connected peer's repository entry from expiring.
+// Repositories without expiry are left alone, as are peers that have not
+// joined a stream yet.
+func (s *WebSocketServer) heartbeatPeer(peerID domain.PeerID) {
+	heartbeater, ok := s.peerRepo.(ports.PeerHeartbeater)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.writeTimeout)
+	defer cancel()
+	if err := heartbeater.Heartbeat(ctx, peerID); err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+		s.logger.Infow("failed to refresh peer registration", "peer_id", peerID, "error", err)
+	}
+}
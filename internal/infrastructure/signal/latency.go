@@ -0,0 +1,85 @@
+package signal
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"rillnet/internal/core/domain"
+)
+
+// pingPayload stamps a ping with its send time; clients echo the payload in
+// the pong, so the round trip needs no per-peer bookkeeping of pings in flight
+func pingPayload(sentAt time.Time) []byte {
+	return []byte(strconv.FormatInt(sentAt.UnixNano(), 10))
+}
+
+// recordPong stores the round-trip time of the ping a pong answers. Pongs
+// that do not echo a ping of ours (unsolicited, or mangled) are ignored.
+func (p *peerConn) recordPong(appData string, receivedAt time.Time) {
+	sentAt, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		return
+	}
+	rtt := receivedAt.Sub(time.Unix(0, sentAt))
+	if rtt <= 0 {
+		return
+	}
+	atomic.StoreInt64(&p.latency, int64(rtt))
+}
+
+// GetPeerLatency returns the signaling round-trip time measured by the last
+// ping/pong with a connected peer, at the WebSocket or the application level;
+// false until one has been measured. It is kept apart from the peer's media
+// metrics.
+func (s *WebSocketServer) GetPeerLatency(peerID domain.PeerID) (time.Duration, bool) {
+	s.mu.RLock()
+	pc, exists := s.connections[peerID]
+	s.mu.RUnlock()
+	if !exists {
+		return 0, false
+	}
+	latency := time.Duration(atomic.LoadInt64(&pc.latency))
+	return latency, latency > 0
+}
+
+// averageLatency returns the mean round-trip time over peers with a
+// measurement, and how many peers that is
+func (s *WebSocketServer) averageLatency() (time.Duration, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total time.Duration
+	measured := 0
+	for _, pc := range s.connections {
+		if latency := time.Duration(atomic.LoadInt64(&pc.latency)); latency > 0 {
+			total += latency
+			measured++
+		}
+	}
+	if measured == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(measured), measured
+}
+
+// stampPong records when a pong answering an application ping was sent and
+// returns the stamp to send in it. Only the latest stamp is accepted back.
+func (p *peerConn) stampPong(sentAt time.Time) int64 {
+	stamp := sentAt.UnixNano()
+	atomic.StoreInt64(&p.pongSentAt, stamp)
+	return stamp
+}
+
+// recordPongEcho stores the round trip closed by a client echoing a pong
+// stamp. The server measures it with its own clock, and only the latest
+// outstanding stamp counts once, so a client can delay the echo but cannot
+// report a shorter round trip.
+func (p *peerConn) recordPongEcho(stamp int64, receivedAt time.Time) {
+	if stamp == 0 || !atomic.CompareAndSwapInt64(&p.pongSentAt, stamp, 0) {
+		return
+	}
+	if rtt := receivedAt.Sub(time.Unix(0, stamp)); rtt > 0 {
+		atomic.StoreInt64(&p.latency, int64(rtt))
+	}
+}
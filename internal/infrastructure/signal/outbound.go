@@ -0,0 +1,124 @@
+package signal
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultMaxOutboundBacklog is the number of queued outbound messages a peer
+// may lag behind before it is disconnected as too slow.
+const DefaultMaxOutboundBacklog = 256
+
+// slowConsumerCloseReason is sent in the close frame to dropped peers
+const slowConsumerCloseReason = "too slow"
+
+// slowConsumerCloseTimeout bounds how long a stalled socket may delay the close
+const slowConsumerCloseTimeout = time.Second
+
+// ErrSlowConsumer is returned when a message cannot be queued because the
+// peer's outbound backlog is full; the peer is disconnected.
+var ErrSlowConsumer = errors.New("peer outbound backlog exceeded")
+
+var slowConsumerDisconnects = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rillnet_signal_slow_consumer_disconnects_total",
+	Help: "WebSocket peers disconnected because their outbound backlog was exceeded",
+})
+
+// peerConn owns a peer's WebSocket and serialises writes through a bounded
+// queue drained by a single writer goroutine.
+type peerConn struct {
+	conn   *websocket.Conn
+	send   chan interface{}
+	userID domain.UserID // Authenticated owner of the connection
+
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   int32
+	latency   int64 // Last ping round-trip time in nanoseconds, 0 until measured
+	// Send time (Unix ns) of the last pong answering an application ping,
+	// until the client echoes it back; 0 when none is outstanding
+	pongSentAt int64
+}
+
+func newPeerConn(conn *websocket.Conn, userID domain.UserID, maxBacklog int) *peerConn {
+	return &peerConn{
+		conn:   conn,
+		send:   make(chan interface{}, maxBacklog),
+		userID: userID,
+		done:   make(chan struct{}),
+	}
+}
+
+// enqueue queues msg for the writer without blocking. It returns
+// ErrSlowConsumer when the backlog is full.
+func (p *peerConn) enqueue(msg interface{}) error {
+	select {
+	case <-p.done:
+		return websocket.ErrCloseSent
+	default:
+	}
+
+	select {
+	case p.send <- msg:
+		return nil
+	default:
+		return ErrSlowConsumer
+	}
+}
+
+// close stops the writer and closes the socket, sending a close frame first
+// unless code is 0. Control frames may be written concurrently with the writer.
+func (p *peerConn) close(code int, reason string, timeout time.Duration) {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		if code != 0 {
+			_ = p.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(timeout))
+		}
+		_ = p.conn.Close()
+	})
+}
+
+// writeLoop drains the peer's queue until the connection is closed
+func (s *WebSocketServer) writeLoop(peerID domain.PeerID, p *peerConn) {
+	for {
+		select {
+		case msg := <-p.send:
+			_ = p.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+			if err := p.conn.WriteJSON(msg); err != nil {
+				s.logger.Infow("error writing message to peer", "peer_id", peerID, "error", err)
+				p.close(0, "", 0)
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// dropSlowConsumer disconnects a peer whose outbound backlog was exceeded
+func (s *WebSocketServer) dropSlowConsumer(peerID domain.PeerID, p *peerConn) {
+	if !atomic.CompareAndSwapInt32(&p.dropped, 0, 1) {
+		return
+	}
+
+	atomic.AddInt64(&s.slowConsumerDrops, 1)
+	slowConsumerDisconnects.Inc()
+	s.logger.Warnw("disconnecting slow peer", "peer_id", peerID, "max_outbound_backlog", cap(p.send))
+
+	// Queuing callers must not wait on the stalled socket
+	go p.close(websocket.ClosePolicyViolation, slowConsumerCloseReason, slowConsumerCloseTimeout)
+}
+
+// SlowConsumerDisconnects returns how many peers were dropped for exceeding
+// the outbound backlog.
+func (s *WebSocketServer) SlowConsumerDisconnects() int64 {
+	return atomic.LoadInt64(&s.slowConsumerDrops)
+}
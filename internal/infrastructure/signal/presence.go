@@ -0,0 +1,237 @@
+package signal
+
+import (
+	"encoding/json"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/gorilla/websocket"
+)
+
+// kickedCloseReason is sent to peers a moderator removed from their stream
+const kickedCloseReason = "kicked"
+
+// joinStreamMembership records that a locally connected peer joined a stream
+// and returns the stream it was in before, if any. Callers hold s.mu.
+func (s *WebSocketServer) joinStreamMembership(peerID domain.PeerID, streamID domain.StreamID) (domain.StreamID, bool) {
+	previous, hadPrevious := s.leaveStreamMembership(peerID)
+
+	members, ok := s.streamMembers[streamID]
+	if !ok {
+		members = make(map[domain.PeerID]struct{})
+		s.streamMembers[streamID] = members
+	}
+	members[peerID] = struct{}{}
+	s.peerStreams[peerID] = streamID
+
+	return previous, hadPrevious && previous != streamID
+}
+
+// leaveStreamMembership forgets a peer's stream membership and returns the
+// stream it was in. Callers hold s.mu.
+func (s *WebSocketServer) leaveStreamMembership(peerID domain.PeerID) (domain.StreamID, bool) {
+	streamID, ok := s.peerStreams[peerID]
+	if !ok {
+		return "", false
+	}
+	delete(s.peerStreams, peerID)
+	if members := s.streamMembers[streamID]; members != nil {
+		delete(members, peerID)
+		if len(members) == 0 {
+			delete(s.streamMembers, streamID)
+		}
+	}
+	return streamID, true
+}
+
+// broadcastToMembers queues message for every local member of a stream except
+// one peer. Callers hold s.mu (read or write).
+func (s *WebSocketServer) broadcastToMembers(streamID domain.StreamID, except domain.PeerID, message interface{}) []error {
+	var errs []error
+	for peerID := range s.streamMembers[streamID] {
+		if peerID == except {
+			continue
+		}
+		pc, ok := s.connections[peerID]
+		if !ok {
+			continue
+		}
+		if err := s.enqueue(peerID, pc, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// peerJoinedEvent announces a newcomer to the peers already in its stream
+func peerJoinedEvent(peer *domain.Peer) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "peer_joined",
+		"peer_id":   peer.ID,
+		"stream_id": peer.StreamID,
+		"address":   peer.Address,
+		"role":      sourceRole(peer),
+		"capabilities": map[string]interface{}{
+			"max_bitrate":  peer.Capabilities.MaxBitrate,
+			"codecs":       peer.Capabilities.SupportedCodecs,
+			"is_publisher": peer.Capabilities.IsPublisher,
+			"can_relay":    peer.Capabilities.CanRelay,
+			"is_observer":  peer.Capabilities.IsObserver,
+		},
+	}
+}
+
+// NotifyLocalPeerLeft sends peer_left to the stream's members connected to
+// this instance, for peers the SFU dropped
+func (s *WebSocketServer) NotifyLocalPeerLeft(streamID domain.StreamID, peerID domain.PeerID) {
+	s.mu.Lock()
+	s.forgetLayersLocked(streamID, peerID)
+	errs := s.broadcastToMembers(streamID, peerID, peerLeftEvent(streamID, peerID))
+	s.mu.Unlock()
+	for _, err := range errs {
+		s.logger.Debugw("failed to notify peer of departure", "left_peer_id", peerID, "error", err)
+	}
+}
+
+// NotifyLocalPeerKicked closes the connection of a peer a moderator kicked
+// from the stream, giving kickedCloseReason in the close frame
+func (s *WebSocketServer) NotifyLocalPeerKicked(streamID domain.StreamID, peerID domain.PeerID) {
+	s.mu.RLock()
+	pc, ok := s.connections[peerID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	s.logger.Infow("disconnecting kicked peer", "peer_id", peerID, "stream_id", streamID)
+	reason, _ := json.Marshal(CloseReason{Reason: kickedCloseReason})
+	pc.close(websocket.ClosePolicyViolation, string(reason), s.writeTimeout)
+}
+
+// NotifyLocalStreamStopped sends stream_stopped to the stream's members
+// connected to this instance
+func (s *WebSocketServer) NotifyLocalStreamStopped(streamID domain.StreamID) {
+	s.mu.Lock()
+	delete(s.streamLayers, streamID)
+	errs := s.broadcastToMembers(streamID, "", map[string]interface{}{
+		"type":      "stream_stopped",
+		"stream_id": streamID,
+	})
+	s.mu.Unlock()
+	for _, err := range errs {
+		s.logger.Debugw("failed to notify peer of stream stop", "stream_id", streamID, "error", err)
+	}
+}
+
+// NotifyLocalStreamReset sends stream_reset to the stream's members
+// connected to this instance, asking them to join again
+func (s *WebSocketServer) NotifyLocalStreamReset(streamID domain.StreamID) {
+	s.mu.Lock()
+	delete(s.streamLayers, streamID)
+	errs := s.broadcastToMembers(streamID, "", map[string]interface{}{
+		"type":      "stream_reset",
+		"stream_id": streamID,
+	})
+	s.mu.Unlock()
+	for _, err := range errs {
+		s.logger.Debugw("failed to notify peer of stream reset", "stream_id", streamID, "error", err)
+	}
+}
+
+// NotifyLocalLayersAvailable sends layers_available to the stream's members
+// connected to this instance, and remembers the layers for subscribers that
+// join later
+func (s *WebSocketServer) NotifyLocalLayersAvailable(streamID domain.StreamID, publisherID domain.PeerID, layers []string) {
+	s.mu.Lock()
+	byPublisher, ok := s.streamLayers[streamID]
+	if !ok {
+		byPublisher = make(map[domain.PeerID][]string)
+		s.streamLayers[streamID] = byPublisher
+	}
+	byPublisher[publisherID] = layers
+	errs := s.broadcastToMembers(streamID, publisherID, layersAvailableEvent(streamID, publisherID, layers))
+	s.mu.Unlock()
+	for _, err := range errs {
+		s.logger.Debugw("failed to notify peer of available layers", "publisher_id", publisherID, "error", err)
+	}
+}
+
+// sendKnownLayers tells a joining subscriber the layers of the stream's
+// simulcasting publishers
+func (s *WebSocketServer) sendKnownLayers(peerID domain.PeerID, streamID domain.StreamID) error {
+	s.mu.RLock()
+	events := make([]map[string]interface{}, 0, len(s.streamLayers[streamID]))
+	for publisherID, layers := range s.streamLayers[streamID] {
+		events = append(events, layersAvailableEvent(streamID, publisherID, layers))
+	}
+	s.mu.RUnlock()
+
+	for _, event := range events {
+		if err := s.sendToPeer(peerID, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forgetLayersLocked drops the remembered layers of a publisher that left.
+// Callers hold s.mu.
+func (s *WebSocketServer) forgetLayersLocked(streamID domain.StreamID, peerID domain.PeerID) {
+	byPublisher, ok := s.streamLayers[streamID]
+	if !ok {
+		return
+	}
+	delete(byPublisher, peerID)
+	if len(byPublisher) == 0 {
+		delete(s.streamLayers, streamID)
+	}
+}
+
+// layersAvailableEvent lists the simulcast layers a publisher is producing
+func layersAvailableEvent(streamID domain.StreamID, publisherID domain.PeerID, layers []string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":         "layers_available",
+		"stream_id":    streamID,
+		"publisher_id": publisherID,
+		"layers":       layers,
+	}
+}
+
+func peerLeftEvent(streamID domain.StreamID, peerID domain.PeerID) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "peer_left",
+		"peer_id":   peerID,
+		"stream_id": streamID,
+	}
+}
+
+// announceJoin records a joined peer's membership, tells the stream's other
+// local peers about it and, when it switched streams, tells its old stream
+// that it left.
+func (s *WebSocketServer) announceJoin(peer *domain.Peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, switched := s.joinStreamMembership(peer.ID, peer.StreamID); switched {
+		s.broadcastToMembers(previous, peer.ID, peerLeftEvent(previous, peer.ID))
+	}
+	for _, err := range s.broadcastToMembers(peer.StreamID, peer.ID, peerJoinedEvent(peer)) {
+		s.logger.Debugw("failed to announce joined peer", "peer_id", peer.ID, "error", err)
+	}
+}
+
+// announceLeave forgets a peer's membership and tells the stream's other local
+// peers that it left. It does nothing for a peer that joined no stream.
+func (s *WebSocketServer) announceLeave(peerID domain.PeerID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	streamID, ok := s.leaveStreamMembership(peerID)
+	if !ok {
+		return
+	}
+	s.forgetLayersLocked(streamID, peerID)
+	for _, err := range s.broadcastToMembers(streamID, peerID, peerLeftEvent(streamID, peerID)) {
+		s.logger.Debugw("failed to announce peer departure", "peer_id", peerID, "error", err)
+	}
+}
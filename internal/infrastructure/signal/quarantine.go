@@ -0,0 +1,228 @@
+package signal
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// quarantineCloseReason is sent to peers closed for running out of strikes
+const quarantineCloseReason = "quarantined"
+
+// goodMessagesToClearStrikes is how many messages in a row a peer must get
+// right for its strikes to be forgiven
+const goodMessagesToClearStrikes = 20
+
+// strikePruneEvery is how many strikes pass between sweeps of stale records
+const strikePruneEvery = 256
+
+var signalQuarantines = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rillnet_signal_quarantines_total",
+	Help: "Peers and IPs quarantined for repeatedly sending invalid messages",
+})
+
+// strikeTracker counts misbehaviour per key (a peer ID or an IP) and
+// quarantines keys that collect limit strikes within window for cooldown.
+type strikeTracker struct {
+	limit    int
+	window   time.Duration
+	cooldown time.Duration
+
+	mu         sync.Mutex
+	records    map[string]*strikeRecord
+	sinceSweep int
+}
+
+type strikeRecord struct {
+	strikes          []time.Time // Strikes within the window, oldest first
+	goodStreak       int         // Well-formed messages since the last strike
+	quarantinedUntil time.Time
+}
+
+func newStrikeTracker(limit int, window, cooldown time.Duration) *strikeTracker {
+	return &strikeTracker{
+		limit:    limit,
+		window:   window,
+		cooldown: cooldown,
+		records:  make(map[string]*strikeRecord),
+	}
+}
+
+// strike records misbehaviour by key at now and reports whether it put the
+// key in quarantine
+func (t *strikeTracker) strike(key string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sinceSweep++
+	if t.sinceSweep >= strikePruneEvery {
+		t.pruneLocked(now)
+	}
+
+	record, ok := t.records[key]
+	if !ok {
+		record = &strikeRecord{}
+		t.records[key] = record
+	}
+	record.goodStreak = 0
+
+	cutoff := now.Add(-t.window)
+	recent := record.strikes[:0]
+	for _, at := range record.strikes {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	record.strikes = append(recent, now)
+
+	if len(record.strikes) < t.limit {
+		return false
+	}
+	record.strikes = nil
+	record.quarantinedUntil = now.Add(t.cooldown)
+	return true
+}
+
+// good records a well-formed message by key; a long enough run of them
+// forgives the key's strikes
+func (t *strikeTracker) good(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, ok := t.records[key]
+	if !ok || len(record.strikes) == 0 {
+		return
+	}
+	record.goodStreak++
+	if record.goodStreak >= goodMessagesToClearStrikes {
+		record.strikes = nil
+		record.goodStreak = 0
+	}
+}
+
+// quarantined returns how much longer key is quarantined at now
+func (t *strikeTracker) quarantined(key string, now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, ok := t.records[key]
+	if !ok {
+		return 0, false
+	}
+	remaining := record.quarantinedUntil.Sub(now)
+	return remaining, remaining > 0
+}
+
+// pruneLocked drops records with no recent strikes and no quarantine in
+// force; the caller holds t.mu
+func (t *strikeTracker) pruneLocked(now time.Time) {
+	t.sinceSweep = 0
+	cutoff := now.Add(-t.window)
+	for key, record := range t.records {
+		if now.Before(record.quarantinedUntil) {
+			continue
+		}
+		if n := len(record.strikes); n > 0 && record.strikes[n-1].After(cutoff) {
+			continue
+		}
+		delete(t.records, key)
+	}
+}
+
+// peerStrikeKey keys strikes on the authenticated user as well as the
+// client-chosen peer ID, so no one can get another user's peer quarantined
+func peerStrikeKey(userID domain.UserID, peerID domain.PeerID) string {
+	return "peer:" + string(userID) + "/" + string(peerID)
+}
+
+func ipStrikeKey(ip string) string { return "ip:" + ip }
+
+// SetQuarantine blocks peers and IPs that send strikes invalid messages
+// within window from connecting again for cooldown. 0 strikes disables it.
+func (s *WebSocketServer) SetQuarantine(strikes int, window, cooldown time.Duration) {
+	if strikes <= 0 || window <= 0 || cooldown <= 0 {
+		s.quarantine = nil
+		return
+	}
+	s.quarantine = newStrikeTracker(strikes, window, cooldown)
+}
+
+// SetQuarantineByIP also counts strikes per remote address and refuses
+// quarantined addresses. Only for servers that clients reach directly: behind
+// a proxy the remote address is the proxy's, shared by every client.
+func (s *WebSocketServer) SetQuarantineByIP(enabled bool) {
+	s.quarantineByIP = enabled
+}
+
+// quarantinedFor returns how much longer the peer or its IP is refused
+func (s *WebSocketServer) quarantinedFor(key string) (time.Duration, bool) {
+	if s.quarantine == nil {
+		return 0, false
+	}
+	return s.quarantine.quarantined(key, time.Now())
+}
+
+// isStrike reports whether a message error is the peer's misbehaviour rather
+// than a transient or server-side failure
+func isStrike(err error) bool {
+	switch errorCode(err) {
+	case ErrCodeInvalidSDP, ErrCodeInvalidPayload, ErrCodeUnknownMessageType, ErrCodeRateLimited, ErrCodeUnauthorized:
+		return true
+	default:
+		return false
+	}
+}
+
+// judgeMessage updates the strikes of the peer and, with SetQuarantineByIP,
+// its IP after a message was handled with err. A peer that runs out of
+// strikes is disconnected, told how long its quarantine lasts, and true is
+// returned.
+func (s *WebSocketServer) judgeMessage(peerID domain.PeerID, ip string, pc *peerConn, err error) bool {
+	if s.quarantine == nil {
+		return false
+	}
+	peerKey := peerStrikeKey(pc.userID, peerID)
+	if err == nil {
+		s.quarantine.good(peerKey)
+		if s.quarantineByIP {
+			s.quarantine.good(ipStrikeKey(ip))
+		}
+		return false
+	}
+	if !isStrike(err) {
+		return false
+	}
+
+	now := time.Now()
+	peerQuarantined := s.quarantine.strike(peerKey, now)
+	ipQuarantined := s.quarantineByIP && s.quarantine.strike(ipStrikeKey(ip), now)
+	if !peerQuarantined && !ipQuarantined {
+		return false
+	}
+
+	signalQuarantines.Inc()
+	s.logger.Warnw("quarantining misbehaving peer", "peer_id", peerID, "remote_addr", ip, "cooldown", s.quarantine.cooldown)
+
+	reason, _ := json.Marshal(CloseReason{
+		Reason:           quarantineCloseReason,
+		ReconnectAfterMs: s.quarantine.cooldown.Milliseconds(),
+	})
+	pc.close(websocket.ClosePolicyViolation, string(reason), s.writeTimeout)
+	return true
+}
+
+// rejectQuarantined refuses a handshake during a quarantine
+func (s *WebSocketServer) rejectQuarantined(w http.ResponseWriter, remaining time.Duration) {
+	seconds := int(math.Ceil(remaining.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "quarantined, retry later", http.StatusTooManyRequests)
+}
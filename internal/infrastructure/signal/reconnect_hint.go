@@ -0,0 +1,71 @@
+package signal
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+)
+
+// Bounds of the reconnect delay advertised in server-initiated close frames
+const (
+	DefaultReconnectBackoff    = time.Second
+	DefaultReconnectBackoffMax = 30 * time.Second
+)
+
+// overloadCloseReason is sent to peers turned away at the connection cap
+const overloadCloseReason = "server overloaded"
+
+// shutdownCloseReason is sent to peers closed by a graceful shutdown
+const shutdownCloseReason = "server shutting down"
+
+// CloseReason is the JSON text of close frames the server sends when it
+// drops peers for its own reasons, telling them when to come back.
+type CloseReason struct {
+	Reason           string `json:"reason"`
+	ReconnectAfterMs int64  `json:"reconnect_after_ms"`
+}
+
+// SetReconnectBackoff sets the range of the reconnect delay advertised to
+// peers the server disconnects: base when idle, up to max at full load.
+func (s *WebSocketServer) SetReconnectBackoff(base, max time.Duration) {
+	if base <= 0 || max < base {
+		return
+	}
+	s.reconnectBackoff = base
+	s.reconnectBackoffMax = max
+}
+
+// connectionPressure is the share of the connection cap in use, from 0 to 1;
+// always 0 without a cap. The caller holds s.mu.
+func (s *WebSocketServer) connectionPressure() float64 {
+	if s.maxConcurrent <= 0 {
+		return 0
+	}
+	pressure := float64(len(s.connections)) / float64(s.maxConcurrent)
+	if pressure > 1 {
+		return 1
+	}
+	return pressure
+}
+
+// reconnectAfter scales the advertised delay with pressure and jitters it, so
+// peers closed together do not all reconnect at the same moment
+func (s *WebSocketServer) reconnectAfter(pressure float64) time.Duration {
+	delay := s.reconnectBackoff + time.Duration(float64(s.reconnectBackoffMax-s.reconnectBackoff)*pressure)
+
+	// Half of the delay is fixed, the other half random
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// closeReason formats the close frame text for a server-initiated close
+func (s *WebSocketServer) closeReason(reason string, pressure float64) string {
+	text, err := json.Marshal(CloseReason{
+		Reason:           reason,
+		ReconnectAfterMs: s.reconnectAfter(pressure).Milliseconds(),
+	})
+	if err != nil {
+		return reason
+	}
+	return string(text)
+}
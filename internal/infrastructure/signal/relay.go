@@ -0,0 +1,191 @@
+package signal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// relayChannelPrefix is followed by the instance ID a relayed message is for
+const relayChannelPrefix = "rillnet:signal:"
+
+// relayPublishTimeout bounds the registry lookup and publish of one message
+const relayPublishTimeout = 2 * time.Second
+
+// PeerLocator records which signal instance each peer is connected to
+// (implemented by distributed.SharedPeerRegistry)
+type PeerLocator interface {
+	RegisterPeer(ctx context.Context, peer *domain.Peer) error
+	UnregisterPeer(ctx context.Context, peerID domain.PeerID) error
+	RefreshPeer(ctx context.Context, peerID domain.PeerID) error
+	RefreshInterval() time.Duration
+	GetPeerInstance(ctx context.Context, peerID domain.PeerID) (string, error)
+}
+
+// relayEnvelope carries a message for a peer connected to another instance
+type relayEnvelope struct {
+	TargetPeer domain.PeerID   `json:"target_peer"`
+	Message    json.RawMessage `json:"message"`
+}
+
+// crossInstanceRelay delivers messages to peers connected to other signal
+// instances through a Redis channel per instance
+type crossInstanceRelay struct {
+	client     *redis.Client
+	locator    PeerLocator
+	instanceID string
+	cancel     context.CancelFunc
+}
+
+func relayChannel(instanceID string) string {
+	return relayChannelPrefix + instanceID
+}
+
+// EnableCrossInstanceRelay routes messages for peers connected to other
+// instances over Redis pub/sub: joined peers are registered with the locator
+// under instanceID, and messages published to this instance's channel are
+// delivered to its local connections until Shutdown.
+func (s *WebSocketServer) EnableCrossInstanceRelay(client *redis.Client, locator PeerLocator, instanceID string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := &crossInstanceRelay{
+		client:     client,
+		locator:    locator,
+		instanceID: instanceID,
+		cancel:     cancel,
+	}
+
+	// Subscribe before returning so no message published after this is missed
+	pubsub := client.Subscribe(ctx, relayChannel(instanceID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		s.logger.Warnw("failed to subscribe to signal relay channel", "instance_id", instanceID, "error", err)
+	}
+
+	s.mu.Lock()
+	s.relay = relay
+	s.mu.Unlock()
+
+	go s.relayLoop(ctx, pubsub)
+	go s.refreshLoop(ctx, locator)
+}
+
+// relayLoop delivers messages relayed to this instance
+func (s *WebSocketServer) relayLoop(ctx context.Context, pubsub *redis.PubSub) {
+	defer func() { _ = pubsub.Close() }()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var envelope relayEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				s.logger.Warnw("failed to unmarshal relayed message", "error", err)
+				continue
+			}
+
+			s.mu.RLock()
+			pc, exists := s.connections[envelope.TargetPeer]
+			s.mu.RUnlock()
+			if !exists {
+				s.logger.Infow("relayed message for peer not connected here", "peer_id", envelope.TargetPeer)
+				continue
+			}
+			_ = s.enqueue(envelope.TargetPeer, pc, envelope.Message)
+		}
+	}
+}
+
+// refreshLoop keeps the registrations of connected peers from expiring. The
+// interval is drawn again before every refresh so instances started together
+// drift apart instead of refreshing in lockstep.
+func (s *WebSocketServer) refreshLoop(ctx context.Context, locator PeerLocator) {
+	timer := time.NewTimer(locator.RefreshInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			for _, peerID := range s.GetConnectedPeers() {
+				if err := locator.RefreshPeer(ctx, peerID); err != nil {
+					s.logger.Infow("failed to refresh peer registration", "peer_id", peerID, "error", err)
+				}
+			}
+			timer.Reset(locator.RefreshInterval())
+		}
+	}
+}
+
+// crossInstanceRelay returns the relay, or nil when it is not enabled
+func (s *WebSocketServer) crossInstanceRelay() *crossInstanceRelay {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.relay
+}
+
+// registerPeerLocation records that a joined peer is connected to this instance
+func (s *WebSocketServer) registerPeerLocation(ctx context.Context, peer *domain.Peer) {
+	relay := s.crossInstanceRelay()
+	if relay == nil {
+		return
+	}
+	if err := relay.locator.RegisterPeer(ctx, peer); err != nil {
+		s.logger.Warnw("failed to register peer location", "peer_id", peer.ID, "error", err)
+	}
+}
+
+// unregisterPeerLocation drops a disconnected peer's registration unless it
+// has since reconnected to another instance
+func (s *WebSocketServer) unregisterPeerLocation(ctx context.Context, peerID domain.PeerID) {
+	relay := s.crossInstanceRelay()
+	if relay == nil {
+		return
+	}
+	instanceID, err := relay.locator.GetPeerInstance(ctx, peerID)
+	if err != nil || instanceID != relay.instanceID {
+		return
+	}
+	if err := relay.locator.UnregisterPeer(ctx, peerID); err != nil {
+		s.logger.Infow("failed to unregister peer location", "peer_id", peerID, "error", err)
+	}
+}
+
+// publishCrossInstance relays data to a peer connected to another instance.
+// It fails like a local send when the peer is not registered elsewhere.
+func (s *WebSocketServer) publishCrossInstance(relay *crossInstanceRelay, peerID domain.PeerID, data interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), relayPublishTimeout)
+	defer cancel()
+
+	instanceID, err := relay.locator.GetPeerInstance(ctx, peerID)
+	if err != nil || instanceID == relay.instanceID {
+		return fmt.Errorf("peer %s not connected", peerID)
+	}
+
+	message, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal relayed message: %w", err)
+	}
+	envelope, err := json.Marshal(relayEnvelope{TargetPeer: peerID, Message: message})
+	if err != nil {
+		return fmt.Errorf("failed to marshal relayed message: %w", err)
+	}
+
+	if err := relay.client.Publish(ctx, relayChannel(instanceID), envelope).Err(); err != nil {
+		return fmt.Errorf("failed to relay message to peer %s: %w", peerID, err)
+	}
+	return nil
+}
+
+func (r *crossInstanceRelay) close() {
+	r.cancel()
+}
@@ -1,23 +1,30 @@
 package signal
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/ports"
 	rlog "rillnet/pkg/logger"
+	"rillnet/pkg/ratelimit"
 	"rillnet/pkg/utils"
+	"rillnet/pkg/validation"
+	sdputil "rillnet/pkg/webrtc"
 
 	"rillnet/internal/core/services"
 
 	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
 	"golang.org/x/time/rate"
 	"go.uber.org/zap"
 )
@@ -26,14 +33,35 @@ type WebSocketServer struct {
 	peerRepo    ports.PeerRepository
 	meshService ports.MeshService
 	authService services.AuthService
-
-	connections map[domain.PeerID]*websocket.Conn
+	streamRepo  ports.StreamRepository // Optional, enables stream_state reporting
+	streams     ports.StreamService    // Optional, admits joins as HTTP joins are
+	ids         utils.IDGenerator
+	relay       *crossInstanceRelay            // Optional, set by EnableCrossInstanceRelay
+	interest    ports.SubscriberInterestSetter // Optional, enables set_interest
+	pauser      ports.PublisherPauser          // Optional, enables pause/resume
+
+	connections map[domain.PeerID]*peerConn
 	mu          sync.RWMutex
+	// Stream membership of local peers that joined a stream, guarded by mu
+	streamMembers map[domain.StreamID]map[domain.PeerID]struct{}
+	peerStreams   map[domain.PeerID]domain.StreamID
+	// Simulcast layers the SFU reported per publisher of a stream, guarded by mu
+	streamLayers map[domain.StreamID]map[domain.PeerID][]string
+
+	// outbound backlog per peer before it is dropped as too slow
+	maxOutboundBacklog int
+	slowConsumerDrops  int64
+
+	// ICE candidates per peer per minute, nil when unlimited
+	candidateLimiter  *ratelimit.KeyedLimiter[domain.PeerID]
+	droppedCandidates int64
 
 	pingInterval time.Duration
 	pongTimeout  time.Duration
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+	// closes connections with no inbound application messages; pongs don't count
+	idleTimeout time.Duration
 
 	allowedOrigins []string
 
@@ -41,18 +69,46 @@ type WebSocketServer struct {
 	upgrader websocket.Upgrader
 
 	// rate limiting
-	connRateLimiter     *rate.Limiter
+	connRateLimiter     atomic.Pointer[rate.Limiter] // swapped whole on reconfiguration
 	messageRateLimiters map[domain.PeerID]*rate.Limiter
 	messageRateMu       sync.Mutex
+	// per-peer message rate given to new peers, guarded by messageRateMu
+	messageRate  rate.Limit
+	messageBurst int
 
+	// guarded by mu
 	maxConcurrent int
 	maxMsgSize    int64
+	// strikes per peer (and IP) for invalid messages, nil when disabled
+	quarantine     *strikeTracker
+	quarantineByIP bool
+	// range of the reconnect delay advertised when the server closes peers
+	reconnectBackoff    time.Duration
+	reconnectBackoffMax time.Duration
+	// deflate level of connections that negotiated compression
+	compressionLevel int
+	// validates tokens after the upgrade when set, see SetAsyncAuth
+	tokenValidator TokenValidator
+	authPolicy     AuthPolicy
+	authTimeout    time.Duration
 
 	// graceful shutdown
 	shuttingDown bool
 	shutdownMu   sync.RWMutex
 }
 
+// ErrSignalingNotAllowed is returned when a peer tries to signal a peer outside its stream.
+var ErrSignalingNotAllowed = errors.New("signaling not allowed")
+
+// ErrMissingPayload is returned for messages whose handler needs a payload but none was sent.
+var ErrMissingPayload = errors.New("missing payload")
+
+// idleCloseReason is sent in the close frame to peers closed for inactivity
+const idleCloseReason = "idle timeout"
+
+// SFUPeerID is the from_peer of messages originating from the SFU
+const SFUPeerID domain.PeerID = "sfu"
+
 type SignalMessage struct {
 	Type     string          `json:"type"`
 	PeerID   domain.PeerID   `json:"peer_id,omitempty"`
@@ -78,6 +134,28 @@ type ICECandidatePayload struct {
 	StreamID   domain.StreamID `json:"stream_id,omitempty"`
 }
 
+// PingPayload lets a client time the signaling round trip: nonce and
+// timestamp come back in the pong
+type PingPayload struct {
+	Timestamp int64  `json:"timestamp,omitempty"` // echoed back in the pong
+	Nonce     string `json:"nonce,omitempty"`     // echoed back in the pong
+}
+
+// PongPayload echoes the server_time of the server's last pong, which lets
+// the server time the round trip with its own clock
+type PongPayload struct {
+	ServerTime int64 `json:"server_time"`
+}
+
+// maxPingNonceLength bounds the nonce a client may have echoed
+const maxPingNonceLength = 64
+
+// SetInterestPayload lists the track IDs a subscriber is displaying; its
+// other video tracks are paused until listed again
+type SetInterestPayload struct {
+	ActiveTracks []string `json:"active_tracks"`
+}
+
 type MetricsUpdatePayload struct {
 	Bandwidth  int     `json:"bandwidth"`
 	PacketLoss float64 `json:"packet_loss"`
@@ -94,7 +172,11 @@ func NewWebSocketServer(
 		peerRepo:       peerRepo,
 		meshService:    meshService,
 		authService:    authService,
-		connections:    make(map[domain.PeerID]*websocket.Conn),
+		ids:            utils.DefaultIDGenerator,
+		connections:    make(map[domain.PeerID]*peerConn),
+		streamMembers:  make(map[domain.StreamID]map[domain.PeerID]struct{}),
+		peerStreams:    make(map[domain.PeerID]domain.StreamID),
+		streamLayers:   make(map[domain.StreamID]map[domain.PeerID][]string),
 		pingInterval:   30 * time.Second, // Default ping interval
 		pongTimeout:    60 * time.Second, // Default pong timeout
 		readTimeout:    60 * time.Second, // Default read timeout
@@ -102,12 +184,19 @@ func NewWebSocketServer(
 		allowedOrigins: allowedOrigins,
 		logger:         rlog.New("info").Sugar(),
 		// Default rate limits: can be overridden via setters from config
-		connRateLimiter:     rate.NewLimiter(rate.Every(time.Second), 5),
 		messageRateLimiters: make(map[domain.PeerID]*rate.Limiter),
+		messageRate:         DefaultMessageRate,
+		messageBurst:        DefaultMessageBurst,
 		maxConcurrent:       0,
 		maxMsgSize:          64 * 1024,
+		maxOutboundBacklog:  DefaultMaxOutboundBacklog,
+		reconnectBackoff:    DefaultReconnectBackoff,
+		reconnectBackoffMax: DefaultReconnectBackoffMax,
+		candidateLimiter:    ratelimit.NewKeyedLimiter[domain.PeerID](DefaultMaxICECandidatesPerMinute),
 	}
 
+	ws.connRateLimiter.Store(rate.NewLimiter(DefaultConnectionRate, DefaultConnectionBurst))
+
 	// Configure upgrader with origin check
 	ws.upgrader = websocket.Upgrader{
 		CheckOrigin: ws.checkOrigin,
@@ -144,24 +233,47 @@ func (s *WebSocketServer) SetPongTimeout(timeout time.Duration) {
 	s.pongTimeout = timeout
 }
 
+// SetIdleTimeout closes connections that send no application messages for
+// the given period, however regularly they answer pings. 0 disables it.
+func (s *WebSocketServer) SetIdleTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		return
+	}
+	s.idleTimeout = timeout
+}
+
 // SetConnectionRateLimit configures connection rate limiting (connections per minute).
 func (s *WebSocketServer) SetConnectionRateLimit(connectionsPerMinute int) {
 	if connectionsPerMinute <= 0 {
 		return
 	}
-	limit := rate.Every(time.Minute / time.Duration(connectionsPerMinute))
-	s.connRateLimiter = rate.NewLimiter(limit, connectionsPerMinute)
+	s.setConnectionRate(rate.Every(time.Minute/time.Duration(connectionsPerMinute)), connectionsPerMinute)
+}
+
+// setConnectionRate replaces the connection limiter, starting from a full
+// burst; safe while connections are being accepted
+func (s *WebSocketServer) setConnectionRate(limit rate.Limit, burst int) {
+	s.connRateLimiter.Store(rate.NewLimiter(limit, burst))
 }
 
-// SetMessageRateLimit configures per-peer message rate limiting.
+// SetMessageRateLimit configures per-peer message rate limiting, for
+// connected and future peers alike.
 func (s *WebSocketServer) SetMessageRateLimit(msgPerSecond float64, burst int) {
 	if msgPerSecond <= 0 || burst <= 0 {
 		return
 	}
+	s.setMessageRate(rate.Limit(msgPerSecond), burst)
+}
+
+func (s *WebSocketServer) setMessageRate(limit rate.Limit, burst int) {
 	s.messageRateMu.Lock()
 	defer s.messageRateMu.Unlock()
-	for peerID := range s.messageRateLimiters {
-		s.messageRateLimiters[peerID] = rate.NewLimiter(rate.Limit(msgPerSecond), burst)
+	s.messageRate = limit
+	s.messageBurst = burst
+	// Connected peers hold their limiter, so it is updated rather than replaced
+	for _, limiter := range s.messageRateLimiters {
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
 	}
 }
 
@@ -170,7 +282,9 @@ func (s *WebSocketServer) SetMaxConcurrentConnections(max int) {
 	if max < 0 {
 		return
 	}
+	s.mu.Lock()
 	s.maxConcurrent = max
+	s.mu.Unlock()
 }
 
 // SetMaxMessageSize sets maximum WebSocket message size in bytes.
@@ -181,6 +295,55 @@ func (s *WebSocketServer) SetMaxMessageSize(maxBytes int64) {
 	s.maxMsgSize = maxBytes
 }
 
+// SetCompression enables permessage-deflate for clients that offer it, at
+// the given flate level (-2 to 9). Applies to connections opened afterwards.
+func (s *WebSocketServer) SetCompression(enabled bool, level int) {
+	s.upgrader.EnableCompression = enabled
+	s.compressionLevel = level
+}
+
+// SetMaxOutboundBacklog sets how many queued outbound messages a peer may lag
+// behind before it is disconnected. Applies to connections opened afterwards.
+func (s *WebSocketServer) SetMaxOutboundBacklog(max int) {
+	if max <= 0 {
+		return
+	}
+	s.maxOutboundBacklog = max
+}
+
+// SetInterestSetter enables set_interest messages, which pause forwarding of
+// the video tracks a subscriber is not displaying.
+func (s *WebSocketServer) SetInterestSetter(setter ports.SubscriberInterestSetter) {
+	s.interest = setter
+}
+
+// SetPublisherPauser enables pause and resume messages, which suspend and
+// resume forwarding of a publisher's media in the SFU.
+func (s *WebSocketServer) SetPublisherPauser(pauser ports.PublisherPauser) {
+	s.pauser = pauser
+}
+
+// SetStreamRepository enables stream lookups so join responses can tell
+// subscribers whether a stream has not started yet or has already ended.
+func (s *WebSocketServer) SetStreamRepository(repo ports.StreamRepository) {
+	s.streamRepo = repo
+}
+
+// SetStreamService makes join_stream go through the stream service's
+// admission (peer limits, bandwidth budget, instance capacity) instead of
+// adding peers to the mesh directly.
+func (s *WebSocketServer) SetStreamService(streams ports.StreamService) {
+	s.streams = streams
+}
+
+// SetIDGenerator replaces the generator used for session IDs.
+func (s *WebSocketServer) SetIDGenerator(ids utils.IDGenerator) {
+	if ids == nil {
+		return
+	}
+	s.ids = ids
+}
+
 func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Check if server is shutting down
 	s.shutdownMu.RLock()
@@ -191,13 +354,22 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	}
 	s.shutdownMu.RUnlock()
 
-	// Basic connection rate limiting by IP
-	if s.connRateLimiter != nil {
-		host, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			host = r.RemoteAddr
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	// Refuse addresses quarantined for misbehaving
+	if s.quarantineByIP {
+		if remaining, quarantined := s.quarantinedFor(ipStrikeKey(host)); quarantined {
+			s.rejectQuarantined(w, remaining)
+			return
 		}
-		if !s.connRateLimiter.Allow() {
+	}
+
+	// Basic connection rate limiting by IP
+	if limiter := s.connRateLimiter.Load(); limiter != nil {
+		if !limiter.Allow() {
 			s.logger.Warnw("websocket connection rate limit exceeded", "remote_addr", host)
 			http.Error(w, "too many connections", http.StatusTooManyRequests)
 			return
@@ -212,12 +384,36 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	claims, err := s.authService.ValidateToken(token)
-	if err != nil {
-		s.logger.Warnw("invalid token", "error", err)
-		http.Error(w, "invalid token", http.StatusUnauthorized)
+	// With async auth the token is validated after the upgrade
+	var claims *services.Claims
+	if s.tokenValidator == nil {
+		claims, err = s.authService.ValidateToken(token)
+		if err != nil {
+			s.logger.Warnw("invalid token", "error", err)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	peerID := domain.PeerID(r.URL.Query().Get("peer_id"))
+	if peerID == "" {
+		s.logger.Warn("missing peer_id in query parameters")
+		http.Error(w, "peer_id is required", http.StatusBadRequest)
 		return
 	}
+	if claims != nil {
+		if remaining, quarantined := s.quarantinedFor(peerStrikeKey(claims.UserID, peerID)); quarantined {
+			s.rejectQuarantined(w, remaining)
+			return
+		}
+
+		// A peer ID may only be taken over by a reconnect of the same user
+		if owner, connected := s.PeerUserID(peerID); connected && owner != claims.UserID {
+			s.logger.Warnw("rejected connection for peer owned by another user", "peer_id", peerID, "user_id", claims.UserID)
+			http.Error(w, "peer_id is in use by another user", http.StatusForbidden)
+			return
+		}
+	}
 
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -230,40 +426,53 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	if s.maxMsgSize > 0 {
 		conn.SetReadLimit(s.maxMsgSize)
 	}
-
-	// Register connection
-	peerID := domain.PeerID(r.URL.Query().Get("peer_id"))
-	if peerID == "" {
-		s.logger.Warn("missing peer_id in query parameters")
-		return
+	if s.upgrader.EnableCompression {
+		if err := conn.SetCompressionLevel(s.compressionLevel); err != nil {
+			s.logger.Warnw("invalid websocket compression level", "level", s.compressionLevel, "error", err)
+		}
 	}
 
-	// Store user ID from token claims in connection context
-	s.logger.Infow("websocket connection authenticated", "peer_id", peerID, "user_id", claims.UserID)
+	pc := newPeerConn(conn, "", s.maxOutboundBacklog)
+	auth := newConnAuth(s.authPolicy)
+	// Messages are authorized as the user this connection authenticated as
+	var msgCtx context.Context
 
-	// Check if peer is reconnecting (already exists)
-	s.mu.Lock()
-	// Global concurrent connections limit
-	if s.maxConcurrent > 0 && len(s.connections) >= s.maxConcurrent {
-		s.mu.Unlock()
-		s.logger.Warnw("websocket concurrent connection limit reached")
-		http.Error(w, "too many concurrent connections", http.StatusServiceUnavailable)
-		return
-	}
-	existingConn, isReconnect := s.connections[peerID]
-	if isReconnect && existingConn != nil {
-		// Close old connection
-		_ = existingConn.Close()
-		s.logger.Infow("closing old connection for reconnecting peer", "peer_id", peerID)
+	// registered is set once pc is the peer's connection; until then the
+	// peer ID may still belong to another connection
+	registered := false
+	if claims != nil {
+		// Store user ID from token claims in connection context
+		s.logger.Infow("websocket connection authenticated", "peer_id", peerID, "user_id", claims.UserID)
+		pc.userID = claims.UserID
+		if !s.registerConn(peerID, pc) {
+			return
+		}
+		registered = true
+		auth.confirm()
+		msgCtx = context.WithValue(context.Background(), domain.UserIDContextKey, pc.userID)
 	}
-	s.connections[peerID] = conn
-	s.mu.Unlock()
 
-	s.logger.Infow("peer connected via WebSocket", "peer_id", peerID, "reconnect", isReconnect)
+	go s.writeLoop(peerID, pc)
+	defer pc.close(0, "", 0)
+
+	// Pending authentication; both channels stay nil in synchronous mode
+	var authResult chan authOutcome
+	var authExpired <-chan struct{}
+	if !registered {
+		authCtx, cancelAuth := context.WithTimeout(context.Background(), s.authTimeout)
+		defer cancelAuth()
+		authResult = make(chan authOutcome, 1)
+		authExpired = authCtx.Done()
+		go func() {
+			claims, err := s.tokenValidator.ValidateToken(authCtx, token)
+			authResult <- authOutcome{claims: claims, err: err}
+		}()
+	}
 
 	// Set read/write deadlines
 	_ = conn.SetReadDeadline(time.Now().Add(s.readTimeout))
-	conn.SetPongHandler(func(string) error {
+	conn.SetPongHandler(func(appData string) error {
+		pc.recordPong(appData, time.Now())
 		_ = conn.SetReadDeadline(time.Now().Add(s.readTimeout))
 		return nil
 	})
@@ -272,15 +481,24 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	pingTicker := time.NewTicker(s.pingInterval)
 	defer pingTicker.Stop()
 
+	// Application idle timer; idle stays nil, and never fires, when disabled
+	var idleTimer *time.Timer
+	var idle <-chan time.Time
+	if s.idleTimeout > 0 {
+		idleTimer = time.NewTimer(s.idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
 	// Channel for message processing
 	messageChan := make(chan SignalMessage, 10)
+	rateLimited := make(chan struct{}, 10)
 	errorChan := make(chan error, 1)
 
 	// Initialize per-peer message rate limiter
 	s.messageRateMu.Lock()
 	if _, exists := s.messageRateLimiters[peerID]; !exists {
-		// Default: 100 messages/sec with burst 200 if not configured differently
-		s.messageRateLimiters[peerID] = rate.NewLimiter(100, 200)
+		s.messageRateLimiters[peerID] = rate.NewLimiter(s.messageRate, s.messageBurst)
 	}
 	peerLimiter := s.messageRateLimiters[peerID]
 	s.messageRateMu.Unlock()
@@ -294,10 +512,12 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 				return
 			}
 
-			// Per-peer message rate limiting
+			// Per-peer message rate limiting; strikes are judged by the
+			// loop below, which owns the connection's user
 			if !peerLimiter.Allow() {
 				s.logger.Infow("rate limit exceeded for peer messages", "peer_id", peerID)
-				s.sendError(conn, "message rate limit exceeded")
+				s.sendError(peerID, pc, ErrRateLimited)
+				rateLimited <- struct{}{}
 				continue
 			}
 
@@ -306,22 +526,89 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 		}
 	}()
 
+	// handle processes one message and reports whether the peer was
+	// quarantined for it
+	handle := func(msg SignalMessage) bool {
+		err := s.handleMessage(msgCtx, peerID, msg)
+		if err != nil {
+			s.logger.Infow("error handling message from peer", "peer_id", peerID, "error", err)
+			s.sendError(peerID, pc, err)
+		}
+		return s.judgeMessage(peerID, host, pc, err)
+	}
+
 	// Process messages and ping
 	for {
 		select {
 		case msg := <-messageChan:
-			if err := s.handleMessage(context.Background(), peerID, msg); err != nil {
-				s.logger.Infow("error handling message from peer", "peer_id", peerID, "error", err)
-				s.sendError(conn, err.Error())
+			if idleTimer != nil {
+				idleTimer.Reset(s.idleTimeout)
+			}
+			if now, err := auth.admit(msg); !now {
+				if err != nil {
+					s.logger.Infow("rejected message before authentication", "peer_id", peerID, "type", msg.Type)
+					s.sendError(peerID, pc, err)
+				}
+				continue
+			}
+			if handle(msg) {
+				goto cleanup
+			}
+
+		case <-rateLimited:
+			// Strikes are keyed on the user, unknown until authenticated
+			if registered && s.judgeMessage(peerID, host, pc, ErrRateLimited) {
+				goto cleanup
+			}
+
+		case outcome := <-authResult:
+			authResult, authExpired = nil, nil
+			if outcome.err != nil {
+				s.logger.Warnw("invalid token", "peer_id", peerID, "error", outcome.err)
+				pc.close(websocket.ClosePolicyViolation, authFailedCloseReason, s.writeTimeout)
+				goto cleanup
+			}
+			if remaining, quarantined := s.quarantinedFor(peerStrikeKey(outcome.claims.UserID, peerID)); quarantined {
+				reason, _ := json.Marshal(CloseReason{
+					Reason:           quarantineCloseReason,
+					ReconnectAfterMs: remaining.Milliseconds(),
+				})
+				pc.close(websocket.ClosePolicyViolation, string(reason), s.writeTimeout)
+				goto cleanup
+			}
+			s.logger.Infow("websocket connection authenticated", "peer_id", peerID, "user_id", outcome.claims.UserID)
+			pc.userID = outcome.claims.UserID
+			if !s.registerConn(peerID, pc) {
+				goto cleanup
+			}
+			registered = true
+			msgCtx = context.WithValue(context.Background(), domain.UserIDContextKey, pc.userID)
+			for _, queued := range auth.confirm() {
+				if handle(queued) {
+					goto cleanup
+				}
 			}
 
+		case <-authExpired:
+			s.logger.Warnw("websocket authentication timed out", "peer_id", peerID, "auth_timeout", s.authTimeout)
+			pc.close(websocket.ClosePolicyViolation, authTimeoutCloseReason, s.writeTimeout)
+			goto cleanup
+
 		case <-pingTicker.C:
 			// Send ping
-			_ = conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			now := time.Now()
+			if err := conn.WriteControl(websocket.PingMessage, pingPayload(now), now.Add(s.writeTimeout)); err != nil {
 				s.logger.Infow("error sending ping", "peer_id", peerID, "error", err)
 				goto cleanup
 			}
+			if registered {
+				s.heartbeatPeer(peerID)
+			}
+
+		case <-idle:
+			s.logger.Infow("closing idle peer connection", "peer_id", peerID, "idle_timeout", s.idleTimeout)
+			pc.close(websocket.CloseNormalClosure, idleCloseReason, s.writeTimeout)
+			goto cleanup
 
 		case err := <-errorChan:
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
@@ -332,18 +619,75 @@ func (s *WebSocketServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	}
 
 cleanup:
+	// A connection that never authenticated holds no peer state
+	if !registered {
+		s.logger.Infow("unauthenticated peer disconnected", "peer_id", peerID)
+		return
+	}
+
 	// Clean up on disconnect
 	s.mu.Lock()
-	delete(s.connections, peerID)
+	current := s.connections[peerID] == pc
+	if current {
+		delete(s.connections, peerID)
+	}
 	s.mu.Unlock()
 
+	// A replaced connection leaves membership to the reconnected one
+	if current {
+		s.announceLeave(peerID)
+		s.candidateLimiter.Forget(peerID)
+	}
+
 	if err := s.meshService.RemovePeer(context.Background(), peerID); err != nil {
 		s.logger.Infow("error removing peer from mesh", "peer_id", peerID, "error", err)
 	}
+	s.unregisterPeerLocation(context.Background(), peerID)
 
 	s.logger.Infow("peer disconnected", "peer_id", peerID)
 }
 
+// registerConn makes pc the connection of peerID, closing the one it
+// replaces on a reconnect. When the server is full or the peer ID belongs to
+// another user, pc is refused with a close frame and false is returned.
+func (s *WebSocketServer) registerConn(peerID domain.PeerID, pc *peerConn) bool {
+	s.mu.Lock()
+	// Global concurrent connections limit
+	if s.maxConcurrent > 0 && len(s.connections) >= s.maxConcurrent {
+		reason := s.closeReason(overloadCloseReason, 1)
+		s.mu.Unlock()
+		s.logger.Warnw("websocket concurrent connection limit reached")
+		// The connection is already upgraded, so refuse it with a close frame
+		pc.close(websocket.CloseTryAgainLater, reason, s.writeTimeout)
+		return false
+	}
+	existingConn, isReconnect := s.connections[peerID]
+	// Re-checked here: another user may have taken the peer ID since the
+	// check before the upgrade
+	if isReconnect && existingConn != nil && existingConn.userID != pc.userID {
+		s.mu.Unlock()
+		s.logger.Warnw("rejected connection for peer owned by another user", "peer_id", peerID, "user_id", pc.userID)
+		pc.close(websocket.ClosePolicyViolation, "peer_id is in use by another user", s.writeTimeout)
+		return false
+	}
+	if isReconnect && existingConn != nil {
+		// Close old connection
+		existingConn.close(0, "", 0)
+		s.logger.Infow("closing old connection for reconnecting peer", "peer_id", peerID)
+	}
+	s.connections[peerID] = pc
+	s.mu.Unlock()
+
+	s.logger.Infow("peer connected via WebSocket", "peer_id", peerID, "reconnect", isReconnect)
+	return true
+}
+
+// connUserID returns the user the message's connection authenticated as
+func connUserID(ctx context.Context) domain.UserID {
+	userID, _ := ctx.Value(domain.UserIDContextKey).(domain.UserID)
+	return userID
+}
+
 func (s *WebSocketServer) handleMessage(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
 	// Validate message type
 	if msg.Type == "" {
@@ -358,16 +702,28 @@ func (s *WebSocketServer) handleMessage(ctx context.Context, peerID domain.PeerI
 	switch msg.Type {
 	case "join_stream":
 		return s.handleJoinStream(ctx, peerID, msg)
+	case "leave_stream":
+		return s.handleLeaveStream(ctx, peerID, msg)
 	case "offer":
 		return s.handleOffer(ctx, peerID, msg)
 	case "answer":
 		return s.handleAnswer(ctx, peerID, msg)
 	case "ice_candidate":
 		return s.handleICECandidate(ctx, peerID, msg)
+	case "pause":
+		return s.handlePublisherPause(ctx, peerID, true)
+	case "resume":
+		return s.handlePublisherPause(ctx, peerID, false)
+	case "set_interest":
+		return s.handleSetInterest(ctx, peerID, msg)
 	case "metrics_update":
 		return s.handleMetricsUpdate(ctx, peerID, msg)
+	case "ping":
+		return s.handlePing(peerID, msg)
+	case "pong":
+		return s.handlePong(peerID, msg)
 	default:
-		return fmt.Errorf("unknown message type: %s", msg.Type)
+		return fmt.Errorf("%w: %s", ErrUnknownMessageType, msg.Type)
 	}
 }
 
@@ -375,13 +731,14 @@ func (s *WebSocketServer) handleJoinStream(ctx context.Context, peerID domain.Pe
 	var payload struct {
 		StreamID     domain.StreamID `json:"stream_id"`
 		IsPublisher  bool            `json:"is_publisher"`
+		IsObserver   bool            `json:"is_observer"`
 		Capabilities struct {
 			MaxBitrate int      `json:"max_bitrate"`
 			Codecs     []string `json:"codecs"`
 		} `json:"capabilities"`
 	}
 
-	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
 		return fmt.Errorf("invalid join_stream payload: %w", err)
 	}
 
@@ -399,17 +756,26 @@ func (s *WebSocketServer) handleJoinStream(ctx context.Context, peerID domain.Pe
 	if payload.Capabilities.MaxBitrate < 0 {
 		return fmt.Errorf("max_bitrate must be >= 0")
 	}
+	if err := validation.ValidateCodecs(payload.Capabilities.Codecs); err != nil {
+		return fmt.Errorf("invalid codecs: %w", err)
+	}
+	if payload.IsObserver && payload.IsPublisher {
+		return fmt.Errorf("a peer cannot be both publisher and observer")
+	}
 
+	userID := connUserID(ctx)
 	peer := &domain.Peer{
 		ID:        peerID,
 		StreamID:  payload.StreamID,
-		SessionID: domain.SessionID(utils.GenerateSessionID()),
+		UserID:    userID,
+		SessionID: domain.SessionID(s.ids.NewID("session")),
 		Address:   "dynamic", // In real implementation, actual address should be obtained
 		Capabilities: domain.PeerCapabilities{
 			MaxBitrate:      payload.Capabilities.MaxBitrate,
 			SupportedCodecs: payload.Capabilities.Codecs,
 			IsPublisher:     payload.IsPublisher,
-			CanRelay:        true,
+			CanRelay:        !payload.IsObserver,
+			IsObserver:      payload.IsObserver,
 		},
 		Metrics: domain.PeerMetrics{
 			Bandwidth:   payload.Capabilities.MaxBitrate,
@@ -422,16 +788,22 @@ func (s *WebSocketServer) handleJoinStream(ctx context.Context, peerID domain.Pe
 	}
 
 	// Add peer to system
-	if err := s.meshService.AddPeer(ctx, peer); err != nil {
+	if err := s.addPeer(ctx, peer); err != nil {
 		return fmt.Errorf("failed to add peer: %w", err)
 	}
+	s.registerPeerLocation(ctx, peer)
+	s.announceJoin(peer)
 
-	// Find optimal sources for P2P connections
-	sources, err := s.meshService.FindOptimalSources(ctx, payload.StreamID, peerID, 4)
-	if err != nil {
-		// If no sources found, continue anyway
-		s.logger.Infow("no optimal sources found for peer", "peer_id", peerID, "error", err)
-		sources = []*domain.Peer{}
+	// Find optimal sources for P2P connections (observers receive no media)
+	sources := []*domain.Peer{}
+	if !payload.IsObserver {
+		found, err := s.meshService.FindOptimalSources(ctx, payload.StreamID, peerID, 4)
+		if err != nil {
+			// If no sources found, continue anyway
+			s.logger.Infow("no optimal sources found for peer", "peer_id", peerID, "error", err)
+		} else {
+			sources = found
+		}
 	}
 
 	var peerList []map[string]interface{}
@@ -440,26 +812,116 @@ func (s *WebSocketServer) handleJoinStream(ctx context.Context, peerID domain.Pe
 			"peer_id": source.ID,
 			"address": source.Address,
 			"quality": "auto",
+			"role":    sourceRole(source),
+			"bitrate": source.Metrics.Bandwidth,
 		})
 	}
 
 	response := map[string]interface{}{
-		"type":  "peers_list",
-		"peers": peerList,
+		"type":         "peers_list",
+		"peers":        peerList,
+		"stream_state": s.streamState(ctx, payload.StreamID, payload.IsPublisher, sources),
 	}
 
-	return s.sendToPeer(peerID, response)
+	if err := s.sendToPeer(peerID, response); err != nil {
+		return err
+	}
+	if payload.IsPublisher || payload.IsObserver {
+		return nil
+	}
+	return s.sendKnownLayers(peerID, payload.StreamID)
+}
+
+// handleLeaveStream removes the peer from a stream's mesh while keeping its
+// connection open, tells the remaining peers it left and confirms with "left".
+func (s *WebSocketServer) handleLeaveStream(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
+	var payload struct {
+		StreamID domain.StreamID `json:"stream_id"`
+	}
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
+		return fmt.Errorf("invalid leave_stream payload: %w", err)
+	}
+	if payload.StreamID == "" {
+		return fmt.Errorf("stream_id is required")
+	}
+
+	peer, err := s.peerRepo.GetByID(ctx, peerID)
+	if err != nil || peer.StreamID != payload.StreamID {
+		return fmt.Errorf("peer is not in stream %s", payload.StreamID)
+	}
+
+	// Collect the remaining peers before the mesh forgets this one
+	peers, err := s.peerRepo.FindByStream(ctx, payload.StreamID)
+	if err != nil {
+		return fmt.Errorf("failed to find stream peers: %w", err)
+	}
+
+	if err := s.meshService.RemovePeer(ctx, peerID); err != nil {
+		return fmt.Errorf("failed to remove peer: %w", err)
+	}
+	s.unregisterPeerLocation(ctx, peerID)
+
+	// Peers are told from the repository, which also reaches other instances
+	s.mu.Lock()
+	s.leaveStreamMembership(peerID)
+	s.mu.Unlock()
+	s.broadcastPeerLeft(peers, payload.StreamID, peerID)
+
+	return s.sendToPeer(peerID, map[string]interface{}{
+		"type":      "left",
+		"stream_id": payload.StreamID,
+	})
+}
+
+// NotifyPeerLeft broadcasts peer_left to the other peers of a stream
+// (ports.PeerLeftNotifier), for peers dropped outside of signaling.
+func (s *WebSocketServer) NotifyPeerLeft(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error {
+	peers, err := s.peerRepo.FindByStream(ctx, streamID)
+	if err != nil {
+		return fmt.Errorf("failed to find stream peers: %w", err)
+	}
+	s.broadcastPeerLeft(peers, streamID, peerID)
+	return nil
+}
+
+// broadcastPeerLeft sends peer_left to every peer in peers except the one that left
+func (s *WebSocketServer) broadcastPeerLeft(peers []*domain.Peer, streamID domain.StreamID, peerID domain.PeerID) {
+	notification := peerLeftEvent(streamID, peerID)
+	for _, p := range peers {
+		if p.ID == peerID {
+			continue
+		}
+		if err := s.sendToPeer(p.ID, notification); err != nil {
+			s.logger.Debugw("failed to notify peer of departure", "peer_id", p.ID, "error", err)
+		}
+	}
+}
+
+// addPeer admits a joining peer through the stream service when one is set
+func (s *WebSocketServer) addPeer(ctx context.Context, peer *domain.Peer) error {
+	if s.streams != nil {
+		return s.streams.JoinStream(ctx, peer.StreamID, peer)
+	}
+	return s.meshService.AddPeer(ctx, peer)
+}
+
+// sourceRole tells clients whether a source is the origin publisher or a relay peer
+func sourceRole(peer *domain.Peer) string {
+	if peer.Capabilities.IsPublisher {
+		return "publisher"
+	}
+	return "relay"
 }
 
 func (s *WebSocketServer) handleOffer(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
 	var payload OfferPayload
-	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
 		return fmt.Errorf("invalid offer payload: %w", err)
 	}
 
 	// Validate SDP
 	if err := s.validateSDP(payload.SDP); err != nil {
-		return fmt.Errorf("invalid SDP in offer: %w", err)
+		return fmt.Errorf("%w in offer: %w", ErrInvalidSDP, err)
 	}
 
 	// Validate stream ID if provided
@@ -475,9 +937,20 @@ func (s *WebSocketServer) handleOffer(ctx context.Context, peerID domain.PeerID,
 		return fmt.Errorf("failed to determine target peer: %w", err)
 	}
 
+	// Sender may only signal peers of the stream it joined
+	sender, err := s.authorizeSignal(ctx, peerID, targetPeerID, payload.StreamID)
+	if err != nil {
+		return err
+	}
+
+	// Media directions must match the sender's role
+	if err := sdputil.ValidateMediaDirections(payload.SDP, mediaRole(sender)); err != nil {
+		return fmt.Errorf("%w in %s: %w", ErrInvalidSDP, msg.Type, err)
+	}
+
 	// Validate target peer exists and is connected
 	if !s.IsPeerConnected(targetPeerID) {
-		return fmt.Errorf("target peer %s is not connected", targetPeerID)
+		return fmt.Errorf("%w: %s", ErrTargetNotConnected, targetPeerID)
 	}
 
 	// Forward offer to target peer
@@ -502,13 +975,13 @@ func (s *WebSocketServer) handleOffer(ctx context.Context, peerID domain.PeerID,
 
 func (s *WebSocketServer) handleAnswer(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
 	var payload AnswerPayload
-	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
 		return fmt.Errorf("invalid answer payload: %w", err)
 	}
 
 	// Validate SDP
 	if err := s.validateSDP(payload.SDP); err != nil {
-		return fmt.Errorf("invalid SDP in answer: %w", err)
+		return fmt.Errorf("%w in answer: %w", ErrInvalidSDP, err)
 	}
 
 	// Validate stream ID if provided
@@ -524,9 +997,20 @@ func (s *WebSocketServer) handleAnswer(ctx context.Context, peerID domain.PeerID
 		return fmt.Errorf("failed to determine target peer: %w", err)
 	}
 
+	// Sender may only signal peers of the stream it joined
+	sender, err := s.authorizeSignal(ctx, peerID, targetPeerID, payload.StreamID)
+	if err != nil {
+		return err
+	}
+
+	// Media directions must match the sender's role
+	if err := sdputil.ValidateMediaDirections(payload.SDP, mediaRole(sender)); err != nil {
+		return fmt.Errorf("%w in %s: %w", ErrInvalidSDP, msg.Type, err)
+	}
+
 	// Validate target peer exists and is connected
 	if !s.IsPeerConnected(targetPeerID) {
-		return fmt.Errorf("target peer %s is not connected", targetPeerID)
+		return fmt.Errorf("%w: %s", ErrTargetNotConnected, targetPeerID)
 	}
 
 	// Forward answer to target peer
@@ -551,7 +1035,7 @@ func (s *WebSocketServer) handleAnswer(ctx context.Context, peerID domain.PeerID
 
 func (s *WebSocketServer) handleICECandidate(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
 	var payload ICECandidatePayload
-	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
 		return fmt.Errorf("invalid ICE candidate payload: %w", err)
 	}
 
@@ -560,15 +1044,25 @@ func (s *WebSocketServer) handleICECandidate(ctx context.Context, peerID domain.
 		return fmt.Errorf("ICE candidate is required")
 	}
 
+	// Drop candidates beyond the peer's limit without replying
+	if !s.allowICECandidate(peerID) {
+		return nil
+	}
+
 	// Determine target peer
 	targetPeerID, err := s.determineTargetPeer(ctx, peerID, payload.TargetPeer, payload.StreamID, msg.StreamID)
 	if err != nil {
 		return fmt.Errorf("failed to determine target peer: %w", err)
 	}
 
+	// Sender may only signal peers of the stream it joined
+	if _, err := s.authorizeSignal(ctx, peerID, targetPeerID, payload.StreamID); err != nil {
+		return err
+	}
+
 	// Validate target peer exists and is connected
 	if !s.IsPeerConnected(targetPeerID) {
-		return fmt.Errorf("target peer %s is not connected", targetPeerID)
+		return fmt.Errorf("%w: %s", ErrTargetNotConnected, targetPeerID)
 	}
 
 	// Forward ICE candidate to target peer
@@ -590,24 +1084,145 @@ func (s *WebSocketServer) handleICECandidate(ctx context.Context, peerID domain.
 	return s.sendToPeer(targetPeerID, response)
 }
 
-func (s *WebSocketServer) handleMetricsUpdate(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
-	var payload MetricsUpdatePayload
-	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
-		return err
+// handlePublisherPause pauses or resumes the publisher's forwarding in the SFU
+// and notifies the other peers of its stream.
+func (s *WebSocketServer) handlePublisherPause(ctx context.Context, peerID domain.PeerID, paused bool) error {
+	peer, err := s.peerRepo.GetByID(ctx, peerID)
+	if err != nil {
+		return fmt.Errorf("peer must join a stream before pausing: %w", err)
+	}
+	if !peer.Capabilities.IsPublisher {
+		return fmt.Errorf("only publishers can pause or resume")
+	}
+	if s.pauser == nil {
+		return fmt.Errorf("pausing is not available")
+	}
+	if err := s.pauser.SetPublisherPaused(peer.StreamID, peerID, paused); err != nil {
+		return fmt.Errorf("failed to update publisher state: %w", err)
+	}
+
+	notifyType, ackType := "publisher_resumed", "resumed"
+	if paused {
+		notifyType, ackType = "publisher_paused", "paused"
+	}
+
+	peers, err := s.peerRepo.FindByStream(ctx, peer.StreamID)
+	if err != nil {
+		return fmt.Errorf("failed to find stream peers: %w", err)
+	}
+
+	notification := map[string]interface{}{
+		"type":      notifyType,
+		"peer_id":   peerID,
+		"stream_id": peer.StreamID,
+	}
+	for _, p := range peers {
+		if p.ID == peerID {
+			continue
+		}
+		if err := s.sendToPeer(p.ID, notification); err != nil {
+			// Peer may have disconnected
+			s.logger.Debugw("failed to notify peer of publisher state", "peer_id", p.ID, "error", err)
+		}
+	}
+
+	return s.sendToPeer(peerID, map[string]interface{}{
+		"type":      ackType,
+		"stream_id": peer.StreamID,
+	})
+}
+
+// handleSetInterest tells the SFU which tracks the subscriber is displaying
+func (s *WebSocketServer) handleSetInterest(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
+	var payload SetInterestPayload
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
+		return fmt.Errorf("invalid set_interest payload: %w", err)
+	}
+	if payload.ActiveTracks == nil {
+		return fmt.Errorf("active_tracks is required")
+	}
+	if s.interest == nil {
+		return fmt.Errorf("selective forwarding is not available")
+	}
+
+	if err := s.interest.SetSubscriberInterest(ctx, peerID, payload.ActiveTracks); err != nil {
+		return fmt.Errorf("failed to set interest: %w", err)
+	}
+
+	return s.sendToPeer(peerID, map[string]interface{}{
+		"type":          "interest_set",
+		"active_tracks": payload.ActiveTracks,
+	})
+}
+
+// handlePing answers an application-level ping; the payload is optional.
+func (s *WebSocketServer) handlePing(peerID domain.PeerID, msg SignalMessage) error {
+	var payload PingPayload
+	if err := decodePayload(msg.Payload, &payload, false); err != nil {
+		return fmt.Errorf("invalid ping payload: %w", err)
+	}
+	if len(payload.Nonce) > maxPingNonceLength {
+		return fmt.Errorf("ping nonce must be at most %d characters", maxPingNonceLength)
+	}
+
+	response := map[string]interface{}{
+		"type": "pong",
+	}
+	if payload.Timestamp != 0 {
+		response["timestamp"] = payload.Timestamp
+	}
+	if payload.Nonce != "" {
+		response["nonce"] = payload.Nonce
+	}
+	s.mu.RLock()
+	pc, exists := s.connections[peerID]
+	s.mu.RUnlock()
+	if exists {
+		response["server_time"] = pc.stampPong(time.Now())
+	}
+	return s.sendToPeer(peerID, response)
+}
+
+// handlePong closes a server-timed round trip: the client echoes the
+// server_time of our last pong straight back. Echoes of other stamps are
+// ignored.
+func (s *WebSocketServer) handlePong(peerID domain.PeerID, msg SignalMessage) error {
+	var payload PongPayload
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
+		return fmt.Errorf("invalid pong payload: %w", err)
 	}
 
-	// Basic validation and clamping for metrics
-	if payload.Bandwidth < 0 {
-		return fmt.Errorf("bandwidth must be >= 0")
+	s.mu.RLock()
+	pc, exists := s.connections[peerID]
+	s.mu.RUnlock()
+	if exists {
+		pc.recordPongEcho(payload.ServerTime, time.Now())
 	}
-	if payload.Bandwidth > 100000000 { // 100 Mbps upper bound for safety
-		return fmt.Errorf("bandwidth value too large")
+	return nil
+}
+
+// decodePayload unmarshals a message payload into v. An absent, empty or null
+// payload yields ErrMissingPayload when required, otherwise v is left as its
+// zero value.
+func decodePayload(raw json.RawMessage, v interface{}, required bool) error {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		if required {
+			return ErrMissingPayload
+		}
+		return nil
 	}
-	if payload.PacketLoss < 0 || payload.PacketLoss > 1 {
-		return fmt.Errorf("packet_loss must be between 0 and 1")
+	return json.Unmarshal(trimmed, v)
+}
+
+func (s *WebSocketServer) handleMetricsUpdate(ctx context.Context, peerID domain.PeerID, msg SignalMessage) error {
+	var payload MetricsUpdatePayload
+	if err := decodePayload(msg.Payload, &payload, true); err != nil {
+		return fmt.Errorf("invalid metrics_update payload: %w", err)
 	}
-	if payload.Latency < 0 {
-		return fmt.Errorf("latency must be >= 0")
+
+	if err := validation.ValidateNetworkMetrics(payload.Bandwidth, payload.PacketLoss, payload.Latency); err != nil {
+		return fmt.Errorf("invalid metrics_update payload: %w", err)
 	}
 
 	// Update peer metrics
@@ -675,9 +1290,9 @@ func (s *WebSocketServer) validateStreamID(ctx context.Context, streamID domain.
 		return fmt.Errorf("stream_id cannot be empty")
 	}
 
-	// Basic format validation (alphanumeric, dash, underscore)
-	if len(string(streamID)) < 1 || len(string(streamID)) > 100 {
-		return fmt.Errorf("stream_id must be between 1 and 100 characters")
+	// Same allowlist as the HTTP API
+	if err := validation.ValidateStreamID(string(streamID)); err != nil {
+		return err
 	}
 
 	// Note: In a full implementation, we would check if stream exists in repository
@@ -685,6 +1300,96 @@ func (s *WebSocketServer) validateStreamID(ctx context.Context, streamID domain.
 	return nil
 }
 
+// streamState derives the subscriber-facing lifecycle state of a stream from
+// its repository record and publisher presence.
+func (s *WebSocketServer) streamState(ctx context.Context, streamID domain.StreamID, isPublisher bool, sources []*domain.Peer) domain.StreamState {
+	if s.streamRepo != nil {
+		stream, err := s.streamRepo.GetByID(ctx, streamID)
+		if err == nil && !stream.Active {
+			return domain.StreamStateEnded
+		}
+		if err != nil && err != domain.ErrStreamNotFound {
+			s.logger.Warnw("failed to load stream for state", "stream_id", streamID, "error", err)
+		}
+	}
+
+	if isPublisher {
+		return domain.StreamStateLive
+	}
+	for _, source := range sources {
+		if source.Capabilities.IsPublisher {
+			return domain.StreamStateLive
+		}
+	}
+
+	// Sources are capped, so fall back to a full scan when the stream is known.
+	if s.streamRepo != nil {
+		peers, err := s.peerRepo.FindByStream(ctx, streamID)
+		if err == nil {
+			for _, peer := range peers {
+				if peer.Capabilities.IsPublisher {
+					return domain.StreamStateLive
+				}
+			}
+		}
+	}
+
+	return domain.StreamStateNotStarted
+}
+
+// mediaRole maps a peer's capabilities to the media directions it may negotiate
+func mediaRole(peer *domain.Peer) sdputil.MediaRole {
+	switch {
+	case peer.Capabilities.IsPublisher:
+		return sdputil.MediaRolePublisher
+	case peer.Capabilities.CanRelay:
+		return sdputil.MediaRoleRelay
+	default:
+		return sdputil.MediaRoleSubscriber
+	}
+}
+
+// authorizeSignal checks that the sender has joined a stream its user has
+// viewer permission in, that the target is in that same stream, and that the
+// sender is not an observer. It returns the sender so callers can check
+// role-specific rules.
+func (s *WebSocketServer) authorizeSignal(ctx context.Context, fromPeer, toPeer domain.PeerID, streamID domain.StreamID) (*domain.Peer, error) {
+	sender, err := s.peerRepo.GetByID(ctx, fromPeer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sender has not joined a stream", ErrSignalingNotAllowed)
+	}
+	if sender.Capabilities.IsObserver {
+		return nil, fmt.Errorf("%w: observers cannot exchange media signaling", ErrSignalingNotAllowed)
+	}
+	if streamID != "" && streamID != sender.StreamID {
+		return nil, fmt.Errorf("%w: sender is not a member of stream %s", ErrSignalingNotAllowed, streamID)
+	}
+
+	userID := connUserID(ctx)
+	if sender.UserID != "" && sender.UserID != userID {
+		return nil, fmt.Errorf("%w: sender peer belongs to another user", ErrSignalingNotAllowed)
+	}
+	if err := s.authService.CheckStreamPermission(ctx, userID, sender.StreamID, domain.RoleViewer); err != nil {
+		return nil, fmt.Errorf("%w: no permission in stream %s: %v", ErrSignalingNotAllowed, sender.StreamID, err)
+	}
+
+	target, err := s.peerRepo.GetByID(ctx, toPeer)
+	if err != nil {
+		return nil, fmt.Errorf("target peer %s not found: %w", toPeer, err)
+	}
+	if target.StreamID != sender.StreamID {
+		s.logger.Warnw("rejected cross-stream signaling",
+			"from_peer", fromPeer,
+			"to_peer", toPeer,
+			"from_stream", sender.StreamID,
+			"to_stream", target.StreamID,
+		)
+		return nil, fmt.Errorf("%w: target peer %s is in a different stream", ErrSignalingNotAllowed, toPeer)
+	}
+
+	return sender, nil
+}
+
 // determineTargetPeer determines the target peer for message routing
 func (s *WebSocketServer) determineTargetPeer(ctx context.Context, fromPeer domain.PeerID, explicitTarget domain.PeerID, payloadStreamID domain.StreamID, messageStreamID domain.StreamID) (domain.PeerID, error) {
 	// Priority 1: Explicit target peer in payload
@@ -732,22 +1437,40 @@ func (s *WebSocketServer) determineTargetPeer(ctx context.Context, fromPeer doma
 
 func (s *WebSocketServer) sendToPeer(peerID domain.PeerID, data interface{}) error {
 	s.mu.RLock()
-	conn, exists := s.connections[peerID]
+	pc, exists := s.connections[peerID]
+	relay := s.relay
 	s.mu.RUnlock()
 
 	if !exists {
+		// The peer may be connected to another signal instance
+		if relay != nil {
+			return s.publishCrossInstance(relay, peerID, data)
+		}
 		return fmt.Errorf("peer %s not connected", peerID)
 	}
 
-	return conn.WriteJSON(data)
+	return s.enqueue(peerID, pc, data)
+}
+
+// enqueue queues data for the peer's writer, dropping the peer if its
+// outbound backlog is exceeded.
+func (s *WebSocketServer) enqueue(peerID domain.PeerID, pc *peerConn, data interface{}) error {
+	err := pc.enqueue(data)
+	if errors.Is(err, ErrSlowConsumer) {
+		s.dropSlowConsumer(peerID, pc)
+	}
+	return err
 }
 
-func (s *WebSocketServer) sendError(conn *websocket.Conn, message string) {
+// sendError reports a rejected message to the peer with a machine-readable
+// code next to the human-readable message.
+func (s *WebSocketServer) sendError(peerID domain.PeerID, pc *peerConn, err error) {
 	errorMsg := map[string]interface{}{
 		"type":    "error",
-		"message": message,
+		"code":    errorCode(err),
+		"message": err.Error(),
 	}
-	_ = conn.WriteJSON(errorMsg)
+	_ = s.enqueue(peerID, pc, errorMsg)
 }
 
 func (s *WebSocketServer) HealthCheck(w http.ResponseWriter, r *http.Request) {
@@ -755,34 +1478,64 @@ func (s *WebSocketServer) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	connectionCount := len(s.connections)
 	s.mu.RUnlock()
 
+	avgLatency, measured := s.averageLatency()
+
 	response := map[string]interface{}{
-		"status":      "healthy",
-		"timestamp":   time.Now().Unix(),
-		"connections": connectionCount,
+		"status":          "healthy",
+		"timestamp":       time.Now().Unix(),
+		"connections":     connectionCount,
+		"avg_latency_ms":  float64(avgLatency) / float64(time.Millisecond),
+		"latency_samples": measured,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// PeerUserID returns the authenticated user a connected peer belongs to
+func (s *WebSocketServer) PeerUserID(peerID domain.PeerID) (domain.UserID, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pc, exists := s.connections[peerID]
+	if !exists {
+		return "", false
+	}
+	return pc.userID, true
+}
+
+// BroadcastToStream sends a message to every local peer that joined the stream
 func (s *WebSocketServer) BroadcastToStream(streamID domain.StreamID, message interface{}) error {
-	// In real implementation, all peers in stream would be found and message broadcasted
-	// This is a simplified version
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var errors []error
-	for peerID, conn := range s.connections {
-		if err := conn.WriteJSON(message); err != nil {
-			errors = append(errors, fmt.Errorf("failed to send to peer %s: %w", peerID, err))
-		}
+	if errs := s.broadcastToMembers(streamID, "", message); len(errs) > 0 {
+		return fmt.Errorf("broadcast completed with %d errors", len(errs))
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("broadcast completed with %d errors", len(errors))
+	return nil
+}
+
+// SendICECandidate pushes an SFU-gathered ICE candidate to a peer
+// (ports.ICECandidateSink). A nil candidate is sent as end-of-candidates.
+func (s *WebSocketServer) SendICECandidate(peerID domain.PeerID, candidate *webrtc.ICECandidateInit) error {
+	payload := map[string]interface{}{}
+	if candidate == nil {
+		payload["end_of_candidates"] = true
+	} else {
+		payload["candidate"] = candidate.Candidate
+		if candidate.SDPMid != nil {
+			payload["sdp_mid"] = *candidate.SDPMid
+		}
+		if candidate.SDPMLineIndex != nil {
+			payload["sdp_mline_index"] = *candidate.SDPMLineIndex
+		}
 	}
 
-	return nil
+	return s.sendToPeer(peerID, map[string]interface{}{
+		"type":      "ice_candidate",
+		"from_peer": SFUPeerID,
+		"payload":   payload,
+	})
 }
 
 // Additional methods for connection management
@@ -815,24 +1568,25 @@ func (s *WebSocketServer) Shutdown(ctx context.Context) error {
 
 	s.logger.Info("shutting down WebSocket server, closing all connections")
 
+	if relay := s.crossInstanceRelay(); relay != nil {
+		relay.close()
+	}
+
 	// Collect all connections
 	s.mu.Lock()
-	connections := make(map[domain.PeerID]*websocket.Conn, len(s.connections))
-	for peerID, conn := range s.connections {
-		connections[peerID] = conn
+	connections := make(map[domain.PeerID]*peerConn, len(s.connections))
+	for peerID, pc := range s.connections {
+		connections[peerID] = pc
 	}
+	pressure := s.connectionPressure()
 	s.mu.Unlock()
 
 	// Close all connections gracefully
 	done := make(chan struct{})
 	go func() {
-		for peerID, conn := range connections {
-			// Send close message
-			_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
-
-			// Close connection
-			_ = conn.Close()
+		for peerID, pc := range connections {
+			// Send close message and close connection
+			pc.close(websocket.CloseGoingAway, s.closeReason(shutdownCloseReason, pressure), 5*time.Second)
 
 			// Remove from mesh
 			if err := s.meshService.RemovePeer(ctx, peerID); err != nil {
@@ -852,18 +1606,20 @@ func (s *WebSocketServer) Shutdown(ctx context.Context) error {
 		s.logger.Warn("shutdown timeout exceeded, forcing connection closure")
 		// Force close remaining connections
 		s.mu.Lock()
-		for peerID, conn := range s.connections {
-			_ = conn.Close()
+		for peerID, pc := range s.connections {
+			pc.close(0, "", 0)
 			s.logger.Infow("force closed WebSocket connection", "peer_id", peerID)
 		}
-		s.connections = make(map[domain.PeerID]*websocket.Conn)
+		s.connections = make(map[domain.PeerID]*peerConn)
 		s.mu.Unlock()
 		return ctx.Err()
 	}
 
 	// Clear connections map
 	s.mu.Lock()
-	s.connections = make(map[domain.PeerID]*websocket.Conn)
+	s.connections = make(map[domain.PeerID]*peerConn)
+	s.streamMembers = make(map[domain.StreamID]map[domain.PeerID]struct{})
+	s.peerStreams = make(map[domain.PeerID]domain.StreamID)
 	s.mu.Unlock()
 
 	return nil
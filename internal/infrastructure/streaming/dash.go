@@ -0,0 +1,124 @@
+package streaming
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+)
+
+// dashSegmentMimeType is the container of the segments. Each one carries all
+// of the stream's media muxed together, as the HLS playlists serve them.
+const dashSegmentMimeType = "video/mp2t"
+
+type dashMPD struct {
+	XMLName               xml.Name     `xml:"MPD"`
+	Namespace             string       `xml:"xmlns,attr"`
+	Profiles              string       `xml:"profiles,attr"`
+	Type                  string       `xml:"type,attr"`
+	AvailabilityStartTime string       `xml:"availabilityStartTime,attr"`
+	MinimumUpdatePeriod   string       `xml:"minimumUpdatePeriod,attr"`
+	MinBufferTime         string       `xml:"minBufferTime,attr"`
+	Periods               []dashPeriod `xml:"Period"`
+}
+
+type dashPeriod struct {
+	ID             string              `xml:"id,attr"`
+	Start          string              `xml:"start,attr"`
+	AdaptationSets []dashAdaptationSet `xml:"AdaptationSet"`
+}
+
+type dashAdaptationSet struct {
+	MimeType         string               `xml:"mimeType,attr"`
+	SegmentAlignment bool                 `xml:"segmentAlignment,attr"`
+	Representations  []dashRepresentation `xml:"Representation"`
+}
+
+type dashRepresentation struct {
+	ID          string          `xml:"id,attr"`
+	Bandwidth   int             `xml:"bandwidth,attr"`
+	SegmentList dashSegmentList `xml:"SegmentList"`
+}
+
+type dashSegmentList struct {
+	Timescale   int              `xml:"timescale,attr"`
+	Duration    int64            `xml:"duration,attr"`
+	StartNumber int              `xml:"startNumber,attr"`
+	SegmentURLs []dashSegmentURL `xml:"SegmentURL"`
+}
+
+type dashSegmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// GenerateDASHManifest generates a live DASH MPD with a representation per
+// quality, listing the same segment URLs as the HLS playlists. segments maps
+// each quality to its segments, ordered by index.
+//
+// Segments are muxed MPEG-TS by design, so there are no separate audio and
+// video tracks to split into an adaptation set per media type. The manifest
+// has a single adaptation set of the muxed content, with no contentType since
+// it is not of one media type.
+func (s *Segmenter) GenerateDASHManifest(ctx context.Context, streamID domain.StreamID, qualities []string, segments map[string][]*Segment) (string, error) {
+	if len(qualities) == 0 {
+		return "", fmt.Errorf("no qualities for stream %s", streamID)
+	}
+
+	var availabilityStart time.Time
+	representations := make([]dashRepresentation, 0, len(qualities))
+	for _, quality := range qualities {
+		list := dashSegmentList{
+			Timescale: 1000,
+			Duration:  s.segmentDuration.Milliseconds(),
+		}
+		for i, segment := range segments[quality] {
+			if i == 0 {
+				list.StartNumber = segment.Index
+			}
+			if availabilityStart.IsZero() || segment.StartTime.Before(availabilityStart) {
+				availabilityStart = segment.StartTime
+			}
+			list.SegmentURLs = append(list.SegmentURLs, dashSegmentURL{Media: segment.URL})
+		}
+
+		representations = append(representations, dashRepresentation{
+			ID:          quality,
+			Bandwidth:   s.getBandwidthForQuality(quality),
+			SegmentList: list,
+		})
+	}
+	if availabilityStart.IsZero() {
+		availabilityStart = time.Now()
+	}
+
+	mpd := dashMPD{
+		Namespace:             "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:              "urn:mpeg:dash:profile:full:2011",
+		Type:                  "dynamic",
+		AvailabilityStartTime: availabilityStart.UTC().Format(time.RFC3339),
+		MinimumUpdatePeriod:   dashDuration(s.segmentDuration),
+		MinBufferTime:         dashDuration(s.segmentDuration),
+		Periods: []dashPeriod{{
+			ID:    "0",
+			Start: dashDuration(0),
+			AdaptationSets: []dashAdaptationSet{{
+				MimeType:         dashSegmentMimeType,
+				SegmentAlignment: true,
+				Representations:  representations,
+			}},
+		}},
+	}
+
+	manifest, err := xml.MarshalIndent(mpd, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode DASH manifest: %w", err)
+	}
+	return xml.Header + string(manifest) + "\n", nil
+}
+
+// dashDuration formats d as an ISO 8601 duration in seconds, e.g. PT2.000S
+func dashDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%.3fS", d.Seconds())
+}
@@ -0,0 +1,92 @@
+package streaming
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"go.uber.org/zap"
+)
+
+func TestSegmenter_GenerateDASHManifest(t *testing.T) {
+	ctx := context.Background()
+	segmenter := NewSegmenter(2*time.Second, t.TempDir(), zap.NewNop().Sugar())
+	streamID := domain.StreamID("dash-stream")
+
+	qualities := []string{"high", "medium", "low"}
+	segments := make(map[string][]*Segment)
+	for _, quality := range qualities {
+		for index := 5; index < 8; index++ {
+			segment, err := segmenter.CreateSegment(ctx, streamID, quality, index, []byte("ts"))
+			if err != nil {
+				t.Fatalf("CreateSegment: %v", err)
+			}
+			segments[quality] = append(segments[quality], segment)
+		}
+	}
+
+	manifest, err := segmenter.GenerateDASHManifest(ctx, streamID, qualities, segments)
+	if err != nil {
+		t.Fatalf("GenerateDASHManifest: %v", err)
+	}
+
+	var mpd struct {
+		Type    string `xml:"type,attr"`
+		Periods []struct {
+			AdaptationSets []struct {
+				MimeType        string `xml:"mimeType,attr"`
+				Representations []struct {
+					ID          string `xml:"id,attr"`
+					Bandwidth   int    `xml:"bandwidth,attr"`
+					SegmentList struct {
+						StartNumber int `xml:"startNumber,attr"`
+						SegmentURLs []struct {
+							Media string `xml:"media,attr"`
+						} `xml:"SegmentURL"`
+					} `xml:"SegmentList"`
+				} `xml:"Representation"`
+			} `xml:"AdaptationSet"`
+		} `xml:"Period"`
+	}
+	if err := xml.NewDecoder(strings.NewReader(manifest)).Decode(&mpd); err != nil {
+		t.Fatalf("manifest is not valid XML: %v\n%s", err, manifest)
+	}
+	if len(mpd.Periods) != 1 || len(mpd.Periods[0].AdaptationSets) != 1 {
+		t.Fatalf("expected one period with one adaptation set, got %s", manifest)
+	}
+
+	adaptationSet := mpd.Periods[0].AdaptationSets[0]
+	if adaptationSet.MimeType != "video/mp2t" {
+		t.Fatalf("expected video/mp2t segments, got %q", adaptationSet.MimeType)
+	}
+	if len(adaptationSet.Representations) != len(qualities) {
+		t.Fatalf("expected %d representations, got %d", len(qualities), len(adaptationSet.Representations))
+	}
+	for i, representation := range adaptationSet.Representations {
+		quality := qualities[i]
+		if representation.ID != quality {
+			t.Fatalf("expected representation %d to be %q, got %q", i, quality, representation.ID)
+		}
+		if representation.Bandwidth != segmenter.getBandwidthForQuality(quality) {
+			t.Fatalf("unexpected bandwidth %d for %q", representation.Bandwidth, quality)
+		}
+		list := representation.SegmentList
+		if list.StartNumber != 5 || len(list.SegmentURLs) != 3 {
+			t.Fatalf("expected segments 5-7 for %q, got start %d and %d URLs", quality, list.StartNumber, len(list.SegmentURLs))
+		}
+		if list.SegmentURLs[0].Media != segments[quality][0].URL {
+			t.Fatalf("expected the HLS segment URL %q, got %q", segments[quality][0].URL, list.SegmentURLs[0].Media)
+		}
+	}
+}
+
+func TestSegmenter_GenerateDASHManifestRequiresQualities(t *testing.T) {
+	segmenter := NewSegmenter(2*time.Second, t.TempDir(), zap.NewNop().Sugar())
+	if _, err := segmenter.GenerateDASHManifest(context.Background(), "dash-stream", nil, nil); err == nil {
+		t.Fatal("expected an error without qualities")
+	}
+}
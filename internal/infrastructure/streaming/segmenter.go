@@ -3,10 +3,17 @@ package streaming
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"rillnet/internal/core/domain"
+	"rillnet/internal/infrastructure/recording"
+	"rillnet/pkg/validation"
 	"go.uber.org/zap"
 )
 
@@ -14,6 +21,7 @@ import (
 type Segmenter struct {
 	segmentDuration time.Duration
 	outputPath      string
+	retention       int // Segment files kept per stream quality, 0 = all
 	logger          *zap.SugaredLogger
 }
 
@@ -28,6 +36,7 @@ type Segment struct {
 	FilePath    string
 	URL         string
 	Size        int64
+	Data        []byte // Segment bytes, served while the segment is cached
 }
 
 // NewSegmenter creates a new segmenter
@@ -39,16 +48,34 @@ func NewSegmenter(segmentDuration time.Duration, outputPath string, logger *zap.
 	}
 }
 
-// CreateSegment creates a new video segment
+// SetRetention keeps only the newest count segment files of each stream
+// quality on disk, removing older ones as segments are created (0 keeps all).
+// Must be called before CreateSegment.
+func (s *Segmenter) SetRetention(count int) {
+	s.retention = count
+}
+
+// CreateSegment writes a new video segment to
+// outputPath/streamID/quality/, replacing the file atomically. The stream ID
+// and quality name directories, so both are validated before any file is
+// touched, the stream ID as for recording directories.
 func (s *Segmenter) CreateSegment(ctx context.Context, streamID domain.StreamID, quality string, index int, data []byte) (*Segment, error) {
+	streamDir, err := recording.StreamDir(s.outputPath, streamID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.ValidateQuality(quality); err != nil {
+		return nil, err
+	}
+
 	segmentID := fmt.Sprintf("segment-%d", index)
-	fileName := fmt.Sprintf("%s-%s-%d.ts", streamID, quality, index)
-	filePath := fmt.Sprintf("%s/%s/%s/%s", s.outputPath, streamID, quality, fileName)
+	fileName := segmentFileName(streamID, quality, index)
+	dir := filepath.Join(streamDir, quality)
+	filePath := filepath.Join(dir, fileName)
 
-	// In a real implementation, this would:
-	// 1. Write segment data to file
-	// 2. Generate segment metadata
-	// 3. Update playlist files
+	if err := writeFileAtomic(dir, fileName, data); err != nil {
+		return nil, fmt.Errorf("failed to write segment %d of stream %s (%s): %w", index, streamID, quality, err)
+	}
 
 	segment := &Segment{
 		ID:        segmentID,
@@ -58,8 +85,9 @@ func (s *Segmenter) CreateSegment(ctx context.Context, streamID domain.StreamID,
 		StartTime: time.Now(),
 		Duration:  s.segmentDuration,
 		FilePath:  filePath,
-		URL:       fmt.Sprintf("/segments/%s/%s/%s", streamID, quality, fileName),
+		URL:       fmt.Sprintf("/hls/%s/%s/%d.ts", streamID, quality, index),
 		Size:      int64(len(data)),
+		Data:      data,
 	}
 
 	s.logger.Debugw("created segment",
@@ -69,22 +97,98 @@ func (s *Segmenter) CreateSegment(ctx context.Context, streamID domain.StreamID,
 		"size", segment.Size,
 	)
 
+	if s.retention > 0 {
+		s.removeExpiredSegments(dir, streamID, quality, index-s.retention)
+	}
+
 	return segment, nil
 }
 
-// GeneratePlaylist generates HLS playlist (M3U8)
-func (s *Segmenter) GeneratePlaylist(ctx context.Context, streamID domain.StreamID, quality string, segments []*Segment) (string, error) {
+// writeFileAtomic writes data to a temporary file in dir and renames it to
+// name, so readers never see a partly written segment
+func writeFileAtomic(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// removeExpiredSegments deletes the segment files in dir with an index at or
+// below lastExpired
+func (s *Segmenter) removeExpiredSegments(dir string, streamID domain.StreamID, quality string, lastExpired int) {
+	if lastExpired < 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.logger.Warnw("failed to list segments for cleanup", "dir", dir, "error", err)
+		return
+	}
+
+	prefix := fmt.Sprintf("%s-%s-", streamID, quality)
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, ".ts")
+		if !ok {
+			continue
+		}
+		index, err := strconv.Atoi(name)
+		if err != nil || index > lastExpired {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			s.logger.Warnw("failed to remove expired segment", "file", entry.Name(), "error", err)
+		}
+	}
+}
+
+// segmentFileName names the file of a segment
+func segmentFileName(streamID domain.StreamID, quality string, index int) string {
+	return fmt.Sprintf("%s-%s-%d.ts", streamID, quality, index)
+}
+
+// GeneratePlaylist generates HLS playlist (M3U8). A live playlist is a sliding
+// window players keep reloading, so only an ended one closes with ENDLIST.
+func (s *Segmenter) GeneratePlaylist(ctx context.Context, streamID domain.StreamID, quality string, segments []*Segment, live bool) (string, error) {
 	playlist := "#EXTM3U\n"
 	playlist += "#EXT-X-VERSION:3\n"
 	playlist += fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", int(s.segmentDuration.Seconds()))
-	playlist += "#EXT-X-MEDIA-SEQUENCE:0\n"
+	// Older segments leave the cache, so the window starts at the first one listed
+	mediaSequence := 0
+	if len(segments) > 0 {
+		mediaSequence = segments[0].Index
+	}
+	playlist += fmt.Sprintf("#EXT-X-MEDIA-SEQUENCE:%d\n", mediaSequence)
 
 	for _, segment := range segments {
 		playlist += fmt.Sprintf("#EXTINF:%.3f,\n", segment.Duration.Seconds())
 		playlist += fmt.Sprintf("%s\n", segment.URL)
 	}
 
-	playlist += "#EXT-X-ENDLIST\n"
+	if !live {
+		playlist += "#EXT-X-ENDLIST\n"
+	}
 
 	return playlist, nil
 }
@@ -97,7 +201,7 @@ func (s *Segmenter) GenerateMasterPlaylist(ctx context.Context, streamID domain.
 	for i, quality := range qualities {
 		bandwidth := s.getBandwidthForQuality(quality)
 		playlist += fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d\n", bandwidth)
-		playlist += fmt.Sprintf("/streams/%s/%s/index.m3u8\n", streamID, quality)
+		playlist += fmt.Sprintf("/hls/%s/%s/index.m3u8\n", streamID, quality)
 		
 		if i < len(qualities)-1 {
 			playlist += "\n"
@@ -171,20 +275,37 @@ func (sc *SegmentCache) Get(streamID domain.StreamID, quality string, index int)
 	return segment, exists
 }
 
-// ListSegments lists all segments for a stream/quality
+// ListSegments lists all segments for a stream/quality, ordered by index
 func (sc *SegmentCache) ListSegments(streamID domain.StreamID, quality string) []*Segment {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
 
 	var result []*Segment
-	prefix := fmt.Sprintf("%s-%s-", streamID, quality)
-
-	for key, segment := range sc.segments {
-		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+	for _, segment := range sc.segments {
+		if segment.StreamID == streamID && segment.Quality == quality {
 			result = append(result, segment)
 		}
 	}
 
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result
+}
+
+// Qualities lists the qualities a stream has cached segments in, sorted
+func (sc *SegmentCache) Qualities(streamID domain.StreamID) []string {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, segment := range sc.segments {
+		if segment.StreamID == streamID && !seen[segment.Quality] {
+			seen[segment.Quality] = true
+			result = append(result, segment.Quality)
+		}
+	}
+
+	sort.Strings(result)
 	return result
 }
 
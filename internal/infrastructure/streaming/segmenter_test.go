@@ -0,0 +1,128 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"go.uber.org/zap"
+)
+
+func TestSegmenter_CreateSegmentWritesFile(t *testing.T) {
+	dir := t.TempDir()
+	segmenter := NewSegmenter(2*time.Second, dir, zap.NewNop().Sugar())
+	data := []byte("mpeg-ts-payload")
+
+	segment, err := segmenter.CreateSegment(context.Background(), "disk-stream", "high", 7, data)
+	if err != nil {
+		t.Fatalf("CreateSegment: %v", err)
+	}
+
+	want := filepath.Join(dir, "disk-stream", "high", "disk-stream-high-7.ts")
+	if segment.FilePath != want {
+		t.Fatalf("expected file %s, got %s", want, segment.FilePath)
+	}
+	written, err := os.ReadFile(segment.FilePath)
+	if err != nil {
+		t.Fatalf("read segment: %v", err)
+	}
+	if !bytes.Equal(written, data) {
+		t.Fatalf("expected %q on disk, got %q", data, written)
+	}
+
+	// Only the segment is left behind, no temporary file
+	entries, err := os.ReadDir(filepath.Dir(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the segment file, got %d entries", len(entries))
+	}
+}
+
+func TestSegmenter_CreateSegmentReportsWriteFailure(t *testing.T) {
+	// A file where the output directory should be makes the write fail
+	outputPath := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(outputPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	segmenter := NewSegmenter(2*time.Second, outputPath, zap.NewNop().Sugar())
+
+	if _, err := segmenter.CreateSegment(context.Background(), "disk-stream", "high", 0, []byte("ts")); err == nil {
+		t.Fatal("expected an error when the segment cannot be written")
+	}
+}
+
+func TestSegmenter_CreateSegmentRejectsUnsafePaths(t *testing.T) {
+	root := t.TempDir()
+	outputPath := filepath.Join(root, "segments")
+	segmenter := NewSegmenter(2*time.Second, outputPath, zap.NewNop().Sugar())
+	segmenter.SetRetention(1)
+
+	// A file beside the output directory that retention must not reach
+	outside := filepath.Join(root, "x", "high", "keep.ts")
+	if err := os.MkdirAll(filepath.Dir(outside), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		streamID domain.StreamID
+		quality  string
+	}{
+		{"../x", "high"},
+		{"..", "high"},
+		{"disk-stream", "../../x"},
+		{"disk-stream", "ultra"},
+	} {
+		if _, err := segmenter.CreateSegment(ctx, tc.streamID, tc.quality, 5, []byte("ts")); err == nil {
+			t.Fatalf("expected stream %q quality %q to be rejected", tc.streamID, tc.quality)
+		}
+	}
+
+	if _, err := os.Stat(outside); err != nil {
+		t.Fatalf("expected the file outside the output directory to be kept: %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written, got %v", err)
+	}
+}
+
+func TestSegmenter_RetentionRemovesOldSegments(t *testing.T) {
+	dir := t.TempDir()
+	segmenter := NewSegmenter(2*time.Second, dir, zap.NewNop().Sugar())
+	segmenter.SetRetention(2)
+
+	ctx := context.Background()
+	for index := 0; index < 5; index++ {
+		if _, err := segmenter.CreateSegment(ctx, "disk-stream", "low", index, []byte("ts")); err != nil {
+			t.Fatalf("CreateSegment %d: %v", index, err)
+		}
+	}
+	// Another quality keeps its own segments
+	if _, err := segmenter.CreateSegment(ctx, "disk-stream", "high", 0, []byte("ts")); err != nil {
+		t.Fatalf("CreateSegment: %v", err)
+	}
+
+	for index, kept := range []bool{false, false, false, true, true} {
+		path := filepath.Join(dir, "disk-stream", "low", segmentFileName("disk-stream", "low", index))
+		_, err := os.Stat(path)
+		if kept && err != nil {
+			t.Fatalf("expected segment %d to be kept: %v", index, err)
+		}
+		if !kept && !os.IsNotExist(err) {
+			t.Fatalf("expected segment %d to be removed, got %v", index, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "disk-stream", "high", segmentFileName("disk-stream", "high", 0))); err != nil {
+		t.Fatalf("expected the other quality's segment to be kept: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package webrtc
+
+import (
+	"fmt"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var iceCandidatesDropped = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rillnet_sfu_ice_candidates_dropped_total",
+	Help: "Client ICE candidates rejected because the peer exceeded its candidate limit",
+})
+
+// allowICECandidate returns ErrTooManyCandidates when a candidate from peerID
+// exceeds MaxICECandidatesPerMinute, warning once per peer
+func (s *SFUService) allowICECandidate(peerID domain.PeerID) error {
+	allowed, firstDrop := s.candidateLimiter.Allow(peerID)
+	if allowed {
+		return nil
+	}
+	iceCandidatesDropped.Inc()
+	if firstDrop {
+		s.logger.Warnw("peer exceeded ICE candidate limit, dropping candidates", "peer_id", peerID)
+	}
+	return fmt.Errorf("%w: peer %s", domain.ErrTooManyCandidates, peerID)
+}
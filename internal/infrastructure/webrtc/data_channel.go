@@ -0,0 +1,102 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// DataChannelLabel names the ordered, reliable channel the SFU opens on
+// every publisher and subscriber connection for application data (chat,
+// reactions, sync cues)
+const DataChannelLabel = "rillnet-data"
+
+// defaultMaxDataChannelMessageSize is used when
+// WebRTCConfig.MaxDataChannelMessageSize is unset
+const defaultMaxDataChannelMessageSize = 16 * 1024
+
+// ErrDataChannelMessageTooLarge is logged for publisher messages over the size cap, which are dropped
+var ErrDataChannelMessageTooLarge = errors.New("data channel message too large")
+
+// createDataChannel opens the application data channel on a connection the
+// SFU offers. Channels default to ordered and reliable.
+func (s *SFUService) createDataChannel(pc *webrtc.PeerConnection) (*webrtc.DataChannel, error) {
+	channel, err := pc.CreateDataChannel(DataChannelLabel, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create data channel: %w", err)
+	}
+	return channel, nil
+}
+
+// acceptPublisherDataChannel relays the publisher's data channel once the
+// browser opens it, for connections negotiated from the browser's offer
+func (s *SFUService) acceptPublisherDataChannel(pc *webrtc.PeerConnection, peerID domain.PeerID, streamID domain.StreamID) {
+	pc.OnDataChannel(func(channel *webrtc.DataChannel) {
+		if channel.Label() != DataChannelLabel {
+			return
+		}
+		s.mu.Lock()
+		if publisher, ok := s.publishers[peerID]; ok && publisher.PC == pc {
+			publisher.DataChannel = channel
+		}
+		s.mu.Unlock()
+		channel.OnMessage(s.handleDataChannelMessage(peerID, streamID))
+	})
+}
+
+// handleDataChannelMessage forwards the publisher's data channel messages to
+// every subscriber of its stream whose channel is open. Subscribers' own
+// messages are not relayed.
+func (s *SFUService) handleDataChannelMessage(peerID domain.PeerID, streamID domain.StreamID) func(webrtc.DataChannelMessage) {
+	return func(msg webrtc.DataChannelMessage) {
+		if len(msg.Data) > s.maxDataChannelMessageSize() {
+			s.errLogger.Warnw(dataChannelLogScope(peerID), ErrDataChannelMessageTooLarge, "dropped data channel message",
+				"peer_id", peerID,
+				"stream_id", streamID,
+				"size", len(msg.Data),
+			)
+			return
+		}
+
+		s.mu.RLock()
+		channels := make(map[domain.PeerID]*webrtc.DataChannel)
+		for subscriberID, subscriber := range s.subscribers {
+			if subscriber.StreamID == streamID && subscriber.DataChannel != nil {
+				channels[subscriberID] = subscriber.DataChannel
+			}
+		}
+		s.mu.RUnlock()
+
+		for subscriberID, channel := range channels {
+			if channel.ReadyState() != webrtc.DataChannelStateOpen {
+				continue
+			}
+			var err error
+			if msg.IsString {
+				err = channel.SendText(string(msg.Data))
+			} else {
+				err = channel.Send(msg.Data)
+			}
+			if err != nil {
+				s.errLogger.Warnw(dataChannelLogScope(subscriberID), err, "failed to relay data channel message",
+					"peer_id", subscriberID,
+					"stream_id", streamID,
+				)
+			}
+		}
+	}
+}
+
+func (s *SFUService) maxDataChannelMessageSize() int {
+	if s.config.MaxDataChannelMessageSize > 0 {
+		return s.config.MaxDataChannelMessageSize
+	}
+	return defaultMaxDataChannelMessageSize
+}
+
+func dataChannelLogScope(peerID domain.PeerID) string {
+	return "datachannel:" + string(peerID)
+}
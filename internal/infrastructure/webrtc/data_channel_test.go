@@ -0,0 +1,105 @@
+package webrtc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// dataChannelClient answers an SFU offer and hands back the data channel
+// the SFU opened once it is usable
+func dataChannelClient(t *testing.T, offer webrtc.SessionDescription) (*webrtc.PeerConnection, webrtc.SessionDescription, <-chan *webrtc.DataChannel) {
+	t.Helper()
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	opened := make(chan *webrtc.DataChannel, 1)
+	client.OnDataChannel(func(channel *webrtc.DataChannel) {
+		if channel.Label() != DataChannelLabel {
+			return
+		}
+		channel.OnOpen(func() { opened <- channel })
+	})
+
+	require.NoError(t, client.SetRemoteDescription(offer))
+	answer, err := client.CreateAnswer(nil)
+	require.NoError(t, err)
+	gathered := webrtc.GatheringCompletePromise(client)
+	require.NoError(t, client.SetLocalDescription(answer))
+	<-gathered
+	return client, *client.LocalDescription(), opened
+}
+
+func awaitDataChannel(t *testing.T, opened <-chan *webrtc.DataChannel) *webrtc.DataChannel {
+	t.Helper()
+	select {
+	case channel := <-opened:
+		return channel
+	case <-time.After(10 * time.Second):
+		t.Fatal("data channel did not open")
+		return nil
+	}
+}
+
+func TestSFU_DataChannelRelaysPublisherMessagesToSubscribers(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{MaxDataChannelMessageSize: 64})
+
+	streamID := domain.StreamID("chat-stream")
+	publisherID := domain.PeerID("chat-publisher")
+	publisherOffer, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+	_, publisherAnswer, publisherOpened := dataChannelClient(t, publisherOffer)
+	require.NoError(t, sfu.HandlePublisherAnswer(ctx, publisherID, publisherAnswer))
+
+	// Subscribers are only offered to streams with media
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "camera", "camera")
+	require.NoError(t, err)
+	sfu.mu.Lock()
+	sfu.trackForwarders["camera"] = &TrackForwarder{
+		TrackID:     "camera",
+		Publisher:   publisherID,
+		StreamID:    streamID,
+		Track:       track,
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+	}
+	sfu.mu.Unlock()
+
+	subscriberID := domain.PeerID("chat-viewer")
+	subscriberOffer, err := sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+	require.NoError(t, err)
+	_, subscriberAnswer, subscriberOpened := dataChannelClient(t, subscriberOffer)
+	require.NoError(t, sfu.HandleSubscriberAnswer(ctx, subscriberID, subscriberAnswer))
+
+	received := make(chan string, 4)
+	subscriberChannel := awaitDataChannel(t, subscriberOpened)
+	subscriberChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		received <- string(msg.Data)
+	})
+	publisherChannel := awaitDataChannel(t, publisherOpened)
+	// The SFU's end may report open slightly after the client's
+	require.Eventually(t, func() bool {
+		subscriber, ok := sfu.GetSubscriber(subscriberID)
+		return ok && subscriber.DataChannel.ReadyState() == webrtc.DataChannelStateOpen
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, publisherChannel.SendText("hello"))
+	require.NoError(t, publisherChannel.SendText(strings.Repeat("x", 65))) // over the cap, dropped
+	require.NoError(t, publisherChannel.SendText("bye"))
+
+	for _, want := range []string{"hello", "bye"} {
+		select {
+		case got := <-received:
+			require.Equal(t, want, got)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("subscriber did not receive %q", want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// EvictionCause identifies why a peer is removed from the SFU
+type EvictionCause string
+
+const (
+	// EvictionICEDisconnect: ICE stayed disconnected or failed
+	EvictionICEDisconnect EvictionCause = "ice_disconnect"
+	// EvictionMetricsStale: no RTCP arrived from a connected peer
+	EvictionMetricsStale EvictionCause = "metrics_stale"
+	// EvictionAnswerTimeout: the client never answered the SFU's offer
+	EvictionAnswerTimeout EvictionCause = "answer_timeout"
+)
+
+// EvictionPolicy holds the grace period for each eviction cause. A zero grace
+// period disables eviction for that cause and the session is kept.
+type EvictionPolicy struct {
+	ICEDisconnectGrace time.Duration
+	MetricsStaleGrace  time.Duration
+	AnswerTimeout      time.Duration
+}
+
+// Grace returns how long a cause must persist before the peer is evicted
+func (p EvictionPolicy) Grace(cause EvictionCause) time.Duration {
+	switch cause {
+	case EvictionICEDisconnect:
+		return p.ICEDisconnectGrace
+	case EvictionMetricsStale:
+		return p.MetricsStaleGrace
+	case EvictionAnswerTimeout:
+		return p.AnswerTimeout
+	default:
+		return 0
+	}
+}
+
+var peerEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rillnet_sfu_peer_evictions_total",
+	Help: "Peers evicted from the SFU after an eviction cause outlived its grace period",
+}, []string{"cause"})
+
+type evictionKey struct {
+	peerID domain.PeerID
+	cause  EvictionCause
+}
+
+// evictionTracker runs one grace timer per peer and cause and reports the
+// causes that outlive their grace period to evict.
+type evictionTracker struct {
+	policy EvictionPolicy
+	evict  func(domain.PeerID, EvictionCause)
+
+	mu     sync.Mutex
+	timers map[evictionKey]*time.Timer
+}
+
+func newEvictionTracker(policy EvictionPolicy, evict func(domain.PeerID, EvictionCause)) *evictionTracker {
+	return &evictionTracker{
+		policy: policy,
+		evict:  evict,
+		timers: make(map[evictionKey]*time.Timer),
+	}
+}
+
+// start begins the grace period for a cause unless it is already running
+func (t *evictionTracker) start(peerID domain.PeerID, cause EvictionCause) {
+	t.arm(peerID, cause, false)
+}
+
+// restart begins the grace period for a cause again from now
+func (t *evictionTracker) restart(peerID domain.PeerID, cause EvictionCause) {
+	t.arm(peerID, cause, true)
+}
+
+func (t *evictionTracker) arm(peerID domain.PeerID, cause EvictionCause, reset bool) {
+	grace := t.policy.Grace(cause)
+	if grace <= 0 {
+		return
+	}
+	key := evictionKey{peerID: peerID, cause: cause}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if timer, ok := t.timers[key]; ok {
+		if !reset {
+			return
+		}
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(grace, func() {
+		t.mu.Lock()
+		if t.timers[key] != timer {
+			t.mu.Unlock()
+			return
+		}
+		delete(t.timers, key)
+		t.mu.Unlock()
+
+		t.evict(peerID, cause)
+	})
+	t.timers[key] = timer
+}
+
+// cancel stops the grace period for a cause that cleared
+func (t *evictionTracker) cancel(peerID domain.PeerID, cause EvictionCause) {
+	key := evictionKey{peerID: peerID, cause: cause}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if timer, ok := t.timers[key]; ok {
+		timer.Stop()
+		delete(t.timers, key)
+	}
+}
+
+// forget stops every grace period of a peer that left
+func (t *evictionTracker) forget(peerID domain.PeerID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, timer := range t.timers {
+		if key.peerID == peerID {
+			timer.Stop()
+			delete(t.timers, key)
+		}
+	}
+}
+
+// forgetAll stops every grace period
+func (t *evictionTracker) forgetAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, timer := range t.timers {
+		timer.Stop()
+		delete(t.timers, key)
+	}
+}
+
+// evictPeer is where every eviction path ends. The cause is checked against
+// the peer's current connection first, since the peer may have reconnected
+// while the grace timer ran.
+func (s *SFUService) evictPeer(peerID domain.PeerID, cause EvictionCause) {
+	pc := s.peerConnection(peerID)
+	if pc == nil || !evictionCauseHolds(pc, cause) {
+		return
+	}
+
+	s.logger.Warnw("evicting peer",
+		"peer_id", peerID,
+		"cause", cause,
+		"grace", s.eviction.policy.Grace(cause),
+	)
+	peerEvictions.WithLabelValues(string(cause)).Inc()
+	s.handlePeerDisconnect(peerID)
+}
+
+func evictionCauseHolds(pc *webrtc.PeerConnection, cause EvictionCause) bool {
+	switch cause {
+	case EvictionICEDisconnect:
+		state := pc.ICEConnectionState()
+		return state == webrtc.ICEConnectionStateDisconnected || state == webrtc.ICEConnectionStateFailed
+	case EvictionAnswerTimeout:
+		return pc.RemoteDescription() == nil
+	default:
+		return true
+	}
+}
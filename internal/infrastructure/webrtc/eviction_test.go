@@ -0,0 +1,123 @@
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictionTracker_EachCauseRespectsItsGracePeriod(t *testing.T) {
+	policy := EvictionPolicy{
+		ICEDisconnectGrace: 20 * time.Millisecond,
+		MetricsStaleGrace:  150 * time.Millisecond,
+		AnswerTimeout:      300 * time.Millisecond,
+	}
+
+	var mu sync.Mutex
+	fired := make(map[EvictionCause]time.Duration)
+	started := time.Now()
+	tracker := newEvictionTracker(policy, func(peerID domain.PeerID, cause EvictionCause) {
+		mu.Lock()
+		defer mu.Unlock()
+		fired[cause] = time.Since(started)
+	})
+
+	causes := []EvictionCause{EvictionICEDisconnect, EvictionMetricsStale, EvictionAnswerTimeout}
+	for _, cause := range causes {
+		tracker.start("peer", cause)
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fired) == len(causes)
+	}, 5*time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, cause := range causes {
+		require.GreaterOrEqual(t, fired[cause], policy.Grace(cause), "cause %s evicted before its grace period", cause)
+	}
+	require.Less(t, fired[EvictionICEDisconnect], fired[EvictionMetricsStale])
+	require.Less(t, fired[EvictionMetricsStale], fired[EvictionAnswerTimeout])
+}
+
+func TestEvictionTracker_CancelDisabledAndRestart(t *testing.T) {
+	policy := EvictionPolicy{
+		ICEDisconnectGrace: 30 * time.Millisecond,
+		MetricsStaleGrace:  80 * time.Millisecond,
+	}
+
+	evicted := make(chan EvictionCause, 8)
+	tracker := newEvictionTracker(policy, func(peerID domain.PeerID, cause EvictionCause) {
+		evicted <- cause
+	})
+
+	// Zero grace disables the cause; cancel stops a running grace period
+	tracker.start("peer", EvictionAnswerTimeout)
+	tracker.start("peer", EvictionICEDisconnect)
+	tracker.cancel("peer", EvictionICEDisconnect)
+
+	// Fresh metrics push the staleness deadline out
+	started := time.Now()
+	tracker.start("peer", EvictionMetricsStale)
+	time.Sleep(50 * time.Millisecond)
+	tracker.restart("peer", EvictionMetricsStale)
+
+	select {
+	case cause := <-evicted:
+		require.Equal(t, EvictionMetricsStale, cause)
+		require.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond+policy.MetricsStaleGrace)
+	case <-time.After(5 * time.Second):
+		t.Fatal("metrics staleness was not evicted")
+	}
+
+	select {
+	case cause := <-evicted:
+		t.Fatalf("unexpected eviction for %s", cause)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSFU_UnansweredOfferEvictedAfterAnswerTimeout(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{
+		Eviction: EvictionPolicy{AnswerTimeout: 50 * time.Millisecond},
+	})
+
+	peerID := domain.PeerID("silent-publisher")
+	_, err := sfu.CreatePublisherOffer(context.Background(), peerID, domain.StreamID("eviction-stream"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, ok := sfu.GetPublisher(peerID)
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSFU_OnlyPublisherRTCPRefreshesStaleness(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{
+		Eviction: EvictionPolicy{MetricsStaleGrace: time.Minute},
+	})
+	key := evictionKey{peerID: "rtcp-peer", cause: EvictionMetricsStale}
+	report := []rtcp.Packet{&rtcp.ReceiverReport{}}
+
+	sfu.processRTCPPackets("rtcp-peer", "rtcp-stream", report, false)
+	sfu.eviction.mu.Lock()
+	_, armed := sfu.eviction.timers[key]
+	sfu.eviction.mu.Unlock()
+	require.False(t, armed, "subscriber RTCP must not keep a publisher alive")
+
+	sfu.processRTCPPackets("rtcp-peer", "rtcp-stream", report, true)
+	sfu.eviction.mu.Lock()
+	_, armed = sfu.eviction.timers[key]
+	sfu.eviction.mu.Unlock()
+	require.True(t, armed)
+
+	sfu.eviction.forget("rtcp-peer")
+}
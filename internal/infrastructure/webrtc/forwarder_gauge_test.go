@@ -0,0 +1,61 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/infrastructure/monitoring"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// gaugeValue reads a registered gauge by name
+func gaugeValue(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("gauge %s is not registered", name)
+	return 0
+}
+
+func TestSFU_ForwarderGaugeTracksForwarders(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+	gauges := monitoring.NewRuntimeGauges(sfu, nil)
+
+	publisherID := domain.PeerID("gauge-publisher")
+	streamID := domain.StreamID("gauge-stream")
+	_, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+
+	for _, id := range []string{"gauge-audio", "gauge-video"} {
+		track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, id, id)
+		require.NoError(t, err)
+		sfu.mu.Lock()
+		sfu.trackForwarders[domain.TrackID(id)] = &TrackForwarder{
+			TrackID:     domain.TrackID(id),
+			Publisher:   publisherID,
+			StreamID:    streamID,
+			Track:       track,
+			Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+		}
+		sfu.mu.Unlock()
+	}
+
+	gauges.Update()
+	require.Equal(t, float64(2), gaugeValue(t, "rillnet_active_forwarders"))
+	require.Positive(t, gaugeValue(t, "rillnet_goroutines"))
+
+	// Tearing the publisher down drops its forwarders
+	sfu.removePeer(publisherID)
+	gauges.Update()
+	require.Equal(t, float64(0), gaugeValue(t, "rillnet_active_forwarders"))
+}
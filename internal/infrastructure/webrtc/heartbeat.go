@@ -0,0 +1,54 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+)
+
+// RunPeerHeartbeats refreshes the repository entry of every peer connected to
+// the SFU each interval until ctx is done, so peers that joined over HTTP and
+// never open a signaling socket do not expire while their media flows
+func (s *SFUService) RunPeerHeartbeats(ctx context.Context, heartbeater ports.PeerHeartbeater, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.heartbeatPeers(ctx, heartbeater)
+		}
+	}
+}
+
+func (s *SFUService) heartbeatPeers(ctx context.Context, heartbeater ports.PeerHeartbeater) {
+	for _, peerID := range s.connectedPeerIDs() {
+		if err := heartbeater.Heartbeat(ctx, peerID); err != nil && !errors.Is(err, domain.ErrPeerNotFound) {
+			s.logger.Warnw("failed to refresh peer registration", "peer_id", peerID, "error", err)
+		}
+	}
+}
+
+// connectedPeerIDs returns every publisher and subscriber the SFU holds
+func (s *SFUService) connectedPeerIDs() []domain.PeerID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	peerIDs := make([]domain.PeerID, 0, len(s.publishers)+len(s.subscribers))
+	for peerID := range s.publishers {
+		peerIDs = append(peerIDs, peerID)
+	}
+	for peerID := range s.subscribers {
+		peerIDs = append(peerIDs, peerID)
+	}
+	return peerIDs
+}
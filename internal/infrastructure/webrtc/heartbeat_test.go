@@ -0,0 +1,50 @@
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHeartbeater struct {
+	mu    sync.Mutex
+	beats map[domain.PeerID]int
+}
+
+func (h *recordingHeartbeater) Heartbeat(ctx context.Context, peerID domain.PeerID) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beats[peerID]++
+	return nil
+}
+
+func (h *recordingHeartbeater) count(peerID domain.PeerID) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.beats[peerID]
+}
+
+func TestSFU_HeartbeatsConnectedPeers(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+	streamID := domain.StreamID("heartbeat-stream")
+
+	sfu.mu.Lock()
+	sfu.publishers["publisher"] = &Publisher{PeerID: "publisher", StreamID: streamID}
+	sfu.subscribers["viewer"] = &Subscriber{PeerID: "viewer", StreamID: streamID}
+	sfu.mu.Unlock()
+
+	heartbeater := &recordingHeartbeater{beats: make(map[domain.PeerID]int)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sfu.RunPeerHeartbeats(ctx, heartbeater, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return heartbeater.count("publisher") >= 2 && heartbeater.count("viewer") >= 2
+	}, time.Second, 10*time.Millisecond)
+	require.Zero(t, heartbeater.count("gone"))
+}
@@ -0,0 +1,31 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_UpdateICEServersAppliesToNewPeerConnections(t *testing.T) {
+	oldServers := []webrtc.ICEServer{{URLs: []string{"stun:old.example.com:3478"}}}
+	newServers := []webrtc.ICEServer{{
+		URLs:       []string{"turn:turn.example.com:3478"},
+		Username:   "rotated",
+		Credential: "secret",
+	}}
+	sfu := newTestSFU(WebRTCConfig{ICEServers: oldServers})
+
+	before, err := sfu.createPeerConnection()
+	require.NoError(t, err)
+	defer before.Close()
+
+	sfu.UpdateICEServers(newServers)
+
+	after, err := sfu.createPeerConnection()
+	require.NoError(t, err)
+	defer after.Close()
+
+	require.Equal(t, oldServers, before.GetConfiguration().ICEServers)
+	require.Equal(t, newServers, after.GetConfiguration().ICEServers)
+}
@@ -0,0 +1,82 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// pausedSender is a subscriber video sender detached from its track because
+// the subscriber is not displaying it
+type pausedSender struct {
+	sender    *webrtc.RTPSender
+	forwarder *TrackForwarder
+}
+
+// SetSubscriberInterest forwards to a subscriber only the video tracks it is
+// displaying. Tracks are named by the IDs the subscriber sees in its offer;
+// video tracks not in activeTracks are detached from their sender, audio is
+// always kept. A resumed track gets a keyframe request so it decodes at once.
+func (s *SFUService) SetSubscriberInterest(ctx context.Context, peerID domain.PeerID, activeTracks []string) error {
+	active := make(map[string]bool, len(activeTracks))
+	for _, id := range activeTracks {
+		active[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriber, exists := s.subscribers[peerID]
+	if !exists {
+		return domain.ErrPeerNotFound
+	}
+	if subscriber.pausedSenders == nil {
+		subscriber.pausedSenders = make(map[string]pausedSender)
+	}
+
+	// Pause forwarded video tracks the subscriber is no longer displaying
+	for _, sender := range subscriber.PC.GetSenders() {
+		forwarder := s.forwarderForTrack(sender.Track())
+		if forwarder == nil || forwarder.Track.Kind() != webrtc.RTPCodecTypeVideo || active[forwarder.Track.ID()] {
+			continue
+		}
+		if err := sender.ReplaceTrack(nil); err != nil {
+			return fmt.Errorf("pause track %s for %s: %w", forwarder.Track.ID(), peerID, err)
+		}
+		forwarder.Mu.Lock()
+		delete(forwarder.Subscribers, peerID)
+		forwarder.Mu.Unlock()
+		subscriber.pausedSenders[forwarder.Track.ID()] = pausedSender{sender: sender, forwarder: forwarder}
+	}
+
+	// Resume paused tracks it displays again
+	for trackID, paused := range subscriber.pausedSenders {
+		if !active[trackID] {
+			continue
+		}
+		delete(subscriber.pausedSenders, trackID)
+		if _, ok := s.trackForwarders[paused.forwarder.TrackID]; !ok {
+			continue // Unpublished while paused
+		}
+		if err := paused.sender.ReplaceTrack(paused.forwarder.Track); err != nil {
+			return fmt.Errorf("resume track %s for %s: %w", trackID, peerID, err)
+		}
+		paused.forwarder.Mu.Lock()
+		paused.forwarder.Subscribers[peerID] = subscriber.PC
+		paused.forwarder.Mu.Unlock()
+
+		go func(publisher domain.PeerID, key domain.TrackID) {
+			_ = s.requestKeyframe(publisher, key)
+		}(paused.forwarder.Publisher, paused.forwarder.TrackID)
+	}
+
+	s.logger.Infow("subscriber interest updated",
+		"peer_id", peerID,
+		"active_tracks", activeTracks,
+		"paused_tracks", len(subscriber.pausedSenders),
+	)
+	return nil
+}
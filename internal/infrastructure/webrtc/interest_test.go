@@ -0,0 +1,79 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_SetSubscriberInterestPausesAndResumesVideo(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	streamID := domain.StreamID("grid-stream")
+	publisherID := domain.PeerID("grid-publisher")
+	_, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+
+	addForwarder := func(id string, mimeType string) *TrackForwarder {
+		track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: mimeType}, id, string(streamID))
+		require.NoError(t, err)
+		forwarder := &TrackForwarder{
+			TrackID:     domain.TrackID(id),
+			Publisher:   publisherID,
+			StreamID:    streamID,
+			Track:       track,
+			Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+		}
+		sfu.mu.Lock()
+		sfu.trackForwarders[forwarder.TrackID] = forwarder
+		sfu.mu.Unlock()
+		return forwarder
+	}
+	tileA := addForwarder("tile-a", webrtc.MimeTypeVP8)
+	tileB := addForwarder("tile-b", webrtc.MimeTypeVP8)
+	audio := addForwarder("audio", webrtc.MimeTypeOpus)
+
+	subscriberID := domain.PeerID("grid-viewer")
+	_, err = sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+	require.NoError(t, err)
+	subscriber, ok := sfu.GetSubscriber(subscriberID)
+	require.True(t, ok)
+
+	isForwarded := func(fwd *TrackForwarder) bool {
+		fwd.Mu.RLock()
+		_, subscribed := fwd.Subscribers[subscriberID]
+		fwd.Mu.RUnlock()
+		for _, sender := range subscriber.PC.GetSenders() {
+			if sender.Track() == webrtc.TrackLocal(fwd.Track) {
+				return subscribed
+			}
+		}
+		return false
+	}
+	require.True(t, isForwarded(tileA))
+	require.True(t, isForwarded(tileB))
+
+	// Deselecting tile-b pauses it; audio is kept although not listed
+	require.NoError(t, sfu.SetSubscriberInterest(ctx, subscriberID, []string{"tile-a"}))
+	require.True(t, isForwarded(tileA))
+	require.False(t, isForwarded(tileB))
+	require.True(t, isForwarded(audio))
+
+	// Selecting it again resumes forwarding and asks the publisher for a keyframe
+	require.NoError(t, sfu.SetSubscriberInterest(ctx, subscriberID, []string{"tile-a", "tile-b"}))
+	require.True(t, isForwarded(tileA))
+	require.True(t, isForwarded(tileB))
+	require.Eventually(t, func() bool {
+		tileB.Mu.RLock()
+		defer tileB.Mu.RUnlock()
+		return !tileB.lastKeyframeRequest.IsZero()
+	}, time.Second, 10*time.Millisecond)
+
+	require.ErrorIs(t, sfu.SetSubscriberInterest(ctx, "unknown", nil), domain.ErrPeerNotFound)
+}
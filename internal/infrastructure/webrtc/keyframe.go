@@ -0,0 +1,173 @@
+package webrtc
+
+import (
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+// keyframeRequestInterval collapses keyframe requests for one track, so a
+// burst of joining or lossy subscribers costs the publisher a single keyframe
+const keyframeRequestInterval = 500 * time.Millisecond
+
+// SetKeyframeOnJoin sets whether a subscriber joining the stream triggers a
+// keyframe request to the publisher. On by default; streams whose publishers
+// are CPU-bound can turn it off and let viewers wait for the natural keyframe.
+func (s *SFUService) SetKeyframeOnJoin(streamID domain.StreamID, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if enabled {
+		delete(s.noKeyframeOnJoin, streamID)
+	} else {
+		s.noKeyframeOnJoin[streamID] = true
+	}
+}
+
+// KeyframeOnJoin reports the stream's keyframe-on-join policy
+func (s *SFUService) KeyframeOnJoin(streamID domain.StreamID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.noKeyframeOnJoin[streamID]
+}
+
+// requestSubscriberKeyframes asks for a keyframe on the video tracks of a
+// subscriber whose connection just came up; a keyframe sent before that would
+// never reach it. Peers that are not subscribers, or have no peer connection
+// yet, are ignored.
+func (s *SFUService) requestSubscriberKeyframes(peerID domain.PeerID) {
+	s.mu.RLock()
+	subscriber, ok := s.subscribers[peerID]
+	if !ok || subscriber.PC == nil {
+		s.mu.RUnlock()
+		return
+	}
+	streamID := subscriber.StreamID
+	var tracks []*webrtc.TrackLocalStaticRTP
+	for _, sender := range subscriber.PC.GetSenders() {
+		if track, ok := sender.Track().(*webrtc.TrackLocalStaticRTP); ok {
+			tracks = append(tracks, track)
+		}
+	}
+	s.mu.RUnlock()
+
+	s.requestJoinKeyframes(peerID, streamID, tracks)
+}
+
+// requestJoinKeyframes asks for a keyframe on the video tracks a connected
+// subscriber is attached to, so its picture starts without waiting for the
+// publisher's keyframe interval.
+func (s *SFUService) requestJoinKeyframes(peerID domain.PeerID, streamID domain.StreamID, tracks []*webrtc.TrackLocalStaticRTP) {
+	if !s.KeyframeOnJoin(streamID) {
+		return
+	}
+
+	for _, track := range tracks {
+		if track.Kind() != webrtc.RTPCodecTypeVideo {
+			continue
+		}
+		s.mu.RLock()
+		forwarder := s.forwarderForTrack(track)
+		s.mu.RUnlock()
+		if forwarder == nil {
+			continue
+		}
+		if err := s.requestKeyframe(forwarder.Publisher, forwarder.TrackID); err != nil {
+			s.logger.Debugw("failed to request keyframe for joining subscriber",
+				"peer_id", peerID,
+				"track_id", forwarder.TrackID,
+				"error", err,
+			)
+		}
+	}
+}
+
+// processSubscriberRTCP reads the RTCP a subscriber sends back through one
+// sender until it is closed. The forwarder is resolved per batch since a
+// simulcast layer switch replaces the sender's track.
+func (s *SFUService) processSubscriberRTCP(peerID domain.PeerID, sender *webrtc.RTPSender) {
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+
+		s.mu.RLock()
+		forwarder := s.forwarderForTrack(sender.Track())
+		s.mu.RUnlock()
+		if forwarder == nil {
+			continue
+		}
+		s.handleSubscriberRTCP(peerID, forwarder.TrackID, packets)
+	}
+}
+
+// handleSubscriberRTCP forwards picture loss (PLI/FIR) from a subscriber to
+// the publisher of the track it was reported for, and records the loss and
+// bandwidth the subscriber reports through transport-cc and REMB feedback.
+func (s *SFUService) handleSubscriberRTCP(peerID domain.PeerID, trackID domain.TrackID, packets []rtcp.Packet) {
+	for _, packet := range packets {
+		switch feedback := packet.(type) {
+		case *rtcp.TransportLayerCC:
+			s.recordTransportCC(peerID, feedback)
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			s.recordREMB(peerID, feedback)
+		}
+	}
+
+	for _, packet := range packets {
+		switch packet.(type) {
+		case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+		default:
+			continue
+		}
+
+		s.mu.RLock()
+		forwarder, ok := s.trackForwarders[trackID]
+		s.mu.RUnlock()
+		if !ok {
+			return
+		}
+
+		if err := s.requestKeyframe(forwarder.Publisher, trackID); err != nil {
+			s.errLogger.Warnw(rtcpLogScope(peerID), err, "failed to forward keyframe request",
+				"peer_id", peerID,
+				"publisher", forwarder.Publisher,
+				"track_id", trackID,
+			)
+		}
+		return
+	}
+}
+
+// requestKeyframe asks a publisher for a keyframe on one of its tracks by
+// writing a PLI for the track's SSRC onto the publisher's PeerConnection.
+func (s *SFUService) requestKeyframe(publisherPeerID domain.PeerID, trackID domain.TrackID) error {
+	s.mu.RLock()
+	forwarder, ok := s.trackForwarders[trackID]
+	publisher, hasPublisher := s.publishers[publisherPeerID]
+	s.mu.RUnlock()
+
+	if !ok || forwarder.Publisher != publisherPeerID {
+		return fmt.Errorf("track %s is not published by %s", trackID, publisherPeerID)
+	}
+	if !hasPublisher || publisher.PC == nil {
+		return domain.ErrPeerNotFound
+	}
+
+	forwarder.Mu.Lock()
+	if time.Since(forwarder.lastKeyframeRequest) < keyframeRequestInterval {
+		forwarder.Mu.Unlock()
+		return nil
+	}
+	forwarder.lastKeyframeRequest = time.Now()
+	ssrc := forwarder.SSRC
+	forwarder.Mu.Unlock()
+
+	return publisher.PC.WriteRTCP([]rtcp.Packet{
+		&rtcp.PictureLossIndication{MediaSSRC: uint32(ssrc)},
+	})
+}
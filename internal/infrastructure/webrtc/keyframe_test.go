@@ -0,0 +1,209 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_SubscriberPLIRequestsKeyframeFromPublisher(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	publisherID := domain.PeerID("keyframe-publisher")
+	streamID := domain.StreamID("keyframe-stream")
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	video, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "keyframe")
+	require.NoError(t, err)
+	sender, err := client.AddTrack(video)
+	require.NoError(t, err)
+
+	offer, err := client.CreateOffer(nil)
+	require.NoError(t, err)
+	gathered := webrtc.GatheringCompletePromise(client)
+	require.NoError(t, client.SetLocalDescription(offer))
+	<-gathered
+
+	answer, err := sfu.HandlePublisherClientOffer(ctx, publisherID, streamID, *client.LocalDescription())
+	require.NoError(t, err)
+	require.NoError(t, client.SetRemoteDescription(answer))
+
+	// Media must flow before the SFU sees the track and creates its forwarder
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = video.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+
+	var forwarder *TrackForwarder
+	require.Eventually(t, func() bool {
+		sfu.mu.RLock()
+		defer sfu.mu.RUnlock()
+		forwarder = sfu.trackForwarders["video"]
+		return forwarder != nil
+	}, 10*time.Second, 20*time.Millisecond)
+	require.Equal(t, publisherID, forwarder.Publisher)
+	require.NotZero(t, forwarder.SSRC)
+
+	plis := make(chan uint32, 16)
+	go func() {
+		for {
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, packet := range packets {
+				if pli, ok := packet.(*rtcp.PictureLossIndication); ok {
+					plis <- pli.MediaSSRC
+				}
+			}
+		}
+	}()
+
+	sfu.handleSubscriberRTCP("keyframe-viewer", forwarder.TrackID, []rtcp.Packet{
+		&rtcp.PictureLossIndication{MediaSSRC: 1234},
+	})
+
+	select {
+	case ssrc := <-plis:
+		require.Equal(t, uint32(forwarder.SSRC), ssrc)
+	case <-time.After(5 * time.Second):
+		t.Fatal("publisher did not receive a keyframe request")
+	}
+}
+
+func TestSFU_RequestKeyframeRejectsForeignTrack(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+
+	sfu.mu.Lock()
+	sfu.trackForwarders["video"] = &TrackForwarder{
+		TrackID:     "video",
+		Publisher:   "owner",
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+	}
+	sfu.mu.Unlock()
+
+	require.Error(t, sfu.requestKeyframe("someone-else", "video"))
+	require.ErrorIs(t, sfu.requestKeyframe("owner", "video"), domain.ErrPeerNotFound)
+}
+
+// publishKeyframeTrack plays a publisher sending VP8 on trackID and returns
+// the SFU's forwarder for it along with the PLIs the publisher receives.
+func publishKeyframeTrack(t *testing.T, sfu *SFUService, publisherID domain.PeerID, streamID domain.StreamID, trackID string) (*TrackForwarder, <-chan uint32) {
+	t.Helper()
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	video, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, trackID, string(streamID))
+	require.NoError(t, err)
+	sender, err := client.AddTrack(video)
+	require.NoError(t, err)
+
+	offer, err := client.CreateOffer(nil)
+	require.NoError(t, err)
+	gathered := webrtc.GatheringCompletePromise(client)
+	require.NoError(t, client.SetLocalDescription(offer))
+	<-gathered
+
+	answer, err := sfu.HandlePublisherClientOffer(context.Background(), publisherID, streamID, *client.LocalDescription())
+	require.NoError(t, err)
+	require.NoError(t, client.SetRemoteDescription(answer))
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = video.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+
+	var forwarder *TrackForwarder
+	require.Eventually(t, func() bool {
+		sfu.mu.RLock()
+		defer sfu.mu.RUnlock()
+		forwarder = sfu.trackForwarders[domain.TrackID(trackID)]
+		return forwarder != nil
+	}, 10*time.Second, 20*time.Millisecond)
+
+	plis := make(chan uint32, 16)
+	go func() {
+		for {
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, packet := range packets {
+				if pli, ok := packet.(*rtcp.PictureLossIndication); ok {
+					plis <- pli.MediaSSRC
+				}
+			}
+		}
+	}()
+	return forwarder, plis
+}
+
+func TestSFU_KeyframeOnJoinPolicy(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	fastJoin := domain.StreamID("fast-join-stream")
+	cpuSaver := domain.StreamID("cpu-saver-stream")
+	sfu.SetKeyframeOnJoin(cpuSaver, false)
+	require.True(t, sfu.KeyframeOnJoin(fastJoin))
+	require.False(t, sfu.KeyframeOnJoin(cpuSaver))
+
+	fastForwarder, fastPLIs := publishKeyframeTrack(t, sfu, "fast-publisher", fastJoin, "fast-video")
+	_, saverPLIs := publishKeyframeTrack(t, sfu, "saver-publisher", cpuSaver, "saver-video")
+
+	// The keyframe is requested once the subscriber connects, so it can't be
+	// sent before the subscriber is able to receive it
+	connect := func(peerID domain.PeerID, streamID domain.StreamID) {
+		offer, err := sfu.CreateSubscriberOffer(ctx, peerID, streamID, nil)
+		require.NoError(t, err)
+		require.NoError(t, sfu.HandleSubscriberAnswer(ctx, peerID, answerOffer(t, offer)))
+	}
+	connect("fast-viewer", fastJoin)
+	connect("saver-viewer", cpuSaver)
+
+	select {
+	case ssrc := <-fastPLIs:
+		require.Equal(t, uint32(fastForwarder.SSRC), ssrc)
+	case <-time.After(10 * time.Second):
+		t.Fatal("publisher did not receive a keyframe request on subscriber join")
+	}
+
+	select {
+	case <-saverPLIs:
+		t.Fatal("publisher received a keyframe request with keyframe-on-join off")
+	case <-time.After(time.Second):
+	}
+}
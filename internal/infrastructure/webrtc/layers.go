@@ -0,0 +1,131 @@
+package webrtc
+
+import (
+	"context"
+	"sort"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+)
+
+// SetLayersNotifier sets who is told when the simulcast layers a publisher
+// produces change. Must be called before peers connect.
+func (s *SFUService) SetLayersNotifier(notifier ports.LayersNotifier) {
+	s.layersNotifier = notifier
+}
+
+// PublisherLayers returns the simulcast layers the publisher is producing,
+// lowest first; empty when it does not simulcast
+func (s *SFUService) PublisherLayers(peerID domain.PeerID) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.publisherLayersLocked(peerID)
+}
+
+// publisherLayersLocked derives a publisher's layers from its active
+// forwarders. Callers hold s.mu.
+func (s *SFUService) publisherLayersLocked(peerID domain.PeerID) []string {
+	seen := make(map[string]bool)
+	layers := []string{}
+	for _, fwd := range s.trackForwarders {
+		if fwd.Publisher != peerID || fwd.Layer == "" || seen[fwd.Layer] {
+			continue
+		}
+		seen[fwd.Layer] = true
+		layers = append(layers, fwd.Layer)
+	}
+	sort.Slice(layers, func(i, j int) bool { return layerRank(layers[i]) < layerRank(layers[j]) })
+	return layers
+}
+
+// forwarderStopped drops a forwarder whose publisher track ended. Subscribers
+// of a dropped simulcast layer move to the nearest remaining one, and the
+// stream is told which layers are left. A forwarder already removed with its
+// publisher is ignored.
+func (s *SFUService) forwarderStopped(forwarder *TrackForwarder) {
+	s.mu.Lock()
+	if s.trackForwarders[forwarder.TrackID] != forwarder {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.trackForwarders, forwarder.TrackID)
+	s.prioritizer.UnregisterTrack(forwarder.TrackID)
+
+	var layers []string
+	if forwarder.Layer != "" {
+		s.moveToSiblingLayerLocked(forwarder)
+		layers = s.publisherLayersLocked(forwarder.Publisher)
+	}
+	s.mu.Unlock()
+
+	s.logger.Infow("publisher track stopped",
+		"track_id", forwarder.TrackID,
+		"publisher", forwarder.Publisher,
+		"layer", forwarder.Layer,
+	)
+	if forwarder.Layer != "" {
+		s.notifyLayersAvailable(forwarder.StreamID, forwarder.Publisher, layers)
+	}
+}
+
+// moveToSiblingLayerLocked switches the subscribers of a removed layer
+// forwarder to the nearest layer the publisher still produces. Callers hold s.mu.
+func (s *SFUService) moveToSiblingLayerLocked(stopped *TrackForwarder) {
+	siblings := make(map[string]*TrackForwarder)
+	for _, fwd := range s.trackForwarders {
+		if fwd.Layer != "" && fwd.Publisher == stopped.Publisher && fwd.Track.ID() == stopped.Track.ID() {
+			siblings[fwd.Layer] = fwd
+		}
+	}
+	if len(siblings) == 0 {
+		return
+	}
+
+	stopped.Mu.Lock()
+	defer stopped.Mu.Unlock()
+	for peerID, pc := range stopped.Subscribers {
+		delete(stopped.Subscribers, peerID)
+
+		want := stopped.Layer
+		if subscriber, ok := s.subscribers[peerID]; ok && subscriber.Quality != "" {
+			want = subscriber.Quality
+		}
+		available := make([]string, 0, len(siblings))
+		for layer := range siblings {
+			available = append(available, layer)
+		}
+		target := siblings[nearestLayer(available, want)]
+
+		for _, sender := range pc.GetSenders() {
+			if sender.Track() != stopped.Track {
+				continue
+			}
+			if err := sender.ReplaceTrack(target.Track); err != nil {
+				s.logger.Warnw("failed to move subscriber off stopped layer",
+					"peer_id", peerID,
+					"publisher", stopped.Publisher,
+					"layer", target.Layer,
+					"error", err,
+				)
+				continue
+			}
+			target.Mu.Lock()
+			target.Subscribers[peerID] = pc
+			target.Mu.Unlock()
+
+			go func(publisher domain.PeerID, key domain.TrackID) {
+				_ = s.requestKeyframe(publisher, key)
+			}(target.Publisher, target.TrackID)
+		}
+	}
+}
+
+// notifyLayersAvailable tells the stream which layers a publisher produces
+func (s *SFUService) notifyLayersAvailable(streamID domain.StreamID, publisherID domain.PeerID, layers []string) {
+	if s.layersNotifier == nil {
+		return
+	}
+	if err := s.layersNotifier.NotifyLayersAvailable(context.Background(), streamID, publisherID, layers); err != nil {
+		s.logger.Infow("failed to notify available layers", "publisher", publisherID, "stream_id", streamID, "error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// layersRecorder records layers_available notifications
+type layersRecorder struct {
+	mu     sync.Mutex
+	layers [][]string
+}
+
+func (r *layersRecorder) NotifyLayersAvailable(ctx context.Context, streamID domain.StreamID, publisherID domain.PeerID, layers []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.layers = append(r.layers, layers)
+	return nil
+}
+
+func TestSFU_LayersAvailableUpdatedWhenLayerForwarderStops(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+	recorder := &layersRecorder{}
+	sfu.SetLayersNotifier(recorder)
+
+	publisherID := domain.PeerID("layers-publisher")
+	streamID := domain.StreamID("layers-stream")
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "layers-video", "layers")
+	require.NoError(t, err)
+
+	forwarders := make(map[string]*TrackForwarder)
+	sfu.mu.Lock()
+	for _, layer := range []string{"high", "low", "medium"} {
+		fwd := &TrackForwarder{
+			TrackID:     forwarderKey("layers-video", layer),
+			Publisher:   publisherID,
+			StreamID:    streamID,
+			Track:       track,
+			Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+			Layer:       layer,
+		}
+		forwarders[layer] = fwd
+		sfu.trackForwarders[fwd.TrackID] = fwd
+	}
+	sfu.mu.Unlock()
+	require.Equal(t, []string{"low", "medium", "high"}, sfu.PublisherLayers(publisherID))
+
+	// The publisher drops its top layer, e.g. under CPU pressure
+	sfu.forwarderStopped(forwarders["high"])
+	require.Equal(t, []string{"low", "medium"}, sfu.PublisherLayers(publisherID))
+	require.Equal(t, 2, sfu.ActiveForwarders())
+
+	// A forwarder that already went with its publisher is not announced again
+	sfu.forwarderStopped(forwarders["high"])
+
+	sfu.forwarderStopped(forwarders["medium"])
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Equal(t, [][]string{{"low", "medium"}, {"low"}}, recorder.layers)
+}
@@ -0,0 +1,76 @@
+package webrtc
+
+import (
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// forwardingLoadSampleInterval bounds how often forwarding loops recount the
+// subscriber tracks; the count is shared by every loop in between
+const forwardingLoadSampleInterval = 250 * time.Millisecond
+
+var packetsShed = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rillnet_sfu_packets_shed_total",
+	Help: "Video RTP packets not forwarded because the SFU was under load",
+})
+
+var forwarderDroppedPackets = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rillnet_forwarder_dropped_packets_total",
+	Help: "RTP packets dropped by track prioritization under load, by stream and packet priority",
+}, []string{"stream_id", "priority"})
+
+// forwardingLoad returns the number of subscriber tracks being forwarded,
+// resampled at most once per forwardingLoadSampleInterval
+func (s *SFUService) forwardingLoad() float64 {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	if time.Since(s.loadSampledAt) < forwardingLoadSampleInterval {
+		return s.load
+	}
+
+	tracks := 0
+	s.mu.RLock()
+	for _, forwarder := range s.trackForwarders {
+		forwarder.Mu.RLock()
+		tracks += len(forwarder.Subscribers)
+		forwarder.Mu.RUnlock()
+	}
+	s.mu.RUnlock()
+
+	s.load = float64(tracks)
+	s.loadSampledAt = time.Now()
+	return s.load
+}
+
+// admitPacket runs a packet through the track prioritizer and reports whether
+// it should be forwarded at the current load. Audio and keyframes always are;
+// other packets of higher-priority streams are shed at a higher load.
+func (s *SFUService) admitPacket(trackID domain.TrackID, packet *rtp.Packet, streamPriority int) bool {
+	s.prioritizer.ProcessPacket(trackID, packet)
+	if s.config.MaxForwardedStreams <= 0 {
+		return true
+	}
+	return s.prioritizer.ShouldForward(trackID, s.forwardingLoad(), s.sheddingCapacity(streamPriority))
+}
+
+// admitForwarded is admitPacket for a forwarder's packet, counting the
+// packet by stream and priority when it is shed
+func (s *SFUService) admitForwarded(forwarder *TrackForwarder, packet *rtp.Packet) bool {
+	if s.admitPacket(forwarder.TrackID, packet, s.streamPriority(forwarder.StreamID)) {
+		return true
+	}
+	packetsShed.Inc()
+	forwarderDroppedPackets.WithLabelValues(string(forwarder.StreamID), s.prioritizer.GetPriority(forwarder.TrackID).String()).Inc()
+	return false
+}
+
+// forgetDroppedPackets removes the drop counters of an ended stream
+func forgetDroppedPackets(streamID domain.StreamID) {
+	forwarderDroppedPackets.DeletePartialMatch(prometheus.Labels{"stream_id": string(streamID)})
+}
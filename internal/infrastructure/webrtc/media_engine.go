@@ -0,0 +1,190 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/interceptor/pkg/twcc"
+	"github.com/pion/webrtc/v3"
+)
+
+// FeedbackPolicy selects the RTCP feedback advertised for a codec. NACK, PLI
+// and FIR only apply to video codecs; transport-cc applies to both kinds.
+type FeedbackPolicy struct {
+	NACK        bool
+	PLI         bool
+	FIR         bool
+	TransportCC bool
+}
+
+// DefaultFeedbackPolicy enables every feedback type; it applies to codecs
+// without an entry in WebRTCConfig.RTCPFeedback
+func DefaultFeedbackPolicy() FeedbackPolicy {
+	return FeedbackPolicy{NACK: true, PLI: true, FIR: true, TransportCC: true}
+}
+
+// transportCCURI is the RTP header extension carrying transport-wide sequence numbers
+const transportCCURI = "http://www.ietf.org/id/draft-holmer-rmcat-transport-wide-cc-extensions-01"
+
+// simulcastHeaderExtensions identify the media section and simulcast layer
+// (RID) of each received video packet
+var simulcastHeaderExtensions = []string{
+	"urn:ietf:params:rtp-hdrext:sdes:mid",
+	"urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id",
+	"urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id",
+}
+
+type sfuCodec struct {
+	kind   webrtc.RTPCodecType
+	params webrtc.RTPCodecParameters
+}
+
+func videoCodec(mimeType, fmtp string, payloadType webrtc.PayloadType) sfuCodec {
+	return sfuCodec{
+		kind: webrtc.RTPCodecTypeVideo,
+		params: webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeType, ClockRate: 90000, SDPFmtpLine: fmtp},
+			PayloadType:        payloadType,
+		},
+	}
+}
+
+func rtxCodec(apt, payloadType webrtc.PayloadType) sfuCodec {
+	return videoCodec("video/rtx", fmt.Sprintf("apt=%d", apt), payloadType)
+}
+
+func audioCodec(mimeType string, clockRate uint32, channels uint16, fmtp string, payloadType webrtc.PayloadType) sfuCodec {
+	return sfuCodec{
+		kind: webrtc.RTPCodecTypeAudio,
+		params: webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeType, ClockRate: clockRate, Channels: channels, SDPFmtpLine: fmtp},
+			PayloadType:        payloadType,
+		},
+	}
+}
+
+// sfuCodecs mirrors pion's default codecs (MediaEngine.RegisterDefaultCodecs),
+// which cannot have their feedback changed once registered
+var sfuCodecs = []sfuCodec{
+	audioCodec(webrtc.MimeTypeOpus, 48000, 2, "minptime=10;useinbandfec=1", 111),
+	audioCodec(webrtc.MimeTypeG722, 8000, 0, "", 9),
+	audioCodec(webrtc.MimeTypePCMU, 8000, 0, "", 0),
+	audioCodec(webrtc.MimeTypePCMA, 8000, 0, "", 8),
+
+	videoCodec(webrtc.MimeTypeVP8, "", 96), rtxCodec(96, 97),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f", 102), rtxCodec(102, 103),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42001f", 104), rtxCodec(104, 105),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f", 106), rtxCodec(106, 107),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42e01f", 108), rtxCodec(108, 109),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=4d001f", 127), rtxCodec(127, 125),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=4d001f", 39), rtxCodec(39, 40),
+	videoCodec(webrtc.MimeTypeAV1, "", 45), rtxCodec(45, 46),
+	videoCodec(webrtc.MimeTypeVP9, "profile-id=0", 98), rtxCodec(98, 99),
+	videoCodec(webrtc.MimeTypeVP9, "profile-id=2", 100), rtxCodec(100, 101),
+	videoCodec(webrtc.MimeTypeH264, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=64001f", 112), rtxCodec(112, 113),
+}
+
+// codecName returns the RTCPFeedback key of a MIME type ("video/VP8" -> "vp8")
+func codecName(mimeType string) string {
+	_, name, _ := strings.Cut(strings.ToLower(mimeType), "/")
+	return name
+}
+
+// rtcpFeedback builds a codec's feedback list; goog-remb is always advertised
+// on video as in pion's defaults
+func rtcpFeedback(kind webrtc.RTPCodecType, policy FeedbackPolicy) []webrtc.RTCPFeedback {
+	var feedback []webrtc.RTCPFeedback
+	if kind == webrtc.RTPCodecTypeVideo {
+		feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBGoogREMB})
+		if policy.FIR {
+			feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"})
+		}
+		if policy.NACK {
+			feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBNACK})
+		}
+		if policy.PLI {
+			feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"})
+		}
+	}
+	if policy.TransportCC {
+		feedback = append(feedback, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBTransportCC})
+	}
+	return feedback
+}
+
+// newMediaEngine registers the SFU codecs with the configured RTCP feedback
+// and the interceptors that act on it: NACK generation and retransmission,
+// RTCP reports, and transport-cc both for received streams (feedback to the
+// publisher) and sent ones (sequence numbers subscribers report against).
+func (s *SFUService) newMediaEngine() (*webrtc.MediaEngine, *interceptor.Registry, error) {
+	mediaEngine := &webrtc.MediaEngine{}
+	registry := &interceptor.Registry{}
+
+	anyNACK := false
+	transportCC := make(map[webrtc.RTPCodecType]bool)
+	for _, codec := range sfuCodecs {
+		params := codec.params
+		if name := codecName(params.MimeType); name != "rtx" {
+			policy, ok := s.config.RTCPFeedback[name]
+			if !ok {
+				policy = DefaultFeedbackPolicy()
+			}
+			params.RTCPFeedback = rtcpFeedback(codec.kind, policy)
+			anyNACK = anyNACK || (policy.NACK && codec.kind == webrtc.RTPCodecTypeVideo)
+			transportCC[codec.kind] = transportCC[codec.kind] || policy.TransportCC
+		}
+		if err := mediaEngine.RegisterCodec(params, codec.kind); err != nil {
+			return nil, nil, fmt.Errorf("register codec %s: %w", params.MimeType, err)
+		}
+	}
+
+	// Simulcast publishers tag each layer with its RID; without these header
+	// extensions the layers can't be told apart
+	for _, uri := range simulcastHeaderExtensions {
+		if err := mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: uri}, webrtc.RTPCodecTypeVideo); err != nil {
+			return nil, nil, fmt.Errorf("register simulcast header extension %s: %w", uri, err)
+		}
+	}
+
+	if anyNACK {
+		generator, err := nack.NewGeneratorInterceptor()
+		if err != nil {
+			return nil, nil, err
+		}
+		responder, err := nack.NewResponderInterceptor()
+		if err != nil {
+			return nil, nil, err
+		}
+		registry.Add(responder)
+		registry.Add(generator)
+	}
+
+	if err := webrtc.ConfigureRTCPReports(registry); err != nil {
+		return nil, nil, err
+	}
+
+	if transportCC[webrtc.RTPCodecTypeAudio] || transportCC[webrtc.RTPCodecTypeVideo] {
+		for kind, enabled := range transportCC {
+			if !enabled {
+				continue
+			}
+			if err := mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: transportCCURI}, kind); err != nil {
+				return nil, nil, err
+			}
+		}
+		sender, err := twcc.NewSenderInterceptor()
+		if err != nil {
+			return nil, nil, err
+		}
+		headerExtension, err := twcc.NewHeaderExtensionInterceptor()
+		if err != nil {
+			return nil, nil, err
+		}
+		registry.Add(sender)
+		registry.Add(headerExtension)
+	}
+
+	return mediaEngine, registry, nil
+}
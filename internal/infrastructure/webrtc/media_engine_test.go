@@ -0,0 +1,95 @@
+package webrtc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_OfferAdvertisesConfiguredTransportCC(t *testing.T) {
+	ctx := context.Background()
+
+	enabled := newTestSFU(WebRTCConfig{RTCPFeedback: map[string]FeedbackPolicy{
+		"vp8": {PLI: true, TransportCC: true},
+	}})
+	offer, err := enabled.CreatePublisherOffer(ctx, "twcc-on", "twcc-stream")
+	require.NoError(t, err)
+	require.Contains(t, offer.SDP, "a=rtcp-fb:96 transport-cc")
+	require.Contains(t, offer.SDP, "a=rtcp-fb:96 nack pli")
+	require.NotContains(t, offer.SDP, "a=rtcp-fb:96 ccm fir")
+	require.Contains(t, offer.SDP, "transport-wide-cc-extensions")
+
+	disabled := newTestSFU(WebRTCConfig{RTCPFeedback: map[string]FeedbackPolicy{
+		"vp8": {NACK: true, PLI: true, FIR: true},
+	}})
+	offer, err = disabled.CreatePublisherOffer(ctx, "twcc-off", "twcc-stream")
+	require.NoError(t, err)
+	require.NotContains(t, offer.SDP, "a=rtcp-fb:96 transport-cc")
+	require.Contains(t, offer.SDP, "a=rtcp-fb:96 ccm fir")
+	// Other codecs keep the defaults
+	require.Contains(t, offer.SDP, "a=rtcp-fb:98 transport-cc")
+}
+
+func TestSFU_AcceptsRIDSimulcastOffer(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+	offer := strings.Join([]string{
+		"v=0",
+		"o=- 4215775240449105457 2 IN IP4 127.0.0.1",
+		"s=-",
+		"t=0 0",
+		"a=group:BUNDLE 0",
+		"a=fingerprint:sha-256 " + strings.TrimSuffix(strings.Repeat("AB:", 32), ":"),
+		"m=video 9 UDP/TLS/RTP/SAVPF 96",
+		"c=IN IP4 0.0.0.0",
+		"a=ice-ufrag:simulcastufrag",
+		"a=ice-pwd:simulcastpasswordsimulcastpwd",
+		"a=setup:actpass",
+		"a=mid:0",
+		"a=sendonly",
+		"a=rtcp-mux",
+		"a=rtpmap:96 VP8/90000",
+		"a=extmap:1 urn:ietf:params:rtp-hdrext:sdes:mid",
+		"a=extmap:2 urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id",
+		"a=extmap:3 urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id",
+		"a=rid:low send",
+		"a=rid:medium send",
+		"a=rid:high send",
+		"a=simulcast:send low;medium;high",
+	}, "\r\n") + "\r\n"
+
+	answer, err := sfu.HandlePublisherClientOffer(context.Background(), "simulcast-publisher", "simulcast-stream",
+		webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sfu.Shutdown(context.Background()) })
+
+	// The RID extensions must be negotiated for the layers to be demultiplexed
+	require.Contains(t, answer.SDP, "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id")
+	require.Contains(t, answer.SDP, "urn:ietf:params:rtp-hdrext:sdes:mid")
+	require.Contains(t, answer.SDP, "a=simulcast:recv ")
+	for _, rid := range []string{"low", "medium", "high"} {
+		require.Contains(t, answer.SDP, "a=rid:"+rid+" recv")
+	}
+}
+
+func TestTransportCCLoss(t *testing.T) {
+	loss, ok := transportCCLoss(&rtcp.TransportLayerCC{
+		PacketStatusCount: 10,
+		PacketChunks: []rtcp.PacketStatusChunk{
+			&rtcp.RunLengthChunk{PacketStatusSymbol: rtcp.TypeTCCPacketReceivedSmallDelta, RunLength: 6},
+			&rtcp.StatusVectorChunk{
+				SymbolSize: rtcp.TypeTCCSymbolSizeOneBit,
+				// Two lost, then padding past the status count
+				SymbolList: []uint16{0, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+	})
+	require.True(t, ok)
+	require.InDelta(t, 0.2, loss, 0.001)
+
+	_, ok = transportCCLoss(&rtcp.TransportLayerCC{})
+	require.False(t, ok)
+}
@@ -0,0 +1,60 @@
+package webrtc
+
+import (
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+)
+
+// SetMetrics sets the backend peer sessions, forwarded bytes and RTCP latency
+// are recorded in. Must be called before peers connect.
+func (s *SFUService) SetMetrics(metrics ports.Metrics) {
+	s.metrics = metrics
+}
+
+// recordPeerConnected records a peer whose ICE connected for the first time,
+// with how long the connection took to set up
+func (s *SFUService) recordPeerConnected(peerID domain.PeerID) {
+	if s.metrics == nil {
+		return
+	}
+
+	now := time.Now()
+	type session struct {
+		streamID    domain.StreamID
+		isPublisher bool
+		setup       time.Duration
+	}
+	var connected []session
+
+	s.mu.Lock()
+	if publisher, ok := s.publishers[peerID]; ok && publisher.connectedAt.IsZero() {
+		publisher.connectedAt = now
+		connected = append(connected, session{publisher.StreamID, true, now.Sub(publisher.CreatedAt)})
+	}
+	if subscriber, ok := s.subscribers[peerID]; ok && subscriber.connectedAt.IsZero() {
+		subscriber.connectedAt = now
+		connected = append(connected, session{subscriber.StreamID, false, now.Sub(subscriber.CreatedAt)})
+	}
+	s.mu.Unlock()
+
+	for _, c := range connected {
+		s.metrics.RecordPeerConnected(c.streamID, c.isPublisher)
+		s.metrics.RecordWebRTCConnection(c.setup)
+	}
+}
+
+// recordPeerLeft records the end of a peer session that connected at
+// connectedAt. Sessions that never connected were not recorded as connected
+// and are skipped. Called with s.mu held.
+func (s *SFUService) recordPeerLeft(streamID domain.StreamID, isPublisher bool, connectedAt time.Time) {
+	if s.metrics == nil || connectedAt.IsZero() {
+		return
+	}
+
+	s.metrics.RecordPeerDisconnected(streamID, isPublisher)
+	if !isPublisher {
+		s.metrics.RecordViewerSession(streamID, time.Since(connectedAt))
+	}
+}
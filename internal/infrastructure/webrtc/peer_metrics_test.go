@@ -0,0 +1,108 @@
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/infrastructure/monitoring"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMetrics counts the peer sessions recorded through ports.Metrics
+type recordingMetrics struct {
+	monitoring.NopMetrics
+
+	mu             sync.Mutex
+	connected      map[bool]int // By isPublisher
+	disconnected   map[bool]int
+	viewerSessions int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		connected:    make(map[bool]int),
+		disconnected: make(map[bool]int),
+	}
+}
+
+func (m *recordingMetrics) RecordPeerConnected(_ domain.StreamID, isPublisher bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected[isPublisher]++
+}
+
+func (m *recordingMetrics) RecordPeerDisconnected(_ domain.StreamID, isPublisher bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disconnected[isPublisher]++
+}
+
+func (m *recordingMetrics) RecordViewerSession(domain.StreamID, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.viewerSessions++
+}
+
+func (m *recordingMetrics) counts() (connected, disconnected map[bool]int, viewerSessions int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	connected, disconnected = make(map[bool]int), make(map[bool]int)
+	for k, v := range m.connected {
+		connected[k] = v
+	}
+	for k, v := range m.disconnected {
+		disconnected[k] = v
+	}
+	return connected, disconnected, m.viewerSessions
+}
+
+func TestSFU_RecordsPeerSessionsInMetrics(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+	metrics := newRecordingMetrics()
+	sfu.SetMetrics(metrics)
+
+	streamID := domain.StreamID("metered-stream")
+	publisherID := domain.PeerID("metered-publisher")
+	publisherOffer, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+	_, publisherAnswer, _ := dataChannelClient(t, publisherOffer)
+	require.NoError(t, sfu.HandlePublisherAnswer(ctx, publisherID, publisherAnswer))
+
+	// Subscribers are only offered to streams with media
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "camera", "camera")
+	require.NoError(t, err)
+	sfu.mu.Lock()
+	sfu.trackForwarders["camera"] = &TrackForwarder{
+		TrackID:     "camera",
+		Publisher:   publisherID,
+		StreamID:    streamID,
+		Track:       track,
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+	}
+	sfu.mu.Unlock()
+
+	subscriberID := domain.PeerID("metered-viewer")
+	subscriberOffer, err := sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+	require.NoError(t, err)
+	_, subscriberAnswer, _ := dataChannelClient(t, subscriberOffer)
+	require.NoError(t, sfu.HandleSubscriberAnswer(ctx, subscriberID, subscriberAnswer))
+
+	require.Eventually(t, func() bool {
+		connected, _, _ := metrics.counts()
+		return connected[true] == 1 && connected[false] == 1
+	}, 10*time.Second, 10*time.Millisecond, "both peers should be recorded as connected")
+
+	sfu.handlePeerDisconnect(subscriberID)
+	sfu.handlePeerDisconnect(publisherID)
+
+	connected, disconnected, viewerSessions := metrics.counts()
+	require.Equal(t, map[bool]int{true: 1, false: 1}, connected, "ICE state changes must not record a peer twice")
+	require.Equal(t, map[bool]int{true: 1, false: 1}, disconnected)
+	require.Equal(t, 1, viewerSessions)
+}
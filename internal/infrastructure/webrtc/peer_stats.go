@@ -0,0 +1,134 @@
+package webrtc
+
+import (
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+// storePeerStats keeps the metrics of the latest RTCP batch for GetPeerStats
+func (s *SFUService) storePeerStats(peerID domain.PeerID, streamID domain.StreamID, metrics domain.NetworkMetrics) {
+	s.peerStatsMu.Lock()
+	defer s.peerStatsMu.Unlock()
+	s.peerStats[peerID] = domain.PeerRTCStats{
+		PeerID:           peerID,
+		StreamID:         streamID,
+		PacketLoss:       metrics.PacketLoss,
+		Jitter:           metrics.Jitter,
+		RoundTripTime:    metrics.Latency,
+		AvailableBitrate: s.peerStats[peerID].AvailableBitrate, // Only REMB updates the estimate
+		UpdatedAt:        metrics.Timestamp,
+	}
+}
+
+// recordTransportCC updates a subscriber's packet loss from its transport-cc
+// feedback, which covers every packet sent to it rather than one SSRC
+func (s *SFUService) recordTransportCC(peerID domain.PeerID, feedback *rtcp.TransportLayerCC) {
+	loss, ok := transportCCLoss(feedback)
+	if !ok {
+		return
+	}
+	streamID := s.peerStreamID(peerID)
+
+	s.peerStatsMu.Lock()
+	defer s.peerStatsMu.Unlock()
+	stats, exists := s.peerStats[peerID]
+	if !exists {
+		stats = domain.PeerRTCStats{PeerID: peerID, StreamID: streamID}
+	}
+	stats.PacketLoss = loss
+	stats.UpdatedAt = time.Now()
+	s.peerStats[peerID] = stats
+}
+
+// recordREMB keeps a subscriber's receiver-side bandwidth estimate
+func (s *SFUService) recordREMB(peerID domain.PeerID, remb *rtcp.ReceiverEstimatedMaximumBitrate) {
+	streamID := s.peerStreamID(peerID)
+
+	s.peerStatsMu.Lock()
+	defer s.peerStatsMu.Unlock()
+	stats, exists := s.peerStats[peerID]
+	if !exists {
+		stats = domain.PeerRTCStats{PeerID: peerID, StreamID: streamID}
+	}
+	stats.AvailableBitrate = int(remb.Bitrate / 1000)
+	stats.UpdatedAt = time.Now()
+	s.peerStats[peerID] = stats
+}
+
+// transportCCLoss returns the fraction of packets a transport-cc feedback
+// reports as not received; ok is false for an empty feedback
+func transportCCLoss(feedback *rtcp.TransportLayerCC) (float64, bool) {
+	total := int(feedback.PacketStatusCount)
+	if total == 0 {
+		return 0, false
+	}
+
+	// Status vectors are padded to a full chunk, so stop at the status count
+	seen, lost := 0, 0
+	count := func(symbol uint16, n int) {
+		n = min(n, total-seen)
+		seen += n
+		if symbol == rtcp.TypeTCCPacketNotReceived {
+			lost += n
+		}
+	}
+	for _, chunk := range feedback.PacketChunks {
+		switch c := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			count(c.PacketStatusSymbol, int(c.RunLength))
+		case *rtcp.StatusVectorChunk:
+			for _, symbol := range c.SymbolList {
+				count(symbol, 1)
+			}
+		}
+	}
+	return float64(lost) / float64(total), true
+}
+
+func (s *SFUService) clearPeerStats(peerID domain.PeerID) {
+	s.peerStatsMu.Lock()
+	defer s.peerStatsMu.Unlock()
+	delete(s.peerStats, peerID)
+}
+
+// GetPeerStats returns live stats for a connected publisher or subscriber: the
+// latest RTCP snapshot plus the byte counters of its ICE transport. A peer
+// that has not produced RTCP yet has a zero UpdatedAt.
+func (s *SFUService) GetPeerStats(peerID domain.PeerID) (*domain.PeerRTCStats, error) {
+	pc := s.peerConnection(peerID)
+	if pc == nil {
+		return nil, domain.ErrPeerNotFound
+	}
+
+	s.peerStatsMu.Lock()
+	stats, ok := s.peerStats[peerID]
+	s.peerStatsMu.Unlock()
+	if !ok {
+		stats = domain.PeerRTCStats{PeerID: peerID, StreamID: s.peerStreamID(peerID)}
+	}
+
+	for _, report := range pc.GetStats() {
+		if transport, ok := report.(webrtc.TransportStats); ok {
+			stats.BytesSent += transport.BytesSent
+			stats.BytesReceived += transport.BytesReceived
+		}
+	}
+	return &stats, nil
+}
+
+// peerStreamID returns the stream a connected peer publishes or subscribes to
+func (s *SFUService) peerStreamID(peerID domain.PeerID) domain.StreamID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if publisher, ok := s.publishers[peerID]; ok {
+		return publisher.StreamID
+	}
+	if subscriber, ok := s.subscribers[peerID]; ok {
+		return subscriber.StreamID
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+)
+
+// discardMeshService accepts the metrics the SFU derives from RTCP and the
+// removal of disconnected peers
+type discardMeshService struct {
+	ports.MeshService
+}
+
+func (discardMeshService) UpdatePeerMetrics(ctx context.Context, peerID domain.PeerID, metrics domain.NetworkMetrics) error {
+	return nil
+}
+
+func (discardMeshService) RemovePeer(ctx context.Context, peerID domain.PeerID) error {
+	return nil
+}
+
+func TestSFU_GetPeerStatsFromRTCP(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		discardMeshService{},
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	streamID := domain.StreamID("stats-stream")
+	peerID := domain.PeerID("stats-publisher")
+	_, err := sfu.CreatePublisherOffer(ctx, peerID, streamID)
+	require.NoError(t, err)
+
+	// Before any RTCP the peer is known but has no snapshot
+	stats, err := sfu.GetPeerStats(peerID)
+	require.NoError(t, err)
+	require.Equal(t, streamID, stats.StreamID)
+	require.True(t, stats.UpdatedAt.IsZero())
+
+	sfu.processRTCPPackets(peerID, streamID, []rtcp.Packet{
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{
+			FractionLost:     51, // 20%
+			Jitter:           20,
+			LastSenderReport: 1,
+			Delay:            65536 / 10, // 100ms, in 1/65536 s
+		}}},
+	}, true)
+
+	stats, err = sfu.GetPeerStats(peerID)
+	require.NoError(t, err)
+	require.Equal(t, peerID, stats.PeerID)
+	require.Equal(t, streamID, stats.StreamID)
+	require.InDelta(t, 0.2, stats.PacketLoss, 0.001)
+	require.Equal(t, 20*time.Millisecond, stats.Jitter)
+	require.InDelta(t, float64(100*time.Millisecond), float64(stats.RoundTripTime), float64(time.Millisecond))
+	require.False(t, stats.UpdatedAt.IsZero())
+	require.Zero(t, stats.AvailableBitrate, "no REMB has arrived yet")
+
+	// A REMB estimate survives later RTCP snapshots
+	sfu.handleSubscriberRTCP(peerID, "no-such-track", []rtcp.Packet{
+		&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1_500_000},
+	})
+	sfu.processRTCPPackets(peerID, streamID, []rtcp.Packet{
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{FractionLost: 51}}},
+	}, true)
+	stats, err = sfu.GetPeerStats(peerID)
+	require.NoError(t, err)
+	require.Equal(t, 1500, stats.AvailableBitrate)
+
+	sfu.handlePeerDisconnect(peerID)
+	_, err = sfu.GetPeerStats(peerID)
+	require.ErrorIs(t, err, domain.ErrPeerNotFound)
+
+	_, err = sfu.GetPeerStats("unknown-peer")
+	require.ErrorIs(t, err, domain.ErrPeerNotFound)
+}
@@ -0,0 +1,247 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/pkg/utils"
+	sdputil "rillnet/pkg/webrtc"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// defaultPreconnectTTL is used when WebRTCConfig.PreconnectTTL is unset
+const defaultPreconnectTTL = 30 * time.Second
+
+// defaultMaxPreconnectsPerUser is used when WebRTCConfig.MaxPreconnectsPerUser is unset
+const defaultMaxPreconnectsPerUser = 4
+
+// preconnect is a warm subscriber PeerConnection waiting to be bound to a stream.
+// Its transceivers carry placeholder tracks that are swapped for the stream's
+// tracks on bind, so no renegotiation is needed.
+type preconnect struct {
+	userID domain.UserID
+	peerID domain.PeerID
+	pc     *webrtc.PeerConnection
+	audio  *webrtc.RTPTransceiver
+	video  *webrtc.RTPTransceiver
+	data   *webrtc.DataChannel
+	expiry *time.Timer
+}
+
+// CreatePreconnect opens a subscriber PeerConnection with an audio and a video
+// slot and gathers ICE before any stream is chosen. Unbound preconnects are
+// closed after PreconnectTTL; a user holding MaxPreconnectsPerUser of them
+// gets ErrTooManyPreconnects.
+func (s *SFUService) CreatePreconnect(ctx context.Context, userID domain.UserID, peerID domain.PeerID) (*ports.Preconnect, error) {
+	if err := s.checkPreconnectQuota(userID); err != nil {
+		return nil, err
+	}
+
+	pc, err := s.createPeerConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	sendOnly := webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly}
+	audio, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, sendOnly)
+	if err != nil {
+		_ = pc.Close()
+		return nil, fmt.Errorf("add preconnect audio transceiver: %w", err)
+	}
+	video, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, sendOnly)
+	if err != nil {
+		_ = pc.Close()
+		return nil, fmt.Errorf("add preconnect video transceiver: %w", err)
+	}
+	data, err := s.createDataChannel(pc)
+	if err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+	// Connection state handlers are attached on bind: closing an expired
+	// preconnect must not tear down the peer's other sessions.
+	s.enableTrickle(pc, peerID)
+
+	offer, err := s.finishLocalOffer(pc)
+	if err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+
+	ttl := s.config.PreconnectTTL
+	if ttl <= 0 {
+		ttl = defaultPreconnectTTL
+	}
+	handle := utils.DefaultIDGenerator.NewID("preconnect")
+	entry := &preconnect{userID: userID, peerID: peerID, pc: pc, audio: audio, video: video, data: data}
+
+	s.preconnectsMu.Lock()
+	// Checked again now that gathering is done, so concurrent creates can't overshoot
+	if err := s.checkPreconnectQuotaLocked(userID); err != nil {
+		s.preconnectsMu.Unlock()
+		_ = pc.Close()
+		return nil, err
+	}
+	s.preconnects[handle] = entry
+	entry.expiry = time.AfterFunc(ttl, func() { s.expirePreconnect(handle, entry) })
+	s.preconnectsMu.Unlock()
+
+	return &ports.Preconnect{
+		Handle:    handle,
+		PeerID:    peerID,
+		Offer:     offer,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// checkPreconnectQuota fails once userID holds its maximum of unbound preconnects
+func (s *SFUService) checkPreconnectQuota(userID domain.UserID) error {
+	s.preconnectsMu.Lock()
+	defer s.preconnectsMu.Unlock()
+	return s.checkPreconnectQuotaLocked(userID)
+}
+
+// checkPreconnectQuotaLocked is checkPreconnectQuota with preconnectsMu held
+func (s *SFUService) checkPreconnectQuotaLocked(userID domain.UserID) error {
+	limit := s.config.MaxPreconnectsPerUser
+	if limit <= 0 {
+		limit = defaultMaxPreconnectsPerUser
+	}
+
+	open := 0
+	for _, entry := range s.preconnects {
+		if entry.userID == userID {
+			open++
+		}
+	}
+	if open >= limit {
+		return fmt.Errorf("%w: %d/%d", domain.ErrTooManyPreconnects, open, limit)
+	}
+	return nil
+}
+
+// BindPreconnect applies the client's answer to a preconnect and attaches the
+// stream's first audio and video tracks, making it the peer's subscriber
+// connection. The preconnect is kept for a retry when the stream has no media yet.
+func (s *SFUService) BindPreconnect(ctx context.Context, handle string, peerID domain.PeerID, streamID domain.StreamID, answer webrtc.SessionDescription) error {
+	if err := sdputil.ValidateMediaDirections(answer.SDP, sdputil.MediaRoleSubscriber); err != nil {
+		return err
+	}
+	if err := s.checkPeerStream(peerID, streamID); err != nil {
+		return err
+	}
+
+	quality := s.initialSubscriberQuality()
+	tracks, sourcePeers := s.collectSubscriberTracks(streamID, nil, quality)
+	if len(tracks) == 0 {
+		return fmt.Errorf("%w: start publishing on this stream first", domain.ErrNoPublisherMedia)
+	}
+
+	s.preconnectsMu.Lock()
+	entry, ok := s.preconnects[handle]
+	if !ok || entry.peerID != peerID {
+		s.preconnectsMu.Unlock()
+		return domain.ErrPreconnectNotFound
+	}
+	delete(s.preconnects, handle)
+	entry.expiry.Stop()
+	s.preconnectsMu.Unlock()
+
+	pc := entry.pc
+	if err := applyRemoteAnswer(pc, answer); err != nil {
+		_ = pc.Close()
+		return err
+	}
+
+	attached := make([]*webrtc.TrackLocalStaticRTP, 0, 2)
+	for _, track := range tracks {
+		transceiver := entry.video
+		if track.Kind() == webrtc.RTPCodecTypeAudio {
+			transceiver = entry.audio
+		}
+		if transceiver == nil {
+			continue
+		}
+		sender := transceiver.Sender()
+		if err := sender.ReplaceTrack(track); err != nil {
+			s.logger.Warnw("failed to attach track to preconnect",
+				"peer_id", peerID,
+				"track_id", track.ID(),
+				"error", err,
+			)
+			continue
+		}
+		attached = append(attached, track)
+		go s.processSubscriberRTCP(peerID, sender)
+		// One track per slot
+		if transceiver == entry.video {
+			entry.video = nil
+		} else {
+			entry.audio = nil
+		}
+	}
+	if len(attached) == 0 {
+		_ = pc.Close()
+		return fmt.Errorf("%w: no stream track matched the preconnect", domain.ErrNoPublisherMedia)
+	}
+
+	s.mu.Lock()
+	// Checked again with the insert, as the peer may have joined another
+	// stream while the answer was applied
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.mu.Unlock()
+		_ = pc.Close()
+		return err
+	}
+	if existing, ok := s.subscribers[peerID]; ok {
+		_ = existing.PC.Close()
+		delete(s.subscribers, peerID)
+		s.recordPeerLeft(existing.StreamID, false, existing.connectedAt)
+	}
+	for _, track := range attached {
+		if fwd := s.forwarderForTrack(track); fwd != nil {
+			fwd.Mu.Lock()
+			fwd.Subscribers[peerID] = pc
+			fwd.Mu.Unlock()
+		}
+	}
+	s.subscribers[peerID] = &Subscriber{
+		PeerID:      peerID,
+		StreamID:    streamID,
+		PC:          pc,
+		Quality:     quality,
+		SourcePeers: sourcePeers,
+		CreatedAt:   time.Now(),
+		DataChannel: entry.data,
+	}
+	s.mu.Unlock()
+
+	pc.OnICEConnectionStateChange(s.handleICEConnectionState(peerID))
+	pc.OnConnectionStateChange(s.handleConnectionState(peerID))
+	// A preconnect is usually connected before it is bound; otherwise the
+	// keyframe is requested by the handler above once it connects
+	if state := pc.ICEConnectionState(); state == webrtc.ICEConnectionStateConnected || state == webrtc.ICEConnectionStateCompleted {
+		s.requestJoinKeyframes(peerID, streamID, attached)
+	}
+
+	s.metricsService.IncrementSubscriberCount(streamID)
+	return nil
+}
+
+// expirePreconnect closes a preconnect that was not bound in time
+func (s *SFUService) expirePreconnect(handle string, entry *preconnect) {
+	s.preconnectsMu.Lock()
+	if s.preconnects[handle] != entry {
+		s.preconnectsMu.Unlock()
+		return
+	}
+	delete(s.preconnects, handle)
+	s.preconnectsMu.Unlock()
+
+	_ = entry.pc.Close()
+	s.logger.Infow("preconnect expired", "peer_id", entry.peerID, "handle", handle)
+}
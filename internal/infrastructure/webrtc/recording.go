@@ -0,0 +1,147 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	recordingindex "rillnet/internal/infrastructure/recording"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/h264writer"
+	"github.com/pion/webrtc/v3/pkg/media/ivfwriter"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	"go.uber.org/zap"
+)
+
+// trackRecording writes one publisher track to a file in its stream's
+// recording directory and indexes the file once the track ends
+type trackRecording struct {
+	rec     *domain.Recording
+	path    string
+	writer  media.Writer
+	packets int
+	index   ports.RecordingIndex
+	logger  *zap.SugaredLogger
+}
+
+// SetRecording records publisher tracks under dir (as <dir>/<stream_id>/<file>)
+// and adds each finished file to index. Must be called before peers connect.
+func (s *SFUService) SetRecording(dir string, index ports.RecordingIndex) {
+	s.recordingDir = dir
+	s.recordingIndex = index
+}
+
+// startRecording opens a recording for a publisher track, or returns nil when
+// recording is off, the codec has no file writer or the track is a lower
+// simulcast layer (only the highest layer is kept).
+func (s *SFUService) startRecording(streamID domain.StreamID, codec webrtc.RTPCodecParameters, layer string) *trackRecording {
+	if s.recordingIndex == nil || (layer != "" && layer != "high") {
+		return nil
+	}
+
+	recording, err := newTrackRecording(s.recordingDir, s.recordingIndex, streamID, codec, s.logger)
+	if err != nil {
+		s.logger.Warnw("failed to start recording", "stream_id", streamID, "codec", codec.MimeType, "error", err)
+		return nil
+	}
+	return recording
+}
+
+func newTrackRecording(dir string, index ports.RecordingIndex, streamID domain.StreamID, codec webrtc.RTPCodecParameters, logger *zap.SugaredLogger) (*trackRecording, error) {
+	streamDir, err := recordingindex.StreamDir(dir, streamID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(streamDir, 0o750); err != nil {
+		return nil, fmt.Errorf("create recording directory: %w", err)
+	}
+
+	startedAt := time.Now()
+	id := domain.RecordingID(fmt.Sprintf("rec-%d", startedAt.UnixNano()))
+
+	var (
+		ext  string
+		open func(path string) (media.Writer, error)
+	)
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		ext = ".ogg"
+		open = func(path string) (media.Writer, error) {
+			return oggwriter.New(path, codec.ClockRate, codec.Channels)
+		}
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		ext = ".ivf"
+		open = func(path string) (media.Writer, error) { return ivfwriter.New(path) }
+	case strings.ToLower(webrtc.MimeTypeH264):
+		ext = ".h264"
+		open = func(path string) (media.Writer, error) { return h264writer.New(path) }
+	default:
+		return nil, fmt.Errorf("no recording writer for codec %s", codec.MimeType)
+	}
+
+	fileName := string(id) + ext
+	path := filepath.Join(streamDir, fileName)
+	writer, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording file: %w", err)
+	}
+
+	return &trackRecording{
+		rec: &domain.Recording{
+			ID:        id,
+			StreamID:  streamID,
+			FileName:  fileName,
+			StartedAt: startedAt,
+		},
+		path:   path,
+		writer: writer,
+		index:  index,
+		logger: logger,
+	}, nil
+}
+
+// write appends a forwarded packet; a nil recording ignores it
+func (r *trackRecording) write(packet *rtp.Packet) {
+	if r == nil {
+		return
+	}
+	if err := r.writer.WriteRTP(packet); err != nil {
+		r.logger.Debugw("failed to write recorded packet", "recording_id", r.rec.ID, "error", err)
+		return
+	}
+	r.packets++
+}
+
+// finish closes the file and indexes it. Recordings without media are removed.
+func (r *trackRecording) finish() {
+	if r == nil {
+		return
+	}
+	if err := r.writer.Close(); err != nil {
+		r.logger.Warnw("failed to close recording", "recording_id", r.rec.ID, "error", err)
+	}
+	if r.packets == 0 {
+		_ = os.Remove(r.path)
+		return
+	}
+
+	r.rec.EndedAt = time.Now()
+	if err := r.index.Add(context.Background(), r.rec); err != nil {
+		r.logger.Warnw("failed to index recording", "recording_id", r.rec.ID, "stream_id", r.rec.StreamID, "error", err)
+		return
+	}
+	r.logger.Infow("recording finished",
+		"recording_id", r.rec.ID,
+		"stream_id", r.rec.StreamID,
+		"size_bytes", r.rec.SizeBytes,
+		"duration", r.rec.Duration(),
+	)
+}
@@ -0,0 +1,73 @@
+package webrtc
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/infrastructure/recording"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTrackRecording_IndexedWhenFinished(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	index := recording.NewFileIndex(dir)
+	streamID := domain.StreamID("recorded-stream")
+	opus := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+		PayloadType:        111,
+	}
+
+	rec, err := newTrackRecording(dir, index, streamID, opus, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		rec.write(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 111, SequenceNumber: uint16(i), Timestamp: uint32(i * 960), SSRC: 1},
+			Payload: []byte{0xfc, 0xff, 0xfe},
+		})
+	}
+
+	recordings, err := index.ListByStream(ctx, streamID)
+	require.NoError(t, err)
+	require.Empty(t, recordings, "recordings are indexed only once finished")
+
+	rec.finish()
+
+	recordings, err = index.ListByStream(ctx, streamID)
+	require.NoError(t, err)
+	require.Len(t, recordings, 1)
+	require.Greater(t, recordings[0].SizeBytes, int64(0))
+
+	_, file, err := index.Open(ctx, streamID, recordings[0].ID)
+	require.NoError(t, err)
+	defer file.Close()
+	header := make([]byte, 4)
+	_, err = io.ReadFull(file, header)
+	require.NoError(t, err)
+	require.Equal(t, "OggS", string(header))
+}
+
+func TestTrackRecording_EmptyRecordingIsDropped(t *testing.T) {
+	dir := t.TempDir()
+	index := recording.NewFileIndex(dir)
+	streamID := domain.StreamID("silent-stream")
+	opus := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+	}
+
+	rec, err := newTrackRecording(dir, index, streamID, opus, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	rec.finish()
+
+	recordings, err := index.ListByStream(context.Background(), streamID)
+	require.NoError(t, err)
+	require.Empty(t, recordings)
+	require.NoFileExists(t, rec.path)
+}
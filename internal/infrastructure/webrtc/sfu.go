@@ -11,9 +11,10 @@ import (
 	"rillnet/internal/core/services"
 	"rillnet/pkg/circuitbreaker"
 	"rillnet/pkg/retry"
+	"rillnet/pkg/ratelimit"
 	rlog "rillnet/pkg/logger"
+	sdputil "rillnet/pkg/webrtc"
 
-	"github.com/pion/interceptor"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
@@ -30,8 +31,44 @@ type WebRTCConfig struct {
 	NAT1To1IPs []string
 	Simulcast  bool
 	MaxBitrate int
+	// KeyRotationInterval renegotiates every peer on this period; 0 disables
+	KeyRotationInterval time.Duration
+	// RenegotiationTimeout is how long a renegotiation offer waits for the
+	// client's answer before it is rolled back (0 = defaultRenegotiationTimeout)
+	RenegotiationTimeout time.Duration
+	// TrickleICE pushes candidates through the ICE candidate sink instead of
+	// waiting for gathering; ignored until a sink is set
+	TrickleICE bool
+	// MaxPendingCandidates bounds the client candidates buffered per peer
+	// until its remote description is set (0 = defaultMaxPendingCandidates)
+	MaxPendingCandidates int
+	// MaxICECandidatesPerMinute limits the client candidates accepted per
+	// peer; excess candidates are rejected with ErrTooManyCandidates (0 = unlimited)
+	MaxICECandidatesPerMinute int
+	// PreconnectTTL is how long an unbound preconnect is kept (0 = defaultPreconnectTTL)
+	PreconnectTTL time.Duration
+	// MaxPreconnectsPerUser caps the unbound preconnects one user may hold
+	// (0 = defaultMaxPreconnectsPerUser)
+	MaxPreconnectsPerUser int
+	// Eviction sets the grace period of each eviction cause
+	Eviction EvictionPolicy
+	// MaxForwardedStreams is the subscriber track count treated as full
+	// load by the track prioritizer; 0 disables shedding
+	MaxForwardedStreams int
+	// RTCPFeedback overrides the negotiated RTCP feedback per lower-case
+	// codec name ("vp8", "opus"); unlisted codecs use DefaultFeedbackPolicy
+	RTCPFeedback map[string]FeedbackPolicy
+	// MaxDataChannelMessageSize caps the publisher data channel messages
+	// relayed to subscribers, in bytes (0 = defaultMaxDataChannelMessageSize)
+	MaxDataChannelMessageSize int
 }
 
+// defaultMaxPendingCandidates is used when WebRTCConfig.MaxPendingCandidates is unset
+const defaultMaxPendingCandidates = 64
+
+// defaultRenegotiationTimeout is used when WebRTCConfig.RenegotiationTimeout is unset
+const defaultRenegotiationTimeout = 30 * time.Second
+
 // SFUService SFU implementation
 type SFUService struct {
 	config         WebRTCConfig
@@ -44,7 +81,65 @@ type SFUService struct {
 	trackForwarders map[domain.TrackID]*TrackForwarder
 	mu              sync.RWMutex
 
+	// Streams whose subscribers join without a keyframe request, guarded by mu
+	noKeyframeOnJoin map[domain.StreamID]bool
+
+	// Receives gathered candidates when trickle ICE is enabled
+	candidateSink ports.ICECandidateSink
+	// Told about peers dropped by the SFU, optional
+	peerLeftNotifier ports.PeerLeftNotifier
+	// Told when a publisher's set of simulcast layers changes, optional
+	layersNotifier ports.LayersNotifier
+	// Adapts the quality of connected subscribers, optional
+	subscriberMonitor ports.SubscriberMonitor
+	// Records peer sessions and forwarded bytes, optional
+	metrics ports.Metrics
+	// Publisher tracks are recorded under recordingDir when an index is set
+	recordingDir   string
+	recordingIndex ports.RecordingIndex
+
+	// Client candidates that arrived before the remote description was set
+	pendingCandidates   map[domain.PeerID]*candidateQueue
+	pendingCandidatesMu sync.Mutex
+	// Client candidates per peer per minute, nil when unlimited
+	candidateLimiter *ratelimit.KeyedLimiter[domain.PeerID]
+
+	// Key rotation offers waiting for the client's answer
+	pendingOffers   map[domain.PeerID]webrtc.SessionDescription
+	pendingOffersMu sync.Mutex
+	// Peers whose renegotiation offer is being prepared, guarded by pendingOffersMu
+	negotiating map[domain.PeerID]bool
+	// Video tracks to request a keyframe for once the pending offer is
+	// answered, guarded by pendingOffersMu
+	answerKeyframes map[domain.PeerID][]domain.TrackID
+
+	// Warm subscriber connections by handle, not yet bound to a stream
+	preconnects   map[string]*preconnect
+	preconnectsMu sync.Mutex
+
+	// Grace timers of pending evictions
+	eviction *evictionTracker
+
+	// Latest RTCP-derived stats per peer
+	peerStats   map[domain.PeerID]domain.PeerRTCStats
+	peerStatsMu sync.Mutex
+
+	// Decides which packets are shed under load
+	prioritizer *TrackPrioritizer
+	// Non-default stream priorities, see SetStreamPriority
+	streamPriorities map[domain.StreamID]int
+	prioritiesMu     sync.RWMutex
+	// Temporal layer limits of streams' SVC video, see SetSVCTemporalLayer
+	svcTemporalLayers map[domain.StreamID]int
+	svcLayersMu       sync.RWMutex
+	// Sampled subscriber track count, see forwardingLoad
+	load          float64
+	loadSampledAt time.Time
+	loadMu        sync.Mutex
+
 	logger *zap.SugaredLogger
+	// Collapses per-packet error warnings from forwarding and RTCP loops
+	errLogger *rlog.RateLimitedLogger
 
 	// Reliability features
 	retryConfig     retry.Config
@@ -61,7 +156,13 @@ type Publisher struct {
 	Tracks      map[domain.TrackID]*webrtc.TrackLocalStaticRTP
 	AudioTrack  *webrtc.TrackLocalStaticRTP
 	VideoTracks map[string]*webrtc.TrackLocalStaticRTP
+	Paused      bool // Forwarding suspended; PeerConnection stays up
 	CreatedAt   time.Time
+	// When ICE first connected, zero before. Guarded by SFUService.mu.
+	connectedAt time.Time
+	// Relayed to the stream's subscribers; nil until the browser opens it
+	// when it made the offer. Guarded by SFUService.mu.
+	DataChannel *webrtc.DataChannel
 }
 
 // Subscriber represents a stream subscriber
@@ -72,6 +173,14 @@ type Subscriber struct {
 	Quality     string
 	SourcePeers []domain.PeerID
 	CreatedAt   time.Time
+	DataChannel *webrtc.DataChannel // Receives the publishers' data channel messages
+
+	// Video senders paused by SetSubscriberInterest by track ID, guarded by SFUService.mu
+	pausedSenders map[string]pausedSender
+	// Whether quality monitoring was started, guarded by SFUService.mu
+	monitored bool
+	// When ICE first connected, zero before. Guarded by SFUService.mu.
+	connectedAt time.Time
 }
 
 // TrackForwarder manages track forwarding
@@ -81,9 +190,20 @@ type TrackForwarder struct {
 	StreamID    domain.StreamID
 	Track       *webrtc.TrackLocalStaticRTP
 	Subscribers map[domain.PeerID]*webrtc.PeerConnection
+	Paused      bool        // Guarded by Mu; inbound packets are dropped while set
+	SSRC        webrtc.SSRC // Publisher's inbound SSRC, target of keyframe requests
+	Layer       string      // Simulcast layer, empty when the track is not simulcast
 	Mu          sync.RWMutex
+
+	recording *trackRecording // Owned by the forwarding goroutine, nil when not recorded
+	svc       LayerSelector   // Owned by the forwarding goroutine, nil when the codec is not SVC
+
+	lastKeyframeRequest time.Time // Guarded by Mu
 }
 
+// errorLogWindow is how often a repeating forwarding/RTCP error is logged
+const errorLogWindow = 10 * time.Second
+
 // NewSFUService creates a new SFU service
 func NewSFUService(
 	config WebRTCConfig,
@@ -94,18 +214,31 @@ func NewSFUService(
 	cbConfig circuitbreaker.Config,
 ) ports.WebRTCService {
 	sfu := &SFUService{
-		config:          config,
-		qualityService:  qualityService,
-		metricsService:  metricsService,
-		meshService:     meshService,
-		publishers:      make(map[domain.PeerID]*Publisher),
-		subscribers:     make(map[domain.PeerID]*Subscriber),
-		trackForwarders: make(map[domain.TrackID]*TrackForwarder),
-		logger:          rlog.New("info").Sugar(),
-		retryConfig:     retryConfig,
-		circuitBreaker:  circuitbreaker.New(cbConfig),
-		peerBreakers:    make(map[domain.PeerID]*circuitbreaker.CircuitBreaker),
-	}
+		config:            config,
+		qualityService:    qualityService,
+		metricsService:    metricsService,
+		meshService:       meshService,
+		publishers:        make(map[domain.PeerID]*Publisher),
+		subscribers:       make(map[domain.PeerID]*Subscriber),
+		trackForwarders:   make(map[domain.TrackID]*TrackForwarder),
+		noKeyframeOnJoin:  make(map[domain.StreamID]bool),
+		pendingOffers:     make(map[domain.PeerID]webrtc.SessionDescription),
+		negotiating:       make(map[domain.PeerID]bool),
+		answerKeyframes:   make(map[domain.PeerID][]domain.TrackID),
+		pendingCandidates: make(map[domain.PeerID]*candidateQueue),
+		preconnects:       make(map[string]*preconnect),
+		peerStats:         make(map[domain.PeerID]domain.PeerRTCStats),
+		candidateLimiter:  ratelimit.NewKeyedLimiter[domain.PeerID](config.MaxICECandidatesPerMinute),
+		prioritizer:       NewTrackPrioritizer(),
+		streamPriorities:  make(map[domain.StreamID]int),
+		svcTemporalLayers: make(map[domain.StreamID]int),
+		logger:            rlog.New("info").Sugar(),
+		retryConfig:       retryConfig,
+		circuitBreaker:    circuitbreaker.New(cbConfig),
+		peerBreakers:      make(map[domain.PeerID]*circuitbreaker.CircuitBreaker),
+	}
+	sfu.errLogger = rlog.NewRateLimitedLogger(sfu.logger, errorLogWindow)
+	sfu.eviction = newEvictionTracker(config.Eviction, sfu.evictPeer)
 
 	// Set up state change callback
 	sfu.circuitBreaker.OnStateChange(func(from, to circuitbreaker.State) {
@@ -118,6 +251,86 @@ func NewSFUService(
 	return sfu
 }
 
+// UpdateICEServers replaces the ICE servers given to peer connections created
+// from now on, e.g. to rotate time-limited TURN credentials. Existing
+// connections keep the servers they were created with.
+func (s *SFUService) UpdateICEServers(servers []webrtc.ICEServer) {
+	updated := append([]webrtc.ICEServer(nil), servers...)
+
+	s.mu.Lock()
+	s.config.ICEServers = updated
+	s.mu.Unlock()
+
+	s.logger.Infow("ICE servers updated", "count", len(updated))
+}
+
+// SetICECandidateSink sets where gathered candidates are pushed when
+// TrickleICE is enabled. Must be called before peers connect.
+func (s *SFUService) SetICECandidateSink(sink ports.ICECandidateSink) {
+	s.candidateSink = sink
+}
+
+// SetPeerLeftNotifier sets who is told when the SFU drops a peer, so
+// signaling stays in step with it. Must be called before peers connect.
+func (s *SFUService) SetPeerLeftNotifier(notifier ports.PeerLeftNotifier) {
+	s.peerLeftNotifier = notifier
+}
+
+// SetSubscriberMonitor sets who adapts subscriber quality: monitoring starts
+// once a subscriber's ICE connects and stops when it leaves. Must be called
+// before peers connect.
+func (s *SFUService) SetSubscriberMonitor(monitor ports.SubscriberMonitor) {
+	s.subscriberMonitor = monitor
+}
+
+// startSubscriberMonitoring hands a newly connected subscriber to the
+// subscriber monitor, once per subscriber
+func (s *SFUService) startSubscriberMonitoring(peerID domain.PeerID) {
+	if s.subscriberMonitor == nil {
+		return
+	}
+
+	s.mu.Lock()
+	subscriber, ok := s.subscribers[peerID]
+	if !ok || subscriber.monitored {
+		s.mu.Unlock()
+		return
+	}
+	subscriber.monitored = true
+	quality := subscriber.Quality
+	s.mu.Unlock()
+
+	s.subscriberMonitor.StartMonitoring(context.Background(), peerID, quality)
+}
+
+// trickling reports whether local descriptions are returned before ICE
+// gathering completes, with candidates pushed to the sink instead.
+func (s *SFUService) trickling() bool {
+	return s.config.TrickleICE && s.candidateSink != nil
+}
+
+// enableTrickle forwards the PeerConnection's gathered candidates, and the
+// end-of-candidates marker, to the peer through the candidate sink.
+func (s *SFUService) enableTrickle(pc *webrtc.PeerConnection, peerID domain.PeerID) {
+	if !s.trickling() {
+		return
+	}
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		var init *webrtc.ICECandidateInit
+		if candidate != nil {
+			c := candidate.ToJSON()
+			init = &c
+		}
+		if err := s.candidateSink.SendICECandidate(peerID, init); err != nil {
+			s.logger.Warnw("failed to push ICE candidate",
+				"peer_id", peerID,
+				"end_of_candidates", candidate == nil,
+				"error", err,
+			)
+		}
+	})
+}
+
 // getPeerCircuitBreaker gets or creates a circuit breaker for a specific peer
 func (s *SFUService) getPeerCircuitBreaker(peerID domain.PeerID) *circuitbreaker.CircuitBreaker {
 	s.peerBreakersMu.RLock()
@@ -150,8 +363,47 @@ func (s *SFUService) getPeerCircuitBreaker(peerID domain.PeerID) *circuitbreaker
 	return cb
 }
 
+// checkPeerStream rejects a session on streamID for a peer that already
+// publishes or subscribes on another stream; forwarder and stats cleanup
+// assume one stream per peer. It fails fast before a session is built; the
+// session is registered only after checkPeerStreamLocked passes under the
+// same lock as the insert.
+func (s *SFUService) checkPeerStream(peerID domain.PeerID, streamID domain.StreamID) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkPeerStreamLocked(peerID, streamID)
+}
+
+// checkPeerStreamLocked is checkPeerStream for callers holding s.mu
+func (s *SFUService) checkPeerStreamLocked(peerID domain.PeerID, streamID domain.StreamID) error {
+	if publisher, ok := s.publishers[peerID]; ok && publisher.StreamID != streamID {
+		return fmt.Errorf("%w: %s", domain.ErrPeerInOtherStream, publisher.StreamID)
+	}
+	if subscriber, ok := s.subscribers[peerID]; ok && subscriber.StreamID != streamID {
+		return fmt.Errorf("%w: %s", domain.ErrPeerInOtherStream, subscriber.StreamID)
+	}
+	return nil
+}
+
+// detachSubscriberPCLocked removes pc from the forwarders it was attached to
+// for peerID, for a subscriber connection that is dropped before it was
+// registered. The caller holds s.mu.
+func (s *SFUService) detachSubscriberPCLocked(peerID domain.PeerID, pc *webrtc.PeerConnection) {
+	for _, forwarder := range s.trackForwarders {
+		forwarder.Mu.Lock()
+		if forwarder.Subscribers[peerID] == pc {
+			delete(forwarder.Subscribers, peerID)
+		}
+		forwarder.Mu.Unlock()
+	}
+}
+
 // CreatePublisherOffer creates an offer for publisher
 func (s *SFUService) CreatePublisherOffer(ctx context.Context, peerID domain.PeerID, streamID domain.StreamID) (webrtc.SessionDescription, error) {
+	if err := s.checkPeerStream(peerID, streamID); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+
 	if s.retryConfig.Enabled {
 		result, err := retry.RetryWithResult(ctx, s.retryConfig, func() (webrtc.SessionDescription, error) {
 			res, err := s.circuitBreaker.ExecuteWithResult(ctx, func() (interface{}, error) {
@@ -176,10 +428,15 @@ func (s *SFUService) createPublisherOfferInternal(ctx context.Context, peerID do
 	var oldPC *webrtc.PeerConnection
 	var oldStreamID domain.StreamID
 	s.mu.Lock()
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.mu.Unlock()
+		return webrtc.SessionDescription{}, err
+	}
 	if existing, ok := s.publishers[peerID]; ok {
 		oldPC = existing.PC
 		oldStreamID = existing.StreamID
 		delete(s.publishers, peerID)
+		s.recordPeerLeft(oldStreamID, true, existing.connectedAt)
 	}
 	s.mu.Unlock()
 	if oldPC != nil {
@@ -235,11 +492,18 @@ func (s *SFUService) createPublisherOfferInternal(ctx context.Context, peerID do
 			return webrtc.SessionDescription{}, err
 		}
 	}
+	dataChannel, err := s.createDataChannel(pc)
+	if err != nil {
+		_ = pc.Close()
+		return webrtc.SessionDescription{}, err
+	}
+	dataChannel.OnMessage(s.handleDataChannelMessage(peerID, streamID))
 
 	// Handle incoming data
 	pc.OnTrack(s.handlePublisherTrack(peerID, streamID))
 	pc.OnICEConnectionStateChange(s.handleICEConnectionState(peerID))
 	pc.OnConnectionStateChange(s.handleConnectionState(peerID))
+	s.enableTrickle(pc, peerID)
 
 	publisher := &Publisher{
 		PeerID:      peerID,
@@ -249,18 +513,32 @@ func (s *SFUService) createPublisherOfferInternal(ctx context.Context, peerID do
 		VideoTracks: videoTracks,
 		Tracks:      make(map[domain.TrackID]*webrtc.TrackLocalStaticRTP),
 		CreatedAt:   time.Now(),
+		DataChannel: dataChannel,
 	}
 
 	s.mu.Lock()
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.mu.Unlock()
+		_ = pc.Close()
+		return webrtc.SessionDescription{}, err
+	}
 	s.publishers[peerID] = publisher
 	s.mu.Unlock()
 
 	s.metricsService.IncrementPublisherCount(streamID)
+	s.eviction.start(peerID, EvictionAnswerTimeout)
 	return s.finishLocalOffer(pc)
 }
 
 // HandlePublisherClientOffer lets the browser send the SDP offer (recommended behind Docker/NAT).
 func (s *SFUService) HandlePublisherClientOffer(ctx context.Context, peerID domain.PeerID, streamID domain.StreamID, offer webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	if err := sdputil.ValidateMediaDirections(offer.SDP, sdputil.MediaRolePublisher); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	if err := s.checkPeerStream(peerID, streamID); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+
 	if s.retryConfig.Enabled {
 		result, err := retry.RetryWithResult(ctx, s.retryConfig, func() (webrtc.SessionDescription, error) {
 			res, err := s.circuitBreaker.ExecuteWithResult(ctx, func() (interface{}, error) {
@@ -284,10 +562,15 @@ func (s *SFUService) handlePublisherClientOfferInternal(ctx context.Context, pee
 	var oldPC *webrtc.PeerConnection
 	var oldStreamID domain.StreamID
 	s.mu.Lock()
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.mu.Unlock()
+		return webrtc.SessionDescription{}, err
+	}
 	if existing, ok := s.publishers[peerID]; ok {
 		oldPC = existing.PC
 		oldStreamID = existing.StreamID
 		delete(s.publishers, peerID)
+		s.recordPeerLeft(oldStreamID, true, existing.connectedAt)
 	}
 	s.mu.Unlock()
 	if oldPC != nil {
@@ -301,8 +584,10 @@ func (s *SFUService) handlePublisherClientOfferInternal(ctx context.Context, pee
 	}
 
 	pc.OnTrack(s.handlePublisherTrack(peerID, streamID))
+	s.acceptPublisherDataChannel(pc, peerID, streamID)
 	pc.OnICEConnectionStateChange(s.handleICEConnectionState(peerID))
 	pc.OnConnectionStateChange(s.handleConnectionState(peerID))
+	s.enableTrickle(pc, peerID)
 
 	publisher := &Publisher{
 		PeerID:      peerID,
@@ -319,6 +604,11 @@ func (s *SFUService) handlePublisherClientOfferInternal(ctx context.Context, pee
 	}
 
 	s.mu.Lock()
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.mu.Unlock()
+		_ = pc.Close()
+		return webrtc.SessionDescription{}, err
+	}
 	s.publishers[peerID] = publisher
 	s.mu.Unlock()
 
@@ -349,11 +639,21 @@ func (s *SFUService) HandlePublisherAnswer(ctx context.Context, peerID domain.Pe
 		return domain.ErrPeerNotFound
 	}
 
-	return applyRemoteAnswer(publisher.PC, answer)
+	if err := applyRemoteAnswer(publisher.PC, answer); err != nil {
+		return err
+	}
+	s.eviction.cancel(peerID, EvictionAnswerTimeout)
+	s.clearPendingOffer(peerID)
+	s.flushPendingCandidates(peerID, publisher.PC)
+	return nil
 }
 
 // CreateSubscriberOffer creates an offer for subscriber
 func (s *SFUService) CreateSubscriberOffer(ctx context.Context, peerID domain.PeerID, streamID domain.StreamID, sourcePeers []domain.PeerID) (webrtc.SessionDescription, error) {
+	if err := s.checkPeerStream(peerID, streamID); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+
 	if s.retryConfig.Enabled {
 		result, err := retry.RetryWithResult(ctx, s.retryConfig, func() (webrtc.SessionDescription, error) {
 			// Use per-peer circuit breaker for subscriber connections
@@ -372,8 +672,9 @@ func (s *SFUService) CreateSubscriberOffer(ctx context.Context, peerID domain.Pe
 	return s.createSubscriberOfferInternal(ctx, peerID, streamID, sourcePeers)
 }
 
-// collectSubscriberTracks resolves source peers and gathers tracks for a subscriber offer.
-func (s *SFUService) collectSubscriberTracks(streamID domain.StreamID, sourcePeers []domain.PeerID) ([]*webrtc.TrackLocalStaticRTP, []domain.PeerID) {
+// collectSubscriberTracks resolves source peers and gathers tracks for a
+// subscriber offer, taking the simulcast layer nearest to quality.
+func (s *SFUService) collectSubscriberTracks(streamID domain.StreamID, sourcePeers []domain.PeerID, quality string) ([]*webrtc.TrackLocalStaticRTP, []domain.PeerID) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -411,14 +712,18 @@ func (s *SFUService) collectSubscriberTracks(streamID domain.StreamID, sourcePee
 		return false
 	}
 
+	var forwarders []*TrackForwarder
 	for _, forwarder := range s.trackForwarders {
 		if forwarder.StreamID != streamID || forwarder.Track == nil {
 			continue
 		}
 		if matchPublisher(forwarder.Publisher) {
-			addTrack(forwarder.Track)
+			forwarders = append(forwarders, forwarder)
 		}
 	}
+	for _, forwarder := range selectLayers(forwarders, quality) {
+		addTrack(forwarder.Track)
+	}
 
 	if len(tracks) == 0 {
 		for _, src := range resolved {
@@ -438,19 +743,25 @@ func (s *SFUService) collectSubscriberTracks(streamID domain.StreamID, sourcePee
 
 // createSubscriberOfferInternal is the internal implementation without retry/circuit breaker
 func (s *SFUService) createSubscriberOfferInternal(ctx context.Context, peerID domain.PeerID, streamID domain.StreamID, sourcePeers []domain.PeerID) (webrtc.SessionDescription, error) {
-	tracks, sourcePeers := s.collectSubscriberTracks(streamID, sourcePeers)
+	quality := s.initialSubscriberQuality()
+	tracks, sourcePeers := s.collectSubscriberTracks(streamID, sourcePeers, quality)
 	// Stale owner/source_peers from the API must not hide an active SFU publisher on this stream.
 	if len(tracks) == 0 && len(sourcePeers) > 0 {
-		tracks, sourcePeers = s.collectSubscriberTracks(streamID, nil)
+		tracks, sourcePeers = s.collectSubscriberTracks(streamID, nil, quality)
 	}
 	if len(tracks) == 0 {
 		return webrtc.SessionDescription{}, fmt.Errorf("%w: start publishing on this stream first", domain.ErrNoPublisherMedia)
 	}
 
 	s.mu.Lock()
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.mu.Unlock()
+		return webrtc.SessionDescription{}, err
+	}
 	if existing, ok := s.subscribers[peerID]; ok {
 		_ = existing.PC.Close()
 		delete(s.subscribers, peerID)
+		s.recordPeerLeft(existing.StreamID, false, existing.connectedAt)
 	}
 	s.mu.Unlock()
 
@@ -460,7 +771,8 @@ func (s *SFUService) createSubscriberOfferInternal(ctx context.Context, peerID d
 	}
 
 	for _, track := range tracks {
-		if _, err := pc.AddTrack(track); err != nil {
+		sender, err := pc.AddTrack(track)
+		if err != nil {
 			s.logger.Warnw("failed to add track to subscriber",
 				"peer_id", peerID,
 				"track_id", track.ID(),
@@ -468,45 +780,47 @@ func (s *SFUService) createSubscriberOfferInternal(ctx context.Context, peerID d
 			)
 			continue
 		}
+		go s.processSubscriberRTCP(peerID, sender)
 
 		s.mu.RLock()
-		if fwd, exists := s.trackForwarders[domain.TrackID(track.ID())]; exists {
+		if fwd := s.forwarderForTrack(track); fwd != nil {
 			fwd.Mu.Lock()
 			fwd.Subscribers[peerID] = pc
 			fwd.Mu.Unlock()
 		}
 		s.mu.RUnlock()
 	}
+	dataChannel, err := s.createDataChannel(pc)
+	if err != nil {
+		s.mu.Lock()
+		s.detachSubscriberPCLocked(peerID, pc)
+		s.mu.Unlock()
+		_ = pc.Close()
+		return webrtc.SessionDescription{}, err
+	}
 
 	// Setup handlers
 	pc.OnICEConnectionStateChange(s.handleICEConnectionState(peerID))
 	pc.OnConnectionStateChange(s.handleConnectionState(peerID))
-
-	// Determine initial quality based on network conditions
-	initialQuality := "medium" // Default quality
-	if s.qualityService != nil {
-		// Get initial metrics (would come from RTCP in real implementation)
-		initialMetrics := domain.NetworkMetrics{
-			BandwidthDown:    1000,
-			BandwidthUp:      500,
-			PacketLoss:       0.02,
-			Latency:          150 * time.Millisecond,
-			Jitter:           40 * time.Millisecond,
-			AvailableBitrate: 800,
-		}
-		initialQuality = s.qualityService.DetermineOptimalQuality(initialMetrics)
-	}
+	s.enableTrickle(pc, peerID)
 
 	subscriber := &Subscriber{
 		PeerID:      peerID,
 		StreamID:    streamID,
 		PC:          pc,
-		Quality:     initialQuality,
+		Quality:     quality,
 		SourcePeers: sourcePeers,
 		CreatedAt:   time.Now(),
+		DataChannel: dataChannel,
 	}
 
 	s.mu.Lock()
+	if err := s.checkPeerStreamLocked(peerID, streamID); err != nil {
+		s.detachSubscriberPCLocked(peerID, pc)
+		s.mu.Unlock()
+		_ = pc.Close()
+		return webrtc.SessionDescription{}, err
+	}
 	s.subscribers[peerID] = subscriber
 	s.mu.Unlock()
 
@@ -519,10 +833,28 @@ func (s *SFUService) createSubscriberOfferInternal(ctx context.Context, peerID d
 		s.mu.Unlock()
 		return webrtc.SessionDescription{}, err
 	}
+	s.eviction.start(peerID, EvictionAnswerTimeout)
 	return offer, nil
 }
 
-// finishLocalAnswer creates an answer and waits for ICE gathering.
+// initialSubscriberQuality determines a new subscriber's quality based on network conditions
+func (s *SFUService) initialSubscriberQuality() string {
+	if s.qualityService == nil {
+		return "medium" // Default quality
+	}
+	// Get initial metrics (would come from RTCP in real implementation)
+	initialMetrics := domain.NetworkMetrics{
+		BandwidthDown:    1000,
+		BandwidthUp:      500,
+		PacketLoss:       0.02,
+		Latency:          150 * time.Millisecond,
+		Jitter:           40 * time.Millisecond,
+		AvailableBitrate: 800,
+	}
+	return s.qualityService.DetermineOptimalQuality(initialMetrics)
+}
+
+// finishLocalAnswer creates an answer and waits for ICE gathering unless trickling.
 func (s *SFUService) finishLocalAnswer(pc *webrtc.PeerConnection) (webrtc.SessionDescription, error) {
 	answer, err := pc.CreateAnswer(nil)
 	if err != nil {
@@ -531,6 +863,9 @@ func (s *SFUService) finishLocalAnswer(pc *webrtc.PeerConnection) (webrtc.Sessio
 	if err := pc.SetLocalDescription(answer); err != nil {
 		return webrtc.SessionDescription{}, err
 	}
+	if s.trickling() {
+		return answer, nil
+	}
 	s.waitICEGathering(pc)
 	if ld := pc.LocalDescription(); ld != nil {
 		return *ld, nil
@@ -539,6 +874,7 @@ func (s *SFUService) finishLocalAnswer(pc *webrtc.PeerConnection) (webrtc.Sessio
 }
 
 // finishLocalOffer creates an offer and waits for ICE gathering so the SDP includes host candidates.
+// When trickling it returns right away; candidates follow through the sink.
 func (s *SFUService) finishLocalOffer(pc *webrtc.PeerConnection) (webrtc.SessionDescription, error) {
 	offer, err := pc.CreateOffer(nil)
 	if err != nil {
@@ -547,6 +883,9 @@ func (s *SFUService) finishLocalOffer(pc *webrtc.PeerConnection) (webrtc.Session
 	if err := pc.SetLocalDescription(offer); err != nil {
 		return webrtc.SessionDescription{}, err
 	}
+	if s.trickling() {
+		return offer, nil
+	}
 	s.waitICEGathering(pc)
 	if ld := pc.LocalDescription(); ld != nil {
 		return *ld, nil
@@ -565,6 +904,11 @@ func (s *SFUService) waitICEGathering(pc *webrtc.PeerConnection) {
 
 // HandleSubscriberAnswer handles answer from subscriber
 func (s *SFUService) HandleSubscriberAnswer(ctx context.Context, peerID domain.PeerID, answer webrtc.SessionDescription) error {
+	// Checked before retries: a mismatched direction is not transient
+	if err := sdputil.ValidateMediaDirections(answer.SDP, sdputil.MediaRoleSubscriber); err != nil {
+		return err
+	}
+
 	if s.retryConfig.Enabled {
 		return retry.Retry(ctx, s.retryConfig, func() error {
 			peerCB := s.getPeerCircuitBreaker(peerID)
@@ -587,7 +931,15 @@ func (s *SFUService) handleSubscriberAnswerInternal(ctx context.Context, peerID
 		return domain.ErrPeerNotFound
 	}
 
-	return applyRemoteAnswer(subscriber.PC, answer)
+	if err := applyRemoteAnswer(subscriber.PC, answer); err != nil {
+		return err
+	}
+	s.eviction.cancel(peerID, EvictionAnswerTimeout)
+	keyframeTracks := s.takeAnswerKeyframes(peerID)
+	s.clearPendingOffer(peerID)
+	s.flushPendingCandidates(peerID, subscriber.PC)
+	s.requestAnswerKeyframes(peerID, keyframeTracks)
+	return nil
 }
 
 // applyRemoteAnswer sets the browser's answer on an SFU peer that created the offer.
@@ -607,20 +959,253 @@ func applyRemoteAnswer(pc *webrtc.PeerConnection, answer webrtc.SessionDescripti
 	}
 }
 
+// candidateQueue holds early candidates for one PeerConnection of a peer
+type candidateQueue struct {
+	pc         *webrtc.PeerConnection
+	candidates []webrtc.ICECandidateInit
+}
+
+// AddICECandidate applies a candidate trickled by the client. Candidates that
+// arrive before the peer's remote description is set are buffered (up to
+// MaxPendingCandidates) and applied in order once it is.
+func (s *SFUService) AddICECandidate(ctx context.Context, peerID domain.PeerID, candidate webrtc.ICECandidateInit) error {
+	pc := s.peerConnection(peerID)
+	if pc == nil {
+		return domain.ErrPeerNotFound
+	}
+	if err := s.allowICECandidate(peerID); err != nil {
+		return err
+	}
+
+	// Held while adding so buffered candidates are never overtaken by newer ones
+	s.pendingCandidatesMu.Lock()
+	defer s.pendingCandidatesMu.Unlock()
+
+	if pc.RemoteDescription() != nil {
+		return pc.AddICECandidate(candidate)
+	}
+
+	queue, ok := s.pendingCandidates[peerID]
+	if !ok || queue.pc != pc {
+		// First early candidate, or the peer reconnected with a new PeerConnection
+		queue = &candidateQueue{pc: pc}
+		s.pendingCandidates[peerID] = queue
+	}
+	if len(queue.candidates) >= s.maxPendingCandidates() {
+		return fmt.Errorf("%w: %d candidates buffered for peer %s", domain.ErrCandidateQueueFull, len(queue.candidates), peerID)
+	}
+	queue.candidates = append(queue.candidates, candidate)
+	return nil
+}
+
+// flushPendingCandidates applies candidates buffered for pc now that its
+// remote description is set. Candidates buffered for a replaced PeerConnection are dropped.
+func (s *SFUService) flushPendingCandidates(peerID domain.PeerID, pc *webrtc.PeerConnection) {
+	s.pendingCandidatesMu.Lock()
+	defer s.pendingCandidatesMu.Unlock()
+
+	queue, ok := s.pendingCandidates[peerID]
+	if !ok || pc.RemoteDescription() == nil {
+		return
+	}
+	delete(s.pendingCandidates, peerID)
+	if queue.pc != pc {
+		return
+	}
+
+	for _, candidate := range queue.candidates {
+		if err := pc.AddICECandidate(candidate); err != nil {
+			s.logger.Warnw("failed to apply buffered ICE candidate",
+				"peer_id", peerID,
+				"candidate", candidate.Candidate,
+				"error", err,
+			)
+		}
+	}
+}
+
+func (s *SFUService) clearPendingCandidates(peerID domain.PeerID) {
+	s.pendingCandidatesMu.Lock()
+	delete(s.pendingCandidates, peerID)
+	s.pendingCandidatesMu.Unlock()
+}
+
+func (s *SFUService) maxPendingCandidates() int {
+	if s.config.MaxPendingCandidates > 0 {
+		return s.config.MaxPendingCandidates
+	}
+	return defaultMaxPendingCandidates
+}
+
+// peerConnection returns the publisher or subscriber PeerConnection of a peer
+func (s *SFUService) peerConnection(peerID domain.PeerID) *webrtc.PeerConnection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if publisher, ok := s.publishers[peerID]; ok {
+		return publisher.PC
+	}
+	if subscriber, ok := s.subscribers[peerID]; ok {
+		return subscriber.PC
+	}
+	return nil
+}
+
+// RotateKeys starts an ICE restart renegotiation for a peer so the connection
+// re-keys without being torn down. The returned offer is also kept as pending
+// until the client answers via the regular publisher/subscriber answer path,
+// and rolled back if no answer arrives within the renegotiation timeout.
+//
+// Note: pion keeps the DTLS association across an ICE restart; SRTP keys are
+// only fully replaced when the client re-establishes DTLS on its side.
+func (s *SFUService) RotateKeys(ctx context.Context, peerID domain.PeerID) (webrtc.SessionDescription, error) {
+	pc := s.peerConnection(peerID)
+	if pc == nil {
+		return webrtc.SessionDescription{}, domain.ErrPeerNotFound
+	}
+	if err := s.beginRenegotiation(peerID); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	defer s.endRenegotiation(peerID)
+	if state := pc.SignalingState(); state != webrtc.SignalingStateStable {
+		return webrtc.SessionDescription{}, fmt.Errorf("cannot rotate keys while signaling state is %s", state)
+	}
+
+	offer, err := pc.CreateOffer(&webrtc.OfferOptions{ICERestart: true})
+	if err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("create key rotation offer: %w", err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("set key rotation offer: %w", err)
+	}
+	if !s.trickling() {
+		s.waitICEGathering(pc)
+		if ld := pc.LocalDescription(); ld != nil {
+			offer = *ld
+		}
+	}
+
+	s.setPendingOffer(peerID, pc, offer)
+
+	s.logger.Infow("key rotation renegotiation started", "peer_id", peerID)
+	return offer, nil
+}
+
+// PendingRenegotiation returns the key rotation or subscription update offer awaiting the peer's answer, if any.
+func (s *SFUService) PendingRenegotiation(peerID domain.PeerID) (webrtc.SessionDescription, bool) {
+	s.pendingOffersMu.Lock()
+	defer s.pendingOffersMu.Unlock()
+	offer, ok := s.pendingOffers[peerID]
+	return offer, ok
+}
+
+// setPendingOffer keeps a renegotiation offer until the peer answers it. An
+// offer still unanswered after the renegotiation timeout is rolled back so the
+// connection returns to stable and can renegotiate again.
+func (s *SFUService) setPendingOffer(peerID domain.PeerID, pc *webrtc.PeerConnection, offer webrtc.SessionDescription) {
+	s.pendingOffersMu.Lock()
+	s.pendingOffers[peerID] = offer
+	s.pendingOffersMu.Unlock()
+
+	time.AfterFunc(s.renegotiationTimeout(), func() {
+		s.pendingOffersMu.Lock()
+		pending, ok := s.pendingOffers[peerID]
+		if !ok || pending.SDP != offer.SDP {
+			s.pendingOffersMu.Unlock()
+			return
+		}
+		delete(s.pendingOffers, peerID)
+		delete(s.answerKeyframes, peerID)
+		s.pendingOffersMu.Unlock()
+
+		if pc.SignalingState() != webrtc.SignalingStateHaveLocalOffer {
+			return
+		}
+		// pion cannot roll back a local offer, so the peer's last answer is
+		// applied again to return the connection to stable
+		previous := pc.CurrentRemoteDescription()
+		if previous == nil || previous.Type != webrtc.SDPTypeAnswer {
+			s.logger.Warnw("no previous answer to roll back unanswered renegotiation offer", "peer_id", peerID)
+			return
+		}
+		if err := pc.SetRemoteDescription(*previous); err != nil {
+			s.logger.Warnw("failed to roll back unanswered renegotiation offer", "peer_id", peerID, "error", err)
+			return
+		}
+		s.logger.Infow("rolled back unanswered renegotiation offer", "peer_id", peerID)
+	})
+}
+
+func (s *SFUService) renegotiationTimeout() time.Duration {
+	if s.config.RenegotiationTimeout > 0 {
+		return s.config.RenegotiationTimeout
+	}
+	return defaultRenegotiationTimeout
+}
+
+func (s *SFUService) clearPendingOffer(peerID domain.PeerID) {
+	s.pendingOffersMu.Lock()
+	delete(s.pendingOffers, peerID)
+	delete(s.answerKeyframes, peerID)
+	s.pendingOffersMu.Unlock()
+}
+
+// beginRenegotiation claims a peer's connection for one renegotiation until
+// endRenegotiation, so two callers cannot both find it stable and race on
+// the offer. It fails while another offer is being prepared or awaits its
+// answer.
+func (s *SFUService) beginRenegotiation(peerID domain.PeerID) error {
+	s.pendingOffersMu.Lock()
+	defer s.pendingOffersMu.Unlock()
+	if _, pending := s.pendingOffers[peerID]; pending || s.negotiating[peerID] {
+		return fmt.Errorf("%w for peer %s", domain.ErrRenegotiationPending, peerID)
+	}
+	s.negotiating[peerID] = true
+	return nil
+}
+
+func (s *SFUService) endRenegotiation(peerID domain.PeerID) {
+	s.pendingOffersMu.Lock()
+	delete(s.negotiating, peerID)
+	s.pendingOffersMu.Unlock()
+}
+
+// StartKeyRotation rotates keys for all connected peers every KeyRotationInterval
+// until ctx is cancelled. It returns immediately when rotation is disabled.
+func (s *SFUService) StartKeyRotation(ctx context.Context) {
+	if s.config.KeyRotationInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.KeyRotationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, peerID := range s.connectedPeerIDs() {
+				if _, err := s.RotateKeys(ctx, peerID); err != nil {
+					s.logger.Warnw("scheduled key rotation failed", "peer_id", peerID, "error", err)
+				}
+			}
+		}
+	}
+}
+
 // createPeerConnection creates a new WebRTC connection
 func (s *SFUService) createPeerConnection() (*webrtc.PeerConnection, error) {
-	mediaEngine := &webrtc.MediaEngine{}
-	if err := mediaEngine.RegisterDefaultCodecs(); err != nil {
-		return nil, fmt.Errorf("register default codecs: %w", err)
+	mediaEngine, interceptorRegistry, err := s.newMediaEngine()
+	if err != nil {
+		return nil, fmt.Errorf("configure media engine: %w", err)
 	}
 
-	interceptorRegistry := &interceptor.Registry{}
-	if err := webrtc.RegisterDefaultInterceptors(mediaEngine, interceptorRegistry); err != nil {
-		return nil, fmt.Errorf("register default interceptors: %w", err)
-	}
+	s.mu.RLock()
+	iceServers := s.config.ICEServers
+	s.mu.RUnlock()
 
 	config := webrtc.Configuration{
-		ICEServers:   s.config.ICEServers,
+		ICEServers:   iceServers,
 		SDPSemantics: webrtc.SDPSemanticsUnifiedPlanWithFallback,
 	}
 
@@ -647,11 +1232,13 @@ func (s *SFUService) createPeerConnection() (*webrtc.PeerConnection, error) {
 // handlePublisherTrack handles incoming tracks from publisher
 func (s *SFUService) handlePublisherTrack(peerID domain.PeerID, streamID domain.StreamID) func(*webrtc.TrackRemote, *webrtc.RTPReceiver) {
 	return func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		layer := simulcastLayer(track.RID())
 		s.logger.Infow("publisher started streaming track",
 			"peer_id", peerID,
 			"stream_id", streamID,
 			"track_id", track.ID(),
 			"codec", track.Codec().MimeType,
+			"layer", layer,
 		)
 
 		// Create local track for forwarding to subscribers
@@ -671,18 +1258,31 @@ func (s *SFUService) handlePublisherTrack(peerID domain.PeerID, streamID domain.
 
 		// Create forwarder for this track
 		forwarder := &TrackForwarder{
-			TrackID:     domain.TrackID(track.ID()),
+			TrackID:     forwarderKey(track.ID(), layer),
 			Publisher:   peerID,
 			StreamID:    streamID,
 			Track:       localTrack,
 			Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+			SSRC:        track.SSRC(),
+			Layer:       layer,
+			recording:   s.startRecording(streamID, track.Codec(), layer),
+			svc:         newLayerSelector(track.Codec().MimeType),
 		}
 
 		s.mu.Lock()
-		s.trackForwarders[domain.TrackID(track.ID())] = forwarder
+		if publisher, ok := s.publishers[peerID]; ok {
+			forwarder.Paused = publisher.Paused
+		}
+		s.trackForwarders[forwarder.TrackID] = forwarder
+		layers := s.publisherLayersLocked(peerID)
 		s.mu.Unlock()
+		s.prioritizer.RegisterTrack(forwarder.TrackID, track.Kind() == webrtc.RTPCodecTypeAudio, layer, track.Codec().MimeType)
+		if layer != "" {
+			s.notifyLayersAvailable(streamID, peerID, layers)
+		}
 
-		// Start RTCP processing for this receiver
+		// Start RTCP processing for this receiver; silence past the grace period evicts
+		s.eviction.start(peerID, EvictionMetricsStale)
 		go s.processRTCP(peerID, streamID, receiver, true) // true = publisher
 
 		// Start forwarding packets to subscribers
@@ -706,6 +1306,12 @@ func (s *SFUService) forwardTrackToSubscribers(forwarder *TrackForwarder, track
 
 	rtpPacket := &rtp.Packet{}
 	packetCount := uint16(0)
+	shedding := false
+	defer s.errLogger.Flush(string(forwarder.TrackID))
+	// The recording ends with the track and is indexed then
+	defer forwarder.recording.finish()
+	// An ended track stops being forwarded, and a dropped layer announced
+	defer s.forwarderStopped(forwarder)
 
 	for {
 		// Read RTP packet from publisher
@@ -721,24 +1327,37 @@ func (s *SFUService) forwardTrackToSubscribers(forwarder *TrackForwarder, track
 
 		// Parse RTP packet
 		if err := rtpPacket.Unmarshal(packetBuffer[:n]); err != nil {
-			s.logger.Warnw("error unmarshaling RTP packet",
+			s.errLogger.Warnw(string(forwarder.TrackID), err, "error unmarshaling RTP packet",
 				"track_id", forwarder.TrackID,
-				"error", err,
 			)
 			continue
 		}
 
-		// Write packet to local track, which will forward to all subscribers
-		if forwarder.Track != nil {
-			if err := forwarder.Track.WriteRTP(rtpPacket); err != nil {
-				s.logger.Warnw("error writing RTP packet to local track",
-					"track_id", forwarder.TrackID,
-					"error", err,
-				)
-				// Continue processing even if one write fails
-			}
+		// Under load, low-priority video is dropped; audio and keyframes are not
+		if !s.admitForwarded(forwarder, rtpPacket) {
+			shedding = true
+			continue
+		}
+		// Subscribers lost frames while shedding and need a keyframe to resume
+		if shedding && s.prioritizer.GetPriority(forwarder.TrackID) != PriorityVideoKeyframe {
+			shedding = false
+			go func() {
+				_ = s.requestKeyframe(forwarder.Publisher, forwarder.TrackID)
+			}()
+		}
+
+		// SVC video is thinned to the layers its stream forwards
+		if !s.selectSVCLayers(forwarder, rtpPacket) {
+			continue
 		}
 
+		// Write packet to local track, which will forward to all subscribers.
+		// Paused publishers keep being read so the receive buffer doesn't back up.
+		if !s.forwardPacket(forwarder, rtpPacket) {
+			continue
+		}
+		forwarder.recording.write(rtpPacket)
+
 		packetCount++
 
 		// Log forwarding stats periodically
@@ -758,6 +1377,69 @@ func (s *SFUService) forwardTrackToSubscribers(forwarder *TrackForwarder, track
 	}
 }
 
+// forwardPacket writes a packet to the forwarder's local track unless the
+// publisher is paused. It reports whether the packet was forwarded.
+func (s *SFUService) forwardPacket(forwarder *TrackForwarder, packet *rtp.Packet) bool {
+	forwarder.Mu.RLock()
+	paused := forwarder.Paused
+	subscriberCount := len(forwarder.Subscribers)
+	forwarder.Mu.RUnlock()
+
+	if paused || forwarder.Track == nil {
+		return false
+	}
+
+	if err := forwarder.Track.WriteRTP(packet); err != nil {
+		s.errLogger.Warnw(string(forwarder.TrackID), err, "error writing RTP packet to local track",
+			"track_id", forwarder.TrackID,
+		)
+		// Continue processing even if one write fails
+	} else if s.metrics != nil && subscriberCount > 0 {
+		s.metrics.RecordServerDataTransferred(int64(packet.MarshalSize() * subscriberCount))
+	}
+	return true
+}
+
+// SetPublisherPaused suspends or resumes forwarding of a publisher's tracks
+// to subscribers without tearing down its PeerConnection. Resuming requests a
+// keyframe on each track.
+func (s *SFUService) SetPublisherPaused(streamID domain.StreamID, peerID domain.PeerID, paused bool) error {
+	s.mu.Lock()
+	publisher, exists := s.publishers[peerID]
+	if !exists || publisher.StreamID != streamID {
+		s.mu.Unlock()
+		return domain.ErrPeerNotFound
+	}
+	publisher.Paused = paused
+
+	var trackIDs []domain.TrackID
+	for _, forwarder := range s.trackForwarders {
+		if forwarder.Publisher != peerID {
+			continue
+		}
+		forwarder.Mu.Lock()
+		forwarder.Paused = paused
+		forwarder.Mu.Unlock()
+		trackIDs = append(trackIDs, forwarder.TrackID)
+	}
+	s.mu.Unlock()
+
+	s.logger.Infow("publisher forwarding state changed",
+		"peer_id", peerID,
+		"stream_id", streamID,
+		"paused", paused,
+	)
+
+	// Subscribers cannot decode the resumed media until the next keyframe
+	if !paused {
+		for _, trackID := range trackIDs {
+			go s.requestKeyframe(peerID, trackID)
+		}
+	}
+
+	return nil
+}
+
 // handleICEConnectionState handles ICE connection state changes
 func (s *SFUService) handleICEConnectionState(peerID domain.PeerID) func(webrtc.ICEConnectionState) {
 	return func(state webrtc.ICEConnectionState) {
@@ -767,14 +1449,56 @@ func (s *SFUService) handleICEConnectionState(peerID domain.PeerID) func(webrtc.
 		)
 
 		switch state {
-		case webrtc.ICEConnectionStateFailed:
-			s.logger.Warnw("peer ICE failed (session kept for retry)", "peer_id", peerID)
+		case webrtc.ICEConnectionStateConnected, webrtc.ICEConnectionStateCompleted:
+			s.eviction.cancel(peerID, EvictionICEDisconnect)
+			s.startSubscriberMonitoring(peerID)
+			// Completed follows Connected, so act on the connection once
+			if state == webrtc.ICEConnectionStateConnected {
+				s.recordPeerConnected(peerID)
+				s.recordConnectionResult(peerID, true)
+				s.requestSubscriberKeyframes(peerID)
+			}
+		case webrtc.ICEConnectionStateDisconnected, webrtc.ICEConnectionStateFailed:
+			if state == webrtc.ICEConnectionStateFailed {
+				s.recordConnectionResult(peerID, false)
+			}
+			s.logger.Warnw("peer ICE lost (session kept for the eviction grace period)",
+				"peer_id", peerID,
+				"ice_state", state,
+			)
+			s.eviction.start(peerID, EvictionICEDisconnect)
 		case webrtc.ICEConnectionStateClosed:
 			s.handlePeerDisconnect(peerID)
 		}
 	}
 }
 
+// recordConnectionResult reports whether a subscriber reached the sources it
+// was assigned, so later source selection for it can favour reachable peers
+func (s *SFUService) recordConnectionResult(peerID domain.PeerID, connected bool) {
+	if s.meshService == nil {
+		return
+	}
+
+	s.mu.RLock()
+	var sources []domain.PeerID
+	if subscriber, ok := s.subscribers[peerID]; ok {
+		sources = append(sources, subscriber.SourcePeers...)
+	}
+	s.mu.RUnlock()
+
+	ctx := context.Background()
+	for _, source := range sources {
+		if err := s.meshService.RecordConnectionResult(ctx, source, peerID, connected); err != nil {
+			s.logger.Debugw("failed to record connection result",
+				"source_peer", source,
+				"peer_id", peerID,
+				"error", err,
+			)
+		}
+	}
+}
+
 // handleConnectionState handles connection state changes
 func (s *SFUService) handleConnectionState(peerID domain.PeerID) func(webrtc.PeerConnectionState) {
 	return func(state webrtc.PeerConnectionState) {
@@ -827,6 +1551,8 @@ func (s *SFUService) GetStreamWebRTCStatus(_ context.Context, streamID domain.St
 
 // processRTCP processes RTCP packets from RTPReceiver to extract quality metrics
 func (s *SFUService) processRTCP(peerID domain.PeerID, streamID domain.StreamID, receiver *webrtc.RTPReceiver, isPublisher bool) {
+	defer s.errLogger.Flush(rtcpLogScope(peerID))
+
 	// Read RTCP packets from receiver
 	for {
 		packets, _, err := receiver.ReadRTCP()
@@ -843,8 +1569,17 @@ func (s *SFUService) processRTCP(peerID domain.PeerID, streamID domain.StreamID,
 	}
 }
 
+func rtcpLogScope(peerID domain.PeerID) string {
+	return "rtcp:" + string(peerID)
+}
+
 // processRTCPPackets processes RTCP packets to extract quality metrics
 func (s *SFUService) processRTCPPackets(peerID domain.PeerID, streamID domain.StreamID, packets []rtcp.Packet, isPublisher bool) {
+	// Only the publisher's own reports show its media is still arriving
+	if isPublisher {
+		s.eviction.restart(peerID, EvictionMetricsStale)
+	}
+
 	var totalPacketLoss uint8
 	var totalJitter uint32
 	var totalLatency time.Duration
@@ -891,7 +1626,7 @@ func (s *SFUService) processRTCPPackets(peerID domain.PeerID, streamID domain.St
 			packetCount++
 
 		case *rtcp.PictureLossIndication:
-			// PLI indicates picture loss (keyframe request)
+			// Publisher-side PLI; subscriber PLIs go through handleSubscriberRTCP
 			s.logger.Debugw("received PLI",
 				"peer_id", peerID,
 				"stream_id", streamID,
@@ -916,13 +1651,14 @@ func (s *SFUService) processRTCPPackets(peerID domain.PeerID, streamID domain.St
 			AvailableBitrate: 0, // Will be calculated from other sources
 		}
 
+		s.storePeerStats(peerID, streamID, metrics)
+
 		// Update metrics through mesh service
 		ctx := context.Background()
 		if err := s.meshService.UpdatePeerMetrics(ctx, peerID, metrics); err != nil {
-			s.logger.Warnw("failed to update peer metrics from RTCP",
+			s.errLogger.Warnw(rtcpLogScope(peerID), err, "failed to update peer metrics from RTCP",
 				"peer_id", peerID,
 				"stream_id", streamID,
-				"error", err,
 			)
 		} else {
 			s.logger.Debugw("updated peer metrics from RTCP",
@@ -937,14 +1673,49 @@ func (s *SFUService) processRTCPPackets(peerID domain.PeerID, streamID domain.St
 
 		// Update stream latency metrics
 		s.metricsService.UpdateLatency(streamID, avgLatency)
+		if s.metrics != nil {
+			s.metrics.RecordNetworkLatency(avgLatency)
+		}
 	}
 }
 
 // handlePeerDisconnect handles peer disconnection
 func (s *SFUService) handlePeerDisconnect(peerID domain.PeerID) {
+	s.eviction.forget(peerID)
+	s.clearPeerStats(peerID)
+	s.candidateLimiter.Forget(peerID)
+
+	if s.subscriberMonitor != nil {
+		s.subscriberMonitor.StopMonitoring(peerID)
+	}
+
+	streamID := s.removePeer(peerID)
+	if streamID == "" {
+		return
+	}
+
+	// Keep the mesh and signaling in step with the SFU
+	ctx := context.Background()
+	if s.meshService != nil {
+		if err := s.meshService.RemovePeer(ctx, peerID); err != nil {
+			s.logger.Infow("failed to remove disconnected peer from mesh", "peer_id", peerID, "error", err)
+		}
+	}
+	if s.peerLeftNotifier != nil {
+		if err := s.peerLeftNotifier.NotifyPeerLeft(ctx, streamID, peerID); err != nil {
+			s.logger.Infow("failed to notify peer left", "peer_id", peerID, "stream_id", streamID, "error", err)
+		}
+	}
+}
+
+// removePeer drops a peer's SFU state and returns the stream it was in, or
+// "" when the peer was not connected
+func (s *SFUService) removePeer(peerID domain.PeerID) domain.StreamID {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var streamID, publisherStream domain.StreamID
+
 	// Clean up publisher
 	if publisher, exists := s.publishers[peerID]; exists {
 		if publisher.PC != nil {
@@ -952,7 +1723,12 @@ func (s *SFUService) handlePeerDisconnect(peerID domain.PeerID) {
 		}
 		delete(s.publishers, peerID)
 		s.metricsService.DecrementPublisherCount(publisher.StreamID)
+		s.recordPeerLeft(publisher.StreamID, true, publisher.connectedAt)
+		streamID = publisher.StreamID
+		publisherStream = publisher.StreamID
 	}
+	s.clearPendingOffer(peerID)
+	s.clearPendingCandidates(peerID)
 
 	// Clean up subscriber
 	if subscriber, exists := s.subscribers[peerID]; exists {
@@ -961,6 +1737,8 @@ func (s *SFUService) handlePeerDisconnect(peerID domain.PeerID) {
 		}
 		delete(s.subscribers, peerID)
 		s.metricsService.DecrementSubscriberCount(subscriber.StreamID)
+		s.recordPeerLeft(subscriber.StreamID, false, subscriber.connectedAt)
+		streamID = subscriber.StreamID
 
 		// Remove subscriber from all forwarders
 		for _, forwarder := range s.trackForwarders {
@@ -983,8 +1761,33 @@ func (s *SFUService) handlePeerDisconnect(peerID domain.PeerID) {
 			}
 			forwarder.Mu.Unlock()
 			delete(s.trackForwarders, trackID)
+			s.prioritizer.UnregisterTrack(trackID)
 		}
 	}
+
+	// The stream ended with its last publisher
+	if publisherStream != "" && !s.streamHasPublisher(publisherStream) {
+		forgetDroppedPackets(publisherStream)
+	}
+
+	return streamID
+}
+
+// streamHasPublisher reports whether a publisher is still on the stream; the caller holds s.mu
+func (s *SFUService) streamHasPublisher(streamID domain.StreamID) bool {
+	for _, publisher := range s.publishers {
+		if publisher.StreamID == streamID {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveForwarders returns how many track forwarders the SFU holds
+func (s *SFUService) ActiveForwarders() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.trackForwarders)
 }
 
 // GetPublisher returns publisher by ID
@@ -1005,38 +1808,5 @@ func (s *SFUService) GetSubscriber(peerID domain.PeerID) (*Subscriber, bool) {
 
 // SwitchSubscriberQuality switches the quality layer for a subscriber (simulcast)
 func (s *SFUService) SwitchSubscriberQuality(ctx context.Context, peerID domain.PeerID, quality string) error {
-	s.mu.RLock()
-	subscriber, exists := s.subscribers[peerID]
-	s.mu.RUnlock()
-
-	if !exists {
-		return domain.ErrPeerNotFound
-	}
-
-	// Validate quality
-	validQualities := map[string]bool{"low": true, "medium": true, "high": true}
-	if !validQualities[quality] {
-		return fmt.Errorf("invalid quality: %s", quality)
-	}
-
-	// Update subscriber quality
-	s.mu.Lock()
-	subscriber.Quality = quality
-	s.mu.Unlock()
-
-	s.logger.Infow("switched subscriber quality",
-		"peer_id", peerID,
-		"quality", quality,
-	)
-
-	// In a full implementation, this would:
-	// 1. Get the RTPSender for the video track
-	// 2. Use SetRTPParameters to switch simulcast layers
-	// 3. Or use SetTrack to replace the track with the desired quality layer
-	// 
-	// For now, we just update the quality field. The actual simulcast switching
-	// would require access to RTPSender and RTPParameters, which needs to be
-	// stored when tracks are added.
-
-	return nil
+	return s.SwitchSubscriberLayer(ctx, peerID, quality)
 }
@@ -0,0 +1,136 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func hostCandidate(i int) webrtc.ICECandidateInit {
+	mid := "0"
+	index := uint16(0)
+	return webrtc.ICECandidateInit{
+		Candidate:     fmt.Sprintf("candidate:%d 1 udp 2130706431 192.0.2.%d 5000 typ host", i, i),
+		SDPMid:        &mid,
+		SDPMLineIndex: &index,
+	}
+}
+
+func remoteCandidateCount(pc *webrtc.PeerConnection) int {
+	count := 0
+	for _, stat := range pc.GetStats() {
+		if candidate, ok := stat.(webrtc.ICECandidateStats); ok && candidate.Type == webrtc.StatsTypeRemoteCandidate {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSFU_BuffersCandidatesUntilAnswer(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	peerID := domain.PeerID("early-trickler")
+	offer, err := sfu.CreatePublisherOffer(ctx, peerID, domain.StreamID("trickle-stream"))
+	require.NoError(t, err)
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.SetRemoteDescription(offer))
+	answer, err := client.CreateAnswer(nil)
+	require.NoError(t, err)
+
+	// Candidates posted before the answer are queued, not rejected
+	const early = 3
+	for i := 1; i <= early; i++ {
+		require.NoError(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(i)))
+	}
+	publisher, ok := sfu.GetPublisher(peerID)
+	require.True(t, ok)
+	require.Zero(t, remoteCandidateCount(publisher.PC))
+
+	require.NoError(t, sfu.HandlePublisherAnswer(ctx, peerID, answer))
+
+	sfu.pendingCandidatesMu.Lock()
+	_, stillQueued := sfu.pendingCandidates[peerID]
+	sfu.pendingCandidatesMu.Unlock()
+	require.False(t, stillQueued, "queue should be flushed once the answer is applied")
+
+	require.Eventually(t, func() bool {
+		return remoteCandidateCount(publisher.PC) == early
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Later candidates go straight to the PeerConnection
+	require.NoError(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(early+1)))
+	require.Eventually(t, func() bool {
+		return remoteCandidateCount(publisher.PC) == early+1
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestSFU_PendingCandidateQueueIsBounded(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{MaxPendingCandidates: 2},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	peerID := domain.PeerID("chatty-trickler")
+	_, err := sfu.CreatePublisherOffer(ctx, peerID, domain.StreamID("trickle-stream"))
+	require.NoError(t, err)
+
+	require.NoError(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(1)))
+	require.NoError(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(2)))
+	require.ErrorIs(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(3)), domain.ErrCandidateQueueFull)
+
+	require.ErrorIs(t, sfu.AddICECandidate(ctx, "unknown-peer", hostCandidate(1)), domain.ErrPeerNotFound)
+}
+
+func TestSFU_ICECandidatesBeyondLimitAreRejected(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{MaxICECandidatesPerMinute: 2},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	peerID := domain.PeerID("flooding-trickler")
+	_, err := sfu.CreatePublisherOffer(ctx, peerID, domain.StreamID("trickle-stream"))
+	require.NoError(t, err)
+
+	dropped := testutil.ToFloat64(iceCandidatesDropped)
+	require.NoError(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(1)))
+	require.NoError(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(2)))
+	require.ErrorIs(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(3)), domain.ErrTooManyCandidates)
+	require.ErrorIs(t, sfu.AddICECandidate(ctx, peerID, hostCandidate(4)), domain.ErrTooManyCandidates)
+	require.Equal(t, dropped+2, testutil.ToFloat64(iceCandidatesDropped))
+
+	// Only the accepted candidates were buffered
+	sfu.pendingCandidatesMu.Lock()
+	require.Len(t, sfu.pendingCandidates[peerID].candidates, 2)
+	sfu.pendingCandidatesMu.Unlock()
+}
@@ -0,0 +1,129 @@
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// removalRecorder records mesh removals and peer-left notifications
+type removalRecorder struct {
+	ports.MeshService
+
+	mu      sync.Mutex
+	removed []domain.PeerID
+	left    []domain.PeerID
+	streams []domain.StreamID
+}
+
+func (r *removalRecorder) RemovePeer(ctx context.Context, peerID domain.PeerID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removed = append(r.removed, peerID)
+	return nil
+}
+
+func (r *removalRecorder) NotifyPeerLeft(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.left = append(r.left, peerID)
+	r.streams = append(r.streams, streamID)
+	return nil
+}
+
+func TestSFU_DisconnectRemovesPeerFromMeshAndSignaling(t *testing.T) {
+	recorder := &removalRecorder{}
+	sfu := NewSFUService(
+		WebRTCConfig{},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		recorder,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+	sfu.SetPeerLeftNotifier(recorder)
+
+	_, err := sfu.CreatePublisherOffer(context.Background(), "dropped-publisher", "dropped-stream")
+	require.NoError(t, err)
+
+	sfu.handlePeerDisconnect("dropped-publisher")
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Equal(t, []domain.PeerID{"dropped-publisher"}, recorder.removed)
+	require.Equal(t, []domain.PeerID{"dropped-publisher"}, recorder.left)
+	require.Equal(t, []domain.StreamID{"dropped-stream"}, recorder.streams)
+}
+
+func TestSFU_DisconnectOfUnknownPeerNotifiesNobody(t *testing.T) {
+	recorder := &removalRecorder{}
+	sfu := newTestSFU(WebRTCConfig{})
+	sfu.SetPeerLeftNotifier(recorder)
+
+	sfu.handlePeerDisconnect("never-connected")
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Empty(t, recorder.left)
+}
+
+// resultRecorder records connection outcomes reported to the mesh
+type resultRecorder struct {
+	ports.MeshService
+
+	mu      sync.Mutex
+	results []string
+}
+
+func (r *resultRecorder) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	outcome := "failed"
+	if connected {
+		outcome = "connected"
+	}
+	r.results = append(r.results, string(sourcePeer)+"->"+string(targetPeer)+":"+outcome)
+	return nil
+}
+
+func TestSFU_ICEStateRecordsConnectionResultPerSource(t *testing.T) {
+	recorder := &resultRecorder{}
+	sfu := NewSFUService(
+		WebRTCConfig{},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		recorder,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+	sfu.subscribers["viewer"] = &Subscriber{
+		PeerID:      "viewer",
+		StreamID:    "results-stream",
+		SourcePeers: []domain.PeerID{"relay-a", "relay-b"},
+	}
+
+	onState := sfu.handleICEConnectionState("viewer")
+	onState(webrtc.ICEConnectionStateFailed)
+	onState(webrtc.ICEConnectionStateConnected)
+	onState(webrtc.ICEConnectionStateCompleted)
+	onState(webrtc.ICEConnectionStateDisconnected)
+	sfu.eviction.forgetAll()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Equal(t, []string{
+		"relay-a->viewer:failed",
+		"relay-b->viewer:failed",
+		"relay-a->viewer:connected",
+		"relay-b->viewer:connected",
+	}, recorder.results)
+}
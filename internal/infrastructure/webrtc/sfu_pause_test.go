@@ -0,0 +1,66 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_SetPublisherPausedStopsForwarding(t *testing.T) {
+	sfu := NewSFUService(
+		WebRTCConfig{},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	publisherID := domain.PeerID("paused-publisher")
+	_, err := sfu.CreatePublisherOffer(context.Background(), publisherID, domain.StreamID("paused-stream"))
+	require.NoError(t, err)
+
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "paused-stream")
+	require.NoError(t, err)
+	forwarder := &TrackForwarder{
+		TrackID:     "video",
+		Publisher:   publisherID,
+		StreamID:    "paused-stream",
+		Track:       track,
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+	}
+	sfu.mu.Lock()
+	sfu.trackForwarders[forwarder.TrackID] = forwarder
+	sfu.mu.Unlock()
+
+	packet := &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 1}}
+	require.True(t, sfu.forwardPacket(forwarder, packet))
+
+	require.ErrorIs(t, sfu.SetPublisherPaused("other-stream", publisherID, true), domain.ErrPeerNotFound)
+	require.True(t, sfu.forwardPacket(forwarder, packet), "a publisher of another stream must not be paused")
+
+	require.NoError(t, sfu.SetPublisherPaused("paused-stream", publisherID, true))
+	require.False(t, sfu.forwardPacket(forwarder, packet))
+	pub, _ := sfu.GetPublisher(publisherID)
+	require.True(t, pub.Paused)
+	require.NotNil(t, pub.PC, "pausing must keep the PeerConnection alive")
+
+	require.NoError(t, sfu.SetPublisherPaused("paused-stream", publisherID, false))
+	require.True(t, sfu.forwardPacket(forwarder, packet))
+	require.Eventually(t, func() bool {
+		forwarder.Mu.RLock()
+		defer forwarder.Mu.RUnlock()
+		return !forwarder.lastKeyframeRequest.IsZero()
+	}, time.Second, 10*time.Millisecond, "resuming must request a keyframe")
+
+	require.ErrorIs(t, sfu.SetPublisherPaused("paused-stream", "unknown", true), domain.ErrPeerNotFound)
+}
@@ -0,0 +1,123 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSFU(cfg WebRTCConfig) *SFUService {
+	return NewSFUService(
+		cfg,
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+}
+
+// answerOffer plays the viewer: it accepts the SFU offer and returns its answer
+func answerOffer(t *testing.T, offer webrtc.SessionDescription) webrtc.SessionDescription {
+	t.Helper()
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	require.NoError(t, client.SetRemoteDescription(offer))
+	answer, err := client.CreateAnswer(nil)
+	require.NoError(t, err)
+	require.NoError(t, client.SetLocalDescription(answer))
+	return answer
+}
+
+func TestSFU_PreconnectBindsToStreamSubscriberFlow(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	streamID := domain.StreamID("preconnect-stream")
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", string(streamID))
+	require.NoError(t, err)
+	forwarder := &TrackForwarder{
+		TrackID:     "video",
+		Publisher:   "preconnect-publisher",
+		StreamID:    streamID,
+		Track:       track,
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+	}
+	sfu.mu.Lock()
+	sfu.trackForwarders[forwarder.TrackID] = forwarder
+	sfu.mu.Unlock()
+
+	viewerID := domain.PeerID("preconnect-viewer")
+	preconnect, err := sfu.CreatePreconnect(ctx, "viewer-user", viewerID)
+	require.NoError(t, err)
+	require.NotEmpty(t, preconnect.Handle)
+	require.Equal(t, webrtc.SDPTypeOffer, preconnect.Offer.Type)
+	require.True(t, preconnect.ExpiresAt.After(time.Now()))
+
+	_, subscribed := sfu.GetSubscriber(viewerID)
+	require.False(t, subscribed, "an unbound preconnect is not a subscriber yet")
+
+	answer := answerOffer(t, preconnect.Offer)
+
+	err = sfu.BindPreconnect(ctx, preconnect.Handle, "someone-else", streamID, answer)
+	require.ErrorIs(t, err, domain.ErrPreconnectNotFound, "handles are bound only by their own peer")
+
+	require.NoError(t, sfu.BindPreconnect(ctx, preconnect.Handle, viewerID, streamID, answer))
+
+	sub, ok := sfu.GetSubscriber(viewerID)
+	require.True(t, ok)
+	require.Equal(t, streamID, sub.StreamID)
+	forwarder.Mu.RLock()
+	require.Same(t, sub.PC, forwarder.Subscribers[viewerID])
+	forwarder.Mu.RUnlock()
+
+	sfu.preconnectsMu.Lock()
+	require.Empty(t, sfu.preconnects)
+	sfu.preconnectsMu.Unlock()
+
+	err = sfu.BindPreconnect(ctx, preconnect.Handle, viewerID, streamID, answer)
+	require.ErrorIs(t, err, domain.ErrPreconnectNotFound, "a handle binds only once")
+}
+
+func TestSFU_UnboundPreconnectExpires(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{PreconnectTTL: 50 * time.Millisecond})
+
+	preconnect, err := sfu.CreatePreconnect(context.Background(), "idle-user", "idle-viewer")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		sfu.preconnectsMu.Lock()
+		defer sfu.preconnectsMu.Unlock()
+		_, ok := sfu.preconnects[preconnect.Handle]
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSFU_PreconnectsCappedPerUser(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{MaxPreconnectsPerUser: 2})
+	t.Cleanup(func() { _ = sfu.Shutdown(context.Background()) })
+
+	for _, peerID := range []domain.PeerID{"tab-1", "tab-2"} {
+		_, err := sfu.CreatePreconnect(ctx, "busy-user", peerID)
+		require.NoError(t, err)
+	}
+
+	_, err := sfu.CreatePreconnect(ctx, "busy-user", "tab-3")
+	require.ErrorIs(t, err, domain.ErrTooManyPreconnects)
+
+	// The cap is per user
+	_, err = sfu.CreatePreconnect(ctx, "other-user", "tab-1")
+	require.NoError(t, err)
+}
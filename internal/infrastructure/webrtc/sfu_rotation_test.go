@@ -0,0 +1,117 @@
+package webrtc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func iceUfrag(sdp string) string {
+	for _, line := range strings.Split(sdp, "\n") {
+		if strings.HasPrefix(line, "a=ice-ufrag:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "a=ice-ufrag:"))
+		}
+	}
+	return ""
+}
+
+func TestSFU_RotateKeysProducesRenegotiationOffer(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	peerID := domain.PeerID("rotating-publisher")
+	offer, err := sfu.CreatePublisherOffer(ctx, peerID, domain.StreamID("rotating-stream"))
+	require.NoError(t, err)
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	answerOffer := func(offer webrtc.SessionDescription) webrtc.SessionDescription {
+		require.NoError(t, client.SetRemoteDescription(offer))
+		answer, err := client.CreateAnswer(nil)
+		require.NoError(t, err)
+		require.NoError(t, client.SetLocalDescription(answer))
+		return answer
+	}
+	require.NoError(t, sfu.HandlePublisherAnswer(ctx, peerID, answerOffer(offer)))
+
+	_, pending := sfu.PendingRenegotiation(peerID)
+	require.False(t, pending)
+
+	rotated, err := sfu.RotateKeys(ctx, peerID)
+	require.NoError(t, err)
+	require.Equal(t, webrtc.SDPTypeOffer, rotated.Type)
+	require.NotEmpty(t, iceUfrag(rotated.SDP))
+	require.NotEqual(t, iceUfrag(offer.SDP), iceUfrag(rotated.SDP), "rotation must restart ICE with fresh credentials")
+
+	pendingOffer, pending := sfu.PendingRenegotiation(peerID)
+	require.True(t, pending)
+	require.Equal(t, rotated.SDP, pendingOffer.SDP)
+
+	// A second rotation can't start before the first is answered
+	_, err = sfu.RotateKeys(ctx, peerID)
+	require.Error(t, err)
+
+	require.NoError(t, sfu.HandlePublisherAnswer(ctx, peerID, answerOffer(rotated)))
+	_, pending = sfu.PendingRenegotiation(peerID)
+	require.False(t, pending)
+
+	_, err = sfu.RotateKeys(ctx, "unknown-peer")
+	require.ErrorIs(t, err, domain.ErrPeerNotFound)
+}
+
+func TestSFU_RotateKeysRollsBackUnansweredOffer(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{RenegotiationTimeout: 50 * time.Millisecond},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	peerID := domain.PeerID("silent-publisher")
+	offer, err := sfu.CreatePublisherOffer(ctx, peerID, domain.StreamID("silent-stream"))
+	require.NoError(t, err)
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.SetRemoteDescription(offer))
+	answer, err := client.CreateAnswer(nil)
+	require.NoError(t, err)
+	require.NoError(t, client.SetLocalDescription(answer))
+	require.NoError(t, sfu.HandlePublisherAnswer(ctx, peerID, answer))
+
+	_, err = sfu.RotateKeys(ctx, peerID)
+	require.NoError(t, err)
+
+	// The client never answers: the offer is dropped and the connection is stable again
+	pc := sfu.peerConnection(peerID)
+	require.Eventually(t, func() bool {
+		_, pending := sfu.PendingRenegotiation(peerID)
+		return !pending && pc.SignalingState() == webrtc.SignalingStateStable
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, err = sfu.RotateKeys(ctx, peerID)
+	require.NoError(t, err, "a rolled back rotation must not block the next one")
+}
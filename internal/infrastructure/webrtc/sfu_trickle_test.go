@@ -0,0 +1,81 @@
+package webrtc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/retry"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+type sentCandidate struct {
+	peerID    domain.PeerID
+	candidate *webrtc.ICECandidateInit
+}
+
+// fakeCandidateSink records candidates pushed by the SFU
+type fakeCandidateSink struct {
+	sent chan sentCandidate
+}
+
+func (f *fakeCandidateSink) SendICECandidate(peerID domain.PeerID, candidate *webrtc.ICECandidateInit) error {
+	f.sent <- sentCandidate{peerID: peerID, candidate: candidate}
+	return nil
+}
+
+func TestSFU_TrickleICEPushesCandidatesAfterOffer(t *testing.T) {
+	ctx := context.Background()
+	sfu := NewSFUService(
+		WebRTCConfig{TrickleICE: true},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	sink := &fakeCandidateSink{sent: make(chan sentCandidate, 64)}
+	sfu.SetICECandidateSink(sink)
+
+	peerID := domain.PeerID("trickle-publisher")
+	offer, err := sfu.CreatePublisherOffer(ctx, peerID, domain.StreamID("trickle-stream"))
+	require.NoError(t, err)
+	require.NotContains(t, offer.SDP, "a=candidate:", "trickle offer should not wait for candidates")
+
+	candidates := 0
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case sent := <-sink.sent:
+			require.Equal(t, peerID, sent.peerID)
+			if sent.candidate == nil {
+				require.Positive(t, candidates, "end-of-candidates arrived before any candidate")
+				return
+			}
+			require.True(t, strings.HasPrefix(sent.candidate.Candidate, "candidate:"), sent.candidate.Candidate)
+			candidates++
+		case <-timeout:
+			t.Fatalf("no end-of-candidates after %d candidates", candidates)
+		}
+	}
+}
+
+func TestSFU_TrickleICEIgnoredWithoutSink(t *testing.T) {
+	sfu := NewSFUService(
+		WebRTCConfig{TrickleICE: true},
+		services.NewQualityService(),
+		services.NewMetricsService(),
+		nil,
+		retry.DefaultConfig(),
+		circuitbreaker.DefaultConfig(),
+	).(*SFUService)
+
+	require.False(t, sfu.trickling())
+}
@@ -0,0 +1,95 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Shutdown closes every publisher, subscriber and preconnect PeerConnection
+// and clears the SFU's state. Forwarding and RTCP goroutines exit once their
+// connection is closed. Connections that fail to close, or are still closing
+// when ctx is done, are reported in the returned error.
+func (s *SFUService) Shutdown(ctx context.Context) error {
+	pcs := make(map[*webrtc.PeerConnection]domain.PeerID)
+	collect := func(pc *webrtc.PeerConnection, peerID domain.PeerID) {
+		if pc != nil {
+			pcs[pc] = peerID
+		}
+	}
+
+	s.mu.Lock()
+	for peerID, publisher := range s.publishers {
+		collect(publisher.PC, peerID)
+		s.metricsService.DecrementPublisherCount(publisher.StreamID)
+	}
+	for peerID, subscriber := range s.subscribers {
+		collect(subscriber.PC, peerID)
+		s.metricsService.DecrementSubscriberCount(subscriber.StreamID)
+	}
+	for trackID, forwarder := range s.trackForwarders {
+		s.prioritizer.UnregisterTrack(trackID)
+		forwarder.Mu.Lock()
+		for peerID, pc := range forwarder.Subscribers {
+			collect(pc, peerID)
+		}
+		forwarder.Subscribers = make(map[domain.PeerID]*webrtc.PeerConnection)
+		forwarder.Mu.Unlock()
+	}
+	s.publishers = make(map[domain.PeerID]*Publisher)
+	s.subscribers = make(map[domain.PeerID]*Subscriber)
+	s.trackForwarders = make(map[domain.TrackID]*TrackForwarder)
+	s.mu.Unlock()
+
+	s.preconnectsMu.Lock()
+	for handle, entry := range s.preconnects {
+		entry.expiry.Stop()
+		collect(entry.pc, entry.peerID)
+		delete(s.preconnects, handle)
+	}
+	s.preconnectsMu.Unlock()
+
+	s.pendingOffersMu.Lock()
+	s.pendingOffers = make(map[domain.PeerID]webrtc.SessionDescription)
+	s.answerKeyframes = make(map[domain.PeerID][]domain.TrackID)
+	s.pendingOffersMu.Unlock()
+	s.pendingCandidatesMu.Lock()
+	s.pendingCandidates = make(map[domain.PeerID]*candidateQueue)
+	s.pendingCandidatesMu.Unlock()
+	s.peerStatsMu.Lock()
+	s.peerStats = make(map[domain.PeerID]domain.PeerRTCStats)
+	s.peerStatsMu.Unlock()
+
+	s.eviction.forgetAll()
+
+	type closeResult struct {
+		peerID domain.PeerID
+		err    error
+	}
+	results := make(chan closeResult, len(pcs))
+	for pc, peerID := range pcs {
+		go func(pc *webrtc.PeerConnection, peerID domain.PeerID) {
+			results <- closeResult{peerID: peerID, err: pc.Close()}
+		}(pc, peerID)
+	}
+
+	var errs []error
+	for remaining := len(pcs); remaining > 0; remaining-- {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				errs = append(errs, fmt.Errorf("close peer %s: %w", res.peerID, res.err))
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("%d peer connections still closing: %w", remaining, ctx.Err()))
+			return errors.Join(errs...)
+		}
+	}
+
+	s.logger.Infow("SFU shut down", "peer_connections", len(pcs))
+	return errors.Join(errs...)
+}
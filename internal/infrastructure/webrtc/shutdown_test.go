@@ -0,0 +1,73 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_ShutdownClosesAllSessions(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	streamID := domain.StreamID("shutdown-stream")
+	var pcs []*webrtc.PeerConnection
+
+	for i := 0; i < 3; i++ {
+		publisherID := domain.PeerID(fmt.Sprintf("publisher-%d", i))
+		_, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+		require.NoError(t, err)
+		pub, ok := sfu.GetPublisher(publisherID)
+		require.True(t, ok)
+		pcs = append(pcs, pub.PC)
+	}
+
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", string(streamID))
+	require.NoError(t, err)
+	forwarder := &TrackForwarder{
+		TrackID:     "video",
+		Publisher:   "publisher-0",
+		StreamID:    streamID,
+		Track:       track,
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+	}
+	sfu.mu.Lock()
+	sfu.trackForwarders[forwarder.TrackID] = forwarder
+	sfu.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		subscriberID := domain.PeerID(fmt.Sprintf("subscriber-%d", i))
+		_, err := sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+		require.NoError(t, err)
+		sub, ok := sfu.GetSubscriber(subscriberID)
+		require.True(t, ok)
+		pcs = append(pcs, sub.PC)
+	}
+
+	_, err = sfu.CreatePreconnect(ctx, "warm-user", "warm-viewer")
+	require.NoError(t, err)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	require.NoError(t, sfu.Shutdown(shutdownCtx))
+
+	sfu.mu.RLock()
+	require.Empty(t, sfu.publishers)
+	require.Empty(t, sfu.subscribers)
+	require.Empty(t, sfu.trackForwarders)
+	sfu.mu.RUnlock()
+
+	sfu.preconnectsMu.Lock()
+	require.Empty(t, sfu.preconnects)
+	sfu.preconnectsMu.Unlock()
+
+	for _, pc := range pcs {
+		require.Equal(t, webrtc.PeerConnectionStateClosed, pc.ConnectionState())
+	}
+}
@@ -1 +1,186 @@
 package webrtc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// simulcastLayers lists the layer names from lowest to highest bitrate
+var simulcastLayers = []string{"low", "medium", "high"}
+
+// layerRank returns a layer's position in simulcastLayers, or -1
+func layerRank(layer string) int {
+	for i, l := range simulcastLayers {
+		if l == layer {
+			return i
+		}
+	}
+	return -1
+}
+
+// simulcastLayer maps a publisher's RTP stream ID to a layer name. Both the
+// low/medium/high and the q/h/f (quarter/half/full) conventions are accepted;
+// an empty RID means the track is not simulcast.
+func simulcastLayer(rid string) string {
+	switch strings.ToLower(rid) {
+	case "":
+		return ""
+	case "low", "l", "q":
+		return "low"
+	case "high", "hi", "f":
+		return "high"
+	default:
+		return "medium"
+	}
+}
+
+// forwarderKey identifies a forwarder. Simulcast layers share the publisher's
+// track ID, so each layer gets its own key.
+func forwarderKey(trackID, layer string) domain.TrackID {
+	if layer == "" {
+		return domain.TrackID(trackID)
+	}
+	return domain.TrackID(trackID + "/" + layer)
+}
+
+// nearestLayer picks the available layer closest to want; ties go to the
+// lower layer to spare bandwidth.
+func nearestLayer(available []string, want string) string {
+	wantRank := layerRank(want)
+	best, bestDist := "", len(simulcastLayers)+1
+	for _, layer := range available {
+		dist := layerRank(layer) - wantRank
+		if dist < 0 {
+			dist = -dist
+		}
+		if dist < bestDist || (dist == bestDist && layerRank(layer) < layerRank(best)) {
+			best, bestDist = layer, dist
+		}
+	}
+	return best
+}
+
+// selectLayers keeps non-simulcast forwarders and, for each simulcast track,
+// only the layer nearest to quality.
+func selectLayers(forwarders []*TrackForwarder, quality string) []*TrackForwarder {
+	type group struct {
+		publisher domain.PeerID
+		trackID   string
+	}
+	layers := make(map[group]map[string]*TrackForwarder)
+	var selected []*TrackForwarder
+
+	for _, fwd := range forwarders {
+		if fwd.Layer == "" {
+			selected = append(selected, fwd)
+			continue
+		}
+		g := group{publisher: fwd.Publisher, trackID: fwd.Track.ID()}
+		if layers[g] == nil {
+			layers[g] = make(map[string]*TrackForwarder)
+		}
+		layers[g][fwd.Layer] = fwd
+	}
+
+	for _, byLayer := range layers {
+		available := make([]string, 0, len(byLayer))
+		for layer := range byLayer {
+			available = append(available, layer)
+		}
+		selected = append(selected, byLayer[nearestLayer(available, quality)])
+	}
+	return selected
+}
+
+// forwarderForTrack returns the forwarder feeding a local track. Callers hold s.mu.
+func (s *SFUService) forwarderForTrack(track webrtc.TrackLocal) *TrackForwarder {
+	if track == nil {
+		return nil
+	}
+	for _, fwd := range s.trackForwarders {
+		if fwd.Track != nil && webrtc.TrackLocal(fwd.Track) == track {
+			return fwd
+		}
+	}
+	return nil
+}
+
+// SwitchSubscriberLayer moves a subscriber to another simulcast layer of the
+// tracks it receives by replacing the track on its sender, so no
+// renegotiation is needed. A layer the publisher is not producing falls back
+// to the nearest one that it is.
+func (s *SFUService) SwitchSubscriberLayer(ctx context.Context, peerID domain.PeerID, quality string) error {
+	if layerRank(quality) < 0 {
+		return fmt.Errorf("invalid quality: %s", quality)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriber, exists := s.subscribers[peerID]
+	if !exists {
+		return domain.ErrPeerNotFound
+	}
+	subscriber.Quality = quality
+
+	for _, sender := range subscriber.PC.GetSenders() {
+		current := s.forwarderForTrack(sender.Track())
+		if current == nil || current.Layer == "" {
+			continue
+		}
+
+		siblings := make(map[string]*TrackForwarder)
+		for _, fwd := range s.trackForwarders {
+			if fwd.Layer != "" && fwd.Publisher == current.Publisher && fwd.Track.ID() == current.Track.ID() {
+				siblings[fwd.Layer] = fwd
+			}
+		}
+		available := make([]string, 0, len(siblings))
+		for layer := range siblings {
+			available = append(available, layer)
+		}
+		layer := nearestLayer(available, quality)
+		if layer != quality {
+			s.logger.Warnw("requested simulcast layer not published, using nearest",
+				"peer_id", peerID,
+				"publisher", current.Publisher,
+				"requested", quality,
+				"layer", layer,
+			)
+		}
+
+		target := siblings[layer]
+		if target == current {
+			continue
+		}
+		if err := sender.ReplaceTrack(target.Track); err != nil {
+			return fmt.Errorf("switch %s to layer %s: %w", peerID, layer, err)
+		}
+
+		current.Mu.Lock()
+		delete(current.Subscribers, peerID)
+		current.Mu.Unlock()
+		target.Mu.Lock()
+		target.Subscribers[peerID] = subscriber.PC
+		target.Mu.Unlock()
+
+		// The new layer is undecodable until its next keyframe
+		go func(publisher domain.PeerID, key domain.TrackID) {
+			_ = s.requestKeyframe(publisher, key)
+		}(target.Publisher, target.TrackID)
+
+		s.logger.Infow("switched subscriber simulcast layer",
+			"peer_id", peerID,
+			"publisher", target.Publisher,
+			"from", current.Layer,
+			"to", layer,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearestLayer(t *testing.T) {
+	require.Equal(t, "high", nearestLayer([]string{"low", "medium", "high"}, "high"))
+	require.Equal(t, "medium", nearestLayer([]string{"low", "medium"}, "high"))
+	require.Equal(t, "low", nearestLayer([]string{"low", "high"}, "medium"))
+	require.Equal(t, "high", nearestLayer([]string{"high"}, "low"))
+	require.Equal(t, "", nearestLayer(nil, "medium"))
+}
+
+func TestSimulcastLayer(t *testing.T) {
+	require.Equal(t, "", simulcastLayer(""))
+	require.Equal(t, "low", simulcastLayer("q"))
+	require.Equal(t, "medium", simulcastLayer("h"))
+	require.Equal(t, "high", simulcastLayer("f"))
+	require.Equal(t, "high", simulcastLayer("HIGH"))
+}
+
+// addLayerForwarder registers one simulcast layer of the publisher's "video" track
+func addLayerForwarder(t *testing.T, sfu *SFUService, streamID domain.StreamID, layer string) *TrackForwarder {
+	t.Helper()
+
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", string(streamID))
+	require.NoError(t, err)
+	forwarder := &TrackForwarder{
+		TrackID:     forwarderKey("video", layer),
+		Publisher:   "simulcast-publisher",
+		StreamID:    streamID,
+		Track:       track,
+		Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+		Layer:       layer,
+	}
+	sfu.mu.Lock()
+	sfu.trackForwarders[forwarder.TrackID] = forwarder
+	sfu.mu.Unlock()
+	return forwarder
+}
+
+func TestSFU_SwitchSubscriberLayerReplacesForwardedTrack(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	streamID := domain.StreamID("simulcast-stream")
+	low := addLayerForwarder(t, sfu, streamID, "low")
+	high := addLayerForwarder(t, sfu, streamID, "high")
+
+	subscriberID := domain.PeerID("simulcast-viewer")
+	_, err := sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+	require.NoError(t, err)
+	subscriber, ok := sfu.GetSubscriber(subscriberID)
+	require.True(t, ok)
+
+	senderTrack := func() webrtc.TrackLocal {
+		senders := subscriber.PC.GetSenders()
+		require.Len(t, senders, 1)
+		return senders[0].Track()
+	}
+	isSubscribed := func(fwd *TrackForwarder) bool {
+		fwd.Mu.RLock()
+		defer fwd.Mu.RUnlock()
+		_, ok := fwd.Subscribers[subscriberID]
+		return ok
+	}
+
+	// Only one layer of the track is offered: medium is missing, the lower neighbour wins
+	require.Equal(t, webrtc.TrackLocal(low.Track), senderTrack())
+	require.True(t, isSubscribed(low))
+	require.False(t, isSubscribed(high))
+
+	require.NoError(t, sfu.SwitchSubscriberLayer(ctx, subscriberID, "high"))
+	require.Equal(t, webrtc.TrackLocal(high.Track), senderTrack())
+	require.False(t, isSubscribed(low))
+	require.True(t, isSubscribed(high))
+	require.Equal(t, "high", subscriber.Quality)
+
+	// An unpublished layer falls back to the nearest published one
+	require.NoError(t, sfu.SwitchSubscriberLayer(ctx, subscriberID, "medium"))
+	require.Equal(t, webrtc.TrackLocal(low.Track), senderTrack())
+	require.True(t, isSubscribed(low))
+	require.False(t, isSubscribed(high))
+
+	require.Error(t, sfu.SwitchSubscriberLayer(ctx, subscriberID, "ultra"))
+	require.ErrorIs(t, sfu.SwitchSubscriberLayer(ctx, "unknown-viewer", "low"), domain.ErrPeerNotFound)
+}
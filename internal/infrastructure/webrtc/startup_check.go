@@ -0,0 +1,63 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rillnet/internal/core/domain"
+)
+
+// The startup self-test publishes as a throwaway peer on a stream of its own
+const (
+	selfTestStreamID domain.StreamID = "rillnet-self-test"
+	selfTestPeerID   domain.PeerID   = "rillnet-self-test-publisher"
+)
+
+// selfTestCodecs are the codecs of the tracks every publisher offer carries
+var selfTestCodecs = []string{"opus", "vp8"}
+
+// RunSelfTest checks that the SFU can create a PeerConnection, add tracks and
+// produce a publisher offer negotiating its own codecs and every codec
+// configured under RTCPFeedback, then tears the throwaway publisher down. It
+// bypasses retries and the circuit breaker so a failure is reported once and
+// does not count against real traffic.
+func (s *SFUService) RunSelfTest(ctx context.Context) error {
+	defer func() { _ = s.CloseStream(ctx, selfTestStreamID) }()
+
+	offer, err := s.createPublisherOfferInternal(ctx, selfTestPeerID, selfTestStreamID)
+	if err != nil {
+		return fmt.Errorf("create publisher offer: %w", err)
+	}
+
+	offered := offeredCodecs(offer.SDP)
+	required := append([]string{}, selfTestCodecs...)
+	for codec := range s.config.RTCPFeedback {
+		required = append(required, codec)
+	}
+	for _, codec := range required {
+		if !offered[codec] {
+			return fmt.Errorf("publisher offer does not negotiate codec %q", codec)
+		}
+	}
+	return nil
+}
+
+// offeredCodecs returns the lower-case codec names of an SDP's rtpmap lines
+func offeredCodecs(sdp string) map[string]bool {
+	codecs := make(map[string]bool)
+	for _, line := range strings.Split(sdp, "\n") {
+		rtpmap, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "a=rtpmap:")
+		if !ok {
+			continue
+		}
+		// "96 VP8/90000"
+		_, encoding, ok := strings.Cut(rtpmap, " ")
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(encoding, "/")
+		codecs[strings.ToLower(name)] = true
+	}
+	return codecs
+}
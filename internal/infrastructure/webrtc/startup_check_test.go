@@ -0,0 +1,32 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_RunSelfTestPassesWithValidConfig(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{RTCPFeedback: map[string]FeedbackPolicy{"vp9": {NACK: true}}})
+
+	require.NoError(t, sfu.RunSelfTest(context.Background()))
+
+	// The throwaway publisher is gone afterwards
+	_, exists := sfu.GetPublisher(selfTestPeerID)
+	require.False(t, exists)
+}
+
+func TestSFU_RunSelfTestFailsForUnusableICEServer(t *testing.T) {
+	// Config.Validate does not check ICE servers, so a TURN server missing its
+	// credentials is only caught when a PeerConnection is created
+	sfu := newTestSFU(WebRTCConfig{ICEServers: []webrtc.ICEServer{{URLs: []string{"turn:turn.example.com:3478"}}}})
+
+	err := sfu.RunSelfTest(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "create publisher offer")
+
+	_, exists := sfu.GetPublisher(selfTestPeerID)
+	require.False(t, exists)
+}
@@ -0,0 +1,76 @@
+package webrtc
+
+import (
+	"context"
+
+	"rillnet/internal/core/domain"
+)
+
+// CloseStream closes the PeerConnections of every publisher and subscriber on
+// the stream and drops their SFU state, e.g. when the stream is stopped. The
+// mesh and signaling are left to the caller, which owns the stream's peers.
+func (s *SFUService) CloseStream(ctx context.Context, streamID domain.StreamID) error {
+	peers := s.closeStreamPeers(streamID)
+
+	s.mu.Lock()
+	delete(s.noKeyframeOnJoin, streamID)
+	s.mu.Unlock()
+
+	s.prioritiesMu.Lock()
+	delete(s.streamPriorities, streamID)
+	s.prioritiesMu.Unlock()
+
+	s.svcLayersMu.Lock()
+	delete(s.svcTemporalLayers, streamID)
+	s.svcLayersMu.Unlock()
+
+	s.logger.Infow("stream closed", "stream_id", streamID, "peers", peers)
+	return nil
+}
+
+// ResetStreamMedia closes the stream's PeerConnections like CloseStream but
+// keeps its keyframe-on-join policy, priority and SVC layer limit, for a
+// stream whose peers join again (ports.StreamMediaResetter)
+func (s *SFUService) ResetStreamMedia(ctx context.Context, streamID domain.StreamID) error {
+	peers := s.closeStreamPeers(streamID)
+	s.logger.Infow("stream media reset", "stream_id", streamID, "peers", peers)
+	return nil
+}
+
+// DisconnectPeer closes a peer's PeerConnection and drops its SFU state as
+// if it had left, e.g. when it is kicked (ports.PeerDisconnector)
+func (s *SFUService) DisconnectPeer(ctx context.Context, peerID domain.PeerID) error {
+	if s.peerConnection(peerID) == nil {
+		return domain.ErrPeerNotFound
+	}
+	s.handlePeerDisconnect(peerID)
+	s.logger.Infow("peer disconnected on request", "peer_id", peerID)
+	return nil
+}
+
+// closeStreamPeers closes and forgets every publisher and subscriber on the
+// stream, returning how many there were
+func (s *SFUService) closeStreamPeers(streamID domain.StreamID) int {
+	var peers []domain.PeerID
+
+	s.mu.RLock()
+	for peerID, publisher := range s.publishers {
+		if publisher.StreamID == streamID {
+			peers = append(peers, peerID)
+		}
+	}
+	for peerID, subscriber := range s.subscribers {
+		if subscriber.StreamID == streamID {
+			peers = append(peers, peerID)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, peerID := range peers {
+		s.eviction.forget(peerID)
+		s.clearPeerStats(peerID)
+		s.candidateLimiter.Forget(peerID)
+		s.removePeer(peerID)
+	}
+	return len(peers)
+}
@@ -0,0 +1,28 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_CloseStreamDropsOnlyThatStreamsPeers(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+	ctx := context.Background()
+
+	_, err := sfu.CreatePublisherOffer(ctx, "stopped-publisher", "stopped-stream")
+	require.NoError(t, err)
+	_, err = sfu.CreatePublisherOffer(ctx, "other-publisher", "other-stream")
+	require.NoError(t, err)
+
+	require.NoError(t, sfu.CloseStream(ctx, "stopped-stream"))
+
+	_, exists := sfu.GetPublisher("stopped-publisher")
+	require.False(t, exists)
+	_, exists = sfu.GetPublisher("other-publisher")
+	require.True(t, exists)
+
+	// Closing it again finds nothing left to close
+	require.NoError(t, sfu.CloseStream(ctx, "stopped-stream"))
+}
@@ -0,0 +1,31 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_RejectsPeerSessionOnSecondStream(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+	ctx := context.Background()
+
+	_, err := sfu.CreatePublisherOffer(ctx, "hopper", "first-stream")
+	require.NoError(t, err)
+
+	_, err = sfu.CreatePublisherOffer(ctx, "hopper", "second-stream")
+	require.ErrorIs(t, err, domain.ErrPeerInOtherStream)
+	_, err = sfu.CreateSubscriberOffer(ctx, "hopper", "second-stream", nil)
+	require.ErrorIs(t, err, domain.ErrPeerInOtherStream)
+
+	publisher, exists := sfu.GetPublisher("hopper")
+	require.True(t, exists)
+	require.Equal(t, domain.StreamID("first-stream"), publisher.StreamID)
+
+	// Renegotiating on the same stream is still allowed
+	_, err = sfu.CreatePublisherOffer(ctx, "hopper", "first-stream")
+	require.NoError(t, err)
+}
@@ -0,0 +1,41 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+
+	"rillnet/internal/core/domain"
+)
+
+// priorityLoadHeadroom is how much each step of stream priority raises the
+// load at which a stream's video is shed, as a fraction of MaxForwardedStreams
+const priorityLoadHeadroom = 0.25
+
+// SetStreamPriority sets the priority the stream's video is forwarded with
+// under load; lower-priority streams are shed first (ports.StreamPrioritySetter)
+func (s *SFUService) SetStreamPriority(ctx context.Context, streamID domain.StreamID, priority int) error {
+	if priority < 0 || priority > domain.MaxStreamPriority {
+		return fmt.Errorf("%w: %d (must be 0-%d)", domain.ErrInvalidStreamPriority, priority, domain.MaxStreamPriority)
+	}
+
+	s.prioritiesMu.Lock()
+	defer s.prioritiesMu.Unlock()
+	if priority == 0 {
+		delete(s.streamPriorities, streamID)
+	} else {
+		s.streamPriorities[streamID] = priority
+	}
+	return nil
+}
+
+// streamPriority returns the stream's priority, 0 unless set
+func (s *SFUService) streamPriority(streamID domain.StreamID) int {
+	s.prioritiesMu.RLock()
+	defer s.prioritiesMu.RUnlock()
+	return s.streamPriorities[streamID]
+}
+
+// sheddingCapacity is the load treated as full for a stream's packets
+func (s *SFUService) sheddingCapacity(priority int) float64 {
+	return float64(s.config.MaxForwardedStreams) * (1 + priorityLoadHeadroom*float64(priority))
+}
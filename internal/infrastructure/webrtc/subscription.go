@@ -0,0 +1,159 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// UpdateSubscription adds and removes forwarded tracks on a subscriber's
+// existing PeerConnection and renegotiates once for all of them. The returned
+// offer is kept as pending until the subscriber answers through
+// HandleSubscriberAnswer, like a key rotation; another update fails with
+// domain.ErrRenegotiationPending until then. Once answered, added video
+// tracks get a keyframe request so they decode at once. A zero
+// SessionDescription is returned when nothing changed.
+func (s *SFUService) UpdateSubscription(ctx context.Context, peerID domain.PeerID, add, remove []domain.TrackID) (webrtc.SessionDescription, error) {
+	if err := s.beginRenegotiation(peerID); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	defer s.endRenegotiation(peerID)
+
+	s.mu.Lock()
+	subscriber, exists := s.subscribers[peerID]
+	if !exists {
+		s.mu.Unlock()
+		return webrtc.SessionDescription{}, domain.ErrPeerNotFound
+	}
+	pc := subscriber.PC
+	if state := pc.SignalingState(); state != webrtc.SignalingStateStable {
+		s.mu.Unlock()
+		return webrtc.SessionDescription{}, fmt.Errorf("cannot update subscription while signaling state is %s", state)
+	}
+
+	// Resolve every added track first so a bad ID changes nothing
+	toAdd := make([]*TrackForwarder, 0, len(add))
+	for _, trackID := range add {
+		forwarder, ok := s.trackForwarders[trackID]
+		if !ok || forwarder.StreamID != subscriber.StreamID {
+			s.mu.Unlock()
+			return webrtc.SessionDescription{}, fmt.Errorf("%w: %s", domain.ErrTrackNotFound, trackID)
+		}
+		toAdd = append(toAdd, forwarder)
+	}
+
+	sending := make(map[domain.TrackID]*webrtc.RTPSender)
+	for _, sender := range pc.GetSenders() {
+		if forwarder := s.forwarderForTrack(sender.Track()); forwarder != nil {
+			sending[forwarder.TrackID] = sender
+		}
+	}
+
+	changed := false
+	for _, trackID := range remove {
+		sender, ok := sending[trackID]
+		if !ok {
+			continue
+		}
+		if err := pc.RemoveTrack(sender); err != nil {
+			s.mu.Unlock()
+			return webrtc.SessionDescription{}, fmt.Errorf("remove track %s for %s: %w", trackID, peerID, err)
+		}
+		if forwarder, ok := s.trackForwarders[trackID]; ok {
+			forwarder.Mu.Lock()
+			delete(forwarder.Subscribers, peerID)
+			forwarder.Mu.Unlock()
+			delete(subscriber.pausedSenders, forwarder.Track.ID())
+		}
+		delete(sending, trackID)
+		changed = true
+	}
+
+	var addedVideo []domain.TrackID
+	for _, forwarder := range toAdd {
+		if _, ok := sending[forwarder.TrackID]; ok {
+			continue
+		}
+		sender, err := pc.AddTrack(forwarder.Track)
+		if err != nil {
+			s.mu.Unlock()
+			return webrtc.SessionDescription{}, fmt.Errorf("add track %s for %s: %w", forwarder.TrackID, peerID, err)
+		}
+		go s.processSubscriberRTCP(peerID, sender)
+
+		forwarder.Mu.Lock()
+		forwarder.Subscribers[peerID] = pc
+		forwarder.Mu.Unlock()
+		sending[forwarder.TrackID] = sender
+		if forwarder.Track.Kind() == webrtc.RTPCodecTypeVideo {
+			addedVideo = append(addedVideo, forwarder.TrackID)
+		}
+		changed = true
+	}
+	s.mu.Unlock()
+
+	if !changed {
+		return webrtc.SessionDescription{}, nil
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("create subscription offer: %w", err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("set subscription offer: %w", err)
+	}
+	if !s.trickling() {
+		s.waitICEGathering(pc)
+		if ld := pc.LocalDescription(); ld != nil {
+			offer = *ld
+		}
+	}
+
+	s.setPendingOffer(peerID, pc, offer)
+	if len(addedVideo) > 0 {
+		s.pendingOffersMu.Lock()
+		s.answerKeyframes[peerID] = addedVideo
+		s.pendingOffersMu.Unlock()
+	}
+
+	s.logger.Infow("subscription updated",
+		"peer_id", peerID,
+		"added", add,
+		"removed", remove,
+	)
+	return offer, nil
+}
+
+// takeAnswerKeyframes returns and forgets the tracks to request a keyframe
+// for once the peer's pending offer is answered
+func (s *SFUService) takeAnswerKeyframes(peerID domain.PeerID) []domain.TrackID {
+	s.pendingOffersMu.Lock()
+	defer s.pendingOffersMu.Unlock()
+	trackIDs := s.answerKeyframes[peerID]
+	delete(s.answerKeyframes, peerID)
+	return trackIDs
+}
+
+// requestAnswerKeyframes asks for a keyframe on tracks a subscriber was just
+// given; a keyframe sent before its answer would never reach it
+func (s *SFUService) requestAnswerKeyframes(peerID domain.PeerID, trackIDs []domain.TrackID) {
+	for _, trackID := range trackIDs {
+		s.mu.RLock()
+		forwarder, ok := s.trackForwarders[trackID]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := s.requestKeyframe(forwarder.Publisher, trackID); err != nil {
+			s.logger.Debugw("failed to request keyframe for added track",
+				"peer_id", peerID,
+				"track_id", trackID,
+				"error", err,
+			)
+		}
+	}
+}
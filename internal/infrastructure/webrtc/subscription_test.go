@@ -0,0 +1,192 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFU_UpdateSubscriptionAddsTrackWithOneRenegotiation(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	streamID := domain.StreamID("picker-stream")
+	publisherID := domain.PeerID("picker-publisher")
+	_, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+
+	addForwarder := func(id string) *TrackForwarder {
+		track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, id, id)
+		require.NoError(t, err)
+		forwarder := &TrackForwarder{
+			TrackID:     domain.TrackID(id),
+			Publisher:   publisherID,
+			StreamID:    streamID,
+			Track:       track,
+			Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+		}
+		sfu.mu.Lock()
+		sfu.trackForwarders[forwarder.TrackID] = forwarder
+		sfu.mu.Unlock()
+		return forwarder
+	}
+	addForwarder("camera")
+
+	subscriberID := domain.PeerID("picker-viewer")
+	offer, err := sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+	require.NoError(t, err)
+
+	client, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer client.Close()
+	received := make(chan string, 4)
+	client.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		received <- track.ID()
+	})
+	answer := func(offer webrtc.SessionDescription) webrtc.SessionDescription {
+		require.NoError(t, client.SetRemoteDescription(offer))
+		answer, err := client.CreateAnswer(nil)
+		require.NoError(t, err)
+		gathered := webrtc.GatheringCompletePromise(client)
+		require.NoError(t, client.SetLocalDescription(answer))
+		<-gathered
+		return *client.LocalDescription()
+	}
+	require.NoError(t, sfu.HandleSubscriberAnswer(ctx, subscriberID, answer(offer)))
+
+	_, err = sfu.UpdateSubscription(ctx, subscriberID, []domain.TrackID{"missing"}, nil)
+	require.ErrorIs(t, err, domain.ErrTrackNotFound)
+	_, err = sfu.UpdateSubscription(ctx, "unknown", nil, nil)
+	require.ErrorIs(t, err, domain.ErrPeerNotFound)
+
+	// Both changes are negotiated by a single offer
+	screen := addForwarder("screen")
+	updated, err := sfu.UpdateSubscription(ctx, subscriberID, []domain.TrackID{"screen"}, []domain.TrackID{"camera"})
+	require.NoError(t, err)
+	require.Equal(t, webrtc.SDPTypeOffer, updated.Type)
+	require.Contains(t, updated.SDP, "screen")
+	pending, ok := sfu.PendingRenegotiation(subscriberID)
+	require.True(t, ok)
+	require.Equal(t, updated.SDP, pending.SDP)
+	require.Equal(t, 1, strings.Count(updated.SDP, "a=msid:screen"))
+
+	// A second update must wait for the answer
+	_, err = sfu.UpdateSubscription(ctx, subscriberID, nil, []domain.TrackID{"screen"})
+	require.ErrorIs(t, err, domain.ErrRenegotiationPending)
+
+	// The added track's keyframe is requested once the subscriber can receive it
+	screen.Mu.RLock()
+	requested := !screen.lastKeyframeRequest.IsZero()
+	screen.Mu.RUnlock()
+	require.False(t, requested, "keyframe requested before the answer")
+
+	require.NoError(t, sfu.HandleSubscriberAnswer(ctx, subscriberID, answer(updated)))
+	_, ok = sfu.PendingRenegotiation(subscriberID)
+	require.False(t, ok)
+
+	screen.Mu.RLock()
+	requested = !screen.lastKeyframeRequest.IsZero()
+	screen.Mu.RUnlock()
+	require.True(t, requested, "no keyframe requested after the answer")
+
+	screen.Mu.RLock()
+	_, forwarded := screen.Subscribers[subscriberID]
+	screen.Mu.RUnlock()
+	require.True(t, forwarded)
+
+	// Media written to the added track reaches the subscriber
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		var seq uint16
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				seq++
+				_ = screen.Track.WriteRTP(&rtp.Packet{
+					Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: seq, Timestamp: uint32(seq) * 3000, SSRC: 4242},
+					Payload: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a},
+				})
+			}
+		}
+	}()
+
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case id := <-received:
+			if id == "screen" {
+				return
+			}
+		case <-deadline:
+			t.Fatal("added track was not delivered to the subscriber")
+		}
+	}
+}
+
+func TestSFU_ConcurrentUpdateSubscriptionsRenegotiateOnce(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{})
+
+	streamID := domain.StreamID("picker-race-stream")
+	publisherID := domain.PeerID("picker-race-publisher")
+	_, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+
+	subscriberID := domain.PeerID("picker-race-viewer")
+	offer, err := sfu.CreateSubscriberOffer(ctx, subscriberID, streamID, nil)
+	require.NoError(t, err)
+	require.NoError(t, sfu.HandleSubscriberAnswer(ctx, subscriberID, answerOffer(t, offer)))
+
+	// Published after the subscriber joined, so each update has a track to add
+	trackIDs := make([]domain.TrackID, 8)
+	for i := range trackIDs {
+		id := fmt.Sprintf("track-%d", i)
+		track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, id, id)
+		require.NoError(t, err)
+		trackIDs[i] = domain.TrackID(id)
+		sfu.mu.Lock()
+		sfu.trackForwarders[trackIDs[i]] = &TrackForwarder{
+			TrackID:     trackIDs[i],
+			Publisher:   publisherID,
+			StreamID:    streamID,
+			Track:       track,
+			Subscribers: make(map[domain.PeerID]*webrtc.PeerConnection),
+		}
+		sfu.mu.Unlock()
+	}
+
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+	)
+	for _, trackID := range trackIDs {
+		wg.Add(1)
+		go func(trackID domain.TrackID) {
+			defer wg.Done()
+			_, err := sfu.UpdateSubscription(ctx, subscriberID, []domain.TrackID{trackID}, nil)
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			require.ErrorIs(t, err, domain.ErrRenegotiationPending)
+		}(trackID)
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), succeeded.Load())
+}
@@ -0,0 +1,100 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+)
+
+// maxSVCTemporalLayer is the highest temporal layer ID a VP9 payload
+// descriptor can carry
+const maxSVCTemporalLayer = 7
+
+// LayerSelector thins an SVC-encoded track, whose layers share one RTP
+// stream, by dropping the packets of unwanted layers instead of switching
+// tracks as for simulcast. Select reports whether a packet is forwarded and
+// renumbers forwarded packets so the dropped ones leave no sequence gap.
+// A selector belongs to one forwarding goroutine.
+type LayerSelector interface {
+	Select(packet *rtp.Packet) bool
+	// SetMaxTemporalLayer forwards temporal layers up to tid; negative forwards all
+	SetMaxTemporalLayer(tid int)
+}
+
+// newLayerSelector returns the layer selector for a codec, or nil when the
+// codec has none. Only VP9 temporal layers are selected so far.
+func newLayerSelector(mimeType string) LayerSelector {
+	if strings.EqualFold(mimeType, webrtc.MimeTypeVP9) {
+		return &vp9TemporalSelector{maxTemporal: -1}
+	}
+	return nil
+}
+
+// vp9TemporalSelector drops VP9 packets above a temporal layer, read from
+// the payload descriptor's layer indices. Packets without layer indices are
+// not SVC and always forwarded.
+type vp9TemporalSelector struct {
+	maxTemporal int
+	dropped     uint16 // Packets dropped so far, taken off later sequence numbers
+	descriptor  codecs.VP9Packet
+}
+
+func (v *vp9TemporalSelector) SetMaxTemporalLayer(tid int) {
+	v.maxTemporal = tid
+}
+
+func (v *vp9TemporalSelector) Select(packet *rtp.Packet) bool {
+	if v.maxTemporal >= 0 {
+		_, err := v.descriptor.Unmarshal(packet.Payload)
+		if err == nil && v.descriptor.L && int(v.descriptor.TID) > v.maxTemporal {
+			v.dropped++
+			return false
+		}
+	}
+	packet.SequenceNumber -= v.dropped
+	return true
+}
+
+// SetSVCTemporalLayer limits the stream's SVC video to temporal layers up to
+// tid, lowering its frame rate for every subscriber; a negative tid forwards
+// all layers again. Raising the limit takes effect at once, so decoders may
+// wait for the next switching point.
+func (s *SFUService) SetSVCTemporalLayer(streamID domain.StreamID, tid int) error {
+	if tid > maxSVCTemporalLayer {
+		return fmt.Errorf("temporal layer %d out of range (max %d)", tid, maxSVCTemporalLayer)
+	}
+
+	s.svcLayersMu.Lock()
+	defer s.svcLayersMu.Unlock()
+	if tid < 0 {
+		delete(s.svcTemporalLayers, streamID)
+	} else {
+		s.svcTemporalLayers[streamID] = tid
+	}
+	return nil
+}
+
+// SVCTemporalLayer returns the stream's temporal layer limit, -1 unless set
+func (s *SFUService) SVCTemporalLayer(streamID domain.StreamID) int {
+	s.svcLayersMu.RLock()
+	defer s.svcLayersMu.RUnlock()
+	if tid, ok := s.svcTemporalLayers[streamID]; ok {
+		return tid
+	}
+	return -1
+}
+
+// selectSVCLayers reports whether a forwarder's packet belongs to the SVC
+// layers its stream forwards; packets of non-SVC codecs always do
+func (s *SFUService) selectSVCLayers(forwarder *TrackForwarder, packet *rtp.Packet) bool {
+	if forwarder.svc == nil {
+		return true
+	}
+	forwarder.svc.SetMaxTemporalLayer(s.SVCTemporalLayer(forwarder.StreamID))
+	return forwarder.svc.Select(packet)
+}
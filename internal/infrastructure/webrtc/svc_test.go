@@ -0,0 +1,72 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// vp9SVCPacket builds a non-flexible VP9 packet carrying layer indices for
+// temporal layer tid
+func vp9SVCPacket(seq uint16, tid uint8) *rtp.Packet {
+	return &rtp.Packet{
+		Header: rtp.Header{Version: 2, SequenceNumber: seq},
+		// I=0 P=0 L=1 F=0 B=1 E=1; layer indices; TL0PICIDX; payload
+		Payload: []byte{0x2c, tid << 5, 0x00, 0xaa},
+	}
+}
+
+func TestVP9TemporalSelector_DropsHigherTemporalLayers(t *testing.T) {
+	selector := newLayerSelector(webrtc.MimeTypeVP9)
+	require.NotNil(t, selector)
+	require.Nil(t, newLayerSelector(webrtc.MimeTypeVP8), "VP8 has no SVC layers to select")
+
+	// A common L1T3 pattern: 0, 2, 1, 2, 0, 2, 1, 2
+	tids := []uint8{0, 2, 1, 2, 0, 2, 1, 2}
+	seq := uint16(100)
+	forward := func() (forwardedTIDs []uint8, seqs []uint16) {
+		for _, tid := range tids {
+			packet := vp9SVCPacket(seq, tid)
+			seq++
+			if selector.Select(packet) {
+				forwardedTIDs = append(forwardedTIDs, tid)
+				seqs = append(seqs, packet.SequenceNumber)
+			}
+		}
+		return forwardedTIDs, seqs
+	}
+
+	forwarded, _ := forward()
+	require.Equal(t, tids, forwarded, "all layers are forwarded until a limit is set")
+
+	selector.SetMaxTemporalLayer(1)
+	forwarded, seqs := forward()
+	require.Equal(t, []uint8{0, 1, 0, 1}, forwarded)
+	require.Equal(t, []uint16{108, 109, 110, 111}, seqs, "dropped packets must leave no sequence gap")
+
+	selector.SetMaxTemporalLayer(0)
+	forwarded, _ = forward()
+	require.Equal(t, []uint8{0, 0}, forwarded)
+
+	// Packets without layer indices are not SVC and always pass
+	plain := &rtp.Packet{Header: rtp.Header{SequenceNumber: 200}, Payload: []byte{0x0c, 0xaa}}
+	require.True(t, selector.Select(plain))
+}
+
+func TestSFU_SetSVCTemporalLayerIsPerStream(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{})
+
+	require.Error(t, sfu.SetSVCTemporalLayer("svc-stream", maxSVCTemporalLayer+1))
+	require.NoError(t, sfu.SetSVCTemporalLayer("svc-stream", 1))
+	require.Equal(t, 1, sfu.SVCTemporalLayer("svc-stream"))
+	require.Equal(t, -1, sfu.SVCTemporalLayer("other-stream"))
+
+	forwarder := &TrackForwarder{StreamID: "svc-stream", svc: newLayerSelector(webrtc.MimeTypeVP9)}
+	require.True(t, sfu.selectSVCLayers(forwarder, vp9SVCPacket(1, 1)))
+	require.False(t, sfu.selectSVCLayers(forwarder, vp9SVCPacket(2, 2)))
+
+	require.NoError(t, sfu.SetSVCTemporalLayer("svc-stream", -1))
+	require.True(t, sfu.selectSVCLayers(forwarder, vp9SVCPacket(3, 2)))
+}
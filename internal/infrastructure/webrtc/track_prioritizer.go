@@ -1,10 +1,13 @@
 package webrtc
 
 import (
+	"strings"
 	"sync"
 
 	"rillnet/internal/core/domain"
+
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
 )
 
 // TrackPriority represents the priority of a track
@@ -17,6 +20,22 @@ const (
 	PriorityVideoLow                    // Low priority - low quality video
 )
 
+// String returns the priority's metric label
+func (p TrackPriority) String() string {
+	switch p {
+	case PriorityAudio:
+		return "audio"
+	case PriorityVideoKeyframe:
+		return "video_keyframe"
+	case PriorityVideoNormal:
+		return "video_normal"
+	case PriorityVideoLow:
+		return "video_low"
+	default:
+		return "unknown"
+	}
+}
+
 // TrackPrioritizer manages track prioritization for forwarding
 type TrackPrioritizer struct {
 	mu sync.RWMutex
@@ -27,8 +46,13 @@ type TrackPrioritizer struct {
 	// Audio track IDs (highest priority)
 	audioTracks map[domain.TrackID]bool
 	
-	// Keyframe detection state
+	// Video codec (MIME type) per track, selects the keyframe parser
+	codecs map[domain.TrackID]string
+
+	// Keyframe detection state: whether the last packet belonged to a keyframe
 	keyframeState map[domain.TrackID]bool
+	// Whether the last packet ended its frame (RTP marker bit)
+	frameEnded map[domain.TrackID]bool
 }
 
 // NewTrackPrioritizer creates a new track prioritizer
@@ -36,15 +60,19 @@ func NewTrackPrioritizer() *TrackPrioritizer {
 	return &TrackPrioritizer{
 		trackPriorities: make(map[domain.TrackID]TrackPriority),
 		audioTracks:     make(map[domain.TrackID]bool),
+		codecs:          make(map[domain.TrackID]string),
 		keyframeState:   make(map[domain.TrackID]bool),
+		frameEnded:      make(map[domain.TrackID]bool),
 	}
 }
 
-// RegisterTrack registers a track with its priority
-func (tp *TrackPrioritizer) RegisterTrack(trackID domain.TrackID, isAudio bool, quality string) {
+// RegisterTrack registers a track with its priority. mimeType is the track's
+// codec, used to recognise keyframes (VP8, VP9 and H.264 are supported).
+func (tp *TrackPrioritizer) RegisterTrack(trackID domain.TrackID, isAudio bool, quality string, mimeType string) {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
+	tp.codecs[trackID] = strings.ToLower(mimeType)
 	if isAudio {
 		tp.trackPriorities[trackID] = PriorityAudio
 		tp.audioTracks[trackID] = true
@@ -67,77 +95,118 @@ func (tp *TrackPrioritizer) RegisterTrack(trackID domain.TrackID, isAudio bool,
 func (tp *TrackPrioritizer) GetPriority(trackID domain.TrackID) TrackPriority {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
+	return tp.priorityLocked(trackID)
+}
 
+func (tp *TrackPrioritizer) priorityLocked(trackID domain.TrackID) TrackPriority {
 	priority, exists := tp.trackPriorities[trackID]
 	if !exists {
 		return PriorityVideoNormal // Default priority
 	}
 
 	// Check if this is a keyframe
-	if tp.isKeyframe(trackID) {
+	if priority != PriorityAudio && tp.keyframeState[trackID] {
 		return PriorityVideoKeyframe
 	}
 
 	return priority
 }
 
-// isKeyframe checks if the last packet for a track was a keyframe
-func (tp *TrackPrioritizer) isKeyframe(trackID domain.TrackID) bool {
-	tp.mu.RLock()
-	defer tp.mu.RUnlock()
-	return tp.keyframeState[trackID]
-}
-
-// ProcessPacket processes an RTP packet and updates keyframe state
+// ProcessPacket processes an RTP packet and updates keyframe state. A
+// keyframe spans every packet up to the one carrying the marker bit, so all
+// of them take keyframe priority, not only the first.
 func (tp *TrackPrioritizer) ProcessPacket(trackID domain.TrackID, packet *rtp.Packet) {
-	// Check if this is a keyframe (VP8/VP9/H264)
-	isKeyframe := tp.detectKeyframe(packet)
-
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
-	if isKeyframe {
-		tp.keyframeState[trackID] = true
-	} else {
-		// Reset keyframe state after a few non-keyframe packets
-		// This ensures we prioritize keyframes when they arrive
-		tp.keyframeState[trackID] = false
+	isKeyframe := detectKeyframe(tp.codecs[trackID], packet.Payload)
+	continuesKeyframe := tp.keyframeState[trackID] && !tp.frameEnded[trackID]
+
+	tp.keyframeState[trackID] = isKeyframe || continuesKeyframe
+	tp.frameEnded[trackID] = packet.Marker
+}
+
+// detectKeyframe reports whether a payload starts a keyframe in the given codec
+func detectKeyframe(mimeType string, payload []byte) bool {
+	if len(payload) == 0 {
+		return false
+	}
+
+	switch mimeType {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return isVP8Keyframe(payload)
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		// Start of a frame (B) that is not inter-picture predicted (P)
+		return payload[0]&0x40 == 0 && payload[0]&0x08 != 0
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return isH264Keyframe(payload)
+	default:
+		return false
 	}
 }
 
-// detectKeyframe detects if an RTP packet contains a keyframe
-func (tp *TrackPrioritizer) detectKeyframe(packet *rtp.Packet) bool {
-	if len(packet.Payload) == 0 {
+// isVP8Keyframe parses the VP8 payload descriptor (RFC 7741) and checks the
+// P bit of the frame header that follows it, which is 0 for keyframes.
+func isVP8Keyframe(payload []byte) bool {
+	descriptor := payload[0]
+	// Only the first packet of partition 0 carries the frame header
+	if descriptor&0x10 == 0 || descriptor&0x07 != 0 {
 		return false
 	}
 
-	// VP8 keyframe detection
-	// VP8 payload starts with a 1-byte header
-	// Bit 0 (X bit) indicates if extended control bits are present
-	// For keyframes, we check the I bit in the extended control bits
-	if len(packet.Payload) >= 1 {
-		firstByte := packet.Payload[0]
-		
-		// Check if extended control bits are present (X bit)
-		if firstByte&0x80 != 0 && len(packet.Payload) >= 2 {
-			// Extended control bits present
-			// I bit (bit 4) indicates if this is a keyframe
-			extendedByte := packet.Payload[1]
-			if extendedByte&0x10 != 0 {
-				return true // Keyframe
+	i := 1
+	if descriptor&0x80 != 0 { // X: extension byte present
+		if len(payload) <= i {
+			return false
+		}
+		extension := payload[i]
+		i++
+		if extension&0x80 != 0 { // I: picture ID, 7 or 15 bits
+			if len(payload) <= i {
+				return false
 			}
+			if payload[i]&0x80 != 0 {
+				i += 2
+			} else {
+				i++
+			}
+		}
+		if extension&0x40 != 0 { // L: TL0PICIDX
+			i++
+		}
+		if extension&0x30 != 0 { // T or K: TID/KEYIDX byte
+			i++
 		}
 	}
 
-	// H.264 keyframe detection (NAL unit type 5 = IDR frame)
-	if len(packet.Payload) >= 1 {
-		nalType := packet.Payload[0] & 0x1F
-		if nalType == 5 {
-			return true // IDR frame (keyframe)
-		}
+	if len(payload) <= i {
+		return false
+	}
+	return payload[i]&0x01 == 0
+}
+
+// isH264Keyframe looks for an IDR slice or SPS in single NAL, STAP-A and FU-A packets
+func isH264Keyframe(payload []byte) bool {
+	isKeyNAL := func(nalType byte) bool {
+		return nalType == 5 || nalType == 7 // IDR slice, SPS ahead of an IDR
 	}
 
-	return false
+	switch nalType := payload[0] & 0x1F; nalType {
+	case 24: // STAP-A: NAL units each prefixed by a 16-bit size
+		for i := 1; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			i += 2
+			if isKeyNAL(payload[i] & 0x1F) {
+				return true
+			}
+			i += size
+		}
+		return false
+	case 28: // FU-A: the start fragment carries the fragmented NAL's type
+		return len(payload) >= 2 && payload[1]&0x80 != 0 && isKeyNAL(payload[1]&0x1F)
+	default:
+		return isKeyNAL(nalType)
+	}
 }
 
 // ShouldForward determines if a packet should be forwarded based on priority and current load
@@ -176,7 +245,7 @@ func (tp *TrackPrioritizer) GetForwardOrder(trackIDs []domain.TrackID) []domain.
 	// Sort by priority
 	priorities := make(map[domain.TrackID]TrackPriority)
 	for _, trackID := range trackIDs {
-		priorities[trackID] = tp.GetPriority(trackID)
+		priorities[trackID] = tp.priorityLocked(trackID)
 	}
 
 	// Simple insertion sort by priority
@@ -204,6 +273,8 @@ func (tp *TrackPrioritizer) UnregisterTrack(trackID domain.TrackID) {
 
 	delete(tp.trackPriorities, trackID)
 	delete(tp.audioTracks, trackID)
+	delete(tp.codecs, trackID)
 	delete(tp.keyframeState, trackID)
+	delete(tp.frameEnded, trackID)
 }
 
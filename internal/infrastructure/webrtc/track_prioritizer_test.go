@@ -0,0 +1,179 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+
+	"rillnet/internal/core/domain"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// VP8 descriptor with S=1, PID=0 followed by a frame header with P=0
+	vp8KeyframeStart = []byte{0x10, 0x00, 0x9d, 0x01, 0x2a}
+	// Same with an extension byte and a 15-bit picture ID
+	vp8KeyframeStartExtended = []byte{0x90, 0x80, 0x81, 0x23, 0x00, 0x9d}
+	// Later packet of the same frame (S=0)
+	vp8Continuation = []byte{0x00, 0xaa, 0xbb}
+	// Interframe: frame header with P=1
+	vp8DeltaStart = []byte{0x10, 0x01, 0xaa}
+)
+
+func rtpPacket(payload []byte, marker bool) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{Marker: marker}, Payload: payload}
+}
+
+func TestDetectKeyframe(t *testing.T) {
+	vp8 := "video/vp8"
+	h264 := "video/h264"
+
+	require.True(t, detectKeyframe(vp8, vp8KeyframeStart))
+	require.True(t, detectKeyframe(vp8, vp8KeyframeStartExtended))
+	require.False(t, detectKeyframe(vp8, vp8Continuation))
+	require.False(t, detectKeyframe(vp8, vp8DeltaStart))
+
+	require.True(t, detectKeyframe(h264, []byte{0x65, 0x88}))                   // IDR slice
+	require.True(t, detectKeyframe(h264, []byte{0x78, 0x00, 0x02, 0x67, 0x42})) // STAP-A with SPS
+	require.True(t, detectKeyframe(h264, []byte{0x7c, 0x85, 0x88}))             // FU-A start of IDR
+	require.False(t, detectKeyframe(h264, []byte{0x7c, 0x05, 0x88}))            // FU-A middle of IDR
+	require.False(t, detectKeyframe(h264, []byte{0x41, 0x9a}))                  // Non-IDR slice
+
+	require.False(t, detectKeyframe("video/av1", vp8KeyframeStart))
+}
+
+func TestTrackPrioritizer_AudioAlwaysForwarded(t *testing.T) {
+	tp := NewTrackPrioritizer()
+	tp.RegisterTrack("audio", true, "", webrtc.MimeTypeOpus)
+
+	for _, load := range []float64{0, 80, 95, 1000} {
+		tp.ProcessPacket("audio", rtpPacket([]byte{0x01, 0x02}, false))
+		require.True(t, tp.ShouldForward("audio", load, 100), "load %v", load)
+	}
+}
+
+func TestTrackPrioritizer_KeyframesAlwaysForwarded(t *testing.T) {
+	tp := NewTrackPrioritizer()
+	tp.RegisterTrack("video/low", false, "low", webrtc.MimeTypeVP8)
+	const load, maxLoad = 95.0, 100.0
+
+	// Every packet of the keyframe, up to the marker, is forwarded
+	tp.ProcessPacket("video/low", rtpPacket(vp8KeyframeStart, false))
+	require.True(t, tp.ShouldForward("video/low", load, maxLoad))
+	tp.ProcessPacket("video/low", rtpPacket(vp8Continuation, false))
+	require.True(t, tp.ShouldForward("video/low", load, maxLoad))
+	tp.ProcessPacket("video/low", rtpPacket(vp8Continuation, true))
+	require.True(t, tp.ShouldForward("video/low", load, maxLoad))
+
+	// The next frame is an interframe and is shed
+	tp.ProcessPacket("video/low", rtpPacket(vp8DeltaStart, false))
+	require.False(t, tp.ShouldForward("video/low", load, maxLoad))
+	tp.ProcessPacket("video/low", rtpPacket(vp8Continuation, true))
+	require.False(t, tp.ShouldForward("video/low", load, maxLoad))
+}
+
+func TestTrackPrioritizer_ShedsVideoByLoad(t *testing.T) {
+	tp := NewTrackPrioritizer()
+	tp.RegisterTrack("video/low", false, "low", webrtc.MimeTypeVP8)
+	tp.RegisterTrack("video/high", false, "high", webrtc.MimeTypeVP8)
+	for _, id := range []domain.TrackID{"video/low", "video/high"} {
+		tp.ProcessPacket(id, rtpPacket(vp8DeltaStart, true))
+	}
+
+	require.True(t, tp.ShouldForward("video/low", 50, 100))
+	require.True(t, tp.ShouldForward("video/high", 50, 100))
+
+	require.False(t, tp.ShouldForward("video/low", 80, 100))
+	require.True(t, tp.ShouldForward("video/high", 80, 100))
+
+	require.False(t, tp.ShouldForward("video/high", 95, 100))
+}
+
+func TestSFU_AdmitPacketShedsVideoUnderLoad(t *testing.T) {
+	sfu := newTestSFU(WebRTCConfig{MaxForwardedStreams: 1})
+
+	// One subscriber track puts the SFU at full load
+	sfu.mu.Lock()
+	sfu.trackForwarders["video"] = &TrackForwarder{
+		TrackID:     "video",
+		Subscribers: map[domain.PeerID]*webrtc.PeerConnection{"viewer": nil},
+	}
+	sfu.mu.Unlock()
+	sfu.prioritizer.RegisterTrack("audio", true, "", webrtc.MimeTypeOpus)
+	sfu.prioritizer.RegisterTrack("video", false, "", webrtc.MimeTypeVP8)
+
+	require.True(t, sfu.admitPacket("audio", rtpPacket([]byte{0x01}, false), 0))
+	require.True(t, sfu.admitPacket("video", rtpPacket(vp8KeyframeStart, true), 0))
+	require.False(t, sfu.admitPacket("video", rtpPacket(vp8DeltaStart, true), 0))
+
+	// Shedding is off without a configured capacity
+	unlimited := newTestSFU(WebRTCConfig{})
+	unlimited.prioritizer.RegisterTrack("video", false, "", webrtc.MimeTypeVP8)
+	require.True(t, unlimited.admitPacket("video", rtpPacket(vp8DeltaStart, true), 0))
+}
+
+func TestSFU_LowerPriorityStreamsAreShedFirst(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{MaxForwardedStreams: 1})
+	t.Cleanup(func() { forgetDroppedPackets("free") })
+	require.NoError(t, sfu.SetStreamPriority(ctx, "premium", 4))
+	require.ErrorIs(t, sfu.SetStreamPriority(ctx, "premium", -1), domain.ErrInvalidStreamPriority)
+
+	// One subscriber track puts the SFU at full load
+	sfu.mu.Lock()
+	for _, streamID := range []domain.StreamID{"free", "premium"} {
+		sfu.trackForwarders[domain.TrackID(streamID)] = &TrackForwarder{
+			TrackID:     domain.TrackID(streamID),
+			StreamID:    streamID,
+			Subscribers: map[domain.PeerID]*webrtc.PeerConnection{},
+		}
+	}
+	sfu.trackForwarders["free"].Subscribers["viewer"] = nil
+	sfu.mu.Unlock()
+	sfu.prioritizer.RegisterTrack("free", false, "", webrtc.MimeTypeVP8)
+	sfu.prioritizer.RegisterTrack("premium", false, "", webrtc.MimeTypeVP8)
+
+	require.False(t, sfu.admitForwarded(sfu.trackForwarders["free"], rtpPacket(vp8DeltaStart, true)))
+	require.True(t, sfu.admitForwarded(sfu.trackForwarders["premium"], rtpPacket(vp8DeltaStart, true)))
+
+	// Stopping the stream drops its priority
+	require.NoError(t, sfu.CloseStream(ctx, "premium"))
+	require.Zero(t, sfu.streamPriority("premium"))
+}
+
+func TestSFU_ShedPacketsAreCountedByStreamAndPriority(t *testing.T) {
+	ctx := context.Background()
+	sfu := newTestSFU(WebRTCConfig{MaxForwardedStreams: 1})
+
+	streamID := domain.StreamID("shed-stream")
+	publisherID := domain.PeerID("shed-publisher")
+	_, err := sfu.CreatePublisherOffer(ctx, publisherID, streamID)
+	require.NoError(t, err)
+
+	// One subscriber track puts the SFU at full load
+	forwarder := &TrackForwarder{
+		TrackID:     "video/low",
+		Publisher:   publisherID,
+		StreamID:    streamID,
+		Subscribers: map[domain.PeerID]*webrtc.PeerConnection{"viewer": nil},
+	}
+	sfu.mu.Lock()
+	sfu.trackForwarders[forwarder.TrackID] = forwarder
+	sfu.mu.Unlock()
+	sfu.prioritizer.RegisterTrack(forwarder.TrackID, false, "low", webrtc.MimeTypeVP8)
+
+	dropped := forwarderDroppedPackets.WithLabelValues(string(streamID), "video_low")
+	before := testutil.ToFloat64(dropped)
+
+	require.True(t, sfu.admitForwarded(forwarder, rtpPacket(vp8KeyframeStart, true)))
+	require.False(t, sfu.admitForwarded(forwarder, rtpPacket(vp8DeltaStart, true)))
+	require.False(t, sfu.admitForwarded(forwarder, rtpPacket(vp8DeltaStart, true)))
+	require.Equal(t, before+2, testutil.ToFloat64(dropped))
+
+	// The stream's counters go away when it ends
+	sfu.handlePeerDisconnect(publisherID)
+	require.Zero(t, testutil.CollectAndCount(forwarderDroppedPackets, "rillnet_forwarder_dropped_packets_total"))
+}
@@ -0,0 +1,281 @@
+package wiring
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	"rillnet/internal/infrastructure/reliability"
+	"rillnet/pkg/circuitbreaker"
+	"rillnet/pkg/config"
+	"rillnet/pkg/retry"
+
+	"go.uber.org/zap"
+)
+
+// Role selects which process a container is built for
+type Role string
+
+const (
+	// RoleIngest hosts the SFU and the HTTP API
+	RoleIngest Role = "ingest"
+	// RoleSignal serves WebSocket signaling and only validates tokens
+	RoleSignal Role = "signal"
+)
+
+// ErrMissingDependency is returned by Build when a dependency the role
+// requires was not provided
+var ErrMissingDependency = errors.New("missing required dependency")
+
+// ErrUnknownRole is returned by Build for a role other than RoleIngest or RoleSignal
+var ErrUnknownRole = errors.New("unknown role")
+
+// Container holds the core services of one process, wired from the
+// dependencies given to the Builder
+type Container struct {
+	Role          Role
+	MeshService   ports.MeshService
+	StreamService ports.StreamService
+	AuthService   services.AuthService
+	// MetricsService and QualityService are only built for RoleIngest
+	MetricsService *services.MetricsService
+	QualityService *services.QualityService
+}
+
+// Builder collects the dependencies of a Container and checks them against
+// the role before constructing any service. The services' own constructors
+// remain usable directly; the builder only saves each process from repeating
+// the wiring.
+type Builder struct {
+	role            Role
+	cfg             *config.Config
+	logger          *zap.SugaredLogger
+	streamRepo      ports.StreamRepository
+	peerRepo        ports.PeerRepository
+	meshRepo        ports.MeshRepository
+	userRepo        ports.UserRepository
+	refreshRepo     ports.RefreshTokenRepository
+	refreshFamilies ports.RefreshTokenFamilyStore
+}
+
+// NewBuilder creates a builder for the given role
+func NewBuilder(role Role) *Builder {
+	return &Builder{role: role}
+}
+
+// WithConfig sets the loaded configuration
+func (b *Builder) WithConfig(cfg *config.Config) *Builder {
+	b.cfg = cfg
+	return b
+}
+
+// WithLogger sets the logger passed to the services
+func (b *Builder) WithLogger(logger *zap.SugaredLogger) *Builder {
+	b.logger = logger
+	return b
+}
+
+// WithStreamRepository sets the stream repository
+func (b *Builder) WithStreamRepository(repo ports.StreamRepository) *Builder {
+	b.streamRepo = repo
+	return b
+}
+
+// WithPeerRepository sets the peer repository
+func (b *Builder) WithPeerRepository(repo ports.PeerRepository) *Builder {
+	b.peerRepo = repo
+	return b
+}
+
+// WithMeshRepository sets the mesh repository
+func (b *Builder) WithMeshRepository(repo ports.MeshRepository) *Builder {
+	b.meshRepo = repo
+	return b
+}
+
+// WithUserRepository sets the user repository behind login and registration
+func (b *Builder) WithUserRepository(repo ports.UserRepository) *Builder {
+	b.userRepo = repo
+	return b
+}
+
+// WithRefreshTokenRepository sets the repository persisting refresh tokens
+func (b *Builder) WithRefreshTokenRepository(repo ports.RefreshTokenRepository) *Builder {
+	b.refreshRepo = repo
+	return b
+}
+
+// WithRefreshTokenFamilyStore sets the store that lets refresh token
+// rotation detect a replayed token. Optional.
+func (b *Builder) WithRefreshTokenFamilyStore(store ports.RefreshTokenFamilyStore) *Builder {
+	b.refreshFamilies = store
+	return b
+}
+
+// dependency is one input a role requires
+type dependency struct {
+	name    string
+	missing bool
+}
+
+// Validate reports the first dependency the role requires that is missing.
+// The ingest role needs the user and refresh token repositories only when
+// the database is enabled, since they are backed by it.
+func (b *Builder) Validate() error {
+	if b.role != RoleIngest && b.role != RoleSignal {
+		return fmt.Errorf("%w: %q", ErrUnknownRole, b.role)
+	}
+
+	required := []dependency{
+		{"config", b.cfg == nil},
+		{"logger", b.logger == nil},
+		{"stream repository", b.streamRepo == nil},
+		{"peer repository", b.peerRepo == nil},
+		{"mesh repository", b.meshRepo == nil},
+	}
+	if b.role == RoleIngest && b.cfg != nil && b.cfg.Database.Enabled {
+		required = append(required,
+			dependency{"user repository", b.userRepo == nil},
+			dependency{"refresh token repository", b.refreshRepo == nil},
+		)
+	}
+
+	for _, dep := range required {
+		if dep.missing {
+			return fmt.Errorf("%w: %s for role %s", ErrMissingDependency, dep.name, b.role)
+		}
+	}
+	return nil
+}
+
+// Build validates the dependencies and constructs the role's services
+func (b *Builder) Build() (*Container, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	if b.role == RoleSignal {
+		return b.buildSignal(), nil
+	}
+	return b.buildIngest()
+}
+
+func (b *Builder) buildIngest() (*Container, error) {
+	cfg := b.cfg
+
+	apiKeys, err := apiKeysFromConfig(cfg.Auth.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	qualityService := services.NewQualityService()
+	metricsService := services.NewMetricsService()
+	metricsService.SetHealthScoreConfig(cfg.Streams.Health)
+	baseMeshService := services.NewMeshService(b.peerRepo, b.meshRepo, b.streamRepo, cfg.Mesh, b.logger)
+
+	// Wrap mesh service with retry and circuit breaker if enabled
+	meshService := baseMeshService
+	if cfg.Retry.Enabled || cfg.CircuitBreaker.Enabled {
+		retryCfg := retry.Config{
+			Enabled:      cfg.Retry.Enabled,
+			MaxAttempts:  cfg.Retry.MaxAttempts,
+			InitialDelay: cfg.Retry.InitialDelay,
+			MaxDelay:     cfg.Retry.MaxDelay,
+			Multiplier:   cfg.Retry.Multiplier,
+			Jitter:       cfg.Retry.Jitter,
+		}
+		cbCfg := circuitbreaker.Config{
+			FailureThreshold:    cfg.CircuitBreaker.FailureThreshold,
+			SuccessThreshold:    cfg.CircuitBreaker.SuccessThreshold,
+			Timeout:             cfg.CircuitBreaker.Timeout,
+			MaxRequestsHalfOpen: cfg.CircuitBreaker.MaxRequestsHalfOpen,
+			WindowSize:          cfg.CircuitBreaker.WindowSize,
+			FailureRatio:        cfg.CircuitBreaker.FailureRatio,
+		}
+		meshService = reliability.NewMeshServiceWrapper(baseMeshService, retryCfg, cbCfg, b.logger)
+	}
+
+	streamService := services.NewStreamServiceWithConfig(b.streamRepo, b.peerRepo, b.meshRepo, meshService, metricsService, cfg.Streams, nil)
+	// Egress reserved at admission is freed however a peer leaves the mesh
+	if observer, ok := streamService.(ports.PeerRemovalObserver); ok {
+		baseMeshService.(services.PeerRemovalHooks).SetRemovalObserver(observer)
+	}
+
+	userRoles := make(map[domain.UserID]domain.UserRole, len(cfg.Auth.UserRoles))
+	for userID, role := range cfg.Auth.UserRoles {
+		userRoles[domain.UserID(userID)] = domain.UserRole(role)
+	}
+	authService := services.NewAuthServiceWithRoles(
+		cfg.Auth.JWTSecret,
+		cfg.Auth.AccessTokenTTL,
+		cfg.Auth.RefreshTokenTTL,
+		streamService,
+		b.userRepo,
+		b.refreshRepo,
+		userRoles,
+	)
+	if b.refreshFamilies != nil {
+		authService.(services.RefreshRotationHooks).SetRefreshTokenStore(b.refreshFamilies)
+	}
+	authService.(services.APIKeyHooks).SetAPIKeys(apiKeys)
+
+	return &Container{
+		Role:           RoleIngest,
+		MeshService:    meshService,
+		StreamService:  streamService,
+		AuthService:    authService,
+		MetricsService: metricsService,
+		QualityService: qualityService,
+	}, nil
+}
+
+// apiKeysFromConfig decodes the configured API key digests
+func apiKeysFromConfig(keys []config.APIKeyConfig) ([]services.APIKey, error) {
+	apiKeys := make([]services.APIKey, 0, len(keys))
+	for i, key := range keys {
+		digest, err := hex.DecodeString(key.SHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("auth.api_keys[%d]: invalid SHA-256 digest", i)
+		}
+		apiKey := services.APIKey{UserID: domain.UserID(key.UserID), Role: domain.UserRole(key.Role)}
+		copy(apiKey.Hash[:], digest)
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, nil
+}
+
+func (b *Builder) buildSignal() *Container {
+	cfg := b.cfg
+
+	meshService := services.NewMeshService(b.peerRepo, b.meshRepo, b.streamRepo, cfg.Mesh, b.logger)
+
+	// Stream service for join admission and the stream permission checks on signaling messages
+	streamCfg := cfg.Streams
+	streamCfg.MaxStreams = 0    // The instance cap is for the ingest servers hosting the media
+	streamCfg.StatsInterval = 0 // Stream stats are served by the ingest servers
+	streamService := services.NewStreamServiceWithConfig(b.streamRepo, b.peerRepo, b.meshRepo, meshService, services.NewMetricsService(), streamCfg, nil)
+	if observer, ok := streamService.(ports.PeerRemovalObserver); ok {
+		meshService.(services.PeerRemovalHooks).SetRemovalObserver(observer)
+	}
+
+	// Signaling only validates tokens, so no user or refresh token store
+	authService := services.NewAuthService(
+		cfg.Auth.JWTSecret,
+		cfg.Auth.AccessTokenTTL,
+		cfg.Auth.RefreshTokenTTL,
+		streamService,
+		nil,
+		nil,
+	)
+
+	return &Container{
+		Role:          RoleSignal,
+		MeshService:   meshService,
+		StreamService: streamService,
+		AuthService:   authService,
+	}
+}
@@ -0,0 +1,65 @@
+package wiring
+
+import (
+	"testing"
+
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func signalBuilder() *Builder {
+	return NewBuilder(RoleSignal).
+		WithConfig(config.DefaultConfig()).
+		WithLogger(zap.NewNop().Sugar()).
+		WithStreamRepository(memory.NewMemoryStreamRepository()).
+		WithPeerRepository(memory.NewMemoryPeerRepository()).
+		WithMeshRepository(memory.NewMemoryMeshRepository())
+}
+
+func TestBuilder_SignalRejectsMissingDependency(t *testing.T) {
+	container, err := signalBuilder().WithPeerRepository(nil).Build()
+
+	require.Nil(t, container)
+	require.ErrorIs(t, err, ErrMissingDependency)
+	require.Contains(t, err.Error(), "peer repository")
+	require.Contains(t, err.Error(), string(RoleSignal))
+}
+
+func TestBuilder_SignalBuildsWithValidDependencies(t *testing.T) {
+	container, err := signalBuilder().Build()
+
+	require.NoError(t, err)
+	require.Equal(t, RoleSignal, container.Role)
+	require.NotNil(t, container.MeshService)
+	require.NotNil(t, container.StreamService)
+	require.NotNil(t, container.AuthService)
+	// Stream stats and quality are served by the ingest servers
+	require.Nil(t, container.MetricsService)
+	require.Nil(t, container.QualityService)
+}
+
+func TestBuilder_IngestRequiresUserStoreWithDatabase(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Database.Enabled = true
+
+	_, err := signalBuilder().WithConfig(cfg).Build()
+	require.NoError(t, err, "signal never needs the user store")
+
+	builder := NewBuilder(RoleIngest).
+		WithConfig(cfg).
+		WithLogger(zap.NewNop().Sugar()).
+		WithStreamRepository(memory.NewMemoryStreamRepository()).
+		WithPeerRepository(memory.NewMemoryPeerRepository()).
+		WithMeshRepository(memory.NewMemoryMeshRepository())
+	_, err = builder.Build()
+	require.ErrorIs(t, err, ErrMissingDependency)
+	require.Contains(t, err.Error(), "user repository")
+}
+
+func TestBuilder_RejectsUnknownRole(t *testing.T) {
+	_, err := NewBuilder("monitor").Build()
+	require.ErrorIs(t, err, ErrUnknownRole)
+}
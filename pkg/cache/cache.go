@@ -202,6 +202,16 @@ func (c *CacheWithFallback) GetOrSet(ctx context.Context, key string, fallback f
 	return value, nil
 }
 
+// Set stores a value computed outside GetOrSet, e.g. by a background
+// refresh, with ttl (0 = default TTL)
+func (c *CacheWithFallback) Set(key string, value interface{}, ttl time.Duration) {
+	if ttl > 0 {
+		c.cache.SetWithTTL(key, value, ttl)
+	} else {
+		c.cache.Set(key, value)
+	}
+}
+
 // Invalidate invalidates cache entries matching pattern
 func (c *CacheWithFallback) Invalidate(pattern string) {
 	c.cache.Invalidate(pattern)
@@ -2,11 +2,15 @@ package circuitbreaker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 )
 
+// ErrRejected is returned, wrapped, when the circuit does not let a request through
+var ErrRejected = errors.New("request rejected")
+
 // State represents the circuit breaker state
 type State int
 
@@ -35,6 +39,10 @@ type Config struct {
 	SuccessThreshold    int           // Number of successes in half-open state to close circuit
 	Timeout             time.Duration // Time to wait before transitioning from open to half-open
 	MaxRequestsHalfOpen int           // Max requests allowed in half-open state
+	// WindowSize, when > 0, opens the circuit on the failure ratio of the
+	// last WindowSize requests instead of on FailureThreshold consecutive failures
+	WindowSize   int
+	FailureRatio float64 // Failure ratio over a full window above which the circuit opens
 }
 
 // DefaultConfig returns a default circuit breaker configuration
@@ -59,16 +67,27 @@ type CircuitBreaker struct {
 	lastFailureTime   time.Time
 	stateChangeTime   time.Time
 
+	// Outcomes of the last requests in window mode (true = failure), a ring
+	// of WindowSize entries of which windowLen are filled
+	window         []bool
+	windowNext     int
+	windowLen      int
+	windowFailures int
+
 	onStateChange func(from, to State)
 }
 
 // New creates a new circuit breaker with the given configuration
 func New(config Config) *CircuitBreaker {
-	return &CircuitBreaker{
+	cb := &CircuitBreaker{
 		config:        config,
 		state:         StateClosed,
 		stateChangeTime: time.Now(),
 	}
+	if config.WindowSize > 0 {
+		cb.window = make([]bool, config.WindowSize)
+	}
+	return cb
 }
 
 // OnStateChange sets a callback function that is called when the circuit breaker state changes
@@ -82,7 +101,7 @@ func (cb *CircuitBreaker) OnStateChange(fn func(from, to State)) {
 func (cb *CircuitBreaker) Execute(ctx context.Context, fn func() error) error {
 	// Check if request should be allowed
 	if !cb.allowRequest() {
-		return fmt.Errorf("circuit breaker is %s, request rejected", cb.getState())
+		return fmt.Errorf("circuit breaker is %s, %w", cb.getState(), ErrRejected)
 	}
 
 	// Execute the function
@@ -103,7 +122,7 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, fn func() error) error {
 func (cb *CircuitBreaker) ExecuteWithResult(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
 	// Check if request should be allowed
 	if !cb.allowRequest() {
-		return nil, fmt.Errorf("circuit breaker is %s, request rejected", cb.getState())
+		return nil, fmt.Errorf("circuit breaker is %s, %w", cb.getState(), ErrRejected)
 	}
 
 	// Execute the function
@@ -119,6 +138,48 @@ func (cb *CircuitBreaker) ExecuteWithResult(ctx context.Context, fn func() (inte
 	return result, nil
 }
 
+// ExecuteWithFallback executes a function through the circuit breaker like
+// Execute, but when the circuit rejects the request, or fn fails and that
+// failure opens the circuit, it returns what fallback makes of the error.
+func (cb *CircuitBreaker) ExecuteWithFallback(ctx context.Context, fn func() error, fallback func(error) error) error {
+	if !cb.allowRequest() {
+		return fallback(fmt.Errorf("circuit breaker is %s, %w", cb.getState(), ErrRejected))
+	}
+
+	err := fn()
+	if err != nil {
+		opened := cb.onFailure()
+		err = fmt.Errorf("circuit breaker execution failed: %w", err)
+		if opened {
+			return fallback(err)
+		}
+		return err
+	}
+
+	cb.onSuccess()
+	return nil
+}
+
+// ExecuteWithResultFallback is ExecuteWithFallback for functions that return a result
+func (cb *CircuitBreaker) ExecuteWithResultFallback(ctx context.Context, fn func() (interface{}, error), fallback func(error) (interface{}, error)) (interface{}, error) {
+	if !cb.allowRequest() {
+		return fallback(fmt.Errorf("circuit breaker is %s, %w", cb.getState(), ErrRejected))
+	}
+
+	result, err := fn()
+	if err != nil {
+		opened := cb.onFailure()
+		err = fmt.Errorf("circuit breaker execution failed: %w", err)
+		if opened {
+			return fallback(err)
+		}
+		return nil, err
+	}
+
+	cb.onSuccess()
+	return result, nil
+}
+
 // allowRequest checks if a request should be allowed based on current state
 func (cb *CircuitBreaker) allowRequest() bool {
 	cb.mu.Lock()
@@ -148,8 +209,9 @@ func (cb *CircuitBreaker) allowRequest() bool {
 	return true
 }
 
-// onFailure records a failure and updates circuit breaker state
-func (cb *CircuitBreaker) onFailure() {
+// onFailure records a failure, updates circuit breaker state and reports
+// whether the failure opened the circuit
+func (cb *CircuitBreaker) onFailure() bool {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
@@ -160,12 +222,15 @@ func (cb *CircuitBreaker) onFailure() {
 	cb.successCount = 0
 
 	// Transition to open if threshold exceeded
-	if cb.state == StateClosed && cb.failureCount >= cb.config.FailureThreshold {
+	if cb.state == StateClosed && cb.tripped() {
 		cb.transitionTo(StateOpen)
+		return true
 	} else if cb.state == StateHalfOpen {
 		// Any failure in half-open state goes back to open
 		cb.transitionTo(StateOpen)
+		return true
 	}
+	return false
 }
 
 // onSuccess records a success and updates circuit breaker state
@@ -174,7 +239,11 @@ func (cb *CircuitBreaker) onSuccess() {
 	defer cb.mu.Unlock()
 
 	cb.successCount++
-	cb.failureCount = 0 // Reset failure count on success
+	if cb.window != nil && cb.state == StateClosed {
+		cb.record(false)
+	} else {
+		cb.failureCount = 0 // Reset failure count on success
+	}
 
 	// Transition from half-open to closed if threshold met
 	if cb.state == StateHalfOpen && cb.successCount >= cb.config.SuccessThreshold {
@@ -183,6 +252,37 @@ func (cb *CircuitBreaker) onSuccess() {
 	}
 }
 
+// tripped records a failure in the closed state and reports whether the
+// circuit should open. Callers hold cb.mu.
+func (cb *CircuitBreaker) tripped() bool {
+	if cb.window == nil {
+		return cb.failureCount >= cb.config.FailureThreshold
+	}
+	cb.record(true)
+	if cb.windowLen < len(cb.window) {
+		return false
+	}
+	return float64(cb.windowFailures)/float64(cb.windowLen) > cb.config.FailureRatio
+}
+
+// record adds an outcome to the window, evicting the oldest once it is full,
+// and keeps failureCount at the failures in the window. Callers hold cb.mu.
+func (cb *CircuitBreaker) record(failed bool) {
+	if cb.windowLen == len(cb.window) {
+		if cb.window[cb.windowNext] {
+			cb.windowFailures--
+		}
+	} else {
+		cb.windowLen++
+	}
+	cb.window[cb.windowNext] = failed
+	if failed {
+		cb.windowFailures++
+	}
+	cb.windowNext = (cb.windowNext + 1) % len(cb.window)
+	cb.failureCount = cb.windowFailures
+}
+
 // transitionTo transitions the circuit breaker to a new state
 func (cb *CircuitBreaker) transitionTo(newState State) {
 	if cb.state == newState {
@@ -199,6 +299,7 @@ func (cb *CircuitBreaker) transitionTo(newState State) {
 		cb.failureCount = 0
 		cb.successCount = 0
 		cb.halfOpenRequests = 0
+		cb.windowNext, cb.windowLen, cb.windowFailures = 0, 0, 0
 	}
 
 	// Call state change callback
@@ -80,6 +80,9 @@ func TestCircuitBreaker_OpenState_RejectsRequests(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error (circuit open), got nil")
 	}
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected ErrRejected, got: %v", err)
+	}
 }
 
 func TestCircuitBreaker_HalfOpenState_TransitionToClosed(t *testing.T) {
@@ -278,6 +281,99 @@ func TestCircuitBreaker_ExecuteWithResult_OpenState(t *testing.T) {
 	}
 }
 
+func TestCircuitBreaker_ExecuteWithFallback_OpenState(t *testing.T) {
+	cfg := Config{
+		FailureThreshold:    2,
+		SuccessThreshold:    1,
+		Timeout:             time.Minute,
+		MaxRequestsHalfOpen: 1,
+	}
+	cb := New(cfg)
+	ctx := context.Background()
+	errDegraded := errors.New("degraded")
+
+	var fallbackErrs []error
+	fallback := func(err error) error {
+		fallbackErrs = append(fallbackErrs, err)
+		return errDegraded
+	}
+
+	// The first failure leaves the circuit closed, so it is returned as is
+	err := cb.ExecuteWithFallback(ctx, func() error { return errTestError }, fallback)
+	if !errors.Is(err, errTestError) {
+		t.Errorf("Expected errTestError, got: %v", err)
+	}
+	if len(fallbackErrs) != 0 {
+		t.Errorf("Expected no fallback while closed, got: %d calls", len(fallbackErrs))
+	}
+
+	// The failure that opens the circuit goes to the fallback
+	err = cb.ExecuteWithFallback(ctx, func() error { return errTestError }, fallback)
+	if !errors.Is(err, errDegraded) {
+		t.Errorf("Expected the fallback's error, got: %v", err)
+	}
+	if len(fallbackErrs) != 1 || !errors.Is(fallbackErrs[0], errTestError) {
+		t.Errorf("Expected the fallback to get errTestError, got: %v", fallbackErrs)
+	}
+
+	// While open, fn is not called and the fallback gets the rejection
+	called := false
+	err = cb.ExecuteWithFallback(ctx, func() error {
+		called = true
+		return nil
+	}, func(err error) error {
+		if !errors.Is(err, ErrRejected) {
+			t.Errorf("Expected ErrRejected, got: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected the fallback's nil to propagate, got: %v", err)
+	}
+	if called {
+		t.Error("Expected fn not to be called while the circuit is open")
+	}
+}
+
+func TestCircuitBreaker_ExecuteWithResultFallback_OpenState(t *testing.T) {
+	cfg := Config{
+		FailureThreshold:    1,
+		SuccessThreshold:    1,
+		Timeout:             time.Minute,
+		MaxRequestsHalfOpen: 1,
+	}
+	cb := New(cfg)
+	ctx := context.Background()
+
+	fallback := func(err error) (interface{}, error) {
+		return "cached", nil
+	}
+
+	result, err := cb.ExecuteWithResultFallback(ctx, func() (interface{}, error) {
+		return "fresh", nil
+	}, fallback)
+	if err != nil || result != "fresh" {
+		t.Errorf("Expected fresh result while closed, got: %v, %v", result, err)
+	}
+
+	result, err = cb.ExecuteWithResultFallback(ctx, func() (interface{}, error) {
+		return nil, errTestError
+	}, fallback)
+	if err != nil || result != "cached" {
+		t.Errorf("Expected the fallback's result when the circuit opens, got: %v, %v", result, err)
+	}
+	if cb.GetState() != StateOpen {
+		t.Fatalf("Expected state Open, got: %v", cb.GetState())
+	}
+
+	result, err = cb.ExecuteWithResultFallback(ctx, func() (interface{}, error) {
+		return "fresh", nil
+	}, fallback)
+	if err != nil || result != "cached" {
+		t.Errorf("Expected the fallback's result while open, got: %v, %v", result, err)
+	}
+}
+
 func TestCircuitBreaker_OnStateChange_Callback(t *testing.T) {
 	cfg := Config{
 		FailureThreshold:    2,
@@ -461,6 +557,113 @@ func TestCircuitBreaker_ConcurrentAccess(t *testing.T) {
 	}
 }
 
+// runOutcomes executes one request per outcome, failing where it is true
+func runOutcomes(cb *CircuitBreaker, outcomes ...bool) {
+	ctx := context.Background()
+	for _, failed := range outcomes {
+		_ = cb.Execute(ctx, func() error {
+			if failed {
+				return errTestError
+			}
+			return nil
+		})
+	}
+}
+
+func TestCircuitBreaker_ConsecutiveMode_InterleavedFailuresNeverOpen(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold:    3,
+		SuccessThreshold:    1,
+		Timeout:             time.Minute,
+		MaxRequestsHalfOpen: 1,
+	})
+
+	// 40% failures, never 3 in a row
+	for i := 0; i < 10; i++ {
+		runOutcomes(cb, true, false, true, false, false)
+	}
+
+	if cb.GetState() != StateClosed {
+		t.Errorf("Expected state Closed, got: %v", cb.GetState())
+	}
+}
+
+func TestCircuitBreaker_WindowMode_OpensOnFailureRatio(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold:    3,
+		SuccessThreshold:    1,
+		Timeout:             time.Minute,
+		MaxRequestsHalfOpen: 1,
+		WindowSize:          10,
+		FailureRatio:        0.3,
+	})
+
+	// 2 failures in 5 requests: the window is not full yet
+	runOutcomes(cb, true, false, true, false, false)
+	if cb.GetState() != StateClosed {
+		t.Fatalf("Expected state Closed before the window fills, got: %v", cb.GetState())
+	}
+
+	// 4 failures in the last 10 requests exceeds 30%
+	runOutcomes(cb, false, false, true, false, true)
+	if cb.GetState() != StateOpen {
+		t.Errorf("Expected state Open, got: %v", cb.GetState())
+	}
+}
+
+func TestCircuitBreaker_WindowMode_StaysClosedAtOrBelowRatio(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold:    3,
+		SuccessThreshold:    1,
+		Timeout:             time.Minute,
+		MaxRequestsHalfOpen: 1,
+		WindowSize:          10,
+		FailureRatio:        0.3,
+	})
+
+	// Three failures in a row would open the consecutive mode
+	runOutcomes(cb, true, true, true, false, false, false, false, false, false, false)
+	if cb.GetState() != StateClosed {
+		t.Fatalf("Expected state Closed at 30%% failures, got: %v", cb.GetState())
+	}
+	if stats := cb.GetStats(); stats.FailureCount != 3 {
+		t.Errorf("Expected 3 failures in the window, got: %d", stats.FailureCount)
+	}
+
+	// The oldest failures slide out of the window as successes come in
+	runOutcomes(cb, false, false, false, true)
+	if cb.GetState() != StateClosed {
+		t.Errorf("Expected state Closed, got: %v", cb.GetState())
+	}
+	if stats := cb.GetStats(); stats.FailureCount != 1 {
+		t.Errorf("Expected 1 failure in the window, got: %d", stats.FailureCount)
+	}
+}
+
+func TestCircuitBreaker_WindowMode_ClearedOnReset(t *testing.T) {
+	cb := New(Config{
+		FailureThreshold:    3,
+		SuccessThreshold:    1,
+		Timeout:             time.Minute,
+		MaxRequestsHalfOpen: 1,
+		WindowSize:          4,
+		FailureRatio:        0.5,
+	})
+
+	runOutcomes(cb, true, false, true, true)
+	if cb.GetState() != StateOpen {
+		t.Fatalf("Expected state Open, got: %v", cb.GetState())
+	}
+
+	cb.Reset()
+
+	// The failures from before the reset no longer count
+	runOutcomes(cb, true, false, false, true)
+	if cb.GetState() != StateClosed {
+		t.Errorf("Expected state Closed, got: %v", cb.GetState())
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
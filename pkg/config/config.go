@@ -1,14 +1,21 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v2"
+	"rillnet/pkg/validation"
 )
 
+// ErrInvalidMeshWeights is wrapped by Validate when mesh scoring weights are
+// out of range or all zero, which would leave peer selection to bonuses alone.
+var ErrInvalidMeshWeights = errors.New("invalid mesh scoring weights")
+
 // MeshConfig contains mesh network configuration
 type MeshConfig struct {
 	MaxConnections        int           `yaml:"max_connections"`
@@ -20,6 +27,128 @@ type MeshConfig struct {
 	LatencyWeight         float64       `yaml:"latency_weight"`
 	BandwidthWeight       float64       `yaml:"bandwidth_weight"`
 	ReliabilityWeight     float64       `yaml:"reliability_weight"`
+	MaxHopDepth           int           `yaml:"max_hop_depth"` // Max relay hops from a publisher (0 = unlimited)
+}
+
+// ValidateWeights requires every scoring weight in [0,1] and at least one positive.
+func (m MeshConfig) ValidateWeights() error {
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"latency_weight", m.LatencyWeight},
+		{"bandwidth_weight", m.BandwidthWeight},
+		{"reliability_weight", m.ReliabilityWeight},
+	}
+
+	anyPositive := false
+	for _, w := range weights {
+		if w.value < 0 || w.value > 1 {
+			return fmt.Errorf("%w: mesh.%s must be between 0 and 1, got %g", ErrInvalidMeshWeights, w.name, w.value)
+		}
+		if w.value > 0 {
+			anyPositive = true
+		}
+	}
+	if !anyPositive {
+		return fmt.Errorf("%w: at least one of mesh latency/bandwidth/reliability weights must be > 0", ErrInvalidMeshWeights)
+	}
+
+	return nil
+}
+
+// StreamConfig contains stream creation limits
+type StreamConfig struct {
+	MaxPerOwner       int               `yaml:"max_per_owner"`         // Active streams per owner (0 = unlimited)
+	MaxPerOwnerByRole map[string]int    `yaml:"max_per_owner_by_role"` // Role-specific overrides of max_per_owner
+	MaxStreams        int               `yaml:"max_streams"`           // Active streams hosted by this instance (0 = unlimited)
+	MaxTotalBitrate   int               `yaml:"max_total_bitrate"`     // Subscriber egress budget per new stream in kbps (0 = unlimited)
+	MaxEgressBitrate  int               `yaml:"max_egress_bitrate"`    // Subscriber egress budget of this instance in kbps, shared by stream priority (0 = unlimited)
+	IDCharset         string            `yaml:"id_charset"`            // Characters allowed in stream IDs, as a regexp character class body
+	IDMaxLength       int               `yaml:"id_max_length"`         // Longest stream ID accepted
+	StatsInterval     time.Duration     `yaml:"stats_interval"`        // How often stream stats are recomputed; entries are cached for two intervals (0 = on every request)
+	Health            HealthScoreConfig `yaml:"health"`
+}
+
+// StreamIDPolicy returns the stream ID charset and maximum length, using the
+// validation package defaults for unset values
+func (c StreamConfig) StreamIDPolicy() (charset string, maxLength int) {
+	charset, maxLength = c.IDCharset, c.IDMaxLength
+	if charset == "" {
+		charset = validation.DefaultStreamIDCharset
+	}
+	if maxLength == 0 {
+		maxLength = validation.DefaultStreamIDMaxLength
+	}
+	return charset, maxLength
+}
+
+// Health score strategies
+const (
+	// HealthStrategyAdditive sums the weighted components, capped at 100
+	HealthStrategyAdditive = "additive"
+	// HealthStrategyPublisherGated is additive, but 0 while the stream has no publisher
+	HealthStrategyPublisherGated = "publisher_gated"
+)
+
+// HealthScoreConfig weights the components of a stream's 0-100 health score:
+//
+//	publishers*PublisherWeight + subscribers*SubscriberWeight
+//	  + bitrate_kbps*BitrateWeight + latency points
+//
+// Latency points are LatencyWeight below 100ms, 2/3 of it below 300ms,
+// 1/3 of it below 500ms and 0 above.
+type HealthScoreConfig struct {
+	Strategy         string  `yaml:"strategy"`          // additive | publisher_gated
+	PublisherWeight  float64 `yaml:"publisher_weight"`  // Points per publisher
+	SubscriberWeight float64 `yaml:"subscriber_weight"` // Points per subscriber
+	BitrateWeight    float64 `yaml:"bitrate_weight"`    // Points per kbps
+	LatencyWeight    float64 `yaml:"latency_weight"`    // Points for latency under 100ms
+}
+
+// DefaultHealthScoreConfig returns the historical formula:
+// publishers*20 + subscribers*2 + kbps/100 + up to 30 latency points.
+func DefaultHealthScoreConfig() HealthScoreConfig {
+	return HealthScoreConfig{
+		Strategy:         HealthStrategyAdditive,
+		PublisherWeight:  20,
+		SubscriberWeight: 2,
+		BitrateWeight:    0.01,
+		LatencyWeight:    30,
+	}
+}
+
+func (h HealthScoreConfig) validate() error {
+	switch h.Strategy {
+	case HealthStrategyAdditive, HealthStrategyPublisherGated:
+	default:
+		return fmt.Errorf("streams.health.strategy must be one of %s, %s", HealthStrategyAdditive, HealthStrategyPublisherGated)
+	}
+
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"publisher_weight", h.PublisherWeight},
+		{"subscriber_weight", h.SubscriberWeight},
+		{"bitrate_weight", h.BitrateWeight},
+		{"latency_weight", h.LatencyWeight},
+	}
+	for _, w := range weights {
+		if w.value < 0 {
+			return fmt.Errorf("streams.health.%s must be >= 0", w.name)
+		}
+	}
+	return nil
+}
+
+// AdaptiveBitrateConfig tunes automatic per-peer quality switching
+type AdaptiveBitrateConfig struct {
+	CheckInterval             time.Duration `yaml:"check_interval"`
+	MinTimeBetweenSwitches    time.Duration `yaml:"min_time_between_switches"`
+	HysteresisFactor          float64       `yaml:"hysteresis_factor"`           // 0.0-1.0
+	RequiredConsecutiveChecks int           `yaml:"required_consecutive_checks"` // Checks a new quality must win in a row
+	MaxCheckBackoff           time.Duration `yaml:"max_check_backoff"`           // Cap for check interval after transient errors
 }
 
 type Config struct {
@@ -28,6 +157,8 @@ type Config struct {
 		ReadTimeout     time.Duration `yaml:"read_timeout"`
 		WriteTimeout    time.Duration `yaml:"write_timeout"`
 		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+		// MaxRequestBodyBytes caps API request bodies; larger ones get 413.
+		MaxRequestBodyBytes int64 `yaml:"max_request_body_bytes"`
 	} `yaml:"server"`
 
 	Signal struct {
@@ -35,6 +166,24 @@ type Config struct {
 		PingInterval    time.Duration `yaml:"ping_interval"`
 		PongTimeout     time.Duration `yaml:"pong_timeout"`
 		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+		// MaxOutboundBacklog is how many queued messages a peer may lag behind before it is dropped as too slow.
+		MaxOutboundBacklog int `yaml:"max_outbound_backlog"`
+		// IdleTimeout closes connections sending no application messages for this long, pongs aside (0 disables).
+		IdleTimeout time.Duration `yaml:"idle_timeout"`
+		// ReconnectBackoff and ReconnectBackoffMax bound the jittered reconnect delay advertised
+		// when the server closes peers, growing toward the max as connections near their cap.
+		ReconnectBackoff    time.Duration `yaml:"reconnect_backoff"`
+		ReconnectBackoffMax time.Duration `yaml:"reconnect_backoff_max"`
+		// CompressionEnabled negotiates permessage-deflate with clients that offer it.
+		CompressionEnabled bool `yaml:"compression_enabled"`
+		// CompressionLevel is the deflate level of compressed messages (-2 Huffman only, 1 fastest, 9 smallest).
+		CompressionLevel int `yaml:"compression_level"`
+		// AsyncAuth validates the token after the upgrade instead of during the handshake.
+		AsyncAuth bool `yaml:"async_auth"`
+		// AuthPolicy decides what happens to messages sent before async auth completes: queue or reject.
+		AuthPolicy string `yaml:"auth_policy"`
+		// AuthTimeout closes connections whose async auth has not completed by then.
+		AuthTimeout time.Duration `yaml:"auth_timeout"`
 	} `yaml:"signal"`
 
 	WebRTC struct {
@@ -47,16 +196,51 @@ type Config struct {
 		NAT1To1IPs []string `yaml:"nat_1to1_ips"`
 		Simulcast  bool     `yaml:"simulcast"`
 		MaxBitrate int      `yaml:"max_bitrate"`
+		// KeyRotationInterval forces periodic renegotiation of long-lived connections (0 disables).
+		KeyRotationInterval time.Duration `yaml:"key_rotation_interval"`
+		// RenegotiationTimeout rolls back a renegotiation offer the client has not answered in time (0 uses the SFU default).
+		RenegotiationTimeout time.Duration `yaml:"renegotiation_timeout"`
+		// TrickleICE returns SFU descriptions before gathering and pushes candidates as they are found.
+		TrickleICE bool `yaml:"trickle_ice"`
+		// MaxPendingCandidates bounds client candidates buffered per peer before its remote description is set.
+		MaxPendingCandidates int `yaml:"max_pending_candidates"`
+		// MaxICECandidatesPerMinute caps the ICE candidates each peer may send, over signaling or HTTP trickle (0 disables).
+		MaxICECandidatesPerMinute int `yaml:"max_ice_candidates_per_minute"`
+		// PreconnectTTL is how long a subscriber preconnect may stay unbound.
+		PreconnectTTL time.Duration `yaml:"preconnect_ttl"`
+		// MaxPreconnectsPerUser caps the unbound preconnects one user may hold.
+		MaxPreconnectsPerUser int `yaml:"max_preconnects_per_user"`
+		// MaxForwardedStreams is the subscriber track count treated as full load; video is shed from 70% of it (0 disables).
+		MaxForwardedStreams int `yaml:"max_forwarded_streams"`
+		// MaxDataChannelMessageBytes caps the publisher data channel messages relayed to subscribers (0 uses 16 KiB).
+		MaxDataChannelMessageBytes int `yaml:"max_data_channel_message_bytes"`
+		// StartupSelfTest creates a throwaway publisher offer at startup: "warn" logs a failure, "fatal" aborts, "off" skips it.
+		StartupSelfTest string `yaml:"startup_self_test"`
+		// Eviction sets how long each eviction cause must last before a peer is dropped (0 keeps the session).
+		Eviction EvictionConfig `yaml:"eviction"`
+		// RTCPFeedback overrides the RTCP feedback negotiated per codec (opus, g722, pcmu, pcma, vp8, vp9, h264, av1); unlisted codecs enable all.
+		RTCPFeedback map[string]RTCPFeedbackConfig `yaml:"rtcp_feedback"`
 	} `yaml:"webrtc"`
 
 	Mesh MeshConfig `yaml:"mesh"`
 
+	Streams StreamConfig `yaml:"streams"`
+
+	AdaptiveBitrate AdaptiveBitrateConfig `yaml:"adaptive_bitrate"`
+
 	Monitoring struct {
 		PrometheusEnabled bool          `yaml:"prometheus_enabled"`
 		PrometheusPort    int           `yaml:"prometheus_port"`
 		MetricsInterval   time.Duration `yaml:"metrics_interval"`
 	} `yaml:"monitoring"`
 
+	Recording struct {
+		// Directory holds recorded files and their per-stream index (empty disables playback endpoints).
+		Directory string `yaml:"directory"`
+		// Enabled records publisher tracks into Directory.
+		Enabled bool `yaml:"enabled"`
+	} `yaml:"recording"`
+
 	Tracing struct {
 		Enabled     bool    `yaml:"enabled"`
 		ServiceName string  `yaml:"service_name"`
@@ -71,11 +255,17 @@ type Config struct {
 	} `yaml:"logging"`
 
 	Redis struct {
-		Enabled  bool   `yaml:"enabled"`
-		Address  string `yaml:"address"`
-		Password string `yaml:"password"`
-		DB       int    `yaml:"db"`
-		PoolSize int    `yaml:"pool_size"`
+		Enabled        bool   `yaml:"enabled"`
+		Address        string `yaml:"address"`
+		Password       string `yaml:"password"`
+		DB             int    `yaml:"db"`
+		PoolSize       int    `yaml:"pool_size"`
+		CircuitBreaker bool   `yaml:"circuit_breaker"` // Fail peer repository calls fast during outages (uses circuit_breaker settings)
+		ReadThrough    bool   `yaml:"read_through"`    // Reads still go to Redis while that breaker is open
+		// Peers expire this long after their last heartbeat (0 = never), and
+		// expired peers are swept from stream sets every PeerSweepInterval
+		PeerTTL           time.Duration `yaml:"peer_ttl"`
+		PeerSweepInterval time.Duration `yaml:"peer_sweep_interval"`
 	} `yaml:"redis"`
 
 	Database struct {
@@ -88,6 +278,12 @@ type Config struct {
 		AccessTokenTTL   time.Duration `yaml:"access_token_ttl"`
 		RefreshTokenTTL  time.Duration `yaml:"refresh_token_ttl"`
 		AllowedOrigins   []string      `yaml:"allowed_origins"`
+		// UserRoles grants users a global role by user ID, e.g. "operator" for
+		// admin endpoints or a key of streams.max_per_owner_by_role
+		UserRoles map[string]string `yaml:"user_roles"`
+		// APIKeys authenticate server-to-server clients presenting
+		// "Authorization: ApiKey <key>" on routes that accept them
+		APIKeys []APIKeyConfig `yaml:"api_keys"`
 	} `yaml:"auth"`
 
 	RateLimiting struct {
@@ -105,6 +301,14 @@ type Config struct {
 			Burst                  int     `yaml:"burst"`
 			MaxConcurrent          int     `yaml:"max_concurrent_connections"`
 			MaxMessageSizeBytes    int64   `yaml:"max_message_size_bytes"`
+			// Invalid messages within the window before a peer is refused for
+			// the cooldown; 0 disables quarantine
+			QuarantineStrikes  int           `yaml:"quarantine_strikes"`
+			QuarantineWindow   time.Duration `yaml:"quarantine_window"`
+			QuarantineCooldown time.Duration `yaml:"quarantine_cooldown"`
+			// Also quarantine the remote address. Only when clients connect
+			// directly: behind a proxy every client shares its address.
+			QuarantineByIP bool `yaml:"quarantine_by_ip"`
 		} `yaml:"websocket"`
 	} `yaml:"rate_limiting"`
 
@@ -123,15 +327,50 @@ type Config struct {
 		SuccessThreshold   int           `yaml:"success_threshold"`
 		Timeout            time.Duration `yaml:"timeout"`
 		MaxRequestsHalfOpen int          `yaml:"max_requests_half_open"`
+		// WindowSize, when > 0, opens on the failure ratio of the last
+		// window_size requests instead of on failure_threshold in a row
+		WindowSize   int     `yaml:"window_size"`
+		FailureRatio float64 `yaml:"failure_ratio"`
 	} `yaml:"circuit_breaker"`
 
 	Distributed struct {
 		InstanceID      string        `yaml:"instance_id"`
 		LockTTL         time.Duration `yaml:"lock_ttl"`
 		PeerRegistryTTL time.Duration `yaml:"peer_registry_ttl"`
+		// PeerRegistryTTLJitter spreads peer key expiry over ttl ± jitter
+		PeerRegistryTTLJitter time.Duration `yaml:"peer_registry_ttl_jitter"`
 	} `yaml:"distributed"`
 }
 
+// EvictionConfig sets the grace period of each SFU eviction cause; 0 disables that cause
+type EvictionConfig struct {
+	ICEDisconnectGrace time.Duration `yaml:"ice_disconnect_grace"` // ICE disconnected or failed
+	MetricsStaleGrace  time.Duration `yaml:"metrics_stale_grace"`  // No RTCP from a publisher
+	AnswerTimeout      time.Duration `yaml:"answer_timeout"`       // SFU offer left unanswered
+}
+
+// RTCPFeedbackConfig selects the RTCP feedback advertised for one codec; nack, pli and fir only apply to video
+type RTCPFeedbackConfig struct {
+	NACK        bool `yaml:"nack"`
+	PLI         bool `yaml:"pli"`
+	FIR         bool `yaml:"fir"`
+	TransportCC bool `yaml:"transport_cc"`
+}
+
+// rtcpFeedbackCodecs are the codec names accepted under webrtc.rtcp_feedback
+var rtcpFeedbackCodecs = map[string]bool{
+	"opus": true, "g722": true, "pcmu": true, "pcma": true,
+	"vp8": true, "vp9": true, "h264": true, "av1": true,
+}
+
+// APIKeyConfig maps an API key, stored only as its hex-encoded SHA-256
+// digest, to the user it authenticates as and an optional global role
+type APIKeyConfig struct {
+	SHA256 string `yaml:"sha256"`
+	UserID string `yaml:"user_id"`
+	Role   string `yaml:"role,omitempty"`
+}
+
 type ICEServerConfig struct {
 	URLs       []string `yaml:"urls"`
 	Username   string   `yaml:"username,omitempty"`
@@ -153,6 +392,9 @@ func (c *Config) Validate() error {
 	if c.Server.ShutdownTimeout <= 0 {
 		return fmt.Errorf("server.shutdown_timeout must be > 0")
 	}
+	if c.Server.MaxRequestBodyBytes <= 0 {
+		return fmt.Errorf("server.max_request_body_bytes must be > 0")
+	}
 
 	// Signal
 	if c.Signal.Address == "" {
@@ -167,6 +409,27 @@ func (c *Config) Validate() error {
 	if c.Signal.ShutdownTimeout <= 0 {
 		return fmt.Errorf("signal.shutdown_timeout must be > 0")
 	}
+	if c.Signal.IdleTimeout < 0 {
+		return fmt.Errorf("signal.idle_timeout must be >= 0")
+	}
+	if c.Signal.CompressionLevel < -2 || c.Signal.CompressionLevel > 9 {
+		return fmt.Errorf("signal.compression_level must be between -2 and 9")
+	}
+	if c.Signal.MaxOutboundBacklog <= 0 {
+		return fmt.Errorf("signal.max_outbound_backlog must be > 0")
+	}
+	if c.Signal.ReconnectBackoff <= 0 {
+		return fmt.Errorf("signal.reconnect_backoff must be > 0")
+	}
+	if c.Signal.ReconnectBackoffMax < c.Signal.ReconnectBackoff {
+		return fmt.Errorf("signal.reconnect_backoff_max must be >= reconnect_backoff")
+	}
+	if c.Signal.AuthPolicy != "queue" && c.Signal.AuthPolicy != "reject" {
+		return fmt.Errorf("signal.auth_policy must be queue or reject")
+	}
+	if c.Signal.AuthTimeout <= 0 {
+		return fmt.Errorf("signal.auth_timeout must be > 0")
+	}
 
 	// WebRTC
 	if c.WebRTC.PortRange.Min > 0 || c.WebRTC.PortRange.Max > 0 {
@@ -177,6 +440,49 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("webrtc.port_range.min must be < max")
 		}
 	}
+	if c.WebRTC.KeyRotationInterval < 0 {
+		return fmt.Errorf("webrtc.key_rotation_interval must be >= 0")
+	}
+	if c.WebRTC.RenegotiationTimeout < 0 {
+		return fmt.Errorf("webrtc.renegotiation_timeout must be >= 0")
+	}
+	if c.WebRTC.MaxPendingCandidates < 0 {
+		return fmt.Errorf("webrtc.max_pending_candidates must be >= 0")
+	}
+	if c.WebRTC.MaxICECandidatesPerMinute < 0 {
+		return fmt.Errorf("webrtc.max_ice_candidates_per_minute must be >= 0")
+	}
+	if c.WebRTC.PreconnectTTL < 0 {
+		return fmt.Errorf("webrtc.preconnect_ttl must be >= 0")
+	}
+	if c.WebRTC.MaxPreconnectsPerUser < 0 {
+		return fmt.Errorf("webrtc.max_preconnects_per_user must be >= 0")
+	}
+	if c.WebRTC.MaxForwardedStreams < 0 {
+		return fmt.Errorf("webrtc.max_forwarded_streams must be >= 0")
+	}
+	if c.WebRTC.MaxDataChannelMessageBytes < 0 {
+		return fmt.Errorf("webrtc.max_data_channel_message_bytes must be >= 0")
+	}
+	switch c.WebRTC.StartupSelfTest {
+	case "", "off", "warn", "fatal":
+	default:
+		return fmt.Errorf("webrtc.startup_self_test must be off, warn or fatal")
+	}
+	for codec := range c.WebRTC.RTCPFeedback {
+		if !rtcpFeedbackCodecs[codec] {
+			return fmt.Errorf("webrtc.rtcp_feedback: unknown codec %q", codec)
+		}
+	}
+	if c.WebRTC.Eviction.ICEDisconnectGrace < 0 {
+		return fmt.Errorf("webrtc.eviction.ice_disconnect_grace must be >= 0")
+	}
+	if c.WebRTC.Eviction.MetricsStaleGrace < 0 {
+		return fmt.Errorf("webrtc.eviction.metrics_stale_grace must be >= 0")
+	}
+	if c.WebRTC.Eviction.AnswerTimeout < 0 {
+		return fmt.Errorf("webrtc.eviction.answer_timeout must be >= 0")
+	}
 
 	// Mesh
 	if c.Mesh.MaxConnections <= 0 {
@@ -200,8 +506,56 @@ func (c *Config) Validate() error {
 	if c.Mesh.RebalanceInterval <= 0 {
 		return fmt.Errorf("mesh.rebalance_interval must be > 0")
 	}
-	if c.Mesh.LatencyWeight < 0 || c.Mesh.BandwidthWeight < 0 || c.Mesh.ReliabilityWeight < 0 {
-		return fmt.Errorf("mesh weight values must be >= 0")
+	if c.Mesh.MaxHopDepth < 0 {
+		return fmt.Errorf("mesh.max_hop_depth must be >= 0")
+	}
+	if err := c.Mesh.ValidateWeights(); err != nil {
+		return err
+	}
+
+	// Streams
+	if c.Streams.MaxPerOwner < 0 {
+		return fmt.Errorf("streams.max_per_owner must be >= 0")
+	}
+	if c.Streams.MaxStreams < 0 {
+		return fmt.Errorf("streams.max_streams must be >= 0")
+	}
+	if c.Streams.MaxTotalBitrate < 0 {
+		return fmt.Errorf("streams.max_total_bitrate must be >= 0")
+	}
+	if c.Streams.MaxEgressBitrate < 0 {
+		return fmt.Errorf("streams.max_egress_bitrate must be >= 0")
+	}
+	if c.Streams.StatsInterval < 0 {
+		return fmt.Errorf("streams.stats_interval must be >= 0")
+	}
+	if err := validation.ValidateStreamIDPolicy(c.Streams.StreamIDPolicy()); err != nil {
+		return fmt.Errorf("streams.id_charset/id_max_length: %w", err)
+	}
+	for role, limit := range c.Streams.MaxPerOwnerByRole {
+		if limit < 0 {
+			return fmt.Errorf("streams.max_per_owner_by_role.%s must be >= 0", role)
+		}
+	}
+	if err := c.Streams.Health.validate(); err != nil {
+		return err
+	}
+
+	// Adaptive bitrate
+	if c.AdaptiveBitrate.CheckInterval <= 0 {
+		return fmt.Errorf("adaptive_bitrate.check_interval must be > 0")
+	}
+	if c.AdaptiveBitrate.MinTimeBetweenSwitches < 0 {
+		return fmt.Errorf("adaptive_bitrate.min_time_between_switches must be >= 0")
+	}
+	if c.AdaptiveBitrate.HysteresisFactor < 0 || c.AdaptiveBitrate.HysteresisFactor > 1 {
+		return fmt.Errorf("adaptive_bitrate.hysteresis_factor must be between 0 and 1")
+	}
+	if c.AdaptiveBitrate.RequiredConsecutiveChecks < 1 {
+		return fmt.Errorf("adaptive_bitrate.required_consecutive_checks must be >= 1")
+	}
+	if c.AdaptiveBitrate.MaxCheckBackoff < c.AdaptiveBitrate.CheckInterval {
+		return fmt.Errorf("adaptive_bitrate.max_check_backoff must be >= check_interval")
 	}
 
 	// Monitoring
@@ -225,6 +579,12 @@ func (c *Config) Validate() error {
 		if c.Redis.PoolSize <= 0 {
 			return fmt.Errorf("redis.pool_size must be > 0 when redis.enabled=true")
 		}
+		if c.Redis.PeerTTL < 0 {
+			return fmt.Errorf("redis.peer_ttl must be >= 0")
+		}
+		if c.Redis.PeerSweepInterval < 0 {
+			return fmt.Errorf("redis.peer_sweep_interval must be >= 0")
+		}
 	}
 
 	// Database
@@ -244,6 +604,19 @@ func (c *Config) Validate() error {
 	if c.Auth.RefreshTokenTTL <= 0 {
 		return fmt.Errorf("auth.refresh_token_ttl must be > 0")
 	}
+	for userID, role := range c.Auth.UserRoles {
+		if strings.TrimSpace(role) == "" {
+			return fmt.Errorf("auth.user_roles.%s must not be empty", userID)
+		}
+	}
+	for i, key := range c.Auth.APIKeys {
+		if digest, err := hex.DecodeString(key.SHA256); err != nil || len(digest) != sha256.Size {
+			return fmt.Errorf("auth.api_keys[%d].sha256 must be a hex-encoded SHA-256 digest", i)
+		}
+		if strings.TrimSpace(key.UserID) == "" {
+			return fmt.Errorf("auth.api_keys[%d].user_id must not be empty", i)
+		}
+	}
 
 	// Rate limiting
 	if c.RateLimiting.Enabled {
@@ -271,6 +644,17 @@ func (c *Config) Validate() error {
 			if c.RateLimiting.WebSocket.MaxMessageSizeBytes < 0 {
 				return fmt.Errorf("rate_limiting.websocket.max_message_size_bytes must be >= 0 when rate limiting is enabled")
 			}
+		if c.RateLimiting.WebSocket.QuarantineStrikes < 0 {
+			return fmt.Errorf("rate_limiting.websocket.quarantine_strikes must be >= 0 when rate limiting is enabled")
+		}
+		if c.RateLimiting.WebSocket.QuarantineStrikes > 0 {
+			if c.RateLimiting.WebSocket.QuarantineWindow <= 0 {
+				return fmt.Errorf("rate_limiting.websocket.quarantine_window must be > 0 when quarantine is enabled")
+			}
+			if c.RateLimiting.WebSocket.QuarantineCooldown <= 0 {
+				return fmt.Errorf("rate_limiting.websocket.quarantine_cooldown must be > 0 when quarantine is enabled")
+			}
+		}
 		}
 
 	// Retry
@@ -306,6 +690,12 @@ func (c *Config) Validate() error {
 		if c.CircuitBreaker.MaxRequestsHalfOpen <= 0 {
 			return fmt.Errorf("circuit_breaker.max_requests_half_open must be > 0 when circuit breaker is enabled")
 		}
+		if c.CircuitBreaker.WindowSize < 0 {
+			return fmt.Errorf("circuit_breaker.window_size must be >= 0")
+		}
+		if c.CircuitBreaker.WindowSize > 0 && (c.CircuitBreaker.FailureRatio <= 0 || c.CircuitBreaker.FailureRatio >= 1) {
+			return fmt.Errorf("circuit_breaker.failure_ratio must be between 0 and 1 when window_size is set")
+		}
 	}
 
 	// Distributed
@@ -324,11 +714,23 @@ func (c *Config) Validate() error {
 	if c.Distributed.PeerRegistryTTL <= 0 {
 		return fmt.Errorf("distributed.peer_registry_ttl must be > 0")
 	}
+	if c.Distributed.PeerRegistryTTLJitter < 0 {
+		return fmt.Errorf("distributed.peer_registry_ttl_jitter must be >= 0")
+	}
+	if c.Distributed.PeerRegistryTTLJitter > c.Distributed.PeerRegistryTTL/2 {
+		return fmt.Errorf("distributed.peer_registry_ttl_jitter must be <= half of distributed.peer_registry_ttl")
+	}
+
+	// Recording
+	if c.Recording.Enabled && c.Recording.Directory == "" {
+		return fmt.Errorf("recording.directory must be set when recording is enabled")
+	}
 
 	return nil
 }
 
-// Load reads configuration from YAML file, applies defaults and env overrides.
+// Load reads configuration from a YAML file, or a JSON one when its name ends
+// in .json, applies defaults and env overrides.
 func Load(configPath string) (*Config, error) {
 	// If file does not exist, fall back to defaults
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -348,8 +750,8 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	cfg := DefaultConfig()
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config yaml: %w", err)
+	if err := unmarshalConfig(safePath, data, cfg); err != nil {
+		return nil, err
 	}
 
 	cfg.applyEnvOverrides()
@@ -368,11 +770,21 @@ func DefaultConfig() *Config {
 	cfg.Server.ReadTimeout = 30 * time.Second
 	cfg.Server.WriteTimeout = 30 * time.Second
 	cfg.Server.ShutdownTimeout = 30 * time.Second
+	cfg.Server.MaxRequestBodyBytes = 1 << 20 // 1 MiB
 
 	cfg.Signal.Address = ":8081"
 	cfg.Signal.PingInterval = 30 * time.Second
 	cfg.Signal.PongTimeout = 60 * time.Second
 	cfg.Signal.ShutdownTimeout = 30 * time.Second
+	cfg.Signal.MaxOutboundBacklog = 256
+	cfg.Signal.ReconnectBackoff = time.Second
+	cfg.Signal.ReconnectBackoffMax = 30 * time.Second
+	cfg.Signal.CompressionLevel = 1
+	cfg.Signal.AuthPolicy = "queue"
+	cfg.Signal.AuthTimeout = 10 * time.Second
+
+	cfg.WebRTC.MaxICECandidatesPerMinute = 200
+	cfg.WebRTC.StartupSelfTest = "warn"
 
 	cfg.Mesh.MaxConnections = 4
 	cfg.Mesh.MinConnections = 2
@@ -383,11 +795,27 @@ func DefaultConfig() *Config {
 	cfg.Mesh.LatencyWeight = 0.4
 	cfg.Mesh.BandwidthWeight = 0.4
 	cfg.Mesh.ReliabilityWeight = 0.2
+	cfg.Mesh.MaxHopDepth = 4
+
+	cfg.Streams.MaxPerOwner = 10
+	cfg.Streams.IDCharset = validation.DefaultStreamIDCharset
+	cfg.Streams.IDMaxLength = validation.DefaultStreamIDMaxLength
+	cfg.Streams.StatsInterval = 5 * time.Second
+	cfg.Streams.Health = DefaultHealthScoreConfig()
+
+	cfg.AdaptiveBitrate.CheckInterval = 5 * time.Second
+	cfg.AdaptiveBitrate.MinTimeBetweenSwitches = 10 * time.Second
+	cfg.AdaptiveBitrate.HysteresisFactor = 0.15
+	cfg.AdaptiveBitrate.RequiredConsecutiveChecks = 3
+	cfg.AdaptiveBitrate.MaxCheckBackoff = time.Minute
 
 	cfg.Monitoring.PrometheusEnabled = true
 	cfg.Monitoring.PrometheusPort = 9090
 	cfg.Monitoring.MetricsInterval = 30 * time.Second
 
+	cfg.Recording.Directory = ""
+	cfg.Recording.Enabled = false
+
 	cfg.Tracing.Enabled = false
 	cfg.Tracing.ServiceName = "rillnet"
 	cfg.Tracing.JaegerURL = "http://localhost:14268/api/traces"
@@ -401,6 +829,10 @@ func DefaultConfig() *Config {
 	cfg.Redis.Address = "localhost:6379"
 	cfg.Redis.DB = 0
 	cfg.Redis.PoolSize = 10
+	cfg.Redis.CircuitBreaker = true
+	cfg.Redis.ReadThrough = false
+	cfg.Redis.PeerTTL = 5 * time.Minute
+	cfg.Redis.PeerSweepInterval = time.Minute
 
 	cfg.Database.Enabled = false
 	cfg.Database.DSN = ""
@@ -420,6 +852,9 @@ func DefaultConfig() *Config {
 	cfg.RateLimiting.WebSocket.Burst = 200
 	cfg.RateLimiting.WebSocket.MaxConcurrent = 0
 	cfg.RateLimiting.WebSocket.MaxMessageSizeBytes = 64 * 1024
+	cfg.RateLimiting.WebSocket.QuarantineStrikes = 10
+	cfg.RateLimiting.WebSocket.QuarantineWindow = time.Minute
+	cfg.RateLimiting.WebSocket.QuarantineCooldown = 5 * time.Minute
 
 	// Retry defaults
 	cfg.Retry.Enabled = true
@@ -435,6 +870,7 @@ func DefaultConfig() *Config {
 	cfg.CircuitBreaker.SuccessThreshold = 2
 	cfg.CircuitBreaker.Timeout = 30 * time.Second
 	cfg.CircuitBreaker.MaxRequestsHalfOpen = 3
+	cfg.CircuitBreaker.FailureRatio = 0.5
 
 	// Distributed defaults
 	hostname, _ := os.Hostname()
@@ -445,6 +881,7 @@ func DefaultConfig() *Config {
 	}
 	cfg.Distributed.LockTTL = 30 * time.Second
 	cfg.Distributed.PeerRegistryTTL = 5 * time.Minute
+	cfg.Distributed.PeerRegistryTTLJitter = 30 * time.Second
 
 	return cfg
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -109,3 +110,152 @@ func TestValidate_RateLimiting_InvalidValues(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate_AdaptiveBitrate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("expected default adaptive bitrate config to be valid, got: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(*AdaptiveBitrateConfig)
+	}{
+		{"check interval must be > 0", func(c *AdaptiveBitrateConfig) { c.CheckInterval = 0 }},
+		{"min time between switches must be >= 0", func(c *AdaptiveBitrateConfig) { c.MinTimeBetweenSwitches = -time.Second }},
+		{"hysteresis must be >= 0", func(c *AdaptiveBitrateConfig) { c.HysteresisFactor = -0.1 }},
+		{"hysteresis must be <= 1", func(c *AdaptiveBitrateConfig) { c.HysteresisFactor = 1.5 }},
+		{"consecutive checks must be >= 1", func(c *AdaptiveBitrateConfig) { c.RequiredConsecutiveChecks = 0 }},
+		{"max backoff must be >= check interval", func(c *AdaptiveBitrateConfig) { c.MaxCheckBackoff = time.Second }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tc.mutate(&cfg.AdaptiveBitrate)
+
+			if err := cfg.Validate(); err == nil {
+				t.Fatalf("expected validation error for case %q, got nil", tc.name)
+			}
+		})
+	}
+}
+
+func TestValidate_MeshWeights(t *testing.T) {
+	cases := []struct {
+		name                            string
+		latency, bandwidth, reliability float64
+		wantErr                         bool
+	}{
+		{"defaults", 0.4, 0.4, 0.2, false},
+		{"single positive weight", 0, 1, 0, false},
+		{"all zero", 0, 0, 0, true},
+		{"negative weight", -0.1, 0.5, 0.5, true},
+		{"weight above one", 1.5, 0, 0, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Mesh.LatencyWeight = tc.latency
+			cfg.Mesh.BandwidthWeight = tc.bandwidth
+			cfg.Mesh.ReliabilityWeight = tc.reliability
+
+			err := cfg.Validate()
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("expected valid weights, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidMeshWeights) {
+				t.Fatalf("expected ErrInvalidMeshWeights, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_HealthScore(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Streams.Health.Strategy = HealthStrategyPublisherGated
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected publisher_gated to be valid, got: %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Streams.Health.Strategy = "geometric"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for unknown health strategy")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Streams.Health.SubscriberWeight = -1
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for negative health weight")
+	}
+}
+
+func TestValidate_StreamIDPolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Streams.IDCharset = ""
+	cfg.Streams.IDMaxLength = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected unset stream ID policy to use defaults, got: %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Streams.IDCharset = "z-a"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for invalid stream ID charset")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Streams.IDMaxLength = -1
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for negative stream ID max length")
+	}
+}
+
+func TestValidate_APIKeys(t *testing.T) {
+	digest := "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+
+	cfg := DefaultConfig()
+	cfg.Auth.APIKeys = []APIKeyConfig{{SHA256: digest, UserID: "ingest-bot"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected API key to be valid, got: %v", err)
+	}
+
+	cfg.Auth.APIKeys = []APIKeyConfig{{SHA256: "password", UserID: "ingest-bot"}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for an API key given in plain text")
+	}
+
+	cfg.Auth.APIKeys = []APIKeyConfig{{SHA256: digest}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for an API key without a user")
+	}
+}
+
+func TestRedacted_HidesSecretsWithoutMutating(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Auth.JWTSecret = "jwt"
+	cfg.Redis.Password = ""
+	cfg.Database.DSN = "postgres://rillnet:pw@db:5432/rillnet?sslmode=disable"
+	cfg.WebRTC.ICEServers = []ICEServerConfig{{URLs: []string{"turn:t"}, Username: "u", Credential: "c"}}
+
+	r := cfg.Redacted()
+	if r.Auth.JWTSecret != RedactedValue || r.WebRTC.ICEServers[0].Credential != RedactedValue {
+		t.Fatalf("secrets not redacted: %+v", r)
+	}
+	if r.Redis.Password != "" {
+		t.Fatalf("empty secret should stay empty, got %q", r.Redis.Password)
+	}
+	if want := "postgres://rillnet:%5BREDACTED%5D@db:5432/rillnet?sslmode=disable"; r.Database.DSN != want {
+		t.Fatalf("dsn = %q, want %q", r.Database.DSN, want)
+	}
+	if got := redactDSN("host=db password=pw"); got != RedactedValue {
+		t.Fatalf("keyword DSN = %q, want fully redacted", got)
+	}
+	if cfg.Auth.JWTSecret != "jwt" || cfg.WebRTC.ICEServers[0].Credential != "c" {
+		t.Fatal("Redacted mutated the original config")
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// unmarshalConfig decodes data into cfg as JSON for .json files and as YAML
+// otherwise
+func unmarshalConfig(path string, data []byte, cfg *Config) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := unmarshalJSONConfig(data, cfg); err != nil {
+			return fmt.Errorf("failed to unmarshal config json: %w", err)
+		}
+		return nil
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to unmarshal config yaml: %w", err)
+	}
+	return nil
+}
+
+// unmarshalJSONConfig decodes a JSON config file keyed like the YAML one.
+// Durations take the same string forms ("30s", "1m30s"), or integer
+// nanoseconds. Fields absent from data keep their current values. It is not
+// Config's UnmarshalJSON, which must keep decoding the Go field names the
+// config API responds with.
+func unmarshalJSONConfig(data []byte, cfg *Config) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after the config object")
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return fmt.Errorf("config must be a JSON object")
+	}
+
+	// The parsed document is re-encoded as YAML so the yaml tags and the
+	// YAML decoder's duration parsing apply unchanged
+	encoded, err := yaml.Marshal(yamlNumbers(document))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(encoded, cfg)
+}
+
+// yamlNumbers replaces the json.Numbers of a decoded document with ints or
+// floats, which YAML encodes unquoted
+func yamlNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = yamlNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
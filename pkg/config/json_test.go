@@ -0,0 +1,94 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const equivalentYAML = `
+server:
+  address: ":9090"
+  read_timeout: 15s
+  shutdown_timeout: 1m30s
+signal:
+  ping_interval: 20s
+  compression_enabled: true
+webrtc:
+  ice_servers:
+    - urls:
+        - "stun:stun.example.com:3478"
+  port_range:
+    min: 40000
+    max: 40100
+  rtcp_feedback:
+    vp8:
+      nack: true
+mesh:
+  latency_weight: 0.5
+  bandwidth_weight: 0.25
+auth:
+  access_token_ttl: 10m
+  user_roles:
+    ops-user: operator
+logging:
+  level: debug
+`
+
+const equivalentJSON = `{
+	"server": {"address": ":9090", "read_timeout": "15s", "shutdown_timeout": "1m30s"},
+	"signal": {"ping_interval": "20s", "compression_enabled": true},
+	"webrtc": {
+		"ice_servers": [{"urls": ["stun:stun.example.com:3478"]}],
+		"port_range": {"min": 40000, "max": 40100},
+		"rtcp_feedback": {"vp8": {"nack": true}}
+	},
+	"mesh": {"latency_weight": 0.5, "bandwidth_weight": 0.25},
+	"auth": {"access_token_ttl": "10m", "user_roles": {"ops-user": "operator"}},
+	"logging": {"level": "debug"}
+}`
+
+func TestLoad_JSONMatchesYAML(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+	jsonPath := filepath.Join(dir, "config.json")
+	writeConfigFile(t, yamlPath, equivalentYAML)
+	writeConfigFile(t, jsonPath, equivalentJSON)
+
+	fromYAML, err := Load(yamlPath)
+	if err != nil {
+		t.Fatalf("Load yaml: %v", err)
+	}
+	fromJSON, err := Load(jsonPath)
+	if err != nil {
+		t.Fatalf("Load json: %v", err)
+	}
+
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Fatalf("expected identical configs\nyaml: %+v\njson: %+v", fromYAML, fromJSON)
+	}
+	if fromJSON.Server.ShutdownTimeout != 90*time.Second || fromJSON.Auth.AccessTokenTTL != 10*time.Minute {
+		t.Fatalf("expected string durations to be parsed, got %v and %v", fromJSON.Server.ShutdownTimeout, fromJSON.Auth.AccessTokenTTL)
+	}
+	if fromJSON.WebRTC.PortRange.Min != 40000 || fromJSON.Mesh.LatencyWeight != 0.5 {
+		t.Fatalf("expected numbers to be applied, got port %d and weight %g", fromJSON.WebRTC.PortRange.Min, fromJSON.Mesh.LatencyWeight)
+	}
+}
+
+func TestLoad_JSONRejectsMalformedFiles(t *testing.T) {
+	cases := map[string]string{
+		"invalid duration": `{"server": {"read_timeout": "soon"}}`,
+		"syntax error":     `{"server": {"address": ":9090",}}`,
+		"not an object":    `["server"]`,
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			writeConfigFile(t, path, content)
+			if _, err := Load(path); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
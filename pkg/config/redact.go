@@ -0,0 +1,55 @@
+package config
+
+import "net/url"
+
+// RedactedValue replaces secrets in Redacted configs
+const RedactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the config safe to show for diagnostics: the JWT
+// secret, API key digests, Redis password, TURN credentials and database
+// password are replaced by RedactedValue. Empty secrets stay empty so a missing one is visible.
+func (c *Config) Redacted() *Config {
+	r := *c
+
+	r.Auth.JWTSecret = redact(c.Auth.JWTSecret)
+	r.Redis.Password = redact(c.Redis.Password)
+	r.Database.DSN = redactDSN(c.Database.DSN)
+
+	// A digest of a short key can be reversed by brute force
+	r.Auth.APIKeys = make([]APIKeyConfig, len(c.Auth.APIKeys))
+	for i, key := range c.Auth.APIKeys {
+		key.SHA256 = redact(key.SHA256)
+		r.Auth.APIKeys[i] = key
+	}
+
+	r.WebRTC.ICEServers = make([]ICEServerConfig, len(c.WebRTC.ICEServers))
+	for i, server := range c.WebRTC.ICEServers {
+		server.Credential = redact(server.Credential)
+		r.WebRTC.ICEServers[i] = server
+	}
+
+	return &r
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// redactDSN hides the password of a URL DSN and keeps host and database for
+// diagnostics; a DSN in any other form is redacted entirely
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" || u.User == nil {
+		return RedactedValue
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), RedactedValue)
+	}
+	return u.String()
+}
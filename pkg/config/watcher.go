@@ -0,0 +1,105 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Reloadable is implemented by components that can adopt a reloaded
+// configuration without a restart. ApplyConfig only sees configs that passed
+// Validate, and picks the settings it can change safely at runtime.
+type Reloadable interface {
+	ApplyConfig(cfg *Config)
+}
+
+// ReloadableFunc adapts a function to Reloadable
+type ReloadableFunc func(cfg *Config)
+
+func (f ReloadableFunc) ApplyConfig(cfg *Config) {
+	f(cfg)
+}
+
+// Watcher re-reads the config file on SIGHUP and hands each valid result to
+// its subscribers. An invalid or unreadable file is rejected and the current
+// config kept. Settings nobody subscribes to, such as listen addresses, keep
+// their startup values until a restart.
+type Watcher struct {
+	path string
+
+	mu          sync.Mutex
+	current     *Config
+	subscribers []Reloadable
+}
+
+// NewWatcher watches the file at path, current being the config loaded from it
+func NewWatcher(path string, current *Config) *Watcher {
+	return &Watcher{
+		path:    path,
+		current: current,
+	}
+}
+
+// Subscribe adds r to the components told about reloaded configs. Must be
+// called before Watch.
+func (w *Watcher) Subscribe(r Reloadable) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, r)
+}
+
+// Current returns the config in effect
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload reads and validates the config file and, when it is valid, makes it
+// current and applies it to every subscriber in subscription order
+func (w *Watcher) Reload() (*Config, error) {
+	// Load falls back to defaults for a missing file, which must not
+	// silently replace a running config
+	if _, err := os.Stat(w.path); err != nil {
+		return nil, fmt.Errorf("failed to reload config: %w", err)
+	}
+	cfg, err := Load(w.path)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.current = cfg
+	for _, r := range w.subscribers {
+		r.ApplyConfig(cfg)
+	}
+	return cfg, nil
+}
+
+// Watch reloads the config on every SIGHUP until ctx is done, reporting each
+// attempt to onReload: the new config, or the error it was rejected with.
+func (w *Watcher) Watch(ctx context.Context, onReload func(cfg *Config, err error)) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	w.watch(ctx, sighup, onReload)
+}
+
+func (w *Watcher) watch(ctx context.Context, reload <-chan os.Signal, onReload func(cfg *Config, err error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			cfg, err := w.Reload()
+			if onReload != nil {
+				onReload(cfg, err)
+			}
+		}
+	}
+}
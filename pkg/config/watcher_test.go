@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher_ReloadOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "logging:\n  level: info\n")
+	initial, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	watcher := NewWatcher(path, initial)
+	var applied []*Config
+	watcher.Subscribe(ReloadableFunc(func(cfg *Config) {
+		applied = append(applied, cfg)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sighup := make(chan os.Signal)
+	results := make(chan error)
+	go watcher.watch(ctx, sighup, func(_ *Config, err error) {
+		results <- err
+	})
+	reload := func() error {
+		sighup <- syscall.SIGHUP
+		select {
+		case err := <-results:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("reload was not attempted")
+			return nil
+		}
+	}
+
+	// A valid file replaces the config and reaches subscribers
+	writeConfigFile(t, path, "logging:\n  level: debug\nmesh:\n  latency_weight: 0.6\n")
+	if err := reload(); err != nil {
+		t.Fatalf("expected valid config to reload, got %v", err)
+	}
+	current := watcher.Current()
+	if current.Logging.Level != "debug" || current.Mesh.LatencyWeight != 0.6 {
+		t.Fatalf("expected reloaded values, got level %q and latency weight %g", current.Logging.Level, current.Mesh.LatencyWeight)
+	}
+	if len(applied) != 1 || applied[0] != current {
+		t.Fatalf("expected subscribers to get the reloaded config once, got %d calls", len(applied))
+	}
+
+	// An invalid file is rejected and the previous config kept
+	writeConfigFile(t, path, "mesh:\n  latency_weight: 0\n  bandwidth_weight: 0\n  reliability_weight: 0\n")
+	if err := reload(); !errors.Is(err, ErrInvalidMeshWeights) {
+		t.Fatalf("expected invalid mesh weights to be rejected, got %v", err)
+	}
+	if watcher.Current() != current {
+		t.Fatal("expected the previous config to stay current")
+	}
+	if len(applied) != 1 {
+		t.Fatalf("expected subscribers not to see a rejected config, got %d calls", len(applied))
+	}
+}
+
+func TestWatcher_ReloadKeepsConfigWhenFileIsGone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	initial := DefaultConfig()
+	watcher := NewWatcher(path, initial)
+
+	if _, err := watcher.Reload(); err == nil {
+		t.Fatal("expected reload of a missing file to fail rather than fall back to defaults")
+	}
+	if watcher.Current() != initial {
+		t.Fatal("expected the previous config to stay current")
+	}
+}
@@ -7,21 +7,30 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// New returns a JSON logger writing to stdout at the given level
 func New(level string) *zap.Logger {
-	var zapLevel zapcore.Level
+	return NewWithLevel(zap.NewAtomicLevelAt(ParseLevel(level)))
+}
+
+// ParseLevel maps a configured level name to its zap level, info when unknown
+func ParseLevel(level string) zapcore.Level {
 	switch level {
 	case "debug":
-		zapLevel = zap.DebugLevel
+		return zap.DebugLevel
 	case "info":
-		zapLevel = zap.InfoLevel
+		return zap.InfoLevel
 	case "warn":
-		zapLevel = zap.WarnLevel
+		return zap.WarnLevel
 	case "error":
-		zapLevel = zap.ErrorLevel
+		return zap.ErrorLevel
 	default:
-		zapLevel = zap.InfoLevel
+		return zap.InfoLevel
 	}
+}
 
+// NewWithLevel is New with a level that can be changed while the logger is
+// in use, e.g. on a config reload
+func NewWithLevel(level zap.AtomicLevel) *zap.Logger {
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "timestamp",
 		LevelKey:       "level",
@@ -39,7 +48,7 @@ func New(level string) *zap.Logger {
 	core := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig),
 		zapcore.AddSync(os.Stdout),
-		zapLevel,
+		level,
 	)
 
 	return zap.New(core, zap.AddCaller())
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RateLimitedLogger collapses repeated error warnings. Occurrences are keyed
+// by a caller-chosen scope (e.g. a track ID) and the error's type. The first
+// occurrence in a window is logged immediately; later ones in the same window
+// are only counted and reported as a single summary line once the window has
+// passed (on the next occurrence or on Flush).
+type RateLimitedLogger struct {
+	logger *zap.SugaredLogger
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[rateLimitKey]*rateLimitEntry
+}
+
+type rateLimitKey struct {
+	scope   string
+	errType string
+}
+
+type rateLimitEntry struct {
+	windowStart   time.Time
+	suppressed    int
+	msg           string
+	keysAndValues []interface{}
+}
+
+// NewRateLimitedLogger creates a logger that emits at most one line plus one
+// summary per scope and error type per window.
+func NewRateLimitedLogger(logger *zap.SugaredLogger, window time.Duration) *RateLimitedLogger {
+	return &RateLimitedLogger{
+		logger:  logger,
+		window:  window,
+		now:     time.Now,
+		entries: make(map[rateLimitKey]*rateLimitEntry),
+	}
+}
+
+// Warnw logs msg at warn level with err attached, unless the same scope and
+// error type was already logged in the current window.
+func (l *RateLimitedLogger) Warnw(scope string, err error, msg string, keysAndValues ...interface{}) {
+	keysAndValues = append(keysAndValues, "error", err)
+	key := rateLimitKey{scope: scope, errType: fmt.Sprintf("%T", err)}
+	now := l.now()
+
+	l.mu.Lock()
+	entry, ok := l.entries[key]
+	if ok && now.Sub(entry.windowStart) < l.window {
+		entry.suppressed++
+		entry.msg = msg
+		entry.keysAndValues = keysAndValues
+		l.mu.Unlock()
+		return
+	}
+	l.entries[key] = &rateLimitEntry{windowStart: now}
+	l.mu.Unlock()
+
+	if ok && entry.suppressed > 0 {
+		l.logSummary(entry)
+	}
+	l.logger.Warnw(msg, keysAndValues...)
+}
+
+// Flush emits pending summaries for scope and forgets it. Call it when the
+// source of the errors goes away (e.g. a track ends).
+func (l *RateLimitedLogger) Flush(scope string) {
+	var pending []*rateLimitEntry
+
+	l.mu.Lock()
+	for key, entry := range l.entries {
+		if key.scope != scope {
+			continue
+		}
+		if entry.suppressed > 0 {
+			pending = append(pending, entry)
+		}
+		delete(l.entries, key)
+	}
+	l.mu.Unlock()
+
+	for _, entry := range pending {
+		l.logSummary(entry)
+	}
+}
+
+func (l *RateLimitedLogger) logSummary(entry *rateLimitEntry) {
+	keysAndValues := append([]interface{}{}, entry.keysAndValues...)
+	keysAndValues = append(keysAndValues, "suppressed_count", entry.suppressed, "window", l.window)
+	l.logger.Warnw(entry.msg+" (repeated)", keysAndValues...)
+}
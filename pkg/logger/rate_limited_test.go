@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newObservedRateLimitedLogger(window time.Duration) (*RateLimitedLogger, *observer.ObservedLogs, *time.Time) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	l := NewRateLimitedLogger(zap.New(core).Sugar(), window)
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+	return l, logs, &now
+}
+
+func TestRateLimitedLogger_CollapsesRepeatsIntoSummary(t *testing.T) {
+	l, logs, now := newObservedRateLimitedLogger(10 * time.Second)
+	err := errors.New("write: broken pipe")
+
+	for i := 0; i < 100; i++ {
+		l.Warnw("track-1", err, "error writing RTP packet", "track_id", "track-1")
+		*now = now.Add(10 * time.Millisecond)
+	}
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected only the first occurrence to be logged within the window, got %d lines", logs.Len())
+	}
+
+	// The next occurrence after the window reports the suppressed count, then logs itself
+	*now = now.Add(10 * time.Second)
+	l.Warnw("track-1", err, "error writing RTP packet", "track_id", "track-1")
+
+	entries := logs.TakeAll()
+	if len(entries) != 3 {
+		t.Fatalf("expected first line, one summary and the new occurrence, got %d lines", len(entries))
+	}
+	summary := entries[1]
+	if summary.Message != "error writing RTP packet (repeated)" {
+		t.Fatalf("unexpected summary message %q", summary.Message)
+	}
+	if got := summary.ContextMap()["suppressed_count"]; got != int64(99) {
+		t.Fatalf("expected suppressed_count 99, got %v", got)
+	}
+}
+
+func TestRateLimitedLogger_KeysByScopeAndErrorType(t *testing.T) {
+	l, logs, _ := newObservedRateLimitedLogger(10 * time.Second)
+
+	l.Warnw("track-1", errors.New("a"), "failure")
+	l.Warnw("track-2", errors.New("a"), "failure")
+	l.Warnw("track-1", &customError{}, "failure")
+	l.Warnw("track-1", errors.New("b"), "failure")
+
+	if logs.Len() != 3 {
+		t.Fatalf("expected one line per scope/error type, got %d", logs.Len())
+	}
+}
+
+func TestRateLimitedLogger_FlushEmitsPendingSummary(t *testing.T) {
+	l, logs, _ := newObservedRateLimitedLogger(time.Minute)
+	err := errors.New("read: EOF")
+
+	l.Warnw("track-1", err, "error reading track")
+	l.Warnw("track-1", err, "error reading track")
+	l.Warnw("track-1", err, "error reading track")
+	l.Flush("track-1")
+	l.Flush("track-1")
+
+	entries := logs.TakeAll()
+	if len(entries) != 2 {
+		t.Fatalf("expected first line and one summary, got %d lines", len(entries))
+	}
+	if got := entries[1].ContextMap()["suppressed_count"]; got != int64(2) {
+		t.Fatalf("expected suppressed_count 2, got %v", got)
+	}
+
+	// A flushed scope starts over and logs immediately
+	l.Warnw("track-1", err, "error reading track")
+	if logs.Len() != 1 {
+		t.Fatalf("expected flushed scope to log immediately, got %d lines", logs.Len())
+	}
+}
+
+type customError struct{}
+
+func (*customError) Error() string { return "custom" }
@@ -0,0 +1,69 @@
+// Package ratelimit provides rate limiters keyed by caller.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// KeyedLimiter allows up to a number of events per minute for each key, with
+// bursts of up to that number. A nil *KeyedLimiter allows everything.
+type KeyedLimiter[K comparable] struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	entries map[K]*keyedEntry
+}
+
+type keyedEntry struct {
+	limiter *rate.Limiter
+	dropped bool
+}
+
+// NewKeyedLimiter returns a limiter allowing perMinute events per key, or nil
+// (no limit) when perMinute <= 0.
+func NewKeyedLimiter[K comparable](perMinute int) *KeyedLimiter[K] {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &KeyedLimiter[K]{
+		limit:   rate.Every(time.Minute / time.Duration(perMinute)),
+		burst:   perMinute,
+		entries: make(map[K]*keyedEntry),
+	}
+}
+
+// Allow reports whether an event for key is within the limit. firstDrop is
+// true for the first rejected event of the key, so callers can warn once.
+func (l *KeyedLimiter[K]) Allow(key K) (allowed, firstDrop bool) {
+	if l == nil {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &keyedEntry{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.entries[key] = entry
+	}
+	if entry.limiter.Allow() {
+		return true, false
+	}
+	firstDrop = !entry.dropped
+	entry.dropped = true
+	return false, firstDrop
+}
+
+// Forget drops the state of a key, e.g. when its peer disconnects
+func (l *KeyedLimiter[K]) Forget(key K) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
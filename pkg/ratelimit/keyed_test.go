@@ -0,0 +1,45 @@
+package ratelimit
+
+import "testing"
+
+func TestKeyedLimiter_LimitsEachKey(t *testing.T) {
+	l := NewKeyedLimiter[string](3)
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := l.Allow("a"); !allowed {
+			t.Fatalf("event %d within burst rejected", i)
+		}
+	}
+	allowed, firstDrop := l.Allow("a")
+	if allowed || !firstDrop {
+		t.Fatalf("4th event: allowed=%v firstDrop=%v, want rejected first drop", allowed, firstDrop)
+	}
+	allowed, firstDrop = l.Allow("a")
+	if allowed || firstDrop {
+		t.Fatalf("5th event: allowed=%v firstDrop=%v, want rejected repeat drop", allowed, firstDrop)
+	}
+
+	// Other keys have their own budget
+	if allowed, _ := l.Allow("b"); !allowed {
+		t.Fatal("key b limited by key a")
+	}
+
+	// A forgotten key starts over
+	l.Forget("a")
+	if allowed, _ := l.Allow("a"); !allowed {
+		t.Fatal("forgotten key still limited")
+	}
+}
+
+func TestKeyedLimiter_DisabledAllowsEverything(t *testing.T) {
+	l := NewKeyedLimiter[string](0)
+	if l != nil {
+		t.Fatal("expected nil limiter for a zero limit")
+	}
+	for i := 0; i < 1000; i++ {
+		if allowed, _ := l.Allow("a"); !allowed {
+			t.Fatal("nil limiter rejected an event")
+		}
+	}
+	l.Forget("a")
+}
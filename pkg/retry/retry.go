@@ -2,6 +2,7 @@ package retry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -15,8 +16,11 @@ type Config struct {
 	MaxDelay         time.Duration // Maximum delay between retries
 	Multiplier       float64       // Exponential backoff multiplier (typically 2.0)
 	Jitter           bool          // Add random jitter to prevent thundering herd
-	RetryableErrors  []error       // List of errors that should trigger retry (nil = all errors)
-	NonRetryableErrors []error     // List of errors that should NOT trigger retry
+	RetryableErrors  []error       // List of errors that should trigger retry (nil = all errors); wrapped errors match
+	NonRetryableErrors []error     // List of errors that should NOT trigger retry; wrapped errors match
+	// OnRetry, when set, is called before each backoff sleep with the attempt
+	// that failed (starting at 1), its error and the delay before the next one
+	OnRetry func(attempt int, err error, nextDelay time.Duration)
 }
 
 // DefaultConfig returns a default retry configuration
@@ -72,6 +76,9 @@ func Retry(ctx context.Context, cfg Config, fn func() error) error {
 
 		// Calculate delay with exponential backoff
 		delay := calculateDelay(cfg, attempt)
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt+1, err, delay)
+		}
 
 		// Wait before retry
 		select {
@@ -128,6 +135,9 @@ func RetryWithResult[T any](ctx context.Context, cfg Config, fn func() (T, error
 
 		// Calculate delay with exponential backoff
 		delay := calculateDelay(cfg, attempt)
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt+1, err, delay)
+		}
 
 		// Wait before retry
 		select {
@@ -164,18 +174,19 @@ func calculateDelay(cfg Config, attempt int) time.Duration {
 
 // isRetryable checks if an error is in the retryable errors list
 func isRetryable(err error, retryableErrors []error) bool {
-	for _, retryableErr := range retryableErrors {
-		if err == retryableErr || fmt.Sprintf("%T", err) == fmt.Sprintf("%T", retryableErr) {
-			return true
-		}
-	}
-	return false
+	return matchesAny(err, retryableErrors)
 }
 
 // isNonRetryable checks if an error is in the non-retryable errors list
 func isNonRetryable(err error, nonRetryableErrors []error) bool {
-	for _, nonRetryableErr := range nonRetryableErrors {
-		if err == nonRetryableErr || fmt.Sprintf("%T", err) == fmt.Sprintf("%T", nonRetryableErr) {
+	return matchesAny(err, nonRetryableErrors)
+}
+
+// matchesAny reports whether err is, or wraps, one of targets. Matching by
+// type instead would make every errors.New sentinel match every other one.
+func matchesAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
 			return true
 		}
 	}
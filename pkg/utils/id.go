@@ -4,12 +4,42 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// IDGenerator produces unique, prefixed identifiers (e.g. "stream_<id>").
+// Services take one so tests can inject deterministic IDs.
+type IDGenerator interface {
+	NewID(prefix string) string
+}
+
+// UUIDGenerator generates IDs from random (v4) UUIDs.
+type UUIDGenerator struct{}
+
+// NewID returns prefix_<uuid>
+func (UUIDGenerator) NewID(prefix string) string {
+	return prefix + "_" + uuid.NewString()
+}
+
+// SequentialGenerator generates predictable IDs (prefix_1, prefix_2, ...) for tests.
+type SequentialGenerator struct {
+	counter atomic.Uint64
+}
+
+// NewID returns prefix_<n> where n increases on every call
+func (g *SequentialGenerator) NewID(prefix string) string {
+	return fmt.Sprintf("%s_%d", prefix, g.counter.Add(1))
+}
+
+// DefaultIDGenerator is used by the package-level Generate*ID helpers.
+var DefaultIDGenerator IDGenerator = UUIDGenerator{}
+
 // GenerateStreamID generates a unique stream ID
 func GenerateStreamID() string {
-	return GenerateID("stream")
+	return DefaultIDGenerator.NewID("stream")
 }
 
 // GeneratePeerID generates a unique peer ID
@@ -19,7 +49,7 @@ func GeneratePeerID() string {
 
 // GenerateSessionID generates a unique session ID
 func GenerateSessionID() string {
-	return GenerateID("session")
+	return DefaultIDGenerator.NewID("session")
 }
 
 // GenerateUserID generates a unique user ID
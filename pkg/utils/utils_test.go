@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -152,6 +153,52 @@ func TestGenerateStreamID(t *testing.T) {
 	}
 }
 
+func TestUUIDGenerator_UniqueUnderConcurrency(t *testing.T) {
+	const workers, perWorker = 32, 500
+	gen := UUIDGenerator{}
+
+	var mu sync.Mutex
+	seen := make(map[string]struct{}, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]string, 0, perWorker)
+			for i := 0; i < perWorker; i++ {
+				ids = append(ids, gen.NewID("session"))
+			}
+			mu.Lock()
+			for _, id := range ids {
+				seen[id] = struct{}{}
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != workers*perWorker {
+		t.Errorf("expected %d unique IDs, got %d", workers*perWorker, len(seen))
+	}
+	for id := range seen {
+		if !strings.HasPrefix(id, "session_") {
+			t.Fatalf("expected prefix 'session_', got %s", id)
+		}
+		break
+	}
+}
+
+func TestSequentialGenerator(t *testing.T) {
+	gen := &SequentialGenerator{}
+
+	if id := gen.NewID("stream"); id != "stream_1" {
+		t.Errorf("expected stream_1, got %s", id)
+	}
+	if id := gen.NewID("session"); id != "session_2" {
+		t.Errorf("expected session_2, got %s", id)
+	}
+}
+
 func TestIsEmpty(t *testing.T) {
 	tests := []struct {
 		input    string
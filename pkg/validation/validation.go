@@ -1,20 +1,62 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"unicode/utf8"
 )
 
+const (
+	// DefaultStreamIDCharset is the regexp character class body of the
+	// characters allowed in stream IDs
+	DefaultStreamIDCharset = "A-Za-z0-9_-"
+	// DefaultStreamIDMaxLength is the longest stream ID accepted
+	DefaultStreamIDMaxLength = 100
+
+	// MaxCodecs is the most codec names a peer may advertise
+	MaxCodecs = 20
+	// MaxCodecNameLength is the longest codec name accepted, in bytes
+	MaxCodecNameLength = 64
+	// MaxCodecsTotalBytes caps the combined length of all codec names
+	MaxCodecsTotalBytes = 512
+
+	// MaxBandwidth is the highest bandwidth a peer may report, in bits/s
+	MaxBandwidth = 100000000
+	// MaxLatencyMs is the highest latency a peer may report, in milliseconds
+	MaxLatencyMs = 60000
+)
+
+var (
+	// ErrCodecNameTooLong is returned for a codec name over MaxCodecNameLength
+	ErrCodecNameTooLong = errors.New("codec name too long")
+	// ErrCodecListTooLarge is returned when the codec list exceeds MaxCodecs
+	// entries or MaxCodecsTotalBytes in total
+	ErrCodecListTooLarge = errors.New("codec list too large")
+	// ErrMetricOutOfRange is returned for a reported network metric outside
+	// its plausible range
+	ErrMetricOutOfRange = errors.New("metric out of range")
+)
+
+// streamIDPolicy is the stream ID allowlist set by SetStreamIDPolicy
+type streamIDPolicy struct {
+	charset   string
+	maxLength int
+	char      *regexp.Regexp // One allowed character
+}
+
+var (
+	streamIDPolicyMu sync.RWMutex
+	streamIDRules    = mustStreamIDPolicy(DefaultStreamIDCharset, DefaultStreamIDMaxLength)
+)
+
 var (
 	// EmailRegex validates email format
 	EmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
 	
-	// StreamIDRegex validates stream ID format
-	StreamIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	
 	// PeerIDRegex validates peer ID format
 	PeerIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 )
@@ -66,16 +108,74 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
-// ValidateStreamID validates stream ID
+func newStreamIDPolicy(charset string, maxLength int) (*streamIDPolicy, error) {
+	if charset == "" {
+		return nil, fmt.Errorf("stream ID charset is required")
+	}
+	if maxLength < 1 {
+		return nil, fmt.Errorf("stream ID max length must be at least 1")
+	}
+	char, err := regexp.Compile(`^[` + charset + `]$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream ID charset %q: %w", charset, err)
+	}
+	return &streamIDPolicy{charset: charset, maxLength: maxLength, char: char}, nil
+}
+
+func mustStreamIDPolicy(charset string, maxLength int) *streamIDPolicy {
+	policy, err := newStreamIDPolicy(charset, maxLength)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// ValidateStreamIDPolicy reports whether SetStreamIDPolicy would accept the
+// charset and maximum length
+func ValidateStreamIDPolicy(charset string, maxLength int) error {
+	_, err := newStreamIDPolicy(charset, maxLength)
+	return err
+}
+
+// SetStreamIDPolicy sets the characters (a regexp character class body such
+// as "A-Za-z0-9_-") and maximum length allowed in stream IDs by
+// ValidateStreamID. The previous policy is kept when either is invalid.
+func SetStreamIDPolicy(charset string, maxLength int) error {
+	policy, err := newStreamIDPolicy(charset, maxLength)
+	if err != nil {
+		return err
+	}
+	streamIDPolicyMu.Lock()
+	streamIDRules = policy
+	streamIDPolicyMu.Unlock()
+	return nil
+}
+
+// ValidateStreamID validates a stream ID against the stream ID policy; IDs
+// with disallowed characters get an error naming them
 func ValidateStreamID(streamID string) error {
+	streamIDPolicyMu.RLock()
+	policy := streamIDRules
+	streamIDPolicyMu.RUnlock()
+
 	if streamID == "" {
 		return fmt.Errorf("stream ID is required")
 	}
-	if len(streamID) > 100 {
-		return fmt.Errorf("stream ID is too long (max 100 characters)")
+	if utf8.RuneCountInString(streamID) > policy.maxLength {
+		return fmt.Errorf("stream ID is too long (max %d characters)", policy.maxLength)
 	}
-	if !StreamIDRegex.MatchString(streamID) {
-		return fmt.Errorf("invalid stream ID format")
+
+	var disallowed []string
+	seen := make(map[rune]bool)
+	for _, r := range streamID {
+		if seen[r] || policy.char.MatchString(string(r)) {
+			continue
+		}
+		seen[r] = true
+		disallowed = append(disallowed, fmt.Sprintf("%q", r))
+	}
+	if len(disallowed) > 0 {
+		return fmt.Errorf("stream ID contains disallowed characters %s (allowed: [%s])", strings.Join(disallowed, ", "), policy.charset)
 	}
 	return nil
 }
@@ -166,6 +266,47 @@ func ValidateQuality(quality string) error {
 	return nil
 }
 
+// ValidateCodecs validates the codec names a peer advertises in its
+// capabilities. They end up in maps and metric labels, so both each name and
+// the list as a whole are bounded.
+func ValidateCodecs(codecs []string) error {
+	if len(codecs) > MaxCodecs {
+		return fmt.Errorf("%w: %d codecs (max %d)", ErrCodecListTooLarge, len(codecs), MaxCodecs)
+	}
+
+	total := 0
+	for i, codec := range codecs {
+		if codec == "" {
+			return fmt.Errorf("codecs[%d] is empty", i)
+		}
+		if len(codec) > MaxCodecNameLength {
+			return fmt.Errorf("%w: codecs[%d] is %d bytes (max %d)", ErrCodecNameTooLong, i, len(codec), MaxCodecNameLength)
+		}
+		total += len(codec)
+	}
+	if total > MaxCodecsTotalBytes {
+		return fmt.Errorf("%w: %d bytes of codec names (max %d)", ErrCodecListTooLarge, total, MaxCodecsTotalBytes)
+	}
+	return nil
+}
+
+// ValidateNetworkMetrics validates the network metrics a peer reports about
+// itself. They feed mesh scoring, so nonsense values are rejected rather
+// than stored.
+func ValidateNetworkMetrics(bandwidth int, packetLoss float64, latencyMs int64) error {
+	if bandwidth < 0 || bandwidth > MaxBandwidth {
+		return fmt.Errorf("%w: bandwidth %d (must be 0-%d)", ErrMetricOutOfRange, bandwidth, MaxBandwidth)
+	}
+	// Written so that NaN fails as well
+	if !(packetLoss >= 0 && packetLoss <= 1) {
+		return fmt.Errorf("%w: packet_loss %v (must be 0-1)", ErrMetricOutOfRange, packetLoss)
+	}
+	if latencyMs < 0 || latencyMs > MaxLatencyMs {
+		return fmt.Errorf("%w: latency %d ms (must be 0-%d)", ErrMetricOutOfRange, latencyMs, MaxLatencyMs)
+	}
+	return nil
+}
+
 // ValidateNonEmptyString validates that string is not empty after trimming
 func ValidateNonEmptyString(s, fieldName string) error {
 	s = strings.TrimSpace(s)
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -175,3 +176,130 @@ func TestValidateQuality(t *testing.T) {
 	}
 }
 
+
+func TestValidateStreamID_NamesDisallowedCharacters(t *testing.T) {
+	err := ValidateStreamID("stream/1 2/x")
+	if err == nil {
+		t.Fatal("expected an error for disallowed characters")
+	}
+	for _, want := range []string{`'/'`, `' '`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not name %s", err, want)
+		}
+	}
+	if strings.Count(err.Error(), `'/'`) != 1 {
+		t.Errorf("error %q names a character more than once", err)
+	}
+}
+
+func TestSetStreamIDPolicy(t *testing.T) {
+	defer func() {
+		_ = SetStreamIDPolicy(DefaultStreamIDCharset, DefaultStreamIDMaxLength)
+	}()
+
+	if err := SetStreamIDPolicy("a-z.", 8); err != nil {
+		t.Fatalf("SetStreamIDPolicy() error = %v", err)
+	}
+	tests := []struct {
+		streamID string
+		wantErr  bool
+	}{
+		{"room.one", false},
+		{"Room", true},
+		{"room_one", true},
+		{"room.one1", true},
+		{"room.too.long", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateStreamID(tt.streamID); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateStreamID(%q) error = %v, wantErr %v", tt.streamID, err, tt.wantErr)
+		}
+	}
+
+	// An invalid policy keeps the current one
+	if err := SetStreamIDPolicy("a-", 0); err == nil {
+		t.Error("expected an error for a zero max length")
+	}
+	if err := SetStreamIDPolicy("z-a", 10); err == nil {
+		t.Error("expected an error for an invalid character class")
+	}
+	if err := ValidateStreamID("room.one"); err != nil {
+		t.Errorf("policy changed by a rejected update: %v", err)
+	}
+}
+
+func TestValidateCodecs(t *testing.T) {
+	manyCodecs := make([]string, MaxCodecs+1)
+	for i := range manyCodecs {
+		manyCodecs[i] = "VP8"
+	}
+	bulkyCodecs := make([]string, MaxCodecs)
+	for i := range bulkyCodecs {
+		bulkyCodecs[i] = strings.Repeat("x", MaxCodecNameLength)
+	}
+
+	tests := []struct {
+		name    string
+		codecs  []string
+		wantErr error
+	}{
+		{"none", nil, nil},
+		{"typical", []string{"VP8", "H264", "opus"}, nil},
+		{"longest name", []string{strings.Repeat("x", MaxCodecNameLength)}, nil},
+		{"name too long", []string{"VP8", strings.Repeat("x", MaxCodecNameLength+1)}, ErrCodecNameTooLong},
+		{"too many", manyCodecs, ErrCodecListTooLarge},
+		{"too many bytes", bulkyCodecs, ErrCodecListTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCodecs(tt.codecs)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("ValidateCodecs() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateCodecs() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := ValidateCodecs([]string{"VP8", ""}); err == nil {
+		t.Error("expected an error for an empty codec name")
+	}
+}
+
+func TestValidateNetworkMetrics(t *testing.T) {
+	tests := []struct {
+		name       string
+		bandwidth  int
+		packetLoss float64
+		latencyMs  int64
+		wantErr    bool
+	}{
+		{"typical", 1500, 0.02, 50, false},
+		{"bounds", MaxBandwidth, 1, MaxLatencyMs, false},
+		{"zeros", 0, 0, 0, false},
+		{"packet loss above one", 1500, 5.0, 50, true},
+		{"negative packet loss", 1500, -0.1, 50, true},
+		{"negative latency", 1500, 0.02, -1, true},
+		{"latency too high", 1500, 0.02, MaxLatencyMs + 1, true},
+		{"negative bandwidth", -1, 0.02, 50, true},
+		{"bandwidth too high", MaxBandwidth + 1, 0.02, 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNetworkMetrics(tt.bandwidth, tt.packetLoss, tt.latencyMs)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("ValidateNetworkMetrics() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrMetricOutOfRange) {
+				t.Errorf("ValidateNetworkMetrics() error = %v, want %v", err, ErrMetricOutOfRange)
+			}
+		})
+	}
+}
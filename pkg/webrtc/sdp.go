@@ -1 +1,132 @@
 package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Media directions as they appear in SDP attributes
+const (
+	DirectionSendRecv = "sendrecv"
+	DirectionSendOnly = "sendonly"
+	DirectionRecvOnly = "recvonly"
+	DirectionInactive = "inactive"
+)
+
+// MediaRole is the part a peer plays in a media session, which decides the
+// media directions its SDP may use.
+type MediaRole int
+
+const (
+	// MediaRoleSubscriber only receives media
+	MediaRoleSubscriber MediaRole = iota
+	// MediaRolePublisher originates media
+	MediaRolePublisher
+	// MediaRoleRelay receives media and forwards it to other peers
+	MediaRoleRelay
+)
+
+// ErrMediaDirectionMismatch is returned when an SDP's media directions do not
+// match the role of the peer that sent it.
+var ErrMediaDirectionMismatch = errors.New("media direction does not match peer role")
+
+// MediaSection is an audio or video m= section of an SDP.
+type MediaSection struct {
+	Kind      string
+	Direction string
+}
+
+// MediaSections returns the audio and video sections of sdp with their
+// effective direction. A section without its own direction attribute inherits
+// the session-level one, which defaults to sendrecv.
+func MediaSections(sdp string) []MediaSection {
+	sessionDirection := DirectionSendRecv
+	var sections []MediaSection
+	var current *MediaSection
+	inMedia := false
+
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.HasPrefix(line, "m=") {
+			inMedia = true
+			current = nil
+			fields := strings.Fields(strings.TrimPrefix(line, "m="))
+			if len(fields) == 0 {
+				continue
+			}
+			kind := fields[0]
+			if kind != "audio" && kind != "video" {
+				continue
+			}
+			sections = append(sections, MediaSection{Kind: kind})
+			current = &sections[len(sections)-1]
+			continue
+		}
+
+		direction, ok := parseDirection(line)
+		if !ok {
+			continue
+		}
+		if !inMedia {
+			sessionDirection = direction
+		} else if current != nil {
+			current.Direction = direction
+		}
+	}
+
+	for i := range sections {
+		if sections[i].Direction == "" {
+			sections[i].Direction = sessionDirection
+		}
+	}
+	return sections
+}
+
+func parseDirection(line string) (string, bool) {
+	switch line {
+	case "a=" + DirectionSendRecv:
+		return DirectionSendRecv, true
+	case "a=" + DirectionSendOnly:
+		return DirectionSendOnly, true
+	case "a=" + DirectionRecvOnly:
+		return DirectionRecvOnly, true
+	case "a=" + DirectionInactive:
+		return DirectionInactive, true
+	}
+	return "", false
+}
+
+// ValidateMediaDirections checks that every audio/video section of sdp fits
+// the sender's role: publishers send (sendonly or sendrecv), subscribers only
+// receive (recvonly) and relays receive with or without sending back
+// (recvonly or sendrecv). Inactive sections carry no media and are always allowed.
+func ValidateMediaDirections(sdp string, role MediaRole) error {
+	for _, section := range MediaSections(sdp) {
+		if section.Direction == DirectionInactive {
+			continue
+		}
+		switch role {
+		case MediaRolePublisher:
+			if section.Direction == DirectionSendOnly || section.Direction == DirectionSendRecv {
+				continue
+			}
+			return fmt.Errorf("%w: publisher %s section is %s, must be %s or %s",
+				ErrMediaDirectionMismatch, section.Kind, section.Direction, DirectionSendOnly, DirectionSendRecv)
+		case MediaRoleRelay:
+			if section.Direction == DirectionRecvOnly || section.Direction == DirectionSendRecv {
+				continue
+			}
+			return fmt.Errorf("%w: relay %s section is %s, must be %s or %s",
+				ErrMediaDirectionMismatch, section.Kind, section.Direction, DirectionRecvOnly, DirectionSendRecv)
+		default:
+			if section.Direction == DirectionRecvOnly {
+				continue
+			}
+			return fmt.Errorf("%w: subscriber %s section is %s, must be %s",
+				ErrMediaDirectionMismatch, section.Kind, section.Direction, DirectionRecvOnly)
+		}
+	}
+	return nil
+}
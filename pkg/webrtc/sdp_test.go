@@ -0,0 +1,82 @@
+package webrtc
+
+import (
+	"errors"
+	"testing"
+)
+
+func buildSDP(session string, media ...string) string {
+	sdp := "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n" + session
+	for _, m := range media {
+		sdp += m
+	}
+	return sdp
+}
+
+func TestMediaSections(t *testing.T) {
+	sdp := buildSDP("a=recvonly\r\n",
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\na=mid:0\r\na=sendonly\r\n",
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\na=mid:1\r\n",
+		"m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\na=mid:2\r\n",
+	)
+
+	sections := MediaSections(sdp)
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 media sections, got %d", len(sections))
+	}
+	if sections[0].Kind != "audio" || sections[0].Direction != DirectionSendOnly {
+		t.Errorf("unexpected audio section: %+v", sections[0])
+	}
+	// No own attribute: inherits the session-level direction
+	if sections[1].Kind != "video" || sections[1].Direction != DirectionRecvOnly {
+		t.Errorf("unexpected video section: %+v", sections[1])
+	}
+}
+
+func TestMediaSections_DefaultsToSendRecv(t *testing.T) {
+	sections := MediaSections(buildSDP("", "m=video 9 UDP/TLS/RTP/SAVPF 96\n"))
+	if len(sections) != 1 || sections[0].Direction != DirectionSendRecv {
+		t.Fatalf("expected a single sendrecv section, got %+v", sections)
+	}
+}
+
+func TestValidateMediaDirections(t *testing.T) {
+	recvOnly := buildSDP("", "m=video 9 UDP/TLS/RTP/SAVPF 96\r\na=recvonly\r\n")
+	sendOnly := buildSDP("", "m=video 9 UDP/TLS/RTP/SAVPF 96\r\na=sendonly\r\n")
+	sendRecv := buildSDP("", "m=audio 9 UDP/TLS/RTP/SAVPF 111\r\na=sendrecv\r\n")
+	inactive := buildSDP("", "m=video 0 UDP/TLS/RTP/SAVPF 96\r\na=inactive\r\n")
+
+	tests := []struct {
+		name    string
+		sdp     string
+		role    MediaRole
+		wantErr bool
+	}{
+		{"subscriber recvonly", recvOnly, MediaRoleSubscriber, false},
+		{"subscriber sendonly", sendOnly, MediaRoleSubscriber, true},
+		{"subscriber sendrecv", sendRecv, MediaRoleSubscriber, true},
+		{"publisher sendonly", sendOnly, MediaRolePublisher, false},
+		{"publisher sendrecv", sendRecv, MediaRolePublisher, false},
+		{"publisher recvonly", recvOnly, MediaRolePublisher, true},
+		{"relay sendrecv", sendRecv, MediaRoleRelay, false},
+		{"relay recvonly", recvOnly, MediaRoleRelay, false},
+		{"relay sendonly", sendOnly, MediaRoleRelay, true},
+		{"inactive allowed for all", inactive, MediaRoleSubscriber, false},
+		{"no media sections", buildSDP(""), MediaRoleSubscriber, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMediaDirections(tt.sdp, tt.role)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMediaDirectionMismatch) {
+					t.Fatalf("expected ErrMediaDirectionMismatch, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
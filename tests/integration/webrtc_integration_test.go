@@ -21,7 +21,7 @@ func TestStreamLifecycleIntegration(t *testing.T) {
 	meshRepo := memory.NewMemoryMeshRepository()
 	cfg := config.DefaultConfig()
 	logger := logger.New("info").Sugar()
-	meshService := services.NewMeshService(peerRepo, meshRepo, cfg.Mesh, logger)
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, logger)
 	metricsService := services.NewMetricsService()
 	streamService := services.NewStreamService(streamRepo, peerRepo, meshRepo, meshService, metricsService)
 
@@ -99,7 +99,7 @@ func TestMeshServiceIntegration(t *testing.T) {
 	meshRepo := memory.NewMemoryMeshRepository()
 	cfg := config.DefaultConfig()
 	logger := logger.New("info").Sugar()
-	meshService := services.NewMeshService(peerRepo, meshRepo, cfg.Mesh, logger)
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, logger)
 
 	ctx := context.Background()
 	streamID := domain.StreamID("mesh-test-stream")
@@ -10,6 +10,7 @@ import (
 	"rillnet/internal/core/services"
 	httphandlers "rillnet/internal/handlers/http"
 	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/recording"
 	repositories "rillnet/internal/infrastructure/repositories"
 	webrtcinfra "rillnet/internal/infrastructure/webrtc"
 	"rillnet/pkg/circuitbreaker"
@@ -56,7 +57,7 @@ func NewIngestTestEnv(t *testing.T, cfg *config.Config) *IngestTestEnv {
 
 	qualityService := services.NewQualityService()
 	metricsService := services.NewMetricsService()
-	meshService := services.NewMeshService(peerRepo, meshRepo, cfg.Mesh, log)
+	meshService := services.NewMeshService(peerRepo, meshRepo, streamRepo, cfg.Mesh, log)
 	streamService := services.NewStreamService(streamRepo, peerRepo, meshRepo, meshService, metricsService)
 	authService := services.NewAuthService(
 		cfg.Auth.JWTSecret,
@@ -66,6 +67,7 @@ func NewIngestTestEnv(t *testing.T, cfg *config.Config) *IngestTestEnv {
 		nil,
 		nil,
 	)
+	authService.(services.RefreshRotationHooks).SetRefreshTokenStore(factory.CreateRefreshTokenFamilyStore())
 
 	var iceServers []webrtc.ICEServer
 	for _, s := range cfg.WebRTC.ICEServers {
@@ -80,9 +82,10 @@ func NewIngestTestEnv(t *testing.T, cfg *config.Config) *IngestTestEnv {
 	}
 
 	webrtcConfig := webrtcinfra.WebRTCConfig{
-		ICEServers: iceServers,
-		Simulcast:  cfg.WebRTC.Simulcast,
-		MaxBitrate: cfg.WebRTC.MaxBitrate,
+		ICEServers:                iceServers,
+		Simulcast:                 cfg.WebRTC.Simulcast,
+		MaxBitrate:                cfg.WebRTC.MaxBitrate,
+		MaxICECandidatesPerMinute: cfg.WebRTC.MaxICECandidatesPerMinute,
 	}
 
 	retryCfg := retry.Config{
@@ -98,20 +101,38 @@ func NewIngestTestEnv(t *testing.T, cfg *config.Config) *IngestTestEnv {
 		SuccessThreshold:    cfg.CircuitBreaker.SuccessThreshold,
 		Timeout:             cfg.CircuitBreaker.Timeout,
 		MaxRequestsHalfOpen: cfg.CircuitBreaker.MaxRequestsHalfOpen,
+		WindowSize:          cfg.CircuitBreaker.WindowSize,
+		FailureRatio:        cfg.CircuitBreaker.FailureRatio,
 	}
 	sfuService := webrtcinfra.NewSFUService(webrtcConfig, qualityService, metricsService, meshService, retryCfg, cbCfg)
+	if hooks, ok := streamService.(services.StreamStopHooks); ok {
+		hooks.SetStopHooks(sfuService.(*webrtcinfra.SFUService), nil)
+	}
 
 	authHandler := httphandlers.NewAuthHandler(authService)
 	streamHandler := httphandlers.NewStreamHandler(streamService, sfuService)
+	if kicker, ok := streamService.(ports.PeerKicker); ok {
+		streamHandler.SetPeerKicker(kicker)
+	}
+	metricsHandler := httphandlers.NewMetricsHandler(streamService, metricsService, nil)
+	adminHandler := httphandlers.NewAdminHandler(meshService)
+	if resetter, ok := streamService.(ports.StreamResetter); ok {
+		adminHandler.SetStreamResetter(resetter)
+	}
+	configHandler := httphandlers.NewConfigHandler(cfg)
+	iceServerHandler := httphandlers.NewICEServerHandler(sfuService.(*webrtcinfra.SFUService))
+	reportHandler := httphandlers.NewReportHandler(streamService, meshService, peerRepo, metricsService, nil)
 
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(middleware.ErrorHandlerMiddleware(log))
 	router.Use(middleware.SimpleCORSMiddleware())
 
-	router.POST("/api/v1/auth/register", authHandler.Register)
-	router.POST("/api/v1/auth/login", authHandler.Login)
-	router.POST("/api/v1/auth/refresh", authHandler.RefreshToken)
+	bodyLimit := middleware.NewBodyLimitMiddleware(cfg.Server.MaxRequestBodyBytes)
+
+	router.POST("/api/v1/auth/register", bodyLimit, authHandler.Register)
+	router.POST("/api/v1/auth/login", bodyLimit, authHandler.Login)
+	router.POST("/api/v1/auth/refresh", bodyLimit, authHandler.RefreshToken)
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "healthy"})
@@ -127,19 +148,73 @@ func NewIngestTestEnv(t *testing.T, cfg *config.Config) *IngestTestEnv {
 	})
 
 	streamAPI := router.Group("/api/v1/streams")
-	streamAPI.Use(middleware.AuthMiddleware(authService))
+	streamAPI.Use(middleware.AuthMiddleware(authService), bodyLimit)
 	{
 		streamAPI.POST("", streamHandler.CreateStream)
 		streamAPI.GET("", streamHandler.ListStreams)
 		streamAPI.GET("/:id", streamHandler.GetStream)
 		streamAPI.POST("/:id/join", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.JoinStream)
 		streamAPI.POST("/:id/leave", streamHandler.LeaveStream)
+		streamAPI.POST("/:id/stop", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.StopStream)
 		streamAPI.GET("/:id/stats", streamHandler.GetStreamStats)
 		streamAPI.GET("/:id/webrtc/ready", streamHandler.GetWebRTCReadiness)
+		streamAPI.GET("/:id/peers", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.SearchPeers)
+		streamAPI.GET("/:id/peers/:peerId/stats", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.GetPeerStats)
+		streamAPI.GET("/:id/peers/:peerId/quality-history", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.GetQualityHistory)
 		streamAPI.POST("/:id/publisher/offer", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.CreatePublisherOffer)
 		streamAPI.POST("/:id/publisher/answer", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.HandlePublisherAnswer)
+		streamAPI.POST("/:id/publisher/pause", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.PausePublisher)
+		streamAPI.POST("/:id/publisher/resume", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.ResumePublisher)
 		streamAPI.POST("/:id/subscriber/offer", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.CreateSubscriberOffer)
 		streamAPI.POST("/:id/subscriber/answer", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.HandleSubscriberAnswer)
+		streamAPI.PUT("/:id/subscriber/tracks", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.UpdateSubscription)
+		streamAPI.POST("/:id/ice-candidate", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.AddICECandidate)
+		streamAPI.POST("/:id/preconnect/:handle/bind", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.BindPreconnect)
+		streamAPI.POST("/:id/rotate-keys", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), streamHandler.RotateKeys)
+		streamAPI.PUT("/:id/keyframe-on-join", middleware.StreamPermissionMiddleware(authService, domain.RoleModerator), streamHandler.SetKeyframeOnJoin)
+		streamAPI.PUT("/:id/svc-layer", middleware.StreamPermissionMiddleware(authService, domain.RoleModerator), streamHandler.SetSVCLayer)
+		streamAPI.POST("/:id/peers/:peerId/kick", middleware.StreamPermissionMiddleware(authService, domain.RoleModerator), streamHandler.KickPeer)
+		streamAPI.GET("/:id/renegotiation", middleware.StreamPermissionMiddleware(authService, domain.RoleViewer), streamHandler.GetPendingRenegotiation)
+	}
+
+	preconnectAPI := router.Group("/api/v1/preconnect")
+	preconnectAPI.Use(middleware.JWTAuthMiddleware(authService), bodyLimit)
+	{
+		preconnectAPI.POST("", streamHandler.CreatePreconnect)
+	}
+
+	metricsAPI := router.Group("/api/v1/metrics")
+	metricsAPI.Use(middleware.AuthMiddleware(authService))
+	{
+		metricsAPI.GET("/snapshot", metricsHandler.GetSnapshot)
+		metricsAPI.GET("/pressure", metricsHandler.GetPressure)
+	}
+
+	// Rotation of TURN credentials for new peer connections, by operators only
+	iceServerAPI := router.Group("/api/v1/ice-servers")
+	iceServerAPI.Use(middleware.AuthMiddleware(authService), middleware.RoleMiddleware(domain.RoleOperator), bodyLimit)
+	{
+		iceServerAPI.POST("", iceServerHandler.UpdateICEServers)
+	}
+
+	// Operator endpoints for debugging mesh state
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(middleware.AuthMiddleware(authService))
+	{
+		adminAPI.GET("/report", middleware.RoleMiddleware(domain.RoleOperator), reportHandler.GetReport)
+		adminAPI.GET("/config", middleware.RoleMiddleware(domain.RoleOperator), configHandler.GetConfig)
+		adminAPI.GET("/streams/:id/topology", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), adminHandler.GetTopology)
+		adminAPI.GET("/streams/:id/topology/diff", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), adminHandler.DiffTopology)
+		adminAPI.POST("/streams/:id/reset", middleware.RoleMiddleware(domain.RoleOperator), adminHandler.ResetStream)
+		adminAPI.GET("/mesh/weights", middleware.RoleMiddleware(domain.RoleOperator), adminHandler.GetScoringWeights)
+		adminAPI.PUT("/mesh/weights", middleware.RoleMiddleware(domain.RoleOperator), bodyLimit, adminHandler.SetScoringWeights)
+	}
+
+	// Recording playback, only when a recording directory is configured
+	if cfg.Recording.Directory != "" {
+		recordingHandler := httphandlers.NewRecordingHandler(recording.NewFileIndex(cfg.Recording.Directory))
+		streamAPI.GET("/:id/recordings", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), recordingHandler.ListRecordings)
+		streamAPI.GET("/:id/recordings/:recording_id", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), recordingHandler.DownloadRecording)
 	}
 
 	return &IngestTestEnv{
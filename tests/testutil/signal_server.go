@@ -42,7 +42,7 @@ func NewSignalTestServer(t *testing.T, cfg *config.Config) *SignalTestServer {
 
 	peerRepo := factory.CreatePeerRepository()
 	meshRepo := factory.CreateMeshRepository()
-	meshService := services.NewMeshService(peerRepo, meshRepo, cfg.Mesh, log)
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, log)
 	authService := services.NewAuthService(
 		cfg.Auth.JWTSecret,
 		cfg.Auth.AccessTokenTTL,
@@ -53,6 +53,11 @@ func NewSignalTestServer(t *testing.T, cfg *config.Config) *SignalTestServer {
 	)
 
 	wsServer := signalserver.NewWebSocketServer(peerRepo, meshService, authService, cfg.Auth.AllowedOrigins)
+	wsServer.SetMaxOutboundBacklog(cfg.Signal.MaxOutboundBacklog)
+	wsServer.SetIdleTimeout(cfg.Signal.IdleTimeout)
+	wsServer.SetReconnectBackoff(cfg.Signal.ReconnectBackoff, cfg.Signal.ReconnectBackoffMax)
+	wsServer.SetCompression(cfg.Signal.CompressionEnabled, cfg.Signal.CompressionLevel)
+	wsServer.SetMaxICECandidates(cfg.WebRTC.MaxICECandidatesPerMinute)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ws", wsServer.HandleWebSocket)
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+	"rillnet/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_ScoringWeightsAreOperatorOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	meshService := services.NewMeshService(memory.NewMemoryPeerRepository(), memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+	authService := services.NewAuthServiceWithRoles("admin-test-secret", time.Minute, time.Hour, nil, nil, nil,
+		map[domain.UserID]domain.UserRole{"operator": domain.RoleOperator})
+	handler := httphandlers.NewAdminHandler(meshService)
+
+	router := gin.New()
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(middleware.AuthMiddleware(authService))
+	adminAPI.GET("/mesh/weights", middleware.RoleMiddleware(domain.RoleOperator), handler.GetScoringWeights)
+	adminAPI.PUT("/mesh/weights", middleware.RoleMiddleware(domain.RoleOperator), handler.SetScoringWeights)
+
+	do := func(userID domain.UserID, method, body string) int {
+		token, err := authService.GenerateToken(userID, string(userID))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/admin/mesh/weights", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	weights := `{"latency_weight": 0, "bandwidth_weight": 1, "reliability_weight": 0}`
+
+	assert.Equal(t, http.StatusForbidden, do("viewer", http.MethodGet, ""))
+	assert.Equal(t, http.StatusForbidden, do("viewer", http.MethodPut, weights))
+	latency, _, _ := meshService.(ports.MeshScoringTuner).GetScoringWeights()
+	assert.NotZero(t, latency, "a non-operator must not change the weights")
+
+	assert.Equal(t, http.StatusOK, do("operator", http.MethodPut, weights))
+	assert.Equal(t, http.StatusOK, do("operator", http.MethodGet, ""))
+	latency, bandwidth, _ := meshService.(ports.MeshScoringTuner).GetScoringWeights()
+	assert.Equal(t, [2]float64{0, 1}, [2]float64{latency, bandwidth})
+}
+
+// recordingResetNotifier records the streams whose peers were told to rejoin
+type recordingResetNotifier struct {
+	streams []domain.StreamID
+}
+
+func (n *recordingResetNotifier) NotifyStreamReset(ctx context.Context, streamID domain.StreamID) error {
+	n.streams = append(n.streams, streamID)
+	return nil
+}
+
+func TestAdminHandler_ResetStreamEvictsPeersAndKeepsStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+	streamService := services.NewStreamService(memory.NewMemoryStreamRepository(), peerRepo, memory.NewMemoryMeshRepository(), meshService, services.NewMetricsService())
+	notifier := &recordingResetNotifier{}
+	streamService.(services.StreamStopHooks).SetResetNotifier(notifier)
+
+	authService := services.NewAuthServiceWithRoles("admin-test-secret", time.Minute, time.Hour, nil, nil, nil,
+		map[domain.UserID]domain.UserRole{"operator": domain.RoleOperator})
+	handler := httphandlers.NewAdminHandler(meshService)
+	handler.SetStreamResetter(streamService.(ports.StreamResetter))
+
+	router := gin.New()
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(middleware.AuthMiddleware(authService))
+	adminAPI.POST("/streams/:id/reset", middleware.RoleMiddleware(domain.RoleOperator), handler.ResetStream)
+
+	stream, err := streamService.CreateStream(ctx, "crashed publisher", "owner", 10)
+	require.NoError(t, err)
+	for _, peer := range []*domain.Peer{
+		{ID: "publisher", StreamID: stream.ID, Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+		{ID: "viewer-1", StreamID: stream.ID},
+		{ID: "viewer-2", StreamID: stream.ID},
+	} {
+		require.NoError(t, streamService.JoinStream(ctx, stream.ID, peer))
+	}
+
+	do := func(userID domain.UserID) int {
+		token, err := authService.GenerateToken(userID, string(userID))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/streams/"+string(stream.ID)+"/reset", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, do("owner"))
+	assert.Equal(t, http.StatusOK, do("operator"))
+
+	peers, err := peerRepo.FindByStream(ctx, stream.ID)
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+
+	kept, err := streamService.GetStream(ctx, stream.ID)
+	require.NoError(t, err)
+	assert.True(t, kept.Active)
+	assert.Equal(t, []domain.StreamID{stream.ID}, notifier.streams)
+}
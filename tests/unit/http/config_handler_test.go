@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/pkg/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigHandler_GetConfigRedactsSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Setenv("RILLNET_LOG_LEVEL", "warn")
+	t.Setenv("RILLNET_JWT_SECRET", "jwt-secret-value")
+	t.Setenv("RILLNET_REDIS_PASSWORD", "redis-secret-value")
+	t.Setenv("RILLNET_DB_DSN", "postgres://rillnet:db-secret-value@db:5432/rillnet")
+	t.Setenv("RILLNET_WEBRTC_TURN_URLS", "turn:turn.example.com:3478")
+	t.Setenv("RILLNET_WEBRTC_TURN_USERNAME", "turn-user")
+	t.Setenv("RILLNET_WEBRTC_TURN_PASSWORD", "turn-secret-value")
+
+	cfg, err := config.Load("non-existent-config.yaml")
+	require.NoError(t, err)
+
+	authService := services.NewAuthServiceWithRoles(cfg.Auth.JWTSecret, time.Minute, time.Hour, nil, nil, nil,
+		map[domain.UserID]domain.UserRole{"operator": domain.RoleOperator})
+	handler := httphandlers.NewConfigHandler(cfg)
+
+	router := gin.New()
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(middleware.AuthMiddleware(authService))
+	adminAPI.GET("/config", middleware.RoleMiddleware(domain.RoleOperator), handler.GetConfig)
+
+	t.Run("requires authentication", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("requires the operator role", func(t *testing.T) {
+		token, err := authService.GenerateToken("viewer", "viewer")
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("returns effective config with secrets redacted", func(t *testing.T) {
+		token, err := authService.GenerateToken("operator", "operator")
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		body := w.Body.String()
+		for _, secret := range []string{"jwt-secret-value", "redis-secret-value", "db-secret-value", "turn-secret-value"} {
+			assert.False(t, strings.Contains(body, secret), "response leaks %s", secret)
+		}
+
+		var got config.Config
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+		// Env overrides and defaults are reflected
+		assert.Equal(t, "warn", got.Logging.Level)
+		assert.Equal(t, cfg.Server.Address, got.Server.Address)
+		assert.Equal(t, cfg.Mesh.MaxConnections, got.Mesh.MaxConnections)
+
+		assert.Equal(t, config.RedactedValue, got.Auth.JWTSecret)
+		assert.Equal(t, config.RedactedValue, got.Redis.Password)
+		assert.Contains(t, got.Database.DSN, "db:5432/rillnet")
+		require.NotEmpty(t, got.WebRTC.ICEServers)
+		turn := got.WebRTC.ICEServers[len(got.WebRTC.ICEServers)-1]
+		assert.Equal(t, "turn-user", turn.Username)
+		assert.Equal(t, config.RedactedValue, turn.Credential)
+	})
+
+	// The loaded config keeps its secrets
+	assert.Equal(t, "jwt-secret-value", cfg.Auth.JWTSecret)
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/streaming"
+	"rillnet/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hlsStreams answers GetStream from a fixed set of streams
+type hlsStreams struct {
+	ports.StreamService
+	streams map[domain.StreamID]*domain.Stream
+}
+
+func (s *hlsStreams) GetStream(ctx context.Context, streamID domain.StreamID) (*domain.Stream, error) {
+	stream, ok := s.streams[streamID]
+	if !ok {
+		return nil, domain.ErrStreamNotFound
+	}
+	return stream, nil
+}
+
+func TestHLSHandler_ServesPlaylistsAndSegments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	log := logger.New("error").Sugar()
+	segmenter := streaming.NewSegmenter(2*time.Second, t.TempDir(), log)
+	cache := streaming.NewSegmentCache(10)
+	for _, streamID := range []domain.StreamID{"live-stream", "ended-stream"} {
+		for _, quality := range []string{"high", "low"} {
+			for index := 3; index < 5; index++ {
+				segment, err := segmenter.CreateSegment(ctx, streamID, quality, index, []byte(quality+"-ts-payload"))
+				require.NoError(t, err)
+				cache.Add(segment)
+			}
+		}
+	}
+	streams := &hlsStreams{streams: map[domain.StreamID]*domain.Stream{
+		"live-stream":  {ID: "live-stream", Active: true},
+		"ended-stream": {ID: "ended-stream", Active: false},
+	}}
+
+	authService := services.NewAuthService("hls-test-secret", time.Minute, time.Hour, nil, nil, nil)
+	handler := httphandlers.NewHLSHandler(segmenter, cache, streams)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandlerMiddleware(log))
+	hlsAPI := router.Group("/hls")
+	hlsAPI.Use(middleware.AuthMiddleware(authService), middleware.StreamPermissionMiddleware(authService, domain.RoleViewer))
+	hlsAPI.GET("/:id/master.m3u8", handler.GetMasterPlaylist)
+	hlsAPI.GET("/:id/:quality/index.m3u8", handler.GetMediaPlaylist)
+	hlsAPI.GET("/:id/:quality/:segment", handler.GetSegment)
+
+	token, err := authService.GenerateToken("viewer-1", "viewer")
+	require.NoError(t, err)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("master playlist lists each quality", func(t *testing.T) {
+		w := get("/hls/live-stream/master.m3u8")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.apple.mpegurl", w.Header().Get("Content-Type"))
+
+		body := w.Body.String()
+		assert.True(t, strings.HasPrefix(body, "#EXTM3U\n"))
+		assert.Contains(t, body, "/hls/live-stream/high/index.m3u8")
+		assert.Contains(t, body, "/hls/live-stream/low/index.m3u8")
+		assert.Equal(t, 2, strings.Count(body, "#EXT-X-STREAM-INF"))
+	})
+
+	t.Run("media playlist lists cached segments in order", func(t *testing.T) {
+		w := get("/hls/live-stream/low/index.m3u8")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.apple.mpegurl", w.Header().Get("Content-Type"))
+
+		body := w.Body.String()
+		assert.Contains(t, body, "#EXT-X-MEDIA-SEQUENCE:3\n")
+		first := strings.Index(body, "/hls/live-stream/low/3.ts")
+		second := strings.Index(body, "/hls/live-stream/low/4.ts")
+		require.NotEqual(t, -1, first)
+		assert.Greater(t, second, first)
+	})
+
+	t.Run("only an ended stream's playlist has ENDLIST", func(t *testing.T) {
+		w := get("/hls/live-stream/high/index.m3u8")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "#EXT-X-ENDLIST")
+
+		w = get("/hls/ended-stream/high/index.m3u8")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasSuffix(w.Body.String(), "#EXT-X-ENDLIST\n"))
+	})
+
+	t.Run("serves segment bytes", func(t *testing.T) {
+		w := get("/hls/live-stream/high/4.ts")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "video/mp2t", w.Header().Get("Content-Type"))
+		assert.Equal(t, []byte("high-ts-payload"), w.Body.Bytes())
+	})
+
+	t.Run("unknown stream, quality or segment is not found", func(t *testing.T) {
+		for _, path := range []string{
+			"/hls/other-stream/master.m3u8",
+			"/hls/live-stream/medium/index.m3u8",
+			"/hls/live-stream/high/9.ts",
+			"/hls/live-stream/high/abc.ts",
+			"/hls/live-stream/high/4.mp4",
+		} {
+			w := get(path)
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+		}
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hls/live-stream/master.m3u8", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// iceServerRecorder keeps the last ICE servers pushed to it
+type iceServerRecorder struct {
+	servers []webrtc.ICEServer
+}
+
+func (r *iceServerRecorder) UpdateICEServers(servers []webrtc.ICEServer) {
+	r.servers = servers
+}
+
+func TestICEServerHandler_OperatorsOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := services.NewAuthServiceWithRoles("ice-test-secret", time.Minute, time.Hour, nil, nil, nil,
+		map[domain.UserID]domain.UserRole{"operator": domain.RoleOperator})
+	recorder := &iceServerRecorder{}
+	handler := httphandlers.NewICEServerHandler(recorder)
+
+	router := gin.New()
+	iceServerAPI := router.Group("/api/v1/ice-servers")
+	iceServerAPI.Use(middleware.AuthMiddleware(authService), middleware.RoleMiddleware(domain.RoleOperator))
+	iceServerAPI.POST("", handler.UpdateICEServers)
+
+	update := func(userID domain.UserID) int {
+		token, err := authService.GenerateToken(userID, string(userID))
+		require.NoError(t, err)
+		body := `{"ice_servers": [{"urls": ["turn:turn.example:3478"], "username": "u", "credential": "rotated"}]}`
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ice-servers", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, update("viewer"))
+	assert.Nil(t, recorder.servers, "a non-operator must not replace the ICE servers")
+
+	assert.Equal(t, http.StatusOK, update("operator"))
+	require.Len(t, recorder.servers, 1)
+	assert.Equal(t, "rotated", recorder.servers[0].Credential)
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+	"rillnet/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticP2PSource map[domain.StreamID]float64
+
+func (s staticP2PSource) P2PEfficiency(streamID domain.StreamID) (float64, bool) {
+	efficiency, ok := s[streamID]
+	return efficiency, ok
+}
+
+func TestMetricsHandler_GetSnapshot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	log := logger.New("error").Sugar()
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+
+	streamRepo := memory.NewMemoryStreamRepository()
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshRepo := memory.NewMemoryMeshRepository()
+	metricsService := services.NewMetricsService()
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, log)
+	streamService := services.NewStreamService(streamRepo, peerRepo, meshRepo, meshService, metricsService)
+	authService := services.NewAuthService("metrics-test-secret", time.Minute, time.Hour, nil, nil, nil)
+
+	streamA, err := streamService.CreateStream(ctx, "stream-a", "owner-a", 10)
+	require.NoError(t, err)
+	streamB, err := streamService.CreateStream(ctx, "stream-b", "owner-b", 10)
+	require.NoError(t, err)
+
+	metricsService.IncrementPublisherCount(streamA.ID)
+	metricsService.IncrementSubscriberCount(streamA.ID)
+	metricsService.IncrementSubscriberCount(streamA.ID)
+	metricsService.RecordConnection(streamA.ID)
+	metricsService.IncrementSubscriberCount(streamB.ID)
+
+	handler := httphandlers.NewMetricsHandler(streamService, metricsService, staticP2PSource{streamA.ID: 75})
+
+	router := gin.New()
+	metricsAPI := router.Group("/api/v1/metrics")
+	metricsAPI.Use(middleware.AuthMiddleware(authService))
+	metricsAPI.GET("/snapshot", handler.GetSnapshot)
+
+	t.Run("requires authentication", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics/snapshot", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("reflects streams and counters", func(t *testing.T) {
+		token, err := authService.GenerateToken("user-1", "tester")
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics/snapshot", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var snapshot services.MetricsSnapshot
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+
+		assert.Equal(t, 2, snapshot.ActiveStreams)
+		assert.Equal(t, 4, snapshot.ConnectedPeers)
+		require.Len(t, snapshot.Streams, 2)
+
+		byID := make(map[domain.StreamID]services.StreamMetricsSnapshot)
+		for _, s := range snapshot.Streams {
+			byID[s.StreamID] = s
+		}
+
+		a := byID[streamA.ID]
+		assert.Equal(t, 1, a.Publishers)
+		assert.Equal(t, 2, a.Subscribers)
+		assert.Equal(t, 1, a.Connections)
+		assert.Greater(t, a.HealthScore, 0.0)
+		require.NotNil(t, a.P2PEfficiencyPercent)
+		assert.Equal(t, 75.0, *a.P2PEfficiencyPercent)
+
+		b := byID[streamB.ID]
+		assert.Equal(t, 0, b.Publishers)
+		assert.Equal(t, 1, b.Subscribers)
+		assert.Nil(t, b.P2PEfficiencyPercent)
+	})
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/recording"
+	"rillnet/pkg/logger"
+	"rillnet/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingHandler_ListAndDownload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	streamID := domain.StreamID("recorded-stream")
+	content := []byte("fake-webm-payload-0123456789")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, string(streamID)), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, string(streamID), "rec-1.webm"), content, 0o644))
+
+	index := recording.NewFileIndex(dir)
+	startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, index.Add(ctx, &domain.Recording{
+		ID:        "rec-1",
+		StreamID:  streamID,
+		FileName:  "rec-1.webm",
+		StartedAt: startedAt,
+		EndedAt:   startedAt.Add(time.Minute),
+	}))
+
+	// An in-progress recording is not indexed
+	require.Error(t, index.Add(ctx, &domain.Recording{
+		ID:        "rec-2",
+		StreamID:  streamID,
+		FileName:  "rec-1.webm",
+		StartedAt: startedAt,
+	}))
+
+	authService := services.NewAuthService("recording-test-secret", time.Minute, time.Hour, nil, nil, nil)
+	handler := httphandlers.NewRecordingHandler(index)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandlerMiddleware(logger.New("error").Sugar()))
+	streamAPI := router.Group("/api/v1/streams")
+	streamAPI.Use(middleware.AuthMiddleware(authService))
+	streamAPI.GET("/:id/recordings", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), handler.ListRecordings)
+	streamAPI.GET("/:id/recordings/:recording_id", middleware.StreamPermissionMiddleware(authService, domain.RoleOwner), handler.DownloadRecording)
+
+	token, err := authService.GenerateToken("owner-1", "owner")
+	require.NoError(t, err)
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("requires authentication", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/streams/recorded-stream/recordings", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("lists completed recordings", func(t *testing.T) {
+		w := get("/api/v1/streams/recorded-stream/recordings", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			StreamID   domain.StreamID     `json:"stream_id"`
+			Recordings []*domain.Recording `json:"recordings"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		require.Len(t, body.Recordings, 1)
+		assert.Equal(t, domain.RecordingID("rec-1"), body.Recordings[0].ID)
+		assert.Equal(t, int64(len(content)), body.Recordings[0].SizeBytes)
+		assert.True(t, body.Recordings[0].StartedAt.Equal(startedAt))
+		assert.Equal(t, time.Minute, body.Recordings[0].Duration())
+	})
+
+	t.Run("downloads the whole file", func(t *testing.T) {
+		w := get("/api/v1/streams/recorded-stream/recordings/rec-1", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, content, w.Body.Bytes())
+		assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	})
+
+	t.Run("serves byte ranges", func(t *testing.T) {
+		w := get("/api/v1/streams/recorded-stream/recordings/rec-1", map[string]string{"Range": "bytes=0-3"})
+		require.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, content[:4], w.Body.Bytes())
+	})
+
+	t.Run("unknown recording is not found", func(t *testing.T) {
+		w := get("/api/v1/streams/recorded-stream/recordings/missing", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestFileIndex_RejectsStreamIDsOutsideRoot(t *testing.T) {
+	// A permissive stream ID policy must not let recordings escape the root
+	require.NoError(t, validation.SetStreamIDPolicy(`A-Za-z0-9_./\\-`, validation.DefaultStreamIDMaxLength))
+	t.Cleanup(func() {
+		_ = validation.SetStreamIDPolicy(validation.DefaultStreamIDCharset, validation.DefaultStreamIDMaxLength)
+	})
+
+	root := t.TempDir()
+	index := recording.NewFileIndex(root)
+	for _, id := range []string{".", "..", "../outside", "nested/stream", `..\outside`} {
+		_, err := index.ListByStream(context.Background(), domain.StreamID(id))
+		assert.Error(t, err, "stream ID %q", id)
+
+		_, err = recording.StreamDir(root, domain.StreamID(id))
+		assert.Error(t, err, "stream ID %q", id)
+	}
+
+	dir, err := recording.StreamDir(root, "plain.stream")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "plain.stream"), dir)
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+	"rillnet/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportHandler_GetReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	log := logger.New("error").Sugar()
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+
+	streamRepo := memory.NewMemoryStreamRepository()
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshRepo := memory.NewMemoryMeshRepository()
+	metricsService := services.NewMetricsService()
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, log)
+	streamService := services.NewStreamService(streamRepo, peerRepo, meshRepo, meshService, metricsService)
+	authService := services.NewAuthServiceWithRoles("report-test-secret", time.Minute, time.Hour, nil, nil, nil,
+		map[domain.UserID]domain.UserRole{"operator": domain.RoleOperator})
+
+	streamA, err := streamService.CreateStream(ctx, "stream-a", "owner-a", 10)
+	require.NoError(t, err)
+	streamB, err := streamService.CreateStream(ctx, "stream-b", "owner-b", 10)
+	require.NoError(t, err)
+
+	peers := []*domain.Peer{
+		{ID: "pub-a", StreamID: streamA.ID, Capabilities: domain.PeerCapabilities{IsPublisher: true}, Metrics: domain.PeerMetrics{Bandwidth: 2000}},
+		{ID: "viewer-a1", StreamID: streamA.ID, Capabilities: domain.PeerCapabilities{CanRelay: true}},
+		{ID: "viewer-a2", StreamID: streamA.ID},
+		{ID: "pub-b", StreamID: streamB.ID, Capabilities: domain.PeerCapabilities{IsPublisher: true}, Metrics: domain.PeerMetrics{Bandwidth: 1000}},
+		{ID: "viewer-b1", StreamID: streamB.ID},
+	}
+	for _, peer := range peers {
+		require.NoError(t, peerRepo.Add(ctx, peer))
+	}
+	require.NoError(t, meshRepo.AddConnection(ctx, &domain.PeerConnection{FromPeer: "pub-a", ToPeer: "viewer-a1"}))
+
+	handler := httphandlers.NewReportHandler(streamService, meshService, peerRepo, metricsService, staticP2PSource{streamA.ID: 50})
+
+	router := gin.New()
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(middleware.AuthMiddleware(authService))
+	adminAPI.GET("/report", middleware.RoleMiddleware(domain.RoleOperator), handler.GetReport)
+
+	viewerToken, err := authService.GenerateToken("viewer", "viewer")
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/report", nil)
+	req.Header.Set("Authorization", "Bearer "+viewerToken)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code, "only operators may read the report")
+
+	token, err := authService.GenerateToken("operator", "operator")
+	require.NoError(t, err)
+	getReport := func() httphandlers.CapacityReport {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/report", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var report httphandlers.CapacityReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	report := getReport()
+	assert.Equal(t, 2, report.ActiveStreams)
+	assert.Equal(t, 5, report.TotalPeers)
+	assert.Equal(t, 2, report.Publishers)
+	assert.Equal(t, 3, report.Subscribers)
+	assert.Equal(t, 1, report.MeshEdges)
+	// Stream A: 2000 kbps to 2 viewers, half over P2P; stream B: 1000 kbps to 1 viewer via the SFU
+	assert.Equal(t, 3000, report.EstimatedEgressKbps)
+
+	require.Len(t, report.Streams, 2)
+	byID := make(map[domain.StreamID]httphandlers.StreamCapacity)
+	for _, s := range report.Streams {
+		byID[s.StreamID] = s
+	}
+	a := byID[streamA.ID]
+	assert.Equal(t, 1, a.Publishers)
+	assert.Equal(t, 2, a.Subscribers)
+	assert.Equal(t, 1, a.MeshEdges)
+	assert.Equal(t, 2000, a.EstimatedEgressKbps)
+	require.NotNil(t, a.P2PEfficiencyPercent)
+	assert.Equal(t, 50.0, *a.P2PEfficiencyPercent)
+	b := byID[streamB.ID]
+	assert.Equal(t, 1000, b.EstimatedEgressKbps)
+	assert.Nil(t, b.P2PEfficiencyPercent)
+
+	// Served from cache until the TTL passes
+	require.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "viewer-b2", StreamID: streamB.ID}))
+	assert.Equal(t, 5, getReport().TotalPeers)
+
+	handler.SetCacheTTL(0)
+	assert.Equal(t, 6, getReport().TotalPeers)
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	httphandlers "rillnet/internal/handlers/http"
+	"rillnet/internal/infrastructure/middleware"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+	"rillnet/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticQualityHistory serves fixed quality switches for every peer
+type staticQualityHistory struct {
+	snapshots []services.QualitySnapshot
+}
+
+func (s staticQualityHistory) GetQualityHistory(peerID domain.PeerID) []services.QualitySnapshot {
+	return s.snapshots
+}
+
+func TestStreamHandler_GetQualityHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+	streamService := services.NewStreamService(memory.NewMemoryStreamRepository(), peerRepo, memory.NewMemoryMeshRepository(), meshService, services.NewMetricsService())
+
+	stream, err := streamService.CreateStream(ctx, "quality history", "owner", 10)
+	require.NoError(t, err)
+	require.NoError(t, streamService.JoinStream(ctx, stream.ID, &domain.Peer{ID: "viewer", StreamID: stream.ID}))
+
+	switchedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := httphandlers.NewStreamHandler(streamService, nil)
+	handler.SetQualityHistory(staticQualityHistory{snapshots: []services.QualitySnapshot{
+		{
+			Quality:   "medium",
+			Timestamp: switchedAt,
+			Metrics:   domain.NetworkMetrics{BandwidthDown: 900, PacketLoss: 0.03, Latency: 120 * time.Millisecond},
+		},
+		{
+			Quality:   "low",
+			Timestamp: switchedAt.Add(time.Minute),
+			Metrics:   domain.NetworkMetrics{BandwidthDown: 300, PacketLoss: 0.2, Latency: 50 * time.Millisecond},
+		},
+	}})
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandlerMiddleware(logger.New("error").Sugar()))
+	router.GET("/api/v1/streams/:id/peers/:peerId/quality-history", handler.GetQualityHistory)
+
+	get := func(streamID domain.StreamID, peerID domain.PeerID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/streams/"+string(streamID)+"/peers/"+string(peerID)+"/quality-history", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(stream.ID, "viewer")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		PeerID  domain.PeerID `json:"peer_id"`
+		History []struct {
+			Quality   string    `json:"quality"`
+			Timestamp time.Time `json:"timestamp"`
+			Metrics   struct {
+				BandwidthDown int     `json:"bandwidth_down_kbps"`
+				PacketLoss    float64 `json:"packet_loss"`
+				LatencyMs     int64   `json:"latency_ms"`
+			} `json:"metrics"`
+		} `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, domain.PeerID("viewer"), body.PeerID)
+	require.Len(t, body.History, 2)
+	assert.Equal(t, "medium", body.History[0].Quality)
+	assert.True(t, switchedAt.Equal(body.History[0].Timestamp))
+	assert.Equal(t, 900, body.History[0].Metrics.BandwidthDown)
+	assert.Equal(t, int64(120), body.History[0].Metrics.LatencyMs)
+	assert.Equal(t, "low", body.History[1].Quality)
+	assert.Equal(t, 0.2, body.History[1].Metrics.PacketLoss)
+
+	// The history of a peer outside the stream is not disclosed
+	assert.Equal(t, http.StatusNotFound, get(stream.ID, "stranger").Code)
+}
+
+// svcLayers is a WebRTC service that only keeps SVC temporal layer limits
+type svcLayers struct {
+	ports.WebRTCService
+	limits map[domain.StreamID]int
+}
+
+func (s *svcLayers) SetSVCTemporalLayer(streamID domain.StreamID, tid int) error {
+	if tid > 7 {
+		return fmt.Errorf("temporal layer %d out of range", tid)
+	}
+	s.limits[streamID] = tid
+	return nil
+}
+
+func (s *svcLayers) SVCTemporalLayer(streamID domain.StreamID) int {
+	if tid, ok := s.limits[streamID]; ok && tid >= 0 {
+		return tid
+	}
+	return -1
+}
+
+func TestStreamHandler_SetSVCLayer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	layers := &svcLayers{limits: make(map[domain.StreamID]int)}
+	handler := httphandlers.NewStreamHandler(nil, layers)
+	router := gin.New()
+	router.PUT("/api/v1/streams/:id/svc-layer", handler.SetSVCLayer)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/streams/svc-stream/svc-layer", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"max_temporal_layer": 1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		MaxTemporalLayer int `json:"max_temporal_layer"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.MaxTemporalLayer)
+	assert.Equal(t, 1, layers.limits["svc-stream"])
+
+	// -1 forwards every layer again
+	w = put(`{"max_temporal_layer": -1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, -1, body.MaxTemporalLayer)
+
+	assert.Equal(t, http.StatusBadRequest, put(`{"max_temporal_layer": 9}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{}`).Code)
+
+	// Services without SVC layer selection report it unsupported
+	unsupported := gin.New()
+	unsupported.PUT("/api/v1/streams/:id/svc-layer", httphandlers.NewStreamHandler(nil, nil).SetSVCLayer)
+	w = httptest.NewRecorder()
+	unsupported.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/streams/svc-stream/svc-layer", strings.NewReader(`{"max_temporal_layer": 1}`)))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	redisrepo "rillnet/internal/infrastructure/repositories/redis"
+	"rillnet/tests/testutil"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func redisTestClient(t *testing.T) (*redis.Client, string) {
+	if !testutil.RedisAvailable() {
+		t.Skip("Redis not available (set RILLNET_REDIS_ADDRESS or start redis:7)")
+	}
+	client := redis.NewClient(&redis.Options{Addr: testutil.RedisAddr()})
+	t.Cleanup(func() { client.Close() })
+	return client, fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+func TestRedisMeshRepository_ConcurrentAddsStoreEachEdgeOnce(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	// Separate repositories stand in for separate instances
+	repoA := redisrepo.NewRedisMeshRepository(client)
+	repoB := redisrepo.NewRedisMeshRepository(client)
+
+	from := domain.PeerID("mesh-from-" + suffix)
+	to := domain.PeerID("mesh-to-" + suffix)
+	t.Cleanup(func() { _ = repoA.RemoveConnection(ctx, from, to) })
+
+	var added int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		repo := repoA
+		if i%2 == 1 {
+			repo = repoB
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if repo.AddConnection(ctx, &domain.PeerConnection{FromPeer: from, ToPeer: to, Direction: domain.DirectionOutbound}) == nil {
+				atomic.AddInt32(&added, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), added, "exactly one concurrent add must win")
+	for _, peerID := range []domain.PeerID{from, to} {
+		conns, err := repoA.GetConnections(ctx, peerID)
+		require.NoError(t, err)
+		assert.Len(t, conns, 1)
+	}
+}
+
+func TestRedisMeshRepository_ConcurrentAddRemoveKeepsEndsConsistent(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	repo := redisrepo.NewRedisMeshRepository(client)
+
+	hub := domain.PeerID("mesh-hub-" + suffix)
+	leaves := make([]domain.PeerID, 10)
+	for i := range leaves {
+		leaves[i] = domain.PeerID(fmt.Sprintf("mesh-leaf-%d-%s", i, suffix))
+	}
+	t.Cleanup(func() {
+		for _, leaf := range leaves {
+			_ = repo.RemoveConnection(ctx, hub, leaf)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				leaf := leaves[(worker+i)%len(leaves)]
+				if (worker+i)%3 == 0 {
+					_ = repo.RemoveConnection(ctx, hub, leaf)
+				} else {
+					_ = repo.AddConnection(ctx, &domain.PeerConnection{FromPeer: hub, ToPeer: leaf})
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// Every edge the hub holds is held by its leaf too, and vice versa
+	hubConns, err := repo.GetConnections(ctx, hub)
+	require.NoError(t, err)
+	hubEdges := make(map[domain.PeerID]bool)
+	for _, conn := range hubConns {
+		assert.False(t, hubEdges[conn.ToPeer], "duplicate edge to %s", conn.ToPeer)
+		hubEdges[conn.ToPeer] = true
+	}
+	for _, leaf := range leaves {
+		conns, err := repo.GetConnections(ctx, leaf)
+		require.NoError(t, err)
+		assert.Equal(t, hubEdges[leaf], len(conns) == 1, "edge %s->%s stored on one end only", hub, leaf)
+		assert.LessOrEqual(t, len(conns), 1)
+	}
+}
+
+func TestRedisMeshRepository_GetOptimalPathFollowsStoredEdges(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	repo := redisrepo.NewRedisMeshRepository(client)
+
+	source := domain.PeerID("path-source-" + suffix)
+	relay := domain.PeerID("path-relay-" + suffix)
+	target := domain.PeerID("path-target-" + suffix)
+	require.NoError(t, repo.AddConnection(ctx, &domain.PeerConnection{FromPeer: source, ToPeer: relay}))
+	require.NoError(t, repo.AddConnection(ctx, &domain.PeerConnection{FromPeer: relay, ToPeer: target}))
+	t.Cleanup(func() {
+		_ = repo.RemoveConnection(ctx, source, relay)
+		_ = repo.RemoveConnection(ctx, relay, target)
+	})
+
+	path, err := repo.GetOptimalPath(ctx, source, target)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerID{source, relay, target}, path)
+}
+
+func TestRedisMeshRepository_SweepRemovesEdgesOfExpiredPeers(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	meshRepo := redisrepo.NewRedisMeshRepository(client)
+	peerRepo := redisrepo.NewRedisPeerRepositoryWithTTL(client, time.Minute)
+
+	streamID := domain.StreamID("sweep-mesh-" + suffix)
+	source := &domain.Peer{ID: domain.PeerID("sweep-source-" + suffix), StreamID: streamID}
+	live := &domain.Peer{ID: domain.PeerID("sweep-live-" + suffix), StreamID: streamID}
+	gone := &domain.Peer{ID: domain.PeerID("sweep-gone-" + suffix), StreamID: streamID}
+	for _, peer := range []*domain.Peer{source, live, gone} {
+		require.NoError(t, peerRepo.Add(ctx, peer))
+	}
+	require.NoError(t, meshRepo.AddConnection(ctx, &domain.PeerConnection{FromPeer: source.ID, ToPeer: live.ID}))
+	require.NoError(t, meshRepo.AddConnection(ctx, &domain.PeerConnection{FromPeer: source.ID, ToPeer: gone.ID}))
+	t.Cleanup(func() {
+		_ = meshRepo.RemoveConnection(ctx, source.ID, live.ID)
+		_ = peerRepo.Remove(ctx, source.ID)
+		_ = peerRepo.Remove(ctx, live.ID)
+	})
+
+	// Stand in for a peer key that expired on a crashed instance
+	require.NoError(t, client.Del(ctx, "rillnet:peer:"+string(gone.ID)).Err())
+
+	removed, err := meshRepo.SweepStaleEdges(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, removed, 1)
+
+	conns, err := meshRepo.GetConnections(ctx, source.ID)
+	require.NoError(t, err)
+	if assert.Len(t, conns, 1) {
+		assert.Equal(t, live.ID, conns[0].ToPeer)
+	}
+	conns, err = meshRepo.GetConnections(ctx, gone.ID)
+	require.NoError(t, err)
+	assert.Empty(t, conns)
+}
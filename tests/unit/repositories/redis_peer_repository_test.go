@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	redisrepo "rillnet/internal/infrastructure/repositories/redis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisPeerRepository_ExpiresPeersWithoutHeartbeat(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	repo := redisrepo.NewRedisPeerRepositoryWithTTL(client, time.Second)
+
+	streamID := domain.StreamID("ttl-stream-" + suffix)
+	ghost := &domain.Peer{ID: domain.PeerID("ttl-ghost-" + suffix), StreamID: streamID}
+	live := &domain.Peer{ID: domain.PeerID("ttl-live-" + suffix), StreamID: streamID}
+	require.NoError(t, repo.Add(ctx, ghost))
+	require.NoError(t, repo.Add(ctx, live))
+	t.Cleanup(func() {
+		_ = repo.Remove(ctx, live.ID)
+		_ = client.Del(ctx, "rillnet:stream:"+string(streamID)+":peers").Err()
+	})
+
+	// Outlive the TTL, heartbeating only one of the peers
+	deadline := time.Now().Add(1500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		require.NoError(t, repo.Heartbeat(ctx, live.ID))
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	_, err := repo.GetByID(ctx, ghost.ID)
+	assert.ErrorIs(t, err, domain.ErrPeerNotFound)
+	assert.ErrorIs(t, repo.Heartbeat(ctx, ghost.ID), domain.ErrPeerNotFound)
+
+	peers, err := repo.FindByStream(ctx, streamID)
+	require.NoError(t, err)
+	if assert.Len(t, peers, 1) {
+		assert.Equal(t, live.ID, peers[0].ID)
+	}
+
+	// The expired peer lingers in the stream set until swept
+	setKey := "rillnet:stream:" + string(streamID) + ":peers"
+	assert.True(t, client.SIsMember(ctx, setKey, string(ghost.ID)).Val())
+	removed, err := repo.SweepStaleMembers(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, removed, 1)
+	assert.False(t, client.SIsMember(ctx, setKey, string(ghost.ID)).Val())
+	assert.True(t, client.SIsMember(ctx, setKey, string(live.ID)).Val())
+}
+
+func TestRedisPeerRepository_FindByFilterAppliesFilterAndLimit(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	repo := redisrepo.NewRedisPeerRepositoryWithTTL(client, time.Minute)
+
+	streamID := domain.StreamID("filter-stream-" + suffix)
+	peers := []*domain.Peer{
+		{ID: domain.PeerID("a-relay-" + suffix), StreamID: streamID, Capabilities: domain.PeerCapabilities{CanRelay: true}},
+		{ID: domain.PeerID("b-leaf-" + suffix), StreamID: streamID},
+		{ID: domain.PeerID("c-relay-" + suffix), StreamID: streamID, Capabilities: domain.PeerCapabilities{CanRelay: true}},
+		{ID: domain.PeerID("d-relay-" + suffix), StreamID: streamID, Capabilities: domain.PeerCapabilities{CanRelay: true}},
+	}
+	for _, peer := range peers {
+		require.NoError(t, repo.Add(ctx, peer))
+	}
+	t.Cleanup(func() {
+		for _, peer := range peers {
+			_ = repo.Remove(ctx, peer.ID)
+		}
+	})
+
+	canRelay := true
+	matched, err := repo.FindByFilter(ctx, streamID, domain.PeerFilter{CanRelay: &canRelay, Limit: 2})
+	require.NoError(t, err)
+	if assert.Len(t, matched, 2) {
+		assert.Equal(t, peers[0].ID, matched[0].ID)
+		assert.Equal(t, peers[2].ID, matched[1].ID)
+	}
+
+	all, err := repo.FindByFilter(ctx, streamID, domain.PeerFilter{})
+	require.NoError(t, err)
+	assert.Len(t, all, len(peers))
+}
+
+func TestRedisPeerRepository_AddRejectsPeerInOtherStream(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	repo := redisrepo.NewRedisPeerRepositoryWithTTL(client, time.Minute)
+
+	peerID := domain.PeerID("dual-peer-" + suffix)
+	first := domain.StreamID("dual-first-" + suffix)
+	second := domain.StreamID("dual-second-" + suffix)
+	t.Cleanup(func() {
+		_ = repo.Remove(ctx, peerID)
+		_ = client.Del(ctx, "rillnet:stream:"+string(first)+":peers", "rillnet:stream:"+string(second)+":peers").Err()
+	})
+
+	require.NoError(t, repo.Add(ctx, &domain.Peer{ID: peerID, StreamID: first}))
+	// Re-adding under the same stream refreshes the peer
+	require.NoError(t, repo.Add(ctx, &domain.Peer{ID: peerID, StreamID: first}))
+
+	err := repo.Add(ctx, &domain.Peer{ID: peerID, StreamID: second})
+	assert.ErrorIs(t, err, domain.ErrPeerInOtherStream)
+
+	stored, err := repo.GetByID(ctx, peerID)
+	require.NoError(t, err)
+	assert.Equal(t, first, stored.StreamID)
+	assert.False(t, client.SIsMember(ctx, "rillnet:stream:"+string(second)+":peers", string(peerID)).Val())
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	redisrepo "rillnet/internal/infrastructure/repositories/redis"
+	"rillnet/tests/testutil"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRefreshTokenStore_RotateAndReuse(t *testing.T) {
+	if !testutil.RedisAvailable() {
+		t.Skip("Redis not available (set RILLNET_REDIS_ADDRESS or start redis:7)")
+	}
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: testutil.RedisAddr()})
+	defer client.Close()
+
+	store := redisrepo.NewRedisRefreshTokenStore(client)
+
+	// Unique hashes keep the test independent of whatever else is in Redis
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	record := ports.RefreshTokenRecord{UserID: "user-1", FamilyID: "family-" + suffix}
+	first, second, third := "first-"+suffix, "second-"+suffix, "third-"+suffix
+	expiresAt := time.Now().Add(time.Minute)
+
+	require.NoError(t, store.StartFamily(ctx, record, first, expiresAt))
+
+	got, err := store.Rotate(ctx, record.FamilyID, first, second, expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, record, got)
+
+	// The used token is rejected and still names its family
+	got, err = store.Rotate(ctx, record.FamilyID, first, third, expiresAt)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+	assert.Equal(t, record.FamilyID, got.FamilyID)
+
+	require.NoError(t, store.RevokeFamily(ctx, record.FamilyID))
+	_, err = store.Rotate(ctx, record.FamilyID, second, third, expiresAt)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+
+	_, err = store.Rotate(ctx, record.FamilyID, "unknown-"+suffix, third, expiresAt)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+
+	// A token presented under another family is unknown there
+	_, err = store.Rotate(ctx, "other-"+suffix, second, third, expiresAt)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	redisrepo "rillnet/internal/infrastructure/repositories/redis"
+	"rillnet/tests/testutil"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStreamRepository_CRUDAndListActive(t *testing.T) {
+	if !testutil.RedisAvailable() {
+		t.Skip("Redis not available (set RILLNET_REDIS_ADDRESS or start redis:7)")
+	}
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: testutil.RedisAddr()})
+	defer client.Close()
+
+	repo := redisrepo.NewRedisStreamRepository(client)
+
+	// Unique IDs keep the test independent of whatever else is in Redis
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	live := &domain.Stream{ID: domain.StreamID("repo-live-" + suffix), Name: "live", Owner: "owner-1", OwnerUserID: "user-1", Active: true, CreatedAt: time.Now()}
+	idle := &domain.Stream{ID: domain.StreamID("repo-idle-" + suffix), Name: "idle", Owner: "owner-1", OwnerUserID: "user-1", Active: false, CreatedAt: time.Now()}
+	t.Cleanup(func() {
+		_ = repo.Delete(ctx, live.ID)
+		_ = repo.Delete(ctx, idle.ID)
+	})
+
+	require.NoError(t, repo.Create(ctx, live))
+	require.NoError(t, repo.Create(ctx, idle))
+
+	got, err := repo.GetByID(ctx, live.ID)
+	require.NoError(t, err)
+	assert.Equal(t, live.Name, got.Name)
+	assert.True(t, got.Active)
+
+	listed := func() map[domain.StreamID]bool {
+		streams, err := repo.ListActive(ctx)
+		require.NoError(t, err)
+		ids := make(map[domain.StreamID]bool)
+		for _, s := range streams {
+			ids[s.ID] = true
+		}
+		return ids
+	}
+	active := listed()
+	assert.True(t, active[live.ID])
+	assert.False(t, active[idle.ID])
+
+	// Toggling Active moves streams in and out of the active set
+	live.Active = false
+	idle.Active = true
+	require.NoError(t, repo.Update(ctx, live))
+	require.NoError(t, repo.Update(ctx, idle))
+	active = listed()
+	assert.False(t, active[live.ID])
+	assert.True(t, active[idle.ID])
+
+	// Updating an unknown stream is reported rather than creating it
+	missing := &domain.Stream{ID: domain.StreamID("repo-missing-" + suffix)}
+	assert.ErrorIs(t, repo.Update(ctx, missing), domain.ErrStreamNotFound)
+
+	require.NoError(t, repo.Delete(ctx, idle.ID))
+	_, err = repo.GetByID(ctx, idle.ID)
+	assert.ErrorIs(t, err, domain.ErrStreamNotFound)
+	assert.False(t, listed()[idle.ID])
+}
+
+func TestRedisStreamRepository_ListActivePageOrdersByCreationThenID(t *testing.T) {
+	client, suffix := redisTestClient(t)
+	ctx := context.Background()
+	repo := redisrepo.NewRedisStreamRepository(client)
+
+	created := time.Now()
+	streams := []*domain.Stream{
+		{ID: domain.StreamID("page-c-" + suffix), Name: "page " + suffix, Active: true, CreatedAt: created},
+		{ID: domain.StreamID("page-a-" + suffix), Name: "page " + suffix, Active: true, CreatedAt: created.Add(time.Second)},
+		// Same creation time as page-a, so the ID breaks the tie
+		{ID: domain.StreamID("page-b-" + suffix), Name: "page " + suffix, Active: true, CreatedAt: created.Add(time.Second)},
+		{ID: domain.StreamID("page-ended-" + suffix), Name: "page " + suffix, CreatedAt: created},
+	}
+	for _, stream := range streams {
+		require.NoError(t, repo.Create(ctx, stream))
+	}
+	t.Cleanup(func() {
+		for _, stream := range streams {
+			_ = repo.Delete(ctx, stream.ID)
+		}
+	})
+	expected := []domain.StreamID{streams[0].ID, streams[1].ID, streams[2].ID}
+
+	ids := func(streams []*domain.Stream) []domain.StreamID {
+		result := []domain.StreamID{}
+		for _, stream := range streams {
+			if strings.HasSuffix(string(stream.ID), suffix) {
+				result = append(result, stream.ID)
+			}
+		}
+		return result
+	}
+
+	// Unfiltered pages come straight off the index
+	all, total, err := repo.ListActivePage(ctx, domain.StreamListOptions{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, total, len(expected))
+	assert.Equal(t, expected, ids(all))
+
+	page, total, err := repo.ListActivePage(ctx, domain.StreamListOptions{NameContains: suffix, Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), total)
+	assert.Equal(t, expected[1:2], ids(page))
+
+	// Stopping a stream takes it out of the index
+	streams[1].Active = false
+	require.NoError(t, repo.Update(ctx, streams[1]))
+	page, total, err = repo.ListActivePage(ctx, domain.StreamListOptions{NameContains: suffix})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []domain.StreamID{streams[0].ID, streams[2].ID}, ids(page))
+}
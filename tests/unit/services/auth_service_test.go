@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/services"
+	"rillnet/internal/infrastructure/repositories/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRotatingAuthService(t *testing.T) services.AuthService {
+	t.Helper()
+	authService := services.NewAuthService("rotation-test-secret", time.Minute, time.Hour, nil, nil, nil)
+	authService.(services.RefreshRotationHooks).SetRefreshTokenStore(memory.NewMemoryRefreshTokenStore())
+	return authService
+}
+
+func TestAuthService_RefreshTokensRotates(t *testing.T) {
+	ctx := context.Background()
+	authService := newRotatingAuthService(t)
+
+	user, _, refresh, err := authService.LoginUser(ctx, "alice", "secret-password")
+	require.NoError(t, err)
+
+	access, next, err := authService.RefreshTokens(ctx, refresh)
+	require.NoError(t, err)
+	assert.NotEqual(t, refresh, next)
+
+	claims, err := authService.ValidateToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+
+	// The rotated token keeps working, once
+	_, _, err = authService.RefreshTokens(ctx, next)
+	require.NoError(t, err)
+}
+
+func TestAuthService_RefreshTokenReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	authService := newRotatingAuthService(t)
+
+	_, _, stolen, err := authService.LoginUser(ctx, "alice", "secret-password")
+	require.NoError(t, err)
+	_, legitimate, err := authService.RefreshTokens(ctx, stolen)
+	require.NoError(t, err)
+
+	// Replaying the rotated token is detected...
+	_, _, err = authService.RefreshTokens(ctx, stolen)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+
+	// ...and ends the login for whoever holds its newest token too
+	_, _, err = authService.RefreshTokens(ctx, legitimate)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+
+	// Other logins of the same user are unaffected
+	_, _, other, err := authService.LoginUser(ctx, "alice", "secret-password")
+	require.NoError(t, err)
+	_, _, err = authService.RefreshTokens(ctx, other)
+	require.NoError(t, err)
+}
+
+// memoryRefreshTokens is a RefreshTokenRepository keeping token states in memory
+type memoryRefreshTokens struct {
+	mu     sync.Mutex
+	active map[string]bool
+	byUser map[domain.UserID][]string
+}
+
+func newMemoryRefreshTokens() *memoryRefreshTokens {
+	return &memoryRefreshTokens{active: make(map[string]bool), byUser: make(map[domain.UserID][]string)}
+}
+
+func (r *memoryRefreshTokens) Store(ctx context.Context, userID domain.UserID, tokenHash string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active[tokenHash] = true
+	r.byUser[userID] = append(r.byUser[userID], tokenHash)
+	return nil
+}
+
+func (r *memoryRefreshTokens) MarkReplaced(ctx context.Context, tokenHash string, replacedByHash string) error {
+	return r.Revoke(ctx, tokenHash, time.Now())
+}
+
+func (r *memoryRefreshTokens) Revoke(ctx context.Context, tokenHash string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.active[tokenHash]; ok {
+		r.active[tokenHash] = false
+	}
+	return nil
+}
+
+func (r *memoryRefreshTokens) IsActive(ctx context.Context, tokenHash string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active[tokenHash], nil
+}
+
+func (r *memoryRefreshTokens) RevokeAllForUser(ctx context.Context, userID domain.UserID, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tokenHash := range r.byUser[userID] {
+		r.active[tokenHash] = false
+	}
+	return nil
+}
+
+func TestAuthService_RefreshTokensRejectsTokensRevokedInRepository(t *testing.T) {
+	ctx := context.Background()
+	refreshRepo := newMemoryRefreshTokens()
+	authService := services.NewAuthService("rotation-test-secret", time.Minute, time.Hour, nil, nil, refreshRepo)
+	authService.(services.RefreshRotationHooks).SetRefreshTokenStore(memory.NewMemoryRefreshTokenStore())
+
+	user, _, refresh, err := authService.LoginUser(ctx, "alice", "secret-password")
+	require.NoError(t, err)
+	_, next, err := authService.RefreshTokens(ctx, refresh)
+	require.NoError(t, err)
+
+	// Revoking the user's tokens in the repository stops rotation even
+	// though the token's family was never flagged
+	require.NoError(t, refreshRepo.RevokeAllForUser(ctx, user.ID, time.Now()))
+	_, _, err = authService.RefreshTokens(ctx, next)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+
+	// A replayed token is inactive in the repository and ends its login
+	_, _, stolen, err := authService.LoginUser(ctx, "alice", "secret-password")
+	require.NoError(t, err)
+	_, legitimate, err := authService.RefreshTokens(ctx, stolen)
+	require.NoError(t, err)
+	_, _, err = authService.RefreshTokens(ctx, stolen)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+	_, _, err = authService.RefreshTokens(ctx, legitimate)
+	require.ErrorIs(t, err, domain.ErrRefreshTokenRevoked)
+}
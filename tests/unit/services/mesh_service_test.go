@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
+	"rillnet/internal/core/services"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+	"rillnet/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeshService_FindOptimalSources_SkipsObservers(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("observed-stream")
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	// No relay here is fed by a publisher yet, which the depth cap would rule out
+	cfg.Mesh.MaxHopDepth = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+
+	peers := []*domain.Peer{
+		{
+			ID:           "publisher",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{IsPublisher: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 1000},
+		},
+		{
+			// Best-looking candidate on paper, but an observer must never relay
+			ID:           "observer",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true, IsObserver: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 10000},
+		},
+		{
+			ID:           "viewer",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 500},
+		},
+	}
+	for _, peer := range peers {
+		require.NoError(t, peerRepo.Add(ctx, peer))
+	}
+
+	sources, err := meshService.FindOptimalSources(ctx, streamID, "viewer", 4)
+	require.NoError(t, err)
+	require.NotEmpty(t, sources)
+	for _, source := range sources {
+		assert.NotEqual(t, domain.PeerID("observer"), source.ID)
+	}
+
+	sources, err = meshService.FindOptimalSources(ctx, streamID, "publisher", 4)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, domain.PeerID("viewer"), sources[0].ID)
+}
+
+func TestMeshService_FindOptimalSources_DeprioritizesFailedConnections(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("history-stream")
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	// No relay here is fed by a publisher yet, which the depth cap would rule out
+	cfg.Mesh.MaxHopDepth = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+
+	peers := []*domain.Peer{
+		{
+			ID:           "relay-strong",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 5000},
+		},
+		{
+			ID:           "relay-weak",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 2000},
+		},
+		{
+			ID:           "viewer",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 500},
+		},
+	}
+	for _, peer := range peers {
+		require.NoError(t, peerRepo.Add(ctx, peer))
+	}
+
+	sources, err := meshService.FindOptimalSources(ctx, streamID, "viewer", 1)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, domain.PeerID("relay-strong"), sources[0].ID)
+
+	// The viewer could not reach the strong relay (e.g. incompatible NATs)
+	require.NoError(t, meshService.RecordConnectionResult(ctx, "relay-strong", "viewer", false))
+
+	sources, err = meshService.FindOptimalSources(ctx, streamID, "viewer", 1)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, domain.PeerID("relay-weak"), sources[0].ID)
+
+	// The outcome is specific to the pair: other targets still prefer the strong relay
+	sources, err = meshService.FindOptimalSources(ctx, streamID, "relay-weak", 1)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, domain.PeerID("relay-strong"), sources[0].ID)
+}
+
+func TestMeshService_TopologyDiff(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("flapping-stream")
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+
+	for _, id := range []domain.PeerID{"publisher", "relay", "viewer"} {
+		require.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: id, StreamID: streamID}))
+	}
+	require.NoError(t, meshService.AddConnection(ctx, &domain.PeerConnection{FromPeer: "publisher", ToPeer: "relay"}))
+	require.NoError(t, meshService.AddConnection(ctx, &domain.PeerConnection{FromPeer: "relay", ToPeer: "viewer"}))
+
+	before, err := meshService.SnapshotTopology(ctx, streamID)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerID{"publisher", "relay", "viewer"}, before.Nodes)
+	assert.Len(t, before.Edges, 2)
+
+	// The viewer flaps from the relay straight to the publisher, and a new peer joins
+	require.NoError(t, meshService.RemoveConnection(ctx, "relay", "viewer"))
+	require.NoError(t, meshService.AddConnection(ctx, &domain.PeerConnection{FromPeer: "publisher", ToPeer: "viewer"}))
+	require.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "late-viewer", StreamID: streamID}))
+
+	after, err := meshService.SnapshotTopology(ctx, streamID)
+	require.NoError(t, err)
+
+	diff := domain.DiffTopology(before, after)
+	assert.Equal(t, []domain.TopologyEdge{{From: "publisher", To: "viewer"}}, diff.AddedEdges)
+	assert.Equal(t, []domain.TopologyEdge{{From: "relay", To: "viewer"}}, diff.RemovedEdges)
+	assert.Equal(t, []domain.PeerID{"late-viewer"}, diff.AddedNodes)
+	assert.Empty(t, diff.RemovedNodes)
+
+	assert.True(t, domain.DiffTopology(after, after).Empty())
+}
+
+func TestMeshService_GetOptimalPath_PrefersQualityOverHops(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("routed-stream")
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+
+	goodRelay := domain.PeerMetrics{Bandwidth: 8000, Latency: 10 * time.Millisecond}
+	peers := []*domain.Peer{
+		{ID: "publisher", StreamID: streamID, Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+		{
+			// One hop from the viewer, but slow, lossy and overloaded
+			ID:           "lossy-relay",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 300, Latency: 400 * time.Millisecond, PacketLoss: 0.3, CPUUsage: 95},
+		},
+		{ID: "relay-a", StreamID: streamID, Capabilities: domain.PeerCapabilities{CanRelay: true}, Metrics: goodRelay},
+		{ID: "relay-b", StreamID: streamID, Capabilities: domain.PeerCapabilities{CanRelay: true}, Metrics: goodRelay},
+		{ID: "viewer", StreamID: streamID},
+	}
+	for _, peer := range peers {
+		require.NoError(t, peerRepo.Add(ctx, peer))
+	}
+
+	// publisher -> lossy-relay -> viewer is the fewest hops,
+	// publisher -> relay-a -> relay-b -> viewer the best quality
+	for _, edge := range [][2]domain.PeerID{
+		{"publisher", "lossy-relay"},
+		{"lossy-relay", "viewer"},
+		{"publisher", "relay-a"},
+		{"relay-a", "relay-b"},
+		{"relay-b", "viewer"},
+	} {
+		require.NoError(t, meshService.AddConnection(ctx, &domain.PeerConnection{FromPeer: edge[0], ToPeer: edge[1]}))
+	}
+
+	path, err := meshService.GetOptimalPath(ctx, "publisher", "viewer")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerID{"publisher", "relay-a", "relay-b", "viewer"}, path)
+
+	// Without the quality path only the lossy relay remains
+	require.NoError(t, meshService.RemoveConnection(ctx, "relay-a", "relay-b"))
+	path, err = meshService.GetOptimalPath(ctx, "publisher", "viewer")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerID{"publisher", "lossy-relay", "viewer"}, path)
+
+	require.NoError(t, meshService.RemoveConnection(ctx, "lossy-relay", "viewer"))
+	_, err = meshService.GetOptimalPath(ctx, "publisher", "viewer")
+	assert.Error(t, err)
+}
+
+func TestMeshService_SetScoringWeights_ReordersSources(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("tuned-stream")
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	// No relay here is fed by a publisher yet, which the depth cap would rule out
+	cfg.Mesh.MaxHopDepth = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+	tuner, ok := meshService.(ports.MeshScoringTuner)
+	require.True(t, ok)
+
+	peers := []*domain.Peer{
+		{
+			// Close by, but on a thin uplink
+			ID:           "nearby-relay",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 1000, Latency: 10 * time.Millisecond},
+		},
+		{
+			// Far away, but with plenty of bandwidth
+			ID:           "fat-pipe-relay",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 10000, Latency: 180 * time.Millisecond},
+		},
+		{ID: "viewer", StreamID: streamID},
+	}
+	for _, peer := range peers {
+		require.NoError(t, peerRepo.Add(ctx, peer))
+	}
+
+	sourceIDs := func() []domain.PeerID {
+		sources, err := meshService.FindOptimalSources(ctx, streamID, "viewer", 2)
+		require.NoError(t, err)
+		ids := make([]domain.PeerID, 0, len(sources))
+		for _, source := range sources {
+			ids = append(ids, source.ID)
+		}
+		return ids
+	}
+
+	require.NoError(t, tuner.SetScoringWeights(1, 0, 0))
+	assert.Equal(t, []domain.PeerID{"nearby-relay", "fat-pipe-relay"}, sourceIDs())
+
+	require.NoError(t, tuner.SetScoringWeights(0, 1, 0))
+	assert.Equal(t, []domain.PeerID{"fat-pipe-relay", "nearby-relay"}, sourceIDs())
+	latency, bandwidth, reliability := tuner.GetScoringWeights()
+	assert.Equal(t, [3]float64{0, 1, 0}, [3]float64{latency, bandwidth, reliability})
+
+	// Invalid weights are rejected and leave the current ones in place
+	assert.ErrorIs(t, tuner.SetScoringWeights(-0.5, 1, 0), config.ErrInvalidMeshWeights)
+	assert.ErrorIs(t, tuner.SetScoringWeights(0, 0, 0), config.ErrInvalidMeshWeights)
+	latency, bandwidth, reliability = tuner.GetScoringWeights()
+	assert.Equal(t, [3]float64{0, 1, 0}, [3]float64{latency, bandwidth, reliability})
+}
+
+func TestMeshService_FindOptimalSources_CapsRelayDepth(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("chain-stream")
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	cfg.Mesh.MaxHopDepth = 3
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshRepo := memory.NewMemoryMeshRepository()
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, logger.New("error").Sugar())
+
+	// Linear chain publisher -> relay-1 -> relay-2 -> relay-3, so relay-3
+	// already sits at the depth limit
+	chain := []domain.PeerID{"publisher", "relay-1", "relay-2", "relay-3"}
+	for i, id := range chain {
+		peer := &domain.Peer{
+			ID:           id,
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{IsPublisher: i == 0, CanRelay: true},
+			// Deeper relays look better on paper
+			Metrics: domain.PeerMetrics{Bandwidth: 1000 * (i + 1), Latency: 10 * time.Millisecond},
+		}
+		require.NoError(t, peerRepo.Add(ctx, peer))
+		if i > 0 {
+			require.NoError(t, meshRepo.AddConnection(ctx, &domain.PeerConnection{FromPeer: chain[i-1], ToPeer: id}))
+		}
+	}
+	require.NoError(t, peerRepo.Add(ctx, &domain.Peer{
+		ID:       "viewer",
+		StreamID: streamID,
+		Metrics:  domain.PeerMetrics{Bandwidth: 1000},
+	}))
+
+	sources, err := meshService.FindOptimalSources(ctx, streamID, "viewer", 4)
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+	for _, source := range sources {
+		assert.NotEqual(t, domain.PeerID("relay-3"), source.ID, "relay-3 would put the viewer past the max depth")
+	}
+	// relay-2 scores best but would leave the viewer at the limit, so closer peers come first
+	assert.Equal(t, domain.PeerID("relay-2"), sources[len(sources)-1].ID)
+
+	// A relay hanging off relay-3 is already past the limit and is never offered either
+	require.NoError(t, peerRepo.Add(ctx, &domain.Peer{
+		ID:           "relay-4",
+		StreamID:     streamID,
+		Capabilities: domain.PeerCapabilities{CanRelay: true},
+		Metrics:      domain.PeerMetrics{Bandwidth: 9000},
+	}))
+	require.NoError(t, meshRepo.AddConnection(ctx, &domain.PeerConnection{FromPeer: "relay-3", ToPeer: "relay-4"}))
+
+	// A relay no publisher feeds has no known depth and is not offered
+	require.NoError(t, peerRepo.Add(ctx, &domain.Peer{
+		ID:           "relay-detached",
+		StreamID:     streamID,
+		Capabilities: domain.PeerCapabilities{CanRelay: true},
+		Metrics:      domain.PeerMetrics{Bandwidth: 9000},
+	}))
+
+	sources, err = meshService.FindOptimalSources(ctx, streamID, "viewer", 4)
+	require.NoError(t, err)
+	for _, source := range sources {
+		assert.NotContains(t, []domain.PeerID{"relay-3", "relay-4", "relay-detached"}, source.ID)
+	}
+}
+
+func TestMeshService_PeriodicRebalanceBuildsEveryActiveStream(t *testing.T) {
+	streamRepo := new(MockStreamRepository)
+	peerRepo := new(MockPeerRepository)
+	streamRepo.On("ListActive", mock.Anything).Return([]*domain.Stream{
+		{ID: "stream-a", Active: true},
+		{ID: "stream-b", Active: true},
+	}, nil)
+
+	// BuildOptimalMesh starts by listing the stream's peers
+	built := make(chan domain.StreamID, 64)
+	for _, streamID := range []domain.StreamID{"stream-a", "stream-b"} {
+		streamID := streamID
+		peerRepo.On("FindByStream", mock.Anything, streamID).Return([]*domain.Peer{}, nil).Run(func(mock.Arguments) {
+			select {
+			case built <- streamID:
+			default:
+			}
+		})
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 20 * time.Millisecond
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), streamRepo, cfg.Mesh, logger.New("error").Sugar())
+	defer meshService.(interface{ Stop() }).Stop()
+
+	// Two ticks' worth of builds for each stream
+	counts := make(map[domain.StreamID]int)
+	deadline := time.After(2 * time.Second)
+	for counts["stream-a"] < 2 || counts["stream-b"] < 2 {
+		select {
+		case streamID := <-built:
+			counts[streamID]++
+		case <-deadline:
+			t.Fatalf("streams were not rebalanced on every tick: %v", counts)
+		}
+	}
+}
+
+func TestMeshService_PeriodicRebalanceDoesNotOverlap(t *testing.T) {
+	streamRepo := new(MockStreamRepository)
+	peerRepo := new(MockPeerRepository)
+
+	var cycles atomic.Int32
+	streamRepo.On("ListActive", mock.Anything).Return([]*domain.Stream{{ID: "slow-stream", Active: true}}, nil).Run(func(mock.Arguments) {
+		cycles.Add(1)
+	})
+	release := make(chan struct{})
+	peerRepo.On("FindByStream", mock.Anything, domain.StreamID("slow-stream")).Return([]*domain.Peer{}, nil).Run(func(mock.Arguments) {
+		<-release
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 10 * time.Millisecond
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), streamRepo, cfg.Mesh, logger.New("error").Sugar())
+	defer meshService.(interface{ Stop() }).Stop()
+
+	require.Eventually(t, func() bool { return cycles.Load() == 1 }, time.Second, 5*time.Millisecond)
+	// Many ticks pass while the first cycle is stuck
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), cycles.Load())
+
+	close(release)
+	assert.Eventually(t, func() bool { return cycles.Load() > 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestMeshService_AddPeer_RejectsSecondStream(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshService := services.NewMeshService(peerRepo, memory.NewMemoryMeshRepository(), nil, cfg.Mesh, logger.New("error").Sugar())
+
+	require.NoError(t, meshService.AddPeer(ctx, &domain.Peer{ID: "hopper", StreamID: "first-stream"}))
+
+	err := meshService.AddPeer(ctx, &domain.Peer{ID: "hopper", StreamID: "second-stream"})
+	assert.ErrorIs(t, err, domain.ErrPeerInOtherStream)
+
+	peers, err := peerRepo.FindByStream(ctx, "second-stream")
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+
+	// Leaving frees the peer to join elsewhere
+	require.NoError(t, meshService.RemovePeer(ctx, "hopper"))
+	assert.NoError(t, meshService.AddPeer(ctx, &domain.Peer{ID: "hopper", StreamID: "second-stream"}))
+}
@@ -2,11 +2,20 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"rillnet/internal/core/domain"
+	"rillnet/internal/core/ports"
 	"rillnet/internal/core/services"
+	"rillnet/internal/infrastructure/monitoring"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/pkg/config"
+	"rillnet/pkg/logger"
+	"rillnet/pkg/utils"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -27,6 +36,11 @@ func (m *MockStreamRepository) Create(ctx context.Context, stream *domain.Stream
 	return args.Error(0)
 }
 
+func (m *MockStreamRepository) CreateWithOwnerLimit(ctx context.Context, stream *domain.Stream, limit int) error {
+	args := m.Called(ctx, stream, limit)
+	return args.Error(0)
+}
+
 func (m *MockStreamRepository) GetByID(ctx context.Context, id domain.StreamID) (*domain.Stream, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -53,6 +67,22 @@ func (m *MockStreamRepository) ListActive(ctx context.Context) ([]*domain.Stream
 	return args.Get(0).([]*domain.Stream), args.Error(1)
 }
 
+func (m *MockStreamRepository) ListActivePage(ctx context.Context, opts domain.StreamListOptions) ([]*domain.Stream, int, error) {
+	args := m.Called(ctx, opts)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*domain.Stream), args.Int(1), args.Error(2)
+}
+
+func (m *MockStreamRepository) ListByOwner(ctx context.Context, owner domain.UserID) ([]*domain.Stream, error) {
+	args := m.Called(ctx, owner)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Stream), args.Error(1)
+}
+
 type MockPeerRepository struct {
 	mock.Mock
 }
@@ -83,6 +113,14 @@ func (m *MockPeerRepository) FindByStream(ctx context.Context, streamID domain.S
 	return args.Get(0).([]*domain.Peer), args.Error(1)
 }
 
+func (m *MockPeerRepository) FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	args := m.Called(ctx, streamID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Peer), args.Error(1)
+}
+
 func (m *MockPeerRepository) FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error) {
 	args := m.Called(ctx, streamID, excludePeers)
 	if args.Get(0) == nil {
@@ -190,6 +228,19 @@ func (m *MockMeshService) GetOptimalPath(ctx context.Context, sourcePeer, target
 	return args.Get(0).([]domain.PeerID), args.Error(1)
 }
 
+func (m *MockMeshService) SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error) {
+	args := m.Called(ctx, streamID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TopologySnapshot), args.Error(1)
+}
+
+func (m *MockMeshService) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	args := m.Called(ctx, sourcePeer, targetPeer, connected)
+	return args.Error(0)
+}
+
 func TestStreamService_CreateStream(t *testing.T) {
 	ctx := context.Background()
 	streamName := "test-stream"
@@ -256,6 +307,118 @@ func TestStreamService_CreateStream(t *testing.T) {
 	})
 }
 
+func TestStreamService_CreateStream_InjectedIDGenerator(t *testing.T) {
+	streamService := services.NewStreamServiceWithConfig(
+		memory.NewMemoryStreamRepository(),
+		new(MockPeerRepository),
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+		config.StreamConfig{},
+		&utils.SequentialGenerator{},
+	)
+
+	first, err := streamService.CreateStream(context.Background(), "first", "owner", 10)
+	assert.NoError(t, err)
+	second, err := streamService.CreateStream(context.Background(), "second", "owner", 10)
+	assert.NoError(t, err)
+
+	assert.Equal(t, domain.StreamID("stream_1"), first.ID)
+	assert.Equal(t, domain.StreamID("stream_2"), second.ID)
+}
+
+func TestStreamService_CreateStream_OwnerQuota(t *testing.T) {
+	const maxPerOwner = 3
+	owner := domain.UserID("user-quota")
+	ctx := context.WithValue(context.Background(), domain.UserIDContextKey, owner)
+
+	newService := func(cfg config.StreamConfig) ports.StreamService {
+		return services.NewStreamServiceWithConfig(
+			memory.NewMemoryStreamRepository(),
+			new(MockPeerRepository),
+			new(MockMeshRepository),
+			new(MockMeshService),
+			services.NewMetricsService(),
+			cfg,
+			nil,
+		)
+	}
+
+	t.Run("rejects stream beyond owner cap", func(t *testing.T) {
+		streamService := newService(config.StreamConfig{MaxPerOwner: maxPerOwner})
+
+		for i := 0; i < maxPerOwner; i++ {
+			_, err := streamService.CreateStream(ctx, "quota-stream", "owner-peer", 10)
+			assert.NoError(t, err)
+		}
+
+		stream, err := streamService.CreateStream(ctx, "quota-stream", "owner-peer", 10)
+		assert.ErrorIs(t, err, domain.ErrStreamQuotaExceeded)
+		assert.Nil(t, stream)
+
+		// Other owners are not affected
+		otherCtx := context.WithValue(context.Background(), domain.UserIDContextKey, domain.UserID("user-other"))
+		_, err = streamService.CreateStream(otherCtx, "quota-stream", "other-peer", 10)
+		assert.NoError(t, err)
+	})
+
+	t.Run("role override raises cap", func(t *testing.T) {
+		streamService := newService(config.StreamConfig{
+			MaxPerOwner:       1,
+			MaxPerOwnerByRole: map[string]int{string(domain.RoleModerator): maxPerOwner},
+		})
+		roleCtx := context.WithValue(ctx, domain.UserRoleContextKey, domain.RoleModerator)
+
+		for i := 0; i < maxPerOwner; i++ {
+			_, err := streamService.CreateStream(roleCtx, "quota-stream", "owner-peer", 10)
+			assert.NoError(t, err)
+		}
+
+		_, err := streamService.CreateStream(roleCtx, "quota-stream", "owner-peer", 10)
+		assert.ErrorIs(t, err, domain.ErrStreamQuotaExceeded)
+	})
+
+	t.Run("concurrent creates stay within cap", func(t *testing.T) {
+		streamService := newService(config.StreamConfig{MaxPerOwner: maxPerOwner})
+
+		var wg sync.WaitGroup
+		var created atomic.Int32
+		for i := 0; i < 4*maxPerOwner; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := streamService.CreateStream(ctx, "quota-stream", "owner-peer", 10); err == nil {
+					created.Add(1)
+				} else {
+					assert.ErrorIs(t, err, domain.ErrStreamQuotaExceeded)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(maxPerOwner), created.Load())
+	})
+}
+
+func TestAuthService_TokenCarriesConfiguredRole(t *testing.T) {
+	operator := domain.UserID("user-operator")
+	authService := services.NewAuthServiceWithRoles("role-test-secret", time.Minute, time.Hour, nil, nil, nil,
+		map[domain.UserID]domain.UserRole{operator: "operator"})
+
+	token, err := authService.GenerateToken(operator, "op")
+	assert.NoError(t, err)
+	claims, err := authService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.UserRole("operator"), claims.Role)
+
+	// Users without a configured role get none
+	token, err = authService.GenerateToken("user-plain", "plain")
+	assert.NoError(t, err)
+	claims, err = authService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Empty(t, claims.Role)
+}
+
 func TestStreamService_JoinStream(t *testing.T) {
 	ctx := context.Background()
 	streamID := domain.StreamID("stream-123")
@@ -673,3 +836,791 @@ func TestQualityService(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamService_CreateStream_InstanceCap(t *testing.T) {
+	const maxStreams = 2
+	ctx := context.Background()
+	streamRepo := memory.NewMemoryStreamRepository()
+	streamService := services.NewStreamServiceWithConfig(
+		streamRepo,
+		new(MockPeerRepository),
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+		config.StreamConfig{MaxStreams: maxStreams},
+		nil,
+	)
+
+	var created []*domain.Stream
+	for i := 0; i < maxStreams; i++ {
+		stream, err := streamService.CreateStream(ctx, "capped", "owner", 10)
+		assert.NoError(t, err)
+		created = append(created, stream)
+	}
+
+	_, err := streamService.CreateStream(ctx, "one-too-many", "owner", 10)
+	assert.ErrorIs(t, err, domain.ErrInstanceAtCapacity)
+
+	// Ending a stream frees its slot
+	created[0].Active = false
+	assert.NoError(t, streamRepo.Update(ctx, created[0]))
+
+	_, err = streamService.CreateStream(ctx, "after-end", "owner", 10)
+	assert.NoError(t, err)
+}
+
+func TestStreamService_JoinStream_InstanceCap(t *testing.T) {
+	ctx := context.Background()
+	streamRepo := memory.NewMemoryStreamRepository()
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshRepo := new(MockMeshRepository)
+	meshRepo.On("BuildMesh", ctx, mock.Anything, 4).Return(nil)
+	meshService := new(MockMeshService)
+	meshService.On("AddPeer", ctx, mock.Anything).Return(nil)
+
+	newService := func() ports.StreamService {
+		return services.NewStreamServiceWithConfig(
+			streamRepo, peerRepo, meshRepo, meshService,
+			services.NewMetricsService(),
+			config.StreamConfig{MaxStreams: 1},
+			nil,
+		)
+	}
+	// Two instances share the repositories, each capped at one stream
+	instanceA, instanceB := newService(), newService()
+
+	remote, err := instanceA.CreateStream(ctx, "remote", "owner", 10)
+	assert.NoError(t, err)
+	local, err := instanceB.CreateStream(ctx, "local", "owner", 10)
+	assert.NoError(t, err)
+
+	err = instanceB.JoinStream(ctx, remote.ID, &domain.Peer{ID: "viewer-1", StreamID: remote.ID})
+	assert.ErrorIs(t, err, domain.ErrInstanceAtCapacity)
+
+	// Joining a stream the instance already hosts takes no new slot
+	err = instanceB.JoinStream(ctx, local.ID, &domain.Peer{ID: "viewer-2", StreamID: local.ID})
+	assert.NoError(t, err)
+}
+
+func TestStreamService_GetStreamStats_HealthScoreConfig(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("weighted-stream")
+	peers := []*domain.Peer{
+		{
+			ID:           "pub-1",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{IsPublisher: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 1000, Latency: 200 * time.Millisecond},
+		},
+		{ID: "sub-1", StreamID: streamID, Metrics: domain.PeerMetrics{Latency: 200 * time.Millisecond}},
+		{ID: "sub-2", StreamID: streamID, Metrics: domain.PeerMetrics{Latency: 200 * time.Millisecond}},
+	}
+
+	newService := func(health config.HealthScoreConfig) ports.StreamService {
+		peerRepo := new(MockPeerRepository)
+		peerRepo.On("FindByStream", ctx, streamID).Return(peers, nil)
+		return services.NewStreamServiceWithConfig(
+			new(MockStreamRepository),
+			peerRepo,
+			new(MockMeshRepository),
+			new(MockMeshService),
+			services.NewMetricsService(),
+			config.StreamConfig{Health: health},
+			nil,
+		)
+	}
+
+	tests := []struct {
+		name   string
+		health config.HealthScoreConfig
+		want   float64
+	}{
+		// 1*20 + 2*2 + 1000/100 + 2/3*30 (latency under 300ms)
+		{"default weights", config.HealthScoreConfig{}, 54},
+		// 1*10 + 2*5 + 1000*0.02 + 2/3*9
+		{"custom weights", config.HealthScoreConfig{
+			Strategy:         config.HealthStrategyAdditive,
+			PublisherWeight:  10,
+			SubscriberWeight: 5,
+			BitrateWeight:    0.02,
+			LatencyWeight:    9,
+		}, 46},
+		// Weights large enough to exceed the cap
+		{"capped at 100", config.HealthScoreConfig{
+			Strategy:        config.HealthStrategyAdditive,
+			PublisherWeight: 150,
+		}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := newService(tt.health).GetStreamStats(ctx, streamID)
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, stats.HealthScore, 1e-9)
+		})
+	}
+}
+
+func TestStreamService_GetStreamStats_PublisherGatedHealth(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("no-publisher")
+	peerRepo := new(MockPeerRepository)
+	peerRepo.On("FindByStream", ctx, streamID).Return([]*domain.Peer{
+		{ID: "sub-1", StreamID: streamID},
+	}, nil)
+
+	health := config.DefaultHealthScoreConfig()
+	health.Strategy = config.HealthStrategyPublisherGated
+	streamService := services.NewStreamServiceWithConfig(
+		new(MockStreamRepository),
+		peerRepo,
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+		config.StreamConfig{Health: health},
+		nil,
+	)
+
+	stats, err := streamService.GetStreamStats(ctx, streamID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, stats.HealthScore)
+}
+
+func TestStreamService_JoinStream_BandwidthBudget(t *testing.T) {
+	ctx := context.Background()
+	mockMeshService := new(MockMeshService)
+	mockMeshRepo := new(MockMeshRepository)
+	mockMeshService.On("AddPeer", ctx, mock.Anything).Return(nil)
+	mockMeshService.On("RemovePeer", ctx, mock.Anything).Return(nil)
+	mockMeshRepo.On("BuildMesh", ctx, mock.Anything, 4).Return(nil)
+
+	// Default quality levels are 2500, 1000 and 500 kbps
+	streamService := services.NewStreamServiceWithConfig(
+		memory.NewMemoryStreamRepository(),
+		memory.NewMemoryPeerRepository(),
+		mockMeshRepo,
+		mockMeshService,
+		services.NewMetricsService(),
+		config.StreamConfig{MaxTotalBitrate: 3000},
+		nil,
+	)
+	stream, err := streamService.CreateStream(ctx, "free-tier", "owner", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 3000, stream.MaxTotalBitrate)
+
+	subscriber := func(id domain.PeerID) *domain.Peer {
+		return &domain.Peer{ID: id, StreamID: stream.ID}
+	}
+
+	first := subscriber("viewer-1")
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, first))
+	assert.Equal(t, 0, first.Capabilities.MaxBitrate, "a join within budget keeps its requested bitrate")
+
+	// Only 500 kbps are left, so the next viewer is downgraded to the low level
+	second := subscriber("viewer-2")
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, second))
+	assert.Equal(t, 500, second.Capabilities.MaxBitrate)
+
+	// Nothing fits anymore
+	third := subscriber("viewer-3")
+	assert.ErrorIs(t, streamService.JoinStream(ctx, stream.ID, third), domain.ErrStreamBandwidthExceeded)
+
+	// Publishers are ingest, not egress, and are always admitted
+	publisher := &domain.Peer{ID: "publisher", StreamID: stream.ID, Capabilities: domain.PeerCapabilities{IsPublisher: true}}
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, publisher))
+
+	// Leaving frees the reservation
+	assert.NoError(t, streamService.LeaveStream(ctx, stream.ID, first.ID))
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, third))
+	assert.Equal(t, 0, third.Capabilities.MaxBitrate)
+}
+
+func TestStreamService_JoinStream_EgressSharedByPriority(t *testing.T) {
+	ctx := context.Background()
+	mockMeshService := new(MockMeshService)
+	mockMeshRepo := new(MockMeshRepository)
+	mockMeshService.On("AddPeer", ctx, mock.Anything).Return(nil)
+	mockMeshRepo.On("BuildMesh", ctx, mock.Anything, 4).Return(nil)
+
+	// Default quality levels are 2500, 1000 and 500 kbps
+	streamService := services.NewStreamServiceWithConfig(
+		memory.NewMemoryStreamRepository(),
+		memory.NewMemoryPeerRepository(),
+		mockMeshRepo,
+		mockMeshService,
+		services.NewMetricsService(),
+		config.StreamConfig{MaxEgressBitrate: 7000},
+		nil,
+	)
+	low, err := streamService.CreateStream(ctx, "free-stream", "owner-1", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, low.Priority, "streams default to equal priority")
+	high, err := streamService.CreateStream(ctx, "premium-stream", "owner-2", 10)
+	assert.NoError(t, err)
+	assert.NoError(t, streamService.(ports.StreamPrioritySetter).SetStreamPriority(ctx, high.ID, 4))
+	assert.ErrorIs(t, streamService.(ports.StreamPrioritySetter).SetStreamPriority(ctx, high.ID, domain.MaxStreamPriority+1), domain.ErrInvalidStreamPriority)
+
+	// The premium stream is owed 5/6 of the budget, so the free stream is
+	// throttled even before premium viewers arrive
+	lowViewer := &domain.Peer{ID: "free-viewer-1", StreamID: low.ID}
+	assert.NoError(t, streamService.JoinStream(ctx, low.ID, lowViewer))
+	assert.Equal(t, 1000, lowViewer.Capabilities.MaxBitrate)
+	assert.ErrorIs(t, streamService.JoinStream(ctx, low.ID, &domain.Peer{ID: "free-viewer-2", StreamID: low.ID}), domain.ErrStreamBandwidthExceeded)
+
+	// Premium viewers keep the full bitrate
+	for _, id := range []domain.PeerID{"premium-viewer-1", "premium-viewer-2"} {
+		viewer := &domain.Peer{ID: id, StreamID: high.ID}
+		assert.NoError(t, streamService.JoinStream(ctx, high.ID, viewer))
+		assert.Equal(t, 0, viewer.Capabilities.MaxBitrate)
+	}
+}
+
+func TestStreamService_EgressReleasedOnAnyMeshRemoval(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshRepo := memory.NewMemoryMeshRepository()
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, config.MeshConfig{}, logger.New("error").Sugar())
+	streamService := services.NewStreamServiceWithConfig(
+		memory.NewMemoryStreamRepository(),
+		peerRepo,
+		meshRepo,
+		meshService,
+		services.NewMetricsService(),
+		config.StreamConfig{MaxTotalBitrate: 2500},
+		nil,
+	)
+	meshService.(services.PeerRemovalHooks).SetRemovalObserver(streamService.(ports.PeerRemovalObserver))
+
+	stream, err := streamService.CreateStream(ctx, "budgeted", "owner", 10)
+	assert.NoError(t, err)
+
+	first := &domain.Peer{ID: "viewer-1", StreamID: stream.ID}
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, first))
+	second := &domain.Peer{ID: "viewer-2", StreamID: stream.ID}
+	assert.ErrorIs(t, streamService.JoinStream(ctx, stream.ID, second), domain.ErrStreamBandwidthExceeded)
+
+	// A disconnect or eviction removes the peer through the mesh, not LeaveStream
+	assert.NoError(t, meshService.RemovePeer(ctx, first.ID))
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, second))
+}
+
+func TestStreamService_GetStreamStats_ServesCacheUntilStale(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("polled-stream")
+	const interval = 200 * time.Millisecond
+
+	streamRepo := new(MockStreamRepository)
+	peerRepo := new(MockPeerRepository)
+	// Not active, so only GetStreamStats computes this stream's stats
+	streamRepo.On("ListActive", mock.Anything).Return([]*domain.Stream{}, nil)
+	peerRepo.On("FindByStream", ctx, streamID).Return([]*domain.Peer{
+		{ID: "pub-1", StreamID: streamID, Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+	}, nil)
+
+	streamService := services.NewStreamServiceWithConfig(
+		streamRepo,
+		peerRepo,
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+		config.StreamConfig{StatsInterval: interval},
+		nil,
+	)
+	defer streamService.(interface{ Stop() }).Stop()
+
+	for i := 0; i < 3; i++ {
+		stats, err := streamService.GetStreamStats(ctx, streamID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, stats.ActivePublishers)
+	}
+	peerRepo.AssertNumberOfCalls(t, "FindByStream", 1)
+
+	// Entries outlive one refresh interval so the background job can renew them
+	time.Sleep(interval + 50*time.Millisecond)
+	_, err := streamService.GetStreamStats(ctx, streamID)
+	assert.NoError(t, err)
+	peerRepo.AssertNumberOfCalls(t, "FindByStream", 1)
+
+	time.Sleep(interval)
+	_, err = streamService.GetStreamStats(ctx, streamID)
+	assert.NoError(t, err)
+	peerRepo.AssertNumberOfCalls(t, "FindByStream", 2)
+}
+
+func TestStreamService_StatsAreRefreshedInBackground(t *testing.T) {
+	streamID := domain.StreamID("busy-stream")
+
+	streamRepo := new(MockStreamRepository)
+	peerRepo := new(MockPeerRepository)
+	computed := make(chan struct{}, 1)
+	streamRepo.On("ListActive", mock.Anything).Return([]*domain.Stream{{ID: streamID, Active: true}}, nil)
+	peerRepo.On("FindByStream", mock.Anything, streamID).Return([]*domain.Peer{
+		{ID: "pub-1", StreamID: streamID, Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+	}, nil).Run(func(mock.Arguments) {
+		select {
+		case computed <- struct{}{}:
+		default:
+		}
+	})
+
+	streamService := services.NewStreamServiceWithConfig(
+		streamRepo,
+		peerRepo,
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+		config.StreamConfig{StatsInterval: 20 * time.Millisecond},
+		nil,
+	)
+	defer streamService.(interface{ Stop() }).Stop()
+
+	// The job computes the stats before anyone asks for them
+	select {
+	case <-computed:
+	case <-time.After(time.Second):
+		t.Fatal("stats were not computed in the background")
+	}
+	stats, err := streamService.GetStreamStats(context.Background(), streamID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.ActivePublishers)
+}
+
+func TestStreamService_FindPeers_FiltersByCapability(t *testing.T) {
+	mockStreamRepo := new(MockStreamRepository)
+	peerRepo := memory.NewMemoryPeerRepository()
+	streamService := services.NewStreamService(
+		mockStreamRepo,
+		peerRepo,
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+	)
+
+	ctx := context.Background()
+	streamID := domain.StreamID("test-stream")
+	peers := []*domain.Peer{
+		{
+			ID:           "relay-vp9",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true, SupportedCodecs: []string{"VP8", "VP9"}},
+			Metrics:      domain.PeerMetrics{Bandwidth: 3000},
+		},
+		{
+			ID:           "relay-vp9-slow",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true, SupportedCodecs: []string{"vp9"}},
+			Metrics:      domain.PeerMetrics{Bandwidth: 500},
+		},
+		{
+			ID:           "relay-h264",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true, SupportedCodecs: []string{"H264"}},
+			Metrics:      domain.PeerMetrics{Bandwidth: 5000},
+		},
+		{
+			ID:           "leaf-vp9",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{SupportedCodecs: []string{"VP9"}},
+			Metrics:      domain.PeerMetrics{Bandwidth: 4000},
+		},
+	}
+	mockStreamRepo.On("GetByID", ctx, streamID).Return(&domain.Stream{ID: streamID, Active: true}, nil)
+	for _, peer := range peers {
+		assert.NoError(t, peerRepo.Add(ctx, peer))
+	}
+
+	canRelay := true
+	matched, err := streamService.FindPeers(ctx, streamID, domain.PeerFilter{
+		CanRelay:     &canRelay,
+		Codec:        "VP9",
+		MinBandwidth: 1000,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, matched, 1) {
+		assert.Equal(t, domain.PeerID("relay-vp9"), matched[0].ID)
+	}
+
+	// An empty filter matches every peer
+	all, err := streamService.FindPeers(ctx, streamID, domain.PeerFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, all, len(peers))
+
+	notRelay := false
+	leaves, err := streamService.FindPeers(ctx, streamID, domain.PeerFilter{CanRelay: &notRelay})
+	assert.NoError(t, err)
+	if assert.Len(t, leaves, 1) {
+		assert.Equal(t, domain.PeerID("leaf-vp9"), leaves[0].ID)
+	}
+
+	// The limit keeps the first matches in peer ID order
+	limited, err := streamService.FindPeers(ctx, streamID, domain.PeerFilter{Codec: "VP9", Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, limited, 2) {
+		assert.Equal(t, domain.PeerID("leaf-vp9"), limited[0].ID)
+		assert.Equal(t, domain.PeerID("relay-vp9"), limited[1].ID)
+	}
+}
+
+func TestStreamService_JoinStream_ObserversDoNotCountTowardMaxPeers(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := config.DefaultConfig()
+	cfg.Mesh.RebalanceInterval = 0
+	streamRepo := memory.NewMemoryStreamRepository()
+	peerRepo := memory.NewMemoryPeerRepository()
+	meshRepo := memory.NewMemoryMeshRepository()
+	meshService := services.NewMeshService(peerRepo, meshRepo, nil, cfg.Mesh, logger.New("error").Sugar())
+	streamService := services.NewStreamService(streamRepo, peerRepo, meshRepo, meshService, services.NewMetricsService())
+
+	stream, err := streamService.CreateStream(ctx, "small", "owner", 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	observer := &domain.Peer{ID: "dashboard", StreamID: stream.ID, Capabilities: domain.PeerCapabilities{IsObserver: true}}
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, observer))
+
+	publisher := &domain.Peer{ID: "publisher", StreamID: stream.ID, Capabilities: domain.PeerCapabilities{IsPublisher: true}}
+	assert.NoError(t, streamService.JoinStream(ctx, stream.ID, publisher), "observer must not use up the only slot")
+
+	viewer := &domain.Peer{ID: "viewer", StreamID: stream.ID}
+	assert.Error(t, streamService.JoinStream(ctx, stream.ID, viewer), "stream is full with one media peer")
+}
+
+func TestStreamService_GetPeer_ScopedToStream(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	streamService := services.NewStreamService(
+		new(MockStreamRepository),
+		mockPeerRepo,
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+	)
+
+	ctx := context.Background()
+	peer := &domain.Peer{ID: "viewer", StreamID: "stream-a"}
+	mockPeerRepo.On("GetByID", ctx, peer.ID).Return(peer, nil)
+	mockPeerRepo.On("GetByID", ctx, domain.PeerID("gone")).Return(nil, domain.ErrPeerNotFound)
+
+	got, err := streamService.GetPeer(ctx, "stream-a", peer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, peer, got)
+
+	_, err = streamService.GetPeer(ctx, "stream-b", peer.ID)
+	assert.ErrorIs(t, err, domain.ErrPeerNotFound)
+
+	_, err = streamService.GetPeer(ctx, "stream-a", "gone")
+	assert.ErrorIs(t, err, domain.ErrPeerNotFound)
+}
+
+// MockStreamCloser records the streams the SFU is asked to close
+type MockStreamCloser struct {
+	mock.Mock
+}
+
+func (m *MockStreamCloser) CloseStream(ctx context.Context, streamID domain.StreamID) error {
+	args := m.Called(ctx, streamID)
+	return args.Error(0)
+}
+
+// MockStreamEndRecorder records streams reported as ended
+type MockStreamEndRecorder struct {
+	mock.Mock
+}
+
+func (m *MockStreamEndRecorder) RecordStreamEnded(streamID domain.StreamID) {
+	m.Called(streamID)
+}
+
+// MockStreamStopNotifier records the stream_stopped notifications sent
+type MockStreamStopNotifier struct {
+	mock.Mock
+}
+
+func (m *MockStreamStopNotifier) NotifyStreamStopped(ctx context.Context, streamID domain.StreamID) error {
+	args := m.Called(ctx, streamID)
+	return args.Error(0)
+}
+
+func TestStreamService_WorksWithNopMetrics(t *testing.T) {
+	ctx := context.Background()
+	streamService := services.NewStreamService(
+		memory.NewMemoryStreamRepository(),
+		memory.NewMemoryPeerRepository(),
+		memory.NewMemoryMeshRepository(),
+		new(MockMeshService),
+		services.NewMetricsService(),
+	)
+	streamService.(services.MetricsHooks).SetMetrics(monitoring.NopMetrics{})
+	streamService.(services.StreamStopHooks).SetStopHooks(nil, monitoring.NopMetrics{})
+
+	stream, err := streamService.CreateStream(ctx, "nop-metrics", "owner", 10)
+	assert.NoError(t, err)
+	assert.NoError(t, streamService.StopStream(ctx, stream.ID))
+
+	stopped, err := streamService.GetStream(ctx, stream.ID)
+	assert.NoError(t, err)
+	assert.False(t, stopped.Active)
+}
+
+func TestStreamService_StopStream(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("live-stream")
+
+	newService := func(streamRepo *MockStreamRepository, peerRepo *MockPeerRepository, meshService *MockMeshService, closer *MockStreamCloser, recorder *MockStreamEndRecorder, notifier ...*MockStreamStopNotifier) ports.StreamService {
+		streamService := services.NewStreamService(streamRepo, peerRepo, new(MockMeshRepository), meshService, services.NewMetricsService())
+		streamService.(services.StreamStopHooks).SetStopHooks(closer, recorder)
+		if len(notifier) > 0 {
+			streamService.(services.StreamStopHooks).SetStopNotifier(notifier[0])
+		}
+		return streamService
+	}
+
+	t.Run("stops active stream and evicts its peers", func(t *testing.T) {
+		mockStreamRepo := new(MockStreamRepository)
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		closer := new(MockStreamCloser)
+		recorder := new(MockStreamEndRecorder)
+		notifier := new(MockStreamStopNotifier)
+		streamService := newService(mockStreamRepo, mockPeerRepo, mockMeshService, closer, recorder, notifier)
+
+		stream := &domain.Stream{ID: streamID, Active: true, MaxPeers: 10}
+		peers := []*domain.Peer{
+			{ID: "publisher", StreamID: streamID, Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+			{ID: "viewer", StreamID: streamID},
+		}
+		mockStreamRepo.On("GetByID", ctx, streamID).Return(stream, nil)
+		mockStreamRepo.On("Update", ctx, mock.MatchedBy(func(s *domain.Stream) bool {
+			return s.ID == streamID && !s.Active
+		})).Return(nil).Once()
+		mockPeerRepo.On("FindByStream", ctx, streamID).Return(peers, nil)
+		mockMeshService.On("RemovePeer", ctx, domain.PeerID("publisher")).Return(nil).Once()
+		mockMeshService.On("RemovePeer", ctx, domain.PeerID("viewer")).Return(nil).Once()
+		closer.On("CloseStream", ctx, streamID).Return(nil).Once()
+		recorder.On("RecordStreamEnded", streamID).Once()
+		notifier.On("NotifyStreamStopped", ctx, streamID).Return(nil).Once()
+
+		assert.NoError(t, streamService.StopStream(ctx, streamID))
+
+		assert.False(t, stream.Active)
+		mockStreamRepo.AssertExpectations(t)
+		mockPeerRepo.AssertExpectations(t)
+		mockMeshService.AssertExpectations(t)
+		closer.AssertExpectations(t)
+		recorder.AssertExpectations(t)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("failed cleanup still closes every connection and keeps the stream active", func(t *testing.T) {
+		mockStreamRepo := new(MockStreamRepository)
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		closer := new(MockStreamCloser)
+		recorder := new(MockStreamEndRecorder)
+		notifier := new(MockStreamStopNotifier)
+		streamService := newService(mockStreamRepo, mockPeerRepo, mockMeshService, closer, recorder, notifier)
+
+		stream := &domain.Stream{ID: streamID, Active: true, MaxPeers: 10}
+		peers := []*domain.Peer{
+			{ID: "publisher", StreamID: streamID, Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+			{ID: "viewer", StreamID: streamID},
+		}
+		mockStreamRepo.On("GetByID", ctx, streamID).Return(stream, nil)
+		mockPeerRepo.On("FindByStream", ctx, streamID).Return(peers, nil)
+		mockMeshService.On("RemovePeer", ctx, domain.PeerID("publisher")).Return(fmt.Errorf("redis unavailable")).Once()
+		mockMeshService.On("RemovePeer", ctx, domain.PeerID("viewer")).Return(nil).Once()
+		closer.On("CloseStream", ctx, streamID).Return(nil).Once()
+
+		assert.Error(t, streamService.StopStream(ctx, streamID))
+
+		// Stopping again retries, since the stream is still active
+		assert.True(t, stream.Active)
+		mockMeshService.AssertExpectations(t)
+		closer.AssertExpectations(t)
+		mockStreamRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		recorder.AssertNotCalled(t, "RecordStreamEnded", mock.Anything)
+		notifier.AssertNotCalled(t, "NotifyStreamStopped", mock.Anything, mock.Anything)
+	})
+
+	t.Run("stopping a stopped stream is a no-op", func(t *testing.T) {
+		mockStreamRepo := new(MockStreamRepository)
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		closer := new(MockStreamCloser)
+		recorder := new(MockStreamEndRecorder)
+		streamService := newService(mockStreamRepo, mockPeerRepo, mockMeshService, closer, recorder)
+
+		mockStreamRepo.On("GetByID", ctx, streamID).Return(&domain.Stream{ID: streamID, Active: false}, nil)
+
+		assert.NoError(t, streamService.StopStream(ctx, streamID))
+
+		mockStreamRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockMeshService.AssertNotCalled(t, "RemovePeer", mock.Anything, mock.Anything)
+		closer.AssertNotCalled(t, "CloseStream", mock.Anything, mock.Anything)
+		recorder.AssertNotCalled(t, "RecordStreamEnded", mock.Anything)
+	})
+
+	t.Run("unknown stream", func(t *testing.T) {
+		mockStreamRepo := new(MockStreamRepository)
+		streamService := newService(mockStreamRepo, new(MockPeerRepository), new(MockMeshService), new(MockStreamCloser), new(MockStreamEndRecorder))
+
+		mockStreamRepo.On("GetByID", ctx, streamID).Return(nil, domain.ErrStreamNotFound)
+
+		assert.ErrorIs(t, streamService.StopStream(ctx, streamID), domain.ErrStreamNotFound)
+	})
+}
+
+func TestStreamService_ListStreamsPage(t *testing.T) {
+	ctx := context.Background()
+	streamRepo := memory.NewMemoryStreamRepository()
+	streamService := services.NewStreamService(
+		streamRepo,
+		new(MockPeerRepository),
+		new(MockMeshRepository),
+		new(MockMeshService),
+		services.NewMetricsService(),
+	)
+
+	created := time.Now()
+	names := []string{"Morning News", "Evening news", "Cooking Live", "Chess Club", "Late News"}
+	for i, name := range names {
+		assert.NoError(t, streamRepo.Create(ctx, &domain.Stream{
+			ID:        domain.StreamID(fmt.Sprintf("stream-%d", i)),
+			Name:      name,
+			Active:    true,
+			CreatedAt: created.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	// Inactive streams are never listed
+	assert.NoError(t, streamRepo.Create(ctx, &domain.Stream{ID: "ended", Name: "Old News", CreatedAt: created}))
+
+	ids := func(streams []*domain.Stream) []domain.StreamID {
+		result := make([]domain.StreamID, 0, len(streams))
+		for _, stream := range streams {
+			result = append(result, stream.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		opts          domain.StreamListOptions
+		expectedIDs   []domain.StreamID
+		expectedTotal int
+	}{
+		{
+			name:          "first page",
+			opts:          domain.StreamListOptions{Limit: 2},
+			expectedIDs:   []domain.StreamID{"stream-0", "stream-1"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "last partial page",
+			opts:          domain.StreamListOptions{Limit: 2, Offset: 4},
+			expectedIDs:   []domain.StreamID{"stream-4"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "offset at the end is an empty page",
+			opts:          domain.StreamListOptions{Limit: 2, Offset: 5},
+			expectedIDs:   []domain.StreamID{},
+			expectedTotal: 5,
+		},
+		{
+			name:          "offset past the end is an empty page",
+			opts:          domain.StreamListOptions{Limit: 2, Offset: 50},
+			expectedIDs:   []domain.StreamID{},
+			expectedTotal: 5,
+		},
+		{
+			name:          "no limit returns the rest",
+			opts:          domain.StreamListOptions{Offset: 3},
+			expectedIDs:   []domain.StreamID{"stream-3", "stream-4"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "name filter is case-insensitive",
+			opts:          domain.StreamListOptions{Limit: 10, NameContains: "NEWS"},
+			expectedIDs:   []domain.StreamID{"stream-0", "stream-1", "stream-4"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "name filter pages over matches only",
+			opts:          domain.StreamListOptions{Limit: 1, Offset: 1, NameContains: "news"},
+			expectedIDs:   []domain.StreamID{"stream-1"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "name filter without matches",
+			opts:          domain.StreamListOptions{Limit: 10, NameContains: "sports"},
+			expectedIDs:   []domain.StreamID{},
+			expectedTotal: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams, total, err := streamService.ListStreamsPage(ctx, tt.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIDs, ids(streams))
+			assert.Equal(t, tt.expectedTotal, total)
+		})
+	}
+
+	t.Run("negative offset is rejected", func(t *testing.T) {
+		_, _, err := streamService.ListStreamsPage(ctx, domain.StreamListOptions{Limit: 1, Offset: -1})
+		assert.Error(t, err)
+	})
+}
+
+// MockPeerDisconnector is a stream closer that can also drop a single peer
+type MockPeerDisconnector struct {
+	MockStreamCloser
+}
+
+func (m *MockPeerDisconnector) DisconnectPeer(ctx context.Context, peerID domain.PeerID) error {
+	args := m.Called(ctx, peerID)
+	return args.Error(0)
+}
+
+// MockPeerKickNotifier records the peer_kicked notifications sent
+type MockPeerKickNotifier struct {
+	mock.Mock
+}
+
+func (m *MockPeerKickNotifier) NotifyPeerKicked(ctx context.Context, streamID domain.StreamID, peerID domain.PeerID) error {
+	args := m.Called(ctx, streamID, peerID)
+	return args.Error(0)
+}
+
+func TestStreamService_KickPeer(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("live-stream")
+
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	disconnector := new(MockPeerDisconnector)
+	notifier := new(MockPeerKickNotifier)
+	streamService := services.NewStreamService(new(MockStreamRepository), mockPeerRepo, new(MockMeshRepository), mockMeshService, services.NewMetricsService())
+	streamService.(services.StreamStopHooks).SetStopHooks(disconnector, nil)
+	streamService.(services.StreamStopHooks).SetKickNotifier(notifier)
+	kicker := streamService.(ports.PeerKicker)
+
+	viewer := &domain.Peer{ID: "viewer", StreamID: streamID}
+	mockPeerRepo.On("GetByID", ctx, viewer.ID).Return(viewer, nil)
+	mockPeerRepo.On("GetByID", ctx, domain.PeerID("gone")).Return(nil, domain.ErrPeerNotFound)
+	mockMeshService.On("RemovePeer", ctx, viewer.ID).Return(nil).Once()
+	disconnector.On("DisconnectPeer", ctx, viewer.ID).Return(nil).Once()
+	notifier.On("NotifyPeerKicked", ctx, streamID, viewer.ID).Return(nil).Once()
+
+	assert.NoError(t, kicker.KickPeer(ctx, streamID, viewer.ID))
+	mockMeshService.AssertExpectations(t)
+	disconnector.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+
+	// Peers of other streams and unknown peers are left alone
+	assert.ErrorIs(t, kicker.KickPeer(ctx, "other-stream", viewer.ID), domain.ErrPeerNotFound)
+	assert.ErrorIs(t, kicker.KickPeer(ctx, streamID, "gone"), domain.ErrPeerNotFound)
+	mockMeshService.AssertNumberOfCalls(t, "RemovePeer", 1)
+}
@@ -0,0 +1,88 @@
+package signal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rillnet/internal/core/domain"
+	"rillnet/internal/infrastructure/distributed"
+	"rillnet/internal/infrastructure/repositories/memory"
+	"rillnet/internal/infrastructure/signal"
+	"rillnet/tests/testutil"
+
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWebSocketServer_RelaysOfferAcrossInstances(t *testing.T) {
+	if !testutil.RedisAvailable() {
+		t.Skip("Redis not available (set RILLNET_REDIS_ADDRESS or start redis:7)")
+	}
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: testutil.RedisAddr()})
+	defer client.Close()
+
+	// Both instances share the peer repository, as they do with Redis storage
+	peerRepo := memory.NewMemoryPeerRepository()
+	publisher := &domain.Peer{ID: "relay-publisher", StreamID: "relay-stream", Capabilities: domain.PeerCapabilities{IsPublisher: true}}
+	viewer := &domain.Peer{ID: "relay-viewer", StreamID: "relay-stream"}
+	require.NoError(t, peerRepo.Add(ctx, publisher))
+	require.NoError(t, peerRepo.Add(ctx, viewer))
+
+	mockMeshService := new(MockMeshService)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+	mockAuthService := createTestAuthService()
+
+	startInstance := func(instanceID string, peer *domain.Peer) (*signal.WebSocketServer, *httptest.Server) {
+		registry := distributed.NewSharedPeerRegistry(client, instanceID, zap.NewNop().Sugar())
+		require.NoError(t, registry.RegisterPeer(ctx, peer))
+		t.Cleanup(func() { _ = registry.UnregisterPeer(ctx, peer.ID) })
+
+		server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+		server.EnableCrossInstanceRelay(client, registry, instanceID)
+		return server, httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	}
+	serverA, testServerA := startInstance("relay-instance-a", viewer)
+	defer testServerA.Close()
+	defer func() { _ = serverA.Shutdown(ctx) }()
+	serverB, testServerB := startInstance("relay-instance-b", publisher)
+	defer testServerB.Close()
+	defer func() { _ = serverB.Shutdown(ctx) }()
+
+	dial := func(testServer *httptest.Server, peerID domain.PeerID) *websocket.Conn {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		return conn
+	}
+	viewerConn := dial(testServerA, viewer.ID)
+	defer viewerConn.Close()
+	publisherConn := dial(testServerB, publisher.ID)
+	defer publisherConn.Close()
+
+	require.Eventually(t, func() bool {
+		return serverA.IsPeerConnected(viewer.ID) && serverB.IsPeerConnected(publisher.ID)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	sdp := `"v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"`
+	err := viewerConn.WriteJSON(signal.SignalMessage{
+		Type:    "offer",
+		Payload: json.RawMessage(`{"sdp": ` + sdp + `, "target_peer": "relay-publisher"}`),
+	})
+	require.NoError(t, err)
+
+	_ = publisherConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var forwarded map[string]interface{}
+	require.NoError(t, publisherConn.ReadJSON(&forwarded))
+	assert.Equal(t, "offer", forwarded["type"])
+	assert.Equal(t, "relay-viewer", forwarded["from_peer"])
+}
@@ -5,15 +5,20 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"rillnet/internal/core/domain"
 	"rillnet/internal/core/services"
+	"rillnet/internal/infrastructure/repositories/memory"
 	"rillnet/internal/infrastructure/signal"
+	"rillnet/pkg/config"
+	sdputil "rillnet/pkg/webrtc"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -49,6 +54,14 @@ func (m *MockPeerRepository) FindByStream(ctx context.Context, streamID domain.S
 	return args.Get(0).([]*domain.Peer), args.Error(1)
 }
 
+func (m *MockPeerRepository) FindByFilter(ctx context.Context, streamID domain.StreamID, filter domain.PeerFilter) ([]*domain.Peer, error) {
+	args := m.Called(ctx, streamID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Peer), args.Error(1)
+}
+
 func (m *MockPeerRepository) FindOptimalSource(ctx context.Context, streamID domain.StreamID, excludePeers []domain.PeerID) (*domain.Peer, error) {
 	args := m.Called(ctx, streamID, excludePeers)
 	if args.Get(0) == nil {
@@ -126,6 +139,19 @@ func (m *MockMeshService) GetOptimalPath(ctx context.Context, sourcePeer, target
 	return args.Get(0).([]domain.PeerID), args.Error(1)
 }
 
+func (m *MockMeshService) SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error) {
+	args := m.Called(ctx, streamID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TopologySnapshot), args.Error(1)
+}
+
+func (m *MockMeshService) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	args := m.Called(ctx, sourcePeer, targetPeer, connected)
+	return args.Error(0)
+}
+
 // MockAuthService for tests
 type MockAuthService struct {
 	mock.Mock
@@ -157,6 +183,14 @@ func (m *MockAuthService) ValidateRefreshToken(tokenString string) (*services.Cl
 	return args.Get(0).(*services.Claims), args.Error(1)
 }
 
+func (m *MockAuthService) ValidateAPIKey(key string) (*services.Claims, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.Claims), args.Error(1)
+}
+
 func (m *MockAuthService) RegisterUser(ctx context.Context, username, email, password string) (*domain.User, string, string, error) {
 	args := m.Called(ctx, username, email, password)
 	var u *domain.User
@@ -180,6 +214,11 @@ func (m *MockAuthService) RotateRefreshToken(ctx context.Context, refreshToken s
 	return args.String(0), args.String(1), args.Error(2)
 }
 
+func (m *MockAuthService) RefreshTokens(ctx context.Context, refreshToken string) (string, string, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
 func (m *MockAuthService) Logout(ctx context.Context, refreshToken string) error {
 	args := m.Called(ctx, refreshToken)
 	return args.Error(0)
@@ -209,11 +248,13 @@ func createTestAuthService() *MockAuthService {
 	// Default behavior: generate tokens successfully
 	mockAuth.On("GenerateToken", mock.AnythingOfType("domain.UserID"), mock.AnythingOfType("string")).Return("test-token-123", nil)
 
+	// Default behavior: every user may view every stream
+	mockAuth.On("CheckStreamPermission", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
 	return mockAuth
 }
 
 func TestWebSocketServer_HandleJoinStream(t *testing.T) {
-	ctx := context.Background()
 	peerID := domain.PeerID("test-peer")
 	streamID := domain.StreamID("test-stream")
 
@@ -224,8 +265,8 @@ func TestWebSocketServer_HandleJoinStream(t *testing.T) {
 		server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
 
 		// Expectations
-		mockMeshService.On("AddPeer", ctx, mock.AnythingOfType("*domain.Peer")).Return(nil)
-		mockMeshService.On("FindOptimalSources", ctx, streamID, peerID, 4).Return([]*domain.Peer{}, nil)
+		mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+		mockMeshService.On("FindOptimalSources", mock.Anything, streamID, peerID, 4).Return([]*domain.Peer{}, nil)
 		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
 
 		// Create test server
@@ -301,8 +342,8 @@ func TestWebSocketServer_HandleJoinStream(t *testing.T) {
 		}
 
 		// Expectations
-		mockMeshService.On("AddPeer", ctx, mock.AnythingOfType("*domain.Peer")).Return(nil)
-		mockMeshService.On("FindOptimalSources", ctx, streamID, peerID, 4).Return(sources, nil)
+		mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+		mockMeshService.On("FindOptimalSources", mock.Anything, streamID, peerID, 4).Return(sources, nil)
 		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
 
 		// Create test server
@@ -350,8 +391,245 @@ func TestWebSocketServer_HandleJoinStream(t *testing.T) {
 	})
 }
 
-func TestWebSocketServer_HandleMetricsUpdate(t *testing.T) {
+func TestWebSocketServer_HandleJoinStream_StreamState(t *testing.T) {
+	ctx := context.Background()
+	streamID := domain.StreamID("state-stream")
+
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	streamRepo := memory.NewMemoryStreamRepository()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+	server.SetStreamRepository(streamRepo)
+
+	stream := &domain.Stream{ID: streamID, Name: "state", Owner: "owner", Active: true, CreatedAt: time.Now()}
+	assert.NoError(t, streamRepo.Create(ctx, stream))
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, streamID, mock.Anything, 4).Return([]*domain.Peer{}, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+	mockPeerRepo.On("FindByStream", mock.Anything, streamID).Return([]*domain.Peer{}, nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	join := func(peerID domain.PeerID) map[string]interface{} {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		err = conn.WriteJSON(signal.SignalMessage{
+			Type:    "join_stream",
+			Payload: json.RawMessage(`{"stream_id": "state-stream", "is_publisher": false}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		return response
+	}
+
+	t.Run("before publisher joins", func(t *testing.T) {
+		response := join("early-subscriber")
+		assert.Equal(t, "peers_list", response["type"])
+		assert.Equal(t, string(domain.StreamStateNotStarted), response["stream_state"])
+	})
+
+	t.Run("after stream ended", func(t *testing.T) {
+		stream.Active = false
+		assert.NoError(t, streamRepo.Update(ctx, stream))
+
+		response := join("late-subscriber")
+		assert.Equal(t, "peers_list", response["type"])
+		assert.Equal(t, string(domain.StreamStateEnded), response["stream_state"])
+	})
+
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_HandleJoinStream_SourceRoles(t *testing.T) {
+	streamID := domain.StreamID("roles-stream")
+	peerID := domain.PeerID("roles-subscriber")
+
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	sources := []*domain.Peer{
+		{
+			ID:           "origin",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{IsPublisher: true, CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 2500},
+		},
+		{
+			ID:           "relay-1",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 1200},
+		},
+		{
+			ID:           "relay-2",
+			StreamID:     streamID,
+			Capabilities: domain.PeerCapabilities{CanRelay: true},
+			Metrics:      domain.PeerMetrics{Bandwidth: 800},
+		},
+	}
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, streamID, peerID, 4).Return(sources, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.WriteJSON(signal.SignalMessage{
+		Type:    "join_stream",
+		Payload: json.RawMessage(`{"stream_id": "roles-stream", "is_publisher": false}`),
+	})
+	assert.NoError(t, err)
+
+	var response struct {
+		Type  string `json:"type"`
+		Peers []struct {
+			PeerID  string `json:"peer_id"`
+			Role    string `json:"role"`
+			Bitrate int    `json:"bitrate"`
+		} `json:"peers"`
+	}
+	assert.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, "peers_list", response.Type)
+
+	roles := make(map[string]string)
+	bitrates := make(map[string]int)
+	for _, entry := range response.Peers {
+		roles[entry.PeerID] = entry.Role
+		bitrates[entry.PeerID] = entry.Bitrate
+	}
+	assert.Equal(t, map[string]string{"origin": "publisher", "relay-1": "relay", "relay-2": "relay"}, roles)
+	assert.Equal(t, map[string]int{"origin": 2500, "relay-1": 1200, "relay-2": 800}, bitrates)
+
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_HandleLeaveStream(t *testing.T) {
 	ctx := context.Background()
+	streamID := domain.StreamID("leave-stream")
+
+	setup := func(t *testing.T) (*MockMeshService, func(domain.PeerID) *websocket.Conn) {
+		peerRepo := memory.NewMemoryPeerRepository()
+		mockMeshService := new(MockMeshService)
+		mockAuthService := createTestAuthService()
+		server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+		assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "leaver", StreamID: streamID}))
+		assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "stayer", StreamID: streamID}))
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.HandleWebSocket(w, r)
+		}))
+		t.Cleanup(testServer.Close)
+
+		dial := func(peerID domain.PeerID) *websocket.Conn {
+			token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+			wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+			conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			assert.NoError(t, err)
+			t.Cleanup(func() { _ = conn.Close() })
+			return conn
+		}
+		return mockMeshService, dial
+	}
+
+	t.Run("successful leave stream", func(t *testing.T) {
+		mockMeshService, dial := setup(t)
+		mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+		leaver := dial("leaver")
+		stayer := dial("stayer")
+
+		err := leaver.WriteJSON(signal.SignalMessage{
+			Type:    "leave_stream",
+			Payload: json.RawMessage(`{"stream_id": "leave-stream"}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, leaver.ReadJSON(&response))
+		assert.Equal(t, "left", response["type"])
+		assert.Equal(t, string(streamID), response["stream_id"])
+
+		_ = stayer.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var notification map[string]interface{}
+		assert.NoError(t, stayer.ReadJSON(&notification))
+		assert.Equal(t, "peer_left", notification["type"])
+		assert.Equal(t, "leaver", notification["peer_id"])
+		assert.Equal(t, string(streamID), notification["stream_id"])
+
+		mockMeshService.AssertCalled(t, "RemovePeer", mock.Anything, domain.PeerID("leaver"))
+
+		// The connection stays open
+		assert.NoError(t, leaver.WriteJSON(signal.SignalMessage{Type: "ping"}))
+		var pong map[string]interface{}
+		assert.NoError(t, leaver.ReadJSON(&pong))
+		assert.Equal(t, "pong", pong["type"])
+	})
+
+	t.Run("leave stream the peer is not in", func(t *testing.T) {
+		mockMeshService, dial := setup(t)
+		mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+		leaver := dial("leaver")
+		err := leaver.WriteJSON(signal.SignalMessage{
+			Type:    "leave_stream",
+			Payload: json.RawMessage(`{"stream_id": "other-stream"}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, leaver.ReadJSON(&response))
+		assert.Equal(t, "error", response["type"])
+		assert.Contains(t, response["message"], "not in stream")
+		mockMeshService.AssertNotCalled(t, "RemovePeer", mock.Anything, domain.PeerID("leaver"))
+	})
+
+	t.Run("missing stream_id", func(t *testing.T) {
+		mockMeshService, dial := setup(t)
+		mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+		leaver := dial("leaver")
+		err := leaver.WriteJSON(signal.SignalMessage{
+			Type:    "leave_stream",
+			Payload: json.RawMessage(`{}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, leaver.ReadJSON(&response))
+		assert.Equal(t, "error", response["type"])
+		assert.Contains(t, response["message"], "stream_id is required")
+	})
+
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_HandleMetricsUpdate(t *testing.T) {
 	peerID := domain.PeerID("test-peer")
 
 	t.Run("successful metrics update", func(t *testing.T) {
@@ -361,7 +639,7 @@ func TestWebSocketServer_HandleMetricsUpdate(t *testing.T) {
 		server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
 
 		// Expectations
-		mockMeshService.On("UpdatePeerMetrics", ctx, peerID, mock.AnythingOfType("domain.NetworkMetrics")).Return(nil)
+		mockMeshService.On("UpdatePeerMetrics", mock.Anything, peerID, mock.AnythingOfType("domain.NetworkMetrics")).Return(nil)
 		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
 
 		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -442,7 +720,49 @@ func TestWebSocketServer_HandleMetricsUpdate(t *testing.T) {
 		assert.Equal(t, "error", response["type"])
 
 		// UpdatePeerMetrics should not be called with invalid payload
-		mockMeshService.AssertNotCalled(t, "UpdatePeerMetrics", ctx, peerID, mock.Anything)
+		mockMeshService.AssertNotCalled(t, "UpdatePeerMetrics", mock.Anything, peerID, mock.Anything)
+
+		_ = conn.Close()
+		time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+	})
+
+	t.Run("metrics update with out of range values", func(t *testing.T) {
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		mockAuthService := createTestAuthService()
+		server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+		// Expectations for disconnection
+		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.HandleWebSocket(w, r)
+		}))
+		defer testServer.Close()
+
+		token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		for _, payload := range []string{
+			`{"bandwidth": 1500, "packet_loss": 5.0, "latency": 50}`,
+			`{"bandwidth": 1500, "packet_loss": 0.02, "latency": -10}`,
+		} {
+			err = conn.WriteJSON(signal.SignalMessage{Type: "metrics_update", Payload: json.RawMessage(payload)})
+			assert.NoError(t, err)
+
+			var response map[string]interface{}
+			err = conn.ReadJSON(&response)
+			assert.NoError(t, err)
+			assert.Equal(t, "error", response["type"])
+			assert.Equal(t, string(signal.ErrCodeInvalidPayload), response["code"])
+		}
+
+		mockMeshService.AssertNotCalled(t, "UpdatePeerMetrics", mock.Anything, peerID, mock.Anything)
 
 		_ = conn.Close()
 		time.Sleep(50 * time.Millisecond) // allow server cleanup to run
@@ -756,5 +1076,1352 @@ func TestWebSocketServer_ErrorHandling(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "error", response["type"])
 		assert.Contains(t, response["message"], "unknown message type")
+		assert.Equal(t, string(signal.ErrCodeUnknownMessageType), response["code"])
+	})
+}
+
+func TestWebSocketServer_EmptyPayload(t *testing.T) {
+	peerID := domain.PeerID("test-peer")
+
+	newConn := func(t *testing.T) *websocket.Conn {
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		mockAuthService := createTestAuthService()
+		server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.HandleWebSocket(w, r)
+		}))
+		t.Cleanup(testServer.Close)
+
+		token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	t.Run("join_stream without payload reports missing payload", func(t *testing.T) {
+		conn := newConn(t)
+
+		err := conn.WriteJSON(signal.SignalMessage{Type: "join_stream"})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		err = conn.ReadJSON(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, "error", response["type"])
+		assert.Contains(t, response["message"], signal.ErrMissingPayload.Error())
+		assert.NotContains(t, response["message"], "unexpected end of JSON input")
+	})
+
+	t.Run("ping without payload is answered", func(t *testing.T) {
+		conn := newConn(t)
+
+		err := conn.WriteJSON(signal.SignalMessage{Type: "ping"})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		err = conn.ReadJSON(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", response["type"])
+	})
+}
+
+func TestWebSocketServer_PingEchoesNonceAndMeasuresRTT(t *testing.T) {
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	peerID := domain.PeerID("timed-peer")
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	sentAt := time.Now().UnixMilli()
+	payload, _ := json.Marshal(signal.PingPayload{Timestamp: sentAt, Nonce: "n-42"})
+	assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "ping", Payload: payload}))
+
+	var pong struct {
+		Type       string `json:"type"`
+		Nonce      string `json:"nonce"`
+		Timestamp  int64  `json:"timestamp"`
+		ServerTime int64  `json:"server_time"`
+	}
+	assert.NoError(t, conn.ReadJSON(&pong))
+	assert.Equal(t, "pong", pong.Type)
+	assert.Equal(t, "n-42", pong.Nonce)
+	assert.Equal(t, sentAt, pong.Timestamp)
+	assert.NotZero(t, pong.ServerTime, "pong carries the server's send time")
+
+	// A made-up stamp is ignored
+	payload, _ = json.Marshal(signal.PongPayload{ServerTime: time.Now().UnixNano()})
+	assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "pong", Payload: payload}))
+	time.Sleep(20 * time.Millisecond)
+	_, measured := server.GetPeerLatency(peerID)
+	assert.False(t, measured)
+
+	// Echoing the server's stamp lets the server time the round trip itself
+	payload, _ = json.Marshal(signal.PongPayload{ServerTime: pong.ServerTime})
+	assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "pong", Payload: payload}))
+	assert.Eventually(t, func() bool {
+		rtt, measured := server.GetPeerLatency(peerID)
+		return measured && rtt > 0 && rtt < time.Second
+	}, time.Second, 10*time.Millisecond)
+
+	// The measurement never touches the peer's media metrics
+	mockMeshService.AssertNotCalled(t, "UpdatePeerMetrics", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebSocketServer_SignalingAuthorization(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	peers := []*domain.Peer{
+		{ID: "publisher-a", StreamID: "stream-a", Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+		{ID: "viewer-a", StreamID: "stream-a"},
+		{ID: "publisher-b", StreamID: "stream-b", Capabilities: domain.PeerCapabilities{IsPublisher: true}},
+	}
+	for _, peer := range peers {
+		assert.NoError(t, peerRepo.Add(ctx, peer))
+	}
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	dial := func(peerID domain.PeerID) *websocket.Conn {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		return conn
+	}
+
+	publisherA := dial("publisher-a")
+	defer publisherA.Close()
+	viewerA := dial("viewer-a")
+	defer viewerA.Close()
+	publisherB := dial("publisher-b")
+	defer publisherB.Close()
+
+	sdp := `"v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"`
+
+	t.Run("cross-stream offer is rejected", func(t *testing.T) {
+		err := viewerA.WriteJSON(signal.SignalMessage{
+			Type:    "offer",
+			Payload: json.RawMessage(`{"sdp": ` + sdp + `, "target_peer": "publisher-b"}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, viewerA.ReadJSON(&response))
+		assert.Equal(t, "error", response["type"])
+		assert.Contains(t, response["message"], signal.ErrSignalingNotAllowed.Error())
+	})
+
+	t.Run("same-stream offer is forwarded", func(t *testing.T) {
+		err := viewerA.WriteJSON(signal.SignalMessage{
+			Type:    "offer",
+			Payload: json.RawMessage(`{"sdp": ` + sdp + `, "target_peer": "publisher-a"}`),
+		})
+		assert.NoError(t, err)
+
+		_ = publisherA.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var forwarded map[string]interface{}
+		assert.NoError(t, publisherA.ReadJSON(&forwarded))
+		assert.Equal(t, "offer", forwarded["type"])
+		assert.Equal(t, "viewer-a", forwarded["from_peer"])
+	})
+
+	t.Run("answer into another stream is rejected", func(t *testing.T) {
+		err := publisherB.WriteJSON(signal.SignalMessage{
+			Type:    "answer",
+			Payload: json.RawMessage(`{"sdp": ` + sdp + `, "target_peer": "viewer-a"}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, publisherB.ReadJSON(&response))
+		assert.Equal(t, "error", response["type"])
+		assert.Contains(t, response["message"], signal.ErrSignalingNotAllowed.Error())
+	})
+}
+
+func TestWebSocketServer_SignalingRequiresStreamPermission(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := new(MockAuthService)
+	mockAuthService.On("ValidateToken", mock.AnythingOfType("string")).Return(&services.Claims{
+		UserID:   domain.UserID("revoked-user"),
+		Username: "revoked",
+	}, nil)
+	mockAuthService.On("CheckStreamPermission", mock.Anything, domain.UserID("revoked-user"), domain.StreamID("stream-a"), domain.RoleViewer).
+		Return(services.ErrUnauthorized)
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "publisher-a", StreamID: "stream-a", Capabilities: domain.PeerCapabilities{IsPublisher: true}}))
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "viewer-a", StreamID: "stream-a"}))
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	dial := func(peerID domain.PeerID) *websocket.Conn {
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=token-" + string(peerID)
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		return conn
+	}
+
+	publisherA := dial("publisher-a")
+	defer publisherA.Close()
+	viewerA := dial("viewer-a")
+	defer viewerA.Close()
+
+	sdp := `"v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\na=recvonly\r\n"`
+	err := viewerA.WriteJSON(signal.SignalMessage{
+		Type:    "offer",
+		Payload: json.RawMessage(`{"sdp": ` + sdp + `, "target_peer": "publisher-a"}`),
 	})
+	assert.NoError(t, err)
+
+	var response map[string]interface{}
+	assert.NoError(t, viewerA.ReadJSON(&response))
+	assert.Equal(t, "error", response["type"])
+	assert.Contains(t, response["message"], signal.ErrSignalingNotAllowed.Error())
+	mockAuthService.AssertCalled(t, "CheckStreamPermission", mock.Anything, domain.UserID("revoked-user"), domain.StreamID("stream-a"), domain.RoleViewer)
+}
+
+func TestWebSocketServer_SignalingAuthorizedAsConnectionUser(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := new(MockAuthService)
+	mockAuthService.On("ValidateToken", mock.AnythingOfType("string")).Return(&services.Claims{
+		UserID:   domain.UserID("mallory"),
+		Username: "mallory",
+	}, nil)
+	mockAuthService.On("CheckStreamPermission", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	// alice's peer is in the mesh but her socket has gone away
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "alice-viewer", StreamID: "stream-a", UserID: "alice"}))
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "publisher-a", StreamID: "stream-a", Capabilities: domain.PeerCapabilities{IsPublisher: true}}))
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=alice-viewer&token=mallory-token"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	sdp := `"v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\na=recvonly\r\n"`
+	assert.NoError(t, conn.WriteJSON(signal.SignalMessage{
+		Type:    "offer",
+		Payload: json.RawMessage(`{"sdp": ` + sdp + `, "target_peer": "publisher-a"}`),
+	}))
+
+	var response map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, "error", response["type"])
+	assert.Contains(t, response["message"], signal.ErrSignalingNotAllowed.Error())
+	mockAuthService.AssertNotCalled(t, "CheckStreamPermission", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebSocketServer_JoinGoesThroughStreamAdmission(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, mock.Anything, mock.Anything, 4).Return([]*domain.Peer{}, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	// Default quality levels top out at 2500 kbps, so the budget fits one viewer
+	streamService := services.NewStreamServiceWithConfig(
+		memory.NewMemoryStreamRepository(),
+		peerRepo,
+		memory.NewMemoryMeshRepository(),
+		mockMeshService,
+		services.NewMetricsService(),
+		config.StreamConfig{MaxTotalBitrate: 2500},
+		nil,
+	)
+	server.SetStreamService(streamService)
+	stream, err := streamService.CreateStream(ctx, "budgeted", "owner", 10)
+	assert.NoError(t, err)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	join := func(peerID domain.PeerID) map[string]interface{} {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		assert.NoError(t, conn.WriteJSON(signal.SignalMessage{
+			Type:    "join_stream",
+			Payload: json.RawMessage(`{"stream_id": "` + string(stream.ID) + `"}`),
+		}))
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		return response
+	}
+
+	assert.Equal(t, "peers_list", join("viewer-1")["type"])
+
+	response := join("viewer-2")
+	assert.Equal(t, "error", response["type"])
+	assert.Contains(t, response["message"], domain.ErrStreamBandwidthExceeded.Error())
+}
+
+func TestWebSocketServer_RejectsMismatchedMediaDirection(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "publisher-a", StreamID: "stream-a", Capabilities: domain.PeerCapabilities{IsPublisher: true}}))
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "viewer-a", StreamID: "stream-a"}))
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "relay-a", StreamID: "stream-a", Capabilities: domain.PeerCapabilities{CanRelay: true}}))
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	dial := func(peerID domain.PeerID) *websocket.Conn {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		return conn
+	}
+
+	publisherA := dial("publisher-a")
+	defer publisherA.Close()
+	viewerA := dial("viewer-a")
+	defer viewerA.Close()
+	relayA := dial("relay-a")
+	defer relayA.Close()
+
+	sdpWith := func(direction string) string {
+		return `"v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\na=` + direction + `\r\n"`
+	}
+
+	t.Run("subscriber offering sendonly is rejected", func(t *testing.T) {
+		err := viewerA.WriteJSON(signal.SignalMessage{
+			Type:    "offer",
+			Payload: json.RawMessage(`{"sdp": ` + sdpWith("sendonly") + `, "target_peer": "publisher-a"}`),
+		})
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		assert.NoError(t, viewerA.ReadJSON(&response))
+		assert.Equal(t, "error", response["type"])
+		assert.Contains(t, response["message"], sdputil.ErrMediaDirectionMismatch.Error())
+		assert.Equal(t, string(signal.ErrCodeInvalidSDP), response["code"])
+	})
+
+	t.Run("subscriber offering recvonly is forwarded", func(t *testing.T) {
+		err := viewerA.WriteJSON(signal.SignalMessage{
+			Type:    "offer",
+			Payload: json.RawMessage(`{"sdp": ` + sdpWith("recvonly") + `, "target_peer": "publisher-a"}`),
+		})
+		assert.NoError(t, err)
+
+		_ = publisherA.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var forwarded map[string]interface{}
+		assert.NoError(t, publisherA.ReadJSON(&forwarded))
+		assert.Equal(t, "offer", forwarded["type"])
+	})
+
+	t.Run("relay offering sendrecv is forwarded", func(t *testing.T) {
+		err := relayA.WriteJSON(signal.SignalMessage{
+			Type:    "offer",
+			Payload: json.RawMessage(`{"sdp": ` + sdpWith("sendrecv") + `, "target_peer": "publisher-a"}`),
+		})
+		assert.NoError(t, err)
+
+		_ = publisherA.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var forwarded map[string]interface{}
+		assert.NoError(t, publisherA.ReadJSON(&forwarded))
+		assert.Equal(t, "offer", forwarded["type"])
+		assert.Equal(t, "relay-a", forwarded["from_peer"])
+	})
+}
+
+func TestWebSocketServer_InvalidSDPErrorCode(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	peerID := domain.PeerID("sdp-peer")
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.WriteJSON(signal.SignalMessage{
+		Type:    "offer",
+		Payload: json.RawMessage(`{"sdp": "not an sdp", "target_peer": "someone"}`),
+	})
+	assert.NoError(t, err)
+
+	var response map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, "error", response["type"])
+	assert.Equal(t, string(signal.ErrCodeInvalidSDP), response["code"])
+	assert.Contains(t, response["message"], "invalid SDP in offer")
+
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_ClosesPingOnlyConnectionAfterIdleTimeout(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	const idleTimeout = 300 * time.Millisecond
+	server.SetPingInterval(20 * time.Millisecond)
+	server.SetIdleTimeout(idleTimeout)
+
+	peerID := domain.PeerID("idle-peer")
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	connected := time.Now()
+
+	pings := 0
+	conn.SetPingHandler(func(data string) error {
+		pings++
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	// Reading answers pings but never sends an application message
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+
+	var closeErr *websocket.CloseError
+	if assert.ErrorAs(t, err, &closeErr) {
+		assert.Equal(t, websocket.CloseNormalClosure, closeErr.Code)
+		assert.Equal(t, "idle timeout", closeErr.Text)
+	}
+	assert.GreaterOrEqual(t, time.Since(connected), idleTimeout)
+	assert.Greater(t, pings, 1, "pongs must not keep an idle connection open")
+	assert.Eventually(t, func() bool { return !server.IsPeerConnected(peerID) }, time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketServer_DropsICECandidatesBeyondLimit(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	const limit = 3
+	server.SetMaxICECandidates(limit)
+
+	peerID := domain.PeerID("flooding-peer")
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	iceMsg := signal.SignalMessage{
+		Type:    "ice_candidate",
+		Payload: json.RawMessage(`{"candidate": "candidate:1 1 UDP 123456 192.168.1.100 8080 typ host"}`),
+	}
+	for i := 0; i < limit+2; i++ {
+		assert.NoError(t, conn.WriteJSON(iceMsg))
+	}
+
+	// Candidates within the limit are processed (and fail for lack of a
+	// target); the excess ones are dropped silently and counted
+	assert.Eventually(t, func() bool { return server.DroppedICECandidates() == 2 }, time.Second, 10*time.Millisecond)
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	errorReplies := 0
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			break
+		}
+		if msg["type"] == "error" {
+			errorReplies++
+		}
+	}
+	assert.Equal(t, limit, errorReplies)
+}
+
+func TestWebSocketServer_DropsSlowConsumerAtBacklog(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	const backlog = 4
+	server.SetMaxOutboundBacklog(backlog)
+
+	peerID := domain.PeerID("slow-peer")
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return server.IsPeerConnected(peerID) }, time.Second, 10*time.Millisecond)
+
+	// The client never reads, so once the socket buffers fill the writer stalls
+	// and the queue grows until the backlog is exceeded.
+	candidate := &webrtc.ICECandidateInit{Candidate: "candidate:" + strings.Repeat("x", 64*1024)}
+	sent := 0
+	for ; sent < 10000; sent++ {
+		if err = server.SendICECandidate(peerID, candidate); err != nil {
+			break
+		}
+	}
+	assert.ErrorIs(t, err, signal.ErrSlowConsumer)
+	assert.GreaterOrEqual(t, sent, backlog)
+	assert.Equal(t, int64(1), server.SlowConsumerDisconnects())
+
+	// Further sends fail without counting the peer twice
+	assert.Error(t, server.SendICECandidate(peerID, candidate))
+	assert.Equal(t, int64(1), server.SlowConsumerDisconnects())
+
+	assert.Eventually(t, func() bool { return !server.IsPeerConnected(peerID) }, 15*time.Second, 20*time.Millisecond)
+}
+
+func TestWebSocketServer_NotifyPeerLeft(t *testing.T) {
+	ctx := context.Background()
+	peerRepo := memory.NewMemoryPeerRepository()
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "sfu-dropped", StreamID: "notify-stream"}))
+	assert.NoError(t, peerRepo.Add(ctx, &domain.Peer{ID: "remaining", StreamID: "notify-stream"}))
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("user-remaining"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=remaining&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return server.IsPeerConnected("remaining") }, 2*time.Second, 10*time.Millisecond)
+	assert.NoError(t, server.NotifyPeerLeft(ctx, "notify-stream", "sfu-dropped"))
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var notification map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&notification))
+	assert.Equal(t, "peer_left", notification["type"])
+	assert.Equal(t, "sfu-dropped", notification["peer_id"])
+	assert.Equal(t, "notify-stream", notification["stream_id"])
+
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_NotifyLocalPeerLeft(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, mock.Anything, mock.Anything, 4).Return([]*domain.Peer{}, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("user-local-member"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=local-member&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "join_stream", Payload: json.RawMessage(`{"stream_id": "local-left-stream"}`)}))
+	var response map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, "peers_list", response["type"])
+
+	// Only the member list is used, so nothing is looked up or relayed
+	server.NotifyLocalPeerLeft("local-left-stream", "sfu-dropped")
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var notification map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&notification))
+	assert.Equal(t, "peer_left", notification["type"])
+	assert.Equal(t, "sfu-dropped", notification["peer_id"])
+	assert.Equal(t, "local-left-stream", notification["stream_id"])
+	mockPeerRepo.AssertNotCalled(t, "FindByStream", mock.Anything, mock.Anything)
+
+	server.NotifyLocalStreamStopped("local-left-stream")
+	var stopped map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&stopped))
+	assert.Equal(t, "stream_stopped", stopped["type"])
+	assert.Equal(t, "local-left-stream", stopped["stream_id"])
+
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_PresenceEvents(t *testing.T) {
+	streamID := domain.StreamID("presence-stream")
+
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, streamID, mock.Anything, 4).Return([]*domain.Peer{}, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	join := func(peerID domain.PeerID, payload string) *websocket.Conn {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "join_stream", Payload: json.RawMessage(payload)}))
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		assert.Equal(t, "peers_list", response["type"])
+		return conn
+	}
+
+	first := join("presence-first", `{"stream_id": "presence-stream"}`)
+	defer first.Close()
+	second := join("presence-second", `{"stream_id": "presence-stream", "is_publisher": true, "capabilities": {"max_bitrate": 2500, "codecs": ["VP8"]}}`)
+
+	_ = first.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var joined struct {
+		Type         string `json:"type"`
+		PeerID       string `json:"peer_id"`
+		StreamID     string `json:"stream_id"`
+		Address      string `json:"address"`
+		Role         string `json:"role"`
+		Capabilities struct {
+			MaxBitrate  int      `json:"max_bitrate"`
+			Codecs      []string `json:"codecs"`
+			IsPublisher bool     `json:"is_publisher"`
+		} `json:"capabilities"`
+	}
+	assert.NoError(t, first.ReadJSON(&joined))
+	assert.Equal(t, "peer_joined", joined.Type)
+	assert.Equal(t, "presence-second", joined.PeerID)
+	assert.Equal(t, string(streamID), joined.StreamID)
+	assert.NotEmpty(t, joined.Address)
+	assert.Equal(t, "publisher", joined.Role)
+	assert.Equal(t, 2500, joined.Capabilities.MaxBitrate)
+	assert.Equal(t, []string{"VP8"}, joined.Capabilities.Codecs)
+	assert.True(t, joined.Capabilities.IsPublisher)
+
+	// Disconnecting tells the remaining peer
+	_ = second.Close()
+	var left map[string]interface{}
+	assert.NoError(t, first.ReadJSON(&left))
+	assert.Equal(t, "peer_left", left["type"])
+	assert.Equal(t, "presence-second", left["peer_id"])
+	assert.Equal(t, string(streamID), left["stream_id"])
+
+	_ = first.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_BroadcastToStreamIsScopedToStream(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, mock.Anything, mock.Anything, 4).Return([]*domain.Peer{}, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	join := func(peerID domain.PeerID, streamID domain.StreamID) *websocket.Conn {
+		token, _ := mockAuthService.GenerateToken(domain.UserID("user-"+string(peerID)), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+
+		payload := json.RawMessage(`{"stream_id": "` + string(streamID) + `"}`)
+		assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "join_stream", Payload: payload}))
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		assert.Equal(t, "peers_list", response["type"])
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		return conn
+	}
+	// nextOfType skips presence events until a message of the given type
+	nextOfType := func(conn *websocket.Conn, msgType string) map[string]interface{} {
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("waiting for %s: %v", msgType, err)
+			}
+			if msg["type"] == msgType {
+				return msg
+			}
+		}
+	}
+
+	a1 := join("stream-a-first", "stream-a")
+	defer a1.Close()
+	b1 := join("stream-b-first", "stream-b")
+	defer b1.Close()
+	a2 := join("stream-a-second", "stream-a")
+	defer a2.Close()
+
+	assert.NoError(t, server.BroadcastToStream("stream-a", map[string]interface{}{"type": "notice", "stream_id": "stream-a"}))
+	assert.NoError(t, server.BroadcastToStream("stream-b", map[string]interface{}{"type": "notice", "stream_id": "stream-b"}))
+
+	assert.Equal(t, "stream-a", nextOfType(a1, "notice")["stream_id"])
+	assert.Equal(t, "stream-a", nextOfType(a2, "notice")["stream_id"])
+	// Stream B's member sees only its own stream's broadcast
+	assert.Equal(t, "stream-b", nextOfType(b1, "notice")["stream_id"])
+
+	_ = a1.Close()
+	_ = a2.Close()
+	_ = b1.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+// recordingInterestSetter records the interest set per subscriber
+type recordingInterestSetter struct {
+	calls chan []string
+}
+
+func (r *recordingInterestSetter) SetSubscriberInterest(ctx context.Context, peerID domain.PeerID, activeTracks []string) error {
+	r.calls <- activeTracks
+	return nil
+}
+
+func TestWebSocketServer_HandleSetInterest(t *testing.T) {
+	peerID := domain.PeerID("grid-viewer")
+	setInterest := signal.SignalMessage{
+		Type:    "set_interest",
+		Payload: json.RawMessage(`{"active_tracks": ["tile-a"]}`),
+	}
+
+	// sendSetInterest sends set_interest to a new server and returns the reply
+	sendSetInterest := func(setter *recordingInterestSetter) map[string]interface{} {
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		mockAuthService := createTestAuthService()
+		server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+		if setter != nil {
+			server.SetInterestSetter(setter)
+		}
+		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			server.HandleWebSocket(w, r)
+		}))
+		defer testServer.Close()
+
+		token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		assert.NoError(t, err)
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+		assert.NoError(t, conn.WriteJSON(setInterest))
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+
+		_ = conn.Close()
+		time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+		return response
+	}
+
+	// Without an SFU the message is rejected
+	assert.Equal(t, "error", sendSetInterest(nil)["type"])
+
+	setter := &recordingInterestSetter{calls: make(chan []string, 1)}
+	response := sendSetInterest(setter)
+	assert.Equal(t, "interest_set", response["type"])
+	assert.Equal(t, []interface{}{"tile-a"}, response["active_tracks"])
+	assert.Equal(t, []string{"tile-a"}, <-setter.calls)
+}
+
+func TestWebSocketServer_MeasuresPingLatency(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+	server.SetPingInterval(20 * time.Millisecond)
+
+	peerID := domain.PeerID("latency-peer")
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// The client answers pings (echoing their payload) only while reading
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	_, measured := server.GetPeerLatency("unknown-peer")
+	assert.False(t, measured)
+
+	assert.Eventually(t, func() bool {
+		_, measured := server.GetPeerLatency(peerID)
+		return measured
+	}, 2*time.Second, 10*time.Millisecond)
+	latency, _ := server.GetPeerLatency(peerID)
+	assert.Greater(t, latency, time.Duration(0))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	server.HealthCheck(w, req)
+	var health map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, float64(1), health["latency_samples"])
+	assert.Greater(t, health["avg_latency_ms"], float64(0))
+
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_AuthenticatesUpgrade(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := new(MockAuthService)
+	for _, user := range []string{"alice", "bob"} {
+		mockAuthService.On("ValidateToken", user+"-token").Return(&services.Claims{
+			UserID:   domain.UserID(user),
+			Username: user,
+		}, nil)
+	}
+	mockAuthService.On("ValidateToken", mock.AnythingOfType("string")).Return(nil, services.ErrInvalidToken)
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"https://app.example"})
+	server.SetConnectionRateLimit(600) // more dials than the default burst
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	allowedOrigin := http.Header{"Origin": []string{"https://app.example"}}
+	wsURL := func(peerID, token string) string {
+		return "ws" + testServer.URL[4:] + "/ws?peer_id=" + peerID + "&token=" + token
+	}
+	dialStatus := func(url string, header http.Header) int {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if err == nil {
+			_ = conn.Close()
+		}
+		if resp == nil {
+			return 0
+		}
+		return resp.StatusCode
+	}
+	aliceToken, bobToken := "alice-token", "bob-token"
+
+	t.Run("missing token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, dialStatus(wsURL("auth-peer", ""), allowedOrigin))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, dialStatus(wsURL("auth-peer", "not-a-jwt"), allowedOrigin))
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		header := http.Header{"Origin": []string{"https://evil.example"}}
+		assert.Equal(t, http.StatusForbidden, dialStatus(wsURL("auth-peer", aliceToken), header))
+	})
+
+	t.Run("peer is bound to its user", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL("owned-peer", aliceToken), allowedOrigin)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		assert.Eventually(t, func() bool { return server.IsPeerConnected("owned-peer") }, time.Second, 10*time.Millisecond)
+		userID, ok := server.PeerUserID("owned-peer")
+		assert.True(t, ok)
+		assert.Equal(t, domain.UserID("alice"), userID)
+
+		// Another user cannot take the peer ID over
+		assert.Equal(t, http.StatusForbidden, dialStatus(wsURL("owned-peer", bobToken), allowedOrigin))
+
+		// The same user may reconnect
+		reconnected, _, err := websocket.DefaultDialer.Dial(wsURL("owned-peer", aliceToken), allowedOrigin)
+		assert.NoError(t, err)
+		_ = reconnected.Close()
+	})
+
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_NegotiatesCompression(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+	server.SetCompression(true, 6)
+
+	peerID := domain.PeerID("compressed-peer")
+	mockPeerRepo.On("GetByID", mock.Anything, mock.Anything).Return(nil, domain.ErrPeerNotFound)
+	mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=" + token
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial(wsURL, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	// A large SDP round-trips over the compressed connection
+	sdp := "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n" + strings.Repeat("a=candidate:1 1 UDP 2130706431 192.0.2.1 5000 typ host\r\n", 500)
+	payload, _ := json.Marshal(map[string]string{"sdp": sdp, "target_peer": "other-peer"})
+	assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "offer", Payload: payload}))
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var response map[string]interface{}
+	assert.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, "error", response["type"])
+
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+}
+
+func TestWebSocketServer_OverloadCloseAdvertisesJitteredReconnect(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+	server.SetConnectionRateLimit(6000)
+	server.SetMaxConcurrentConnections(1)
+
+	const base, max = 2 * time.Second, 20 * time.Second
+	server.SetReconnectBackoff(base, max)
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	dial := func(peerID string) *websocket.Conn {
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + peerID + "&token=" + token
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return conn
+	}
+
+	holder := dial("holder")
+	defer holder.Close()
+	assert.Eventually(t, func() bool { return server.IsPeerConnected("holder") }, time.Second, 10*time.Millisecond)
+
+	hints := make(map[int64]bool)
+	for i := 0; i < 5; i++ {
+		conn := dial("rejected-" + string(rune('a'+i)))
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := conn.ReadMessage()
+		conn.Close()
+
+		var closeErr *websocket.CloseError
+		if !assert.ErrorAs(t, err, &closeErr) {
+			continue
+		}
+		assert.Equal(t, websocket.CloseTryAgainLater, closeErr.Code)
+
+		var reason signal.CloseReason
+		if !assert.NoError(t, json.Unmarshal([]byte(closeErr.Text), &reason)) {
+			continue
+		}
+		assert.Equal(t, "server overloaded", reason.Reason)
+		// At the cap the delay is scaled to max, with up to half of it jittered away
+		assert.GreaterOrEqual(t, reason.ReconnectAfterMs, (max / 2).Milliseconds())
+		assert.LessOrEqual(t, reason.ReconnectAfterMs, max.Milliseconds())
+		hints[reason.ReconnectAfterMs] = true
+	}
+	assert.Greater(t, len(hints), 1, "reconnect hints must be jittered")
+}
+
+func TestWebSocketServer_QuarantinesPeerExceedingStrikeLimit(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := new(MockAuthService)
+	mockAuthService.On("ValidateToken", "other-token").Return(&services.Claims{UserID: domain.UserID("other-user")}, nil)
+	mockAuthService.On("ValidateToken", mock.AnythingOfType("string")).Return(&services.Claims{UserID: domain.UserID("test-user")}, nil)
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+	server.SetQuarantine(3, time.Minute, time.Minute)
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleWebSocket(w, r)
+	}))
+	defer testServer.Close()
+
+	wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=noisy-peer&token=test-token"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		_ = conn.WriteJSON(map[string]interface{}{"type": "bogus", "peer_id": "noisy-peer"})
+	}
+
+	// Error replies arrive until the third strike closes the socket
+	var closeErr *websocket.CloseError
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err = conn.ReadMessage()
+		if err != nil {
+			break
+		}
+	}
+	if assert.ErrorAs(t, err, &closeErr) {
+		assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+		var reason signal.CloseReason
+		if assert.NoError(t, json.Unmarshal([]byte(closeErr.Text), &reason)) {
+			assert.Equal(t, "quarantined", reason.Reason)
+			assert.Equal(t, time.Minute.Milliseconds(), reason.ReconnectAfterMs)
+		}
+	}
+
+	// Reconnecting during the cooldown is refused at the handshake
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	}
+
+	// Strikes belong to the user, so another user picking the same peer ID
+	// (or sharing the address, IP strikes being off) is not locked out
+	time.Sleep(50 * time.Millisecond) // allow server cleanup to run
+	other, _, err := websocket.DefaultDialer.Dial("ws"+testServer.URL[4:]+"/ws?peer_id=noisy-peer&token=other-token", nil)
+	if assert.NoError(t, err) {
+		_ = other.Close()
+	}
+}
+
+// heartbeatingPeerRepository is a peer repository whose entries expire
+// unless heartbeated
+type heartbeatingPeerRepository struct {
+	*MockPeerRepository
+	heartbeats chan domain.PeerID
+}
+
+func (r *heartbeatingPeerRepository) Heartbeat(ctx context.Context, peerID domain.PeerID) error {
+	select {
+	case r.heartbeats <- peerID:
+	default:
+	}
+	return nil
+}
+
+func TestWebSocketServer_PingLoopHeartbeatsPeer(t *testing.T) {
+	peerRepo := &heartbeatingPeerRepository{MockPeerRepository: new(MockPeerRepository), heartbeats: make(chan domain.PeerID, 16)}
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(peerRepo, mockMeshService, mockAuthService, []string{"*"})
+	server.SetPingInterval(20 * time.Millisecond)
+
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	testServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer testServer.Close()
+
+	token, _ := mockAuthService.GenerateToken(domain.UserID("test-user"), "testuser")
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+testServer.URL[4:]+"/ws?peer_id=beating-peer&token="+token, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	// Reading lets the client answer the server's pings
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case peerID := <-peerRepo.heartbeats:
+		assert.Equal(t, domain.PeerID("beating-peer"), peerID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("peer was not heartbeated by the ping loop")
+	}
+}
+
+// gatedValidator validates every token as test-user once release is closed
+type gatedValidator struct {
+	release chan struct{}
+}
+
+func (v gatedValidator) ValidateToken(ctx context.Context, token string) (*services.Claims, error) {
+	select {
+	case <-v.release:
+		return &services.Claims{UserID: domain.UserID("test-user")}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestWebSocketServer_MessagesBeforeAsyncAuth(t *testing.T) {
+	peerID := domain.PeerID("async-peer")
+	joinMsg := signal.SignalMessage{
+		Type:    "join_stream",
+		Payload: json.RawMessage(`{"stream_id": "test-stream", "is_publisher": false}`),
+	}
+
+	dial := func(t *testing.T, policy signal.AuthPolicy, timeout time.Duration) (*websocket.Conn, *MockMeshService, chan struct{}) {
+		mockPeerRepo := new(MockPeerRepository)
+		mockMeshService := new(MockMeshService)
+		server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, createTestAuthService(), []string{"*"})
+		release := make(chan struct{})
+		server.SetAsyncAuth(gatedValidator{release: release}, policy, timeout)
+
+		mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+		mockMeshService.On("FindOptimalSources", mock.Anything, domain.StreamID("test-stream"), peerID, 4).Return([]*domain.Peer{}, nil)
+		mockMeshService.On("RemovePeer", mock.Anything, peerID).Return(nil)
+
+		testServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+		t.Cleanup(testServer.Close)
+
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + string(peerID) + "&token=test-token"
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn, mockMeshService, release
+	}
+
+	t.Run("queue policy handles the join once authenticated", func(t *testing.T) {
+		conn, mockMeshService, release := dial(t, signal.AuthPolicyQueue, 5*time.Second)
+
+		assert.NoError(t, conn.WriteJSON(joinMsg))
+		time.Sleep(100 * time.Millisecond)
+		mockMeshService.AssertNotCalled(t, "AddPeer", mock.Anything, mock.Anything)
+
+		close(release)
+
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		assert.Equal(t, "peers_list", response["type"])
+		mockMeshService.AssertCalled(t, "AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer"))
+	})
+
+	t.Run("reject policy answers AUTH_PENDING until authenticated", func(t *testing.T) {
+		conn, mockMeshService, release := dial(t, signal.AuthPolicyReject, 5*time.Second)
+
+		assert.NoError(t, conn.WriteJSON(joinMsg))
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		assert.Equal(t, "error", response["type"])
+		assert.Equal(t, string(signal.ErrCodeAuthPending), response["code"])
+		mockMeshService.AssertNotCalled(t, "AddPeer", mock.Anything, mock.Anything)
+
+		close(release)
+
+		// The rejected join is not replayed; once authenticated a retry succeeds
+		assert.Eventually(t, func() bool {
+			if err := conn.WriteJSON(joinMsg); err != nil {
+				return false
+			}
+			var response map[string]interface{}
+			if err := conn.ReadJSON(&response); err != nil {
+				return false
+			}
+			return response["type"] == "peers_list"
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("connection is closed when auth does not complete in time", func(t *testing.T) {
+		conn, mockMeshService, _ := dial(t, signal.AuthPolicyQueue, 100*time.Millisecond)
+
+		assert.NoError(t, conn.WriteJSON(joinMsg))
+		_, _, err := conn.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		if assert.ErrorAs(t, err, &closeErr) {
+			assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+			assert.Equal(t, "authentication timed out", closeErr.Text)
+		}
+		mockMeshService.AssertNotCalled(t, "AddPeer", mock.Anything, mock.Anything)
+		mockMeshService.AssertNotCalled(t, "RemovePeer", mock.Anything, peerID)
+	})
+}
+
+func TestWebSocketServer_NotifyLocalLayersAvailable(t *testing.T) {
+	mockPeerRepo := new(MockPeerRepository)
+	mockMeshService := new(MockMeshService)
+	mockAuthService := createTestAuthService()
+	server := signal.NewWebSocketServer(mockPeerRepo, mockMeshService, mockAuthService, []string{"*"})
+
+	mockMeshService.On("AddPeer", mock.Anything, mock.AnythingOfType("*domain.Peer")).Return(nil)
+	mockMeshService.On("FindOptimalSources", mock.Anything, mock.Anything, mock.Anything, 4).Return([]*domain.Peer{}, nil)
+	mockMeshService.On("RemovePeer", mock.Anything, mock.Anything).Return(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer testServer.Close()
+
+	join := func(peerID string) *websocket.Conn {
+		wsURL := "ws" + testServer.URL[4:] + "/ws?peer_id=" + peerID + "&token=test-token"
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.NoError(t, conn.WriteJSON(signal.SignalMessage{Type: "join_stream", Payload: json.RawMessage(`{"stream_id": "layers-stream"}`)}))
+		var response map[string]interface{}
+		assert.NoError(t, conn.ReadJSON(&response))
+		assert.Equal(t, "peers_list", response["type"])
+		return conn
+	}
+
+	member := join("layers-member")
+	defer member.Close()
+
+	server.NotifyLocalLayersAvailable("layers-stream", "layers-publisher", []string{"low", "high"})
+	var notification map[string]interface{}
+	assert.NoError(t, member.ReadJSON(&notification))
+	assert.Equal(t, "layers_available", notification["type"])
+	assert.Equal(t, "layers-publisher", notification["publisher_id"])
+	assert.Equal(t, []interface{}{"low", "high"}, notification["layers"])
+
+	// The publisher dropped its high layer
+	server.NotifyLocalLayersAvailable("layers-stream", "layers-publisher", []string{"low"})
+	assert.NoError(t, member.ReadJSON(&notification))
+	assert.Equal(t, []interface{}{"low"}, notification["layers"])
+
+	// A subscriber joining later is told the current layers after its peers_list
+	late := join("layers-late")
+	defer late.Close()
+	var known map[string]interface{}
+	assert.NoError(t, late.ReadJSON(&known))
+	assert.Equal(t, "layers_available", known["type"])
+	assert.Equal(t, []interface{}{"low"}, known["layers"])
 }
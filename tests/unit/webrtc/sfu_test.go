@@ -136,6 +136,19 @@ func (m *MockMeshService) GetOptimalPath(ctx context.Context, sourcePeer, target
 	return args.Get(0).([]domain.PeerID), args.Error(1)
 }
 
+func (m *MockMeshService) SnapshotTopology(ctx context.Context, streamID domain.StreamID) (*domain.TopologySnapshot, error) {
+	args := m.Called(ctx, streamID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TopologySnapshot), args.Error(1)
+}
+
+func (m *MockMeshService) RecordConnectionResult(ctx context.Context, sourcePeer, targetPeer domain.PeerID, connected bool) error {
+	args := m.Called(ctx, sourcePeer, targetPeer, connected)
+	return args.Error(0)
+}
+
 // createTestSFUService creates SFU service for testing with correct types
 func createTestSFUService(
 	config webRTC.WebRTCConfig,
@@ -143,6 +156,11 @@ func createTestSFUService(
 	metricsService *services.MetricsService, // Use correct type - pointer to struct
 	meshService ports.MeshService,
 ) ports.WebRTCService {
+	// Closed peer connections are removed from the mesh
+	if mockMesh, ok := meshService.(*MockMeshService); ok {
+		mockMesh.On("RemovePeer", mock.Anything, mock.Anything).Return(nil).Maybe()
+	}
+
 	// Use default retry and circuit breaker configs for tests (disabled by default)
 	retryCfg := retry.Config{
 		Enabled:      false, // Disable retry in tests for predictable behavior
@@ -501,21 +519,20 @@ func TestSFUService_MultipleStreams(t *testing.T) {
 	ctx := context.Background()
 	peerID := domain.PeerID("test-peer")
 
-	t.Run("publisher in multiple streams", func(t *testing.T) {
+	t.Run("publisher cannot join a second stream", func(t *testing.T) {
 		stream1 := domain.StreamID("stream-1")
 		stream2 := domain.StreamID("stream-2")
 
 		// Connect to first stream
 		offer1, err := sfuService.CreatePublisherOffer(ctx, peerID, stream1)
-		assert.NoError(t, err)
-		assert.NotNil(t, offer1)
+		require.NoError(t, err)
+		require.NotNil(t, offer1)
 
-		// Connect to second stream
-		offer2, err := sfuService.CreatePublisherOffer(ctx, peerID, stream2)
-		assert.NoError(t, err)
-		assert.NotNil(t, offer2)
+		// Publishing into a second stream is rejected while in the first
+		_, err = sfuService.CreatePublisherOffer(ctx, peerID, stream2)
+		assert.ErrorIs(t, err, domain.ErrPeerInOtherStream)
 
-		// Handle answers
+		// The first stream keeps its publisher
 		pc1, err := webrtc.NewPeerConnection(webrtc.Configuration{})
 		require.NoError(t, err)
 		defer pc1.Close()
@@ -524,30 +541,12 @@ func TestSFUService_MultipleStreams(t *testing.T) {
 		require.NoError(t, err)
 		require.NoError(t, pc1.SetLocalDescription(a1))
 		<-webrtc.GatheringCompletePromise(pc1)
-		answer1 := *pc1.LocalDescription()
 
-		pc2, err := webrtc.NewPeerConnection(webrtc.Configuration{})
-		require.NoError(t, err)
-		defer pc2.Close()
-		require.NoError(t, pc2.SetRemoteDescription(offer2))
-		a2, err := pc2.CreateAnswer(nil)
-		require.NoError(t, err)
-		require.NoError(t, pc2.SetLocalDescription(a2))
-		<-webrtc.GatheringCompletePromise(pc2)
-		answer2 := *pc2.LocalDescription()
-
-		err = sfuService.HandlePublisherAnswer(ctx, peerID, answer1)
-		assert.NoError(t, err)
-
-		err = sfuService.HandlePublisherAnswer(ctx, peerID, answer2)
+		err = sfuService.HandlePublisherAnswer(ctx, peerID, *pc1.LocalDescription())
 		assert.NoError(t, err)
 
-		// Check metrics for both streams
-		metrics1 := metricsService.GetStreamMetrics(stream1)
-		metrics2 := metricsService.GetStreamMetrics(stream2)
-		// Same peer re-publishes on a different stream => stream1 publisher is replaced.
-		assert.Equal(t, 0, metrics1.ActivePublishers)
-		assert.Equal(t, 1, metrics2.ActivePublishers)
+		assert.Equal(t, 1, metricsService.GetStreamMetrics(stream1).ActivePublishers)
+		assert.Equal(t, 0, metricsService.GetStreamMetrics(stream2).ActivePublishers)
 	})
 }
 
@@ -154,6 +154,7 @@ class SignalClient {
                 this.emit('error', {
                     type: 'server_error',
                     message: message.message || message.error,
+                    code: message.code,
                 });
                 break;
             default:
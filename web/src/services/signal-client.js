@@ -84,7 +84,7 @@ class SignalClient {
                     }
 
                     this.reconnectAttempts++;
-                    const delay = this.reconnectDelay * this.reconnectAttempts;
+                    const delay = Math.max(this.reconnectDelay * this.reconnectAttempts, this.reconnectHint(event.reason));
                     setTimeout(() => {
                         this.scheduleReconnect().catch(console.error);
                     }, delay);
@@ -95,6 +95,17 @@ class SignalClient {
         });
     }
 
+    // reconnectHint returns the reconnect delay (ms) the server advertised in
+    // its close reason, or 0 when it gave none
+    reconnectHint(reason) {
+        try {
+            const hint = JSON.parse(reason).reconnect_after_ms;
+            return Number.isFinite(hint) && hint > 0 ? hint : 0;
+        } catch (e) {
+            return 0;
+        }
+    }
+
     async scheduleReconnect() {
         if (this.reconnecting) {
             return;
@@ -153,6 +164,7 @@ class SignalClient {
                 this.emit('error', {
                     type: 'server_error',
                     message: message.message || message.error,
+                    code: message.code,
                 });
                 break;
             default: